@@ -0,0 +1,216 @@
+// Package accesslog writes one line per HTTP request in Common Log Format, Combined Log Format
+// or JSON. It is independent of the application's log output so access logs can be shipped to
+// a separate pipeline.
+package accesslog
+
+import (
+	"component-service/tracing"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format selects the access log line layout.
+type Format string
+
+const (
+	FormatCommon   Format = "common"   // NCSA Common Log Format
+	FormatCombined Format = "combined" // Common Log Format plus referer and user agent
+	FormatJSON     Format = "json"     // One JSON object per line
+)
+
+// clfTimeLayout is the timestamp layout used by Common and Combined Log Format.
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Logger writes access log lines to an io.Writer. Writes are serialized so lines never interleave.
+type Logger struct {
+	format Format
+	prefix string // prepended to every line, e.g. to tag access lines on a shared stdout stream
+
+	mu  sync.Mutex
+	out io.Writer
+	now func() time.Time
+}
+
+// NewLogger returns a Logger writing lines in the given format to out, each prefixed with prefix.
+func NewLogger(out io.Writer, format Format, prefix string) (*Logger, error) {
+	switch format {
+	case FormatCommon, FormatCombined, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported access log format %q (expected common, combined or json)", format)
+	}
+	return &Logger{format: format, prefix: prefix, out: out, now: time.Now}, nil
+}
+
+// FromEnv configures access logging from the environment:
+//
+//	ACCESS_LOG_FORMAT  common, combined or json; empty or "off" disables access logging
+//	ACCESS_LOG_OUTPUT  file path, or "stdout"/"stderr" (default stdout)
+//	ACCESS_LOG_TAG     prefix for each line written to stdout/stderr (default "access: ")
+//
+// It returns a nil Logger when access logging is disabled. Files are opened in append mode.
+func FromEnv() (*Logger, error) {
+	format := strings.ToLower(strings.TrimSpace(os.Getenv("ACCESS_LOG_FORMAT")))
+	if format == "" || format == "off" {
+		return nil, nil
+	}
+
+	output := os.Getenv("ACCESS_LOG_OUTPUT")
+	var out io.Writer
+	prefix := ""
+	switch output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening access log file %s: %w", output, err)
+		}
+		out = f
+	}
+	if out == os.Stdout || out == os.Stderr {
+		prefix = "access: "
+		if tag, ok := os.LookupEnv("ACCESS_LOG_TAG"); ok {
+			prefix = tag
+		}
+	}
+	return NewLogger(out, Format(format), prefix)
+}
+
+// Handler wraps next so every request it serves is logged once the response completes.
+// A nil Logger returns next unchanged.
+func (l *Logger) Handler(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := l.now()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		l.log(r, rec, start)
+	})
+}
+
+// entry is the JSON representation of a request.
+type entry struct {
+	Time       string  `json:"time"`
+	RemoteAddr string  `json:"remote_addr"`
+	User       string  `json:"user,omitempty"`
+	Method     string  `json:"method"`
+	URI        string  `json:"uri"`
+	Proto      string  `json:"proto"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMS float64 `json:"duration_ms"`
+	Referer    string  `json:"referer,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"` // set when tracing.Handler wraps the logger
+	TraceID    string  `json:"trace_id,omitempty"`
+}
+
+func (l *Logger) log(r *http.Request, rec *responseRecorder, start time.Time) {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = h
+	}
+	user, _, _ := r.BasicAuth()
+
+	var line []byte
+	switch l.format {
+	case FormatJSON:
+		e := entry{
+			Time:       start.UTC().Format(time.RFC3339Nano),
+			RemoteAddr: host,
+			User:       user,
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			DurationMS: float64(l.now().Sub(start).Microseconds()) / 1000,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		}
+		if trace := tracing.FromContext(r.Context()); trace != nil {
+			e.RequestID, e.TraceID = trace.RequestID, trace.TraceID()
+		}
+		encoded, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		line = encoded
+	default:
+		size := "-"
+		if rec.bytes > 0 {
+			size = strconv.FormatInt(rec.bytes, 10)
+		}
+		line = fmt.Appendf(nil, "%s - %s [%s] \"%s %s %s\" %d %s",
+			host, escape(orDash(user)), start.Format(clfTimeLayout),
+			escape(r.Method), escape(r.RequestURI), escape(r.Proto), rec.status, size)
+		if l.format == FormatCombined {
+			line = fmt.Appendf(line, " \"%s\" \"%s\"", escape(orDash(r.Referer())), escape(orDash(r.UserAgent())))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(append([]byte(l.prefix), line...), '\n'))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// clfEscaper keeps client-controlled values from breaking the quoted CLF fields or the line itself.
+var clfEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func escape(s string) string {
+	return clfEscaper.Replace(s)
+}
+
+// responseRecorder captures the status code and body size written by the wrapped handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming handlers (e.g. the NDJSON export) working through the wrapper.
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,138 @@
+package accesslog
+
+import (
+	"bytes"
+	"component-service/tracing"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveLogged(t *testing.T, format Format, prefix string, req *http.Request, handler http.HandlerFunc) string {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, format, prefix)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	fixed := time.Date(2024, 3, 5, 14, 7, 9, 0, time.FixedZone("", -7*3600))
+	logger.now = func() time.Time { return fixed }
+	logger.Handler(handler).ServeHTTP(httptest.NewRecorder(), req)
+	return buf.String()
+}
+
+func newRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/components/7?depth=2", nil)
+	req.RemoteAddr = "10.0.0.5:51234"
+	req.Header.Set("Referer", "http://explorer.local/")
+	req.Header.Set("User-Agent", `curl/8.4 "quoted"`)
+	return req
+}
+
+func TestLoggerFormats(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Not found"}`))
+	}
+
+	tests := []struct {
+		format   Format
+		prefix   string
+		expected string
+	}{
+		{
+			format:   FormatCommon,
+			expected: `10.0.0.5 - - [05/Mar/2024:14:07:09 -0700] "GET /components/7?depth=2 HTTP/1.1" 404 21` + "\n",
+		},
+		{
+			format:   FormatCombined,
+			prefix:   "access: ",
+			expected: `access: 10.0.0.5 - - [05/Mar/2024:14:07:09 -0700] "GET /components/7?depth=2 HTTP/1.1" 404 21 "http://explorer.local/" "curl/8.4 \"quoted\""` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got := serveLogged(t, tt.format, tt.prefix, newRequest(), handler)
+			if got != tt.expected {
+				t.Errorf("got  %q\nwant %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	got := serveLogged(t, FormatJSON, "", newRequest(), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	if !strings.HasSuffix(got, "\n") || strings.Count(got, "\n") != 1 {
+		t.Fatalf("Expected a single newline-terminated line, got %q", got)
+	}
+	var e entry
+	if err := json.Unmarshal([]byte(got), &e); err != nil {
+		t.Fatalf("Line is not valid JSON: %v", err)
+	}
+	if e.Status != http.StatusOK || e.Bytes != 2 || e.Method != http.MethodGet || e.URI != "/components/7?depth=2" || e.RemoteAddr != "10.0.0.5" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+}
+
+func TestLoggerJSONTrace(t *testing.T) {
+	trace := &tracing.Context{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", RequestID: "req-1"}
+	req := newRequest()
+	got := serveLogged(t, FormatJSON, "", req.WithContext(tracing.NewContext(req.Context(), trace)), func(w http.ResponseWriter, r *http.Request) {})
+	var e entry
+	if err := json.Unmarshal([]byte(got), &e); err != nil {
+		t.Fatalf("Line is not valid JSON: %v", err)
+	}
+	if e.RequestID != "req-1" || e.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the request's trace logged, got %+v", e)
+	}
+}
+
+func TestLoggerEmptyBodyAndEscaping(t *testing.T) {
+	req := newRequest()
+	req.RequestURI = "/components/\"x\nforged"
+	got := serveLogged(t, FormatCommon, "", req, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	expected := `10.0.0.5 - - [05/Mar/2024:14:07:09 -0700] "GET /components/\"x\nforged HTTP/1.1" 204 -` + "\n"
+	if got != expected {
+		t.Errorf("got  %q\nwant %q", got, expected)
+	}
+}
+
+func TestNewLoggerRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "apache", ""); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestFromEnvDisabled(t *testing.T) {
+	t.Setenv("ACCESS_LOG_FORMAT", "off")
+	logger, err := FromEnv()
+	if err != nil || logger != nil {
+		t.Fatalf("Expected access logging to be disabled, got %v, %v", logger, err)
+	}
+	next := http.NotFoundHandler()
+	if logger.Handler(next) == nil {
+		t.Error("Expected a nil Logger to return the wrapped handler")
+	}
+}
+
+func TestHandlerPreservesFlusher(t *testing.T) {
+	logger, _ := NewLogger(&bytes.Buffer{}, FormatCommon, "")
+	rec := httptest.NewRecorder()
+	logger.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected the wrapped writer to implement http.Flusher")
+		}
+		w.Write([]byte("x"))
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(rec, newRequest())
+	if !rec.Flushed {
+		t.Error("Expected Flush to reach the underlying writer")
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"component-service/cache"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultPrincipalHeader = "X-Principal"
+
+// ACLEnforcer authorizes component requests against the cache's compiled ACL index, so a check
+// costs a map lookup rather than a database round trip. The caller's principal is read from a
+// request header, which an authenticating proxy in front of the service must set and strip from
+// client requests.
+type ACLEnforcer struct {
+	PrincipalHeader string
+	// AdminPrincipals may use the /admin/ endpoints, and read every component through /sync/,
+	// as followers do. Everyone else gets 403 from /admin/ and only what they can read from /sync/.
+	AdminPrincipals map[string]bool
+}
+
+// ACLFromEnv configures ACL enforcement from the environment:
+//
+//	ACL_ENABLED           true enforces component ACLs (default false)
+//	ACL_PRINCIPAL_HEADER  header holding the caller's principal (default X-Principal)
+//	ACL_ADMIN_PRINCIPALS  comma-separated principals allowed the admin endpoints and a full sync
+//
+// It returns a nil ACLEnforcer when enforcement is disabled.
+func ACLFromEnv() (*ACLEnforcer, error) {
+	value := os.Getenv("ACL_ENABLED")
+	if value == "" {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACL_ENABLED %q: expected true or false", value)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	header := os.Getenv("ACL_PRINCIPAL_HEADER")
+	if header == "" {
+		header = defaultPrincipalHeader
+	}
+	admins := make(map[string]bool)
+	for _, principal := range strings.Split(os.Getenv("ACL_ADMIN_PRINCIPALS"), ",") {
+		if principal = strings.TrimSpace(principal); principal != "" {
+			admins[principal] = true
+		}
+	}
+	return &ACLEnforcer{PrincipalHeader: header, AdminPrincipals: admins}, nil
+}
+
+// principalKey is the request context key holding the principal of an ACL-enforced request.
+type principalKey struct{}
+
+// adminPrincipalKey is the request context key set on requests from one of AdminPrincipals.
+type adminPrincipalKey struct{}
+
+// Handler wraps next so that requests addressing a component need the matching permission on
+// it: read for GET, write for PUT, PATCH and DELETE, and admin for its ACL, share links and
+// visibility. Collection endpoints hide what the principal cannot read themselves. The admin
+// endpoints are reserved for AdminPrincipals. Requests made through a share link are left alone.
+// A nil ACLEnforcer returns next unchanged.
+func (e *ACLEnforcer) Handler(next http.Handler) http.Handler {
+	if e == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sharedLinkFrom(r) != nil {
+			next.ServeHTTP(w, r) // already confined to the link's subtree, read-only
+			return
+		}
+		principal := r.Header.Get(e.PrincipalHeader)
+		ctx := context.WithValue(r.Context(), principalKey{}, principal)
+		if e.AdminPrincipals[principal] {
+			ctx = context.WithValue(ctx, adminPrincipalKey{}, true)
+		} else if path := strings.Trim(r.URL.Path, "/"); path == "admin" || strings.HasPrefix(path, "admin/") {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("Principal %q may not use the admin endpoints; see ACL_ADMIN_PRINCIPALS", principal))
+			return
+		}
+		r = r.WithContext(ctx)
+		if id, needed, ok := aclTarget(r); ok && !canAccess(r, id, needed) {
+			respondForbidden(w, principal, needed, id)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// respondForbidden answers 403 for a principal lacking the needed permission on a component.
+func respondForbidden(w http.ResponseWriter, principal string, needed cache.Permission, id int64) {
+	who := fmt.Sprintf("Principal %q", principal)
+	if principal == "" {
+		who = "Anonymous request"
+	}
+	respondWithError(w, http.StatusForbidden, fmt.Sprintf("%s lacks %s permission on component %d", who, needed, id))
+}
+
+// aclTarget returns the component a request addresses and the permission it needs, or ok false
+// for requests that do not address a single component. A component's tree checks reads itself:
+// an unreadable root with readable descendants is served as a placeholder. Simulating an
+// operation, commenting and watching only need read.
+func aclTarget(r *http.Request) (id int64, needed cache.Permission, ok bool) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 2 || len(pathParts) > 4 || pathParts[0] != "components" {
+		return 0, 0, false
+	}
+	id, err := strconv.ParseInt(pathParts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false // /components/search, /components/export, or an invalid ID
+	}
+	switch {
+	case len(pathParts) >= 3 && (pathParts[2] == "acl" || pathParts[2] == "share" || pathParts[2] == "visibility"):
+		return id, cache.PermissionAdmin, true
+	case len(pathParts) == 3 && pathParts[2] == "tree" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		return 0, 0, false
+	case len(pathParts) == 3 && pathParts[2] == "simulate":
+		return id, cache.PermissionRead, true // a preview; the permissions it lacks are reported
+	case len(pathParts) >= 3 && pathParts[2] == "comments":
+		return id, cache.PermissionRead, true // deleting another's comment checks admin itself
+	case len(pathParts) == 3 && pathParts[2] == "watch":
+		return id, cache.PermissionRead, true
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return id, cache.PermissionRead, true
+	default:
+		return id, cache.PermissionWrite, true
+	}
+}
+
+// principalFrom returns the principal of a request, and false when ACLs are not enforced.
+func principalFrom(r *http.Request) (string, bool) {
+	principal, enforced := r.Context().Value(principalKey{}).(string)
+	return principal, enforced
+}
+
+// canAccess reports whether the request's principal holds the needed permission on a component.
+// It is always true when ACLs are not enforced.
+func canAccess(r *http.Request, id int64, needed cache.Permission) bool {
+	principal, enforced := principalFrom(r)
+	if !enforced || cache.GlobalComponentCache == nil {
+		return true
+	}
+	return cache.GlobalComponentCache.Allows(id, principal, needed)
+}
+
+// readableFilter returns a predicate telling which components the request's principal may read,
+// or nil when every component is readable: ACLs are not enforced or no component is restricted.
+func readableFilter(r *http.Request) func(id int64) bool {
+	principal, enforced := principalFrom(r)
+	if !enforced || cache.GlobalComponentCache == nil || !cache.GlobalComponentCache.HasACLs() {
+		return nil
+	}
+	return func(id int64) bool {
+		return cache.GlobalComponentCache.Allows(id, principal, cache.PermissionRead)
+	}
+}
+
+// readerOf returns the principal a listing's cache.Filter is restricted to, so counts and pages
+// leave out what it may not read, or nil under the same conditions readableFilter returns nil.
+func readerOf(r *http.Request) *string {
+	principal, enforced := principalFrom(r)
+	if !enforced || cache.GlobalComponentCache == nil || !cache.GlobalComponentCache.HasACLs() {
+		return nil
+	}
+	return &principal
+}
+
+// syncFilter is readableFilter for the sync endpoints, which serve every component to
+// AdminPrincipals.
+func syncFilter(r *http.Request) func(id int64) bool {
+	if admin, _ := r.Context().Value(adminPrincipalKey{}).(bool); admin {
+		return nil
+	}
+	return readableFilter(r)
+}
+
+// hideUnreadable drops the components the request's principal may not read from an encoded array.
+func hideUnreadable(r *http.Request, array json.RawMessage) (json.RawMessage, error) {
+	readable := readableFilter(r)
+	if readable == nil {
+		return array, nil
+	}
+	var objects []json.RawMessage
+	if err := json.Unmarshal(array, &objects); err != nil {
+		return nil, err
+	}
+	visible := make([]json.RawMessage, 0, len(objects))
+	for _, object := range objects {
+		var ref struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(object, &ref); err != nil {
+			return nil, err
+		}
+		if readable(ref.ID) {
+			visible = append(visible, object)
+		}
+	}
+	return json.Marshal(visible)
+}
+
+// componentACL is the body of GET and PUT /components/{id}/acl.
+type componentACL struct {
+	ComponentID int64            `json:"component_id"`
+	Entries     []cache.ACLEntry `json:"entries"`   // the component's own entries
+	Effective   []cache.ACLEntry `json:"effective"` // what applies, own or inherited; read-only
+}
+
+// componentACLHandler serves GET and PUT /components/{id}/acl.
+func componentACLHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "ACLs require the component cache, which is not initialized")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		respondWithComponentACL(w, id)
+	case http.MethodPut:
+		replaceComponentACL(w, r, id)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for ACL endpoint")
+	}
+}
+
+// replaceComponentACL replaces a component's own entries with the request body's.
+func replaceComponentACL(w http.ResponseWriter, r *http.Request, id int64) {
+	var body componentACL
+	if err := decodeBody(r, &body, false); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if err := validateACLEntries(body.Entries); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := componentStore.ReplaceComponentACL(id, body.Entries); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error replacing ACL: "+err.Error())
+		}
+		return
+	}
+	respondWithComponentACL(w, id)
+}
+
+// validateACLEntries checks that every entry names a principal, at most once.
+func validateACLEntries(entries []cache.ACLEntry) error {
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Principal == "" {
+			return errors.New("Every ACL entry needs a principal; use \"*\" for everyone")
+		}
+		if seen[entry.Principal] {
+			return fmt.Errorf("Duplicate ACL entry for principal %q", entry.Principal)
+		}
+		seen[entry.Principal] = true
+	}
+	return nil
+}
+
+func respondWithComponentACL(w http.ResponseWriter, id int64) {
+	own, effective, found := cache.GlobalComponentCache.ACL(id)
+	if !found {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("component with ID %d not found", id))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, componentACL{ComponentID: id, Entries: own, Effective: effective})
+}
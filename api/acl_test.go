@@ -0,0 +1,269 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// aclTestStore feeds the ACL tests' cache without a database.
+type aclTestStore struct {
+	components []*models.Component
+	entries    []cache.ACLEntry
+}
+
+func (s *aclTestStore) ListComponents() ([]*models.Component, error) { return s.components, nil }
+
+func (s *aclTestStore) ListACLEntries() ([]cache.ACLEntry, error) { return s.entries, nil }
+
+func TestACLEnforcerHandler(t *testing.T) {
+	// 1 is readable by everyone and writable by alice; its child 2 is hidden from everyone else.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "child", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 1, Principal: cache.EveryonePrincipal, Permission: cache.PermissionRead},
+			{ComponentID: 1, Principal: "alice", Permission: cache.PermissionWrite},
+			{ComponentID: 2, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+		},
+	})
+	assert.NoError(t, err)
+
+	var visible string
+	enforcer := &ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}
+	handler := enforcer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := hideUnreadable(r, []byte(`[{"id":1},{"id":2}]`))
+		assert.NoError(t, err)
+		visible = string(body)
+	}))
+	serve := func(method, path, principal string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		if principal != "" {
+			req.Header.Set(defaultPrincipalHeader, principal)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/components/1", ""))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/components/1", "bob"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/components/1", "alice"))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/components/1/acl", "alice"), "ACLs need admin")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/components/1/comments", "bob"), "Commenting needs read")
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/components/2/comments", "bob"))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/components/2/children", "bob"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/components/2", "alice"), "Alice's inherited entry beats everyone's")
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/components/3", "bob"), "Unknown components are left to the handler")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/components", "bob"))
+	assert.Equal(t, `[{"id":1}]`, visible)
+
+	// Without enforcement nothing is hidden.
+	req, _ := http.NewRequest(http.MethodGet, "/components", nil)
+	body, err := hideUnreadable(req, []byte(`[{"id":1},{"id":2}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1},{"id":2}]`, string(body))
+	assert.Equal(t, http.DefaultServeMux, (*ACLEnforcer)(nil).Handler(http.DefaultServeMux))
+}
+
+func TestACLTreePlaceholders(t *testing.T) {
+	// 2 is hidden from everyone but alice; its child 3 is readable again, 4 is not.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "secret", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+			{ID: 3, Name: "shared", ParentID: sql.NullInt64{Int64: 2, Valid: true}},
+			{ID: 4, Name: "private", ParentID: sql.NullInt64{Int64: 2, Valid: true}},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 2, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+			{ComponentID: 2, Principal: "alice", Permission: cache.PermissionRead},
+			{ComponentID: 3, Principal: cache.EveryonePrincipal, Permission: cache.PermissionRead},
+		},
+	})
+	assert.NoError(t, err)
+
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	get := func(path, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/components/1/tree", "bob")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `{"id":2,"hidden":true,"children":[{"id":3,"name":"shared"`)
+	assert.NotContains(t, rr.Body.String(), "secret")
+	assert.NotContains(t, rr.Body.String(), "private")
+
+	rr = get("/components/2/tree", "bob")
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected a hidden root with readable descendants to be a placeholder")
+	assert.True(t, strings.HasPrefix(rr.Body.String(), `{"id":2,"hidden":true,`), rr.Body.String())
+	assert.Equal(t, http.StatusForbidden, get("/components/4/tree", "bob").Code)
+	assert.Equal(t, http.StatusForbidden, get("/components/2", "bob").Code)
+
+	rr = get("/components/2/tree", "alice")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "private")
+}
+
+func TestValidateACLEntries(t *testing.T) {
+	assert.NoError(t, validateACLEntries([]cache.ACLEntry{{Principal: "alice"}, {Principal: cache.EveryonePrincipal}}))
+	assert.Error(t, validateACLEntries([]cache.ACLEntry{{Principal: ""}}))
+	assert.Error(t, validateACLEntries([]cache.ACLEntry{{Principal: "alice"}, {Principal: "alice"}}))
+}
+
+func TestACLSyncAndAdmin(t *testing.T) {
+	// 2 is hidden from everyone but the replica principal, which may use every endpoint.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "hidden", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		},
+		entries: []cache.ACLEntry{{ComponentID: 2, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone}},
+	})
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sync/", SyncHandler)
+	mux.HandleFunc("/admin/", AdminHandler)
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader, AdminPrincipals: map[string]bool{"replica": true}}).Handler(mux)
+	serve := func(target, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if principal != "" {
+			req.Header.Set(defaultPrincipalHeader, principal)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	ids := func(components []*models.Component) []int64 {
+		ids := []int64{}
+		for _, comp := range components {
+			ids = append(ids, comp.ID)
+		}
+		return ids
+	}
+
+	var checkpoint cache.SyncCheckpoint
+	rr := serve("/sync/checkpoint?include=components", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &checkpoint))
+	assert.Equal(t, []int64{1}, ids(checkpoint.Components), "Expected the hidden component to be left out")
+	assert.Empty(t, checkpoint.Hash)
+	assert.Empty(t, checkpoint.Roots)
+
+	rr = serve("/sync/checkpoint?include=components", "replica")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &checkpoint))
+	assert.ElementsMatch(t, []int64{1, 2}, ids(checkpoint.Components))
+	assert.NotEmpty(t, checkpoint.Hash)
+
+	// A change to the hidden component reaches others as a deletion
+	cache.GlobalComponentCache.Set(&models.Component{ID: 2, Name: "renamed", ParentID: sql.NullInt64{Int64: 1, Valid: true}})
+	var delta cache.SyncDelta
+	rr = serve("/sync/delta?since="+checkpoint.Checkpoint, "alice")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &delta))
+	assert.Empty(t, delta.Upserted)
+	assert.Equal(t, []int64{2}, delta.Deleted)
+	rr = serve("/sync/delta?since="+checkpoint.Checkpoint, "replica")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &delta))
+	assert.Equal(t, []int64{2}, ids(delta.Upserted))
+
+	assert.Equal(t, http.StatusForbidden, serve("/admin/debug/cache/snapshot", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/admin/debug/cache/snapshot", "alice").Code)
+	assert.Equal(t, http.StatusOK, serve("/admin/debug/cache/snapshot", "replica").Code)
+}
+
+func TestACLListPaging(t *testing.T) {
+	// 1's children are 2, 3 and 4; 3 is hidden from everyone but alice.
+	defer func(c *cache.ComponentCache, cfg cache.Config, max int) {
+		cache.GlobalComponentCache, cache.GlobalConfig, MaxUnpaginatedChildren = c, cfg, max
+	}(cache.GlobalComponentCache, cache.GlobalConfig, MaxUnpaginatedChildren)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "a", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+			{ID: 3, Name: "b", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+			{ID: 4, Name: "c", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 3, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+			{ComponentID: 3, Principal: "alice", Permission: cache.PermissionRead},
+		},
+	})
+	require.NoError(t, err)
+	MaxUnpaginatedChildren = 2
+
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	get := func(path, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	ids := func(rr *httptest.ResponseRecorder) []int64 {
+		var components []models.Component
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &components), rr.Body.String())
+		var ids []int64
+		for _, component := range components {
+			ids = append(ids, component.ID)
+		}
+		return ids
+	}
+
+	rr := get("/components/1/children?limit=1&offset=1", "bob")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"), "Hidden children are not counted")
+	assert.Equal(t, []int64{4}, ids(rr), "Pages skip hidden children")
+	assert.NotContains(t, rr.Header().Get("Link"), `rel="next"`)
+
+	rr = get("/components/1/children", "bob")
+	require.Equal(t, http.StatusOK, rr.Code, "Expected the readable children to fit unpaginated: %s", rr.Body.String())
+	assert.Equal(t, []int64{2, 4}, ids(rr))
+	assert.Equal(t, http.StatusBadRequest, get("/components/1/children", "alice").Code)
+
+	rr = get("/components?limit=2", "bob")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "3", rr.Header().Get("X-Total-Count"))
+	assert.Len(t, ids(rr), 2)
+	assert.NotContains(t, rr.Body.String(), `"name":"b"`)
+
+	rr = get("/components/1/descendants", "bob")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"))
+	assert.Equal(t, []int64{2, 4}, ids(rr))
+
+	rr = get("/components?after=&limit=3", "bob")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var cursor cursorPage
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &cursor))
+	assert.NotContains(t, string(cursor.Components), `"name":"b"`)
+	assert.Nil(t, cursor.NextCursor, "A full page of the readable components is the last")
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/jobs"
+	"component-service/store"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// AdminHandler routes operator-facing diagnostics under /admin/.
+func AdminHandler(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // e.g., ["admin", "diagnostics", "indexes"]
+
+	if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "diagnostics" && pathParts[2] == "indexes" { // /admin/diagnostics/indexes
+		if r.Method == http.MethodGet {
+			getIndexDiagnostics(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 4 && pathParts[0] == "admin" && pathParts[1] == "debug" && pathParts[2] == "cache" && pathParts[3] == "memory" { // /admin/debug/cache/memory
+		if r.Method == http.MethodGet {
+			getCacheMemory(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 4 && pathParts[0] == "admin" && pathParts[1] == "debug" && pathParts[2] == "cache" && pathParts[3] == "snapshot" { // /admin/debug/cache/snapshot
+		if r.Method == http.MethodGet {
+			getCacheSnapshot(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "search" && pathParts[2] == "reindex" { // /admin/search/reindex
+		if r.Method == http.MethodPost {
+			startSearchReindex(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "reporting" && pathParts[2] == "refresh" { // /admin/reporting/refresh
+		if r.Method == http.MethodPost {
+			startReportingRefresh(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "closure" && pathParts[2] == "rebuild" { // /admin/closure/rebuild
+		if r.Method == http.MethodPost {
+			startClosureRebuild(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "closure" && pathParts[2] == "check" { // /admin/closure/check
+		if r.Method == http.MethodGet {
+			getClosureCheck(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "components" { // /admin/components/{id}
+		id, err := strconv.ParseInt(pathParts[2], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodDelete {
+			purgeComponent(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "admin" && pathParts[1] == "unreferenced" { // /admin/unreferenced
+		if r.Method == http.MethodGet || r.Method == http.MethodPost {
+			unreferencedHandler(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "admin" && pathParts[1] == "duplicates" { // /admin/duplicates
+		if r.Method == http.MethodGet {
+			listDuplicates(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "duplicates" && pathParts[2] == "scan" { // /admin/duplicates/scan
+		if r.Method == http.MethodPost {
+			startDuplicateScan(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "admin" && pathParts[1] == "webhooks" { // /admin/webhooks
+		webhooksHandler(w, r)
+	} else if (len(pathParts) >= 3 && len(pathParts) <= 6) && pathParts[0] == "admin" && pathParts[1] == "webhooks" { // /admin/webhooks/{id}[/deliveries[/{deliveryID}/replay]]
+		id, err := strconv.ParseInt(pathParts[2], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid webhook ID in path")
+			return
+		}
+		if len(pathParts) == 3 {
+			webhookHandler(w, r, id)
+		} else if pathParts[3] != "deliveries" || len(pathParts) == 5 || (len(pathParts) == 6 && pathParts[5] != "replay") {
+			respondWithError(w, http.StatusNotFound, "Not found")
+		} else if len(pathParts) == 6 {
+			deliveryID, err := strconv.ParseInt(pathParts[4], 10, 64)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid delivery ID in path")
+			} else if r.Method == http.MethodPost {
+				replayWebhookDelivery(w, r, id, deliveryID)
+			} else {
+				respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+		} else if r.Method == http.MethodGet {
+			listWebhookDeliveries(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "admin" && pathParts[1] == "slo" { // /admin/slo
+		if r.Method == http.MethodGet {
+			getSLO(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "admin" && pathParts[1] == "jobs" { // /admin/jobs/{id}
+		if r.Method == http.MethodGet {
+			getJob(w, r, pathParts[2])
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else {
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// searchReindexJob names the reindex job; only one runs at a time.
+const searchReindexJob = "search-reindex"
+
+// startSearchReindex rebuilds the full-text search index in the background and answers 202 with
+// the job, whose progress is polled at the Location URL. While a reindex runs, another request
+// gets 409 with the running job.
+func startSearchReindex(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	batchSize := q.intRange("batch_size", store.DefaultReindexBatchSize, 1, maxExportBatchSize)
+	if !q.valid(w) {
+		return
+	}
+	job, err := jobs.Default.Start(searchReindexJob, func(ctx context.Context, report func(done, total int)) error {
+		return componentStore.ReindexSearch(ctx, batchSize, report)
+	})
+	w.Header().Set("Location", "/admin/jobs/"+job.ID)
+	if errors.Is(err, jobs.ErrAlreadyRunning) {
+		respondWithJSON(w, http.StatusConflict, job)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// reportingRefreshJob names the reporting refresh job; only one runs at a time.
+const reportingRefreshJob = "reporting-refresh"
+
+// startReportingRefresh refreshes the database's reporting views in the background, answering
+// like startSearchReindex.
+func startReportingRefresh(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	job, err := jobs.Default.Start(reportingRefreshJob, func(ctx context.Context, report func(done, total int)) error {
+		return componentStore.RefreshReporting(ctx, report)
+	})
+	w.Header().Set("Location", "/admin/jobs/"+job.ID)
+	if errors.Is(err, jobs.ErrAlreadyRunning) {
+		respondWithJSON(w, http.StatusConflict, job)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// closureRebuildJob names the closure table rebuild job; only one runs at a time.
+const closureRebuildJob = "closure-rebuild"
+
+// startClosureRebuild rebuilds the closure table from the adjacency list in the background,
+// answering like startSearchReindex.
+func startClosureRebuild(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	job, err := jobs.Default.Start(closureRebuildJob, func(ctx context.Context, report func(done, total int)) error {
+		return componentStore.RebuildClosure(ctx, report)
+	})
+	w.Header().Set("Location", "/admin/jobs/"+job.ID)
+	if errors.Is(err, jobs.ErrAlreadyRunning) {
+		respondWithJSON(w, http.StatusConflict, job)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// getClosureCheck compares the closure table with the adjacency list, reporting the pairs that
+// differ. An inconsistent table is repaired by POST /admin/closure/rebuild.
+func getClosureCheck(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	check, err := componentStore.CheckClosure(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking the closure table: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, check)
+}
+
+// purgeComponent hard-deletes a component, live or soft-deleted, so it can no longer be restored.
+func purgeComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if err := writeStore(r).PurgeComponent(id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error purging component: "+err.Error())
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Component purged successfully"})
+}
+
+// getJob reports a background job's status and progress.
+func getJob(w http.ResponseWriter, r *http.Request, id string) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	job, found := jobs.Default.Get(id)
+	if !found {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("job %s not found", id))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// getIndexDiagnostics runs EXPLAIN on the store's representative queries and reports missing indexes.
+func getIndexDiagnostics(w http.ResponseWriter, r *http.Request) {
+	advice, err := componentStore.ExplainRepresentativeQueries()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error explaining queries: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, advice)
+}
+
+// cacheMemoryReport pairs the cache's own estimate with the Go runtime's heap figures for context.
+type cacheMemoryReport struct {
+	Cache          cache.MemoryStats `json:"cache"`
+	HeapAllocBytes uint64            `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64            `json:"heap_inuse_bytes"`
+	HeapObjects    uint64            `json:"heap_objects"`
+}
+
+// getCacheMemory reports the approximate memory held by each cache structure.
+func getCacheMemory(w http.ResponseWriter, r *http.Request) {
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Component cache is not initialized")
+		return
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	respondWithJSON(w, http.StatusOK, cacheMemoryReport{
+		Cache:          cache.GlobalComponentCache.MemoryStats(),
+		HeapAllocBytes: ms.HeapAlloc,
+		HeapInuseBytes: ms.HeapInuse,
+		HeapObjects:    ms.HeapObjects,
+	})
+}
+
+// getCacheSnapshot downloads the cached components as a gzip-compressed JSON array.
+func getCacheSnapshot(w http.ResponseWriter, r *http.Request) {
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Component cache is not initialized")
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="component-cache.json.gz"`)
+	w.WriteHeader(http.StatusOK)
+	if err := cache.GlobalComponentCache.WriteSnapshot(w); err != nil {
+		// Headers are already sent; the truncated gzip stream signals the failure to the client.
+		log.Printf("Error writing cache snapshot: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"component-service/store"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// multipartOverhead is the room an upload body gets beyond store.MaxAttachmentBytes, for the
+// multipart headers and boundaries around the file.
+const multipartOverhead = 1 << 20
+
+// attachmentsHandler serves /components/{id}/attachments (POST uploads a file, GET lists them)
+// and /components/{id}/attachments/{attachmentID} (GET downloads one, DELETE removes it).
+func attachmentsHandler(w http.ResponseWriter, r *http.Request, id int64, attachmentID string) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if store.AttachmentStorage == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Attachments are disabled: set ATTACHMENTS_STORAGE to enable them")
+		return
+	}
+	if attachmentID == "" {
+		switch r.Method {
+		case http.MethodPost:
+			uploadAttachment(w, r, id)
+		case http.MethodGet:
+			list, err := componentStore.ListAttachments(id)
+			if err != nil {
+				respondWithAttachmentError(w, err, "Error listing attachments")
+				return
+			}
+			respondWithJSON(w, http.StatusOK, list)
+		default:
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for attachments endpoint")
+		}
+		return
+	}
+
+	parsed, err := strconv.ParseInt(attachmentID, 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid attachment ID in path")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		downloadAttachment(w, r, id, parsed)
+	case http.MethodDelete:
+		if err := componentStore.DeleteAttachment(id, parsed); err != nil {
+			respondWithAttachmentError(w, err, "Error deleting attachment")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Attachment deleted successfully"})
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for attachment endpoint")
+	}
+}
+
+// uploadAttachment stores the part named "file" of a multipart/form-data body. Parts before it
+// are skipped; parts after it are not read.
+func uploadAttachment(w http.ResponseWriter, r *http.Request, id int64) {
+	r.Body = http.MaxBytesReader(w, r.Body, store.MaxAttachmentBytes+multipartOverhead)
+	defer r.Body.Close()
+	reader, err := r.MultipartReader()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid upload: expected a multipart/form-data body with a file part")
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			respondWithError(w, http.StatusBadRequest, "Invalid upload: no part named file")
+			return
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithAttachmentError(w, err, "")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid upload: "+err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		attachment, err := componentStore.CreateAttachment(r.Context(), id, part.FileName(), part.Header.Get("Content-Type"), part)
+		part.Close()
+		if err != nil {
+			respondWithAttachmentError(w, err, "Error storing attachment")
+			return
+		}
+		log.Printf("Attachment %d (%s, %d bytes) added to component %d", attachment.ID, attachment.Filename, attachment.Size, id)
+		w.Header().Set("Location", fmt.Sprintf("/components/%d/attachments/%d", id, attachment.ID))
+		respondWithJSON(w, http.StatusCreated, attachment)
+		return
+	}
+}
+
+// downloadAttachment sends an attachment's data as a download, never rendered inline, so an
+// uploaded page cannot run in the service's origin. Its SHA-256 is the ETag.
+func downloadAttachment(w http.ResponseWriter, r *http.Request, id, attachmentID int64) {
+	attachment, data, err := componentStore.OpenAttachment(r.Context(), id, attachmentID)
+	if err != nil {
+		respondWithAttachmentError(w, err, "Error reading attachment")
+		return
+	}
+	defer data.Close()
+
+	etag := `"` + attachment.SHA256 + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, data); err != nil {
+		log.Printf("Error sending attachment %d of component %d: %v", attachmentID, id, err)
+	}
+}
+
+// respondWithAttachmentError maps an attachment error to its status, prefixing unexpected ones
+// with context.
+func respondWithAttachmentError(w http.ResponseWriter, err error, context string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, store.ErrAttachmentsDisabled):
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, store.ErrAttachmentTooLarge), errors.As(err, &tooLarge):
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Attachment exceeds the limit of %d bytes", store.MaxAttachmentBytes))
+	case errors.Is(err, store.ErrInvalidAttachment):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, context+": "+err.Error())
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"component-service/attachments"
+	"component-service/db"
+	"component-service/models"
+	"component-service/store"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enableTestAttachments configures local attachment storage for the test.
+func enableTestAttachments(t *testing.T, maxBytes int64) {
+	storage, err := attachments.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	store.AttachmentStorage, store.MaxAttachmentBytes = storage, maxBytes
+	t.Cleanup(func() { store.AttachmentStorage, store.MaxAttachmentBytes = nil, attachments.DefaultMaxBytes })
+}
+
+// multipartBody encodes parts, by form name, as a multipart/form-data body; "file" is sent as a
+// file named filename.
+func multipartBody(t *testing.T, filename string, parts map[string]string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, content := range parts {
+		var part interface{ Write([]byte) (int, error) }
+		var err error
+		if name == "file" {
+			part, err = writer.CreateFormFile(name, filename)
+		} else {
+			part, err = writer.CreateFormField(name)
+		}
+		require.NoError(t, err)
+		part.Write([]byte(content))
+	}
+	require.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestAttachmentsRequests(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/1/attachments", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Expected attachments to be disabled without storage")
+
+	enableTestAttachments(t, 16)
+	bigField, bigType := multipartBody(t, "", map[string]string{"comment": strings.Repeat("x", 2<<20)})
+	noFile, noFileType := multipartBody(t, "", map[string]string{"comment": "hello"})
+	for _, tc := range []struct {
+		method, target, contentType string
+		body                        *bytes.Buffer
+		code                        int
+	}{
+		{http.MethodPut, "/components/1/attachments", "", &bytes.Buffer{}, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/components/1/attachments/2", "", &bytes.Buffer{}, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/components/x/attachments", "", &bytes.Buffer{}, http.StatusBadRequest},
+		{http.MethodGet, "/components/1/attachments/x", "", &bytes.Buffer{}, http.StatusBadRequest},
+		{http.MethodGet, "/components/1/attachments?limit=5", "", &bytes.Buffer{}, http.StatusBadRequest},
+		{http.MethodPost, "/components/1/attachments", "application/json", bytes.NewBufferString(`{}`), http.StatusBadRequest},
+		{http.MethodPost, "/components/1/attachments", noFileType, noFile, http.StatusBadRequest},
+		{http.MethodPost, "/components/1/attachments", bigType, bigField, http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, tc.body)
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, req)
+		assert.Equal(t, tc.code, rr.Code, "%s %s: %s", tc.method, tc.target, rr.Body.String())
+	}
+}
+
+func TestAPIAttachments(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	enableTestAttachments(t, 1024)
+	comp := createTestComponentDirectly(t, "Pump", "", sql.NullInt64{})
+	base := fmt.Sprintf("/components/%d/attachments", comp.ID)
+
+	body, contentType := multipartBody(t, "Wiring diagram.txt", map[string]string{"file": "L1 to T1", "comment": "ignored"})
+	req := httptest.NewRequest(http.MethodPost, base, body)
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created models.Attachment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "Wiring diagram.txt", created.Filename)
+	assert.Equal(t, int64(8), created.Size)
+	assert.Equal(t, fmt.Sprintf("%s/%d", base, created.ID), rr.Header().Get("Location"))
+
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, base, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list []models.Attachment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Len(t, list, 1)
+
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d", base, created.ID), nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "L1 to T1", rr.Body.String())
+	assert.Equal(t, `attachment; filename="Wiring diagram.txt"`, rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, `"`+created.SHA256+`"`, rr.Header().Get("ETag"))
+
+	big, bigType := multipartBody(t, "big.bin", map[string]string{"file": strings.Repeat("x", 1025)})
+	req = httptest.NewRequest(http.MethodPost, base, big)
+	req.Header.Set("Content-Type", bigType)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%d", base, created.ID), nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d", base, created.ID), nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/components/88888/attachments", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"component-service/models"
+	"component-service/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Caps on ?limit of GET /attribute-schemas/{type}/violations, the violating components listed.
+const (
+	defaultAttributeViolations = 100
+	maxAttributeViolations     = 1000
+)
+
+// AttributeSchemasHandler routes the attribute schemas of component types:
+// GET /attribute-schemas, GET, PUT and DELETE /attribute-schemas/{type}, and
+// GET /attribute-schemas/{type}/violations.
+func AttributeSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // e.g., ["attribute-schemas", "pump"]
+	if len(pathParts) >= 2 && pathParts[0] == "attribute-schemas" && !models.IsTypeName(pathParts[1]) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid component type %q: expected a lowercase letter followed by lowercase letters, digits, - and _", pathParts[1]))
+		return
+	}
+
+	if len(pathParts) == 1 && pathParts[0] == "attribute-schemas" { // /attribute-schemas
+		if r.Method == http.MethodGet {
+			listAttributeSchemas(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "attribute-schemas" { // /attribute-schemas/{type}
+		t := pathParts[1]
+		switch r.Method {
+		case http.MethodGet:
+			getAttributeSchema(w, r, t)
+		case http.MethodPut:
+			replaceAttributeSchema(w, r, t)
+		case http.MethodDelete:
+			deleteAttributeSchema(w, r, t)
+		default:
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "attribute-schemas" && pathParts[2] == "violations" { // /attribute-schemas/{type}/violations
+		if r.Method == http.MethodGet {
+			listAttributeViolations(w, r, pathParts[1])
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	} else {
+		respondWithError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func listAttributeSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := componentStore.ListAttributeSchemas()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing attribute schemas: "+err.Error())
+		return
+	}
+	if schemas == nil {
+		schemas = []*models.AttributeSchema{}
+	}
+	respondWithJSON(w, http.StatusOK, schemas)
+}
+
+func getAttributeSchema(w http.ResponseWriter, r *http.Request, t string) {
+	schema, err := componentStore.GetAttributeSchema(t)
+	if err != nil {
+		respondWithAttributeSchemaError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, schema)
+}
+
+// replaceAttributeSchema defines the attributes of type t from a body of the form
+// {"strict": false, "attributes": [...]}. A type in the body must match the path.
+func replaceAttributeSchema(w http.ResponseWriter, r *http.Request, t string) {
+	var schema models.AttributeSchema
+	if err := decodeBody(r, &schema, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if schema.Type != "" && schema.Type != t {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("The body is for type %q but the path for type %q", schema.Type, t))
+		return
+	}
+	schema.Type = t
+	if err := componentStore.ReplaceAttributeSchema(&schema); err != nil {
+		respondWithAttributeSchemaError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, schema)
+}
+
+func deleteAttributeSchema(w http.ResponseWriter, r *http.Request, t string) {
+	if err := componentStore.DeleteAttributeSchema(t); err != nil {
+		respondWithAttributeSchemaError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Attribute schema deleted successfully"})
+}
+
+// listAttributeViolations reports the components of type t whose metadata breaks its schema,
+// checked as if the schema were strict, so what making it strict would reject can be fixed first.
+// ?limit caps the components listed, not those counted.
+func listAttributeViolations(w http.ResponseWriter, r *http.Request, t string) {
+	q := newQueryParams(r)
+	limit := q.intRange("limit", defaultAttributeViolations, 1, maxAttributeViolations)
+	if !q.valid(w) {
+		return
+	}
+	report, err := componentStore.AttributeViolations(t, limit)
+	if err != nil {
+		respondWithAttributeSchemaError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// respondWithAttributeSchemaError sends the error response of a failed attribute schema request.
+func respondWithAttributeSchemaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrAttributeSchemaNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, store.ErrInvalidAttributeSchema):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error accessing attribute schema: "+err.Error())
+	}
+}
+
+// respondWithInvalidAttributes sends the 422 for a write failing with store.ErrInvalidAttributes,
+// naming the field as metadata.{attribute} and giving its value in metadata, null when missing.
+func respondWithInvalidAttributes(w http.ResponseWriter, err error, metadata json.RawMessage) {
+	field := "metadata"
+	var value interface{}
+	var attrErr *models.AttributeError
+	if errors.As(err, &attrErr) {
+		field += "." + attrErr.Attribute
+		var object map[string]json.RawMessage
+		if json.Unmarshal(metadata, &object) == nil && object[attrErr.Attribute] != nil {
+			value = object[attrErr.Attribute]
+		}
+	}
+	respondWithJSON(w, http.StatusUnprocessableEntity, invalidFieldResponse{Error: err.Error(), Field: field, Value: value})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"component-service/models"
+	"component-service/store"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeSchemasRequests(t *testing.T) {
+	for _, tc := range []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodGet, "/attribute-schemas/Pump", "", http.StatusBadRequest},
+		{http.MethodPost, "/attribute-schemas", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/attribute-schemas/pump", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/attribute-schemas/pump/attributes", "", http.StatusNotFound},
+		{http.MethodGet, "/attribute-schemas/Pump/violations", "", http.StatusBadRequest},
+		{http.MethodPost, "/attribute-schemas/pump/violations", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/attribute-schemas/pump/violations?limit=0", "", http.StatusBadRequest},
+		{http.MethodGet, "/attribute-schemas/pump/violations?strict=true", "", http.StatusBadRequest},
+		{http.MethodPut, "/attribute-schemas/pump", `{"strict": "yes", "attributes": [{"name": "vendor", "data_type": "string"}]}`, http.StatusBadRequest},
+		{http.MethodPut, "/attribute-schemas/pump", `{"attributes": [`, http.StatusBadRequest},
+		{http.MethodPut, "/attribute-schemas/pump", `{"attributes": [], "version": 2}`, http.StatusBadRequest},
+		{http.MethodPut, "/attribute-schemas/pump", `{"type": "valve", "attributes": [{"name": "vendor", "data_type": "string"}]}`, http.StatusBadRequest},
+		{http.MethodPut, "/attribute-schemas/pump", `{"attributes": [{"name": "vendor", "data_type": "date"}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/attribute-schemas/pump", `{"attributes": [{"name": "stages", "data_type": "integer", "enum": ["1"]}]}`, http.StatusUnprocessableEntity},
+	} {
+		rr := httptest.NewRecorder()
+		AttributeSchemasHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, tc.code, rr.Code, "%s %s %s: %s", tc.method, tc.target, tc.body, rr.Body.String())
+	}
+}
+
+func TestRespondWithInvalidAttributes(t *testing.T) {
+	err := fmt.Errorf("%w: %w", store.ErrInvalidAttributes, &models.AttributeError{Attribute: "vendor", Reason: `must be one of ["acme"]`})
+	rr := httptest.NewRecorder()
+	respondWithInvalidAttributes(rr, err, []byte(`{"vendor":"other","rated_kw":7.5}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.JSONEq(t, `{"error": "invalid attributes: attribute \"vendor\" must be one of [\"acme\"]", "field": "metadata.vendor", "value": "other"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	respondWithInvalidAttributes(rr, fmt.Errorf("%w: %w", store.ErrInvalidAttributes, &models.AttributeError{Attribute: "vendor", Reason: "is required"}), nil)
+	assert.JSONEq(t, `{"error": "invalid attributes: attribute \"vendor\" is required", "field": "metadata.vendor", "value": null}`, rr.Body.String())
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// listBacklinks serves GET /components/{id}/backlinks: the components whose description or
+// metadata refers to the component, by its ID, its slug or a URL to either (see
+// models.Component.Links), ordered by ID. Components the caller may not read are left out.
+// Backlinks are indexed by the component cache, which this needs.
+func listBacklinks(w http.ResponseWriter, r *http.Request, id int64) {
+	q := newQueryParams(r)
+	fields := parseFields(q)
+	if !q.valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Backlinks require the component cache, which is not initialized")
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+	components, found := cache.GlobalComponentCache.Backlinks(id)
+	if !found {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("component with ID %d not found", id))
+		return
+	}
+	if readable := readableFilter(r); readable != nil {
+		visible := make([]*models.Component, 0, len(components))
+		for _, component := range components {
+			if readable(component.ID) {
+				visible = append(visible, component)
+			}
+		}
+		components = visible
+	}
+	body, err := json.Marshal(components)
+	if err == nil {
+		body, err = fields.projectArray(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing backlinks: "+err.Error())
+		return
+	}
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListBacklinks(t *testing.T) {
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "pump", Slug: "pump"},
+		{ID: 2, Name: "valve", Slug: "valve", Description: "Fed by [[pump]]."},
+		{ID: 3, Name: "plan", Metadata: []byte(`{"equipment":["https://explorer.example.com/components/1"]}`)},
+	}})
+	assert.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	rr := get("/components/1/backlinks?fields=id,name")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"id":2,"name":"valve"},{"id":3,"name":"plan"}]`, rr.Body.String())
+	assert.NotEmpty(t, rr.Header().Get("ETag"))
+
+	rr = get("/components/2/backlinks")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[]`, rr.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/components/9/backlinks").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/components/1/backlinks?limit=1").Code)
+
+	cache.GlobalComponentCache = nil
+	assert.Equal(t, http.StatusServiceUnavailable, get("/components/1/backlinks").Code)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"component-service/events"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// changeFeedKeepalive is how often GET /components/events writes a comment while no event is
+// sent, so proxies do not close the idle connection.
+const changeFeedKeepalive = 15 * time.Second
+
+// changeFeed is the feed configured with EnableChangeFeed, or nil.
+var changeFeed *events.Feed
+
+// EnableChangeFeed serves GET /components/events from f, which must be started.
+func EnableChangeFeed(f *events.Feed) {
+	changeFeed = f
+}
+
+// streamFormat is how the change streams encode events: in an event schema version, and signed
+// or not.
+type streamFormat struct {
+	version int
+	signed  bool
+}
+
+// parseStreamFormat reads the schema_version and signed parameters of the change streams, then
+// checks the parameters as queryParams.valid does. schema_version defaults to the current
+// version; with signed=true each event is sent as the compact JWS of its JSON. ok is false once
+// the request has been answered, for an invalid parameter or, with 503, for signed=true without
+// signing keys.
+func parseStreamFormat(w http.ResponseWriter, q *queryParams) (format streamFormat, ok bool) {
+	versions := make([]string, len(events.SchemaVersions))
+	for i, v := range events.SchemaVersions {
+		versions[i] = strconv.Itoa(v)
+	}
+	format.version, _ = strconv.Atoi(q.oneOf("schema_version", strconv.Itoa(events.SchemaVersion), versions...))
+	format.signed = q.oneOf("signed", "false", "true", "false") == "true"
+	if !q.valid(w) {
+		return format, false
+	}
+	if format.signed && eventKeys == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Event signing is not configured")
+		return format, false
+	}
+	return format, true
+}
+
+// encode returns e as the change streams send it.
+func (f streamFormat) encode(e events.Event) ([]byte, error) {
+	data, err := events.Encode(e, f.version, "")
+	if err != nil || !f.signed {
+		return data, err
+	}
+	return []byte(eventKeys.Sign(data)), nil
+}
+
+// changeFeedReset is the data of a "reset" event: the client missed changes and should reload
+// what it shows.
+type changeFeedReset struct {
+	Reason string `json:"reason"`
+}
+
+// streamChangeFeed serves GET /components/events, a Server-Sent Events stream of every component
+// event, for clients that cannot use the WebSocket of GET /components/watch. Each event
+// carries its ID from the feed. A client resuming with the Last-Event-ID header, or the
+// last_event_id parameter, first receives what it missed; when the feed no longer holds that, it
+// receives a "reset" event instead and continues from the newest event. Events of components the
+// request's principal may not read are left out. Events are encoded as parseStreamFormat reads.
+func streamChangeFeed(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	lastID := q.str("last_event_id")
+	format, ok := parseStreamFormat(w, q)
+	if !ok {
+		return
+	}
+	if changeFeed == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "The change feed is not enabled")
+		return
+	}
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		lastID = header
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	flusher := http.NewResponseController(w)
+
+	if lastID == "" {
+		lastID = changeFeed.LastID()
+	}
+	keepalive := time.NewTicker(changeFeedKeepalive)
+	defer keepalive.Stop()
+	for {
+		entries, changed, ok := changeFeed.After(lastID)
+		if !ok {
+			lastID = changeFeed.LastID()
+			reset, _ := json.Marshal(changeFeedReset{Reason: "the changes after the last event received are no longer available"})
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: reset\ndata: %s\n\n", lastID, reset); err != nil {
+				return
+			}
+			continue
+		}
+		for _, entry := range entries {
+			lastID = entry.ID
+			if !eventReadable(r, entry.Event) {
+				continue
+			}
+			data, err := format.encode(entry.Event)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", entry.ID, data); err != nil {
+				return
+			}
+		}
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bufio"
+	"component-service/cache"
+	"component-service/events"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamChangeFeed(t *testing.T) {
+	defer func(f *events.Feed) { changeFeed = f }(changeFeed)
+	feed := events.NewFeed(2)
+	feed.Start()
+	defer feed.Stop()
+	EnableChangeFeed(feed)
+	server := httptest.NewServer(YAMLHandler(http.HandlerFunc(ComponentsHandler)))
+	defer server.Close()
+
+	// open streams the feed; next returns the fields of the following message, keepalives skipped.
+	open := func(lastEventID string) (next func() map[string]string, close func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/components/events", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		lines := bufio.NewScanner(resp.Body)
+		return func() map[string]string {
+				fields := map[string]string{}
+				for lines.Scan() {
+					if lines.Text() == "" && len(fields) > 0 {
+						return fields
+					}
+					if name, value, ok := strings.Cut(lines.Text(), ": "); ok && name != "" {
+						fields[name] = value
+					}
+				}
+				t.Fatalf("stream ended: %v", lines.Err())
+				return nil
+			}, func() {
+				cancel()
+				resp.Body.Close()
+			}
+	}
+
+	start := feed.LastID()
+	next, closeStream := open(start)
+	events.Publish(events.Event{Type: events.ComponentCreated, ComponentID: 1})
+	first := next()
+	assert.Contains(t, first["data"], `"component_id":1`)
+	assert.Contains(t, first["data"], `"type":"component.created"`)
+	assert.Contains(t, first["data"], `"schema_version":1`)
+	assert.NotEmpty(t, first["id"])
+	closeStream()
+
+	// Resuming replays what followed the last event received
+	events.Publish(events.Event{Type: events.ComponentUpdated, ComponentID: 2})
+	next, closeStream = open(first["id"])
+	second := next()
+	assert.Contains(t, second["data"], `"component_id":2`)
+	closeStream()
+
+	// Once the feed has dropped what followed, the client is told to reload
+	events.Publish(events.Event{Type: events.ComponentUpdated, ComponentID: 3})
+	next, closeStream = open(start)
+	reset := next()
+	assert.Equal(t, "reset", reset["event"])
+	assert.Equal(t, feed.LastID(), reset["id"])
+	events.Publish(events.Event{Type: events.ComponentDeleted, ComponentID: 4})
+	assert.Contains(t, next()["data"], `"component_id":4`)
+	closeStream()
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/events?schema_version=99", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected an unknown schema version to be refused")
+
+	changeFeed = nil
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/events", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestStreamChangeFeedReplayOfDeleted(t *testing.T) {
+	// 2 is readable by alice only, and deleted before bob resumes the stream.
+	defer func(f *events.Feed) { changeFeed = f }(changeFeed)
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "private", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 2, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+			{ComponentID: 2, Principal: "alice", Permission: cache.PermissionRead},
+		},
+	})
+	require.NoError(t, err)
+	feed := events.NewFeed(10)
+	feed.Start()
+	defer feed.Stop()
+	EnableChangeFeed(feed)
+	enforcer := &ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}
+	server := httptest.NewServer(YAMLHandler(enforcer.Handler(http.HandlerFunc(ComponentsHandler))))
+	defer server.Close()
+
+	start := feed.LastID()
+	root := int64(1)
+	events.Publish(events.Event{Type: events.ComponentUpdated, ComponentID: 2, OldParentID: &root, NewParentID: &root})
+	cache.GlobalComponentCache.Delete(2)
+	events.Publish(events.Event{Type: events.ComponentDeleted, ComponentID: 2, OldParentID: &root})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/components/events", nil)
+	req.Header.Set("Last-Event-ID", start)
+	req.Header.Set(defaultPrincipalHeader, "bob")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+	var data string
+	for data == "" && lines.Scan() {
+		if value, ok := strings.CutPrefix(lines.Text(), "data: "); ok {
+			data = value
+		}
+	}
+	assert.Contains(t, data, `"type":"component.deleted"`, "Expected the update of 2, no longer cached, to be left out of the replay")
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bufio"
+	"component-service/models"
+	"component-service/store"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// childrenImportColumns are the columns a children CSV may have. Only name is required.
+var childrenImportColumns = []string{"name", "description", "type", "metadata"}
+
+// childImportRow is a row of a children CSV in the report of POST /components/{id}/children/import.
+type childImportRow struct {
+	Line  int    `json:"line"` // the row's line in the CSV, the header being line 1
+	Name  string `json:"name"`
+	ID    int64  `json:"id,omitempty"`    // once created
+	Error string `json:"error,omitempty"` // why the row cannot be created
+}
+
+// childrenImportReport is the body of POST /components/{id}/children/import: 201 with every row
+// created, or 422 with the rows that cannot be, when none is.
+type childrenImportReport struct {
+	ParentID int64            `json:"parent_id"`
+	Created  int              `json:"created"`
+	Error    string           `json:"error,omitempty"`
+	Rows     []childImportRow `json:"rows"`
+}
+
+// importChildren serves POST /components/{id}/children/import, which creates a child of the
+// component for every row of the CSV body, all in one transaction, and reports on each row.
+func importChildren(w http.ResponseWriter, r *http.Request, parentID int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	defer r.Body.Close()
+	children, lines, err := parseChildrenCSV(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report := childrenImportReport{ParentID: parentID, Rows: make([]childImportRow, len(children))}
+	for i, child := range children {
+		report.Rows[i] = childImportRow{Line: lines[i], Name: child.Name}
+	}
+	ids, err := writeStore(r).CreateChildren(parentID, children)
+	var rejected store.ChildErrors
+	switch {
+	case errors.As(err, &rejected):
+		for i, rowErr := range rejected {
+			report.Rows[i].Error = rowErr.Error()
+		}
+		report.Error = fmt.Sprintf("%d of %d rows cannot be created, so none was", len(rejected), len(children))
+		respondWithJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	case errors.Is(err, store.ErrParentNotFound):
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Component with ID %d not found", parentID))
+		return
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Error creating children: "+err.Error())
+		return
+	}
+	for i, id := range ids {
+		report.Rows[i].ID = id
+	}
+	report.Created = len(ids)
+	respondWithJSON(w, http.StatusCreated, report)
+}
+
+// parseChildrenCSV reads the children of a children CSV, and the line each starts on. The header
+// row names the columns, in any order and case; a UTF-8 byte order mark, as spreadsheets write,
+// is skipped. Names and types are trimmed of surrounding spaces, and an empty metadata cell is no
+// metadata. Errors are messages for a 400.
+func parseChildrenCSV(body io.Reader) ([]*models.Component, []int, error) {
+	buffered := bufio.NewReader(body)
+	if bom, err := buffered.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		buffered.Discard(3)
+	}
+	reader := csv.NewReader(buffered)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("No rows given: the CSV needs a header row naming its columns, then a row per child")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		known := false
+		for _, column := range childrenImportColumns {
+			known = known || name == column
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("Unknown column %q: the columns are %s", header[i], strings.Join(childrenImportColumns, ", "))
+		}
+		if _, repeated := columns[name]; repeated {
+			return nil, nil, fmt.Errorf("Column %q is given twice", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, nil, errors.New("The CSV needs a name column")
+	}
+	cell := func(record []string, column string) string {
+		if i, ok := columns[column]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	var children []*models.Component
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		if len(children) == maxImportSize {
+			return nil, nil, fmt.Errorf("Too many rows: at most %d per request; import larger sheets in batches", maxImportSize)
+		}
+		line, _ := reader.FieldPos(0)
+		child := &models.Component{
+			Name:        strings.TrimSpace(cell(record, "name")),
+			Type:        strings.TrimSpace(cell(record, "type")),
+			Description: cell(record, "description"),
+		}
+		if metadata := strings.TrimSpace(cell(record, "metadata")); metadata != "" {
+			child.Metadata = json.RawMessage(metadata)
+		}
+		children = append(children, child)
+		lines = append(lines, line)
+	}
+	if len(children) == 0 {
+		return nil, nil, errors.New("No rows given: the CSV needs a row per child after its header")
+	}
+	return children, lines, nil
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"component-service/db"
+	"component-service/models"
+	"component-service/store"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChildrenCSV(t *testing.T) {
+	children, lines, err := parseChildrenCSV(strings.NewReader("\xef\xbb\xbfMetadata, Name ,type\n" +
+		"\"{\"\"vendor\"\": \"\"acme\"\"}\", Pump 1 ,device\n" +
+		",\"Pump\n2\",\n" +
+		",Pump 3,\n"))
+	require.NoError(t, err)
+	require.Len(t, children, 3)
+	assert.Equal(t, []int{2, 3, 5}, lines, "a cell spanning lines starts its row's line")
+	assert.Equal(t, models.Component{Name: "Pump 1", Type: "device", Metadata: json.RawMessage(`{"vendor": "acme"}`)}, *children[0])
+	assert.Equal(t, "Pump\n2", children[1].Name)
+	assert.Nil(t, children[1].Metadata, "an empty cell is no metadata")
+	assert.Equal(t, "Pump 3", children[2].Name)
+
+	for body, message := range map[string]string{
+		"":                       "No rows given",
+		"name,description\n":     "No rows given",
+		"name,owner\na,b\n":      `Unknown column "owner"`,
+		"name,Name\na,b\n":       `Column "name" is given twice`,
+		"description\na\n":       "needs a name column",
+		"name,type\na\n":         "Invalid CSV",
+		"name\n\"unterminated\n": "Invalid CSV",
+		"name\n" + strings.Repeat("a\n", maxImportSize+1): "Too many rows",
+	} {
+		_, _, err := parseChildrenCSV(strings.NewReader(body))
+		if assert.Error(t, err, body) {
+			assert.Contains(t, err.Error(), message)
+		}
+	}
+}
+
+func TestAPIImportChildren(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	parent := createTestComponentDirectly(t, "line", "", sql.NullInt64{})
+	createTestComponentDirectly(t, "existing", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+	post := func(id int64, body string) (*httptest.ResponseRecorder, childrenImportReport) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/children/import", id), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/csv")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var report childrenImportReport
+		json.Unmarshal(rr.Body.Bytes(), &report)
+		return rr, report
+	}
+
+	rr, report := post(parent.ID, "name,description,metadata\npump,Feed pump,\"{\"\"vendor\"\":\"\"acme\"\"}\"\nvalve,,\n")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, 2, report.Created)
+	require.Len(t, report.Rows, 2)
+	pump, err := componentStore.Strong().GetComponentByID(report.Rows[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pump", pump.Name)
+	assert.Equal(t, "Feed pump", pump.Description)
+	assert.JSONEq(t, `{"vendor":"acme"}`, string(pump.Metadata))
+	assert.Equal(t, parent.ID, pump.ParentID.Int64)
+	valve, err := componentStore.Strong().GetComponentByID(report.Rows[1].ID)
+	require.NoError(t, err)
+	assert.Greater(t, valve.Position, pump.Position, "rows keep their order after the current children")
+
+	// One bad row rejects them all, and every bad row is reported.
+	store.UniqueSiblingNames = true
+	defer func() { store.UniqueSiblingNames = false }()
+	rr, report = post(parent.ID, "name,metadata\nfilter,\n,\nexisting,\nsensor,[1]\nfilter,\n")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assert.Equal(t, 0, report.Created)
+	require.Len(t, report.Rows, 5)
+	assert.Empty(t, report.Rows[0].Error)
+	assert.Contains(t, report.Rows[1].Error, "name is required")
+	assert.Contains(t, report.Rows[2].Error, "duplicate sibling name")
+	assert.Contains(t, report.Rows[3].Error, "invalid metadata")
+	assert.Contains(t, report.Rows[4].Error, "duplicate sibling name")
+	children, err := componentStore.Strong().ListChildComponents(parent.ID)
+	require.NoError(t, err)
+	assert.Len(t, children, 3, "nothing is created")
+
+	rr, _ = post(999999, "name\npump\n")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"component-service/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxCommentsLimit caps ?limit on GET /components/{id}/comments.
+const maxCommentsLimit = 1000
+
+// commentRequest is the body of POST /components/{id}/comments.
+type commentRequest struct {
+	Author string `json:"author"` // ignored when ACLs are enforced: the principal is the author
+	Body   string `json:"body"`
+}
+
+// commentsHandler serves /components/{id}/comments (POST adds one, GET lists them, oldest first)
+// and DELETE /components/{id}/comments/{commentID}. Under ACL enforcement, commenting needs only
+// read permission on the component, and deleting another principal's comment needs admin.
+func commentsHandler(w http.ResponseWriter, r *http.Request, id int64, commentID string) {
+	switch {
+	case commentID == "" && r.Method == http.MethodPost:
+		createComment(w, r, id)
+	case commentID == "" && r.Method == http.MethodGet:
+		q := newQueryParams(r)
+		p := parsePage(q, maxCommentsLimit)
+		if !q.valid(w) {
+			return
+		}
+		list, total, err := componentStore.ListComments(id, p.limit, p.offset)
+		if err != nil {
+			respondWithCommentError(w, err, "Error listing comments")
+			return
+		}
+		setPaginationHeaders(w, r, p, total)
+		respondWithJSON(w, http.StatusOK, list)
+	case commentID != "" && r.Method == http.MethodDelete:
+		if !newQueryParams(r).valid(w) {
+			return
+		}
+		parsed, err := strconv.ParseInt(commentID, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid comment ID in path")
+			return
+		}
+		canDelete := func(comment *models.Comment) bool {
+			principal, enforced := principalFrom(r)
+			return !enforced || comment.Author == principal || canAccess(r, id, cache.PermissionAdmin)
+		}
+		if err := componentStore.DeleteComment(id, parsed, canDelete); err != nil {
+			respondWithCommentError(w, err, "Error deleting comment")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Comment deleted successfully"})
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for comments endpoint")
+	}
+}
+
+func createComment(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body commentRequest
+	if err := decodeBody(r, &body, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if principal, enforced := principalFrom(r); enforced {
+		body.Author = principal
+	}
+
+	comment, err := componentStore.CreateComment(id, body.Author, body.Body)
+	if err != nil {
+		respondWithCommentError(w, err, "Error creating comment")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/components/%d/comments/%d", id, comment.ID))
+	respondWithJSON(w, http.StatusCreated, comment)
+}
+
+// respondWithCommentError maps a comment error to its status, prefixing unexpected ones with
+// context.
+func respondWithCommentError(w http.ResponseWriter, err error, context string) {
+	switch {
+	case errors.Is(err, store.ErrInvalidComment):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, store.ErrCommentNotPermitted):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, context+": "+err.Error())
+	}
+}
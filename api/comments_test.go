@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentsRequests(t *testing.T) {
+	for _, tc := range []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodPut, "/components/1/comments", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/components/1/comments/2", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/components/x/comments", "", http.StatusBadRequest},
+		{http.MethodDelete, "/components/1/comments/x", "", http.StatusBadRequest},
+		{http.MethodGet, "/components/1/comments?limit=0", "", http.StatusBadRequest},
+		{http.MethodGet, "/components/1/comments?sort=name", "", http.StatusBadRequest},
+		{http.MethodPost, "/components/1/comments", `{"text":"hello"}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/1/comments", `not json`, http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, tc.code, rr.Code, "%s %s: %s", tc.method, tc.target, rr.Body.String())
+	}
+}
+
+func TestAPIComments(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	comp := createTestComponentDirectly(t, "Pump", "", sql.NullInt64{})
+	base := fmt.Sprintf("/components/%d/comments", comp.ID)
+
+	var created []models.Comment
+	for _, body := range []string{`{"author":"alice","body":"Seal leaks."}`, `{"body":"Replaced it."}`} {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, base, bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var comment models.Comment
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &comment))
+		assert.Equal(t, fmt.Sprintf("%s/%d", base, comment.ID), rr.Header().Get("Location"))
+		created = append(created, comment)
+	}
+	assert.Equal(t, "alice", created[0].Author)
+
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, base, bytes.NewBufferString(`{"body":"  "}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, base+"?limit=1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"))
+	assert.Contains(t, rr.Header().Get("Link"), "offset=1")
+	var list []models.Comment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "Seal leaks.", list[0].Body)
+
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%d", base, created[0].ID), nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%d", base, created[0].ID), nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/components/88888/comments", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
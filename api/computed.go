@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"encoding/json"
+	"errors"
+)
+
+// parseIncludeComputed reads ?include=computed, which adds each component's computed fields to
+// the response under "computed".
+func parseIncludeComputed(q *queryParams) bool {
+	if q.oneOf("include", "", "computed") != "computed" {
+		return false
+	}
+	if cache.GlobalConfig.Computed == nil {
+		q.reject("include", "nothing: no computed fields are defined (see COMPUTED_FIELDS_FILE)")
+		return false
+	}
+	return true
+}
+
+// withComputed adds a "computed" member to an encoded component, or to each component in an
+// encoded array, holding its computed field values.
+func withComputed(encoded json.RawMessage) (json.RawMessage, error) {
+	if cache.GlobalComponentCache == nil {
+		return nil, errors.New("computed fields require the component cache, which is not initialized")
+	}
+	encoded = bytes.TrimSpace(encoded)
+	isArray := len(encoded) > 0 && encoded[0] == '['
+	objects := []json.RawMessage{encoded}
+	if isArray {
+		objects = nil
+		if err := json.Unmarshal(encoded, &objects); err != nil {
+			return nil, err
+		}
+	}
+	ids := make([]int64, len(objects))
+	for i, object := range objects {
+		var ref struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(object, &ref); err != nil {
+			return nil, err
+		}
+		ids[i] = ref.ID
+	}
+	values, err := cache.GlobalComponentCache.ComputedFields(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if isArray {
+		buf.WriteByte('[')
+	}
+	for i, object := range objects {
+		computed, err := json.Marshal(values[ids[i]])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(withMember(object, "computed", computed))
+	}
+	if isArray {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/computed"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeComputed(t *testing.T) {
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	fields, err := computed.Parse(map[string]string{"size": "1 + sum(children.size)"})
+	assert.NoError(t, err)
+	cache.GlobalConfig.Computed = fields
+	err = cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "root"},
+		{ID: 2, Name: "child", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+	}})
+	assert.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	rr := get("/components/1?include=computed")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"id":1,"name":"root","description":"","parent_id":{"Int64":0,"Valid":false},"position":0,"computed":{"size":2}}`, rr.Body.String())
+
+	rr = get("/components/1/children?include=computed&fields=name")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"name":"child","computed":{"size":1}}]`, rr.Body.String())
+
+	assert.NotContains(t, get("/components/1").Body.String(), "computed")
+
+	cache.GlobalConfig.Computed = nil
+	assert.Equal(t, http.StatusBadRequest, get("/components/1?include=computed").Code)
+}
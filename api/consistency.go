@@ -0,0 +1,50 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/store"
+	"component-service/tracing"
+	"net/http"
+)
+
+// consistencyHeader lets a caller choose where its reads are served from: "cached" (the default)
+// reads the component cache, and "strong" reads the database, for read-after-write flows that
+// cannot tolerate a cache lagging behind writes made around the service. Responses echo the
+// consistency they were served with.
+const consistencyHeader = "X-Consistency"
+
+const (
+	consistencyCached = "cached"
+	consistencyStrong = "strong"
+)
+
+// readStore returns the store serving the request's reads.
+func readStore(r *http.Request) *store.ComponentStore {
+	if r.Header.Get(consistencyHeader) == consistencyStrong {
+		return componentStore.Strong()
+	}
+	return componentStore
+}
+
+// writeStore returns the store making the request's writes, whose events are traced to it.
+func writeStore(r *http.Request) *store.ComponentStore {
+	return componentStore.WithTrace(tracing.FromContext(r.Context()))
+}
+
+// checkConsistency validates the request's X-Consistency header and echoes the consistency its
+// reads get: strong when requested, and whenever there is no cache to read. It responds 400 and
+// returns false for an unknown value.
+func checkConsistency(w http.ResponseWriter, r *http.Request) bool {
+	switch requested := r.Header.Get(consistencyHeader); requested {
+	case "", consistencyCached, consistencyStrong:
+		if requested == consistencyStrong || cache.GlobalComponentCache == nil {
+			w.Header().Set(consistencyHeader, consistencyStrong)
+		} else {
+			w.Header().Set(consistencyHeader, consistencyCached)
+		}
+		return true
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid "+consistencyHeader+" header: use "+consistencyCached+" or "+consistencyStrong)
+		return false
+	}
+}
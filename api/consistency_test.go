@@ -0,0 +1,57 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistencyHeader(t *testing.T) {
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{{ID: 1, Name: "root"}}})
+	assert.NoError(t, err)
+
+	get := func(consistency string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/components/1", nil)
+		if consistency != "" {
+			req.Header.Set("X-Consistency", consistency)
+		}
+		ComponentsHandler(rr, req)
+		return rr
+	}
+	rr := get("")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "cached", rr.Header().Get("X-Consistency"))
+	assert.Equal(t, "cached", get("cached").Header().Get("X-Consistency"))
+	assert.Equal(t, "strong", get("strong").Header().Get("X-Consistency"))
+	assert.Equal(t, http.StatusBadRequest, get("eventual").Code)
+
+	cache.GlobalComponentCache = nil
+	assert.Equal(t, "strong", get("").Header().Get("X-Consistency"), "Expected reads without a cache to be strong")
+}
+
+func TestReadyz(t *testing.T) {
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	cache.GlobalComponentCache = nil
+	rr := httptest.NewRecorder()
+	ReadyzHandler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":"unavailable"`)
+
+	assert.NoError(t, cache.InitGlobalCache(&publicTestStore{components: []*models.Component{{ID: 1, Name: "root"}}}))
+	rr = httptest.NewRecorder()
+	ReadyzHandler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"components":1`)
+
+	rr = httptest.NewRecorder()
+	ReadyzHandler(rr, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"component-service/cache"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// parseDepth reads ?depth=N, the number of levels below the root to return, 1 being the children.
+// It returns 0, meaning every level, when the parameter is absent.
+func parseDepth(q *queryParams) int {
+	return q.minInt("depth", 0, 1)
+}
+
+// listDescendants returns the subtree below rootID as a flat list, level by level and by ID within
+// a level, paged like the children listing. ?depth=N stops after N levels, and ?type= and ?tag=
+// keep only the descendants of a type or having a tag.
+func listDescendants(w http.ResponseWriter, r *http.Request, rootID int64) {
+	maxDescendants := MaxUnpaginatedChildren
+	q := newQueryParams(r)
+	p := parsePage(q, maxDescendants)
+	depth := parseDepth(q)
+	filter := cache.Filter{Type: parseType(q), Tag: parseTag(q), Metadata: parseMetadataFilter(q), Reader: readerOf(r)}
+	fields := parseFields(q)
+	includeComputed := parseIncludeComputed(q)
+	render := parseRender(q)
+	if !q.valid(w) {
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	if _, err := readStore(r).GetComponentByID(rootID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error getting component: "+err.Error())
+		}
+		return
+	}
+
+	limit := p.limit
+	if limit == 0 {
+		limit = maxDescendants // bounds the work; a larger remainder is refused below
+	}
+	body, total, err := readStore(r).ListDescendantsJSON(rootID, depth, filter, p.offset, limit) // Always an array, never null
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing descendants: "+err.Error())
+		return
+	}
+	if p.limit == 0 && total-p.offset > maxDescendants {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf(
+			"Component %d has %d descendants, more than the %d that can be listed at once; page through them with ?limit=%d&offset=0 and follow the Link header",
+			rootID, total, maxDescendants, maxDescendants))
+		return
+	}
+	if includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectArray(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing descendants: "+err.Error())
+		return
+	}
+	setPaginationHeaders(w, r, p, total)
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
+}
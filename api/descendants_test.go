@@ -0,0 +1,52 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDescendants(t *testing.T) {
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "root"},
+		{ID: 2, Name: "child", ParentID: under(1)},
+		{ID: 3, Name: "grandchild", ParentID: under(2)},
+	}})
+	assert.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	rr := get("/components/1/descendants?fields=id,name")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"id":2,"name":"child"},{"id":3,"name":"grandchild"}]`, rr.Body.String())
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"))
+
+	rr = get("/components/1/descendants?limit=1&fields=id")
+	assert.JSONEq(t, `[{"id":2}]`, rr.Body.String())
+	assert.Contains(t, rr.Header().Get("Link"), `rel="next"`)
+
+	rr = get("/components/1/descendants?depth=1&fields=id")
+	assert.JSONEq(t, `[{"id":2}]`, rr.Body.String())
+	assert.Equal(t, "1", rr.Header().Get("X-Total-Count"))
+	assert.Equal(t, http.StatusBadRequest, get("/components/1/descendants?depth=0").Code)
+
+	assert.Equal(t, http.StatusNotFound, get("/components/99/descendants").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/components/1/descendants?sort=name").Code)
+
+	defer func(max int) { MaxUnpaginatedChildren = max }(MaxUnpaginatedChildren)
+	MaxUnpaginatedChildren = 1
+	assert.Equal(t, http.StatusBadRequest, get("/components/1/descendants").Code)
+	assert.Equal(t, http.StatusOK, get("/components/1/descendants?offset=1").Code)
+}
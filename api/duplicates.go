@@ -0,0 +1,197 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/jobs"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults and caps of /admin/duplicates and POST /admin/duplicates/scan.
+const (
+	defaultDuplicateSimilarity = 80 // percent
+	minDuplicateSimilarity     = 50
+	defaultDuplicatesLimit     = 100
+	maxDuplicatesLimit         = 1000
+	maxDuplicateKeyFields      = 10
+)
+
+// duplicateScanJob names the duplicate scan job; only one runs at a time.
+const duplicateScanJob = "duplicate-scan"
+
+// duplicateScan is the result of the last duplicate scan to finish on this instance.
+type duplicateScan struct {
+	ScannedAt     time.Time
+	MinSimilarity int
+	KeyFields     []string
+	Components    int
+	Pairs         []cache.DuplicatePair
+}
+
+var lastDuplicateScan struct {
+	mu   sync.Mutex
+	scan *duplicateScan
+}
+
+// mergeLink is a request merging a pair's duplicate into the component kept, or previewing it.
+type mergeLink struct {
+	Method string         `json:"method"`
+	Href   string         `json:"href"`
+	Body   map[string]any `json:"body"`
+}
+
+// duplicateEntry is a pair listed by GET /admin/duplicates, with the components' current names.
+type duplicateEntry struct {
+	cache.DuplicatePair
+	Name          string    `json:"name"`
+	DuplicateName string    `json:"duplicate_name"`
+	Merge         mergeLink `json:"merge"`
+	MergePreview  mergeLink `json:"merge_preview"`
+}
+
+// duplicatesReport is the response of GET /admin/duplicates.
+type duplicatesReport struct {
+	ScannedAt     time.Time        `json:"scanned_at"`
+	MinSimilarity int              `json:"min_similarity"`
+	KeyFields     []string         `json:"key_fields"`
+	Components    int              `json:"components"` // how many the scan compared
+	Total         int              `json:"total"`      // pairs whose components both still exist
+	Pairs         []duplicateEntry `json:"pairs"`
+}
+
+// startDuplicateScan compares every cached component with the others in the background, looking
+// for likely duplicates, and answers like startSearchReindex. ?min_similarity is the percentage
+// two names' trigrams must share, and ?keys the comma-separated metadata keys holding identifiers
+// from other systems, two components with the same value at one of them being flagged. The result
+// replaces the previous scan's once the job succeeds, and is read with GET /admin/duplicates.
+func startDuplicateScan(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	minSimilarity := q.intRange("min_similarity", defaultDuplicateSimilarity, minDuplicateSimilarity, 100)
+	keyFields := parseKeyFields(q)
+	if !q.valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Component cache is not initialized")
+		return
+	}
+	c := cache.GlobalComponentCache
+	job, err := jobs.Default.Start(duplicateScanJob, func(ctx context.Context, report func(done, total int)) error {
+		scanned := 0
+		opts := cache.DuplicateOptions{MinNameSimilarity: float64(minSimilarity) / 100, KeyFields: keyFields}
+		pairs, err := c.FindDuplicates(ctx, opts, func(done, total int) {
+			scanned = total
+			report(done, total)
+		})
+		if err != nil {
+			return err
+		}
+		lastDuplicateScan.mu.Lock()
+		defer lastDuplicateScan.mu.Unlock()
+		lastDuplicateScan.scan = &duplicateScan{
+			ScannedAt:     time.Now().UTC().Truncate(time.Second),
+			MinSimilarity: minSimilarity,
+			KeyFields:     keyFields,
+			Components:    scanned,
+			Pairs:         pairs,
+		}
+		return nil
+	})
+	w.Header().Set("Location", "/admin/jobs/"+job.ID)
+	if errors.Is(err, jobs.ErrAlreadyRunning) {
+		respondWithJSON(w, http.StatusConflict, job)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// parseKeyFields reads ?keys, a comma-separated list of metadata keys.
+func parseKeyFields(q *queryParams) []string {
+	fields := []string{}
+	if q.str("keys") == "" {
+		return fields
+	}
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(q.str("keys"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || len(fields) == maxDuplicateKeyFields {
+			q.reject("keys", fmt.Sprintf("up to %d comma-separated metadata keys", maxDuplicateKeyFields))
+			return nil
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// listDuplicates serves GET /admin/duplicates, a page of the likely duplicates the last scan found,
+// strongest first, 100 unless ?limit says otherwise. Pairs with a component deleted since the scan
+// are left out. Each pair links the request previewing the merge of the newer component into the
+// older; the preview tells what the merge would reparent and break before anyone applies it.
+func listDuplicates(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	p := parsePage(q, maxDuplicatesLimit)
+	if !q.valid(w) {
+		return
+	}
+	if p.limit == 0 {
+		p.limit = defaultDuplicatesLimit
+	}
+	lastDuplicateScan.mu.Lock()
+	scan := lastDuplicateScan.scan
+	lastDuplicateScan.mu.Unlock()
+	if scan == nil {
+		respondWithError(w, http.StatusNotFound, "No duplicate scan has finished; start one with POST /admin/duplicates/scan")
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Component cache is not initialized")
+		return
+	}
+
+	report := duplicatesReport{
+		ScannedAt:     scan.ScannedAt,
+		MinSimilarity: scan.MinSimilarity,
+		KeyFields:     scan.KeyFields,
+		Components:    scan.Components,
+		Pairs:         []duplicateEntry{},
+	}
+	for _, pair := range scan.Pairs {
+		kept, found := cache.GlobalComponentCache.GetByID(pair.ID)
+		if !found {
+			continue
+		}
+		duplicate, found := cache.GlobalComponentCache.GetByID(pair.DuplicateID)
+		if !found {
+			continue
+		}
+		report.Total++
+		if report.Total <= p.offset || len(report.Pairs) == p.limit {
+			continue
+		}
+		report.Pairs = append(report.Pairs, duplicateEntry{
+			DuplicatePair: pair,
+			Name:          kept.Name,
+			DuplicateName: duplicate.Name,
+			Merge: mergeLink{
+				Method: http.MethodPost,
+				Href:   fmt.Sprintf("/components/%d/merge", pair.DuplicateID),
+				Body:   map[string]any{"into_id": pair.ID},
+			},
+			MergePreview: mergeLink{
+				Method: http.MethodPost,
+				Href:   fmt.Sprintf("/components/%d/simulate", pair.DuplicateID),
+				Body:   map[string]any{"operation": cache.OperationMerge, "into_id": pair.ID},
+			},
+		})
+	}
+	setPaginationHeaders(w, r, p, report.Total)
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/jobs"
+	"component-service/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicatesRequests(t *testing.T) {
+	for _, tc := range []struct {
+		method, target string
+		code           int
+	}{
+		{http.MethodPost, "/admin/duplicates", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/duplicates/scan", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/duplicates/scan?min_similarity=20", http.StatusBadRequest},
+		{http.MethodPost, "/admin/duplicates/scan?keys=serial,,asset_tag", http.StatusBadRequest},
+		{http.MethodPost, "/admin/duplicates/scan?keys=a,b,c,d,e,f,g,h,i,j,k", http.StatusBadRequest},
+		{http.MethodGet, "/admin/duplicates?limit=0", http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		AdminHandler(rr, httptest.NewRequest(tc.method, tc.target, nil))
+		assert.Equal(t, tc.code, rr.Code, "%s %s: %s", tc.method, tc.target, rr.Body.String())
+	}
+}
+
+func TestDuplicateScan(t *testing.T) {
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	defer func(scan *duplicateScan) { lastDuplicateScan.scan = scan }(lastDuplicateScan.scan)
+	lastDuplicateScan.scan = nil
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "Boiler feed pump"},
+		{ID: 2, Name: "Boiler Feed Pump"},
+		{ID: 3, Name: "Valve", Metadata: json.RawMessage(`{"serial": "SN-7"}`)},
+		{ID: 4, Name: "Gate", Metadata: json.RawMessage(`{"serial": "SN-7"}`)},
+		{ID: 5, Name: "Relay"},
+	}})
+	require.NoError(t, err)
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		AdminHandler(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/admin/duplicates")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected 404 before any scan")
+
+	rr = serve(http.MethodPost, "/admin/duplicates/scan?keys=serial")
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	var job jobs.Job
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &job))
+	assert.Equal(t, "/admin/jobs/"+job.ID, rr.Header().Get("Location"))
+	require.Eventually(t, func() bool {
+		current, _ := jobs.Default.Get(job.ID)
+		return current.Status != jobs.StatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rr = serve(http.MethodGet, "/admin/duplicates")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var report duplicatesReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, 5, report.Components)
+	assert.Equal(t, []string{"serial"}, report.KeyFields)
+	assert.Equal(t, 2, report.Total)
+	require.Len(t, report.Pairs, 2)
+	assert.Equal(t, int64(1), report.Pairs[0].ID)
+	assert.Equal(t, "Boiler Feed Pump", report.Pairs[0].DuplicateName)
+	assert.Equal(t, []string{"name"}, report.Pairs[0].Reasons)
+	assert.Equal(t, "/components/2/merge", report.Pairs[0].Merge.Href)
+	assert.Equal(t, map[string]any{"into_id": float64(1)}, report.Pairs[0].Merge.Body)
+	assert.Equal(t, "/components/2/simulate", report.Pairs[0].MergePreview.Href)
+	assert.Equal(t, map[string]any{"operation": "merge", "into_id": float64(1)}, report.Pairs[0].MergePreview.Body)
+	assert.Equal(t, []string{"key:serial"}, report.Pairs[1].Reasons)
+
+	rr = serve(http.MethodGet, "/admin/duplicates?limit=1&offset=1")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	require.Len(t, report.Pairs, 1)
+	assert.Equal(t, int64(3), report.Pairs[0].ID)
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"))
+
+	// A pair is dropped once one of its components is deleted
+	cache.GlobalComponentCache.Delete(4)
+	rr = serve(http.MethodGet, "/admin/duplicates")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Total)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"component-service/cache"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// cachedETag returns the entity tag of a response computed from the component cache for r: a weak
+// tag derived from the cache's generation and the request's principal, whose ACLs filter what it
+// is shown. It must be taken before the response's data is read, so the tag is never newer than
+// the data. It is "" when the request's reads bypass the cache, and the response is then not
+// tagged.
+func cachedETag(r *http.Request) string {
+	if r.Header.Get(consistencyHeader) == consistencyStrong || cache.GlobalComponentCache == nil {
+		return ""
+	}
+	principal, _ := principalFrom(r)
+	sum := sha256.Sum256([]byte(cache.GlobalComponentCache.Generation() + "\x00" + principal))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// notModified answers 304 Not Modified, with etag, and returns true when the request's
+// If-None-Match already holds etag. An empty etag matches nothing.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" || !matchesETag(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// setETag tags a response with etag, unless it is empty.
+func setETag(w http.ResponseWriter, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// matchesETag reports whether an If-None-Match header value lists etag, or is "*". Tags are
+// compared weakly, as If-None-Match requires: W/"x" matches "x".
+func matchesETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/fixtures"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesETag(t *testing.T) {
+	for _, tc := range []struct {
+		header, etag string
+		want         bool
+	}{
+		{`W/"abc"`, `W/"abc"`, true},
+		{`"abc"`, `W/"abc"`, true},
+		{`W/"abc"`, `"abc"`, true},
+		{`"x", W/"abc"`, `W/"abc"`, true},
+		{`*`, `"3"`, true},
+		{`"abcd"`, `W/"abc"`, false},
+		{``, `"3"`, false},
+	} {
+		assert.Equal(t, tc.want, matchesETag(tc.header, tc.etag), "%s against %s", tc.header, tc.etag)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "plant")
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, path := range []string{
+		"/components",
+		"/components?limit=2",
+		"/components?after=",
+		fmt.Sprintf("/components/%d/children", w.ID("line-a")),
+		fmt.Sprintf("/components/%d/descendants", w.ID("plant")),
+		fmt.Sprintf("/components/%d/tree", w.ID("plant")),
+		"/components/flat",
+		"/components/by-path?path=/plant",
+		"/components/slug/" + w.Component("plant").Slug,
+	} {
+		rr := get(path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, path)
+		etag := rr.Header().Get("ETag")
+		require.NotEmpty(t, etag, path)
+		assert.Regexp(t, `^W/"[0-9a-f]+"$`, etag, path)
+
+		rr = get(path, http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusNotModified, rr.Code, path)
+		assert.Equal(t, etag, rr.Header().Get("ETag"), path)
+		assert.Empty(t, rr.Body.String(), path)
+
+		assert.Equal(t, http.StatusOK, get(path, http.Header{"If-None-Match": {`W/"stale"`}}).Code, path)
+		assert.NotEqual(t, etag, get(path, http.Header{defaultPrincipalHeader: {"alice"}}).Header().Get("ETag"), "%s: the tag depends on the principal", path)
+		assert.Empty(t, get(path, http.Header{consistencyHeader: {consistencyStrong}}).Header().Get("ETag"), "%s: strong reads are not tagged", path)
+	}
+
+	// Any change to the cache retires the tags taken before it.
+	path := fmt.Sprintf("/components/%d/children", w.ID("line-a"))
+	etag := get(path, nil).Header().Get("ETag")
+	renamed := *w.Component("valve")
+	renamed.Name = "valve-renamed"
+	cache.GlobalComponentCache.Set(&renamed)
+	rr := get(path, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+}
+
+func TestConditionalGetByVersion(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "plant")
+	comp := *w.Component("valve")
+	comp.Version = 4
+	cache.GlobalComponentCache.Set(&comp)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d", comp.ID), nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, req)
+		return rr
+	}
+
+	rr := get("")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, get(`"4"`).Code)
+	assert.Equal(t, http.StatusNotModified, get(`W/"4"`).Code, "If-None-Match compares weakly")
+	assert.Equal(t, http.StatusOK, get(`"3"`).Code)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"component-service/events"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// eventSchemaIndex is the body of GET /schemas.
+type eventSchemaIndex struct {
+	SchemaVersion  int           `json:"schema_version"`  // the current version
+	SchemaVersions []int         `json:"schema_versions"` // the versions sent, oldest first
+	Schemas        []eventSchema `json:"schemas"`
+}
+
+// eventSchema locates the JSON Schema of an event type in a schema version.
+type eventSchema struct {
+	Type          events.Type `json:"type"`
+	SchemaVersion int         `json:"schema_version"`
+	URL           string      `json:"url"`
+}
+
+// EventSchemasHandler serves the JSON Schemas of the events webhooks and the change streams send:
+// GET /schemas lists them, for every event type and schema version sent, and
+// GET /schemas/v{version}/{type}.json returns one.
+func EventSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) == 1 && pathParts[0] == "schemas" { // /schemas
+		index := eventSchemaIndex{SchemaVersion: events.SchemaVersion, SchemaVersions: events.SchemaVersions}
+		for _, version := range events.SchemaVersions {
+			for _, eventType := range events.Types {
+				index.Schemas = append(index.Schemas, eventSchema{Type: eventType, SchemaVersion: version, URL: events.SchemaPath(eventType, version)})
+			}
+		}
+		respondWithJSON(w, http.StatusOK, index)
+		return
+	}
+	if len(pathParts) != 3 || pathParts[0] != "schemas" { // /schemas/v{version}/{type}.json
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(pathParts[1], "v"))
+	name, isJSON := strings.CutSuffix(pathParts[2], ".json")
+	var schema map[string]interface{}
+	if err == nil && isJSON && strings.HasPrefix(pathParts[1], "v") {
+		schema = events.Schema(events.Type(name), version)
+	}
+	if schema == nil {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("No event schema at %s: see /schemas", r.URL.Path))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, schema)
+}
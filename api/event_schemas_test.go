@@ -0,0 +1,44 @@
+package api
+
+import (
+	"component-service/events"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventSchemasHandler(t *testing.T) {
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		EventSchemasHandler(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/schemas")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var index eventSchemaIndex
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &index))
+	assert.Equal(t, events.SchemaVersion, index.SchemaVersion)
+	assert.Len(t, index.Schemas, len(events.Types)*len(events.SchemaVersions))
+	for _, entry := range index.Schemas {
+		rr := serve(http.MethodGet, entry.URL)
+		require.Equal(t, http.StatusOK, rr.Code, entry.URL)
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &schema))
+		assert.Equal(t, entry.URL, schema["$id"])
+		assert.Equal(t, string(entry.Type), schema["title"])
+	}
+
+	rr = serve(http.MethodGet, "/schemas/v1/component.deleted.json")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"component":false`, "Expected deletions to carry no component")
+
+	for _, target := range []string{"/schemas/v99/component.created.json", "/schemas/v1/component.renamed.json", "/schemas/1/component.created.json", "/schemas/v1/component.created", "/schemas/v1"} {
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, target).Code, target)
+	}
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/schemas").Code)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"component-service/models"
+	"component-service/store"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const maxExportBatchSize = 10000
+
+// ExportBatchSize is the number of rows an export fetches per round trip when the request sets no
+// ?batch_size.
+var ExportBatchSize = store.DefaultExportBatchSize
+
+// ExportBatchSizeFromEnv reads EXPORT_BATCH_SIZE, an integer between 1 and 10000 that defaults to
+// store.DefaultExportBatchSize.
+func ExportBatchSizeFromEnv() (int, error) {
+	value := os.Getenv("EXPORT_BATCH_SIZE")
+	if value == "" {
+		return store.DefaultExportBatchSize, nil
+	}
+	batchSize, err := strconv.Atoi(value)
+	if err != nil || batchSize < 1 || batchSize > maxExportBatchSize {
+		return 0, fmt.Errorf("invalid EXPORT_BATCH_SIZE %q: expected an integer between 1 and %d", value, maxExportBatchSize)
+	}
+	return batchSize, nil
+}
+
+// exportComponents streams all components as newline-delimited JSON straight from the database,
+// from a single snapshot identified by the X-Snapshot-* response headers.
+// Once streaming has started the status code is committed, so later errors end the stream early
+// and are logged.
+func exportComponents(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	batchSize := q.intRange("batch_size", ExportBatchSize, 1, maxExportBatchSize)
+	fields := parseFields(q)
+	if !q.valid(w) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="components.ndjson"`)
+	readable := readableFilter(r)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	written := 0
+
+	stampSnapshot := func(snapshot store.ExportSnapshot) {
+		if snapshot.Position != "" {
+			w.Header().Set("X-Snapshot-Position", snapshot.Position)
+		}
+		w.Header().Set("X-Snapshot-Timestamp", snapshot.Timestamp.UTC().Format(time.RFC3339Nano))
+	}
+	err := componentStore.ExportComponents(r.Context(), batchSize, stampSnapshot, func(comp *models.Component) error {
+		if readable != nil && !readable(comp.ID) {
+			return nil
+		}
+		var line interface{} = comp
+		if fields != nil {
+			projected, err := fields.project(comp)
+			if err != nil {
+				return err
+			}
+			line = projected
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%batchSize == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if written == 0 && r.Context().Err() == nil {
+			respondWithError(w, http.StatusInternalServerError, "Error exporting components: "+err.Error())
+			return
+		}
+		log.Printf("Component export aborted after %d rows: %v", written, err)
+	}
+}
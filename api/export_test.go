@@ -0,0 +1,25 @@
+package api
+
+import (
+	"component-service/store"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportBatchSizeFromEnv(t *testing.T) {
+	batchSize, err := ExportBatchSizeFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, store.DefaultExportBatchSize, batchSize)
+
+	t.Setenv("EXPORT_BATCH_SIZE", "250")
+	batchSize, err = ExportBatchSizeFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 250, batchSize)
+
+	for _, value := range []string{"0", "10001", "many"} {
+		t.Setenv("EXPORT_BATCH_SIZE", value)
+		_, err = ExportBatchSizeFromEnv()
+		assert.ErrorContains(t, err, "invalid EXPORT_BATCH_SIZE", value)
+	}
+}
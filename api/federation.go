@@ -0,0 +1,93 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/federation"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// mounts is the federation configured with EnableFederation, or nil.
+var mounts *federation.Federation
+
+// EnableFederation makes the subtrees f mounts from other instances available: in trees with
+// ?include=mounts, and under /federation/.
+func EnableFederation(f *federation.Federation) {
+	mounts = f
+}
+
+// parseIncludeMounts reads ?include=mounts, which grafts the subtrees mounted from other instances
+// under their local parents.
+func parseIncludeMounts(q *queryParams) bool {
+	if q.oneOf("include", "", "mounts") != "mounts" {
+		return false
+	}
+	if mounts == nil {
+		q.reject("include", "nothing: no instances are mounted (see FEDERATION_MOUNTS)")
+		return false
+	}
+	return true
+}
+
+// FederationHandler serves GET /federation/mounts, listing the mounts and how current each is, and
+// GET /federation/mounts/{name}/tree, a mounted subtree on its own. Mounted components are
+// read-only: they are changed on the instance they come from.
+func FederationHandler(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // e.g., ["federation", "mounts", "team-a", "tree"]
+	if len(pathParts) < 2 || pathParts[0] != "federation" || pathParts[1] != "mounts" ||
+		(len(pathParts) != 2 && (len(pathParts) != 4 || pathParts[3] != "tree")) {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Mounted components are read-only; change them on the instance they come from")
+		return
+	}
+	if mounts == nil {
+		respondWithError(w, http.StatusNotFound, "No instances are mounted (see FEDERATION_MOUNTS)")
+		return
+	}
+	if len(pathParts) == 2 {
+		if !newQueryParams(r).valid(w) {
+			return
+		}
+		respondWithJSON(w, http.StatusOK, mounts.Status())
+		return
+	}
+	getMountTree(w, r, pathParts[2])
+}
+
+// getMountTree serves GET /federation/mounts/{name}/tree like GET /components/{id}/tree, from the
+// mount's replica. Its IDs are those of the instance the mount comes from.
+func getMountTree(w http.ResponseWriter, r *http.Request, name string) {
+	q := newQueryParams(r)
+	depth := parseDepth(q)
+	if !q.valid(w) {
+		return
+	}
+	mount := mounts.Mount(name)
+	if mount == nil {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Mount %q not found", name))
+		return
+	}
+	replica := mount.Cache()
+	if replica == nil {
+		respondWithError(w, http.StatusServiceUnavailable, fmt.Sprintf("Mount %q has not synced from %s yet", name, mount.RemoteURL.Redacted()))
+		return
+	}
+	maxNodes := MaxUnpaginatedChildren
+	body, err := replica.TreeJSON(mount.RemoteRootID, cache.TreeOptions{MaxDepth: depth, MaxNodes: maxNodes})
+	switch {
+	case err == nil:
+		respondWithRawJSON(w, http.StatusOK, body)
+	case errors.Is(err, cache.ErrTreeTooLarge):
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf(
+			"The mounted tree has more than the %d components that can be returned at once; use ?depth to expand it lazily", maxNodes))
+	case strings.Contains(err.Error(), "not found"):
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Component %d no longer exists on the instance mounted as %q", mount.RemoteRootID, name))
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error building tree: "+err.Error())
+	}
+}
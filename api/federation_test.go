@@ -0,0 +1,46 @@
+package api
+
+import (
+	"component-service/federation"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederationHandler(t *testing.T) {
+	defer EnableFederation(mounts)
+	serve := func(handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	EnableFederation(nil)
+	assert.Equal(t, http.StatusNotFound, serve(FederationHandler, http.MethodGet, "/federation/mounts").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(ComponentsHandler, http.MethodGet, "/components/tree?include=mounts").Code,
+		"Expected include=mounts to be rejected without mounts")
+
+	remote, _ := url.Parse("https://team-a.example")
+	f, err := federation.New([]*federation.Mount{{Name: "team-a", ParentID: 12, RemoteURL: remote, RemoteRootID: 1}})
+	require.NoError(t, err)
+	EnableFederation(f)
+
+	rr := serve(FederationHandler, http.MethodGet, "/federation/mounts")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var statuses []federation.MountStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "team-a", statuses[0].Name)
+	assert.False(t, statuses[0].Synced)
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(FederationHandler, http.MethodGet, "/federation/mounts/team-a/tree").Code,
+		"Expected a mount that has not synced to be unavailable")
+	assert.Equal(t, http.StatusNotFound, serve(FederationHandler, http.MethodGet, "/federation/mounts/team-b/tree").Code)
+	assert.Equal(t, http.StatusNotFound, serve(FederationHandler, http.MethodGet, "/federation/mounts/team-a").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(FederationHandler, http.MethodPost, "/federation/mounts").Code)
+}
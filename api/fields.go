@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"component-service/models"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// componentFields are the JSON field names of models.Component in declaration order, which is
+// the order projected objects keep.
+var componentFields = jsonFieldNames(reflect.TypeOf(models.Component{}))
+
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// fieldSet is the component fields selected by ?fields, in componentFields order. nil selects
+// every field.
+type fieldSet []string
+
+// parseFields reads ?fields, a comma-separated list of component field names.
+func parseFields(q *queryParams) fieldSet {
+	value := q.str("fields")
+	if value == "" {
+		return nil
+	}
+	requested := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		requested[strings.TrimSpace(name)] = true
+	}
+	var fields fieldSet
+	for _, name := range componentFields {
+		if requested[name] {
+			fields = append(fields, name)
+			delete(requested, name)
+		}
+	}
+	if len(requested) > 0 || len(fields) == 0 {
+		q.reject("fields", "a comma-separated list of "+strings.Join(componentFields, ", "))
+		return nil
+	}
+	return fields
+}
+
+// projectObject keeps only the selected fields of an encoded component.
+func (f fieldSet) projectObject(object json.RawMessage) (json.RawMessage, error) {
+	if f == nil {
+		return object, nil
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(object, &values); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range f {
+		value, ok := values[name]
+		if !ok {
+			continue // omitted by omitempty
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	// Added by ?include=computed and ?render=html, which select them themselves
+	for _, name := range []string{"computed", "description_html"} {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// projectArray keeps only the selected fields of each component in an encoded array.
+func (f fieldSet) projectArray(array json.RawMessage) (json.RawMessage, error) {
+	if f == nil {
+		return array, nil
+	}
+	var objects []json.RawMessage
+	if err := json.Unmarshal(array, &objects); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, object := range objects {
+		projected, err := f.projectObject(object)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(projected)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// project encodes a component with only the selected fields.
+func (f fieldSet) project(component *models.Component) (json.RawMessage, error) {
+	encoded, err := json.Marshal(component)
+	if err != nil {
+		return nil, err
+	}
+	return f.projectObject(encoded)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	parse := func(query string) (fieldSet, []invalidParam) {
+		req, _ := http.NewRequest(http.MethodGet, "/components?"+query, nil)
+		q := newQueryParams(req)
+		return parseFields(q), q.invalid
+	}
+
+	fields, invalid := parse("fields=parent_id,%20name,id")
+	assert.Empty(t, invalid)
+	assert.Equal(t, fieldSet{"id", "name", "parent_id"}, fields, "Fields keep the model's order")
+
+	fields, invalid = parse("")
+	assert.Nil(t, fields)
+	assert.Empty(t, invalid)
+
+	for _, query := range []string{"fields=id,bogus", "fields=,"} {
+		_, invalid = parse(query)
+		if assert.Len(t, invalid, 1, query) {
+			assert.Contains(t, invalid[0].Accepted, "id, name, slug, type, status, tags, description, metadata, parent_id, position, version, created_at, updated_at")
+		}
+	}
+}
+
+func TestFieldSetProject(t *testing.T) {
+	comp := &models.Component{ID: 7, Name: "Pump", Description: "A very long description", ParentID: sql.NullInt64{Int64: 1, Valid: true}}
+
+	body, err := fieldSet{"id", "name", "created_at"}.project(comp)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":7,"name":"Pump"}`, string(body), "Fields omitted by omitempty stay omitted")
+
+	all, err := fieldSet(nil).project(comp)
+	assert.NoError(t, err)
+	assert.Contains(t, string(all), "A very long description")
+
+	array, err := fieldSet{"id"}.projectArray([]byte(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1},{"id":2}]`, string(array))
+
+	empty, err := fieldSet{"id"}.projectArray([]byte(`[]`))
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, string(empty))
+}
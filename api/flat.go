@@ -0,0 +1,101 @@
+package api
+
+import (
+	"component-service/cache"
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// flatCSVHeader names the CSV columns, in the order of cache.FlatRow's JSON fields.
+var flatCSVHeader = []string{
+	"id", "name", "description", "parent_id", "parent_name", "root_id", "root_name", "depth",
+	"ancestor_ids", "ancestor_names", "child_count", "descendant_count", "created_at", "updated_at",
+}
+
+// flatPathSeparator joins ancestor IDs and names into a single CSV cell.
+const flatPathSeparator = " / "
+
+// listFlat serves GET /components/flat: every component as one denormalized row, for reporting
+// tools to ingest without rebuilding the tree. Rows come from the cache's flat projection, as JSON
+// by default or as CSV with ?format=csv.
+func listFlat(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	format := q.oneOf("format", "json", "json", "csv")
+	if !q.valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "The flat view requires the component cache, which is not initialized")
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	if format == "json" {
+		body, err := cache.GlobalComponentCache.FlatJSON()
+		if err == nil {
+			body, err = hideUnreadable(r, body)
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error listing flat components: "+err.Error())
+			return
+		}
+		setETag(w, etag)
+		respondWithRawJSON(w, http.StatusOK, body)
+		return
+	}
+
+	rows := cache.GlobalComponentCache.FlatRows()
+	readable := readableFilter(r)
+	setETag(w, etag)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="components.csv"`)
+	out := csv.NewWriter(w)
+	out.Write(flatCSVHeader)
+	for _, row := range rows {
+		if readable != nil && !readable(row.ID) {
+			continue
+		}
+		out.Write(flatCSVRecord(row))
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("Flat CSV listing aborted: %v", err)
+	}
+}
+
+// flatCSVRecord formats a row for CSV. A root's parent columns are empty.
+func flatCSVRecord(row *cache.FlatRow) []string {
+	var parentID, parentName string
+	if row.ParentID != nil {
+		parentID = strconv.FormatInt(*row.ParentID, 10)
+	}
+	if row.ParentName != nil {
+		parentName = *row.ParentName
+	}
+	ancestorIDs := make([]string, len(row.AncestorIDs))
+	for i, id := range row.AncestorIDs {
+		ancestorIDs[i] = strconv.FormatInt(id, 10)
+	}
+	return []string{
+		strconv.FormatInt(row.ID, 10),
+		row.Name,
+		row.Description,
+		parentID,
+		parentName,
+		strconv.FormatInt(row.RootID, 10),
+		row.RootName,
+		strconv.Itoa(row.Depth),
+		strings.Join(ancestorIDs, flatPathSeparator),
+		strings.Join(row.AncestorNames, flatPathSeparator),
+		strconv.Itoa(row.ChildCount),
+		strconv.Itoa(row.DescendantCount),
+		row.CreatedAt,
+		row.UpdatedAt,
+	}
+}
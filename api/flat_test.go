@@ -0,0 +1,50 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFlat(t *testing.T) {
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	cache.GlobalComponentCache = nil
+	assert.Equal(t, http.StatusServiceUnavailable, get("/components/flat").Code)
+
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "root"},
+		{ID: 2, Name: "child, first", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+	}})
+	assert.NoError(t, err)
+
+	rr := get("/components/flat")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[
+		{"id":1,"name":"root","description":"","parent_id":null,"parent_name":null,"root_id":1,"root_name":"root","depth":0,
+		 "ancestor_ids":[],"ancestor_names":[],"child_count":1,"descendant_count":1,"created_at":"","updated_at":""},
+		{"id":2,"name":"child, first","description":"","parent_id":1,"parent_name":"root","root_id":1,"root_name":"root","depth":1,
+		 "ancestor_ids":[1],"ancestor_names":["root"],"child_count":0,"descendant_count":0,"created_at":"","updated_at":""}
+	]`, rr.Body.String())
+
+	rr = get("/components/flat?format=csv")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name,description,parent_id,parent_name,root_id,root_name,depth,ancestor_ids,ancestor_names,child_count,descendant_count,created_at,updated_at\n"+
+		"1,root,,,,1,root,0,,,1,1,,\n"+
+		"2,\"child, first\",,1,root,1,root,1,1,root,0,0,,\n", rr.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, get("/components/flat?format=xml").Code)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"component-service/db"
+	"component-service/fixtures"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The fuzz targets below drive ComponentsHandler with malformed input, the plant fixture cached
+// and no database. Their seeds run with every go test; go test -fuzz=FuzzX ./api explores further.
+// Whatever the input, a response must be a status the API documents with a JSON body, and a 500
+// only for the missing database, which a real deployment has.
+
+// fuzzStatuses are the statuses ComponentsHandler may answer with.
+var fuzzStatuses = map[int]bool{
+	http.StatusOK: true, http.StatusCreated: true, http.StatusAccepted: true, http.StatusNoContent: true,
+	http.StatusNotModified: true, http.StatusBadRequest: true, http.StatusForbidden: true,
+	http.StatusNotFound: true, http.StatusMethodNotAllowed: true, http.StatusConflict: true,
+	http.StatusGone: true, http.StatusUnprocessableEntity: true, http.StatusInternalServerError: true,
+	http.StatusServiceUnavailable: true,
+}
+
+// serveFuzzed serves req with ACLs enforced for alice, failing t on a panic or a response
+// breaking the invariants above.
+func serveFuzzed(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	req.Header.Set(defaultPrincipalHeader, "alice")
+	rr := httptest.NewRecorder()
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				t.Fatalf("%s %q?%q panicked: %v", req.Method, req.URL.Path, req.URL.RawQuery, p)
+			}
+		}()
+		(&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler)).ServeHTTP(rr, req)
+	}()
+	if !fuzzStatuses[rr.Code] {
+		t.Fatalf("%s %q?%q: unexpected status %d: %s", req.Method, req.URL.Path, req.URL.RawQuery, rr.Code, rr.Body.String())
+	}
+	if req.Method != http.MethodHead && rr.Code != http.StatusNoContent && rr.Code != http.StatusNotModified &&
+		strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") && !json.Valid(rr.Body.Bytes()) {
+		t.Fatalf("%s %q?%q: status %d with a body that is not JSON: %q", req.Method, req.URL.Path, req.URL.RawQuery, rr.Code, rr.Body.String())
+	}
+	if rr.Code == http.StatusInternalServerError && !strings.Contains(rr.Body.String(), db.ErrNotInitialized.Error()) {
+		t.Fatalf("%s %q?%q: 500 not caused by the missing database: %s", req.Method, req.URL.Path, req.URL.RawQuery, rr.Body.String())
+	}
+	return rr
+}
+
+// fuzzRequest builds a request for any path and query, including ones httptest.NewRequest would
+// refuse to parse, as a server can receive them escaped.
+func fuzzRequest(method, path, rawQuery string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(body))
+	req.Method = method
+	req.URL.Path = path
+	req.URL.RawQuery = rawQuery
+	return req
+}
+
+func FuzzComponentsHandlerPath(f *testing.F) {
+	fixtures.MustLoadCache(f, "plant")
+	for _, seed := range []struct{ method, path, query string }{
+		{"GET", "/components", "limit=2&offset=1&sort=name"},
+		{"GET", "/components/1", "fields=id,name&include=computed"},
+		{"GET", "/components/1/children", "limit=-1"},
+		{"GET", "/components/1/descendants", "max_depth=99999999999999999999"},
+		{"GET", "/components/1/tree", "max_depth=2&max_nodes=1e9"},
+		{"GET", "/components/99999999999999999999", ""},
+		{"GET", "/components/-1/children", ""},
+		{"GET", "/components/0x10", ""},
+		{"GET", "/components/１", ""},
+		{"GET", "/components/1/ñ", ""},
+		{"GET", "/components/\x00/tree", ""},
+		{"GET", "/components//children", ""},
+		{"GET", "/components/1/share/../../..", ""},
+		{"GET", "/components/by-path", "path=/plant/%E2%80%AE/pump"},
+		{"GET", "/components/by-path", `path=/plant\`},
+		{"GET", "/components/slug/", ""},
+		{"GET", "/components/slug/ä-pump", ""},
+		{"GET", "/components/flat", "cursor=%%%"},
+		{"GET", "/components", "after=not-a-cursor&name_contains=%00"},
+		{"DELETE", "/components/1", "cascade=yes"},
+		{"BREW", "/components/1/reorder", ""},
+		{"GET", "/" + strings.Repeat("components/", 64), ""},
+	} {
+		f.Add(seed.method, seed.path, seed.query)
+	}
+	f.Fuzz(func(t *testing.T, method, path, query string) {
+		if method == "" || strings.ContainsAny(method, " \t\r\n") {
+			return // not a method a server would parse
+		}
+		serveFuzzed(t, fuzzRequest(method, path, query, nil))
+	})
+}
+
+// fuzzBodyTargets are the endpoints that decode a request body, by index.
+var fuzzBodyTargets = []struct{ method, path, query string }{
+	{http.MethodPost, "/components", ""},
+	{http.MethodPut, "/components/2", ""},
+	{http.MethodPatch, "/components/2", ""},
+	{http.MethodPost, "/components/move", ""},
+	{http.MethodPost, "/components/3/move", ""},
+	{http.MethodPost, "/components/3/reorder", ""},
+	{http.MethodPost, "/components/3/simulate", ""},
+	{http.MethodPost, "/components/import", "source=fuzz"},
+	{http.MethodPut, "/components/1/acl", ""},
+	{http.MethodPut, "/components/1/visibility", ""},
+	{http.MethodPost, "/components/1/share", ""},
+}
+
+func FuzzComponentsHandlerBody(f *testing.F) {
+	fixtures.MustLoadCache(f, "plant")
+	for _, body := range []string{
+		``,
+		`{}`,
+		`null`,
+		`[]`,
+		`"name"`,
+		`{"name": "pump", "parent_id": 1}`,
+		`{"name": "pump", "parent_id": 99999999999999999999999}`,
+		`{"name": "pump", "parent_id": 1e400}`,
+		`{"name": "pump", "parent_id": -0.5}`,
+		`{"name": "pump", "parent_id": {"Int64": 1, "Valid": "yes"}}`,
+		`{"name": "\ud800", "description": "\u0000"}`,
+		"{\"name\": \"\xff\xfe\"}",
+		`{"name": "pump"} trailing`,
+		`{"name": "pump"}{"name": "pump"}`,
+		`{"position": 18446744073709551616}`,
+		`{"position": -1}`,
+		`{"operation": "merge", "into_id": 1e3}`,
+		`{"operation": "move", "new_parent_id": "1"}`,
+		`{"moves": [{"id": 3, "new_parent_id": null}]}`,
+		`[{"id": 3, "new_parent_id": 9223372036854775807}]`,
+		`{"entries": [{"principal": "", "permission": "root"}]}`,
+		`{"public": 1}`,
+		`{"expires_in": "-1h"}`,
+		`{"id": 1, "name": "a", "parent_id": 1}` + "\n" + `{"id": 1, "name": "b"}`,
+		strings.Repeat(`[`, 20000),
+		strings.Repeat(`{"a":`, 20000) + `1` + strings.Repeat(`}`, 20000),
+		`{"name": "` + strings.Repeat("x", 1<<16) + `"}`,
+	} {
+		for target := range fuzzBodyTargets {
+			f.Add(uint8(target), []byte(body))
+		}
+	}
+	f.Fuzz(func(t *testing.T, target uint8, body []byte) {
+		endpoint := fuzzBodyTargets[int(target)%len(fuzzBodyTargets)]
+		rr := serveFuzzed(t, fuzzRequest(endpoint.method, endpoint.path, endpoint.query, body))
+		if endpoint.path != "/components/import" && !json.Valid(body) && len(bytes.TrimSpace(body)) > 0 && rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s with malformed JSON %q: status %d, expected 400: %s", endpoint.method, endpoint.path, body, rr.Code, rr.Body.String())
+		}
+	})
+}
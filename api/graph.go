@@ -0,0 +1,382 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"component-service/store"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGraphDepth = 2  // Levels below the focus node returned when ?depth is omitted
+	maxGraphDepth     = 10 // Upper bound to keep graph payloads renderable
+
+	// defaultGraphChildrenLimit is how many children of each node are returned when
+	// ?children_limit is omitted, so one node with a huge child list cannot swamp the response.
+	defaultGraphChildrenLimit = 100
+
+	defaultRelationDepth = 1 // Hops along links from the subtree returned when ?relation_depth is omitted
+	maxRelationDepth     = 3
+
+	defaultTreeWalkTimeout = 10 * time.Second
+)
+
+// TreeWalkTimeout bounds a single tree traversal.
+var TreeWalkTimeout = defaultTreeWalkTimeout
+
+// TreeWalkTimeoutFromEnv reads TREE_WALK_TIMEOUT, a positive duration that defaults to 10s.
+func TreeWalkTimeoutFromEnv() (time.Duration, error) {
+	value := os.Getenv("TREE_WALK_TIMEOUT")
+	if value == "" {
+		return defaultTreeWalkTimeout, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid TREE_WALK_TIMEOUT %q: expected a positive duration such as 10s", value)
+	}
+	return parsed, nil
+}
+
+// respondWithTreeWalkError maps a traversal failure to a response. A client that has gone away
+// gets nothing; an exceeded deadline is reported as 503 so callers can retry with a smaller depth.
+func respondWithTreeWalkError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case r.Context().Err() != nil:
+		log.Printf("Tree walk for %s abandoned: client disconnected", r.URL.Path)
+	case errors.Is(err, context.DeadlineExceeded):
+		respondWithError(w, http.StatusServiceUnavailable, "Tree traversal exceeded its deadline; request a smaller depth")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error listing child components: "+err.Error())
+	}
+}
+
+// GraphElement is a node or edge in the cytoscape "elements" shape: the payload lives under
+// "data" and "classes" carries space-separated styling hints. d3 consumers can read data directly.
+type GraphElement struct {
+	Data    map[string]interface{} `json:"data"`
+	Classes string                 `json:"classes,omitempty"`
+}
+
+// GraphData is the response body of GET /components/{id}/graph-data.
+type GraphData struct {
+	Nodes []GraphElement `json:"nodes"`
+	Edges []GraphElement `json:"edges"`
+}
+
+// getComponentGraphData returns the subtree rooted at id, down to ?depth levels, as nodes and edges.
+// Each node contributes at most ?children_limit children; a node with more carries its child count
+// and a cursor that continues its child list when passed back as ?children_cursor with the node
+// as the focus. The components the subtree links to or is linked from follow, up to
+// ?relation_depth hops away, with link edges; see addRelations.
+func getComponentGraphData(w http.ResponseWriter, r *http.Request, id int64) {
+	q := newQueryParams(r)
+	depth := q.intRange("depth", defaultGraphDepth, 0, maxGraphDepth)
+	childrenLimit := q.intRange("children_limit", defaultGraphChildrenLimit, 1, MaxUnpaginatedChildren)
+	relationDepth := q.intRange("relation_depth", defaultRelationDepth, 0, maxRelationDepth)
+	focusOffset := 0
+	if cursorParam := q.str("children_cursor"); cursorParam != "" {
+		offset, err := decodeChildrenCursor(cursorParam, id)
+		if err != nil {
+			q.reject("children_cursor", err.Error())
+		}
+		focusOffset = offset
+	}
+	if !q.valid(w) {
+		return
+	}
+
+	reads := readStore(r)
+	focus, err := reads.GetComponentByID(id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error getting component: "+err.Error())
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), TreeWalkTimeout)
+	defer cancel()
+
+	readable := readableFilter(r)
+	graph := GraphData{Nodes: []GraphElement{}, Edges: []GraphElement{}}
+	var walked []*models.Component
+	level := []*models.Component{focus}
+	for currentDepth := 0; len(level) > 0; currentDepth++ {
+		var nextLevel []*models.Component
+		for _, comp := range level {
+			var children []*models.Component
+			if currentDepth < depth {
+				children, err = reads.ListChildComponentsContext(ctx, comp.ID)
+				if err != nil {
+					respondWithTreeWalkError(w, r, err)
+					return
+				}
+				if readable != nil {
+					children = readableOnly(children, readable)
+				}
+			}
+			node := graphNode(comp, comp.ID == focus.ID, currentDepth < depth && len(children) == 0)
+			node.Data["depth"] = currentDepth
+			offset := 0
+			if comp.ID == focus.ID {
+				offset = min(focusOffset, len(children))
+			}
+			if total := len(children); total-offset > childrenLimit {
+				node.Data["child_count"] = total
+				node.Data["children_cursor"] = encodeChildrenCursor(comp.ID, offset+childrenLimit)
+				node.Classes = strings.TrimSpace(node.Classes + " truncated")
+				children = children[offset : offset+childrenLimit]
+			} else {
+				children = children[offset:]
+			}
+			graph.Nodes = append(graph.Nodes, node)
+			walked = append(walked, comp)
+			for _, child := range children {
+				graph.Edges = append(graph.Edges, graphEdge(comp.ID, child.ID))
+			}
+			nextLevel = append(nextLevel, children...)
+		}
+		level = nextLevel
+	}
+	if relationDepth > 0 {
+		if err := addRelations(ctx, r, reads, &graph, walked, relationDepth, childrenLimit); err != nil {
+			respondWithTreeWalkError(w, r, err)
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, graph)
+}
+
+// addRelations adds the components linked with the subtree's nodes, as models.Component.Links
+// finds them, and then those linked with the components added, up to relationDepth hops. Each
+// link is a "link" edge from the component mentioning the other. A component added this way is a
+// "related" node with its relation_depth instead of a depth, and its children are not walked. Each
+// node contributes at most limit links. Links to components the request may not see are left out,
+// as are, for share links and the public view, links leading out of what they serve. Incoming
+// links come from the cache's backlinks index, so without the cache only outgoing ones are found.
+func addRelations(ctx context.Context, r *http.Request, reads *store.ComponentStore, graph *GraphData, walked []*models.Component, relationDepth, limit int) error {
+	visible := relatedVisible(r)
+	included := make(map[int64]bool, len(walked))
+	for _, comp := range walked {
+		included[comp.ID] = true
+	}
+	linked := map[string]bool{}
+	frontier := walked
+	for hop := 1; hop <= relationDepth && len(frontier) > 0; hop++ {
+		var next []*models.Component
+		for _, comp := range frontier {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			outgoing, err := resolveLinks(reads, comp)
+			if err != nil {
+				return err
+			}
+			var incoming []*models.Component
+			if cache.GlobalComponentCache != nil {
+				incoming, _ = cache.GlobalComponentCache.Backlinks(comp.ID)
+			}
+			added := 0
+			for i, other := range append(outgoing, incoming...) {
+				if added == limit {
+					break
+				}
+				if other.ID == comp.ID || !visible(other.ID) {
+					continue
+				}
+				source, target := comp.ID, other.ID
+				if i >= len(outgoing) {
+					source, target = other.ID, comp.ID
+				}
+				edge := graphLink(source, target)
+				if id := edge.Data["id"].(string); !linked[id] {
+					linked[id] = true
+					graph.Edges = append(graph.Edges, edge)
+				}
+				added++
+				if !included[other.ID] {
+					included[other.ID] = true
+					node := graphNode(other, false, false)
+					node.Data["relation_depth"] = hop
+					node.Classes = strings.TrimSpace(node.Classes + " related")
+					graph.Nodes = append(graph.Nodes, node)
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// resolveLinks returns the components comp links to that exist, in the order it mentions them.
+func resolveLinks(reads *store.ComponentStore, comp *models.Component) ([]*models.Component, error) {
+	var linked []*models.Component
+	for _, ref := range comp.Links() {
+		var other *models.Component
+		var err error
+		if ref.Slug != "" {
+			other, err = reads.GetComponentBySlug(ref.Slug)
+		} else {
+			other, err = reads.GetComponentByID(ref.ID)
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				continue // a link to a component that does not exist (yet)
+			}
+			return nil, err
+		}
+		linked = append(linked, other)
+	}
+	return linked, nil
+}
+
+// relatedVisible returns a predicate telling which components a graph may reach through links:
+// those the principal may read, and for share links and the public view only those they serve.
+func relatedVisible(r *http.Request) func(id int64) bool {
+	if link := sharedLinkFrom(r); link != nil {
+		return func(id int64) bool {
+			inside, err := withinSubtree(id, link.ComponentID)
+			return err == nil && inside
+		}
+	}
+	if publicView(r) {
+		return func(id int64) bool {
+			public, _ := cache.GlobalComponentCache.IsPublic(id)
+			return public
+		}
+	}
+	if readable := readableFilter(r); readable != nil {
+		return readable
+	}
+	return func(int64) bool { return true }
+}
+
+// readableOnly drops the components readable rejects, together with their subtrees.
+func readableOnly(components []*models.Component, readable func(id int64) bool) []*models.Component {
+	visible := make([]*models.Component, 0, len(components))
+	for _, comp := range components {
+		if readable(comp.ID) {
+			visible = append(visible, comp)
+		}
+	}
+	return visible
+}
+
+// encodeChildrenCursor renders the position in a node's child list where the next page starts.
+// The node ID is part of the token so it cannot be applied to another node's children.
+func encodeChildrenCursor(parentID int64, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", parentID, offset)))
+}
+
+var errInvalidChildrenCursor = errors.New("a children_cursor value returned for this component by graph-data")
+
+// decodeChildrenCursor parses a token produced by encodeChildrenCursor for parentID.
+func decodeChildrenCursor(token string, parentID int64) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errInvalidChildrenCursor
+	}
+	id, offsetText, found := strings.Cut(string(raw), ".")
+	if !found || id != strconv.FormatInt(parentID, 10) {
+		return 0, errInvalidChildrenCursor
+	}
+	offset, err := strconv.Atoi(offsetText)
+	if err != nil || offset < 0 {
+		return 0, errInvalidChildrenCursor
+	}
+	return offset, nil
+}
+
+// graphNode renders a component as a node. Its display hints, the "display" object of its
+// metadata, are copied into the node: the words of its "classes" string join the node's classes,
+// and its other string, number and boolean members go into data, for style mappers such as
+// cytoscape's data(color), without replacing the members set here.
+func graphNode(comp *models.Component, isFocus bool, isLeaf bool) GraphElement {
+	data := map[string]interface{}{}
+	var hinted []string
+	var display map[string]json.RawMessage
+	if raw, ok := metadataMember(comp, "display"); ok && json.Unmarshal(raw, &display) == nil {
+		for key, value := range display {
+			var decoded interface{}
+			if json.Unmarshal(value, &decoded) != nil {
+				continue
+			}
+			switch v := decoded.(type) {
+			case string:
+				if key == "classes" {
+					hinted = strings.Fields(v)
+				} else {
+					data[key] = v
+				}
+			case float64, bool:
+				data[key] = v
+			}
+		}
+	}
+	data["id"] = strconv.FormatInt(comp.ID, 10)
+	data["label"] = comp.Name
+	if comp.ParentID.Valid {
+		data["parent_id"] = strconv.FormatInt(comp.ParentID.Int64, 10)
+	}
+
+	var classes []string
+	if isFocus {
+		classes = append(classes, "focus")
+	}
+	if !comp.ParentID.Valid {
+		classes = append(classes, "root")
+	}
+	if isLeaf {
+		classes = append(classes, "leaf")
+	}
+	classes = append(classes, hinted...)
+	return GraphElement{Data: data, Classes: strings.Join(classes, " ")}
+}
+
+// metadataMember returns the raw value of a top-level key of a component's metadata.
+func metadataMember(comp *models.Component, key string) (json.RawMessage, bool) {
+	if len(comp.Metadata) == 0 {
+		return nil, false
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(comp.Metadata, &object); err != nil {
+		return nil, false
+	}
+	value, ok := object[key]
+	return value, ok
+}
+
+func graphEdge(parentID, childID int64) GraphElement {
+	return GraphElement{
+		Data: map[string]interface{}{
+			"id":     fmt.Sprintf("%d-%d", parentID, childID),
+			"source": strconv.FormatInt(parentID, 10),
+			"target": strconv.FormatInt(childID, 10),
+			"kind":   "child",
+		},
+	}
+}
+
+func graphLink(sourceID, targetID int64) GraphElement {
+	return GraphElement{
+		Data: map[string]interface{}{
+			"id":     fmt.Sprintf("link:%d-%d", sourceID, targetID),
+			"source": strconv.FormatInt(sourceID, 10),
+			"target": strconv.FormatInt(targetID, 10),
+			"kind":   "link",
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeWalkTimeoutFromEnv(t *testing.T) {
+	timeout, err := TreeWalkTimeoutFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultTreeWalkTimeout, timeout)
+
+	t.Setenv("TREE_WALK_TIMEOUT", "250ms")
+	timeout, err = TreeWalkTimeoutFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, timeout)
+
+	for _, value := range []string{"0s", "-1s", "soon"} {
+		t.Setenv("TREE_WALK_TIMEOUT", value)
+		_, err = TreeWalkTimeoutFromEnv()
+		assert.ErrorContains(t, err, "invalid TREE_WALK_TIMEOUT", value)
+	}
+}
+
+func TestGraphDataRelations(t *testing.T) {
+	// 1 -> 2; 2 links to 3, 3 to 4, and 5 to 1. 4 is hidden from everyone but alice.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "Plant", Metadata: json.RawMessage(`{"display":{"color":"#c00","classes":"critical pump","id":"x","nested":{"a":1}}}`)},
+			{ID: 2, Name: "Feed", ParentID: sql.NullInt64{Int64: 1, Valid: true}, Description: "Fills [[#3]]"},
+			{ID: 3, Name: "Tank", Description: "Drains through /components/4"},
+			{ID: 4, Name: "Valve"},
+			{ID: 5, Name: "Sensor", Description: "Watches [[#1]]"},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 4, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+			{ComponentID: 4, Principal: "alice", Permission: cache.PermissionRead},
+		},
+	})
+	require.NoError(t, err)
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	graphOf := func(query, principal string) (nodes map[string]GraphElement, edges map[string]GraphElement) {
+		req := httptest.NewRequest(http.MethodGet, "/components/1/graph-data?depth=1"+query, nil)
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var graph GraphData
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &graph))
+		nodes, edges = map[string]GraphElement{}, map[string]GraphElement{}
+		for _, node := range graph.Nodes {
+			nodes[node.Data["id"].(string)] = node
+		}
+		for _, edge := range graph.Edges {
+			edges[edge.Data["id"].(string)] = edge
+		}
+		return nodes, edges
+	}
+
+	nodes, edges := graphOf("", "alice")
+	assert.Len(t, nodes, 4, "The subtree, 3 linked from it and 5 linking to it")
+	assert.Equal(t, "link", edges["link:2-3"].Data["kind"])
+	assert.Contains(t, edges, "link:5-1")
+	assert.Equal(t, "root related", nodes["3"].Classes, "Related nodes are not walked, so none is a leaf")
+	assert.Equal(t, float64(1), nodes["3"].Data["relation_depth"])
+	assert.Nil(t, nodes["3"].Data["depth"])
+
+	plant := nodes["1"]
+	assert.Equal(t, "focus root critical pump", plant.Classes)
+	assert.Equal(t, "#c00", plant.Data["color"])
+	assert.Equal(t, "1", plant.Data["id"], "Display hints do not replace the node's own members")
+	assert.NotContains(t, plant.Data, "nested")
+
+	nodes, _ = graphOf("&relation_depth=2", "alice")
+	assert.Contains(t, nodes, "4")
+	nodes, edges = graphOf("&relation_depth=2", "bob")
+	assert.NotContains(t, nodes, "4", "bob may not read 4")
+	assert.NotContains(t, edges, "link:3-4")
+	nodes, edges = graphOf("&relation_depth=0", "alice")
+	assert.Len(t, nodes, 2)
+	assert.Len(t, edges, 1)
+}
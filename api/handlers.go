@@ -1,10 +1,14 @@
 package api
 
 import (
+	"component-service/cache"
 	"component-service/models"
 	"component-service/store"
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings" // For parsing URL paths
@@ -17,6 +21,30 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
 
+// invalidFieldResponse is the 422 body for a well-formed request body field whose value refers to
+// something that does not exist.
+type invalidFieldResponse struct {
+	Error string      `json:"error"`
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// codedErrorResponse is an error body with a code, for clients to tell the error apart without
+// parsing the message, which may change.
+type codedErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// errorCodeDuplicateName is the code of a 409 for a name a sibling already has; see
+// store.UniqueSiblingNames.
+const errorCodeDuplicateName = "duplicate_name"
+
+// respondWithDuplicateName sends the 409 for a write failing with store.ErrDuplicateName.
+func respondWithDuplicateName(w http.ResponseWriter, err error) {
+	respondWithJSON(w, http.StatusConflict, codedErrorResponse{Error: err.Error(), Code: errorCodeDuplicateName})
+}
+
 // respondWithJSON sends a JSON response.
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
@@ -29,19 +57,103 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(response)
 }
 
+// respondWithRawJSON sends an already-encoded JSON body.
+func respondWithRawJSON(w http.ResponseWriter, code int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// errTrailingData is returned by decodeBody for a body holding more than a single JSON value.
+var errTrailingData = errors.New("unexpected data after the JSON value")
+
+// decodeBody decodes the request body, a single JSON value, into v. With disallowUnknownFields,
+// an object field v has no place for is an error. Unlike a bare json.Decoder, anything after the
+// value but white space is an error too, instead of being ignored.
+func decodeBody(r *http.Request, v interface{}, disallowUnknownFields bool) error {
+	decoder := json.NewDecoder(r.Body)
+	if disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
 // ComponentsHandler routes requests for /components and /components/{id}
 func ComponentsHandler(w http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // e.g., ["components", "123"] or ["components"]
+	if !checkConsistency(w, r) {
+		return
+	}
 
 	if len(pathParts) == 1 && pathParts[0] == "components" { // /components
 		switch r.Method {
 		case http.MethodGet:
 			listComponents(w, r)
 		case http.MethodPost:
-			createComponent(w, r)
+			idempotent(w, r, createComponent)
 		default:
 			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "export" { // /components/export
+		if r.Method == http.MethodGet {
+			exportComponents(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for export endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "search" { // /components/search
+		if r.Method == http.MethodGet {
+			searchComponents(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for search endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "by-path" { // /components/by-path
+		if r.Method == http.MethodGet {
+			getComponentByPath(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for by-path endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "flat" { // /components/flat
+		if r.Method == http.MethodGet {
+			listFlat(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for flat endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "tree" { // /components/tree
+		if r.Method == http.MethodGet {
+			getTree(w, r, nil)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for tree endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "import" { // /components/import
+		if r.Method == http.MethodPost {
+			importComponents(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for import endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "watch" { // /components/watch
+		if r.Method == http.MethodGet {
+			watchComponentsSocket(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for watch endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "events" { // /components/events
+		if r.Method == http.MethodGet {
+			streamChangeFeed(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for events endpoint")
+		}
+	} else if len(pathParts) == 2 && pathParts[0] == "components" && pathParts[1] == "move" { // /components/move
+		if r.Method == http.MethodPost {
+			moveComponents(w, r)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for move endpoint")
+		}
 	} else if len(pathParts) == 2 && pathParts[0] == "components" { // /components/{id}
 		id, err := strconv.ParseInt(pathParts[1], 10, 64)
 		if err != nil {
@@ -53,11 +165,19 @@ func ComponentsHandler(w http.ResponseWriter, r *http.Request) {
 			getComponent(w, r, id)
 		case http.MethodPut:
 			updateComponent(w, r, id)
+		case http.MethodPatch:
+			patchComponent(w, r, id)
 		case http.MethodDelete:
 			deleteComponent(w, r, id)
 		default:
 			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[1] == "slug" { // /components/slug/{slug}
+		if r.Method == http.MethodGet {
+			getComponentBySlug(w, r, pathParts[2])
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for slug endpoint")
+		}
 	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "children" { // /components/{id}/children
 		parentID, err := strconv.ParseInt(pathParts[1], 10, 64)
 		if err != nil {
@@ -69,14 +189,203 @@ func ComponentsHandler(w http.ResponseWriter, r *http.Request) {
 		} else {
 			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for child components endpoint")
 		}
+	} else if len(pathParts) == 4 && pathParts[0] == "components" && pathParts[2] == "children" && pathParts[3] == "import" { // /components/{id}/children/import
+		parentID, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid parent component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			importChildren(w, r, parentID)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for children import endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "descendants" { // /components/{id}/descendants
+		rootID, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodGet {
+			listDescendants(w, r, rootID)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for descendants endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "backlinks" { // /components/{id}/backlinks
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodGet {
+			listBacklinks(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for backlinks endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "tree" { // /components/{id}/tree
+		rootID, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodGet {
+			getTree(w, r, &rootID)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for tree endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "move" { // /components/{id}/move
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			moveComponent(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for move endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "reorder" { // /components/{id}/reorder
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			reorderComponent(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for reorder endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "status" { // /components/{id}/status
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			setComponentStatus(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for status endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "restore" { // /components/{id}/restore
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			restoreComponent(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for restore endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "simulate" { // /components/{id}/simulate
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			simulateOperation(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for simulate endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "merge" { // /components/{id}/merge
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodPost {
+			mergeComponent(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for merge endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "checksum" { // /components/{id}/checksum
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodGet {
+			getComponentChecksum(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for checksum endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "graph-data" { // /components/{id}/graph-data
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		if r.Method == http.MethodGet {
+			getComponentGraphData(w, r, id)
+		} else {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for graph data endpoint")
+		}
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "acl" { // /components/{id}/acl
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		componentACLHandler(w, r, id)
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "visibility" { // /components/{id}/visibility
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		visibilityHandler(w, r, id)
+	} else if (len(pathParts) == 3 || len(pathParts) == 4) && pathParts[0] == "components" && pathParts[2] == "share" { // /components/{id}/share[/{linkID}]
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		linkID := ""
+		if len(pathParts) == 4 {
+			linkID = pathParts[3]
+		}
+		shareHandler(w, r, id, linkID)
+	} else if (len(pathParts) == 3 || len(pathParts) == 4) && pathParts[0] == "components" && pathParts[2] == "attachments" { // /components/{id}/attachments[/{attachmentID}]
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		attachmentID := ""
+		if len(pathParts) == 4 {
+			attachmentID = pathParts[3]
+		}
+		attachmentsHandler(w, r, id, attachmentID)
+	} else if (len(pathParts) == 3 || len(pathParts) == 4) && pathParts[0] == "components" && pathParts[2] == "comments" { // /components/{id}/comments[/{commentID}]
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		commentID := ""
+		if len(pathParts) == 4 {
+			commentID = pathParts[3]
+		}
+		commentsHandler(w, r, id, commentID)
+	} else if len(pathParts) == 3 && pathParts[0] == "components" && pathParts[2] == "watch" { // /components/{id}/watch
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid component ID in path")
+			return
+		}
+		watchHandler(w, r, id)
 	} else {
 		respondWithError(w, http.StatusNotFound, "Not found")
 	}
 }
 
 func createComponent(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
 	var comp models.Component
-	if err := json.NewDecoder(r.Body).Decode(&comp); err != nil {
+	if err := decodeBody(r, &comp, false); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
 		return
 	}
@@ -87,35 +396,81 @@ func createComponent(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Component name is required")
 		return
 	}
+	if comp.ParentID.Valid && !canAccess(r, comp.ParentID.Int64, cache.PermissionWrite) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Creating a child of component %d requires write permission on it", comp.ParentID.Int64))
+		return
+	}
 
 	// If ParentID is present in JSON but is 0, it means "no parent".
 	// If ParentID is not in JSON, comp.ParentID.Valid will be false.
 	// The store layer handles sql.NullInt64 conversion.
 
-	id, err := componentStore.CreateComponent(&comp)
+	id, err := writeStore(r).CreateComponent(&comp)
+	if errors.Is(err, store.ErrParentNotFound) {
+		respondWithJSON(w, http.StatusUnprocessableEntity, invalidFieldResponse{
+			Error: fmt.Sprintf("Parent component %d not found", comp.ParentID.Int64),
+			Field: "parent_id",
+			Value: comp.ParentID.Int64,
+		})
+		return
+	}
+	if errors.Is(err, store.ErrInvalidType) {
+		respondWithInvalidType(w, err, comp.Type)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidStatus) {
+		respondWithInvalidStatus(w, err, comp.Status)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidTags) {
+		respondWithInvalidTags(w, err, "tags", comp.Tags)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidMetadata) {
+		respondWithInvalidMetadata(w, err, comp.Metadata)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidAttributes) {
+		respondWithInvalidAttributes(w, err, comp.Metadata)
+		return
+	}
+	if errors.Is(err, store.ErrDuplicateName) {
+		respondWithDuplicateName(w, err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating component: "+err.Error())
 		return
 	}
 	comp.ID = id
-	// To return the full component including timestamps, we could fetch it again,
-	// but for now, let's return what we have plus the ID.
-	// For a more complete response, you might do:
-	// createdComp, err := componentStore.GetComponentByID(id)
-	// if err != nil { ... }
-	// respondWithJSON(w, http.StatusCreated, createdComp)
-
-	// Set CreatedAt and UpdatedAt to empty strings, as they are not returned by CreateComponent
-	// but are present in the struct. The actual values are set in the DB.
-	// A fetch after create would populate these accurately.
-	comp.CreatedAt = ""
-	comp.UpdatedAt = ""
-
-	respondWithJSON(w, http.StatusCreated, comp)
+	// Read the row back from the database for the timestamps and defaults it was stored with.
+	// Should that read fail, the create still succeeded and answers with what was sent.
+	created, err := componentStore.Strong().GetComponentByID(id)
+	if err != nil {
+		log.Printf("Error reading created component %d: %v", id, err)
+		created = &comp
+	}
+	w.Header().Set("Location", fmt.Sprintf("/components/%d", id))
+	respondWithComponent(w, http.StatusCreated, created)
 }
 
 func getComponent(w http.ResponseWriter, r *http.Request, id int64) {
-	comp, err := componentStore.GetComponentByID(id)
+	q := newQueryParams(r)
+	fields := parseFields(q)
+	includeComputed := parseIncludeComputed(q)
+	render := parseRender(q)
+	if !q.valid(w) {
+		return
+	}
+	// The version covers the component itself; computed fields also follow its subtree.
+	var etag string
+	if includeComputed {
+		etag = cachedETag(r)
+		if notModified(w, r, etag) {
+			return
+		}
+	}
+	comp, err := readStore(r).GetComponentByID(id)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			respondWithError(w, http.StatusNotFound, err.Error())
@@ -124,12 +479,37 @@ func getComponent(w http.ResponseWriter, r *http.Request, id int64) {
 		}
 		return
 	}
-	respondWithJSON(w, http.StatusOK, comp)
+	recordView(comp.ID)
+	if !includeComputed && comp.Version > 0 {
+		etag = versionETag(comp.Version)
+		if notModified(w, r, etag) {
+			return
+		}
+	}
+	body, err := json.Marshal(comp)
+	if err == nil && includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectObject(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error encoding component: "+err.Error())
+		return
+	}
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
 }
 
 func updateComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
 	var comp models.Component
-	if err := json.NewDecoder(r.Body).Decode(&comp); err != nil {
+	if err := decodeBody(r, &comp, false); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
 		return
 	}
@@ -139,13 +519,38 @@ func updateComponent(w http.ResponseWriter, r *http.Request, id int64) {
 		respondWithError(w, http.StatusBadRequest, "Component name is required for update")
 		return
 	}
+	if comp.ParentID.Valid && !canAccess(r, comp.ParentID.Int64, cache.PermissionWrite) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Moving a component under component %d requires write permission on it", comp.ParentID.Int64))
+		return
+	}
+
+	version, fromHeader, ok := expectedVersion(w, r, comp.Version)
+	if !ok {
+		return
+	}
+	comp.Version = version
 
 	// Ensure the ID from the path is used, not from the body if present.
-	err := componentStore.UpdateComponent(id, &comp)
+	err := writeStore(r).UpdateComponent(id, &comp)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		switch {
+		case errors.Is(err, store.ErrVersionConflict):
+			respondWithVersionConflict(w, err, fromHeader)
+		case strings.Contains(err.Error(), "not found"):
 			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
+		case errors.Is(err, store.ErrCycle):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.ErrInvalidType):
+			respondWithInvalidType(w, err, comp.Type)
+		case errors.Is(err, store.ErrInvalidTags):
+			respondWithInvalidTags(w, err, "tags", comp.Tags)
+		case errors.Is(err, store.ErrInvalidMetadata):
+			respondWithInvalidMetadata(w, err, comp.Metadata)
+		case errors.Is(err, store.ErrInvalidAttributes):
+			respondWithInvalidAttributes(w, err, comp.Metadata)
+		case errors.Is(err, store.ErrDuplicateName):
+			respondWithDuplicateName(w, err)
+		default:
 			respondWithError(w, http.StatusInternalServerError, "Error updating component: "+err.Error())
 		}
 		return
@@ -156,15 +561,32 @@ func updateComponent(w http.ResponseWriter, r *http.Request, id int64) {
 		respondWithError(w, http.StatusInternalServerError, "Error fetching updated component: "+err.Error())
 		return
 	}
-	respondWithJSON(w, http.StatusOK, updatedComp)
+	respondWithComponent(w, http.StatusOK, updatedComp)
 }
 
+// deleteComponent serves DELETE /components/{id}, a soft delete that POST
+// /components/{id}/restore undoes. The component's children become roots, unless cascade=true
+// deletes the whole subtree, which needs write permission on every component in it.
 func deleteComponent(w http.ResponseWriter, r *http.Request, id int64) {
-	err := componentStore.DeleteComponent(id)
+	params := newQueryParams(r)
+	cascade := params.oneOf("cascade", "false", "true", "false") == "true"
+	if !params.valid(w) {
+		return
+	}
+	var err error
+	if cascade {
+		canDelete := func(id int64) bool { return canAccess(r, id, cache.PermissionWrite) }
+		err = writeStore(r).DeleteSubtree(id, canDelete)
+	} else {
+		err = writeStore(r).DeleteComponent(id)
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
 			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
+		case errors.Is(err, store.ErrDeleteNotPermitted):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		default:
 			respondWithError(w, http.StatusInternalServerError, "Error deleting component: "+err.Error())
 		}
 		return
@@ -172,21 +594,157 @@ func deleteComponent(w http.ResponseWriter, r *http.Request, id int64) {
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Component deleted successfully"})
 }
 
+// restoreComponent serves POST /components/{id}/restore, which brings back a soft-deleted
+// component under its former parent, or as a root when that parent is gone. Restoring under a
+// parent needs write permission on it, as creating a child there would, and restoring at all needs
+// write permission under the ACL the component had when it was deleted. The restored component is
+// returned.
+func restoreComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	canAttach := func(parentID int64) bool { return canAccess(r, parentID, cache.PermissionWrite) }
+	// The cache dropped the component's ACL with it, so the ACL middleware let the request through
+	var canRestore func(acl []cache.ACLEntry) bool
+	if principal, enforced := principalFrom(r); enforced {
+		canRestore = func(acl []cache.ACLEntry) bool { return cache.EntriesAllow(acl, principal, cache.PermissionWrite) }
+	}
+	if err := writeStore(r).RestoreComponent(id, canAttach, canRestore); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, store.ErrNotDeleted):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, store.ErrRestoreNotPermitted):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error restoring component: "+err.Error())
+		}
+		return
+	}
+	restored, err := componentStore.GetComponentByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error fetching restored component: "+err.Error())
+		return
+	}
+	respondWithComponent(w, http.StatusOK, restored)
+}
+
+// listComponents serves GET /components, optionally filtered by ?name (exact), ?name_contains
+// (case-insensitive substring), ?type and ?tag, and ordered by ?sort and ?order.
 func listComponents(w http.ResponseWriter, r *http.Request) {
-	comps, err := componentStore.ListComponents()
+	q := newQueryParams(r)
+	p := parsePage(q, maxListLimit)
+	filter := cache.Filter{Name: q.str("name"), NameContains: q.str("name_contains"), Type: parseType(q), Tag: parseTag(q), Metadata: parseMetadataFilter(q), Reader: readerOf(r)}
+	order := parseSort(q)
+	fields := parseFields(q)
+	includeComputed := parseIncludeComputed(q)
+	render := parseRender(q)
+	if q.has("after") {
+		if !order.IsZero() {
+			q.reject("sort", "nothing in cursor mode: cursor pages always follow created_at, id order")
+		}
+		listComponentsAfter(w, r, q, filter, fields, includeComputed, render, p)
+		return
+	}
+	if !q.valid(w) {
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+	total, err := readStore(r).CountComponents(filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting components: "+err.Error())
+		return
+	}
+	body, err := readStore(r).ListComponentsJSON(filter, order, p.offset, p.limit) // Always an array, never null
+	if err == nil && includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectArray(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing components: "+err.Error())
+		return
+	}
+	setPaginationHeaders(w, r, p, total)
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
+}
+
+// listComponentsAfter serves cursor mode: ?after={cursor}, or an empty ?after= for the first page.
+// Pages follow (created_at, id) order, so concurrent inserts never shift later pages.
+func listComponentsAfter(w http.ResponseWriter, r *http.Request, q *queryParams, filter cache.Filter, fields fieldSet, includeComputed, render bool, p page) {
+	if q.values.Has("offset") {
+		q.reject("offset", "nothing in cursor mode: offset cannot be combined with after; follow next_cursor instead")
+	}
+	var after *cache.Cursor
+	if token := q.str("after"); token != "" {
+		cursor, err := decodeCursor(token)
+		if err != nil {
+			q.reject("after", err.Error())
+		}
+		after = &cursor
+	}
+	if !q.valid(w) {
+		return
+	}
+	limit := p.limit
+	if limit == 0 {
+		limit = defaultCursorPageSize
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+	body, next, err := readStore(r).ListComponentsAfterJSON(filter, after, limit) // Always an array, never null
+	if err == nil && includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectArray(body)
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error listing components: "+err.Error())
 		return
 	}
-	if comps == nil { // Ensure we return an empty list, not null, if no components
-		comps = []*models.Component{}
+	response := cursorPage{Components: body}
+	if next != nil {
+		token := encodeCursor(*next)
+		response.NextCursor = &token
 	}
-	respondWithJSON(w, http.StatusOK, comps)
+	setETag(w, etag)
+	respondWithJSON(w, http.StatusOK, response)
 }
 
 func listChildComponents(w http.ResponseWriter, r *http.Request, parentID int64) {
+	maxChildren := MaxUnpaginatedChildren
+	q := newQueryParams(r)
+	p := parsePage(q, maxChildren)
+	filter := cache.Filter{Type: parseType(q), Tag: parseTag(q), Metadata: parseMetadataFilter(q), Reader: readerOf(r)}
+	order := parseSort(q)
+	fields := parseFields(q)
+	includeComputed := parseIncludeComputed(q)
+	render := parseRender(q)
+	if !q.valid(w) {
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+
 	// First, check if the parent component exists
-	_, err := componentStore.GetComponentByID(parentID)
+	_, err := readStore(r).GetComponentByID(parentID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			respondWithError(w, http.StatusNotFound, fmt.Sprintf("Parent component with ID %d not found", parentID))
@@ -196,13 +754,33 @@ func listChildComponents(w http.ResponseWriter, r *http.Request, parentID int64)
 		return
 	}
 
-	children, err := componentStore.ListChildComponents(parentID)
+	total, err := readStore(r).CountChildComponents(parentID, filter)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error listing child components: "+err.Error())
+		respondWithError(w, http.StatusInternalServerError, "Error counting child components: "+err.Error())
 		return
 	}
-	if children == nil { // Ensure empty list, not null
-		children = []*models.Component{}
+	if p.limit == 0 && total > maxChildren {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf(
+			"Component %d has %d children, more than the %d that can be listed at once; page through them with ?limit=%d&offset=0 and follow the Link header",
+			parentID, total, maxChildren, maxChildren))
+		return
+	}
+
+	body, err := readStore(r).ListChildComponentsJSON(parentID, filter, order, p.offset, p.limit) // Always an array, never null
+	if err == nil && includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectArray(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing child components: "+err.Error())
+		return
 	}
-	respondWithJSON(w, http.StatusOK, children)
+	setPaginationHeaders(w, r, p, total)
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"component-service/cache"
 	"component-service/db"
 	"component-service/models"
 	"component-service/store"
@@ -11,11 +12,13 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
-	"strings"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	_ "github.com/lib/pq" // DB driver
 )
 
@@ -29,10 +32,12 @@ func TestMain(m *testing.M) {
 	// Setup: Initialize database for tests
 	if os.Getenv("DB_HOST") == "" || os.Getenv("DB_USER") == "" || os.Getenv("DB_NAME") == "" {
 		log.Println("Skipping API integration tests: DB_HOST, DB_USER, or DB_NAME environment variables not set.")
-		os.Exit(0) // Exit if DB is not configured, as all API tests depend on it.
+		os.Exit(m.Run()) // The integration tests skip themselves; tests served from the cache, and the fuzz seeds, still run.
 	}
 
-	db.InitDB() // Initialize connection using env vars
+	if err := db.InitDB(); err != nil { // Initialize connection using env vars
+		log.Fatalf("Failed to initialize database for API tests: %v", err)
+	}
 	testAPIStore = &store.ComponentStore{} // Used by handlers, and directly for setup/assertions
 
 	// Setup router
@@ -101,6 +106,9 @@ func TestAPIComponentsFlow(t *testing.T) {
 		assert.Equal(t, "APIRoot", comp.Name)
 		assert.NotZero(t, comp.ID)
 		assert.False(t, comp.ParentID.Valid)
+		assert.NotEmpty(t, comp.CreatedAt, "the stored timestamps are returned")
+		assert.NotEmpty(t, comp.UpdatedAt)
+		assert.Equal(t, fmt.Sprintf("/components/%d", comp.ID), rr.Header().Get("Location"))
 		createdRootID = comp.ID
 	})
 
@@ -179,6 +187,28 @@ func TestAPIComponentsFlow(t *testing.T) {
 		assert.Equal(t, "APIChild", children[0].Name)
 	})
 
+	// 5.5 Graph data for the root component
+	t.Run("GET_GraphData", func(t *testing.T) {
+		assumeIDSet(t, createdRootID, "createdRootID for graph data")
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/graph-data?depth=1", createdRootID), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var graph GraphData
+		err := json.Unmarshal(rr.Body.Bytes(), &graph)
+		assert.NoError(t, err)
+		assert.Len(t, graph.Nodes, 2, "Root and its single child")
+		assert.Len(t, graph.Edges, 1)
+		assert.Equal(t, fmt.Sprintf("%d", createdRootID), graph.Edges[0].Data["source"])
+		assert.Equal(t, fmt.Sprintf("%d", createdChildID), graph.Edges[0].Data["target"])
+
+		reqBad, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/graph-data?depth=-1", createdRootID), nil)
+		rrBad := httptest.NewRecorder()
+		testRouter.ServeHTTP(rrBad, reqBad)
+		assert.Equal(t, http.StatusBadRequest, rrBad.Code)
+	})
+
 	// 6. Update the child component (e.g., change its name and make it a root)
 	t.Run("PUT_UpdateChildComponent", func(t *testing.T) {
 		assumeIDSet(t, createdChildID, "createdChildID for update")
@@ -268,6 +298,530 @@ func TestAPIComponentsFlow(t *testing.T) {
 	})
 }
 
+func TestAPIChildrenFanOutGuard(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	defer func(max int) { MaxUnpaginatedChildren = max }(MaxUnpaginatedChildren)
+	MaxUnpaginatedChildren = 2
+
+	parent := createTestComponentDirectly(t, "FanOutParent", "", sql.NullInt64{Valid: false})
+	for i := 0; i < 3; i++ {
+		createTestComponentDirectly(t, fmt.Sprintf("FanOutChild%d", i), "", sql.NullInt64{Int64: parent.ID, Valid: true})
+	}
+
+	t.Run("Unpaginated_OverLimit", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/children", parent.ID), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "limit=2")
+	})
+
+	t.Run("Paginated", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/children?limit=2", parent.ID), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "3", rr.Header().Get("X-Total-Count"))
+		assert.Contains(t, rr.Header().Get("Link"), "offset=2")
+		var children []*models.Component
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &children))
+		assert.Len(t, children, 2)
+
+		reqLast, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/children?limit=2&offset=2", parent.ID), nil)
+		rrLast := httptest.NewRecorder()
+		testRouter.ServeHTTP(rrLast, reqLast)
+		assert.Equal(t, http.StatusOK, rrLast.Code)
+		assert.Empty(t, rrLast.Header().Get("Link"), "No next page after the last one")
+		assert.NoError(t, json.Unmarshal(rrLast.Body.Bytes(), &children))
+		assert.Len(t, children, 1)
+	})
+
+	t.Run("LimitAboveCap", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/children?limit=3", parent.ID), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAPIGraphDataChildrenPaging(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+
+	root := createTestComponentDirectly(t, "SkewedRoot", "", sql.NullInt64{Valid: false})
+	for i := 0; i < 5; i++ {
+		createTestComponentDirectly(t, fmt.Sprintf("SkewedChild%d", i), "", sql.NullInt64{Int64: root.ID, Valid: true})
+	}
+
+	getGraph := func(query string) (*httptest.ResponseRecorder, GraphData) {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d/graph-data?%s", root.ID, query), nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var graph GraphData
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &graph))
+		}
+		return rr, graph
+	}
+
+	rr, graph := getGraph("depth=1&children_limit=2")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, graph.Edges, 2, "Only the first page of children")
+	assert.Contains(t, graph.Nodes[0].Classes, "truncated")
+	assert.Equal(t, float64(5), graph.Nodes[0].Data["child_count"])
+	seen := map[interface{}]bool{}
+	for _, edge := range graph.Edges {
+		seen[edge.Data["target"]] = true
+	}
+
+	for pages := 1; graph.Nodes[0].Data["children_cursor"] != nil; pages++ {
+		cursor := graph.Nodes[0].Data["children_cursor"].(string)
+		rr, graph = getGraph("depth=1&children_limit=2&children_cursor=" + url.QueryEscape(cursor))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		for _, edge := range graph.Edges {
+			assert.False(t, seen[edge.Data["target"]], "Child returned twice")
+			seen[edge.Data["target"]] = true
+		}
+		if pages > 5 {
+			t.Fatal("Cursor did not terminate")
+		}
+	}
+	assert.Len(t, seen, 5, "Every child across the pages")
+
+	rr, _ = getGraph("children_cursor=" + encodeChildrenCursor(root.ID+1, 2))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Cursor issued for another node")
+	rr, _ = getGraph("children_limit=0")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAPISearchComponents(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	createTestComponentDirectly(t, "Hydraulic pump", "Main unit", sql.NullInt64{Valid: false})
+	createTestComponentDirectly(t, "Valve", "Feeds the pump", sql.NullInt64{Valid: false})
+
+	req, _ := http.NewRequest(http.MethodGet, "/components/search?q=pump", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("X-Search-Backend"))
+	var results []struct {
+		Rank      float64           `json:"rank"`
+		Component *models.Component `json:"component"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "Hydraulic pump", results[0].Component.Name, "A name match ranks above a description match")
+		assert.Greater(t, results[0].Rank, results[1].Rank)
+	}
+
+	for _, query := range []string{"", "q=", "q=pump&limit=0"} {
+		req, _ := http.NewRequest(http.MethodGet, "/components/search?"+query, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestAPIInvalidQueryParameters(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/components?limit=1e9&offset=-1&sort=bogus", nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body invalidParamsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	var names []string
+	for _, p := range body.InvalidParameters {
+		names = append(names, p.Parameter)
+	}
+	assert.Equal(t, []string{"limit", "offset", "sort"}, names)
+}
+
+func TestAPISortComponents(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	parent := createTestComponentDirectly(t, "sort-parent", "", sql.NullInt64{Valid: false})
+	createTestComponentDirectly(t, "sort-b", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+	createTestComponentDirectly(t, "sort-c", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+	createTestComponentDirectly(t, "sort-a", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+
+	names := func(path string) []string {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+		var components []*models.Component
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &components))
+		var result []string
+		for _, comp := range components {
+			result = append(result, comp.Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"sort-a", "sort-b", "sort-c", "sort-parent"}, names("/components?sort=name"))
+	assert.Equal(t, []string{"sort-parent", "sort-c"}, names("/components?sort=name&order=desc&limit=2"))
+	assert.Equal(t, []string{"sort-c", "sort-b", "sort-a"}, names(fmt.Sprintf("/components/%d/children?sort=name&order=desc", parent.ID)))
+	assert.Equal(t, []string{"sort-b"}, names(fmt.Sprintf("/components/%d/children?sort=name&limit=1&offset=1", parent.ID)))
+
+	for _, query := range []string{"sort=bogus", "order=desc", "sort=name&order=up", "sort=name&after="} {
+		req, _ := http.NewRequest(http.MethodGet, "/components?"+query, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestAPISparseFields(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	parent := createTestComponentDirectly(t, "fields-parent", "parent description", sql.NullInt64{Valid: false})
+	createTestComponentDirectly(t, "fields-child", "child description", sql.NullInt64{Int64: parent.ID, Valid: true})
+
+	for _, path := range []string{
+		"/components?fields=name,id",
+		"/components?fields=name,id&after=",
+		fmt.Sprintf("/components/%d?fields=name,id", parent.ID),
+		fmt.Sprintf("/components/%d/children?fields=name,id", parent.ID),
+		"/components/search?q=fields&fields=name,id",
+		"/components/export?fields=name,id",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+		body := rr.Body.String()
+		assert.Contains(t, body, `{"id":`, path)
+		assert.Contains(t, body, `"name":"fields-`, path)
+		assert.NotContains(t, body, "description", path)
+		assert.NotContains(t, body, "created_at", path)
+	}
+
+	for _, query := range []string{"fields=bogus", "fields=id,", "fields="} {
+		req, _ := http.NewRequest(http.MethodGet, "/components?"+query, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		if query == "fields=" {
+			assert.Equal(t, http.StatusOK, rr.Code, query)
+			continue
+		}
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestAPICreateComponentMissingParent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	req, _ := http.NewRequest(http.MethodPost, "/components", bytes.NewBufferString(`{"name": "orphan", "parent_id": {"Int64": 999999, "Valid": true}}`))
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.JSONEq(t, `{"error": "Parent component 999999 not found", "field": "parent_id", "value": 999999}`, rr.Body.String())
+}
+
+func TestAPIDuplicateSiblingName(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	store.UniqueSiblingNames = true
+	defer func() { store.UniqueSiblingNames = false }()
+	createTestComponentDirectly(t, "pump", "", sql.NullInt64{})
+	valve := createTestComponentDirectly(t, "valve", "", sql.NullInt64{})
+
+	for _, tc := range []struct{ method, target, body string }{
+		{http.MethodPost, "/components", `{"name": "pump"}`},
+		{http.MethodPut, fmt.Sprintf("/components/%d", valve.ID), `{"name": "pump"}`},
+		{http.MethodPatch, fmt.Sprintf("/components/%d", valve.ID), `{"name": "pump"}`},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusConflict, rr.Code, tc.method)
+		assert.JSONEq(t, `{"error": "duplicate sibling name: a root component is already named \"pump\"", "code": "duplicate_name"}`, rr.Body.String(), tc.method)
+	}
+}
+
+func TestAPISoftDeleteAndRestore(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	parent := createTestComponentDirectly(t, "restore-parent", "", sql.NullInt64{Valid: false})
+	comp := createTestComponentDirectly(t, "restore-me", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, target, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, fmt.Sprintf("/components/%d/restore", comp.ID)).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, fmt.Sprintf("/components/%d", comp.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, fmt.Sprintf("/components/%d", comp.ID)).Code)
+
+	rr := serve(http.MethodPost, fmt.Sprintf("/components/%d/restore", comp.ID))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var restored models.Component
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &restored))
+	assert.Equal(t, comp.ID, restored.ID)
+	assert.Equal(t, parent.ID, restored.ParentID.Int64)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/components/999999/restore").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/components/x/restore").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, fmt.Sprintf("/components/%d/restore", comp.ID)).Code)
+
+	// A cascade takes the component's subtree with it instead of leaving roots behind.
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodDelete, fmt.Sprintf("/components/%d?cascade=yes", parent.ID)).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, fmt.Sprintf("/components/%d?cascade=true", parent.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, fmt.Sprintf("/components/%d", comp.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, fmt.Sprintf("/components/%d?cascade=true", parent.ID)).Code)
+}
+
+func TestAPIRestoreRestrictedComponent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	root := createTestComponentDirectly(t, "restricted-root", "", sql.NullInt64{Valid: false})
+	require.NoError(t, componentStore.ReplaceComponentACL(root.ID, []cache.ACLEntry{
+		{Principal: "alice", Permission: cache.PermissionWrite},
+		{Principal: cache.EveryonePrincipal, Permission: cache.PermissionRead},
+	}))
+	require.NoError(t, componentStore.DeleteComponent(root.ID))
+
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(testRouter)
+	restore := func(principal string) int {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/restore", root.ID), nil)
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// The cache no longer holds the deleted root's ACL; its persisted entries still apply.
+	assert.Equal(t, http.StatusForbidden, restore("bob"))
+	assert.Equal(t, http.StatusOK, restore("alice"))
+}
+
+func TestAPIPatchComponent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	parent := createTestComponentDirectly(t, "patch-parent", "", sql.NullInt64{Valid: false})
+	comp := createTestComponentDirectly(t, "patch-me", "kept", sql.NullInt64{Int64: parent.ID, Valid: true})
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/components/%d", comp.ID), bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := patch(`{"name": "patched"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var patched models.Component
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &patched))
+	assert.Equal(t, "patched", patched.Name)
+	assert.Equal(t, "kept", patched.Description)
+	assert.Equal(t, parent.ID, patched.ParentID.Int64)
+
+	rr = patch(`{"parent_id": null}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &patched))
+	assert.False(t, patched.ParentID.Valid)
+	assert.Equal(t, "patched", patched.Name)
+
+	rr = patch(fmt.Sprintf(`{"parent_id": %d}`, comp.ID))
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "Expected a component under itself to be refused")
+
+	for _, body := range []string{`{}`, `{"name": ""}`, `{"bogus": 1}`, `not json`} {
+		assert.Equal(t, http.StatusBadRequest, patch(body).Code, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodPatch, "/components/999999", bytes.NewBufferString(`{"name": "x"}`))
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAPIShareLinks(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	shared := createTestComponentDirectly(t, "shared-root", "", sql.NullInt64{Valid: false})
+	inside := createTestComponentDirectly(t, "shared-child", "", sql.NullInt64{Int64: shared.ID, Valid: true})
+	outside := createTestComponentDirectly(t, "not-shared", "", sql.NullInt64{Valid: false})
+	router := ShareLinkHandler(testRouter)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodPost, fmt.Sprintf("/components/%d/share", shared.ID), "")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created struct {
+		ID    int64  `json:"id"`
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Token)
+	prefix := "/shared/" + created.Token
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, created.URL, "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, fmt.Sprintf("%s/components/%d", prefix, inside.ID), "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, fmt.Sprintf("%s/components/%d/children", prefix, shared.ID), "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, fmt.Sprintf("%s/components/%d", prefix, outside.ID), "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, prefix+"/components", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, fmt.Sprintf("%s/components/%d", prefix, inside.ID), "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/shared/bogus/components/1", "").Code)
+
+	rr = serve(http.MethodGet, fmt.Sprintf("/components/%d/share", shared.ID), "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), created.Token, "Listed links must not reveal tokens")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, fmt.Sprintf("/components/%d/share/%d", shared.ID, created.ID), "").Code)
+	assert.Equal(t, http.StatusGone, serve(http.MethodGet, created.URL, "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, fmt.Sprintf("/components/%d/share", shared.ID), `{"expires_at": "2000-01-01T00:00:00Z"}`).Code)
+}
+
+func TestAPIListComponentsPagination(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	for i := 0; i < 3; i++ {
+		createTestComponentDirectly(t, fmt.Sprintf("PagedComp%d", i), "", sql.NullInt64{Valid: false})
+	}
+
+	t.Run("FirstPage", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/components?limit=2", nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "3", rr.Header().Get("X-Total-Count"))
+		assert.Contains(t, rr.Header().Get("Link"), "offset=2")
+		var components []*models.Component
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &components))
+		assert.Len(t, components, 2)
+	})
+
+	t.Run("LastPage", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/components?limit=2&offset=2", nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Link"), "No next page after the last one")
+		var components []*models.Component
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &components))
+		assert.Len(t, components, 1)
+	})
+
+	t.Run("InvalidLimit", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/components?limit=0", nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestAPIListComponentsCursor(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	for i := 0; i < 3; i++ {
+		createTestComponentDirectly(t, fmt.Sprintf("CursorComp%d", i), "", sql.NullInt64{Valid: false})
+	}
+
+	type cursorResponse struct {
+		Components []*models.Component `json:"components"`
+		NextCursor *string             `json:"next_cursor"`
+	}
+	get := func(target string) (int, cursorResponse) {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		var body cursorResponse
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		}
+		return rr.Code, body
+	}
+
+	code, first := get("/components?after=&limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, first.Components, 2)
+	if assert.NotNil(t, first.NextCursor) {
+		createTestComponentDirectly(t, "CursorCompLate", "", sql.NullInt64{Valid: false})
+		code, second := get("/components?limit=2&after=" + *first.NextCursor)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, second.Components, 2, "The remaining original component plus the one created meanwhile")
+		assert.Nil(t, second.NextCursor)
+	}
+
+	code, _ = get("/components?after=garbage")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/components?after=&offset=1")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestAPIListComponentsNameFilter(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	createTestComponentDirectly(t, "Pump", "", sql.NullInt64{Valid: false})
+	createTestComponentDirectly(t, "Backup pump", "", sql.NullInt64{Valid: false})
+	createTestComponentDirectly(t, "100%_Valve", "", sql.NullInt64{Valid: false})
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{query: "name=Pump", expected: 1},
+		{query: "name_contains=pump", expected: 2},
+		{query: "name_contains=" + url.QueryEscape("%_"), expected: 1}, // wildcards match literally
+		{query: "name=Missing", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/components?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, strconv.Itoa(tt.expected), rr.Header().Get("X-Total-Count"))
+			var components []*models.Component
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &components))
+			assert.Len(t, components, tt.expected)
+		})
+	}
+}
+
 // assumeIDSet checks if an ID is non-zero, failing the test if it's zero,
 // as it indicates a setup step (like creation) might have failed.
 func assumeIDSet(t *testing.T, id int64, idName string) {
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"component-service/store"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds an Idempotency-Key, as stored in idempotency_keys.
+const maxIdempotencyKeyLength = 255
+
+// Codes of the 409 and 422 answering a request whose Idempotency-Key cannot be served; see
+// store.ClaimIdempotencyKey.
+const (
+	errorCodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	errorCodeIdempotencyKeyReused     = "idempotency_key_reused"
+)
+
+// replayedHeaders are the response headers recorded for an Idempotency-Key and replayed with the
+// response's status and body.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// idempotent serves r through serve once per Idempotency-Key header: the response to the first
+// request with a key is recorded, and replayed to retries of that request, with an
+// Idempotent-Replayed header, instead of serving them again. A key names one request, so reusing
+// it for a different body is refused with 422. A server error is not recorded, so a retry is
+// served anew. Requests without the header are served as usual. Keys are scoped to the principal
+// under ACL enforcement.
+func idempotent(w http.ResponseWriter, r *http.Request, serve http.HandlerFunc) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		serve(w, r)
+		return
+	}
+	if !validIdempotencyKey(key) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("%s must be 1 to %d printable ASCII characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error reading request body: "+err.Error())
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256([]byte(r.Method + " " + r.URL.RequestURI() + "\n" + string(body)))
+	principal, _ := principalFrom(r)
+
+	recorded, err := componentStore.ClaimIdempotencyKey(key, principal, hex.EncodeToString(sum[:]))
+	switch {
+	case errors.Is(err, store.ErrIdempotencyKeyReused):
+		respondWithJSON(w, http.StatusUnprocessableEntity, codedErrorResponse{Error: err.Error(), Code: errorCodeIdempotencyKeyReused})
+		return
+	case errors.Is(err, store.ErrIdempotencyKeyInProgress):
+		w.Header().Set("Retry-After", "1")
+		respondWithJSON(w, http.StatusConflict, codedErrorResponse{Error: err.Error(), Code: errorCodeIdempotencyKeyInProgress})
+		return
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Error checking idempotency key: "+err.Error())
+		return
+	case recorded != nil:
+		for name, value := range recorded.Header {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(recorded.StatusCode)
+		w.Write(recorded.Body)
+		return
+	}
+
+	response := newBufferedResponse()
+	serve(response, r)
+	if response.status >= http.StatusInternalServerError {
+		if err := componentStore.ReleaseIdempotencyKey(key, principal); err != nil {
+			log.Printf("Releasing idempotency key %q failed: %v", key, err)
+		}
+	} else {
+		header := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := response.header.Get(name); value != "" {
+				header[name] = value
+			}
+		}
+		err := componentStore.CompleteIdempotencyKey(key, principal, &store.IdempotentResponse{StatusCode: response.status, Header: header, Body: response.body.Bytes()})
+		if err != nil {
+			log.Printf("Recording the response of idempotency key %q failed: %v", key, err)
+		}
+	}
+	for name, values := range response.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(response.status)
+	w.Write(response.body.Bytes())
+}
+
+// validIdempotencyKey reports whether key is 1 to maxIdempotencyKeyLength printable ASCII
+// characters.
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
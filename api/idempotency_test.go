@@ -0,0 +1,79 @@
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/db"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidIdempotencyKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"3f2a9c1e-7b4d-4c2a-9e8f-1a2b3c4d5e6f": true,
+		"create pump #7":                       true,
+		"":                                     false,
+		strings.Repeat("k", 256):               false,
+		"tab\tkey":                             false,
+		"clé":                                  false,
+	} {
+		assert.Equal(t, want, validIdempotencyKey(key), "%q", key)
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/components", bytes.NewBufferString(`{"name":"Pump"}`))
+	req.Header.Set(idempotencyKeyHeader, "tab\tkey")
+	ComponentsHandler(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected an invalid key to be refused before anything is created")
+}
+
+func TestAPIIdempotencyKey(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	_, err := db.DB.Exec("DELETE FROM idempotency_keys")
+	require.NoError(t, err)
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/components", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := post("create-pump", `{"name":"Pump"}`)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	retry := post("create-pump", `{"name":"Pump"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+	total, err := testAPIStore.CountComponents(cache.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total, "Expected the retry not to create a second component")
+
+	rr := post("create-pump", `{"name":"Valve"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var body codedErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, errorCodeIdempotencyKeyReused, body.Code)
+
+	// Client errors are replayed too; only server errors are forgotten
+	assert.Equal(t, http.StatusBadRequest, post("no-name", `{"description":"x"}`).Code)
+	rr = post("no-name", `{"description":"x"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+
+	assert.Equal(t, http.StatusCreated, post("", `{"name":"Pump"}`).Code)
+	assert.Equal(t, http.StatusCreated, post("", `{"name":"Pump"}`).Code, "Expected requests without a key to be served every time")
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"component-service/cache"
+	"component-service/models"
+	"component-service/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxImportSize bounds how many components one POST /components/import may create, keeping the
+// transaction short. Larger catalogs are imported in batches, parents first.
+const maxImportSize = 10000
+
+// maxImportSourceLength is the length of component_import_sources.source.
+const maxImportSourceLength = 255
+
+// importResponse is the body of a successful import.
+type importResponse struct {
+	Source  string                `json:"source"`
+	Mapping []store.ImportMapping `json:"mapping"`
+}
+
+// importNode is a component in an import body, as exported or written by hand. Its children can
+// be given inline, nested in children, as well as through their parent_id.
+type importNode struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Tags        []string        `json:"tags"`
+	Description string          `json:"description"`
+	Metadata    json.RawMessage `json:"metadata"`
+	ParentID    json.RawMessage `json:"parent_id"`
+	CreatedAt   string          `json:"created_at"`
+	UpdatedAt   string          `json:"updated_at"`
+	Children    []*importNode   `json:"children"`
+}
+
+// importComponents serves POST /components/import?source=NAME, which copies components exported
+// from another instance (GET /components/export) under new IDs and returns the ID mapping. The
+// body is newline-delimited JSON; parent_id takes the same forms as in PATCH. A line can also be a
+// nested document holding its descendants in children arrays, and the body a JSON array of such
+// documents, as GET /components/tree returns. Components in a nested document may omit their ID.
+func importComponents(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	source := q.str("source")
+	if source == "" || len(source) > maxImportSourceLength {
+		q.reject("source", fmt.Sprintf("a name for the instance the components come from, of up to %d bytes", maxImportSourceLength))
+	}
+	if !q.valid(w) {
+		return
+	}
+	defer r.Body.Close()
+
+	body := bufio.NewReader(r.Body)
+	array := startsJSONArray(body)
+	decoder := json.NewDecoder(body)
+	if array {
+		decoder.Token() // [
+	}
+	flattener := &importFlattener{}
+	for {
+		if array && !decoder.More() {
+			if _, err := decoder.Token(); err != nil { // ]
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request payload at component %d: %v", len(flattener.components), err))
+				return
+			}
+			if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+				respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+errTrailingData.Error())
+				return
+			}
+			break
+		}
+		var node importNode
+		err := decoder.Decode(&node)
+		if !array && errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request payload at component %d: %v", len(flattener.components), err))
+			return
+		}
+		if err := flattener.add(&node, nil, array || len(node.Children) > 0); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	components := flattener.components
+	if len(components) == 0 {
+		respondWithError(w, http.StatusBadRequest, "No components given; send the newline-delimited JSON of GET /components/export")
+		return
+	}
+
+	canAttach := func(parentID int64) bool { return canAccess(r, parentID, cache.PermissionWrite) }
+	mapping, err := writeStore(r).ImportComponents(source, components, canAttach)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidImport), errors.Is(err, store.ErrCycle):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.ErrImportNotPermitted):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error importing components: "+err.Error())
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, importResponse{Source: source, Mapping: mapping})
+}
+
+// importFlattener lists the components of an import body, each nested document top-down, for
+// store.ImportComponents.
+type importFlattener struct {
+	components []*models.Component
+	inlineIDs  int64 // the last ID given to a component without one, counting down from -1
+}
+
+// add lists node and its children. parent is the component node is nested in, or nil at the top
+// level; nested reports whether node is part of a nested document, where the ID may be omitted.
+func (f *importFlattener) add(node *importNode, parent *models.Component, nested bool) error {
+	n := len(f.components)
+	if n == maxImportSize {
+		return fmt.Errorf("Too many components: at most %d per request; import larger catalogs in batches, parents first", maxImportSize)
+	}
+	if node.ID < 0 || (node.ID == 0 && !nested) {
+		return fmt.Errorf("Component %d: id must be the component's ID in the source; only components in a nested document may omit it", n)
+	}
+	if node.Name == "" {
+		return fmt.Errorf("Component %d: name is required", n)
+	}
+	comp := &models.Component{ID: node.ID, Name: node.Name, Slug: node.Slug, Type: node.Type, Status: node.Status, Tags: node.Tags, Description: node.Description, Metadata: node.Metadata, CreatedAt: node.CreatedAt, UpdatedAt: node.UpdatedAt}
+	if comp.ID == 0 {
+		f.inlineIDs--
+		comp.ID = f.inlineIDs
+	}
+	if node.ParentID != nil {
+		parentID, err := parsePatchParentID(node.ParentID)
+		if err != nil {
+			return fmt.Errorf("Component %d: parent_id must be a component ID in the source or null", n)
+		}
+		if parent != nil && (!parentID.Valid || parentID.Int64 != parent.ID) {
+			return fmt.Errorf("Component %d: parent_id must be omitted, or be the ID of the component it is nested in", n)
+		}
+		comp.ParentID = parentID
+	}
+	if parent != nil {
+		comp.ParentID = sql.NullInt64{Int64: parent.ID, Valid: true}
+	}
+	f.components = append(f.components, comp)
+	for _, child := range node.Children {
+		if err := f.add(child, comp, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startsJSONArray reports whether the first value in r, after any white space, is an array. It
+// consumes the white space only.
+func startsJSONArray(r *bufio.Reader) bool {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		default:
+			return b[0] == '['
+		}
+	}
+}
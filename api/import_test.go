@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportComponentsValidation(t *testing.T) {
+	post := func(target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body)))
+		return rr
+	}
+	valid := `{"id": 1, "name": "root"}`
+	assert.Equal(t, http.StatusBadRequest, post("/components/import", valid).Code, "Expected source to be required")
+	assert.Equal(t, http.StatusBadRequest, post("/components/import?source="+strings.Repeat("s", 256), valid).Code)
+	for _, body := range []string{
+		``,
+		`not json`,
+		`{"name": "no id"}`,
+		`{"id": 1}`,
+		`{"id": 1, "name": "a", "parent_id": "two"}`,
+		valid + "\n" + `{"id": 2, "name": "b"` + "\n",
+		`{"id": -1, "name": "negative"}`,
+		`{"name": "a", "children": [{"id": -1, "name": "b"}]}`,
+		`{"name": "a", "children": [{"description": "no name"}]}`,
+		`{"id": 1, "name": "a", "children": [{"id": 2, "name": "b", "parent_id": 3}]}`,
+		`{"id": 1, "name": "a", "children": [{"id": 2, "name": "b", "parent_id": null}]}`,
+		`[{"name": "a"}`,
+		`[{"name": "a"}] {"id": 2, "name": "b"}`,
+		`[]`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, post("/components/import?source=eu", body).Code, body)
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/import?source=eu", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestImportFlattener(t *testing.T) {
+	var nodes []*importNode
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"name": "plant", "children": [
+			{"id": 5, "name": "line", "children": [{"id": 7, "name": "pump", "parent_id": 5}]},
+			{"name": "valve"}
+		]},
+		{"id": 3, "name": "spare", "parent_id": 1}
+	]`), &nodes))
+
+	f := &importFlattener{}
+	for _, node := range nodes {
+		require.NoError(t, f.add(node, nil, true))
+	}
+	var got []string
+	for _, comp := range f.components {
+		parent := "-"
+		if comp.ParentID.Valid {
+			parent = strconv.FormatInt(comp.ParentID.Int64, 10)
+		}
+		got = append(got, fmt.Sprintf("%d %s under %s", comp.ID, comp.Name, parent))
+	}
+	assert.Equal(t, []string{"-1 plant under -", "5 line under -1", "7 pump under 5", "-2 valve under -1", "3 spare under 1"}, got,
+		"Expected every component after its parent, with IDs given to those without one")
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// mergeComponent serves POST /components/{id}/merge, which folds a duplicate into the component
+// kept: {"into_id": ...} receives the component's children, after its own, and the component is
+// then deleted, in one transaction. The merge is checked as POST /components/{id}/simulate
+// previews it, and needs write on both components. The component merged into is returned.
+func mergeComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body struct {
+		IntoID *int64 `json:"into_id"`
+	}
+	if err := decodeBody(r, &body, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if body.IntoID == nil {
+		respondWithError(w, http.StatusBadRequest, "into_id is required: the component that receives the children")
+		return
+	}
+	intoID := *body.IntoID
+	if !canAccess(r, intoID, cache.PermissionWrite) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Merging a component into component %d requires write permission on it", intoID))
+		return
+	}
+	// The cached tree refuses a merge the preview would refuse, with the same error; the store
+	// checks again inside its transaction.
+	if cache.GlobalComponentCache != nil {
+		_, err := cache.GlobalComponentCache.Simulate(id, cache.Simulation{Operation: cache.OperationMerge, IntoID: intoID})
+		if errors.Is(err, cache.ErrInvalidSimulation) {
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+
+	if err := writeStore(r).MergeComponent(id, intoID); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, store.ErrCycle):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.ErrDuplicateName):
+			respondWithDuplicateName(w, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error merging components: "+err.Error())
+		}
+		return
+	}
+	into, err := componentStore.GetComponentByID(intoID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error fetching merged component: "+err.Error())
+		return
+	}
+	respondWithComponent(w, http.StatusOK, into)
+}
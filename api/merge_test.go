@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"component-service/store"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeComponentValidation(t *testing.T) {
+	for _, body := range []string{
+		`{}`,
+		`{"into_id": "two"}`,
+		`{"into_id": 2, "operation": "merge"}`,
+		`[{"into_id": 2}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/components/1/merge", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, "/components/one/merge", bytes.NewBufferString(`{"into_id": 2}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/1/merge", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestMergeComponentChecks(t *testing.T) {
+	// 1 -> 2 -> 3; alice may write 1's subtree, bob only 2's.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "pump", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+			{ID: 3, Name: "valve", ParentID: sql.NullInt64{Int64: 2, Valid: true}},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 1, Principal: "alice", Permission: cache.PermissionWrite},
+			{ComponentID: 1, Principal: cache.EveryonePrincipal, Permission: cache.PermissionRead},
+			{ComponentID: 2, Principal: "bob", Permission: cache.PermissionWrite},
+		},
+	})
+	require.NoError(t, err)
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	merge := func(id, intoID int64, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/merge", id), bytes.NewBufferString(fmt.Sprintf(`{"into_id": %d}`, intoID)))
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusForbidden, merge(1, 2, "bob").Code, "Expected write on the merged component")
+	assert.Equal(t, http.StatusForbidden, merge(2, 1, "bob").Code, "Expected write on the component merged into")
+	rr := merge(2, 2, "alice")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cannot be merged into itself")
+	rr = merge(2, 3, "alice")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "Expected a target below the component to be refused as the preview refuses it")
+	assert.Contains(t, rr.Body.String(), "one of its descendants")
+}
+
+func TestMergeComponent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	kept := createTestComponentDirectly(t, "Boiler feed pump", "", sql.NullInt64{})
+	duplicate := createTestComponentDirectly(t, "Boiler Feed Pump", "", sql.NullInt64{})
+	child := createTestComponentDirectly(t, "Impeller", "", sql.NullInt64{Int64: duplicate.ID, Valid: true})
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/merge", duplicate.ID), bytes.NewBufferString(fmt.Sprintf(`{"into_id": %d}`, kept.ID))))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), fmt.Sprintf(`"id":%d`, kept.ID))
+
+	moved, err := componentStore.GetComponentByID(child.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullInt64{Int64: kept.ID, Valid: true}, moved.ParentID)
+	_, err = componentStore.GetComponentByID(duplicate.ID)
+	assert.Error(t, err, "Expected the duplicate to be deleted")
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/merge", duplicate.ID), bytes.NewBufferString(fmt.Sprintf(`{"into_id": %d}`, kept.ID))))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestMergeComponentDuplicateName(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	store.UniqueSiblingNames = true
+	defer func() { store.UniqueSiblingNames = false }()
+	kept := createTestComponentDirectly(t, "Boiler feed pump", "", sql.NullInt64{})
+	createTestComponentDirectly(t, "Impeller", "", sql.NullInt64{Int64: kept.ID, Valid: true})
+	duplicate := createTestComponentDirectly(t, "Boiler Feed Pump", "", sql.NullInt64{})
+	createTestComponentDirectly(t, "Impeller", "", sql.NullInt64{Int64: duplicate.ID, Valid: true})
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/merge", duplicate.ID), bytes.NewBufferString(fmt.Sprintf(`{"into_id": %d}`, kept.ID))))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"error": "duplicate sibling name: component %d already has a child named \"Impeller\"", "code": "duplicate_name"}`, kept.ID), rr.Body.String())
+}
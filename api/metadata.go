@@ -0,0 +1,48 @@
+package api
+
+import (
+	"component-service/cache"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// metadataParamPrefix starts the ?metadata.<key>=<value> parameters, each keeping only the
+// components whose metadata holds value at the top-level key.
+const metadataParamPrefix = "metadata."
+
+// parseMetadataFilter reads every ?metadata.<key>= parameter, sorted by key. A value matches a
+// string as it reads, and a number or boolean as written in the metadata; objects, arrays and null
+// never match.
+func parseMetadataFilter(q *queryParams) []cache.MetadataMatch {
+	q.known[metadataParamPrefix+"<key>"] = true // named among the accepted parameters
+	var matches []cache.MetadataMatch
+	for name, values := range q.values {
+		if !strings.HasPrefix(name, metadataParamPrefix) {
+			continue
+		}
+		q.known[name] = true
+		key := strings.TrimPrefix(name, metadataParamPrefix)
+		if key == "" {
+			q.reject(name, "a metadata key after metadata., as in metadata.vendor=acme")
+			continue
+		}
+		for _, value := range values {
+			matches = append(matches, cache.MetadataMatch{Key: key, Value: value})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Key != matches[j].Key {
+			return matches[i].Key < matches[j].Key
+		}
+		return matches[i].Value < matches[j].Value
+	})
+	return matches
+}
+
+// respondWithInvalidMetadata sends the 422 for a write failing with store.ErrInvalidMetadata,
+// naming the field and the metadata given.
+func respondWithInvalidMetadata(w http.ResponseWriter, err error, metadata json.RawMessage) {
+	respondWithJSON(w, http.StatusUnprocessableEntity, invalidFieldResponse{Error: err.Error(), Field: "metadata", Value: metadata})
+}
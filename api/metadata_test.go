@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"component-service/fixtures"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListByMetadata(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "plant")
+	ids := func(body []byte) []int64 {
+		var components []struct{ ID int64 }
+		require.NoError(t, json.Unmarshal(body, &components))
+		var ids []int64
+		for _, comp := range components {
+			ids = append(ids, comp.ID)
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		target string
+		want   []int64
+	}{
+		{"/components?metadata.vendor=acme&sort=name", []int64{w.ID("pump-a"), w.ID("valve")}},
+		{"/components?metadata.vendor=acme&metadata.rated_kw=7.5", []int64{w.ID("pump-a")}},
+		{"/components?metadata.rated_kw=11&name=pump", []int64{w.ID("pump-b")}},
+		{"/components?metadata.vendor=ACME", nil},
+		{"/components?metadata.vendor=acme&metadata.vendor=flowco", nil},
+		{fmt.Sprintf("/components/%d/children?metadata.vendor=acme", w.ID("line-a")), []int64{w.ID("pump-a"), w.ID("valve")}},
+		{fmt.Sprintf("/components/%d/descendants?metadata.vendor=flowco", w.ID("plant")), []int64{w.ID("pump-b")}},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if assert.Equal(t, http.StatusOK, rr.Code, "%s: %s", tc.target, rr.Body.String()) {
+			assert.Equal(t, tc.want, ids(rr.Body.Bytes()), tc.target)
+			assert.Equal(t, fmt.Sprint(len(tc.want)), rr.Header().Get("X-Total-Count"), tc.target)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components?metadata.=acme", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"parameter":"metadata."`)
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components?vendor=acme", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "metadata.\\u003ckey\\u003e", "Expected the metadata parameters among the accepted ones")
+}
+
+func TestWriteInvalidMetadata(t *testing.T) {
+	fixtures.MustLoadCache(t, "plant")
+
+	for _, tc := range []struct{ method, target, body string }{
+		{http.MethodPost, "/components", `{"name": "pump", "metadata": ["acme"]}`},
+		{http.MethodPut, "/components/2", `{"name": "pump", "metadata": ["acme"]}`},
+		{http.MethodPatch, "/components/2", `{"metadata": ["acme"]}`},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, tc.method)
+		assert.JSONEq(t, `{"error": "invalid metadata: metadata must be a JSON object", "field": "metadata", "value": ["acme"]}`, rr.Body.String(), tc.method)
+	}
+}
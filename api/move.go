@@ -0,0 +1,141 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"component-service/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxMoveBatchSize bounds how many components one POST /components/move may reparent, keeping
+// the transaction and its row locks short.
+const maxMoveBatchSize = 1000
+
+// moveComponents serves POST /components/move, which reparents a batch of components atomically.
+// The body is an array of {"id": ..., "new_parent_id": ...}; new_parent_id takes the same forms
+// as parent_id in PATCH. The moved components are returned in request order.
+func moveComponents(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body []struct {
+		ID          int64           `json:"id"`
+		NewParentID json.RawMessage `json:"new_parent_id"`
+	}
+	if err := decodeBody(r, &body, false); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if len(body) == 0 {
+		respondWithError(w, http.StatusBadRequest, "No moves given; send an array of {\"id\", \"new_parent_id\"}")
+		return
+	}
+	if len(body) > maxMoveBatchSize {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Too many moves: at most %d per request", maxMoveBatchSize))
+		return
+	}
+
+	moves := make([]models.ComponentMove, 0, len(body))
+	for i, entry := range body {
+		if entry.ID <= 0 {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Move %d: id must be a component ID", i))
+			return
+		}
+		if entry.NewParentID == nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Move %d: new_parent_id is required; use null to make the component a root", i))
+			return
+		}
+		parentID, err := parsePatchParentID(entry.NewParentID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Move %d: new_parent_id must be a component ID or null", i))
+			return
+		}
+		if !canAccess(r, entry.ID, cache.PermissionWrite) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("Moving component %d requires write permission on it", entry.ID))
+			return
+		}
+		if parentID.Valid && !canAccess(r, parentID.Int64, cache.PermissionWrite) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("Moving a component under component %d requires write permission on it", parentID.Int64))
+			return
+		}
+		moves = append(moves, models.ComponentMove{ID: entry.ID, NewParentID: parentID})
+	}
+
+	if err := writeStore(r).MoveComponents(moves); err != nil {
+		respondWithMoveError(w, err)
+		return
+	}
+	moved := make([]*models.Component, 0, len(moves))
+	for _, move := range moves {
+		comp, err := componentStore.GetComponentByID(move.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error fetching moved component: "+err.Error())
+			return
+		}
+		moved = append(moved, comp)
+	}
+	respondWithJSON(w, http.StatusOK, moved)
+}
+
+// moveComponent serves POST /components/{id}/move, which reparents a single component. The body is
+// {"new_parent_id": ...}, taking the same forms as in the batch move. The move is checked like a
+// batch of one: the new parent must exist and must not be the component or one of its
+// descendants. The moved component is returned.
+func moveComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body struct {
+		NewParentID json.RawMessage `json:"new_parent_id"`
+	}
+	if err := decodeBody(r, &body, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if body.NewParentID == nil {
+		respondWithError(w, http.StatusBadRequest, "new_parent_id is required; use null to make the component a root")
+		return
+	}
+	parentID, err := parsePatchParentID(body.NewParentID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "new_parent_id must be a component ID or null")
+		return
+	}
+	if parentID.Valid && !canAccess(r, parentID.Int64, cache.PermissionWrite) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Moving a component under component %d requires write permission on it", parentID.Int64))
+		return
+	}
+
+	if err := writeStore(r).MoveComponents([]models.ComponentMove{{ID: id, NewParentID: parentID}}); err != nil {
+		respondWithMoveError(w, err)
+		return
+	}
+	moved, err := componentStore.GetComponentByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error fetching moved component: "+err.Error())
+		return
+	}
+	respondWithComponent(w, http.StatusOK, moved)
+}
+
+// respondWithMoveError maps an error of ComponentStore.MoveComponents to a response: 404 for a
+// missing component or new parent, 422 for a move that would form a cycle, and 400 for a
+// component moved twice.
+func respondWithMoveError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, store.ErrCycle):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case strings.Contains(err.Error(), "more than once"):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error moving components: "+err.Error())
+	}
+}
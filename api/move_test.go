@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveComponentsValidation(t *testing.T) {
+	for _, body := range []string{
+		`[]`,
+		`{"id": 1, "new_parent_id": 2}`,
+		`[{"id": 1}]`,
+		`[{"new_parent_id": 2}]`,
+		`[{"id": 1, "new_parent_id": "two"}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/components/move", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/move", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestMoveComponentValidation(t *testing.T) {
+	for _, body := range []string{
+		`{}`,
+		`{"new_parent_id": "two"}`,
+		`{"new_parent_id": 2, "id": 3}`,
+		`[{"id": 1, "new_parent_id": 2}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/components/1/move", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, "/components/one/move", bytes.NewBufferString(`{"new_parent_id": 2}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/1/move", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
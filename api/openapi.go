@@ -0,0 +1,379 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// swaggerUIVersion is the Swagger UI release /docs loads from swaggerUIBase.
+const (
+	swaggerUIVersion = "5.17.14"
+	swaggerUIBase    = "https://unpkg.com/swagger-ui-dist@" + swaggerUIVersion
+)
+
+// apiOperation describes one endpoint in the OpenAPI document served at /openapi.json.
+type apiOperation struct {
+	method, path string
+	tag, summary string
+	query        []string // the query parameters it accepts, all optional unless also in required
+	required     []string
+	body         string // the schema of its JSON request body, if it takes one
+	rawBody      string // the media type of a request body in another format, such as text/csv
+	status       int    // its success status
+	response     string // the schema of its success response, if JSON
+}
+
+// Schemas of request and response bodies, under components/schemas.
+const (
+	schemaComponent  = "Component"
+	schemaComponents = "ComponentList"
+	schemaPatch      = "ComponentPatch"
+	schemaWatch      = "Watch"
+	schemaObject     = "object" // any JSON object, not described further
+)
+
+// Query parameters shared by the component reads; see queryParamDescriptions.
+var (
+	readQuery = []string{"fields", "include", "render"}
+	listQuery = []string{"limit", "offset", "type", "tag", "sort", "order", "fields", "include", "render"}
+)
+
+// apiOperations lists every endpoint, in the order of the README's API Endpoints.
+var apiOperations = []apiOperation{
+	{method: http.MethodPost, path: "/components", tag: "Components", summary: "Create a component", body: schemaComponent, status: http.StatusCreated, response: schemaComponent},
+	{method: http.MethodGet, path: "/components/{id}", tag: "Components", summary: "Get a component by ID", query: readQuery, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodGet, path: "/components/by-path", tag: "Components", summary: "Get a component by its path of names", query: []string{"path", "strict", "fields", "include", "render"}, required: []string{"path"}, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodGet, path: "/components/slug/{slug}", tag: "Components", summary: "Get a component by slug", query: readQuery, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPut, path: "/components/{id}", tag: "Components", summary: "Replace a component", body: schemaComponent, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPatch, path: "/components/{id}", tag: "Components", summary: "Change some of a component's fields", body: schemaPatch, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPost, path: "/components/{id}/move", tag: "Components", summary: "Reparent a component with its subtree", body: schemaObject, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPost, path: "/components/move", tag: "Components", summary: "Reparent several components at once", body: schemaObject, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/reorder", tag: "Components", summary: "Move a component among its siblings", body: schemaObject, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPost, path: "/components/{id}/status", tag: "Components", summary: "Change a component's lifecycle status", body: schemaObject, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodDelete, path: "/components/{id}", tag: "Components", summary: "Delete a component, or its subtree with cascade", query: []string{"cascade"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/restore", tag: "Components", summary: "Restore a deleted component", status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPost, path: "/components/{id}/merge", tag: "Components", summary: "Merge a duplicate into another component", body: schemaObject, status: http.StatusOK, response: schemaComponent},
+	{method: http.MethodPost, path: "/components/{id}/simulate", tag: "Components", summary: "Preview a move, delete or merge without applying it", body: schemaObject, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components", tag: "Listing", summary: "List components", query: append([]string{"after", "name", "name_contains"}, listQuery...), status: http.StatusOK, response: schemaComponents},
+	{method: http.MethodGet, path: "/components/search", tag: "Listing", summary: "Search component names and descriptions", query: []string{"q", "limit", "tag", "fields"}, required: []string{"q"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/children", tag: "Listing", summary: "List a component's children", query: listQuery, status: http.StatusOK, response: schemaComponents},
+	{method: http.MethodGet, path: "/components/{id}/descendants", tag: "Listing", summary: "List a component's descendants", query: []string{"depth", "limit", "offset", "type", "tag", "fields", "include", "render"}, status: http.StatusOK, response: schemaComponents},
+	{method: http.MethodGet, path: "/components/{id}/backlinks", tag: "Listing", summary: "List the components referring to a component", query: []string{"fields"}, status: http.StatusOK, response: schemaComponents},
+	{method: http.MethodGet, path: "/components/tree", tag: "Listing", summary: "Get every root with its subtree", query: []string{"depth", "include"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/tree", tag: "Listing", summary: "Get a component with its subtree nested", query: []string{"depth", "include"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/checksum", tag: "Listing", summary: "Get a hash of a component's subtree", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/graph-data", tag: "Listing", summary: "Get a component's neighbourhood as graph nodes and edges", query: []string{"depth", "children_limit", "children_cursor", "relation_depth"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/export", tag: "Import and export", summary: "Export every component as newline-delimited JSON", query: []string{"batch_size", "fields"}, status: http.StatusOK},
+	{method: http.MethodPost, path: "/components/import", tag: "Import and export", summary: "Import components exported from another instance", query: []string{"source"}, required: []string{"source"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/children/import", tag: "Import and export", summary: "Create a child for every row of a CSV", rawBody: "text/csv", status: http.StatusCreated, response: schemaObject},
+	{method: http.MethodGet, path: "/components/flat", tag: "Import and export", summary: "Get every component with its ancestry, as JSON or CSV", query: []string{"format"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/acl", tag: "Access", summary: "Get a component's access control list", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPut, path: "/components/{id}/acl", tag: "Access", summary: "Replace a component's access control list", body: schemaObject, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/share", tag: "Access", summary: "Create a read-only share link to a subtree", body: schemaObject, status: http.StatusCreated, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/share", tag: "Access", summary: "List a component's share links", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodDelete, path: "/components/{id}/share/{linkID}", tag: "Access", summary: "Revoke a share link", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/visibility", tag: "Access", summary: "Get whether a component is public", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPut, path: "/components/{id}/visibility", tag: "Access", summary: "Make a component public or private", body: schemaObject, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/attachments", tag: "Attachments and comments", summary: "Upload an attachment", rawBody: "multipart/form-data", status: http.StatusCreated, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/attachments", tag: "Attachments and comments", summary: "List a component's attachments", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/attachments/{attachmentID}", tag: "Attachments and comments", summary: "Download an attachment", status: http.StatusOK},
+	{method: http.MethodDelete, path: "/components/{id}/attachments/{attachmentID}", tag: "Attachments and comments", summary: "Remove an attachment", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/comments", tag: "Attachments and comments", summary: "Comment on a component", body: schemaObject, status: http.StatusCreated, response: schemaObject},
+	{method: http.MethodGet, path: "/components/{id}/comments", tag: "Attachments and comments", summary: "List a component's comments", query: []string{"limit", "offset"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodDelete, path: "/components/{id}/comments/{commentID}", tag: "Attachments and comments", summary: "Remove a comment", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/components/{id}/watch", tag: "Watches", summary: "Watch a component, or its subtree", body: schemaObject, status: http.StatusCreated, response: schemaWatch},
+	{method: http.MethodDelete, path: "/components/{id}/watch", tag: "Watches", summary: "Stop watching a component", status: http.StatusOK, response: schemaWatch},
+	{method: http.MethodGet, path: "/watches", tag: "Watches", summary: "List the caller's watches with the components changed since a time", query: []string{"since", "limit"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/components/watch", tag: "Watches", summary: "Upgrade to a WebSocket receiving every component change", query: []string{"schema_version", "signed"}, status: http.StatusSwitchingProtocols},
+	{method: http.MethodGet, path: "/components/events", tag: "Watches", summary: "Stream every component change as Server-Sent Events", query: []string{"last_event_id", "schema_version", "signed"}, status: http.StatusOK},
+	{method: http.MethodGet, path: "/.well-known/jwks.json", tag: "Watches", summary: "Get the public keys that signed events are verified with", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/schemas", tag: "Watches", summary: "List the JSON Schemas of the events sent out", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/schemas/v{version}/{type}.json", tag: "Watches", summary: "Get the JSON Schema of an event type in a schema version", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/sync/checkpoint", tag: "Sync", summary: "Take a checkpoint to sync from", query: []string{"include"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/sync/delta", tag: "Sync", summary: "Get the changes since a checkpoint", query: []string{"since"}, required: []string{"since"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/federation/mounts", tag: "Federation", summary: "List the subtrees mounted from other instances", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/federation/mounts/{name}/tree", tag: "Federation", summary: "Get a mounted subtree", query: []string{"depth"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/attribute-schemas", tag: "Attribute schemas", summary: "List the attribute schemas of component types", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/attribute-schemas/{type}", tag: "Attribute schemas", summary: "Get a type's attribute schema", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPut, path: "/attribute-schemas/{type}", tag: "Attribute schemas", summary: "Replace a type's attribute schema", body: schemaObject, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodDelete, path: "/attribute-schemas/{type}", tag: "Attribute schemas", summary: "Delete a type's attribute schema", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/attribute-schemas/{type}/violations", tag: "Attribute schemas", summary: "List components breaking a type's attribute schema, checked strictly", query: []string{"limit"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/diagnostics/indexes", tag: "Admin", summary: "Check the database indexes", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/debug/cache/memory", tag: "Admin", summary: "Estimate the cache's memory use", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/debug/cache/snapshot", tag: "Admin", summary: "Dump the cache", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/search/reindex", tag: "Admin", summary: "Rebuild the search index in the background", query: []string{"batch_size"}, status: http.StatusAccepted, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/reporting/refresh", tag: "Admin", summary: "Refresh the reporting views in the background", status: http.StatusAccepted, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/closure/check", tag: "Admin", summary: "Compare the closure table with the parent links", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/closure/rebuild", tag: "Admin", summary: "Rebuild the closure table in the background", status: http.StatusAccepted, response: schemaObject},
+	{method: http.MethodDelete, path: "/admin/components/{id}", tag: "Admin", summary: "Purge a deleted component for good", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/jobs/{jobID}", tag: "Admin", summary: "Get a background job's progress", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/unreferenced", tag: "Admin", summary: "Report idle leaf components nothing refers to", query: []string{"idle_days", "limit"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/unreferenced", tag: "Admin", summary: "Report idle leaf components nothing refers to, adding the tag to each", query: []string{"idle_days", "limit", "tag"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/duplicates/scan", tag: "Admin", summary: "Look for likely duplicate components in the background", query: []string{"min_similarity", "keys"}, status: http.StatusAccepted, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/duplicates", tag: "Admin", summary: "List the likely duplicates the last scan found", query: []string{"limit", "offset"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/webhooks", tag: "Admin", summary: "List the webhooks", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/webhooks", tag: "Admin", summary: "Register a webhook", body: schemaObject, status: http.StatusCreated, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/webhooks/{id}", tag: "Admin", summary: "Get a webhook", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPut, path: "/admin/webhooks/{id}", tag: "Admin", summary: "Replace a webhook's URL, secret and events", body: schemaObject, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodDelete, path: "/admin/webhooks/{id}", tag: "Admin", summary: "Delete a webhook with its deliveries", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/webhooks/{id}/deliveries", tag: "Admin", summary: "List a webhook's delivery attempts", query: []string{"limit", "offset"}, status: http.StatusOK, response: schemaObject},
+	{method: http.MethodPost, path: "/admin/webhooks/{id}/deliveries/{deliveryID}/replay", tag: "Admin", summary: "Send a delivery's payload to its webhook again", status: http.StatusAccepted, response: schemaObject},
+	{method: http.MethodGet, path: "/admin/slo", tag: "Admin", summary: "Report the service level objectives", status: http.StatusOK, response: schemaObject},
+	{method: http.MethodGet, path: "/readyz", tag: "Admin", summary: "Report readiness, with the cache's state", status: http.StatusOK, response: schemaObject},
+}
+
+// queryParamDescriptions describes the query parameters apiOperations name. Those listed in
+// integerQueryParams take integers; the rest take strings.
+var queryParamDescriptions = map[string]string{
+	"after":           "Cursor paging: empty for the first page, then the previous page's next_cursor",
+	"batch_size":      "Rows fetched or refreshed per round trip",
+	"cascade":         "true to delete the whole subtree",
+	"children_cursor": "Continues the focus component's children from a previous response",
+	"children_limit":  "Children included per node",
+	"depth":           "Number of levels to return",
+	"fields":          "Comma-separated component fields to include",
+	"format":          "json or csv",
+	"idle_days":       "Days without a create, update or view for a component to count as idle",
+	"include":         "computed adds computed fields; on trees, mounts nests mounted subtrees; on checkpoints, components returns every component",
+	"last_event_id":   "The ID of the last event received, to resume after; the Last-Event-ID header takes precedence",
+	"keys":            "Comma-separated metadata keys holding identifiers from other systems",
+	"limit":           "Page size",
+	"min_similarity":  "Percentage of trigrams two names must share to be flagged",
+	"name":            "Only components with exactly this name",
+	"name_contains":   "Only components whose name contains this text, ignoring case",
+	"offset":          "Number of results to skip",
+	"order":           "asc or desc; requires sort",
+	"path":            "The names on the component's path from the roots, each preceded by /",
+	"q":               "Words to search for",
+	"relation_depth":  "Hops along links between components to follow from the subtree",
+	"render":          "html adds description_html, the description rendered from Markdown and sanitized",
+	"schema_version":  "The event schema version to send, listed at /schemas; the current one by default",
+	"since":           "The time or checkpoint to report changes since",
+	"signed":          "true to send each event as a JWS signed with a key of /.well-known/jwks.json",
+	"sort":            "name, created_at or updated_at",
+	"source":          "The name of the instance the components come from",
+	"strict":          "true to reject paths through siblings sharing a name",
+	"tag":             "Only components having this tag",
+	"type":            "Only components of this type",
+}
+
+var integerQueryParams = map[string]bool{
+	"batch_size": true, "children_limit": true, "depth": true, "idle_days": true, "limit": true, "min_similarity": true, "offset": true, "relation_depth": true,
+	"schema_version": true,
+}
+
+// stringPathParams are the path parameters that are not integer IDs.
+var stringPathParams = map[string]bool{"slug": true, "type": true, "name": true, "jobID": true}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPISchemas are the schemas apiOperations refer to by name.
+var openAPISchemas = map[string]interface{}{
+	"NullInt64": map[string]interface{}{
+		"type":        "object",
+		"description": "A nullable ID; Valid is false for none",
+		"properties": map[string]interface{}{
+			"Int64": map[string]interface{}{"type": "integer", "format": "int64"},
+			"Valid": map[string]interface{}{"type": "boolean"},
+		},
+	},
+	schemaComponent: map[string]interface{}{
+		"type":     "object",
+		"required": []string{"name"},
+		"properties": map[string]interface{}{
+			"id":          map[string]interface{}{"type": "integer", "format": "int64", "readOnly": true},
+			"name":        map[string]interface{}{"type": "string"},
+			"slug":        map[string]interface{}{"type": "string", "readOnly": true},
+			"type":        map[string]interface{}{"type": "string"},
+			"status":      map[string]interface{}{"type": "string"},
+			"tags":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"description": map[string]interface{}{"type": "string", "description": "Markdown"},
+			"metadata":    map[string]interface{}{"type": "object"},
+			"parent_id":   map[string]interface{}{"$ref": "#/components/schemas/NullInt64"},
+			"position":    map[string]interface{}{"type": "integer", "format": "int64", "readOnly": true},
+			"version":     map[string]interface{}{"type": "integer", "format": "int64"},
+			"created_at":  map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true},
+			"updated_at":  map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true},
+		},
+	},
+	schemaComponents: map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": "#/components/schemas/" + schemaComponent},
+	},
+	schemaPatch: map[string]interface{}{
+		"type":        "object",
+		"description": "Fields left out are unchanged; see the README's Patch Component",
+		"properties": map[string]interface{}{
+			"name":        map[string]interface{}{"type": "string"},
+			"type":        map[string]interface{}{"type": "string"},
+			"description": map[string]interface{}{"type": "string"},
+			"metadata":    map[string]interface{}{"type": "object", "nullable": true},
+			"parent_id":   map[string]interface{}{"type": "integer", "format": "int64", "nullable": true},
+			"tags":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"add_tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"remove_tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	},
+	schemaWatch: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"component_id": map[string]interface{}{"type": "integer", "format": "int64"},
+			"principal":    map[string]interface{}{"type": "string"},
+			"subtree":      map[string]interface{}{"type": "boolean"},
+			"created_at":   map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+	},
+	"Error": map[string]interface{}{
+		"type":     "object",
+		"required": []string{"error"},
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{"type": "string"},
+			"code":  map[string]interface{}{"type": "string"},
+		},
+	},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]interface{}
+)
+
+// openAPIDocument returns the OpenAPI 3 document describing apiOperations, built once.
+func openAPIDocument() map[string]interface{} {
+	openAPIOnce.Do(func() {
+		paths := map[string]interface{}{}
+		for _, op := range apiOperations {
+			item, _ := paths[op.path].(map[string]interface{})
+			if item == nil {
+				item = map[string]interface{}{}
+				paths[op.path] = item
+			}
+			item[strings.ToLower(op.method)] = op.document()
+		}
+		openAPIDoc = map[string]interface{}{
+			"openapi": "3.0.3",
+			"info": map[string]interface{}{
+				"title":       "Component Service",
+				"version":     "1.0",
+				"description": "Hierarchical components. See the README for the behaviour of each endpoint.",
+			},
+			"paths":      paths,
+			"components": map[string]interface{}{"schemas": openAPISchemas},
+		}
+	})
+	return openAPIDoc
+}
+
+// document returns the operation object of op.
+func (op apiOperation) document() map[string]interface{} {
+	var params []interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.path, -1) {
+		schema := map[string]interface{}{"type": "integer", "format": "int64"}
+		if stringPathParams[match[1]] {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		params = append(params, map[string]interface{}{"name": match[1], "in": "path", "required": true, "schema": schema})
+	}
+	required := map[string]bool{}
+	for _, name := range op.required {
+		required[name] = true
+	}
+	for _, name := range op.query {
+		schema := map[string]interface{}{"type": "string"}
+		if integerQueryParams[name] {
+			schema = map[string]interface{}{"type": "integer"}
+		}
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "query", "required": required[name],
+			"description": queryParamDescriptions[name], "schema": schema,
+		})
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(op.status)}
+	if op.response != "" {
+		success["content"] = bodyContent(op.response)
+	}
+	doc := map[string]interface{}{
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"operationId": operationID(op.method, op.path),
+		"responses": map[string]interface{}{
+			strconv.Itoa(op.status): success,
+			"default":               map[string]interface{}{"description": "An error", "content": bodyContent("Error")},
+		},
+	}
+	if len(params) > 0 {
+		doc["parameters"] = params
+	}
+	if op.body != "" {
+		doc["requestBody"] = map[string]interface{}{"required": true, "content": bodyContent(op.body)}
+	}
+	if op.rawBody != "" {
+		doc["requestBody"] = map[string]interface{}{"required": true, "content": map[string]interface{}{
+			op.rawBody: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}}
+	}
+	return doc
+}
+
+// jsonContent returns the content of a JSON body of the given schema.
+func bodyContent(schema string) map[string]interface{} {
+	s := map[string]interface{}{"type": "object"}
+	if schema != schemaObject {
+		s = map[string]interface{}{"$ref": "#/components/schemas/" + schema}
+	}
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": s}}
+}
+
+// operationID derives an operation's ID from its method and path, as in getComponentsIdChildren.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '{' || r == '}' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// OpenAPIHandler serves the OpenAPI document at GET /openapi.json.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, openAPIDocument())
+}
+
+// docsPage is the Swagger UI page at /docs, which browses /openapi.json.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Component Service API</title>
+<link rel="stylesheet" href="` + swaggerUIBase + `/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="` + swaggerUIBase + `/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+  window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`
+
+// DocsHandler serves the Swagger UI page at GET /docs. The page loads Swagger UI itself from
+// swaggerUIBase, so browsing it needs access to that CDN.
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
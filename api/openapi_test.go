@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	rr := httptest.NewRecorder()
+	OpenAPIHandler(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name, In string
+				Required bool
+			} `json:"parameters"`
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	ids := map[string]bool{}
+	for path, item := range doc.Paths {
+		placeholders := pathParamPattern.FindAllStringSubmatch(path, -1)
+		for method, op := range item {
+			assert.False(t, ids[op.OperationID], "%s %s: operation IDs are unique", method, path)
+			ids[op.OperationID] = true
+			assert.Contains(t, op.Responses, "default", "%s %s", method, path)
+			var inPath []string
+			for _, param := range op.Parameters {
+				if param.In == "path" {
+					assert.True(t, param.Required, "%s %s: path parameter %s", method, path, param.Name)
+					inPath = append(inPath, param.Name)
+				}
+			}
+			assert.Len(t, inPath, len(placeholders), "%s %s: every placeholder is a parameter", method, path)
+		}
+	}
+	assert.Len(t, ids, len(apiOperations))
+
+	refs := regexp.MustCompile(`"#/components/schemas/(\w+)"`).FindAllStringSubmatch(rr.Body.String(), -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components.Schemas, ref[1], "referenced schemas are defined")
+	}
+}
+
+// TestOpenAPIDocumentCoversReadme keeps the document in step with the endpoints the README lists.
+func TestOpenAPIDocumentCoversReadme(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	require.NoError(t, err)
+	documented := map[string]bool{}
+	for _, op := range apiOperations {
+		documented[op.method+" "+pathParamPattern.ReplaceAllString(op.path, "{}")] = true
+	}
+	endpoints := regexp.MustCompile("\\*\\*Endpoint:\\*\\* `([A-Z]+) ([^`?]+)").FindAllStringSubmatch(string(readme), -1)
+	require.NotEmpty(t, endpoints)
+	for _, endpoint := range endpoints {
+		path := pathParamPattern.ReplaceAllString(strings.TrimSuffix(endpoint[2], "/"), "{}")
+		assert.True(t, documented[endpoint[1]+" "+path], "%s %s is in the OpenAPI document", endpoint[1], endpoint[2])
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	rr := httptest.NewRecorder()
+	DocsHandler(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `url: "/openapi.json"`)
+
+	rr = httptest.NewRecorder()
+	DocsHandler(rr, httptest.NewRequest(http.MethodPost, "/docs", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
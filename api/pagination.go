@@ -0,0 +1,116 @@
+package api
+
+import (
+	"component-service/cache"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxUnpaginatedChildren is the largest child listing served without ?limit. Beyond it the
+// cache copy and JSON encoding of a single response grow unbounded, so clients must page.
+const defaultMaxUnpaginatedChildren = 1000
+
+// maxListLimit caps ?limit on GET /components.
+const maxListLimit = 1000
+
+// defaultCursorPageSize is the page size in cursor mode when ?limit is not given.
+const defaultCursorPageSize = 100
+
+// MaxUnpaginatedChildren is the largest child listing, descendant listing or tree served without
+// paging. The same value caps ?limit.
+var MaxUnpaginatedChildren = defaultMaxUnpaginatedChildren
+
+// MaxUnpaginatedChildrenFromEnv reads CHILDREN_MAX_UNPAGINATED, a positive integer that defaults to
+// 1000.
+func MaxUnpaginatedChildrenFromEnv() (int, error) {
+	value := os.Getenv("CHILDREN_MAX_UNPAGINATED")
+	if value == "" {
+		return defaultMaxUnpaginatedChildren, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("invalid CHILDREN_MAX_UNPAGINATED %q: expected a positive integer", value)
+	}
+	return parsed, nil
+}
+
+// page is a ?limit/?offset window. limit == 0 means the client did not ask for pagination.
+type page struct {
+	limit  int
+	offset int
+}
+
+// parsePage reads ?limit and ?offset. limit must be between 1 and maxLimit; offset is non-negative.
+func parsePage(q *queryParams, maxLimit int) page {
+	return page{
+		limit:  q.intRange("limit", 0, 1, maxLimit),
+		offset: q.minInt("offset", 0, 0),
+	}
+}
+
+// parseSort reads ?sort and ?order (asc or desc, default asc). Without ?sort the listing keeps
+// its default order and ?order is rejected.
+func parseSort(q *queryParams) cache.Sort {
+	field := q.oneOf("sort", "", cache.SortFields...)
+	order := q.oneOf("order", "", "asc", "desc")
+	if order != "" && !q.has("sort") {
+		q.reject("order", "asc or desc, together with sort")
+	}
+	return cache.Sort{Field: field, Descending: order == "desc"}
+}
+
+// setPaginationHeaders reports the total size and, when another page follows, a Link to it.
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, p page, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if p.limit > 0 && p.offset+p.limit < total {
+		next := url.Values{}
+		for key, values := range r.URL.Query() {
+			next[key] = values
+		}
+		next.Set("limit", strconv.Itoa(p.limit))
+		next.Set("offset", strconv.Itoa(p.offset+p.limit))
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+}
+
+// cursorPage is the response body of GET /components in cursor mode.
+type cursorPage struct {
+	Components json.RawMessage `json:"components"`
+	NextCursor *string         `json:"next_cursor"` // null on the last page
+}
+
+// encodeCursor renders a cursor as an opaque URL-safe token.
+func encodeCursor(cursor cache.Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", cursor.CreatedAt.UnixNano(), cursor.ID)))
+}
+
+var errInvalidCursor = errors.New("a next_cursor value returned by this service, or empty for the first page")
+
+// decodeCursor parses a token produced by encodeCursor.
+func decodeCursor(token string) (cache.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cache.Cursor{}, errInvalidCursor
+	}
+	nanos, id, found := strings.Cut(string(raw), ".")
+	if !found {
+		return cache.Cursor{}, errInvalidCursor
+	}
+	unixNanos, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return cache.Cursor{}, errInvalidCursor
+	}
+	componentID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return cache.Cursor{}, errInvalidCursor
+	}
+	return cache.Cursor{CreatedAt: time.Unix(0, unixNanos).UTC(), ID: componentID}, nil
+}
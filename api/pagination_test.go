@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxUnpaginatedChildrenFromEnv(t *testing.T) {
+	max, err := MaxUnpaginatedChildrenFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultMaxUnpaginatedChildren, max)
+
+	t.Setenv("CHILDREN_MAX_UNPAGINATED", "50")
+	max, err = MaxUnpaginatedChildrenFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 50, max)
+
+	for _, value := range []string{"0", "-3", "lots"} {
+		t.Setenv("CHILDREN_MAX_UNPAGINATED", value)
+		_, err = MaxUnpaginatedChildrenFromEnv()
+		assert.ErrorContains(t, err, "invalid CHILDREN_MAX_UNPAGINATED", value)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// invalidParam describes one rejected query parameter.
+type invalidParam struct {
+	Parameter string `json:"parameter"`
+	Value     string `json:"value"`
+	Accepted  string `json:"accepted"` // what the parameter accepts, e.g. "an integer between 1 and 1000"
+}
+
+// invalidParamsResponse is the 400 body listing every rejected query parameter at once.
+type invalidParamsResponse struct {
+	Error             string         `json:"error"`
+	InvalidParameters []invalidParam `json:"invalid_parameters"`
+}
+
+// queryParams reads and validates a request's query string. Each accessor declares a parameter
+// the endpoint accepts and records a problem instead of failing, so a client learns about every
+// bad parameter from one response; valid reports them all, together with any parameter the
+// endpoint does not accept.
+type queryParams struct {
+	values  url.Values
+	known   map[string]bool
+	invalid []invalidParam
+}
+
+func newQueryParams(r *http.Request) *queryParams {
+	return &queryParams{values: r.URL.Query(), known: map[string]bool{}}
+}
+
+// has reports whether name was given, even with an empty value.
+func (q *queryParams) has(name string) bool {
+	q.known[name] = true
+	return q.values.Has(name)
+}
+
+// str returns name's value, or "" when it is absent.
+func (q *queryParams) str(name string) string {
+	q.known[name] = true
+	return q.values.Get(name)
+}
+
+// intRange returns name as an integer between min and max, or def when it is absent.
+func (q *queryParams) intRange(name string, def, min, max int) int {
+	value := q.str(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		q.reject(name, fmt.Sprintf("an integer between %d and %d", min, max))
+		return def
+	}
+	return parsed
+}
+
+// minInt returns name as an integer of at least min, or def when it is absent.
+func (q *queryParams) minInt(name string, def, min int) int {
+	value := q.str(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min {
+		q.reject(name, fmt.Sprintf("an integer of at least %d", min))
+		return def
+	}
+	return parsed
+}
+
+// oneOf returns name's value when it is one of allowed, or def when it is absent.
+func (q *queryParams) oneOf(name string, def string, allowed ...string) string {
+	value := q.str(name)
+	if value == "" {
+		return def
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return value
+		}
+	}
+	q.reject(name, "one of "+strings.Join(allowed, ", "))
+	return def
+}
+
+// reject records name's value as invalid; accepted describes what the parameter takes.
+func (q *queryParams) reject(name, accepted string) {
+	q.known[name] = true
+	q.invalid = append(q.invalid, invalidParam{Parameter: name, Value: q.values.Get(name), Accepted: accepted})
+}
+
+// valid responds 400 listing every invalid or unknown parameter and returns false, or returns
+// true when the query string is acceptable. Call it after reading every parameter.
+func (q *queryParams) valid(w http.ResponseWriter) bool {
+	var unknown []string
+	for name := range q.values {
+		if !q.known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	accepted := "nothing: this endpoint takes no query parameters"
+	if len(q.known) > 0 {
+		names := make([]string, 0, len(q.known))
+		for name := range q.known {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		accepted = "not a parameter of this endpoint; accepted parameters are " + strings.Join(names, ", ")
+	}
+	for _, name := range unknown {
+		q.invalid = append(q.invalid, invalidParam{Parameter: name, Value: q.values.Get(name), Accepted: accepted})
+	}
+	if len(q.invalid) == 0 {
+		return true
+	}
+	names := make([]string, 0, len(q.invalid))
+	for _, p := range q.invalid {
+		names = append(names, p.Parameter)
+	}
+	respondWithJSON(w, http.StatusBadRequest, invalidParamsResponse{
+		Error:             "Invalid query parameters: " + strings.Join(names, ", "),
+		InvalidParameters: q.invalid,
+	})
+	return false
+}
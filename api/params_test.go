@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryParams(t *testing.T) {
+	validate := func(query string, read func(q *queryParams)) (*httptest.ResponseRecorder, bool) {
+		req, _ := http.NewRequest(http.MethodGet, "/components?"+query, nil)
+		rr := httptest.NewRecorder()
+		q := newQueryParams(req)
+		read(q)
+		return rr, q.valid(rr)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		var limit int
+		var order string
+		_, ok := validate("limit=5&order=desc", func(q *queryParams) {
+			limit = q.intRange("limit", 0, 1, 10)
+			order = q.oneOf("order", "asc", "asc", "desc")
+		})
+		assert.True(t, ok)
+		assert.Equal(t, 5, limit)
+		assert.Equal(t, "desc", order)
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		_, ok := validate("", func(q *queryParams) {
+			assert.Equal(t, 7, q.intRange("limit", 7, 1, 10))
+			assert.Equal(t, "asc", q.oneOf("order", "asc", "asc", "desc"))
+			assert.False(t, q.has("after"))
+		})
+		assert.True(t, ok)
+	})
+
+	t.Run("EveryProblemListed", func(t *testing.T) {
+		rr, ok := validate("limit=1e9&depth=-1&order=bogus&colour=red", func(q *queryParams) {
+			q.intRange("limit", 0, 1, 1000)
+			q.minInt("depth", 0, 0)
+			q.oneOf("order", "asc", "asc", "desc")
+		})
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var body invalidParamsResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "Invalid query parameters: limit, depth, order, colour", body.Error)
+		if assert.Len(t, body.InvalidParameters, 4) {
+			assert.Equal(t, invalidParam{Parameter: "limit", Value: "1e9", Accepted: "an integer between 1 and 1000"}, body.InvalidParameters[0])
+			assert.Equal(t, "an integer of at least 0", body.InvalidParameters[1].Accepted)
+			assert.Equal(t, "one of asc, desc", body.InvalidParameters[2].Accepted)
+			assert.Contains(t, body.InvalidParameters[3].Accepted, "accepted parameters are depth, limit, order")
+		}
+	})
+
+	t.Run("NoParametersAccepted", func(t *testing.T) {
+		rr, ok := validate("verbose=1", func(*queryParams) {})
+		assert.False(t, ok)
+		assert.Contains(t, rr.Body.String(), "takes no query parameters")
+	})
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"component-service/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// patchComponent serves PATCH /components/{id}: only the fields present in the JSON body change,
+// unlike PUT, which replaces name, type, tags, description, metadata and parent_id together.
+// add_tags and remove_tags change single tags, leaving the others in place; metadata replaces the
+// whole object.
+func patchComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body map[string]json.RawMessage
+	if err := decodeBody(r, &body, false); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	patch, err := parseComponentPatch(body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	version, fromHeader, ok := expectedVersion(w, r, patch.Version)
+	if !ok {
+		return
+	}
+	patch.Version = version
+	if patch.ParentID != nil && patch.ParentID.Valid && !canAccess(r, patch.ParentID.Int64, cache.PermissionWrite) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Moving a component under component %d requires write permission on it", patch.ParentID.Int64))
+		return
+	}
+
+	if err := writeStore(r).PatchComponent(id, patch); err != nil {
+		switch {
+		case errors.Is(err, store.ErrVersionConflict):
+			respondWithVersionConflict(w, err, fromHeader)
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, store.ErrCycle):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.ErrInvalidType):
+			respondWithInvalidType(w, err, *patch.Type)
+		case errors.Is(err, store.ErrInvalidTags):
+			field, tags := patchTagsField(patch)
+			respondWithInvalidTags(w, err, field, tags)
+		case errors.Is(err, store.ErrInvalidMetadata):
+			respondWithInvalidMetadata(w, err, *patch.Metadata)
+		case errors.Is(err, store.ErrInvalidAttributes):
+			var metadata json.RawMessage
+			if patch.Metadata != nil {
+				metadata = *patch.Metadata
+			}
+			respondWithInvalidAttributes(w, err, metadata)
+		case errors.Is(err, store.ErrDuplicateName):
+			respondWithDuplicateName(w, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error updating component: "+err.Error())
+		}
+		return
+	}
+	updatedComp, err := componentStore.GetComponentByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error fetching updated component: "+err.Error())
+		return
+	}
+	respondWithComponent(w, http.StatusOK, updatedComp)
+}
+
+// patchFields lists the fields a PATCH body may set, for error messages.
+const patchFields = "name, type, tags, add_tags, remove_tags, description, metadata and parent_id"
+
+// parseComponentPatch reads the fields of a PATCH body. parent_id takes an ID, null for a root,
+// or the {"Int64": ..., "Valid": ...} object that component responses carry. version is not a
+// field to change but the version the component must be at.
+func parseComponentPatch(body map[string]json.RawMessage) (models.ComponentPatch, error) {
+	var patch models.ComponentPatch
+	for field, value := range body {
+		switch field {
+		case "name":
+			if err := json.Unmarshal(value, &patch.Name); err != nil || patch.Name == nil || *patch.Name == "" {
+				return patch, fmt.Errorf("name must be a non-empty string")
+			}
+		case "type":
+			if err := json.Unmarshal(value, &patch.Type); err != nil || patch.Type == nil {
+				return patch, fmt.Errorf("type must be a string, empty to remove the type")
+			}
+		case "description":
+			if err := json.Unmarshal(value, &patch.Description); err != nil || patch.Description == nil {
+				return patch, fmt.Errorf("description must be a string")
+			}
+		case "metadata":
+			metadata := value // null removes the metadata; the store checks the rest
+			patch.Metadata = &metadata
+		case "parent_id":
+			parentID, err := parsePatchParentID(value)
+			if err != nil {
+				return patch, err
+			}
+			patch.ParentID = &parentID
+		case "tags":
+			if err := json.Unmarshal(value, &patch.Tags); err != nil || patch.Tags == nil {
+				return patch, fmt.Errorf("tags must be an array of strings, empty to remove every tag")
+			}
+		case "add_tags":
+			if err := json.Unmarshal(value, &patch.AddTags); err != nil || patch.AddTags == nil {
+				return patch, fmt.Errorf("add_tags must be an array of strings")
+			}
+		case "remove_tags":
+			if err := json.Unmarshal(value, &patch.RemoveTags); err != nil || patch.RemoveTags == nil {
+				return patch, fmt.Errorf("remove_tags must be an array of strings")
+			}
+		case "version":
+			if err := json.Unmarshal(value, &patch.Version); err != nil || patch.Version < 1 {
+				return patch, fmt.Errorf("version must be a positive integer: the version the patch applies to")
+			}
+		case "status":
+			return patch, fmt.Errorf("status changes only along the lifecycle's transitions; use POST /components/{id}/status")
+		default:
+			return patch, fmt.Errorf("unknown field %q; PATCH accepts %s", field, patchFields)
+		}
+	}
+	if patch.IsEmpty() {
+		return patch, fmt.Errorf("no fields to update; send one or more of %s", patchFields)
+	}
+	return patch, nil
+}
+
+func parsePatchParentID(value json.RawMessage) (sql.NullInt64, error) {
+	var parentID sql.NullInt64
+	if string(value) == "null" {
+		return parentID, nil
+	}
+	if err := json.Unmarshal(value, &parentID.Int64); err == nil {
+		parentID.Valid = true
+		return parentID, nil
+	}
+	if err := json.Unmarshal(value, &parentID); err != nil {
+		return parentID, fmt.Errorf("parent_id must be a component ID or null")
+	}
+	return parentID, nil
+}
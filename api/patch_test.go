@@ -0,0 +1,53 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseComponentPatch(t *testing.T) {
+	parse := func(body string) (map[string]json.RawMessage, error) {
+		var fields map[string]json.RawMessage
+		err := json.Unmarshal([]byte(body), &fields)
+		return fields, err
+	}
+
+	fields, _ := parse(`{"description": ""}`)
+	patch, err := parseComponentPatch(fields)
+	assert.NoError(t, err)
+	assert.Nil(t, patch.Name)
+	assert.Nil(t, patch.ParentID)
+	if assert.NotNil(t, patch.Description) {
+		assert.Equal(t, "", *patch.Description, "An empty description clears it")
+	}
+
+	for body, expected := range map[string]sql.NullInt64{
+		`{"parent_id": 7}`:                           {Int64: 7, Valid: true},
+		`{"parent_id": null}`:                        {},
+		`{"parent_id": {"Int64": 7, "Valid": true}}`: {Int64: 7, Valid: true},
+	} {
+		fields, _ := parse(body)
+		patch, err := parseComponentPatch(fields)
+		if assert.NoError(t, err, body) && assert.NotNil(t, patch.ParentID, body) {
+			assert.Equal(t, expected, *patch.ParentID, body)
+		}
+	}
+
+	fields, _ = parse(`{"tags": [], "add_tags": ["critical"], "remove_tags": ["spare"]}`)
+	patch, err = parseComponentPatch(fields)
+	if assert.NoError(t, err) && assert.NotNil(t, patch.Tags) {
+		assert.Empty(t, *patch.Tags, "An empty array removes every tag")
+		assert.Equal(t, []string{"critical"}, patch.AddTags)
+		assert.Equal(t, []string{"spare"}, patch.RemoveTags)
+	}
+
+	for _, body := range []string{`{}`, `{"name": ""}`, `{"name": null}`, `{"name": 1}`, `{"parent_id": "x"}`, `{"id": 3}`,
+		`{"tags": null}`, `{"tags": "critical"}`, `{"add_tags": [1]}`, `{"remove_tags": null}`, `{"status": "active"}`} {
+		fields, _ := parse(body)
+		_, err := parseComponentPatch(fields)
+		assert.Error(t, err, body)
+	}
+}
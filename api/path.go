@@ -0,0 +1,62 @@
+package api
+
+import (
+	"component-service/cache"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// getComponentByPath serves GET /components/by-path?path=/root/child, which resolves a component
+// by the names on its path from the roots. Siblings sharing a name resolve to the first in
+// sibling order, and the rest of the path is looked up under it only; strict=true answers 409
+// instead. Components the caller may not read are left out, as if they did not exist. The
+// response is the component, as GET /components/{id} returns it.
+func getComponentByPath(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	names, err := cache.ParsePath(q.str("path"))
+	if err != nil {
+		q.reject("path", "a path of component names such as /root/child; "+err.Error())
+	}
+	strict := q.oneOf("strict", "false", "true", "false") == "true"
+	fields := parseFields(q)
+	includeComputed := parseIncludeComputed(q)
+	render := parseRender(q)
+	if !q.valid(w) {
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+	comp, err := readStore(r).ResolvePath(names, readableFilter(r), strict)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, cache.ErrAmbiguousPath):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error resolving path: "+err.Error())
+		}
+		return
+	}
+	recordView(comp.ID)
+	body, err := json.Marshal(comp)
+	if err == nil && includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectObject(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error encoding component: "+err.Error())
+		return
+	}
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
+}
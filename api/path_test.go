@@ -0,0 +1,65 @@
+package api
+
+import (
+	"component-service/fixtures"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetComponentByPath(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "duplicate_names")
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	resolve := func(query, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/components/by-path?"+query, nil)
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	path := func(p string) string { return "path=" + url.QueryEscape(p) }
+
+	for _, tc := range []struct {
+		query, principal string
+		want             string // the fixture key of the component expected
+	}{
+		{path("/plant/unit/pump"), "alice", "pump-1"},
+		{path("/plant/unit/pump"), "bob", "pump-2"}, // unit-1 is hidden from bob
+		{path("/plant/unit") + "&strict=true", "bob", "unit-2"},
+		{path(`/plant/in\/out`), "bob", "in/out"},
+	} {
+		rr := resolve(tc.query, tc.principal)
+		if assert.Equal(t, http.StatusOK, rr.Code, "%s as %s: %s", tc.query, tc.principal, rr.Body.String()) {
+			var body struct{ ID int64 }
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, w.ID(tc.want), body.ID, tc.query)
+		}
+	}
+
+	rr := resolve(path("/plant/unit")+"&fields=name", "alice")
+	assert.JSONEq(t, `{"name": "unit"}`, rr.Body.String())
+
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{path("/plant/unit") + "&strict=true", http.StatusConflict},
+		{path("/plant/pump"), http.StatusNotFound},
+		{path("/missing"), http.StatusNotFound},
+		{path("plant"), http.StatusBadRequest},
+		{path("/plant//unit"), http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+		{path("/plant") + "&strict=maybe", http.StatusBadRequest},
+	} {
+		rr := resolve(tc.query, "alice")
+		assert.Equal(t, tc.want, rr.Code, "%s: %s", tc.query, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, "/components/by-path", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/ratelimit"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPublicMaxAge    = 5 * time.Minute
+	defaultPublicRateLimit = 10 // requests per second per client IP
+	defaultPublicRateBurst = 20
+)
+
+// publicViewKey is the request context key marking requests served by the public view.
+type publicViewKey struct{}
+
+// publicView reports whether a request is served by the public view.
+func publicView(r *http.Request) bool {
+	public, _ := r.Context().Value(publicViewKey{}).(bool)
+	return public
+}
+
+// PublicRouter serves the anonymous, read-only public view: components flagged publicly visible
+// and their subtrees, and nothing else. It runs on a listener of its own, so no other endpoint is
+// reachable through it. Responses carry an ETag and a Cache-Control max-age so browsers and CDNs
+// can cache them, and each client IP is rate limited.
+type PublicRouter struct {
+	Port          string
+	MaxAge        time.Duration
+	RatePerSecond float64
+	Burst         int
+}
+
+// PublicFromEnv configures the public router from the environment:
+//
+//	PUBLIC_PORT           port of the public listener; unset disables the public router
+//	PUBLIC_CACHE_MAX_AGE  Cache-Control max-age of public responses (default 5m)
+//	PUBLIC_RATE_LIMIT     requests per second allowed per client IP (default 10)
+//	PUBLIC_RATE_BURST     requests a client IP may send at once (default 20)
+//
+// It returns a nil PublicRouter when the public router is disabled.
+func PublicFromEnv() (*PublicRouter, error) {
+	port := os.Getenv("PUBLIC_PORT")
+	if port == "" {
+		return nil, nil
+	}
+	p := &PublicRouter{Port: port, MaxAge: defaultPublicMaxAge, RatePerSecond: defaultPublicRateLimit, Burst: defaultPublicRateBurst}
+	if value := os.Getenv("PUBLIC_CACHE_MAX_AGE"); value != "" {
+		maxAge, err := time.ParseDuration(value)
+		if err != nil || maxAge < 0 {
+			return nil, fmt.Errorf("invalid PUBLIC_CACHE_MAX_AGE %q: expected a non-negative duration", value)
+		}
+		p.MaxAge = maxAge
+	}
+	if value := os.Getenv("PUBLIC_RATE_LIMIT"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid PUBLIC_RATE_LIMIT %q: expected a positive number", value)
+		}
+		p.RatePerSecond = rate
+	}
+	if value := os.Getenv("PUBLIC_RATE_BURST"); value != "" {
+		burst, err := strconv.Atoi(value)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("invalid PUBLIC_RATE_BURST %q: expected a positive integer", value)
+		}
+		p.Burst = burst
+	}
+	return p, nil
+}
+
+// Handler returns the rate-limited public router.
+func (p *PublicRouter) Handler() http.Handler {
+	return ratelimit.New(p.RatePerSecond, p.Burst).Handler(http.HandlerFunc(p.serve))
+}
+
+// serve answers GET /components with the flagged components, and passes GET requests for a
+// public component, its children, descendants, tree, checksum or graph data to ComponentsHandler.
+// Everything else, including components outside public subtrees, is 404, and strong reads are 400.
+func (p *PublicRouter) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "The public view is read-only")
+		return
+	}
+	// Anonymous callers read the cache only: a strong read would send each of them to the database.
+	if r.Header.Get(consistencyHeader) == consistencyStrong {
+		respondWithError(w, http.StatusBadRequest, "The public view serves cached reads only; drop the "+consistencyHeader+" header")
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "The public view requires the component cache, which is not initialized")
+		return
+	}
+	response := newBufferedResponse()
+	defer p.writeCacheable(w, r, response)
+	if target := strings.Trim(r.URL.Path, "/"); target == "components" {
+		if !newQueryParams(r).valid(response) {
+			return
+		}
+		body, err := cache.GlobalComponentCache.PublicJSON()
+		if err != nil {
+			respondWithError(response, http.StatusInternalServerError, "Error listing public components: "+err.Error())
+			return
+		}
+		respondWithRawJSON(response, http.StatusOK, body)
+	} else if id, ok := sharedComponentID(target); ok {
+		if public, _ := cache.GlobalComponentCache.IsPublic(id); !public {
+			respondWithError(response, http.StatusNotFound, fmt.Sprintf("component with ID %d not found", id))
+			return
+		}
+		ComponentsHandler(response, r.WithContext(context.WithValue(r.Context(), publicViewKey{}, true)))
+	} else {
+		respondWithError(response, http.StatusNotFound, "Not found")
+	}
+}
+
+// writeCacheable sends a buffered response. A 200 gets an ETag from its body and a public max-age,
+// and a request whose If-None-Match holds that ETag gets 304 without the body.
+func (p *PublicRouter) writeCacheable(w http.ResponseWriter, r *http.Request, response *bufferedResponse) {
+	for key, values := range response.header {
+		w.Header()[key] = values
+	}
+	if response.status != http.StatusOK {
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(response.status)
+		w.Write(response.body.Bytes())
+		return
+	}
+	sum := sha256.Sum256(response.body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(p.MaxAge.Seconds())))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(response.body.Bytes())
+}
+
+// bufferedResponse holds a response in memory until it is complete.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
+
+// componentVisibility is the body of GET and PUT /components/{id}/visibility.
+type componentVisibility struct {
+	ComponentID int64 `json:"component_id"`
+	Public      bool  `json:"public"`    // flagged itself; the field set by PUT
+	Inherited   bool  `json:"inherited"` // public through a flagged ancestor; read-only
+}
+
+// visibilityHandler serves GET and PUT /components/{id}/visibility, which read and set the
+// component's public visibility flag.
+func visibilityHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Visibility requires the component cache, which is not initialized")
+		return
+	}
+	if !cache.GlobalConfig.LoadPublic {
+		respondWithError(w, http.StatusServiceUnavailable, "Visibility flags are not loaded: the public view is disabled (set PUBLIC_PORT)")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body componentVisibility
+		if err := decodeBody(r, &body, false); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		defer r.Body.Close()
+		if err := componentStore.SetComponentPublic(id, body.Public); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				respondWithError(w, http.StatusNotFound, err.Error())
+			} else {
+				respondWithError(w, http.StatusInternalServerError, "Error changing visibility: "+err.Error())
+			}
+			return
+		}
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for visibility endpoint")
+		return
+	}
+	if _, found := cache.GlobalComponentCache.GetByID(id); !found {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("component with ID %d not found", id))
+		return
+	}
+	public, flagged := cache.GlobalComponentCache.IsPublic(id)
+	respondWithJSON(w, http.StatusOK, componentVisibility{ComponentID: id, Public: flagged, Inherited: public && !flagged})
+}
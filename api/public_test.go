@@ -0,0 +1,93 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// publicTestStore feeds the public router tests' cache without a database.
+type publicTestStore struct {
+	components []*models.Component
+	publicIDs  []int64
+}
+
+func (s *publicTestStore) ListComponents() ([]*models.Component, error) { return s.components, nil }
+
+func (s *publicTestStore) ListPublicComponentIDs() ([]int64, error) { return s.publicIDs, nil }
+
+func TestPublicRouter(t *testing.T) {
+	// 1 is flagged public, so its child 2 is public too; 3 is private.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadPublic = true
+	err := cache.InitGlobalCache(&publicTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "public"},
+			{ID: 2, Name: "child", ParentID: sql.NullInt64{Int64: 1, Valid: true}, Description: "Next to [[#3]]"},
+			{ID: 3, Name: "private"},
+		},
+		publicIDs: []int64{1},
+	})
+	assert.NoError(t, err)
+
+	handler := (&PublicRouter{MaxAge: time.Minute, RatePerSecond: 1, Burst: 100}).Handler()
+	serve := func(method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/components/2", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "public, max-age=60", rr.Header().Get("Cache-Control"))
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	rr = serve(http.MethodGet, "/components/2", etag)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	rr = serve(http.MethodGet, "/components", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"public"`)
+	assert.NotContains(t, rr.Body.String(), `"name":"private"`)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/components/1/children", "").Code)
+	rr = serve(http.MethodGet, "/components/1/graph-data", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), `"private"`, "Links do not lead out of the public view")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/components/3", "").Code, "private components are hidden")
+	assert.Equal(t, "no-store", serve(http.MethodGet, "/components/3", "").Header().Get("Cache-Control"))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/components/1/acl", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/admin/jobs", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/components/1", "").Code)
+
+	// Anonymous callers cannot bypass the cache
+	req := httptest.NewRequest(http.MethodGet, "/components/2", nil)
+	req.Header.Set(consistencyHeader, consistencyStrong)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestPublicRouterRateLimit(t *testing.T) {
+	handler := (&PublicRouter{RatePerSecond: 0.001, Burst: 2}).Handler()
+	codes := make([]int, 3)
+	for i := range codes {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/components", nil))
+		codes[i] = rr.Code
+	}
+	assert.Equal(t, []int{http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, http.StatusTooManyRequests}, codes)
+}
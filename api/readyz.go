@@ -0,0 +1,30 @@
+package api
+
+import (
+	"component-service/cache"
+	"net/http"
+)
+
+// readiness is the body of GET /readyz.
+type readiness struct {
+	Status string            `json:"status"` // ready, degraded or unavailable
+	Cache  cache.HealthState `json:"cache"`
+}
+
+// ReadyzHandler answers readiness probes: 200 while the cache serves reads, degraded or not, so
+// a degraded instance stays in rotation, and 503 until there is a cache to serve them.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	state := cache.Health()
+	switch state.Status {
+	case "unavailable":
+		respondWithJSON(w, http.StatusServiceUnavailable, readiness{Status: "unavailable", Cache: state})
+	case "degraded":
+		respondWithJSON(w, http.StatusOK, readiness{Status: "degraded", Cache: state})
+	default:
+		respondWithJSON(w, http.StatusOK, readiness{Status: "ready", Cache: state})
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"component-service/markdown"
+	"encoding/json"
+)
+
+// renderedDescriptions memoizes rendered descriptions across requests; see markdown.Cache.
+var renderedDescriptions markdown.Cache
+
+// parseRender reads ?render=html, which adds each component's description rendered from Markdown
+// to sanitized HTML to the response under "description_html".
+func parseRender(q *queryParams) bool {
+	return q.oneOf("render", "", "html") == "html"
+}
+
+// withRenderedDescriptions adds a "description_html" member to an encoded component, or to each
+// component in an encoded array.
+func withRenderedDescriptions(encoded json.RawMessage) (json.RawMessage, error) {
+	encoded = bytes.TrimSpace(encoded)
+	isArray := len(encoded) > 0 && encoded[0] == '['
+	objects := []json.RawMessage{encoded}
+	if isArray {
+		objects = nil
+		if err := json.Unmarshal(encoded, &objects); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if isArray {
+		buf.WriteByte('[')
+	}
+	for i, object := range objects {
+		var component struct {
+			ID          int64  `json:"id"`
+			Description string `json:"description"`
+			UpdatedAt   string `json:"updated_at"`
+		}
+		if err := json.Unmarshal(object, &component); err != nil {
+			return nil, err
+		}
+		html, err := renderedDescriptions.Render(component.ID, component.UpdatedAt, component.Description)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(html)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(withMember(object, "description_html", value))
+	}
+	if isArray {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
+
+// withMember returns an encoded object with one more member, name, appended.
+func withMember(object json.RawMessage, name string, value json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	object = bytes.TrimSuffix(bytes.TrimSpace(object), []byte("}"))
+	buf.Write(object)
+	if len(bytes.TrimSpace(object)) > 1 { // not an empty object
+		buf.WriteByte(',')
+	}
+	key, _ := json.Marshal(name)
+	buf.Write(key)
+	buf.WriteByte(':')
+	buf.Write(value)
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
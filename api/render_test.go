@@ -0,0 +1,37 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHTML(t *testing.T) {
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "root", Description: "**bold** <script>alert(1)</script>", UpdatedAt: "2024-01-01T00:00:00Z"},
+		{ID: 2, Name: "child", Description: "[x](javascript:alert(1))", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+	}})
+	assert.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	rr := get("/components/1?render=html&fields=name")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"name":"root","description_html":"<p><strong>bold</strong> alert(1)</p>\n"}`, rr.Body.String())
+
+	rr = get("/components/1/children?render=html&fields=id")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"id":2,"description_html":"<p>x</p>\n"}]`, rr.Body.String())
+
+	assert.NotContains(t, get("/components/1").Body.String(), "description_html")
+	assert.Equal(t, http.StatusBadRequest, get("/components/1?render=markdown").Code)
+}
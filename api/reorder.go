@@ -0,0 +1,60 @@
+package api
+
+import (
+	"component-service/cache"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// reorderComponent serves POST /components/{id}/reorder, which moves a component among its
+// siblings without changing its parent. The body is {"position": N}: the component's index among
+// its siblings afterwards, 0 being the first. A position past the last sibling moves it to the end.
+// The reordered component is returned.
+func reorderComponent(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body struct {
+		Position *int `json:"position"`
+	}
+	if err := decodeBody(r, &body, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if body.Position == nil || *body.Position < 0 {
+		respondWithError(w, http.StatusBadRequest, "position is required: the component's index among its siblings, 0 being the first")
+		return
+	}
+
+	// Reordering changes the parent's order of children, so it needs the parent as a move would.
+	comp, err := componentStore.GetComponentByID(id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error fetching component: "+err.Error())
+		}
+		return
+	}
+	if comp.ParentID.Valid && !canAccess(r, comp.ParentID.Int64, cache.PermissionWrite) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Reordering the children of component %d requires write permission on it", comp.ParentID.Int64))
+		return
+	}
+
+	if err := writeStore(r).ReorderComponent(id, *body.Position); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error reordering component: "+err.Error())
+		}
+		return
+	}
+	reordered, err := componentStore.GetComponentByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error fetching reordered component: "+err.Error())
+		return
+	}
+	respondWithComponent(w, http.StatusOK, reordered)
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderComponentValidation(t *testing.T) {
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodPost, "/components/1/reorder", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/1/reorder", `{"position":-1}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/1/reorder", `{"position":"first"}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/1/reorder", `{"position":0,"parent_id":2}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/x/reorder", `{"position":0}`, http.StatusBadRequest},
+		{http.MethodGet, "/components/1/reorder", ``, http.StatusMethodNotAllowed},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		assert.Equal(t, tc.want, rr.Code, "%s %s %s", tc.method, tc.target, tc.body)
+	}
+}
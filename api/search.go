@@ -0,0 +1,62 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/store"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchComponents serves GET /components/search?q=...: components whose name or description
+// contains every word of q, best match first, and have the ?tag given, if any. X-Search-Backend reports whether the database
+// index or the in-memory fallback answered.
+func searchComponents(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	text := q.str("q")
+	if len(cache.SearchTerms(text)) == 0 {
+		q.reject("q", "one or more words to search for")
+	}
+	tag := parseTag(q)
+	limit := q.intRange("limit", defaultSearchLimit, 1, maxSearchLimit)
+	fields := parseFields(q)
+	if !q.valid(w) {
+		return
+	}
+
+	results, backend, err := componentStore.SearchComponents(r.Context(), text, tag, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrSearchUnavailable) {
+			respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error searching components: "+err.Error())
+		}
+		return
+	}
+	readable := readableFilter(r)
+	response := make([]searchResult, 0, len(results))
+	for _, result := range results {
+		if readable != nil && !readable(result.Component.ID) {
+			continue
+		}
+		component, err := fields.project(result.Component)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error encoding search result: "+err.Error())
+			return
+		}
+		response = append(response, searchResult{Rank: result.Rank, Component: component})
+	}
+	w.Header().Set("X-Search-Backend", backend)
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// searchResult is cache.SearchResult with the component projected to the requested ?fields.
+type searchResult struct {
+	Rank      float64         `json:"rank"`
+	Component json.RawMessage `json:"component"`
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"component-service/store"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sharedPrefix starts the URL of every share link: /shared/{token}/components/{id}.
+const sharedPrefix = "/shared/"
+
+// maxShareDepth bounds the ancestor walk that checks a component lies inside a shared subtree.
+const maxShareDepth = 10000
+
+// sharedLinkKey is the request context key holding the link a shared request was made through.
+type sharedLinkKey struct{}
+
+// sharedLinkFrom returns the link a request was made through, or nil for ordinary requests.
+func sharedLinkFrom(r *http.Request) *store.ShareLink {
+	link, _ := r.Context().Value(sharedLinkKey{}).(*store.ShareLink)
+	return link
+}
+
+// ShareLinkHandler serves share links. A request to /shared/{token}/components/{id}, or to the
+// component's children, descendants, tree, checksum or graph data, is checked against the link and
+// then served by next as the same request without the /shared/{token} prefix. Links are read-only
+// and reach only their own subtree; the ACL middleware lets their requests through, since the
+// link's creator needed admin permission on the subtree. Other requests pass through unchanged.
+func ShareLinkHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, sharedPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, target, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, sharedPrefix), "/")
+		link, err := componentStore.ShareLinkByToken(token)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking share link: "+err.Error())
+			return
+		}
+		if link == nil {
+			respondWithError(w, http.StatusNotFound, "Unknown share link")
+			return
+		}
+		if !link.Active(time.Now()) {
+			respondWithError(w, http.StatusGone, "Share link has expired or been revoked")
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			respondWithError(w, http.StatusMethodNotAllowed, "Share links are read-only")
+			return
+		}
+		id, ok := sharedComponentID(target)
+		if !ok {
+			respondWithError(w, http.StatusNotFound, "Not available through a share link")
+			return
+		}
+		inside, err := withinSubtree(id, link.ComponentID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking share link scope: "+err.Error())
+			return
+		}
+		if !inside {
+			respondWithError(w, http.StatusNotFound, fmt.Sprintf("component with ID %d not found", id))
+			return
+		}
+
+		shared := r.Clone(context.WithValue(r.Context(), sharedLinkKey{}, link))
+		shared.URL.Path = "/" + target
+		shared.URL.RawPath = ""
+		next.ServeHTTP(w, shared)
+	})
+}
+
+// sharedComponentID returns the component addressed by a path a share link may reach:
+// components/{id} and its children, descendants, tree, checksum and graph-data.
+func sharedComponentID(target string) (int64, bool) {
+	pathParts := strings.Split(strings.Trim(target, "/"), "/")
+	if len(pathParts) < 2 || len(pathParts) > 3 || pathParts[0] != "components" {
+		return 0, false
+	}
+	if len(pathParts) == 3 && pathParts[2] != "children" && pathParts[2] != "descendants" && pathParts[2] != "tree" &&
+		pathParts[2] != "checksum" && pathParts[2] != "graph-data" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(pathParts[1], 10, 64)
+	return id, err == nil
+}
+
+// withinSubtree reports whether id is rootID or one of its descendants.
+func withinSubtree(id, rootID int64) (bool, error) {
+	for depth := 0; depth < maxShareDepth; depth++ {
+		if id == rootID {
+			return true, nil
+		}
+		comp, err := componentStore.GetComponentByID(id)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return false, nil
+			}
+			return false, err
+		}
+		if !comp.ParentID.Valid {
+			return false, nil
+		}
+		id = comp.ParentID.Int64
+	}
+	return false, nil
+}
+
+// shareRequest is the optional body of POST /components/{id}/share.
+type shareRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createdShareLink is the response to POST /components/{id}/share: the only time the token is shown.
+type createdShareLink struct {
+	*store.ShareLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// shareHandler serves /components/{id}/share (POST creates a link, GET lists them) and
+// DELETE /components/{id}/share/{linkID}, which revokes one.
+func shareHandler(w http.ResponseWriter, r *http.Request, id int64, linkID string) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	switch {
+	case linkID == "" && r.Method == http.MethodPost:
+		createShareLink(w, r, id)
+	case linkID == "" && r.Method == http.MethodGet:
+		links, err := componentStore.ListShareLinks(id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error listing share links: "+err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusOK, links)
+	case linkID != "" && r.Method == http.MethodDelete:
+		parsed, err := strconv.ParseInt(linkID, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid share link ID in path")
+			return
+		}
+		link, err := componentStore.RevokeShareLink(id, parsed)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				respondWithError(w, http.StatusNotFound, err.Error())
+			} else {
+				respondWithError(w, http.StatusInternalServerError, "Error revoking share link: "+err.Error())
+			}
+			return
+		}
+		respondWithJSON(w, http.StatusOK, link)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for share endpoint")
+	}
+}
+
+func createShareLink(w http.ResponseWriter, r *http.Request, id int64) {
+	var body shareRequest
+	if err := decodeBody(r, &body, false); err != nil && !errors.Is(err, io.EOF) { // the body is optional
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	link, token, err := componentStore.CreateShareLink(id, body.ExpiresAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error creating share link: "+err.Error())
+		}
+		return
+	}
+	log.Printf("Share link %d created for component %d", link.ID, id)
+	respondWithJSON(w, http.StatusCreated, createdShareLink{
+		ShareLink: link,
+		Token:     token,
+		URL:       fmt.Sprintf("%s%s/components/%d", sharedPrefix, token, id),
+	})
+}
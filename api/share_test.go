@@ -0,0 +1,26 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedComponentID(t *testing.T) {
+	for target, expected := range map[string]int64{
+		"components/4":             4,
+		"components/4/children":    4,
+		"components/4/descendants": 4,
+		"components/4/tree":        4,
+		"components/4/checksum":    4,
+		"components/4/graph-data":  4,
+	} {
+		id, ok := sharedComponentID(target)
+		assert.True(t, ok, target)
+		assert.Equal(t, expected, id, target)
+	}
+	for _, target := range []string{"", "components", "components/search", "components/4/acl", "components/4/share", "components/4/attachments", "admin/jobs/1"} {
+		_, ok := sharedComponentID(target)
+		assert.False(t, ok, target)
+	}
+}
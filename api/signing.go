@@ -0,0 +1,38 @@
+package api
+
+import (
+	"component-service/signing"
+	"fmt"
+	"net/http"
+)
+
+// jwksMaxAge is how long receivers may cache GET /.well-known/jwks.json. A key added second to
+// EVENT_SIGNING_KEYS should be published for longer than this before it is moved first.
+const jwksMaxAge = 300 // seconds
+
+// eventKeys signs the events sent out; it is the key ring set with EnableEventSigning, or nil.
+var eventKeys *signing.KeyRing
+
+// EnableEventSigning signs the events the change streams send, when asked to, with k, and
+// publishes its public keys at /.well-known/jwks.json.
+func EnableEventSigning(k *signing.KeyRing) {
+	eventKeys = k
+}
+
+// JWKSHandler serves GET /.well-known/jwks.json, the public keys events are signed with as a JSON
+// Web Key Set, the key currently signing first. Without signing keys the set is empty.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	set := signing.JWKSet{Keys: []signing.JWK{}}
+	if eventKeys != nil {
+		set = eventKeys.JWKSet()
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	respondWithJSON(w, http.StatusOK, set)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bufio"
+	"component-service/events"
+	"component-service/signing"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	defer func(k *signing.KeyRing) { eventKeys = k }(eventKeys)
+	eventKeys = nil
+	rr := httptest.NewRecorder()
+	JWKSHandler(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"keys": []}`, rr.Body.String())
+
+	keys, err := signing.ParseKeys("new:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + ",old:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	require.NoError(t, err)
+	EnableEventSigning(keys)
+	rr = httptest.NewRecorder()
+	JWKSHandler(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "public, max-age=300", rr.Header().Get("Cache-Control"))
+	var set signing.JWKSet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &set))
+	assert.Equal(t, keys.JWKSet(), set)
+
+	rr = httptest.NewRecorder()
+	JWKSHandler(rr, httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestSignedStreams(t *testing.T) {
+	defer func(k *signing.KeyRing) { eventKeys = k }(eventKeys)
+	defer func(f *events.Feed) { changeFeed = f }(changeFeed)
+	feed := events.NewFeed(10)
+	feed.Start()
+	defer feed.Stop()
+	EnableChangeFeed(feed)
+
+	eventKeys = nil
+	for _, target := range []string{"/components/events?signed=true", "/components/watch?signed=true"} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Expected %s to be refused without signing keys", target)
+	}
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/events?signed=yes", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	keys, err := signing.ParseKeys("k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	EnableEventSigning(keys)
+	server := httptest.NewServer(http.HandlerFunc(ComponentsHandler))
+	defer server.Close()
+	verify := func(jws string) events.Event {
+		payload, err := signing.Verify(keys.JWKSet(), jws, nil)
+		require.NoError(t, err, jws)
+		var e events.Event
+		require.NoError(t, json.Unmarshal(payload, &e))
+		return e
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/components/watch?signed=true", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/components/events?signed=true", nil)
+	req.Header.Set("Last-Event-ID", feed.LastID())
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events.Publish(events.Event{Type: events.ComponentCreated, ComponentID: 5})
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), verify(string(message)).ComponentID)
+	lines := bufio.NewScanner(resp.Body)
+	var data string
+	for data == "" && lines.Scan() {
+		if value, ok := strings.CutPrefix(lines.Text(), "data: "); ok {
+			data = value
+		}
+	}
+	assert.Equal(t, int64(5), verify(data).ComponentID)
+}
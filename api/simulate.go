@@ -0,0 +1,109 @@
+package api
+
+import (
+	"component-service/cache"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// permissionViolation is a permission the caller lacks to apply a simulated operation.
+type permissionViolation struct {
+	ComponentID int64            `json:"component_id"`
+	Needed      cache.Permission `json:"needed"`
+}
+
+// simulationResult is the body of a POST /components/{id}/simulate response.
+type simulationResult struct {
+	*cache.Impact
+	PermissionViolations []permissionViolation `json:"permission_violations"`
+}
+
+// simulateOperation serves POST /components/{id}/simulate, which previews a move, delete or
+// merge of the component from the cache without touching the database. The body names the
+// operation and its target: {"operation": "move", "new_parent_id": ...}, {"operation": "delete"}
+// or {"operation": "merge", "into_id": ...}. The response holds the projected impact and the
+// permissions the caller would lack to apply the operation.
+func simulateOperation(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body struct {
+		Operation   string          `json:"operation"`
+		NewParentID json.RawMessage `json:"new_parent_id"`
+		IntoID      *int64          `json:"into_id"`
+	}
+	if err := decodeBody(r, &body, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	sim := cache.Simulation{Operation: body.Operation}
+	needed := []int64{id} // the components the operation needs write on
+	switch body.Operation {
+	case cache.OperationMove:
+		if body.NewParentID == nil || body.IntoID != nil {
+			respondWithError(w, http.StatusBadRequest, "A move takes new_parent_id, a component ID or null to make the component a root")
+			return
+		}
+		parentID, err := parsePatchParentID(body.NewParentID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "new_parent_id must be a component ID or null")
+			return
+		}
+		sim.NewParentID = parentID
+		if parentID.Valid {
+			needed = append(needed, parentID.Int64)
+		}
+	case cache.OperationDelete:
+		if body.NewParentID != nil || body.IntoID != nil {
+			respondWithError(w, http.StatusBadRequest, "A delete takes no target")
+			return
+		}
+	case cache.OperationMerge:
+		if body.IntoID == nil || body.NewParentID != nil {
+			respondWithError(w, http.StatusBadRequest, "A merge takes into_id, the component that receives the children")
+			return
+		}
+		sim.IntoID = *body.IntoID
+		needed = append(needed, sim.IntoID)
+	default:
+		respondWithError(w, http.StatusBadRequest, "operation must be move, delete or merge")
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Simulation requires the component cache, which is not initialized")
+		return
+	}
+
+	impact, err := cache.GlobalComponentCache.Simulate(id, sim)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, cache.ErrInvalidSimulation):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error simulating operation: "+err.Error())
+		}
+		return
+	}
+	// ACL entries are only shown to those who may read them on the component that defines them.
+	relations := make([]cache.BrokenRelation, 0, len(impact.BrokenRelations))
+	for _, relation := range impact.BrokenRelations {
+		if relation.Type != cache.RelationACL || canAccess(r, relation.RelatedID, cache.PermissionAdmin) {
+			relations = append(relations, relation)
+		}
+	}
+	impact.BrokenRelations = relations
+
+	result := simulationResult{Impact: impact, PermissionViolations: []permissionViolation{}}
+	for _, neededID := range needed {
+		if !canAccess(r, neededID, cache.PermissionWrite) {
+			result.PermissionViolations = append(result.PermissionViolations, permissionViolation{ComponentID: neededID, Needed: cache.PermissionWrite})
+		}
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"component-service/fixtures"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulateOperation(t *testing.T) {
+	// root (1) -> child (2) -> grandchild (3), with other (4) on its own. Everyone reads root and
+	// alice administers it; other is unrestricted.
+	fixtures.MustLoadCache(t, "acl_tree")
+
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	simulate := func(target, body, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := simulate("/components/2/simulate", `{"operation":"move","new_parent_id":4}`, "alice")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"operation":"move","component_id":2,"affected_descendants":1,"resulting_depth":2,"broken_relations":[
+		{"type":"acl","component_id":2,"related_id":1,"principal":"*"},
+		{"type":"acl","component_id":2,"related_id":1,"principal":"alice"}
+	],"permission_violations":[]}`, rr.Body.String())
+
+	// Bob may read 2 to preview the move, but not write it, nor see the ACL entries it loses.
+	rr = simulate("/components/2/simulate", `{"operation":"move","new_parent_id":4}`, "bob")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"operation":"move","component_id":2,"affected_descendants":1,"resulting_depth":2,"broken_relations":[],
+		"permission_violations":[{"component_id":2,"needed":"write"}]}`, rr.Body.String())
+
+	rr = simulate("/components/2/simulate", `{"operation":"delete"}`, "alice")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"operation":"delete","component_id":2,"affected_descendants":1,"resulting_depth":0,"broken_relations":[
+		{"type":"parent","component_id":3,"related_id":2},
+		{"type":"acl","component_id":3,"related_id":1,"principal":"*"},
+		{"type":"acl","component_id":3,"related_id":1,"principal":"alice"}
+	],"permission_violations":[]}`, rr.Body.String())
+
+	for _, tc := range []struct {
+		target, body string
+		want         int
+	}{
+		{"/components/2/simulate", `{"operation":"merge","into_id":1}`, http.StatusOK},
+		{"/components/2/simulate", `{"operation":"copy"}`, http.StatusBadRequest},
+		{"/components/2/simulate", `{"operation":"move"}`, http.StatusBadRequest},
+		{"/components/2/simulate", `{"operation":"move","new_parent_id":"x"}`, http.StatusBadRequest},
+		{"/components/2/simulate", `{"operation":"delete","into_id":1}`, http.StatusBadRequest},
+		{"/components/2/simulate", `{"operation":"merge"}`, http.StatusBadRequest},
+		{"/components/2/simulate", `{"operation":"delete","cascade":true}`, http.StatusBadRequest},
+		{"/components/x/simulate", `{"operation":"delete"}`, http.StatusBadRequest},
+		{"/components/99/simulate", `{"operation":"delete"}`, http.StatusNotFound},
+		{"/components/2/simulate", `{"operation":"move","new_parent_id":99}`, http.StatusNotFound},
+		{"/components/2/simulate", `{"operation":"move","new_parent_id":3}`, http.StatusUnprocessableEntity},
+		{"/components/2/simulate", `{"operation":"merge","into_id":2}`, http.StatusUnprocessableEntity},
+	} {
+		rr := simulate(tc.target, tc.body, "alice")
+		assert.Equal(t, tc.want, rr.Code, "%s %s: %s", tc.target, tc.body, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/2/simulate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"component-service/slo"
+	"net/http"
+)
+
+// objectives is the tracker configured with EnableSLO, or nil.
+var objectives *slo.Tracker
+
+// EnableSLO serves GET /admin/slo from t, which records the requests the service answers.
+func EnableSLO(t *slo.Tracker) {
+	objectives = t
+}
+
+// getSLO reports availability and latency against the objectives over each rolling window.
+func getSLO(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if objectives == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "SLO tracking is not enabled")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, objectives.Report())
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// getComponentBySlug serves GET /components/slug/{slug}, which looks a component up by the slug
+// it was given on create, so links and UIs can refer to it by a readable name that survives
+// renames. A component the caller may not read is answered 404, as if it did not exist. The
+// response is the component, as GET /components/{id} returns it.
+func getComponentBySlug(w http.ResponseWriter, r *http.Request, slug string) {
+	q := newQueryParams(r)
+	fields := parseFields(q)
+	includeComputed := parseIncludeComputed(q)
+	render := parseRender(q)
+	if !q.valid(w) {
+		return
+	}
+	etag := cachedETag(r)
+	if notModified(w, r, etag) {
+		return
+	}
+	if !models.IsSlug(slug) {
+		respondWithError(w, http.StatusBadRequest, "Invalid slug in path: expected lowercase letters and digits separated by single hyphens")
+		return
+	}
+	comp, err := readStore(r).GetComponentBySlug(slug)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error getting component: "+err.Error())
+		}
+		return
+	}
+	if readable := readableFilter(r); readable != nil && !readable(comp.ID) {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("component with slug %q not found", slug))
+		return
+	}
+	recordView(comp.ID)
+	body, err := json.Marshal(comp)
+	if err == nil && includeComputed {
+		body, err = withComputed(body)
+	}
+	if err == nil && render {
+		body, err = withRenderedDescriptions(body)
+	}
+	if err == nil {
+		body, err = fields.projectObject(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error encoding component: "+err.Error())
+		return
+	}
+	setETag(w, etag)
+	respondWithRawJSON(w, http.StatusOK, body)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"component-service/fixtures"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetComponentBySlug(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "duplicate_names")
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(http.HandlerFunc(ComponentsHandler))
+	get := func(target, principal string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(defaultPrincipalHeader, principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, tc := range []struct {
+		slug, want string // want is the fixture key of the component expected
+	}{
+		{"unit", "unit-1"},
+		{"unit-2", "unit-2"},
+		{"pump-2", "pump-2"},
+		{"in-out", "in/out"},
+	} {
+		rr := get("/components/slug/"+tc.slug, "alice")
+		if assert.Equal(t, http.StatusOK, rr.Code, "%s: %s", tc.slug, rr.Body.String()) {
+			var body struct {
+				ID   int64
+				Slug string
+			}
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, w.ID(tc.want), body.ID, tc.slug)
+			assert.Equal(t, tc.slug, body.Slug)
+		}
+	}
+
+	rr := get("/components/slug/unit-2?fields=id,slug", "alice")
+	assert.JSONEq(t, `{"id": 4, "slug": "unit-2"}`, rr.Body.String())
+
+	for _, tc := range []struct {
+		target, principal string
+		want              int
+	}{
+		{"/components/slug/unit", "bob", http.StatusNotFound}, // unit-1 is hidden from bob
+		{"/components/slug/missing", "alice", http.StatusNotFound},
+		{"/components/slug/Unit", "alice", http.StatusBadRequest},
+		{"/components/slug/unit--2", "alice", http.StatusBadRequest},
+		{"/components/slug/unit?fields=bogus", "alice", http.StatusBadRequest},
+	} {
+		rr := get(tc.target, tc.principal)
+		assert.Equal(t, tc.want, rr.Code, "%s as %s: %s", tc.target, tc.principal, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodDelete, "/components/slug/unit", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bufio"
+	"component-service/cache"
+	"component-service/events"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Timing and buffering of GET /components/watch connections.
+const (
+	socketPingInterval = 30 * time.Second // a client missing two pings in a row is disconnected
+	socketWriteTimeout = 10 * time.Second
+	socketBacklog      = 256 // events queued per connection; a client falling further behind is disconnected
+)
+
+// socketUpgrader accepts WebSocket handshakes. Its default origin check refuses browsers on other
+// sites, so a page elsewhere cannot watch with a visitor's credentials.
+var socketUpgrader = websocket.Upgrader{}
+
+// watchComponentsSocket serves GET /components/watch, which upgrades to a WebSocket and sends
+// every component event published from then on as a text message, for UIs to update without
+// polling. Events of components the request's principal may not read are left out. The client
+// sends nothing; a client too slow to keep up is disconnected with close code 1013 (try again
+// later) and has to reload what it shows. Events are encoded as parseStreamFormat reads.
+func watchComponentsSocket(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseStreamFormat(w, newQueryParams(r))
+	if !ok {
+		return
+	}
+	// Subscribe before upgrading, so nothing published once the client is told it is connected is missed
+	queue := make(chan events.Event, socketBacklog)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+	unsubscribe := events.Subscribe(func(e events.Event) {
+		select {
+		case queue <- e:
+		default:
+			overflowOnce.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	conn, err := socketUpgrader.Upgrade(hijackableResponse{w}, r, nil)
+	if err != nil {
+		return // Upgrade has answered with the error
+	}
+	defer conn.Close()
+
+	// Clients send nothing, but reading is what processes pongs and notices the client closing
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(2 * socketPingInterval))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * socketPingInterval))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case e := <-queue:
+			if !eventReadable(r, e) {
+				continue
+			}
+			message, err := format.encode(e)
+			if err != nil {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteTimeout)); err != nil {
+				return
+			}
+		case <-overflow:
+			message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow to keep up with changes")
+			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(socketWriteTimeout))
+			return
+		case <-closed:
+			return
+		}
+	}
+}
+
+// eventReadable reports whether the request's principal may see an event. A deleted component has
+// left the ACL index, so its deletion is shown to those who may read its former parent. With ACLs
+// enforced, a component the cache no longer holds, such as one deleted before a replayed or
+// queued event of it is sent, is unreadable: its ACL left with it, so neither its events nor the
+// deletion of a root are shown. HasACLs is no guide here, as the deletion may have taken the
+// last restricted component out of the index.
+func eventReadable(r *http.Request, e events.Event) bool {
+	principal, enforced := principalFrom(r)
+	if !enforced || cache.GlobalComponentCache == nil {
+		return true
+	}
+	id := e.ComponentID
+	if e.Type == events.ComponentDeleted {
+		if e.OldParentID == nil {
+			return false
+		}
+		id = *e.OldParentID
+	}
+	return cache.GlobalComponentCache.AllowsHeld(id, principal, cache.PermissionRead)
+}
+
+// hijackableResponse lets the WebSocket upgrader take over a connection through the middleware's
+// response wrappers, which expose the server's writer to http.ResponseController only.
+type hijackableResponse struct {
+	http.ResponseWriter
+}
+
+func (h hijackableResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchComponentsSocket(t *testing.T) {
+	// 2 is hidden from everyone but alice, and so is the deletion of its child 3.
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	cache.GlobalConfig.LoadACL = true
+	err := cache.InitGlobalCache(&aclTestStore{
+		components: []*models.Component{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "private", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		},
+		entries: []cache.ACLEntry{
+			{ComponentID: 2, Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+			{ComponentID: 2, Principal: "alice", Permission: cache.PermissionRead},
+		},
+	})
+	require.NoError(t, err)
+
+	// Served through the middleware, whose response wrappers the upgrade has to get through
+	enforcer := &ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}
+	server := httptest.NewServer(YAMLHandler(enforcer.Handler(http.HandlerFunc(ComponentsHandler))))
+	defer server.Close()
+	dial := func(principal string) *websocket.Conn {
+		header := http.Header{defaultPrincipalHeader: {principal}}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/components/watch", header)
+		require.NoError(t, err)
+		return conn
+	}
+	alice, bob := dial("alice"), dial("bob")
+	defer alice.Close()
+	defer bob.Close()
+
+	parent := int64(2)
+	events.Publish(events.Event{Type: events.ComponentUpdated, ComponentID: 2})
+	events.Publish(events.Event{Type: events.ComponentDeleted, ComponentID: 3, OldParentID: &parent})
+	// 9 is no longer cached, so nothing tells who could read it: its events are shown to no one
+	events.Publish(events.Event{Type: events.ComponentUpdated, ComponentID: 9})
+	events.Publish(events.Event{Type: events.ComponentDeleted, ComponentID: 9})
+	events.Publish(events.Event{Type: events.ComponentCreated, ComponentID: 1, Component: &models.Component{ID: 1, Name: "root"}})
+
+	receive := func(conn *websocket.Conn) events.Event {
+		var e events.Event
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		require.NoError(t, conn.ReadJSON(&e))
+		return e
+	}
+	assert.Equal(t, int64(2), receive(alice).ComponentID)
+	assert.Equal(t, events.ComponentDeleted, receive(alice).Type)
+	assert.Equal(t, int64(1), receive(alice).ComponentID, "alice does not see the events of 9")
+	e := receive(bob)
+	assert.Equal(t, events.ComponentCreated, e.Type, "bob sees only the change to 1")
+	assert.Equal(t, "root", e.Component.Name)
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/watch", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "a request without the handshake is refused")
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, "/components/watch", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"component-service/store"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// errorCodeIllegalTransition is the code of a 409 for a status change the lifecycle does not allow
+// from the component's current status; see store.ComponentStatuses.
+const errorCodeIllegalTransition = "illegal_transition"
+
+// setComponentStatus serves POST /components/{id}/status, which moves a component along its
+// lifecycle. The body is {"status": "deprecated"}. The updated component is returned; setting the
+// status it already has changes nothing.
+func setComponentStatus(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := decodeBody(r, &body, true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	if body.Status == "" {
+		respondWithError(w, http.StatusBadRequest, "status is required: the status to move the component to")
+		return
+	}
+
+	if err := writeStore(r).SetComponentStatus(id, body.Status); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidStatus):
+			respondWithInvalidStatus(w, err, body.Status)
+		case errors.Is(err, store.ErrIllegalTransition):
+			respondWithJSON(w, http.StatusConflict, codedErrorResponse{Error: err.Error(), Code: errorCodeIllegalTransition})
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error setting component status: "+err.Error())
+		}
+		return
+	}
+	updated, err := componentStore.GetComponentByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error fetching updated component: "+err.Error())
+		return
+	}
+	respondWithComponent(w, http.StatusOK, updated)
+}
+
+// respondWithInvalidStatus sends the 422 for a write failing with store.ErrInvalidStatus, naming
+// the field and the status given.
+func respondWithInvalidStatus(w http.ResponseWriter, err error, status string) {
+	respondWithJSON(w, http.StatusUnprocessableEntity, invalidFieldResponse{Error: err.Error(), Field: "status", Value: status})
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"component-service/db"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetComponentStatusValidation(t *testing.T) {
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodPost, "/components/2/status", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/2/status", `{"status": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/2/status", `{"status": "active", "reason": "x"}`, http.StatusBadRequest},
+		{http.MethodPost, "/components/x/status", `{"status": "active"}`, http.StatusBadRequest},
+		{http.MethodGet, "/components/2/status", ``, http.StatusMethodNotAllowed},
+		{http.MethodPatch, "/components/2", `{"status": "active"}`, http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, tc.want, rr.Code, "%s %s %s: %s", tc.method, tc.target, tc.body, rr.Body.String())
+	}
+
+	for _, tc := range []struct{ target, body string }{
+		{"/components/2/status", `{"status": "archived"}`},
+		{"/components", `{"name": "pump", "status": "archived"}`},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, tc.target)
+		assert.JSONEq(t, `{"error": "invalid status \"archived\": expected one of draft, active, deprecated, retired", "field": "status", "value": "archived"}`, rr.Body.String(), tc.target)
+	}
+}
+
+func TestSetComponentStatus(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	comp := createTestComponentDirectly(t, "Pump", "", sql.NullInt64{})
+	assert.Equal(t, "draft", comp.Status)
+	target := fmt.Sprintf("/components/%d/status", comp.ID)
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(`{"status": "active"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"status":"active"`)
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(`{"status": "retired"}`)))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"error": "illegal status transition: component %d cannot move from active to retired; allowed: deprecated", "code": "illegal_transition"}`, comp.ID), rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/components/%d/status", comp.ID+1000), bytes.NewBufferString(`{"status": "active"}`)))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
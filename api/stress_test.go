@@ -0,0 +1,376 @@
+//go:build stress
+
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/db"
+	"component-service/fixtures"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The stress tests run only with the stress build tag, for STRESS_DURATION (default 10s), and are
+// meant for the race detector:
+//
+//	go test -tags stress -race -run Stress ./cache ./api
+//
+// Readers send every kind of GET while writers change the tree. Every 20ms the writers pause and
+// the views the endpoints give of the tree are compared.
+
+// stressDuration reads STRESS_DURATION, a Go duration.
+func stressDuration(t *testing.T) time.Duration {
+	value := os.Getenv("STRESS_DURATION")
+	if value == "" {
+		return 10 * time.Second
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		t.Fatalf("invalid STRESS_DURATION %q: %v", value, err)
+	}
+	return d
+}
+
+// stressGets are the reads sent by the stress tests, %d standing for a random component ID.
+var stressGets = []string{
+	"/components?limit=20",
+	"/components?name=pump&sort=name",
+	"/components?after=&limit=10",
+	"/components/%d",
+	"/components/%d/children?sort=name",
+	"/components/%d/descendants?limit=50",
+	"/components/%d/tree?depth=3",
+	"/components/tree?depth=2",
+	"/components/%d/checksum",
+	"/components/%d/graph-data?depth=2",
+	"/components/flat",
+	"/components/search?q=pump",
+	"/components/by-path?path=/plant/line-a",
+	"/components/slug/pump",
+	"/sync/checkpoint",
+}
+
+// stressTree tracks the components the writers may change: those they created. Created components
+// are only ever placed under the components present at the start, or at the root, so concurrent
+// moves cannot form a cycle.
+type stressTree struct {
+	mu      sync.Mutex
+	fixed   []int64
+	created []int64
+}
+
+// parent returns a random component present at the start, or a root.
+func (s *stressTree) parent(rng *rand.Rand) sql.NullInt64 {
+	if i := rng.Intn(len(s.fixed) + 1); i < len(s.fixed) {
+		return sql.NullInt64{Int64: s.fixed[i], Valid: true}
+	}
+	return sql.NullInt64{}
+}
+
+// take removes and returns a random created component, so no other writer changes it meanwhile.
+// Callers return it with add unless they deleted it.
+func (s *stressTree) take(rng *rand.Rand) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.created) == 0 {
+		return 0, false
+	}
+	i := rng.Intn(len(s.created))
+	id := s.created[i]
+	s.created = append(s.created[:i], s.created[i+1:]...)
+	return id, true
+}
+
+func (s *stressTree) add(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created = append(s.created, id)
+}
+
+// stressRun runs readers and writers until STRESS_DURATION passes. Every 20ms it stops the writers
+// and runs check. write makes one random write and returns an error for a failure.
+func stressRun(t *testing.T, write func(rng *rand.Rand) error, check func() error) {
+	const writers, readers = 2, 6
+	ctx, stop := context.WithTimeout(context.Background(), stressDuration(t))
+	defer stop()
+	var pause sync.RWMutex // writes share it; checks take it alone, to see the tree at rest
+	var wg sync.WaitGroup
+	run := func(seed int64, step func(rng *rand.Rand) error, exclusive bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for ctx.Err() == nil {
+				if exclusive {
+					pause.RLock()
+				}
+				err := step(rng)
+				if exclusive {
+					pause.RUnlock()
+				}
+				if err != nil {
+					t.Error(err)
+					stop()
+				}
+			}
+		}()
+	}
+	for i := 0; i < writers; i++ {
+		run(int64(i), write, true)
+	}
+	for i := 0; i < readers; i++ {
+		run(int64(writers+i), stressGet, false)
+	}
+
+	checks := 0
+	for ticker := time.NewTicker(20 * time.Millisecond); ctx.Err() == nil; <-ticker.C {
+		pause.Lock()
+		err := check()
+		pause.Unlock()
+		if err != nil {
+			t.Errorf("after %d checks: %v", checks, err)
+			stop()
+		}
+		checks++
+	}
+	wg.Wait()
+	t.Logf("%d consistency checks", checks)
+}
+
+// stressGet sends one random read and fails on a server error or a JSON body that does not parse.
+func stressGet(rng *rand.Rand) error {
+	target := stressGets[rng.Intn(len(stressGets))]
+	if strings.Contains(target, "%d") {
+		target = fmt.Sprintf(target, rng.Intn(cache.GlobalComponentCache.Count()+10)+1)
+	}
+	rr := stressServe(http.MethodGet, target, "", "")
+	if rr.Code >= 500 && rr.Code != http.StatusServiceUnavailable {
+		return fmt.Errorf("GET %s: %d %s", target, rr.Code, rr.Body.String())
+	}
+	if rr.Code == http.StatusOK && strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") && !json.Valid(rr.Body.Bytes()) {
+		return fmt.Errorf("GET %s returned invalid JSON: %s", target, rr.Body.String())
+	}
+	return nil
+}
+
+// stressServe sends a request to the handler serving its path.
+func stressServe(method, target, body, consistency string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	if consistency != "" {
+		req.Header.Set("X-Consistency", consistency)
+	}
+	if strings.HasPrefix(target, "/sync/") {
+		SyncHandler(rr, req)
+	} else {
+		ComponentsHandler(rr, req)
+	}
+	return rr
+}
+
+// checkStressViews compares the component count of the list, the tree and the sync checkpoint.
+func checkStressViews() error {
+	rr := stressServe(http.MethodGet, "/components?limit=1", "", "")
+	total, err := strconv.Atoi(rr.Header().Get("X-Total-Count"))
+	if err != nil {
+		return fmt.Errorf("GET /components: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = stressServe(http.MethodGet, "/components/tree", "", "")
+	var forest []treeCount
+	if err := json.Unmarshal(rr.Body.Bytes(), &forest); err != nil {
+		return fmt.Errorf("GET /components/tree: %w", err)
+	}
+	inTree := 0
+	for _, root := range forest {
+		inTree += root.count()
+	}
+
+	rr = stressServe(http.MethodGet, "/sync/checkpoint?include=components", "", "")
+	var checkpoint cache.SyncCheckpoint
+	if err := json.Unmarshal(rr.Body.Bytes(), &checkpoint); err != nil {
+		return fmt.Errorf("GET /sync/checkpoint: %w", err)
+	}
+
+	if inTree != total || len(checkpoint.Components) != total {
+		return fmt.Errorf("the list counts %d components, the tree %d and the checkpoint %d", total, inTree, len(checkpoint.Components))
+	}
+	return nil
+}
+
+// treeCount decodes a tree node only as far as counting it needs.
+type treeCount struct {
+	Children []treeCount `json:"children"`
+}
+
+func (n treeCount) count() int {
+	total := 1
+	for _, child := range n.Children {
+		total += child.count()
+	}
+	return total
+}
+
+// TestStress_Handlers applies writes to the cache directly, as the CDC consumer and follower sync
+// do, while the handlers serve reads from it. It needs no database.
+func TestStress_Handlers(t *testing.T) {
+	fixtures.MustLoadCache(t, "plant")
+	c := cache.GlobalComponentCache
+	tree := &stressTree{}
+	for _, comp := range c.GetAll() {
+		tree.fixed = append(tree.fixed, comp.ID)
+	}
+	var nextID int64 = 1000 // written by one writer at a time, under tree.mu
+
+	write := func(rng *rand.Rand) error {
+		id, ok := tree.take(rng)
+		if !ok || rng.Intn(3) == 0 {
+			if ok {
+				tree.add(id)
+			}
+			tree.mu.Lock()
+			nextID++
+			id := nextID
+			tree.mu.Unlock()
+			c.Set(&models.Component{ID: id, Name: "pump", Slug: fmt.Sprintf("pump-%d", id), ParentID: tree.parent(rng)})
+			tree.add(id)
+			return nil
+		}
+		comp, found := c.GetByID(id)
+		if !found {
+			return fmt.Errorf("created component %d is missing from the cache", id)
+		}
+		changed := *comp
+		switch rng.Intn(4) {
+		case 0:
+			changed.Name = fmt.Sprintf("valve %d", rng.Intn(10))
+			c.Set(&changed)
+		case 1:
+			changed.ParentID = tree.parent(rng)
+			c.Set(&changed)
+		case 2:
+			other, ok := tree.take(rng)
+			if !ok {
+				break
+			}
+			otherComp, _ := c.GetByID(other)
+			moved := *otherComp
+			changed.ParentID, moved.ParentID = tree.parent(rng), tree.parent(rng)
+			c.SetMany([]*models.Component{&changed, &moved})
+			tree.add(other)
+		default:
+			c.Delete(id)
+			return nil
+		}
+		tree.add(id)
+		return nil
+	}
+	stressRun(t, write, checkStressViews)
+}
+
+// TestStress_HandlerWrites sends writes through the handlers as well, so they reach the database
+// and the cache through the store. Each check also compares the cached listing with a strong one.
+func TestStress_HandlerWrites(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping stress test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	t.Cleanup(clearComponentsTableForAPITests)
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	tree := &stressTree{}
+	for i := 0; i < 5; i++ {
+		tree.fixed = append(tree.fixed, createTestComponentDirectly(t, fmt.Sprintf("Root %d", i), "", sql.NullInt64{}).ID)
+	}
+	if err := cache.InitGlobalCache(testAPIStore); err != nil {
+		t.Fatal(err)
+	}
+
+	parentJSON := func(rng *rand.Rand) string {
+		if parent := tree.parent(rng); parent.Valid {
+			return strconv.FormatInt(parent.Int64, 10)
+		}
+		return "null"
+	}
+	write := func(rng *rand.Rand) error {
+		id, ok := tree.take(rng)
+		var rr *httptest.ResponseRecorder
+		switch op := rng.Intn(4); {
+		case !ok || op == 0:
+			if ok {
+				tree.add(id)
+			}
+			rr = stressServe(http.MethodPost, "/components", fmt.Sprintf(`{"name": "pump", "parent_id": %s}`, parentJSON(rng)), "")
+			var created models.Component
+			if rr.Code == http.StatusCreated && json.Unmarshal(rr.Body.Bytes(), &created) == nil {
+				tree.add(created.ID)
+				return nil
+			}
+		case op == 1:
+			rr = stressServe(http.MethodPatch, fmt.Sprintf("/components/%d", id), fmt.Sprintf(`{"name": "valve %d"}`, rng.Intn(10)), "")
+			tree.add(id)
+		case op == 2:
+			rr = stressServe(http.MethodPost, fmt.Sprintf("/components/%d/move", id), fmt.Sprintf(`{"new_parent_id": %s}`, parentJSON(rng)), "")
+			tree.add(id)
+		default:
+			rr = stressServe(http.MethodDelete, fmt.Sprintf("/components/%d", id), "", "")
+			if rr.Code == http.StatusOK {
+				return nil
+			}
+		}
+		if rr.Code >= 300 {
+			return fmt.Errorf("write answered %d: %s", rr.Code, rr.Body.String())
+		}
+		return nil
+	}
+	check := func() error {
+		if err := checkStressViews(); err != nil {
+			return err
+		}
+		cached, err := stressListing("cached")
+		if err != nil {
+			return err
+		}
+		strong, err := stressListing("strong")
+		if err != nil {
+			return err
+		}
+		if cached != strong {
+			return fmt.Errorf("the cached listing differs from the database's:\n%s\n%s", cached, strong)
+		}
+		return nil
+	}
+	stressRun(t, write, check)
+}
+
+// stressListing lists every component with the given consistency, as sorted "id name parent" lines.
+func stressListing(consistency string) (string, error) {
+	rr := stressServe(http.MethodGet, "/components?fields=id,name,parent_id", "", consistency)
+	var components []struct {
+		ID       int64           `json:"id"`
+		Name     string          `json:"name"`
+		ParentID json.RawMessage `json:"parent_id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &components); err != nil {
+		return "", fmt.Errorf("GET /components with %s consistency: %d %s", consistency, rr.Code, rr.Body.String())
+	}
+	lines := make([]string, len(components))
+	for i, comp := range components {
+		lines[i] = fmt.Sprintf("%d %q %s", comp.ID, comp.Name, comp.ParentID)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
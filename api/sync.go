@@ -0,0 +1,125 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SyncHandler routes the differential sync protocol under /sync/:
+// GET /sync/checkpoint and GET /sync/delta?since={checkpoint}.
+func SyncHandler(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // e.g., ["sync", "delta"]
+
+	if len(pathParts) != 2 || pathParts[0] != "sync" || (pathParts[1] != "checkpoint" && pathParts[1] != "delta") {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Sync requires the component cache, which is not initialized")
+		return
+	}
+	if pathParts[1] == "checkpoint" {
+		getSyncCheckpoint(w, r)
+	} else {
+		getSyncDelta(w, r)
+	}
+}
+
+// getSyncCheckpoint returns the current checkpoint token and root subtree hashes. With
+// ?include=components the full component list is returned alongside, consistent with the token.
+// With ACLs, the components the principal cannot read are left out, and so are the hashes, which
+// cover them.
+func getSyncCheckpoint(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	includeComponents := q.oneOf("include", "", "components") == "components"
+	if !q.valid(w) {
+		return
+	}
+	checkpoint := cache.GlobalComponentCache.Checkpoint(includeComponents)
+	if readable := syncFilter(r); readable != nil {
+		checkpoint.Hash, checkpoint.Roots = "", []cache.SubtreeHash{}
+		if includeComponents {
+			visible := make([]*models.Component, 0, len(checkpoint.Components))
+			for _, comp := range checkpoint.Components {
+				if readable(comp.ID) {
+					visible = append(visible, comp)
+				}
+			}
+			checkpoint.Components = visible
+		}
+	}
+	respondWithJSON(w, http.StatusOK, checkpoint)
+}
+
+// getSyncDelta returns the components changed and deleted since ?since. A checkpoint whose
+// changes are no longer retained yields 410 Gone: the client must start over from a full checkpoint.
+// With ACLs, a changed component the principal cannot read is listed as deleted, so a copy drops
+// it, and the hashes are left out.
+func getSyncDelta(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	since := q.str("since")
+	if since == "" {
+		q.reject("since", "the checkpoint token from a previous checkpoint or delta")
+	}
+	if !q.valid(w) {
+		return
+	}
+	delta, err := cache.GlobalComponentCache.Delta(since)
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrCheckpointExpired):
+			respondWithError(w, http.StatusGone, "Checkpoint expired; fetch GET /sync/checkpoint?include=components and resync from scratch")
+		case errors.Is(err, cache.ErrInvalidCheckpoint):
+			respondWithError(w, http.StatusBadRequest, "Invalid since parameter: not a checkpoint token issued by this service")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error computing delta: "+err.Error())
+		}
+		return
+	}
+	if readable := syncFilter(r); readable != nil {
+		delta.Hash, delta.Branches = "", []cache.SubtreeHash{}
+		visible := make([]*models.Component, 0, len(delta.Upserted))
+		for _, comp := range delta.Upserted {
+			if readable(comp.ID) {
+				visible = append(visible, comp)
+			} else {
+				delta.Deleted = append(delta.Deleted, comp.ID)
+			}
+		}
+		delta.Upserted = visible
+	}
+	respondWithJSON(w, http.StatusOK, delta)
+}
+
+// ComponentChecksum is the response body of GET /components/{id}/checksum.
+type ComponentChecksum struct {
+	ID        int64  `json:"id"`
+	Hash      string `json:"hash"`
+	Algorithm string `json:"algorithm"`
+}
+
+// getComponentChecksum returns the Merkle hash of the subtree rooted at id. The cache maintains
+// it incrementally, so the call is constant-time regardless of subtree size.
+func getComponentChecksum(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Checksums require the component cache, which is not initialized")
+		return
+	}
+	hash, found := cache.GlobalComponentCache.SubtreeHash(id)
+	if !found {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("component with ID %d not found", id))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, ComponentChecksum{ID: id, Hash: hash, Algorithm: "sha256-merkle"})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"component-service/models"
+	"net/http"
+)
+
+// parseTag reads ?tag=, which keeps only the components having that tag. Absent or empty,
+// components are listed whatever their tags.
+func parseTag(q *queryParams) string {
+	tag := q.str("tag")
+	if tag != "" && !models.IsTagName(tag) {
+		q.reject("tag", "a tag: a lowercase letter followed by lowercase letters, digits, - and _")
+	}
+	return tag
+}
+
+// respondWithInvalidTags sends the 422 for a write failing with store.ErrInvalidTags, naming the
+// field and the tags given in it.
+func respondWithInvalidTags(w http.ResponseWriter, err error, field string, tags []string) {
+	respondWithJSON(w, http.StatusUnprocessableEntity, invalidFieldResponse{Error: err.Error(), Field: field, Value: tags})
+}
+
+// patchTagsField returns the field of a PATCH body to blame for store.ErrInvalidTags: add_tags
+// when it holds a malformed tag or the patch replaces no tags, tags otherwise. remove_tags is
+// never at fault, as removing a tag a component lacks changes nothing.
+func patchTagsField(patch models.ComponentPatch) (string, []string) {
+	for _, tag := range patch.AddTags {
+		if !models.IsTagName(tag) {
+			return "add_tags", patch.AddTags
+		}
+	}
+	if patch.Tags != nil {
+		return "tags", *patch.Tags
+	}
+	return "add_tags", patch.AddTags
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"component-service/fixtures"
+	"component-service/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListByTag(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "plant")
+	names := func(body []byte) []string {
+		var components []struct {
+			Name string
+			Tags []string
+		}
+		require.NoError(t, json.Unmarshal(body, &components))
+		var names []string
+		for _, comp := range components {
+			names = append(names, comp.Name+"["+strings.Join(comp.Tags, ",")+"]")
+		}
+		return names
+	}
+
+	for _, tc := range []struct {
+		target string
+		want   []string
+		total  string
+	}{
+		{"/components?tag=critical&sort=name", []string{"pump[critical,rotating]", "valve[critical]"}, "2"},
+		{"/components?tag=rotating&type=device", []string{"pump[rotating]", "pump[critical,rotating]"}, "2"},
+		{"/components?tag=spare", nil, "0"},
+		{fmt.Sprintf("/components/%d/children?tag=rotating", w.ID("line-b")), []string{"pump[rotating]"}, "1"},
+		{fmt.Sprintf("/components/%d/descendants?tag=critical", w.ID("plant")), []string{"pump[critical,rotating]", "valve[critical]"}, "2"},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if assert.Equal(t, http.StatusOK, rr.Code, "%s: %s", tc.target, rr.Body.String()) {
+			assert.Equal(t, tc.want, names(rr.Body.Bytes()), tc.target)
+			assert.Equal(t, tc.total, rr.Header().Get("X-Total-Count"), tc.target)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/search?q=pump&tag=critical", nil))
+	if assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+		var results []searchResult
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+		if assert.Len(t, results, 1) {
+			assert.Contains(t, string(results[0].Component), "Line A feed pump")
+		}
+	}
+
+	for _, target := range []string{
+		"/components?tag=Critical",
+		fmt.Sprintf("/components/%d/children?tag=a%%20b", w.ID("plant")),
+		fmt.Sprintf("/components/%d/descendants?tag=-", w.ID("plant")),
+		"/components/search?q=pump&tag=_",
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+	}
+}
+
+func TestWriteInvalidTags(t *testing.T) {
+	fixtures.MustLoadCache(t, "plant")
+
+	for _, tc := range []struct{ method, target, body string }{
+		{http.MethodPost, "/components", `{"name": "pump", "tags": ["critical", "Spare"]}`},
+		{http.MethodPut, "/components/2", `{"name": "pump", "tags": ["critical", "Spare"]}`},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, tc.method)
+		assert.JSONEq(t, `{"error": "invalid tags: \"Spare\" is not a tag: expected a lowercase letter followed by lowercase letters, digits, - and _", "field": "tags", "value": ["critical", "Spare"]}`, rr.Body.String(), tc.method)
+	}
+}
+
+func TestPatchTagsField(t *testing.T) {
+	replaced := []string{"critical"}
+	for _, tc := range []struct {
+		patch models.ComponentPatch
+		field string
+	}{
+		{models.ComponentPatch{Tags: &replaced, AddTags: []string{"Spare"}}, "add_tags"},
+		{models.ComponentPatch{Tags: &replaced, AddTags: []string{"spare"}}, "tags"},
+		{models.ComponentPatch{AddTags: []string{"spare"}, RemoveTags: []string{"Bad"}}, "add_tags"},
+	} {
+		field, _ := patchTagsField(tc.patch)
+		assert.Equal(t, tc.field, field, "%+v", tc.patch)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"component-service/cache"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// getTree serves GET /components/tree and GET /components/{id}/tree: the whole hierarchy, or the
+// subtree rooted at rootID, as nested JSON with each component's children under "children". The
+// tree is built in one response, so it is capped at CHILDREN_MAX_UNPAGINATED components. ?depth=N
+// stops N levels below the roots, so a UI can expand the tree lazily; components at that level come
+// without "children". ?include=mounts grafts the subtrees mounted from other instances under their
+// local parents, each mounted root marked with "mount". With ACLs, a component the caller cannot
+// read but with readable descendants is a placeholder holding only its ID and "hidden":true.
+func getTree(w http.ResponseWriter, r *http.Request, rootID *int64) {
+	q := newQueryParams(r)
+	depth := parseDepth(q)
+	includeMounts := parseIncludeMounts(q)
+	if !q.valid(w) {
+		return
+	}
+	var etag string // Mounted subtrees change outside the cache
+	if !includeMounts {
+		etag = cachedETag(r)
+		if notModified(w, r, etag) {
+			return
+		}
+	}
+	maxNodes := MaxUnpaginatedChildren
+	opts := cache.TreeOptions{MaxDepth: depth, MaxNodes: maxNodes, Keep: readableFilter(r)}
+	if includeMounts {
+		opts.Grafts = mounts.Grafts
+	}
+	var body []byte
+	var err error
+	if rootID == nil {
+		body, err = readStore(r).GetForestJSON(opts)
+	} else {
+		body, err = readStore(r).GetTreeJSON(*rootID, opts)
+	}
+	switch {
+	case err == nil && rootID != nil && string(body) == "null": // nothing readable in the subtree
+		principal, _ := principalFrom(r)
+		respondForbidden(w, principal, cache.PermissionRead, *rootID)
+	case err == nil:
+		setETag(w, etag)
+		respondWithRawJSON(w, http.StatusOK, body)
+	case errors.Is(err, cache.ErrTreeTooLarge):
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf(
+			"The tree has more than the %d components that can be returned at once; page through GET /components/{id}/descendants instead",
+			maxNodes))
+	case strings.Contains(err.Error(), "not found"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error building tree: "+err.Error())
+	}
+}
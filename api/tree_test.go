@@ -0,0 +1,56 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTree(t *testing.T) {
+	defer func(c *cache.ComponentCache, cfg cache.Config) {
+		cache.GlobalComponentCache, cache.GlobalConfig = c, cfg
+	}(cache.GlobalComponentCache, cache.GlobalConfig)
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		{ID: 1, Name: "root"},
+		{ID: 2, Name: "child", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		{ID: 3, Name: "other"},
+	}})
+	assert.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	child := `{"id":2,"name":"child","description":"","parent_id":{"Int64":1,"Valid":true},"position":0,"children":[]}`
+	root := `{"id":1,"name":"root","description":"","parent_id":{"Int64":0,"Valid":false},"position":0,"children":[` + child + `]}`
+	other := `{"id":3,"name":"other","description":"","parent_id":{"Int64":0,"Valid":false},"position":0,"children":[]}`
+
+	rr := get("/components/1/tree")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, root, rr.Body.String())
+
+	rr = get("/components/tree")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "["+root+","+other+"]", rr.Body.String())
+
+	// ?depth=1 stops below the roots; the children come without "children".
+	rr = get("/components/1/tree?depth=1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"id":1,"name":"root","description":"","parent_id":{"Int64":0,"Valid":false},"position":0,"children":[`+
+		`{"id":2,"name":"child","description":"","parent_id":{"Int64":1,"Valid":true},"position":0}]}`, rr.Body.String())
+	assert.Equal(t, http.StatusBadRequest, get("/components/tree?depth=0").Code)
+
+	assert.Equal(t, http.StatusNotFound, get("/components/99/tree").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/components/tree?limit=1").Code)
+
+	defer func(max int) { MaxUnpaginatedChildren = max }(MaxUnpaginatedChildren)
+	MaxUnpaginatedChildren = 2
+	assert.Equal(t, http.StatusBadRequest, get("/components/tree").Code)
+	assert.Equal(t, http.StatusOK, get("/components/1/tree").Code)
+}
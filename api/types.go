@@ -0,0 +1,22 @@
+package api
+
+import (
+	"component-service/models"
+	"net/http"
+)
+
+// parseType reads ?type=, which keeps only the components of that type in a listing. Absent or
+// empty, components of every type are listed.
+func parseType(q *queryParams) string {
+	t := q.str("type")
+	if t != "" && !models.IsTypeName(t) {
+		q.reject("type", "a component type: a lowercase letter followed by lowercase letters, digits, - and _")
+	}
+	return t
+}
+
+// respondWithInvalidType sends the 422 for a write failing with store.ErrInvalidType, naming the
+// field and the type given.
+func respondWithInvalidType(w http.ResponseWriter, err error, t string) {
+	respondWithJSON(w, http.StatusUnprocessableEntity, invalidFieldResponse{Error: err.Error(), Field: "type", Value: t})
+}
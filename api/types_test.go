@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"component-service/fixtures"
+	"component-service/models"
+	"component-service/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListByType(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "plant")
+	names := func(body []byte) []string {
+		var components []struct{ Name, Type string }
+		require.NoError(t, json.Unmarshal(body, &components))
+		var names []string
+		for _, comp := range components {
+			names = append(names, comp.Type+":"+comp.Name)
+		}
+		return names
+	}
+
+	for _, tc := range []struct {
+		target string
+		want   []string
+		total  string
+	}{
+		{"/components?type=line&sort=name", []string{"line:line-a", "line:line-b"}, "2"},
+		{"/components?type=device&name=pump", []string{"device:pump", "device:pump"}, "2"},
+		{"/components?type=folder", nil, "0"},
+		{fmt.Sprintf("/components/%d/children?type=device", w.ID("line-a")), []string{"device:pump", "device:valve"}, "2"},
+		{fmt.Sprintf("/components/%d/children?type=device&limit=1&offset=1", w.ID("line-a")), []string{"device:valve"}, "2"},
+		{fmt.Sprintf("/components/%d/descendants?type=device", w.ID("plant")), []string{"device:pump", "device:valve", "device:pump"}, "3"},
+		{fmt.Sprintf("/components/%d/descendants?type=line&depth=1", w.ID("plant")), []string{"line:line-a", "line:line-b"}, "2"},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if assert.Equal(t, http.StatusOK, rr.Code, "%s: %s", tc.target, rr.Body.String()) {
+			assert.Equal(t, tc.want, names(rr.Body.Bytes()), tc.target)
+			assert.Equal(t, tc.total, rr.Header().Get("X-Total-Count"), tc.target)
+		}
+	}
+
+	for _, target := range []string{
+		"/components?type=Line",
+		fmt.Sprintf("/components/%d/children?type=a%%20b", w.ID("plant")),
+		fmt.Sprintf("/components/%d/descendants?type=-", w.ID("plant")),
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+	}
+}
+
+func TestWriteUnknownType(t *testing.T) {
+	fixtures.MustLoadCache(t, "plant")
+	registry, err := models.ParseTypeRegistry("folder,service,device")
+	require.NoError(t, err)
+	store.ComponentTypes = registry
+	defer func() { store.ComponentTypes = nil }()
+
+	for _, tc := range []struct{ method, target, body string }{
+		{http.MethodPost, "/components", `{"name": "pump", "type": "gadget"}`},
+		{http.MethodPut, "/components/2", `{"name": "pump", "type": "gadget"}`},
+		{http.MethodPatch, "/components/2", `{"type": "gadget"}`},
+	} {
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, tc.method)
+		assert.JSONEq(t, `{"error": "invalid component type \"gadget\": expected one of folder, service, device", "field": "type", "value": "gadget"}`, rr.Body.String(), tc.method)
+	}
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPatch, "/components/2", bytes.NewBufferString(`{"type": 7}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"component-service/store"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Defaults and caps of GET and POST /admin/unreferenced.
+const (
+	defaultUnreferencedIdleDays = 90
+	maxUnreferencedIdleDays     = 3650
+	defaultUnreferencedLimit    = 100
+	maxUnreferencedLimit        = 1000
+	defaultUnreferencedTag      = "unreferenced"
+)
+
+// unreferencedReport is the response of /admin/unreferenced.
+type unreferencedReport struct {
+	IdleSince time.Time `json:"idle_since"`
+	// ViewsRecordedSince is when this instance started recording reads; a component read only
+	// before then counts as never viewed.
+	ViewsRecordedSince time.Time           `json:"views_recorded_since"`
+	Total              int                 `json:"total"`
+	Components         []*models.Component `json:"components"` // ordered by ID, at most ?limit
+	Tag                string              `json:"tag,omitempty"`
+	Tagged             int                 `json:"tagged"` // components POST added the tag to, skipping those at models.MaxTags or deleted
+}
+
+// unreferencedHandler serves /admin/unreferenced, a garbage collection report of the leaf
+// components nothing refers to, which no one has created, updated or viewed in ?idle_days. GET
+// lists them; POST also adds ?tag to each one listed that does not have it yet, so they can be
+// reviewed with GET /components?tag=. Tagging updates a component, which takes it off the report
+// until it is idle again.
+func unreferencedHandler(w http.ResponseWriter, r *http.Request) {
+	q := newQueryParams(r)
+	idleDays := q.intRange("idle_days", defaultUnreferencedIdleDays, 0, maxUnreferencedIdleDays)
+	limit := q.intRange("limit", defaultUnreferencedLimit, 1, maxUnreferencedLimit)
+	tag := ""
+	if r.Method == http.MethodPost {
+		if tag = parseTag(q); tag == "" {
+			tag = defaultUnreferencedTag
+		}
+	}
+	if !q.valid(w) {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Component cache is not initialized")
+		return
+	}
+
+	report := unreferencedReport{
+		IdleSince:          time.Now().UTC().AddDate(0, 0, -idleDays).Truncate(time.Second),
+		ViewsRecordedSince: cache.GlobalComponentCache.ViewsRecordedSince().Truncate(time.Second),
+		Tag:                tag,
+	}
+	components := cache.GlobalComponentCache.Unreferenced(report.IdleSince)
+	report.Total = len(components)
+	if len(components) > limit {
+		components = components[:limit]
+	}
+	report.Components = components
+	if report.Components == nil {
+		report.Components = []*models.Component{}
+	}
+	if tag != "" {
+		for _, comp := range components {
+			if comp.HasTag(tag) {
+				continue
+			}
+			err := writeStore(r).PatchComponent(comp.ID, models.ComponentPatch{AddTags: []string{tag}})
+			if errors.Is(err, store.ErrInvalidTags) || (err != nil && strings.Contains(err.Error(), "not found")) {
+				continue // it has as many tags as it may, or was deleted since the report
+			}
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error tagging unreferenced components: "+err.Error())
+				return
+			}
+			report.Tagged++
+		}
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// recordView notes a read of a single component for the unreferenced report.
+func recordView(id int64) {
+	if cache.GlobalComponentCache != nil {
+		cache.GlobalComponentCache.RecordView(id)
+	}
+}
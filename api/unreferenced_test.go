@@ -0,0 +1,76 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnreferencedReport(t *testing.T) {
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	const old = "2020-01-01T00:00:00Z"
+	idle := func(id, parentID int64, description string) *models.Component {
+		comp := &models.Component{ID: id, Name: "comp", Description: description, CreatedAt: old, UpdatedAt: old}
+		if parentID != 0 {
+			comp.ParentID = sql.NullInt64{Int64: parentID, Valid: true}
+		}
+		return comp
+	}
+	err := cache.InitGlobalCache(&publicTestStore{components: []*models.Component{
+		idle(1, 0, ""), idle(2, 1, ""), idle(3, 0, "See /components/1."), idle(4, 0, ""), idle(5, 0, ""),
+	}})
+	require.NoError(t, err)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		AdminHandler(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+	report := func(target string) unreferencedReport {
+		rr := serve(http.MethodGet, target)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var report unreferencedReport
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		return report
+	}
+	ids := func(components []*models.Component) []int64 {
+		ids := []int64{}
+		for _, comp := range components {
+			ids = append(ids, comp.ID)
+		}
+		return ids
+	}
+
+	got := report("/admin/unreferenced")
+	assert.Equal(t, []int64{2, 4, 5}, ids(got.Components)) // 1 has a child and a backlink; 3 refers to 1
+	assert.Equal(t, 3, got.Total)
+	assert.Empty(t, got.Tag)
+
+	got = report("/admin/unreferenced?limit=1")
+	assert.Equal(t, []int64{2}, ids(got.Components))
+	assert.Equal(t, 3, got.Total)
+
+	// Reading a component counts as a view.
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodGet, "/components/4", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []int64{2, 5}, ids(report("/admin/unreferenced").Components))
+
+	// Nothing was idle for longer than the components' age.
+	assert.Empty(t, report("/admin/unreferenced?idle_days=3650").Components)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/unreferenced?idle_days=-1").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/unreferenced?tag=review").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/admin/unreferenced?tag=Not+A+Tag").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/admin/unreferenced").Code)
+
+	cache.GlobalComponentCache = nil
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodGet, "/admin/unreferenced").Code)
+}
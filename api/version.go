@@ -0,0 +1,90 @@
+package api
+
+import (
+	"component-service/models"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RequireIfMatch makes PUT and PATCH /components/{id} refuse a write that names no version, in an
+// If-Match header or a version field, with 428 Precondition Required, so no client can overwrite a
+// change it has not seen.
+var RequireIfMatch bool
+
+// RequireIfMatchFromEnv reads REQUIRE_IF_MATCH, a boolean that defaults to false.
+func RequireIfMatchFromEnv() (bool, error) {
+	value := os.Getenv("REQUIRE_IF_MATCH")
+	if value == "" {
+		return false, nil
+	}
+	require, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid REQUIRE_IF_MATCH %q: expected true or false", value)
+	}
+	return require, nil
+}
+
+// errorCodeVersionConflict is the code of a 409 for a write whose version field names a version
+// the component has moved past; see store.ErrVersionConflict.
+const errorCodeVersionConflict = "version_conflict"
+
+// versionETag returns the entity tag of a component at version.
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// respondWithComponent sends a single component, with its version as the ETag.
+func respondWithComponent(w http.ResponseWriter, code int, comp *models.Component) {
+	if comp.Version > 0 {
+		w.Header().Set("ETag", versionETag(comp.Version))
+	}
+	respondWithJSON(w, code, comp)
+}
+
+// expectedVersion returns the version a write expects the component at: the If-Match header's,
+// or else bodyVersion, the body's version field, with 0 for any. fromHeader reports whether it came
+// from If-Match, whose mismatch is answered 412 rather than 409. When the request is not
+// acceptable, it responds and returns ok false.
+func expectedVersion(w http.ResponseWriter, r *http.Request, bodyVersion int64) (version int64, fromHeader, ok bool) {
+	if bodyVersion < 0 {
+		respondWithError(w, http.StatusBadRequest, "version must be a positive integer")
+		return 0, false, false
+	}
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case ifMatch == "" && bodyVersion == 0 && RequireIfMatch:
+		respondWithError(w, http.StatusPreconditionRequired, "This write requires an If-Match header or a version field naming the version it changes")
+		return 0, false, false
+	case ifMatch == "":
+		return bodyVersion, false, true
+	case ifMatch == "*": // any current version
+		return bodyVersion, false, true
+	case strings.Contains(ifMatch, ","):
+		respondWithError(w, http.StatusBadRequest, "If-Match takes a single entity tag")
+		return 0, false, false
+	}
+	version, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(ifMatch, `"`), `"`), 10, 64)
+	if err != nil || version < 1 || ifMatch != versionETag(version) {
+		// A weak or foreign entity tag never matches a component's strong one.
+		respondWithError(w, http.StatusPreconditionFailed, fmt.Sprintf("If-Match %s matches no version of the component", ifMatch))
+		return 0, false, false
+	}
+	if bodyVersion != 0 && bodyVersion != version {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("The If-Match header names version %d but the version field %d", version, bodyVersion))
+		return 0, false, false
+	}
+	return version, true, true
+}
+
+// respondWithVersionConflict sends the response for a write failing with
+// store.ErrVersionConflict: 412 when If-Match named the version, and 409 otherwise.
+func respondWithVersionConflict(w http.ResponseWriter, err error, fromHeader bool) {
+	if fromHeader {
+		respondWithError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusConflict, codedErrorResponse{Error: err.Error(), Code: errorCodeVersionConflict})
+}
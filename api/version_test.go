@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"component-service/db"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireIfMatchFromEnv(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "false": false, "true": true} {
+		t.Setenv("REQUIRE_IF_MATCH", value)
+		required, err := RequireIfMatchFromEnv()
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, required, value)
+	}
+	t.Setenv("REQUIRE_IF_MATCH", "always")
+	_, err := RequireIfMatchFromEnv()
+	assert.ErrorContains(t, err, "invalid REQUIRE_IF_MATCH")
+}
+
+func TestExpectedVersion(t *testing.T) {
+	for _, tc := range []struct {
+		ifMatch     string
+		bodyVersion int64
+		require     bool
+		want        int64
+		fromHeader  bool
+		status      int // 0 when accepted
+	}{
+		{"", 0, false, 0, false, 0},
+		{"", 3, false, 3, false, 0},
+		{`"3"`, 0, false, 3, true, 0},
+		{`"3"`, 3, false, 3, true, 0},
+		{"*", 0, false, 0, false, 0},
+		{"*", 0, true, 0, false, 0},
+		{"", 2, true, 2, false, 0},
+		{"", 0, true, 0, false, http.StatusPreconditionRequired},
+		{`"3"`, 4, false, 0, false, http.StatusBadRequest},
+		{`"3", "4"`, 0, false, 0, false, http.StatusBadRequest},
+		{"", -1, false, 0, false, http.StatusBadRequest},
+		{`W/"3"`, 0, false, 0, false, http.StatusPreconditionFailed},
+		{`"v3"`, 0, false, 0, false, http.StatusPreconditionFailed},
+		{`"03"`, 0, false, 0, false, http.StatusPreconditionFailed},
+		{"3", 0, false, 0, false, http.StatusPreconditionFailed},
+	} {
+		RequireIfMatch = tc.require
+		r := httptest.NewRequest(http.MethodPut, "/components/1", nil)
+		if tc.ifMatch != "" {
+			r.Header.Set("If-Match", tc.ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		version, fromHeader, ok := expectedVersion(rr, r, tc.bodyVersion)
+		if tc.status != 0 {
+			assert.False(t, ok, "%q %d", tc.ifMatch, tc.bodyVersion)
+			assert.Equal(t, tc.status, rr.Code, "%q %d", tc.ifMatch, tc.bodyVersion)
+			continue
+		}
+		if assert.True(t, ok, "%q %d: %s", tc.ifMatch, tc.bodyVersion, rr.Body.String()) {
+			assert.Equal(t, tc.want, version, "%q %d", tc.ifMatch, tc.bodyVersion)
+			assert.Equal(t, tc.fromHeader, fromHeader, "%q %d", tc.ifMatch, tc.bodyVersion)
+		}
+	}
+	RequireIfMatch = false
+
+	rr := httptest.NewRecorder()
+	ComponentsHandler(rr, httptest.NewRequest(http.MethodPatch, "/components/1", bytes.NewBufferString(`{"name": "pump", "version": 0}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "a version must be positive")
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	comp := createTestComponentDirectly(t, "Pump", "", sql.NullInt64{})
+	require.Equal(t, int64(1), comp.Version)
+	target := fmt.Sprintf("/components/%d", comp.ID)
+	send := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		ComponentsHandler(rr, r)
+		return rr
+	}
+
+	rr := send(http.MethodGet, "", "")
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+	rr = send(http.MethodPut, `"1"`, `{"name": "Pump 2"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+	assert.Contains(t, rr.Body.String(), `"version":2`)
+
+	rr = send(http.MethodPut, `"1"`, `{"name": "Pump 3"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code, "a stale If-Match is refused")
+	rr = send(http.MethodPatch, "", `{"name": "Pump 3", "version": 1}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, "and so is a stale version field")
+	assert.JSONEq(t, fmt.Sprintf(`{"error": "version conflict: component %d is at version 2, not 1", "code": "version_conflict"}`, comp.ID), rr.Body.String())
+
+	rr = send(http.MethodPatch, "", `{"name": "Pump 3", "version": 2}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+	rr = send(http.MethodPut, "", `{"name": "Pump 4"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "writes naming no version apply unless required")
+
+	RequireIfMatch = true
+	defer func() { RequireIfMatch = false }()
+	rr = send(http.MethodPatch, "", `{"name": "Pump 5"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+	rr = send(http.MethodPatch, `"4"`, `{"name": "Pump 5"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Caps on ?limit of GET /watches, the changed components listed per watch.
+const (
+	defaultWatchChanges = 100
+	maxWatchChanges     = 1000
+)
+
+// watchRequest is the optional body of POST /components/{id}/watch.
+type watchRequest struct {
+	Subtree bool `json:"subtree"` // watch the component's descendants too
+}
+
+// watchDigest is a watch in GET /watches, with the components it has seen change.
+type watchDigest struct {
+	*models.Watch
+	ChangedCount int                 `json:"changed_count"`
+	Changed      []*models.Component `json:"changed"` // most recent first, at most ?limit
+}
+
+// watchHandler serves /components/{id}/watch: POST makes the request's principal watch the
+// component, or its subtree with {"subtree": true}, and DELETE stops it. Watching needs only read
+// permission on the component.
+func watchHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	principal, ok := watchPrincipal(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		var body watchRequest
+		if err := decodeBody(r, &body, true); err != nil && !errors.Is(err, io.EOF) { // the body is optional
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		defer r.Body.Close()
+		watch, created, err := componentStore.WatchComponent(id, principal, body.Subtree)
+		if err != nil {
+			respondWithWatchError(w, err, "Error watching component")
+			return
+		}
+		code := http.StatusOK
+		if created {
+			code = http.StatusCreated
+		}
+		respondWithJSON(w, code, watch)
+	case http.MethodDelete:
+		if err := componentStore.UnwatchComponent(id, principal); err != nil {
+			respondWithWatchError(w, err, "Error unwatching component")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Component unwatched successfully"})
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed for watch endpoint")
+	}
+}
+
+// WatchesHandler serves GET /watches: the request's principal's watches, oldest first, each with
+// a digest of the components it covers that changed after ?since (an RFC 3339 time; by default
+// when the watch was created). ?limit caps the changed components listed per watch.
+func WatchesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	q := newQueryParams(r)
+	var since time.Time
+	if value := q.str("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			q.reject("since", "an RFC 3339 time such as 2024-01-02T15:04:05Z")
+		}
+		since = parsed
+	}
+	limit := q.intRange("limit", defaultWatchChanges, 1, maxWatchChanges)
+	if !q.valid(w) {
+		return
+	}
+	principal, ok := watchPrincipal(w, r)
+	if !ok {
+		return
+	}
+	if cache.GlobalComponentCache == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Watch digests require the component cache, which is not initialized")
+		return
+	}
+
+	watches, err := componentStore.ListWatches(principal)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing watches: "+err.Error())
+		return
+	}
+	readable := readableFilter(r)
+	digests := make([]watchDigest, 0, len(watches))
+	for _, watch := range watches {
+		if readable != nil && !readable(watch.ComponentID) {
+			continue // its ACL changed since it was watched
+		}
+		from := since
+		if from.IsZero() {
+			from, _ = time.Parse(time.RFC3339, watch.CreatedAt)
+		}
+		changed, _ := cache.GlobalComponentCache.ChangedSince(watch.ComponentID, watch.Subtree, from, readable)
+		digest := watchDigest{Watch: watch, ChangedCount: len(changed), Changed: changed}
+		if len(digest.Changed) > limit {
+			digest.Changed = digest.Changed[:limit]
+		}
+		if digest.Changed == nil {
+			digest.Changed = []*models.Component{}
+		}
+		digests = append(digests, digest)
+	}
+	respondWithJSON(w, http.StatusOK, digests)
+}
+
+// watchPrincipal returns the principal whose watches a request addresses. Watches belong to an
+// authenticated principal, so without ACL enforcement, or for an anonymous request, it responds
+// 401 and returns ok false.
+func watchPrincipal(w http.ResponseWriter, r *http.Request) (principal string, ok bool) {
+	principal, enforced := principalFrom(r)
+	if !enforced || principal == "" {
+		respondWithError(w, http.StatusUnauthorized, "Watches belong to an authenticated principal; send the request through the authenticating proxy with ACLs enabled")
+		return "", false
+	}
+	return principal, true
+}
+
+// respondWithWatchError maps a watch store error to its status: 404 for a missing component or
+// watch, and 500 otherwise.
+func respondWithWatchError(w http.ResponseWriter, err error, context string) {
+	if strings.Contains(err.Error(), "not found") {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, context+": "+err.Error())
+}
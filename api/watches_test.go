@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchesRequests(t *testing.T) {
+	enforced := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler
+	for _, tc := range []struct {
+		method, target, principal string
+		handler                   http.Handler
+		code                      int
+	}{
+		{http.MethodPost, "/components/1/watch", "", http.HandlerFunc(ComponentsHandler), http.StatusUnauthorized},
+		{http.MethodPost, "/components/1/watch", "", enforced(http.HandlerFunc(ComponentsHandler)), http.StatusUnauthorized},
+		{http.MethodPut, "/components/1/watch", "alice", enforced(http.HandlerFunc(ComponentsHandler)), http.StatusMethodNotAllowed},
+		{http.MethodPost, "/components/x/watch", "alice", enforced(http.HandlerFunc(ComponentsHandler)), http.StatusBadRequest},
+		{http.MethodPost, "/components/1/watch?subtree=true", "alice", enforced(http.HandlerFunc(ComponentsHandler)), http.StatusBadRequest},
+		{http.MethodGet, "/watches", "", http.HandlerFunc(WatchesHandler), http.StatusUnauthorized},
+		{http.MethodPost, "/watches", "alice", enforced(http.HandlerFunc(WatchesHandler)), http.StatusMethodNotAllowed},
+		{http.MethodGet, "/watches?since=yesterday", "alice", enforced(http.HandlerFunc(WatchesHandler)), http.StatusBadRequest},
+		{http.MethodGet, "/watches?limit=0", "alice", enforced(http.HandlerFunc(WatchesHandler)), http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		if tc.principal != "" {
+			req.Header.Set(defaultPrincipalHeader, tc.principal)
+		}
+		rr := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rr, req)
+		assert.Equal(t, tc.code, rr.Code, "%s %s: %s", tc.method, tc.target, rr.Body.String())
+	}
+}
+
+func TestAPIWatches(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	clearComponentsTableForAPITests()
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	pump := createTestComponentDirectly(t, "Pump", "", sql.NullInt64{})
+	seal := createTestComponentDirectly(t, "Seal", "", sql.NullInt64{Int64: pump.ID, Valid: true})
+	require.NoError(t, cache.InitGlobalCache(testAPIStore))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/components/", ComponentsHandler)
+	mux.HandleFunc("/watches", WatchesHandler)
+	handler := (&ACLEnforcer{PrincipalHeader: defaultPrincipalHeader}).Handler(mux)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set(defaultPrincipalHeader, "alice")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	watch := fmt.Sprintf("/components/%d/watch", pump.ID)
+
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, watch, "").Code)
+	rr := serve(http.MethodPost, watch, `{"subtree":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected watching again to update the watch")
+	var updated models.Watch
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+	assert.Equal(t, models.Watch{ComponentID: pump.ID, Principal: "alice", Subtree: true, CreatedAt: updated.CreatedAt}, updated)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/components/88888/watch", "").Code)
+
+	var digests []watchDigest
+	rr = serve(http.MethodGet, "/watches?since=2000-01-01T00:00:00Z&limit=1", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &digests))
+	require.Len(t, digests, 1)
+	assert.Equal(t, pump.ID, digests[0].ComponentID)
+	assert.Equal(t, 2, digests[0].ChangedCount, "Expected the pump and its seal")
+	assert.Len(t, digests[0].Changed, 1)
+
+	rr = serve(http.MethodGet, "/watches?since=2000-01-01T00:00:00Z", "")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &digests))
+	require.Len(t, digests, 1)
+	var changed []int64
+	for _, comp := range digests[0].Changed {
+		changed = append(changed, comp.ID)
+	}
+	assert.ElementsMatch(t, []int64{pump.ID, seal.ID}, changed)
+
+	rr = serve(http.MethodGet, "/watches?since=2100-01-01T00:00:00Z", "")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &digests))
+	require.Len(t, digests, 1)
+	assert.Zero(t, digests[0].ChangedCount)
+	assert.NotNil(t, digests[0].Changed)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, watch, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, watch, "").Code)
+	rr = serve(http.MethodGet, "/watches", "")
+	assert.JSONEq(t, `[]`, rr.Body.String())
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"component-service/models"
+	"component-service/store"
+	"component-service/webhooks"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Default and cap of ?limit on GET /admin/webhooks/{id}/deliveries.
+const (
+	defaultWebhookDeliveriesLimit = 100
+	maxWebhookDeliveriesLimit     = 1000
+)
+
+// webhookDispatcher delivers events to the registered webhooks; it is the one set with
+// EnableWebhooks, or nil.
+var webhookDispatcher *webhooks.Dispatcher
+
+// EnableWebhooks makes registrations through /admin/webhooks take effect on d at once, rather
+// than when it next rereads them.
+func EnableWebhooks(d *webhooks.Dispatcher) {
+	webhookDispatcher = d
+}
+
+// webhookRequest is the body of POST /admin/webhooks and PUT /admin/webhooks/{id}.
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"` // required on POST; on PUT, empty keeps the current one
+	Events []string `json:"events"` // event types to deliver; empty for all of them
+	// SchemaVersion is the event schema version to deliver: on POST, 0 for the current one; on
+	// PUT, 0 keeps the webhook's.
+	SchemaVersion int `json:"schema_version"`
+}
+
+// webhooksHandler serves /admin/webhooks: GET lists the webhooks, oldest first, and POST registers
+// one.
+func webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		list, err := componentStore.ListWebhooks()
+		if err != nil {
+			respondWithWebhookError(w, err, "Error listing webhooks")
+			return
+		}
+		for _, hook := range list {
+			hook.Secret = ""
+		}
+		respondWithJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var body webhookRequest
+		if err := decodeBody(r, &body, true); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		defer r.Body.Close()
+		hook := &models.Webhook{URL: body.URL, Secret: body.Secret, Events: body.Events, SchemaVersion: body.SchemaVersion}
+		if err := componentStore.CreateWebhook(hook); err != nil {
+			respondWithWebhookError(w, err, "Error creating webhook")
+			return
+		}
+		reloadWebhooks()
+		hook.Secret = ""
+		w.Header().Set("Location", fmt.Sprintf("/admin/webhooks/%d", hook.ID))
+		respondWithJSON(w, http.StatusCreated, hook)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// webhookHandler serves /admin/webhooks/{id}: GET returns the webhook, PUT replaces its URL,
+// secret, events and schema version, and DELETE removes it with its delivery log. Its secret is never returned.
+func webhookHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		hook, err := componentStore.GetWebhook(id)
+		if err != nil {
+			respondWithWebhookError(w, err, "Error getting webhook")
+			return
+		}
+		hook.Secret = ""
+		respondWithJSON(w, http.StatusOK, hook)
+	case http.MethodPut:
+		var body webhookRequest
+		if err := decodeBody(r, &body, true); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		defer r.Body.Close()
+		hook := &models.Webhook{ID: id, URL: body.URL, Secret: body.Secret, Events: body.Events, SchemaVersion: body.SchemaVersion}
+		if err := componentStore.UpdateWebhook(hook); err != nil {
+			respondWithWebhookError(w, err, "Error updating webhook")
+			return
+		}
+		reloadWebhooks()
+		hook.Secret = ""
+		respondWithJSON(w, http.StatusOK, hook)
+	case http.MethodDelete:
+		if err := componentStore.DeleteWebhook(id); err != nil {
+			respondWithWebhookError(w, err, "Error deleting webhook")
+			return
+		}
+		reloadWebhooks()
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Webhook deleted successfully"})
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// listWebhookDeliveries serves GET /admin/webhooks/{id}/deliveries, a page of the webhook's
+// delivery attempts, newest first, 100 unless ?limit says otherwise.
+func listWebhookDeliveries(w http.ResponseWriter, r *http.Request, id int64) {
+	q := newQueryParams(r)
+	p := parsePage(q, maxWebhookDeliveriesLimit)
+	if !q.valid(w) {
+		return
+	}
+	if p.limit == 0 {
+		p.limit = defaultWebhookDeliveriesLimit
+	}
+	list, total, err := componentStore.ListWebhookDeliveries(id, p.limit, p.offset)
+	if err != nil {
+		respondWithWebhookError(w, err, "Error listing webhook deliveries")
+		return
+	}
+	setPaginationHeaders(w, r, p, total)
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// replayWebhookDelivery serves POST /admin/webhooks/{id}/deliveries/{deliveryID}/replay, which
+// queues the payload of a recorded attempt to be sent to the webhook again, with the usual
+// retries, and answers 202 with that attempt. An event the webhook has already received is not
+// sent again: 409. Nor is an attempt recorded without its payload, before payloads were kept: 422.
+func replayWebhookDelivery(w http.ResponseWriter, r *http.Request, id, deliveryID int64) {
+	if !newQueryParams(r).valid(w) {
+		return
+	}
+	if webhookDispatcher == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Webhooks are not delivered by this instance")
+		return
+	}
+	delivery, err := componentStore.GetWebhookDelivery(id, deliveryID)
+	if err != nil {
+		respondWithWebhookError(w, err, "Error getting webhook delivery")
+		return
+	}
+	if delivery.Payload == "" {
+		respondWithError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Delivery %d was recorded without its payload and cannot be replayed", deliveryID))
+		return
+	}
+	delivered, err := componentStore.WebhookEventDelivered(id, delivery.EventID)
+	if err != nil {
+		respondWithWebhookError(w, err, "Error checking webhook deliveries")
+		return
+	}
+	if delivered {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Event %s has already been delivered to webhook %d", delivery.EventID, id))
+		return
+	}
+	if err := webhookDispatcher.Replay(delivery); err != nil {
+		if errors.Is(err, webhooks.ErrQueueFull) {
+			respondWithError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		respondWithWebhookError(w, err, "Error replaying webhook delivery")
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, delivery)
+}
+
+// reloadWebhooks applies a registration change to this instance's dispatcher. Others pick it up
+// when they next reread the webhooks.
+func reloadWebhooks() {
+	if webhookDispatcher == nil {
+		return
+	}
+	if err := webhookDispatcher.Reload(); err != nil {
+		log.Printf("Error reloading webhooks: %v", err)
+	}
+}
+
+// respondWithWebhookError maps a webhook store error to its status: 422 for an invalid webhook,
+// 404 for a missing one and 500 otherwise.
+func respondWithWebhookError(w http.ResponseWriter, err error, context string) {
+	switch {
+	case errors.Is(err, store.ErrInvalidWebhook):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, context+": "+err.Error())
+	}
+}
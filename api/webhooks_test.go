@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"component-service/db"
+	"component-service/models"
+	"component-service/webhooks"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooksRequests(t *testing.T) {
+	for _, tc := range []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodPatch, "/admin/webhooks", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/webhooks/1", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/webhooks/1/deliveries", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/webhooks/x", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/webhooks/1/attempts", "", http.StatusNotFound},
+		{http.MethodGet, "/admin/webhooks/1/deliveries/2", "", http.StatusNotFound},
+		{http.MethodPost, "/admin/webhooks/1/deliveries/2/resend", "", http.StatusNotFound},
+		{http.MethodPost, "/admin/webhooks/1/deliveries/x/replay", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/webhooks/1/deliveries/2/replay", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/webhooks/1/deliveries/2/replay", "", http.StatusServiceUnavailable},
+		{http.MethodGet, "/admin/webhooks/1/deliveries?limit=0", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/webhooks?limit=5", "", http.StatusBadRequest},
+		{http.MethodPost, "/admin/webhooks", `{"url":"https://hooks.example.com","filters":[]}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/webhooks/1", `not json`, http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		AdminHandler(rr, httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body)))
+		assert.Equal(t, tc.code, rr.Code, "%s %s: %s", tc.method, tc.target, rr.Body.String())
+	}
+}
+
+func TestAPIWebhooks(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping API test: DB connection not initialized.")
+	}
+	_, err := db.DB.Exec("DELETE FROM webhooks")
+	require.NoError(t, err)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		AdminHandler(rr, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/admin/webhooks", `{"url":"https://hooks.example.com/a","secret":"0123456789abcdef","events":["component.created"]}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var hook models.Webhook
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &hook))
+	assert.Empty(t, hook.Secret, "Expected the secret to be left out")
+	assert.Equal(t, fmt.Sprintf("/admin/webhooks/%d", hook.ID), rr.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/webhooks", `{"url":"https://hooks.example.com/a","secret":"short"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPost, "/admin/webhooks", `{"url":"https://hooks.example.com/a","secret":"0123456789abcdef","events":["component.renamed"]}`).Code)
+
+	base := fmt.Sprintf("/admin/webhooks/%d", hook.ID)
+	rr = serve(http.MethodPut, base, `{"url":"https://hooks.example.com/b"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = serve(http.MethodGet, base, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "hooks.example.com/b")
+	assert.NotContains(t, rr.Body.String(), "0123456789abcdef")
+
+	rr = serve(http.MethodGet, base+"/deliveries", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[]`, rr.Body.String())
+	assert.Equal(t, "0", rr.Header().Get("X-Total-Count"))
+
+	defer func(d *webhooks.Dispatcher) { webhookDispatcher = d }(webhookDispatcher)
+	webhookDispatcher = webhooks.New(componentStore, webhooks.Config{})
+	record := func(d *models.WebhookDelivery) string {
+		d.WebhookID, d.EventType, d.ComponentID, d.Attempt = hook.ID, "component.created", 7, 1
+		require.NoError(t, componentStore.RecordWebhookDelivery(d))
+		return fmt.Sprintf("%s/deliveries/%d/replay", base, d.ID)
+	}
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, base+"/deliveries/88888/replay", "").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPost, record(&models.WebhookDelivery{EventID: "0123456789abcdef0123456789abcdef"}), "").Code,
+		"Expected an attempt without its payload to be refused")
+	failed := record(&models.WebhookDelivery{EventID: "fedcba9876543210fedcba9876543210", StatusCode: 500, Payload: `{"id":"fedcba9876543210fedcba9876543210"}`})
+	record(&models.WebhookDelivery{EventID: "fedcba9876543210fedcba9876543210", StatusCode: 200, Succeeded: true, Payload: `{}`})
+	rr = serve(http.MethodPost, failed, "")
+	assert.Equal(t, http.StatusConflict, rr.Code, "Expected an event delivered since to be refused")
+	assert.Contains(t, rr.Body.String(), "already been delivered")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, base, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, base, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, base+"/deliveries", "").Code)
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlMediaType is the content type of YAML responses. Requests may also use the legacy types in
+// yamlMediaTypes.
+const yamlMediaType = "application/yaml"
+
+var yamlMediaTypes = map[string]bool{
+	yamlMediaType: true, "application/x-yaml": true, "text/yaml": true, "text/x-yaml": true,
+}
+
+// maxYAMLAsJSONBytes caps the JSON a YAML request body converts to, so aliases cannot expand a
+// small document into an unbounded one.
+const maxYAMLAsJSONBytes = 64 << 20
+
+var errYAMLTooLarge = fmt.Errorf("the document expands to more than %d bytes", maxYAMLAsJSONBytes)
+
+// YAMLHandler lets clients speak YAML to next, which speaks JSON. A request body sent as YAML is
+// converted to JSON before next sees it, so it is validated exactly as JSON is; a document that
+// is not valid YAML gets 400. Several YAML documents become newline-delimited JSON, one line each,
+// as imports take. A request whose Accept prefers YAML gets JSON responses converted to YAML, with
+// fields in the same order; other responses, such as CSV or the NDJSON export, pass through.
+func YAMLHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept") // for caches, as JSON and YAML share the same URLs
+		if prefersYAML(r.Header.Get("Accept")) {
+			yw := &yamlResponse{ResponseWriter: w}
+			defer yw.finish()
+			w = yw
+		}
+		if isYAML(r.Header.Get("Content-Type")) {
+			body, err := yamlBodyToJSON(r.Body)
+			r.Body.Close()
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+				return
+			}
+			r = r.Clone(r.Context())
+			r.Header.Set("Content-Type", "application/json")
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isYAML reports whether contentType is one of yamlMediaTypes.
+func isYAML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && yamlMediaTypes[mediaType]
+}
+
+// prefersYAML reports whether an Accept header ranks a YAML type above JSON. YAML must be asked
+// for by name: it wins a tie with a wildcard, but not with application/json.
+func prefersYAML(accept string) bool {
+	var yamlQ, jsonQ, wildcardQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case yamlMediaTypes[mediaType]:
+			yamlQ = math.Max(yamlQ, q)
+		case mediaType == "application/json":
+			jsonQ = math.Max(jsonQ, q)
+		case mediaType == "application/*" || mediaType == "*/*":
+			wildcardQ = math.Max(wildcardQ, q)
+		}
+	}
+	return yamlQ > 0 && yamlQ > jsonQ && yamlQ >= wildcardQ
+}
+
+// yamlBodyToJSON converts the YAML documents in body to JSON: a single document to its value,
+// several to one line each. An empty body stays empty.
+func yamlBodyToJSON(body io.Reader) ([]byte, error) {
+	decoder := yaml.NewDecoder(body)
+	var out bytes.Buffer
+	for documents := 0; ; documents++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if documents > 0 {
+			out.WriteByte('\n')
+		}
+		if err := writeYAMLAsJSON(&out, &node); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeYAMLAsJSON writes node to out as JSON. Numbers already written as JSON numbers are kept
+// as written, so 7.50 stays 7.50; others, such as 0x1F, are written in decimal. Timestamps and
+// other scalars JSON has no type for are written as strings. Mapping keys must be scalars.
+func writeYAMLAsJSON(out *bytes.Buffer, node *yaml.Node) error {
+	if out.Len() > maxYAMLAsJSONBytes {
+		return errYAMLTooLarge
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			out.WriteString("null")
+			return nil
+		}
+		return writeYAMLAsJSON(out, node.Content[0])
+	case yaml.AliasNode:
+		return writeYAMLAsJSON(out, node.Alias)
+	case yaml.MappingNode:
+		out.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			for key.Kind == yaml.AliasNode {
+				key = key.Alias
+			}
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, key.Value)
+			out.WriteByte(':')
+			if err := writeYAMLAsJSON(out, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		out.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeYAMLAsJSON(out, item); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+		return nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		out.WriteString("null")
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return err
+		}
+		out.WriteString(strconv.FormatBool(b))
+	case "!!int", "!!float":
+		if isJSONNumber(node.Value) {
+			out.WriteString(node.Value)
+			return nil
+		}
+		var n interface{}
+		if err := node.Decode(&n); err != nil {
+			return err
+		}
+		if f, ok := n.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return fmt.Errorf("line %d: %s is not a number JSON can hold", node.Line, node.Value)
+		}
+		encoded, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	default:
+		writeJSONString(out, node.Value)
+	}
+	return nil
+}
+
+// isJSONNumber reports whether s is a number as JSON writes it.
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+	var n json.Number
+	return json.Unmarshal([]byte(s), &n) == nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	encoded, _ := json.Marshal(s) // a string always marshals
+	out.Write(encoded)
+}
+
+// jsonToYAML converts a single JSON value to a YAML node, keeping the order of object fields and
+// the text of numbers.
+func jsonToYAML(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if v == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if v == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			value, err := jsonToYAML(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		if _, err := decoder.Token(); err != nil { // the closing delimiter
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(string(v), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected JSON token %v", token)
+}
+
+// jsonBodyToYAML converts a JSON response body to YAML.
+func jsonBodyToYAML(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	node, err := jsonToYAML(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// yamlResponse buffers a JSON response for finish to convert to YAML. A response of another
+// content type is written through as it comes.
+type yamlResponse struct {
+	http.ResponseWriter
+	status    int
+	buffering bool
+	decided   bool
+	body      bytes.Buffer
+}
+
+func (y *yamlResponse) WriteHeader(code int) {
+	if y.decided {
+		return
+	}
+	y.decided = true
+	mediaType, _, _ := mime.ParseMediaType(y.Header().Get("Content-Type"))
+	if mediaType == "application/json" {
+		y.buffering = true
+		y.status = code
+		return
+	}
+	y.ResponseWriter.WriteHeader(code)
+}
+
+func (y *yamlResponse) Write(p []byte) (int, error) {
+	y.WriteHeader(http.StatusOK)
+	if y.buffering {
+		return y.body.Write(p)
+	}
+	return y.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming responses streaming; buffered ones are written by finish.
+func (y *yamlResponse) Flush() {
+	if f, ok := y.ResponseWriter.(http.Flusher); ok && !y.buffering {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (y *yamlResponse) Unwrap() http.ResponseWriter {
+	return y.ResponseWriter
+}
+
+// finish writes a buffered JSON response as YAML. A body that does not convert, which a handler
+// writing valid JSON never produces, is written unchanged.
+func (y *yamlResponse) finish() {
+	if !y.buffering {
+		return
+	}
+	header := y.Header()
+	body := y.body.Bytes()
+	if len(body) > 0 {
+		converted, err := jsonBodyToYAML(body)
+		if err != nil {
+			y.ResponseWriter.WriteHeader(y.status)
+			y.ResponseWriter.Write(body)
+			return
+		}
+		body = converted
+	}
+	header.Set("Content-Type", yamlMediaType)
+	header.Del("Content-Length")
+	y.ResponseWriter.WriteHeader(y.status)
+	y.ResponseWriter.Write(body)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/fixtures"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPrefersYAML(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                   false,
+		"application/json":                   false,
+		"*/*":                                false,
+		"application/yaml":                   true,
+		"application/x-yaml":                 true,
+		"text/yaml; charset=utf-8":           true,
+		"application/yaml, */*":              true,
+		"application/json, application/yaml": false,
+		"application/json;q=0.5, application/yaml":     true,
+		"application/yaml;q=0.5, application/json":     false,
+		"application/yaml;q=0":                         false,
+		"text/html, application/yaml;q=0.9, */*;q=0.8": true,
+	} {
+		assert.Equal(t, want, prefersYAML(accept), "Accept: %s", accept)
+	}
+}
+
+func TestYAMLBodyToJSON(t *testing.T) {
+	for _, tc := range []struct{ yaml, json string }{
+		{"name: pump\nparent_id: {Int64: 1, Valid: true}\n", `{"name":"pump","parent_id":{"Int64":1,"Valid":true}}`},
+		{"b: 1\na: 2\n", `{"b":1,"a":2}`},
+		{"rated_kw: 7.50\nmask: 0x1F\nnothing: ~\non: true\n", `{"rated_kw":7.50,"mask":31,"nothing":null,"on":true}`},
+		{"installed: 2024-06-01\nzip: '01234'\n", `{"installed":"2024-06-01","zip":"01234"}`},
+		{"base: &b {vendor: acme}\ncopy: *b\n", `{"base":{"vendor":"acme"},"copy":{"vendor":"acme"}}`},
+		{"- name: a\n- name: b\n", `[{"name":"a"},{"name":"b"}]`},
+		{"id: 1\n---\nid: 2\n", "{\"id\":1}\n{\"id\":2}"},
+		{"", ""},
+	} {
+		got, err := yamlBodyToJSON(strings.NewReader(tc.yaml))
+		require.NoError(t, err, tc.yaml)
+		assert.Equal(t, tc.json, string(got), tc.yaml)
+	}
+
+	for _, bad := range []string{"name: [unclosed\n", "? [a, b]\n: c\n", "n: .nan\n"} {
+		_, err := yamlBodyToJSON(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestYAMLHandler(t *testing.T) {
+	var received string
+	handler := YAMLHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Header.Get("Content-Type") + " " + string(body)
+		if r.URL.Path == "/csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("id,name\n"))
+			return
+		}
+		respondWithRawJSON(w, http.StatusCreated, []byte(`{"id":3,"name":"123","metadata":{"z":1.50,"a":[true,null]}}`))
+	}))
+	serve := func(path, contentType, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("/components", "application/yaml", "application/yaml", "name: pump\n")
+	assert.Equal(t, `application/json {"name":"pump"}`, received)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, yamlMediaType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+	assert.Equal(t, "id: 3\nname: \"123\"\nmetadata:\n  z: 1.50\n  a:\n    - true\n    - null\n", rr.Body.String())
+
+	rr = serve("/components", "application/json", "", `{"name":"pump"}`)
+	assert.Equal(t, `application/json {"name":"pump"}`, received, "JSON is passed through")
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"id":3,"name":"123","metadata":{"z":1.50,"a":[true,null]}}`, rr.Body.String())
+
+	rr = serve("/csv", "application/json", "application/yaml", "")
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"), "other content types are passed through")
+	assert.Equal(t, "id,name\n", rr.Body.String())
+
+	received = ""
+	rr = serve("/components", "application/yaml", "application/yaml", "name: [pump\n")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, received)
+	assert.Equal(t, yamlMediaType, rr.Header().Get("Content-Type"), "errors are YAML too")
+	var body map[string]string
+	require.NoError(t, yaml.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Contains(t, body["error"], "Invalid request payload")
+}
+
+func TestYAMLComponentRead(t *testing.T) {
+	w := fixtures.MustLoadCache(t, "plant")
+	valve := *w.Component("valve")
+	valve.Version = 2
+	cache.GlobalComponentCache.Set(&valve)
+	handler := YAMLHandler(http.HandlerFunc(ComponentsHandler))
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/components/%d", valve.ID), nil)
+	req.Header.Set("Accept", "application/yaml")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, yamlMediaType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+	var comp map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(rr.Body.Bytes(), &comp))
+	assert.Equal(t, "valve", comp["name"])
+	assert.True(t, strings.HasPrefix(rr.Body.String(), fmt.Sprintf("id: %d\nname: valve\n", valve.ID)), "fields keep their order")
+
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+}
@@ -0,0 +1,181 @@
+// Package attachments holds the files attached to components, in a local directory or an S3
+// bucket. Their metadata lives in the database; a Storage only keeps the bytes, by key.
+package attachments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxBytes bounds the size of an attachment unless ATTACHMENTS_MAX_BYTES says otherwise.
+const DefaultMaxBytes = 25 << 20
+
+// ErrNotFound is returned by Get for a key the storage does not hold.
+var ErrNotFound = errors.New("attachment data not found")
+
+// Storage keeps attachment data by key. Keys are relative paths of lowercase letters, digits
+// and slashes, chosen by the caller.
+type Storage interface {
+	// Put stores size bytes read from body under key, replacing any data it held.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the data under key, failing with ErrNotFound when there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the data under key. A key holding nothing is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv configures attachment storage from the environment:
+//
+//	ATTACHMENTS_STORAGE      local or s3; unset disables attachments
+//	ATTACHMENTS_DIR          the directory files are kept in, for local
+//	ATTACHMENTS_S3_BUCKET    the bucket, for s3
+//	ATTACHMENTS_S3_REGION    the bucket's region (default us-east-1)
+//	ATTACHMENTS_S3_ENDPOINT  an S3-compatible endpoint, addressed path-style (default https://s3.{region}.amazonaws.com)
+//	ATTACHMENTS_S3_PREFIX    prepended to every key, such as "components-prod/"
+//	ATTACHMENTS_MAX_BYTES    the largest attachment accepted (default 25 MiB)
+//
+// S3 credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and, for temporary ones,
+// AWS_SESSION_TOKEN. It returns a nil Storage when attachments are disabled.
+func FromEnv() (Storage, int64, error) {
+	maxBytes := int64(DefaultMaxBytes)
+	if value := os.Getenv("ATTACHMENTS_MAX_BYTES"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return nil, 0, fmt.Errorf("invalid ATTACHMENTS_MAX_BYTES %q: expected a positive number of bytes", value)
+		}
+		maxBytes = parsed
+	}
+	switch kind := os.Getenv("ATTACHMENTS_STORAGE"); kind {
+	case "":
+		return nil, maxBytes, nil
+	case "local":
+		dir := os.Getenv("ATTACHMENTS_DIR")
+		if dir == "" {
+			return nil, 0, fmt.Errorf("ATTACHMENTS_DIR is required with ATTACHMENTS_STORAGE=local")
+		}
+		storage, err := NewLocal(dir)
+		return storage, maxBytes, err
+	case "s3":
+		storage, err := s3FromEnv()
+		return storage, maxBytes, err
+	default:
+		return nil, 0, fmt.Errorf("unsupported ATTACHMENTS_STORAGE %q (expected local or s3)", kind)
+	}
+}
+
+func s3FromEnv() (*S3, error) {
+	s := &S3{
+		Bucket:          os.Getenv("ATTACHMENTS_S3_BUCKET"),
+		Region:          os.Getenv("ATTACHMENTS_S3_REGION"),
+		Prefix:          os.Getenv("ATTACHMENTS_S3_PREFIX"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Client:          &http.Client{Timeout: 5 * time.Minute},
+	}
+	if s.Bucket == "" {
+		return nil, fmt.Errorf("ATTACHMENTS_S3_BUCKET is required with ATTACHMENTS_STORAGE=s3")
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required with ATTACHMENTS_STORAGE=s3")
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	endpoint := os.Getenv("ATTACHMENTS_S3_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	var err error
+	s.Endpoint, err = url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || s.Endpoint.Scheme == "" || s.Endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid ATTACHMENTS_S3_ENDPOINT %q: expected an absolute URL such as https://minio:9000", endpoint)
+	}
+	return s, nil
+}
+
+// Local keeps attachments as files under a directory, one per key.
+type Local struct {
+	dir string
+}
+
+// NewLocal returns a Local storage in dir, creating the directory when missing.
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating attachment directory %s: %w", dir, err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid attachment key %q", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the data to a temporary file beside its final path and renames it into place, so a
+// failed write never leaves a partial file under key.
+func (l *Local) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating directory for attachment %s: %w", key, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error storing attachment %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("wrote %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error storing attachment %s: %w", key, err)
+	}
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading attachment %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting attachment %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Permission is the access a principal has to a component. Each level includes the ones below it.
+type Permission int
+
+const (
+	PermissionNone  Permission = iota // No access; overrides a grant inherited from an ancestor
+	PermissionRead                    // Read the component and list it
+	PermissionWrite                   // Update or delete the component and create children under it
+	PermissionAdmin                   // Write, plus manage the component's ACL
+)
+
+var permissionNames = []string{"none", "read", "write", "admin"}
+
+func (p Permission) String() string {
+	if p < 0 || int(p) >= len(permissionNames) {
+		return fmt.Sprintf("Permission(%d)", int(p))
+	}
+	return permissionNames[p]
+}
+
+// ParsePermission returns the permission with the given name: none, read, write or admin.
+func ParsePermission(name string) (Permission, error) {
+	for i, candidate := range permissionNames {
+		if name == candidate {
+			return Permission(i), nil
+		}
+	}
+	return PermissionNone, fmt.Errorf("unknown permission %q (expected none, read, write or admin)", name)
+}
+
+// MarshalText encodes a permission by name, so ACL entries read naturally in JSON.
+func (p Permission) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a permission name.
+func (p *Permission) UnmarshalText(text []byte) error {
+	parsed, err := ParsePermission(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// EveryonePrincipal matches every principal without an entry of its own, including anonymous
+// requests.
+const EveryonePrincipal = "*"
+
+// ACLEntry grants a principal a permission on a component. It applies to the component's
+// descendants too, down to the nearest one with an entry for the same principal.
+type ACLEntry struct {
+	ComponentID int64      `json:"component_id"`
+	Principal   string     `json:"principal"`
+	Permission  Permission `json:"permission"`
+}
+
+// ACLSource is implemented by stores that persist ACL entries. InitGlobalCache loads the entries
+// from its store when Config.LoadACL is set.
+type ACLSource interface {
+	ListACLEntries() ([]ACLEntry, error)
+}
+
+// aclTable is a component's compiled effective ACL: the entry that applies to each principal,
+// whether the component's own or inherited. Tables are never modified once built, so a component
+// without entries of its own shares its parent's table.
+type aclTable map[string]ACLEntry
+
+// allows reports whether principal holds at least the needed permission. The principal's own
+// entry, even an inherited one, takes precedence over the everyone entry. A nil table means no
+// ACL applies anywhere above the component, which leaves it unrestricted.
+func (t aclTable) allows(principal string, needed Permission) bool {
+	if t == nil {
+		return true
+	}
+	entry, found := t[principal]
+	if !found {
+		entry, found = t[EveryonePrincipal]
+	}
+	return found && entry.Permission >= needed
+}
+
+// sortedEntries returns entries ordered by principal.
+func sortedEntries(entries []ACLEntry) []ACLEntry {
+	sorted := append([]ACLEntry{}, entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Principal < sorted[j].Principal })
+	return sorted
+}
+
+// loadACL replaces every ACL entry and compiles the index from scratch. Assumes the write lock is
+// held.
+func (c *ComponentCache) loadACL(entries []ACLEntry) {
+	c.aclByID = make(map[int64][]ACLEntry)
+	for _, entry := range entries {
+		c.aclByID[entry.ComponentID] = append(c.aclByID[entry.ComponentID], entry)
+	}
+	c.effectiveACL = make(map[int64]aclTable)
+	if len(c.aclByID) == 0 {
+		return
+	}
+	for _, root := range c.childrenByParentID[RootParentIDKey] {
+		c.compileACLFrom(root.ID, nil)
+	}
+}
+
+// compileACL rebuilds the effective ACL of a component and its descendants after the component's
+// own entries or its parent changed. Assumes the write lock is held.
+func (c *ComponentCache) compileACL(id int64) {
+	var inherited aclTable
+	if comp, exists := c.componentsByID[id]; exists && comp.ParentID.Valid {
+		inherited = c.effectiveACL[comp.ParentID.Int64]
+	}
+	c.compileACLFrom(id, inherited)
+}
+
+// compileACLFrom compiles the subtree rooted at id, whose parent's effective ACL is inherited.
+// The walk uses an explicit stack so deep trees cannot exhaust the goroutine stack. Assumes the
+// write lock is held.
+func (c *ComponentCache) compileACLFrom(id int64, inherited aclTable) {
+	type pending struct {
+		id        int64
+		inherited aclTable
+	}
+	stack := []pending{{id, inherited}}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		table := next.inherited
+		if own := c.aclByID[next.id]; len(own) > 0 {
+			table = make(aclTable, len(next.inherited)+len(own))
+			for principal, entry := range next.inherited {
+				table[principal] = entry
+			}
+			for _, entry := range own {
+				table[entry.Principal] = entry
+			}
+		}
+		if table == nil {
+			delete(c.effectiveACL, next.id)
+		} else {
+			c.effectiveACL[next.id] = table
+		}
+		for _, child := range c.childrenByParentID[next.id] {
+			stack = append(stack, pending{child.ID, table})
+		}
+	}
+}
+
+// SetACL replaces a component's own ACL entries and recompiles the effective ACL of its subtree.
+// An empty entries removes the component's own ACL, so it inherits its parent's again.
+func (c *ComponentCache) SetACL(componentID int64, entries []ACLEntry) {
+	own := make([]ACLEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.ComponentID = componentID
+		own = append(own, entry)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(own) == 0 {
+		delete(c.aclByID, componentID)
+	} else {
+		c.aclByID[componentID] = sortedEntries(own)
+	}
+	c.compileACL(componentID)
+	c.accessChanges++
+}
+
+// ACL returns a component's own ACL entries and its effective ACL, both ordered by principal.
+// Effective entries keep the ID of the component that defines them. found is false when the
+// component is not cached.
+func (c *ComponentCache) ACL(componentID int64) (own, effective []ACLEntry, found bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	if _, found := c.componentsByID[componentID]; !found {
+		return nil, nil, false
+	}
+	own = append([]ACLEntry{}, c.aclByID[componentID]...)
+	effective = []ACLEntry{}
+	for _, entry := range c.effectiveACL[componentID] {
+		effective = append(effective, entry)
+	}
+	return own, sortedEntries(effective), true
+}
+
+// Allows reports whether principal holds at least the needed permission on a component. The
+// lookup is a single map read into the compiled index. Components no ACL applies to, including
+// components the cache does not hold, are unrestricted.
+func (c *ComponentCache) Allows(componentID int64, principal string, needed Permission) bool {
+	c.rlock()
+	table := c.effectiveACL[componentID]
+	c.mu.RUnlock()
+	return table.allows(principal, needed)
+}
+
+// AllowsHeld is Allows for a component the cache must still hold. One it does not hold, such as
+// a component deleted since, is refused, as its ACL left the index with it.
+func (c *ComponentCache) AllowsHeld(componentID int64, principal string, needed Permission) bool {
+	c.rlock()
+	_, held := c.componentsByID[componentID]
+	table := c.effectiveACL[componentID]
+	c.mu.RUnlock()
+	return held && table.allows(principal, needed)
+}
+
+// EntriesAllow reports whether principal holds at least the needed permission under an effective
+// ACL given as its entries, at most one per principal, for components the index does not hold.
+// No entries leave the component unrestricted, as Allows does.
+func EntriesAllow(effective []ACLEntry, principal string, needed Permission) bool {
+	var table aclTable
+	for _, entry := range effective {
+		if table == nil {
+			table = make(aclTable, len(effective))
+		}
+		table[entry.Principal] = entry
+	}
+	return table.allows(principal, needed)
+}
+
+// HasACLs reports whether any component is restricted, letting collection endpoints skip
+// per-component checks entirely when none is.
+func (c *ComponentCache) HasACLs() bool {
+	c.rlock()
+	defer c.mu.RUnlock()
+	return len(c.effectiveACL) > 0
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"testing"
+)
+
+// aclMockStore is a MockComponentStore that also persists ACL entries.
+type aclMockStore struct {
+	MockComponentStore
+	entries []ACLEntry
+}
+
+func (m *aclMockStore) ListACLEntries() ([]ACLEntry, error) {
+	return m.entries, nil
+}
+
+func aclTestComponent(id int64, parentID int64) *models.Component {
+	comp := &models.Component{ID: id, Name: "Comp", ParentID: invalidNullInt64()}
+	if parentID != 0 {
+		comp.ParentID = nullInt64(parentID)
+	}
+	return comp
+}
+
+func TestComponentCache_ACL(t *testing.T) {
+	// 1 -> 2 -> 3, and 4 on its own.
+	store := &aclMockStore{
+		MockComponentStore: MockComponentStore{mockComponents: []*models.Component{
+			aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2), aclTestComponent(4, 0),
+		}},
+		entries: []ACLEntry{
+			{ComponentID: 1, Principal: "alice", Permission: PermissionWrite},
+			{ComponentID: 1, Principal: EveryonePrincipal, Permission: PermissionRead},
+			{ComponentID: 2, Principal: "alice", Permission: PermissionNone},
+		},
+	}
+	defer func(cfg Config) { GlobalConfig = cfg }(GlobalConfig)
+	GlobalConfig.LoadACL = true
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+
+	checks := []struct {
+		id        int64
+		principal string
+		needed    Permission
+		want      bool
+	}{
+		{1, "alice", PermissionWrite, true},
+		{1, "alice", PermissionAdmin, false},
+		{1, "bob", PermissionRead, true}, // everyone
+		{1, "bob", PermissionWrite, false},
+		{1, "", PermissionRead, true},       // anonymous falls under everyone too
+		{2, "alice", PermissionRead, false}, // overridden
+		{3, "alice", PermissionRead, false}, // override inherited
+		{3, "bob", PermissionRead, true},
+		{4, "bob", PermissionAdmin, true}, // no ACL applies
+		{99, "bob", PermissionAdmin, true},
+	}
+	for _, check := range checks {
+		if got := cache.Allows(check.id, check.principal, check.needed); got != check.want {
+			t.Errorf("Allows(%d, %q, %s) = %v, want %v", check.id, check.principal, check.needed, got, check.want)
+		}
+	}
+
+	// A filter's Reader leaves out what the principal may not read from counts and pages.
+	alice := "alice"
+	if got := cache.CountMatching(Filter{Reader: &alice}); got != 2 {
+		t.Errorf("CountMatching for alice = %d, want 2", got)
+	}
+	if got := cache.CountChildrenMatching(1, Filter{Reader: &alice}); got != 0 {
+		t.Errorf("CountChildrenMatching(1) for alice = %d, want 0", got)
+	}
+	if body, _, _ := cache.DescendantsJSON(1, 0, Filter{Reader: &alice}, 0, 0); string(body) != "[]" {
+		t.Errorf("DescendantsJSON(1) for alice = %s, want []", body)
+	}
+
+	// Moving 3 under 4 leaves it unrestricted; a new child of 2 inherits 2's ACL.
+	cache.Set(aclTestComponent(3, 4))
+	cache.Set(aclTestComponent(5, 2))
+	if !cache.Allows(3, "alice", PermissionAdmin) {
+		t.Error("Expected component 3 to be unrestricted after moving under 4")
+	}
+	if cache.Allows(5, "alice", PermissionRead) || !cache.Allows(5, "bob", PermissionRead) {
+		t.Error("Expected new component 5 to inherit component 2's ACL")
+	}
+
+	// Replacing 2's entries recompiles its subtree; clearing them restores the inherited ACL.
+	cache.SetACL(2, []ACLEntry{{Principal: "bob", Permission: PermissionAdmin}})
+	if !cache.Allows(5, "bob", PermissionAdmin) || !cache.Allows(5, "alice", PermissionWrite) {
+		t.Error("Expected component 5 to pick up component 2's new entries")
+	}
+	own, effective, found := cache.ACL(5)
+	if !found || len(own) != 0 || len(effective) != 3 || effective[0].Principal != EveryonePrincipal || effective[2].ComponentID != 2 {
+		t.Errorf("Unexpected ACL of component 5: own %+v, effective %+v", own, effective)
+	}
+	cache.SetACL(2, nil)
+	if !cache.Allows(5, "alice", PermissionWrite) || cache.Allows(5, "bob", PermissionWrite) {
+		t.Error("Expected component 5 to inherit component 1's ACL once 2's entries are cleared")
+	}
+
+	// Deleting 1 makes 2 a root; with no entries of its own it becomes unrestricted.
+	cache.Delete(1)
+	if !cache.Allows(2, "bob", PermissionAdmin) || !cache.Allows(5, "bob", PermissionAdmin) {
+		t.Error("Expected orphaned subtree to be unrestricted")
+	}
+	if cache.HasACLs() {
+		t.Error("Expected no restricted components after deleting the only ACL")
+	}
+}
+
+func TestComponentCache_ACLNotLoadedByDefault(t *testing.T) {
+	store := &aclMockStore{
+		MockComponentStore: MockComponentStore{mockComponents: []*models.Component{aclTestComponent(1, 0)}},
+		entries:            []ACLEntry{{ComponentID: 1, Principal: "alice", Permission: PermissionRead}},
+	}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	if GlobalComponentCache.HasACLs() {
+		t.Error("Expected ACL entries to be loaded only with Config.LoadACL")
+	}
+}
+
+func TestPermissionJSON(t *testing.T) {
+	var entry ACLEntry
+	if err := json.Unmarshal([]byte(`{"principal":"alice","permission":"write"}`), &entry); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if entry.Permission != PermissionWrite {
+		t.Errorf("Expected write, got %s", entry.Permission)
+	}
+	encoded, _ := json.Marshal(entry)
+	if string(encoded) != `{"component_id":0,"principal":"alice","permission":"write"}` {
+		t.Errorf("Unexpected encoding %s", encoded)
+	}
+	if err := json.Unmarshal([]byte(`{"permission":"owner"}`), &entry); err == nil {
+		t.Error("Expected an unknown permission to be rejected")
+	}
+}
+
+func TestEntriesAllow(t *testing.T) {
+	effective := []ACLEntry{
+		{ComponentID: 1, Principal: "alice", Permission: PermissionWrite},
+		{ComponentID: 2, Principal: EveryonePrincipal, Permission: PermissionRead},
+	}
+	if !EntriesAllow(effective, "alice", PermissionWrite) {
+		t.Error("Expected alice's own entry to grant write")
+	}
+	if EntriesAllow(effective, "bob", PermissionWrite) || !EntriesAllow(effective, "bob", PermissionRead) {
+		t.Error("Expected bob to fall under the everyone entry")
+	}
+	if !EntriesAllow(nil, "bob", PermissionAdmin) {
+		t.Error("Expected no entries to leave the component unrestricted")
+	}
+}
+
+func TestComponentCache_AllowsHeld(t *testing.T) {
+	c := NewComponentCache()
+	c.SetMany([]*models.Component{aclTestComponent(1, 0), aclTestComponent(2, 0)})
+	c.SetACL(1, []ACLEntry{{ComponentID: 1, Principal: "alice", Permission: PermissionRead}})
+	if !c.AllowsHeld(1, "alice", PermissionRead) || c.AllowsHeld(1, "bob", PermissionRead) {
+		t.Error("Expected a held component to be checked against its ACL")
+	}
+	if !c.AllowsHeld(2, "bob", PermissionRead) {
+		t.Error("Expected a held component without an ACL to be unrestricted")
+	}
+	c.Delete(1)
+	if c.AllowsHeld(1, "alice", PermissionRead) || !c.Allows(1, "alice", PermissionRead) {
+		t.Error("Expected only AllowsHeld to refuse a component the cache no longer holds")
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+	"time"
+)
+
+// ChangedSince returns the components changed after since: the component rootID and, when subtree
+// is set, its descendants, most recently changed first with ties by ID. keep, when not nil, drops
+// the components it refuses. A component has changed when its updated_at is after since, so
+// deleted components, which leave the cache, are not reported. found is false when rootID is not
+// cached.
+func (c *ComponentCache) ChangedSince(rootID int64, subtree bool, since time.Time, keep func(id int64) bool) (changed []*models.Component, found bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	root, found := c.componentsByID[rootID]
+	if !found {
+		return nil, false
+	}
+	scope := []*models.Component{root}
+	if subtree {
+		scope = append(scope, c.descendants(rootID, 0)...)
+	}
+	changedAt := make(map[int64]time.Time)
+	for _, comp := range scope {
+		updatedAt, err := time.Parse(time.RFC3339, comp.UpdatedAt)
+		if err != nil || !updatedAt.After(since) || (keep != nil && !keep(comp.ID)) {
+			continue
+		}
+		changedAt[comp.ID] = updatedAt
+		changed = append(changed, c.readOut(comp))
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		a, b := changedAt[changed[i].ID], changedAt[changed[j].ID]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return changed[i].ID < changed[j].ID
+	})
+	return changed, true
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"component-service/models"
+	"testing"
+	"time"
+)
+
+func TestChangedSince(t *testing.T) {
+	at := func(minute int) string {
+		return time.Date(2024, 1, 2, 15, minute, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+	components := []*models.Component{
+		{ID: 1, Name: "root", UpdatedAt: at(0)},
+		{ID: 2, Name: "a", ParentID: nullInt64(1), UpdatedAt: at(20)},
+		{ID: 3, Name: "b", ParentID: nullInt64(1), UpdatedAt: at(10)},
+		{ID: 4, Name: "a1", ParentID: nullInt64(2), UpdatedAt: at(20)},
+		{ID: 5, Name: "other", UpdatedAt: at(30)},
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+	since := time.Date(2024, 1, 2, 15, 5, 0, 0, time.UTC)
+	ids := func(changed []*models.Component) []int64 {
+		var ids []int64
+		for _, comp := range changed {
+			ids = append(ids, comp.ID)
+		}
+		return ids
+	}
+	equal := func(a, b []int64) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	changed, found := cache.ChangedSince(1, true, since, nil)
+	if !found || !equal(ids(changed), []int64{2, 4, 3}) {
+		t.Errorf("Expected the subtree's changes most recent first, then by ID, got %v (found %v)", ids(changed), found)
+	}
+	if changed, _ := cache.ChangedSince(1, false, since, nil); len(changed) != 0 {
+		t.Errorf("Expected the unchanged root alone to report nothing, got %v", ids(changed))
+	}
+	if changed, _ := cache.ChangedSince(2, false, since, nil); !equal(ids(changed), []int64{2}) {
+		t.Errorf("Expected only the watched component without its subtree, got %v", ids(changed))
+	}
+	if changed, _ := cache.ChangedSince(1, true, since, func(id int64) bool { return id != 2 }); !equal(ids(changed), []int64{4, 3}) {
+		t.Errorf("Expected keep to drop component 2 alone, got %v", ids(changed))
+	}
+	if changed, _ := cache.ChangedSince(1, true, time.Date(2024, 1, 2, 15, 20, 0, 0, time.UTC), nil); len(changed) != 0 {
+		t.Errorf("Expected changes at since itself to be excluded, got %v", ids(changed))
+	}
+	if _, found := cache.ChangedSince(99, true, since, nil); found {
+		t.Errorf("Expected an unknown component not to be found")
+	}
+}
@@ -2,18 +2,50 @@ package cache
 
 import (
 	"component-service/models"
-	"component-service/store" // Import the store package
+	"crypto/sha256"
 	"database/sql"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
+// ComponentStoreInterface defines the methods that the cache will use to interact with the component store.
+// It lives in the cache package so that the store can depend on the cache without an import cycle.
+type ComponentStoreInterface interface {
+	ListComponents() ([]*models.Component, error)
+}
+
 // ComponentCache holds the in-memory cache for components.
+//
+// Immutability contract: a *models.Component is never modified once it is stored. Set and Delete
+// replace stored pointers with new values instead of updating them in place, so a pointer read
+// from the cache stays a consistent snapshot of that component. With Config.CopyOnRead off,
+// readers receive these shared pointers and must not modify them either.
 type ComponentCache struct {
 	mu                 sync.RWMutex
+	config             Config
 	componentsByID     map[int64]*models.Component
 	childrenByParentID map[int64][]*models.Component // Key is ParentID.Value.Int64, or a special key for nil parents
 	allComponents      []*models.Component
+	jsonByID           map[int64][]byte                // Pre-marshaled JSON of each component, kept in step with componentsByID
+	journal            syncJournal                     // Changed component IDs, for delta sync
+	hashByID           map[int64][sha256.Size]byte     // Merkle subtree hash of each component, maintained incrementally
+	createdOrder       []Cursor                        // Every component's (created_at, id), sorted, for cursor pagination
+	nameIndex          map[string][]int64              // Component IDs by exact name, for name filters
+	slugIndex          map[string]int64                // Component IDs by slug, for slug lookups
+	tagIndex           map[string][]int64              // Component IDs by tag, for tag filters
+	linkIndex          map[models.ComponentRef][]int64 // Component IDs by the components they refer to, for backlinks
+	aclByID            map[int64][]ACLEntry            // Each component's own ACL entries
+	effectiveACL       map[int64]aclTable              // Compiled effective ACL; absent for unrestricted components
+	publicIDs          map[int64]bool                  // Components flagged publicly visible, with their subtrees
+	accessChanges      uint64                          // Changes to ACLs and public flags, which journal does not record
+	flat               flatProjection                  // Reporting rows, rebuilt on read as writes drop them
+	flatMu             sync.Mutex                      // Serializes readers filling flat under the read lock
+	loader             ComponentLoader                 // Reloads invalidated components; nil unless WriteAround
+	staleIDs           map[int64]bool                  // Components invalidated since the last reload
+	staleMu            sync.Mutex                      // Guards staleIDs and serializes reloads
+	staleCount         atomic.Int64                    // len(staleIDs), checked without staleMu
+	views              viewLog                         // When components were last read, for Unreferenced
 }
 
 var GlobalComponentCache *ComponentCache
@@ -21,21 +53,67 @@ var GlobalComponentCache *ComponentCache
 // RootParentIDKey is a conventional key for root components (those with no parent or ParentID.Valid is false).
 const RootParentIDKey = 0 // Or use -1 if 0 is a valid component ID and also a valid ParentID for some components
 
+// NewComponentCache returns an empty cache with DefaultConfig.
 func NewComponentCache() *ComponentCache {
+	return NewComponentCacheWithConfig(DefaultConfig())
+}
+
+// NewComponentCacheWithConfig returns an empty cache with the given configuration.
+func NewComponentCacheWithConfig(cfg Config) *ComponentCache {
 	return &ComponentCache{
+		config:             cfg,
 		componentsByID:     make(map[int64]*models.Component),
 		childrenByParentID: make(map[int64][]*models.Component),
 		allComponents:      make([]*models.Component, 0),
+		jsonByID:           make(map[int64][]byte),
+		staleIDs:           make(map[int64]bool),
+		journal:            newSyncJournal(),
+		hashByID:           make(map[int64][sha256.Size]byte),
+		nameIndex:          make(map[string][]int64),
+		slugIndex:          make(map[string]int64),
+		tagIndex:           make(map[string][]int64),
+		linkIndex:          make(map[models.ComponentRef][]int64),
+		aclByID:            make(map[int64][]ACLEntry),
+		effectiveACL:       make(map[int64]aclTable),
+		publicIDs:          make(map[int64]bool),
+		flat:               newFlatProjection(),
+		views:              newViewLog(),
 	}
 }
 
 // InitGlobalCache initializes and populates the global component cache.
 // It fetches all components from the store and organizes them for quick access.
-func InitGlobalCache(s store.ComponentStoreInterface) error {
-	GlobalComponentCache = NewComponentCache() // Initialize the global instance
+// Rebuilding a global cache that held components counts them as evicted, since every checkpoint
+// issued by the old one expires.
+func InitGlobalCache(s ComponentStoreInterface) error {
+	if previous := GlobalComponentCache; previous != nil {
+		previous.mu.RLock()
+		held := len(previous.allComponents)
+		previous.mu.RUnlock()
+		if held > 0 {
+			recordEviction(ReasonRebuilt, held)
+		}
+	}
+	GlobalComponentCache = NewComponentCacheWithConfig(GlobalConfig) // Initialize the global instance
+	GlobalComponentCache.journal.trimmed = func(n int) { recordEviction(ReasonJournalTrimmed, n) }
+	return GlobalComponentCache.load(s)
+}
+
+// LoadComponentCache builds a cache of its own from s, apart from the global one, such as a
+// replica of another instance's components.
+func LoadComponentCache(s ComponentStoreInterface, cfg Config) (*ComponentCache, error) {
+	c := NewComponentCacheWithConfig(cfg)
+	if err := c.load(s); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
 
-	GlobalComponentCache.mu.Lock()
-	defer GlobalComponentCache.mu.Unlock()
+// load fills a new cache with every component of s, and with the ACL entries and public flags its
+// config asks for.
+func (c *ComponentCache) load(s ComponentStoreInterface) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	components, err := s.ListComponents()
 	if err != nil {
@@ -46,6 +124,7 @@ func InitGlobalCache(s store.ComponentStoreInterface) error {
 	tempComponentsByID := make(map[int64]*models.Component)
 	tempChildrenByParentID := make(map[int64][]*models.Component)
 	var tempAllComponents []*models.Component
+	tempJSONByID := make(map[int64][]byte, len(components))
 
 	for _, component := range components {
 		// Deep copy the component to avoid a pointer to loop variable issue
@@ -53,9 +132,15 @@ func InitGlobalCache(s store.ComponentStoreInterface) error {
 		// However, models.Component is a struct of basic types and sql.NullInt64,
 		// so a direct copy is fine unless there are deeper pointers. For now, direct assign is okay.
 		compCopy := *component // Create a copy
+		internComponentStrings(&compCopy)
 
 		tempComponentsByID[compCopy.ID] = &compCopy
+		tempJSONByID[compCopy.ID] = marshalFragment(&compCopy)
 		tempAllComponents = append(tempAllComponents, &compCopy)
+		c.indexName(&compCopy)
+		c.indexTags(&compCopy)
+		c.indexSlug(&compCopy)
+		c.indexLinks(&compCopy)
 
 		var parentKey int64
 		if compCopy.ParentID.Valid {
@@ -66,11 +151,38 @@ func InitGlobalCache(s store.ComponentStoreInterface) error {
 		tempChildrenByParentID[parentKey] = append(tempChildrenByParentID[parentKey], &compCopy)
 	}
 
-	GlobalComponentCache.componentsByID = tempComponentsByID
-	GlobalComponentCache.childrenByParentID = tempChildrenByParentID
-	GlobalComponentCache.allComponents = tempAllComponents
+	c.componentsByID = tempComponentsByID
+	c.childrenByParentID = tempChildrenByParentID
+	c.allComponents = tempAllComponents
+	c.jsonByID = tempJSONByID
+	c.hashByID = c.subtreeHashes()
+	c.sortCreatedOrder()
+
+	if c.WritePolicy() == WriteAround {
+		loader, ok := s.(ComponentLoader)
+		if !ok {
+			return fmt.Errorf("cache write policy %s needs a store that can load components by ID", WriteAround)
+		}
+		c.loader = loader
+	}
+	if source, ok := s.(ACLSource); ok && c.config.LoadACL {
+		entries, err := source.ListACLEntries()
+		if err != nil {
+			return fmt.Errorf("failed to list ACL entries for cache initialization: %w", err)
+		}
+		c.loadACL(entries)
+	}
+	if source, ok := s.(PublicSource); ok && c.config.LoadPublic {
+		ids, err := source.ListPublicComponentIDs()
+		if err != nil {
+			return fmt.Errorf("failed to list public components for cache initialization: %w", err)
+		}
+		for _, id := range ids {
+			c.publicIDs[id] = true
+		}
+	}
 
-	// fmt.Printf("Cache initialized with %d components, %d parent groups.\n", len(GlobalComponentCache.allComponents), len(GlobalComponentCache.childrenByParentID))
+	// fmt.Printf("Cache initialized with %d components, %d parent groups.\n", len(c.allComponents), len(c.childrenByParentID))
 	return nil
 }
 
@@ -80,32 +192,78 @@ func (c *ComponentCache) Set(component *models.Component) {
 	if component == nil {
 		return
 	}
+	c.SetMany([]*models.Component{component})
+}
+
+// SetMany adds or updates several components in a single pass under the write lock, so readers
+// see either none or all of them changed, as when a batch move reparents several subtrees.
+func (c *ComponentCache) SetMany(components []*models.Component) {
+	copies := make([]*models.Component, 0, len(components))
+	fragments := make([][]byte, 0, len(components))
+	for _, component := range components {
+		if component == nil {
+			continue
+		}
+		compCopy := *component // Store a copy
+		internComponentStrings(&compCopy)
+		copies = append(copies, &compCopy)
+		fragments = append(fragments, marshalFragment(&compCopy)) // Marshal outside the lock
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	oldParentIDs := make([]sql.NullInt64, len(copies))
+	reparented := make([]bool, len(copies))
+	for i, compCopy := range copies {
+		oldParentIDs[i], reparented[i] = c.set(compCopy, fragments[i])
+	}
+	// Hashes and ACLs are derived from the finished tree: midway through a batch, the tree can
+	// hold a transient cycle (two subtrees swapping places), which the ACL walk must not enter.
+	for i, compCopy := range copies {
+		c.rehashFrom(compCopy.ID)
+		if oldParentIDs[i].Valid {
+			c.rehashFrom(oldParentIDs[i].Int64)
+		}
+		if len(c.effectiveACL) > 0 && reparented[i] {
+			c.compileACL(compCopy.ID) // a new or moved component inherits from its new parent
+		}
+	}
+}
 
+// set stores a component copy and its JSON fragment in every index but the Merkle hashes and
+// ACLs, which SetMany derives afterwards. It returns the parent the component moved away from,
+// and whether the component is new or moved. Assumes the write lock is held.
+func (c *ComponentCache) set(compCopy *models.Component, fragment []byte) (oldParentID sql.NullInt64, reparented bool) {
 	// Remove from old parent's children list if it exists and parent has changed
-	if oldComp, exists := c.componentsByID[component.ID]; exists {
-		if oldComp.ParentID != component.ParentID { // This comparison works for sql.NullInt64
+	oldComp, existed := c.componentsByID[compCopy.ID]
+	if existed {
+		c.unindexCreated(oldComp)
+		c.unindexName(oldComp)
+		c.unindexTags(oldComp)
+		c.unindexSlug(oldComp)
+		c.unindexLinks(oldComp)
+		if oldComp.ParentID != compCopy.ParentID { // This comparison works for sql.NullInt64
 			oldParentKey := getParentKey(oldComp.ParentID)
 			c.removeChildFromParent(oldComp.ID, oldParentKey)
+			oldParentID = oldComp.ParentID
 		}
 	}
 
-	compCopy := *component // Store a copy
-	c.componentsByID[compCopy.ID] = &compCopy
+	c.componentsByID[compCopy.ID] = compCopy
+	c.jsonByID[compCopy.ID] = fragment
 
 	// Update allComponents: remove old if exists, then add new
 	// More efficient to rebuild if component found, or append if not.
 	foundInAll := false
 	for i, comp := range c.allComponents {
 		if comp.ID == compCopy.ID {
-			c.allComponents[i] = &compCopy // replace with new version
+			c.allComponents[i] = compCopy // replace with new version
 			foundInAll = true
 			break
 		}
 	}
 	if !foundInAll {
-		c.allComponents = append(c.allComponents, &compCopy) // add if new
+		c.allComponents = append(c.allComponents, compCopy) // add if new
 	}
 
 	// Add to new parent's children list
@@ -115,7 +273,24 @@ func (c *ComponentCache) Set(component *models.Component) {
 	// or if a component is moved to a parent list where it might already exist due to some complex scenario.
 	// First, try to remove it from the new parent's list to avoid duplicates, then add it.
 	c.removeChildFromParent(compCopy.ID, newParentKey)
-	c.childrenByParentID[newParentKey] = append(c.childrenByParentID[newParentKey], &compCopy)
+	c.childrenByParentID[newParentKey] = append(c.childrenByParentID[newParentKey], compCopy)
+	c.indexCreated(compCopy)
+	c.indexName(compCopy)
+	c.indexTags(compCopy)
+	c.indexSlug(compCopy)
+	c.indexLinks(compCopy)
+	c.journal.record(compCopy.ID)
+
+	reparented = !existed || oldComp.ParentID != compCopy.ParentID
+	c.dropFlat(compCopy.ID)
+	if existed && (reparented || oldComp.Name != compCopy.Name) {
+		c.dropFlatSubtree(compCopy.ID)
+	}
+	if reparented {
+		c.dropFlatAncestors(oldParentID)
+		c.dropFlatAncestors(compCopy.ParentID)
+	}
+	return oldParentID, reparented
 }
 
 // Delete removes a component from the cache.
@@ -129,6 +304,11 @@ func (c *ComponentCache) Delete(componentID int64) {
 	}
 
 	delete(c.componentsByID, componentID)
+	delete(c.jsonByID, componentID)
+	delete(c.hashByID, componentID)
+	delete(c.aclByID, componentID)
+	delete(c.effectiveACL, componentID)
+	delete(c.publicIDs, componentID)
 
 	var updatedAllComponents []*models.Component
 	for _, comp := range c.allComponents {
@@ -140,6 +320,97 @@ func (c *ComponentCache) Delete(componentID int64) {
 
 	parentKey := getParentKey(component.ParentID)
 	c.removeChildFromParent(componentID, parentKey)
+	c.unindexCreated(component)
+	c.unindexName(component)
+	c.unindexTags(component)
+	c.unindexSlug(component)
+	c.unindexLinks(component)
+	c.forgetViews(componentID)
+	c.journal.record(componentID)
+	c.dropFlat(componentID)
+	c.dropFlatAncestors(component.ParentID)
+
+	// Mirror the schema's ON DELETE SET NULL: direct children of the deleted component become roots.
+	if orphans, ok := c.childrenByParentID[componentID]; ok {
+		delete(c.childrenByParentID, componentID)
+		for _, orphan := range orphans {
+			orphanCopy := *orphan
+			orphanCopy.ParentID = sql.NullInt64{Valid: false}
+			c.replaceComponent(&orphanCopy)
+			c.childrenByParentID[RootParentIDKey] = append(c.childrenByParentID[RootParentIDKey], &orphanCopy)
+			c.journal.record(orphanCopy.ID)
+			c.dropFlat(orphanCopy.ID)
+			c.dropFlatSubtree(orphanCopy.ID) // roots now: their subtrees lost every ancestor above
+			if len(c.effectiveACL) > 0 {
+				c.compileACL(orphanCopy.ID) // roots now: only their own entries apply
+			}
+		}
+	}
+
+	if component.ParentID.Valid {
+		c.rehashFrom(component.ParentID.Int64)
+	}
+}
+
+// DeleteSubtree removes a component and every component below it from the cache under a single
+// write lock, as a cascading delete removes them in one transaction. Nothing is orphaned.
+func (c *ComponentCache) DeleteSubtree(rootID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	root, exists := c.componentsByID[rootID]
+	if !exists {
+		return
+	}
+	removed := map[int64]bool{rootID: true}
+	for _, descendant := range c.descendants(rootID, 0) {
+		removed[descendant.ID] = true
+	}
+
+	for id := range removed {
+		component := c.componentsByID[id]
+		delete(c.componentsByID, id)
+		delete(c.jsonByID, id)
+		delete(c.hashByID, id)
+		delete(c.aclByID, id)
+		delete(c.effectiveACL, id)
+		delete(c.publicIDs, id)
+		delete(c.childrenByParentID, id)
+		c.unindexCreated(component)
+		c.unindexName(component)
+		c.unindexTags(component)
+		c.unindexSlug(component)
+		c.unindexLinks(component)
+		c.forgetViews(id)
+		c.journal.record(id)
+		c.dropFlat(id)
+	}
+	updatedAllComponents := make([]*models.Component, 0, len(c.allComponents)-len(removed))
+	for _, comp := range c.allComponents {
+		if !removed[comp.ID] {
+			updatedAllComponents = append(updatedAllComponents, comp)
+		}
+	}
+	c.allComponents = updatedAllComponents
+
+	c.removeChildFromParent(rootID, getParentKey(root.ParentID))
+	c.dropFlatAncestors(root.ParentID)
+	if root.ParentID.Valid {
+		c.rehashFrom(root.ParentID.Int64)
+	}
+}
+
+// replaceComponent swaps the stored pointer for a component in componentsByID and allComponents.
+// It does not touch childrenByParentID. Assumes lock is already held.
+func (c *ComponentCache) replaceComponent(component *models.Component) {
+	c.componentsByID[component.ID] = component
+	c.jsonByID[component.ID] = marshalFragment(component)
+	for i, comp := range c.allComponents {
+		if comp.ID == component.ID {
+			c.allComponents[i] = component
+			break
+		}
+	}
 }
 
 // removeChildFromParent is an internal helper to remove a child from a parent's list.
@@ -164,33 +435,33 @@ func (c *ComponentCache) removeChildFromParent(childID int64, parentKey int64) {
 
 // GetByID retrieves a component by its ID from the cache.
 func (c *ComponentCache) GetByID(id int64) (*models.Component, bool) {
-	c.mu.RLock()
+	c.rlock()
 	defer c.mu.RUnlock()
 	component, found := c.componentsByID[id]
 	if !found {
 		return nil, false
 	}
-	compCopy := *component // Return a copy
-	return &compCopy, true
+	return c.readOut(component), true
 }
 
-// GetAll retrieves all components from the cache.
+// GetAll retrieves all components from the cache, newest first with ties by descending ID, as
+// the database lists them.
 func (c *ComponentCache) GetAll() []*models.Component {
-	c.mu.RLock()
+	c.rlock()
 	defer c.mu.RUnlock()
-	// Return copies to prevent external modification of cached objects
+	// Return copies to prevent external modification of cached objects, unless configured otherwise
 	copiedComponents := make([]*models.Component, 0, len(c.allComponents))
-	for _, comp := range c.allComponents {
-		compCopy := *comp
-		copiedComponents = append(copiedComponents, &compCopy)
+	for _, comp := range c.inCreatedOrder(Filter{}, true, 0, 0) {
+		copiedComponents = append(copiedComponents, c.readOut(comp))
 	}
 	return copiedComponents
 }
 
-// GetChildren retrieves direct children of a given parent ID from the cache.
+// GetChildren retrieves direct children of a given parent ID from the cache, by position, then
+// oldest first with ties by ID, as the database lists them.
 // The parentID parameter here is the actual value of the parent's ID, or RootParentIDKey for root items.
 func (c *ComponentCache) GetChildren(parentID int64) ([]*models.Component, bool) {
-	c.mu.RLock()
+	c.rlock()
 	defer c.mu.RUnlock()
 
 	// The key in childrenByParentID (parentKey) was determined by getParentKey during Set/Init.
@@ -202,13 +473,53 @@ func (c *ComponentCache) GetChildren(parentID int64) ([]*models.Component, bool)
 	}
 
 	copiedChildren := make([]*models.Component, 0, len(children))
-	for _, comp := range children {
-		compCopy := *comp
-		copiedChildren = append(copiedChildren, &compCopy)
+	for _, comp := range siblingOrder.sorted(children) {
+		copiedChildren = append(copiedChildren, c.readOut(comp))
 	}
 	return copiedChildren, true
 }
 
+// descendants returns the subtree below rootID, excluding rootID itself, in breadth-first order
+// with each level ordered by ID. maxDepth > 0 stops the walk after that many levels. Components
+// already visited are skipped, so a parent cycle cannot loop the walk. Assumes the read lock is held.
+func (c *ComponentCache) descendants(rootID int64, maxDepth int) []*models.Component {
+	var result []*models.Component
+	visited := map[int64]bool{rootID: true}
+	level := []int64{rootID}
+	for depth := 1; len(level) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		var next []*models.Component
+		for _, id := range level {
+			for _, child := range c.childrenByParentID[id] {
+				if !visited[child.ID] {
+					visited[child.ID] = true
+					next = append(next, child)
+				}
+			}
+		}
+		next = sortedByID(next)
+		result = append(result, next...)
+		level = level[:0]
+		for _, comp := range next {
+			level = append(level, comp.ID)
+		}
+	}
+	return result
+}
+
+// Count returns the number of cached components.
+func (c *ComponentCache) Count() int {
+	c.rlock()
+	defer c.mu.RUnlock()
+	return len(c.allComponents)
+}
+
+// ChildCount returns the number of direct children of parentID without copying them.
+func (c *ComponentCache) ChildCount(parentID int64) int {
+	c.rlock()
+	defer c.mu.RUnlock()
+	return len(c.childrenByParentID[parentID])
+}
+
 // getParentKey is a helper to determine the key for the childrenByParentID map.
 // It uses RootParentIDKey if ParentID is not valid (i.e., for root components).
 func getParentKey(parentID sql.NullInt64) int64 {
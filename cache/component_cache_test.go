@@ -2,10 +2,9 @@ package cache
 
 import (
 	"component-service/models"
-	"component-service/store"
 	"database/sql"
+	"encoding/json"
 	"reflect"
-	"sort"
 	"testing"
 )
 
@@ -472,7 +471,7 @@ func TestComponentCache_Delete(t *testing.T) {
         // Children of the deleted root should still exist (now as orphans or attached to RootParentIDKey implicitly)
         // Let's verify their existence and potentially their new parentage if we expect them to become roots.
         c200, foundC200 := GlobalComponentCache.GetByID(200)
-        c300, foundC300 := GlobalComponentCache.GetByID(300)
+        _, foundC300 := GlobalComponentCache.GetByID(300)
 
         if !foundC200 {
             t.Errorf("DeleteRootWithChildren: Child C200 not found, it should remain")
@@ -514,3 +513,114 @@ func TestComponentCache_Delete(t *testing.T) {
 		}
 	})
 }
+
+func TestComponentCache_DeleteSubtree(t *testing.T) {
+	// 1 -> 2 -> 3, 1 -> 4, with 5 on its own.
+	store := &MockComponentStore{mockComponents: []*models.Component{
+		{ID: 1, Name: "root", ParentID: invalidNullInt64()},
+		{ID: 2, Name: "child", ParentID: nullInt64(1)},
+		{ID: 3, Name: "grandchild", ParentID: nullInt64(2)},
+		{ID: 4, Name: "sibling", ParentID: nullInt64(1)},
+		{ID: 5, Name: "other", ParentID: invalidNullInt64()},
+	}}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+	before, _ := cache.SubtreeHash(1)
+
+	cache.DeleteSubtree(2)
+	for _, id := range []int64{2, 3} {
+		if _, found := cache.GetByID(id); found {
+			t.Errorf("component %d still cached after deleting its subtree", id)
+		}
+	}
+	if got := len(cache.GetAll()); got != 3 {
+		t.Errorf("expected 3 components left, got %d", got)
+	}
+	if children, _ := cache.GetChildren(1); len(children) != 1 || children[0].ID != 4 {
+		t.Errorf("expected 4 to be the only child of 1, got %+v", children)
+	}
+	if roots, _ := cache.GetChildren(RootParentIDKey); len(roots) != 2 {
+		t.Errorf("expected no new roots, got %d roots", len(roots))
+	}
+	if after, _ := cache.SubtreeHash(1); after == before {
+		t.Error("expected the hash of 1 to change")
+	}
+
+	cache.DeleteSubtree(99) // not cached: a no-op
+	if got := len(cache.GetAll()); got != 3 {
+		t.Errorf("expected 3 components left, got %d", got)
+	}
+}
+
+func TestComponentCache_SetMany(t *testing.T) {
+	// 1 -> 2 -> 3; the batch swaps 2 and 3, so applying the first move alone would leave 2 and 3
+	// parents of each other.
+	store := &aclMockStore{
+		MockComponentStore: MockComponentStore{mockComponents: []*models.Component{
+			aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2),
+		}},
+		entries: []ACLEntry{{ComponentID: 1, Principal: EveryonePrincipal, Permission: PermissionRead}},
+	}
+	defer func(cfg Config) { GlobalConfig = cfg }(GlobalConfig)
+	GlobalConfig.LoadACL = true
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	c := GlobalComponentCache
+
+	c.SetMany([]*models.Component{aclTestComponent(2, 3), aclTestComponent(3, 1)})
+
+	if children, _ := c.GetChildren(1); len(children) != 1 || children[0].ID != 3 {
+		t.Errorf("children of 1 = %v; want [3]", children)
+	}
+	if children, _ := c.GetChildren(3); len(children) != 1 || children[0].ID != 2 {
+		t.Errorf("children of 3 = %v; want [2]", children)
+	}
+	if children, found := c.GetChildren(2); found && len(children) > 0 {
+		t.Errorf("children of 2 = %v; want none", children)
+	}
+	if !c.Allows(2, "anyone", PermissionRead) || c.Allows(2, "anyone", PermissionWrite) {
+		t.Error("component 2 does not inherit the root's ACL after the batch")
+	}
+}
+
+func TestComponentCache_DescendantsJSON(t *testing.T) {
+	// 1 -> {5, 2}, 2 -> 3, 5 -> 4; 6 on its own.
+	store := &MockComponentStore{mockComponents: []*models.Component{
+		aclTestComponent(1, 0), aclTestComponent(5, 1), aclTestComponent(2, 1),
+		aclTestComponent(3, 2), aclTestComponent(4, 5), aclTestComponent(6, 0),
+	}}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	ids := func(body []byte) []int64 {
+		var components []models.Component
+		if err := json.Unmarshal(body, &components); err != nil {
+			t.Fatalf("DescendantsJSON returned invalid JSON: %v", err)
+		}
+		var result []int64
+		for _, comp := range components {
+			result = append(result, comp.ID)
+		}
+		return result
+	}
+
+	body, total, err := GlobalComponentCache.DescendantsJSON(1, 0, Filter{}, 0, 0)
+	if err != nil || total != 4 || !reflect.DeepEqual(ids(body), []int64{2, 5, 3, 4}) {
+		t.Errorf("DescendantsJSON(1) = %v, %d, %v; want [2 5 3 4] level by level, 4", ids(body), total, err)
+	}
+	body, total, _ = GlobalComponentCache.DescendantsJSON(1, 0, Filter{}, 1, 2)
+	if total != 4 || !reflect.DeepEqual(ids(body), []int64{5, 3}) {
+		t.Errorf("DescendantsJSON(1, offset 1, limit 2) = %v, %d; want [5 3], 4", ids(body), total)
+	}
+	body, total, _ = GlobalComponentCache.DescendantsJSON(1, 1, Filter{}, 0, 0)
+	if total != 2 || !reflect.DeepEqual(ids(body), []int64{2, 5}) {
+		t.Errorf("DescendantsJSON(1, depth 1) = %v, %d; want [2 5], 2", ids(body), total)
+	}
+	body, total, _ = GlobalComponentCache.DescendantsJSON(6, 0, Filter{}, 0, 0)
+	if total != 0 || string(body) != "[]" {
+		t.Errorf("DescendantsJSON of a leaf = %s, %d; want [], 0", body, total)
+	}
+}
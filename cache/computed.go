@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"component-service/computed"
+	"component-service/models"
+	"errors"
+	"fmt"
+)
+
+// computedNode exposes a cached component to the computed field evaluator. Its key is the
+// subtree hash, which changes whenever the component or a descendant does. Assumes the read lock
+// is held for as long as the node is used.
+type computedNode struct {
+	c    *ComponentCache
+	comp *models.Component
+}
+
+func (n computedNode) Field(name string) computed.Value {
+	switch name {
+	case "id":
+		return float64(n.comp.ID)
+	case "name":
+		return n.comp.Name
+	case "description":
+		return n.comp.Description
+	}
+	return nil
+}
+
+func (n computedNode) Children() []computed.Node {
+	children := sortedByID(n.c.childrenByParentID[n.comp.ID])
+	nodes := make([]computed.Node, len(children))
+	for i, child := range children {
+		nodes[i] = computedNode{n.c, child}
+	}
+	return nodes
+}
+
+func (n computedNode) Key() string {
+	hash, found := n.c.hashByID[n.comp.ID]
+	if !found {
+		return ""
+	}
+	return string(hash[:])
+}
+
+// ErrNoComputedFields is returned by ComputedFields when Config.Computed defines no fields.
+var ErrNoComputedFields = errors.New("no computed fields are defined")
+
+// ComputedFields evaluates the computed fields of Config.Computed for the given components. The
+// whole batch is evaluated under one read lock, so rollups see a consistent tree. Components the
+// cache does not hold are left out of the result.
+func (c *ComponentCache) ComputedFields(ids []int64) (map[int64]map[string]computed.Value, error) {
+	fields := c.config.Computed
+	if fields == nil {
+		return nil, ErrNoComputedFields
+	}
+	c.rlock()
+	defer c.mu.RUnlock()
+	values := make(map[int64]map[string]computed.Value, len(ids))
+	for _, id := range ids {
+		comp, found := c.componentsByID[id]
+		if !found {
+			continue
+		}
+		componentValues, err := fields.Evaluate(computedNode{c, comp})
+		if err != nil {
+			return nil, fmt.Errorf("error computing fields of component %d: %w", id, err)
+		}
+		values[id] = componentValues
+	}
+	return values, nil
+}
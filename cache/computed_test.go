@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"component-service/computed"
+	"component-service/models"
+	"testing"
+)
+
+func TestComponentCache_ComputedFields(t *testing.T) {
+	fields, err := computed.Parse(map[string]string{"size": "1 + sum(children.size)"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	defer func(cfg Config) { GlobalConfig = cfg }(GlobalConfig)
+	GlobalConfig.Computed = fields
+	store := &MockComponentStore{mockComponents: []*models.Component{
+		aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2),
+	}}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	c := GlobalComponentCache
+
+	values, err := c.ComputedFields([]int64{1, 3, 99})
+	if err != nil {
+		t.Fatalf("ComputedFields failed: %v", err)
+	}
+	if values[1]["size"] != 3.0 || values[3]["size"] != 1.0 {
+		t.Errorf("sizes = %v, %v; want 3, 1", values[1]["size"], values[3]["size"])
+	}
+	if _, found := values[99]; found {
+		t.Error("an unknown component got computed values")
+	}
+
+	// The memo is keyed by subtree hash, so a change below a component is picked up.
+	c.Set(aclTestComponent(4, 2))
+	values, err = c.ComputedFields([]int64{1})
+	if err != nil {
+		t.Fatalf("ComputedFields failed: %v", err)
+	}
+	if values[1]["size"] != 4.0 {
+		t.Errorf("size after adding a grandchild = %v; want 4", values[1]["size"])
+	}
+
+	GlobalConfig.Computed = nil
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	if _, err := GlobalComponentCache.ComputedFields([]int64{1}); err != ErrNoComputedFields {
+		t.Errorf("ComputedFields without definitions error = %v; want ErrNoComputedFields", err)
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"component-service/computed"
+	"component-service/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Config tunes cache behavior.
+type Config struct {
+	// CopyOnRead makes GetByID, GetAll and GetChildren return copies of the cached components, so
+	// callers may modify what they get back. When false they return the cached pointers
+	// themselves, saving an allocation and copy per component; callers must then honor the
+	// immutability contract on ComponentCache and never modify a returned component.
+	CopyOnRead bool
+
+	// LoadACL makes InitGlobalCache load ACL entries from a store implementing ACLSource and
+	// compile them into the ACL index. It is off unless ACLs are enforced, so deployments that
+	// do not use them need not have the component_acl table.
+	LoadACL bool
+
+	// LoadPublic makes InitGlobalCache load public visibility flags from a store implementing
+	// PublicSource. Like LoadACL, it is off unless the public router is enabled.
+	LoadPublic bool
+
+	// WritePolicy chooses how the service's own writes reach the cache. Empty means
+	// WriteThrough.
+	WritePolicy WritePolicy
+
+	// Computed defines the computed fields ComputedFields evaluates, or is nil when none are.
+	Computed *computed.Fields
+}
+
+// DefaultConfig is the safe configuration: every read returns copies.
+func DefaultConfig() Config {
+	return Config{CopyOnRead: true}
+}
+
+// GlobalConfig is applied by InitGlobalCache, including when the global cache is rebuilt.
+var GlobalConfig = DefaultConfig()
+
+// ConfigFromEnv reads CACHE_COPY_ON_READ (a boolean, default true) and CACHE_WRITE_POLICY
+// (default write-through) over DefaultConfig, and the computed field definitions named by
+// COMPUTED_FIELDS_FILE.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	if value := os.Getenv("CACHE_COPY_ON_READ"); value != "" {
+		copyOnRead, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid CACHE_COPY_ON_READ %q: expected true or false", value)
+		}
+		cfg.CopyOnRead = copyOnRead
+	}
+	if value := os.Getenv("CACHE_WRITE_POLICY"); value != "" {
+		policy, err := parseWritePolicy(value)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.WritePolicy = policy
+	}
+	computedFields, err := computed.FromEnv()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Computed = computedFields
+	return cfg, nil
+}
+
+// readOut returns what a read hands to callers: a copy, with tags and metadata of its own, or the
+// cached pointer itself when CopyOnRead is off.
+func (c *ComponentCache) readOut(component *models.Component) *models.Component {
+	if !c.config.CopyOnRead {
+		return component
+	}
+	compCopy := *component
+	if len(compCopy.Tags) > 0 {
+		compCopy.Tags = append([]string(nil), compCopy.Tags...)
+	}
+	if len(compCopy.Metadata) > 0 {
+		compCopy.Metadata = append(json.RawMessage(nil), compCopy.Metadata...)
+	}
+	return &compCopy
+}
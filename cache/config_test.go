@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"component-service/models"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	cfg, err := ConfigFromEnv()
+	if err != nil || !cfg.CopyOnRead {
+		t.Errorf("Expected copy-on-read by default, got %+v, %v", cfg, err)
+	}
+	t.Setenv("CACHE_COPY_ON_READ", "false")
+	if cfg, err = ConfigFromEnv(); err != nil || cfg.CopyOnRead {
+		t.Errorf("Expected copy-on-read disabled, got %+v, %v", cfg, err)
+	}
+	t.Setenv("CACHE_COPY_ON_READ", "sometimes")
+	if _, err = ConfigFromEnv(); err == nil {
+		t.Error("Expected an error for an invalid CACHE_COPY_ON_READ")
+	}
+	t.Setenv("CACHE_COPY_ON_READ", "")
+	t.Setenv("CACHE_WRITE_POLICY", "write-around")
+	if cfg, err = ConfigFromEnv(); err != nil || cfg.WritePolicy != WriteAround {
+		t.Errorf("Expected write-around, got %+v, %v", cfg, err)
+	}
+	for _, policy := range []string{"write-back", "sometimes"} {
+		t.Setenv("CACHE_WRITE_POLICY", policy)
+		if _, err = ConfigFromEnv(); err == nil {
+			t.Errorf("Expected an error for CACHE_WRITE_POLICY %s", policy)
+		}
+	}
+}
+
+func TestCopyOnRead(t *testing.T) {
+	defer func(previous Config) { GlobalConfig = previous }(GlobalConfig)
+
+	for _, copyOnRead := range []bool{true, false} {
+		GlobalConfig = Config{CopyOnRead: copyOnRead}
+		c1 := *comp1Global
+		c2 := *comp2Global
+		if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c1, &c2}}); err != nil {
+			t.Fatalf("InitGlobalCache failed: %v", err)
+		}
+		cache := GlobalComponentCache
+
+		first, _ := cache.GetByID(1)
+		second, _ := cache.GetByID(1)
+		all := cache.GetAll()
+		children, _ := cache.GetChildren(1)
+		shared := first == second && all[1] == first && children[0] == all[0] // all is newest first
+		if shared == copyOnRead {
+			t.Errorf("CopyOnRead=%v: expected shared pointers %v, got %v", copyOnRead, !copyOnRead, shared)
+		}
+
+		// Either way, a later write never changes a component already handed out.
+		renamed := c1
+		renamed.Name = "Renamed"
+		cache.Set(&renamed)
+		if first.Name != "Comp 1" {
+			t.Errorf("CopyOnRead=%v: expected a previously read component to stay unchanged, got %q", copyOnRead, first.Name)
+		}
+	}
+}
+
+func BenchmarkGetByID(b *testing.B) {
+	defer func(previous Config) { GlobalConfig = previous }(GlobalConfig)
+	for _, copyOnRead := range []bool{true, false} {
+		GlobalConfig = Config{CopyOnRead: copyOnRead}
+		if err := InitGlobalCache(&MockComponentStore{mockComponents: repetitiveComponents(1000)}); err != nil {
+			b.Fatalf("InitGlobalCache failed: %v", err)
+		}
+		name := "copy"
+		if !copyOnRead {
+			name = "shared"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				GlobalComponentCache.GetByID(int64(i%1000) + 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+	"time"
+)
+
+// Cursor is a position in (created_at, id) order. Unlike an offset it stays valid when components
+// are inserted or deleted before it, so paging by cursor neither skips nor repeats components.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// After reports whether c sorts after other.
+func (c Cursor) After(other Cursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.After(other.CreatedAt)
+	}
+	return c.ID > other.ID
+}
+
+// cursorOf returns a component's position. An unparseable created_at sorts first.
+func cursorOf(component *models.Component) Cursor {
+	createdAt, _ := time.Parse(time.RFC3339Nano, component.CreatedAt)
+	return Cursor{CreatedAt: createdAt, ID: component.ID}
+}
+
+// indexCreated inserts a component into createdOrder. Assumes the write lock is held.
+func (c *ComponentCache) indexCreated(component *models.Component) {
+	key := cursorOf(component)
+	i := sort.Search(len(c.createdOrder), func(i int) bool { return !key.After(c.createdOrder[i]) })
+	c.createdOrder = append(c.createdOrder, Cursor{})
+	copy(c.createdOrder[i+1:], c.createdOrder[i:])
+	c.createdOrder[i] = key
+}
+
+// unindexCreated removes a component from createdOrder. Assumes the write lock is held.
+func (c *ComponentCache) unindexCreated(component *models.Component) {
+	key := cursorOf(component)
+	i := sort.Search(len(c.createdOrder), func(i int) bool { return !key.After(c.createdOrder[i]) })
+	if i < len(c.createdOrder) && !c.createdOrder[i].After(key) {
+		c.createdOrder = append(c.createdOrder[:i], c.createdOrder[i+1:]...)
+	}
+}
+
+// sortCreatedOrder builds createdOrder from scratch. Assumes the write lock is held.
+func (c *ComponentCache) sortCreatedOrder() {
+	c.createdOrder = make([]Cursor, 0, len(c.allComponents))
+	for _, comp := range c.allComponents {
+		c.createdOrder = append(c.createdOrder, cursorOf(comp))
+	}
+	sort.Slice(c.createdOrder, func(i, j int) bool { return c.createdOrder[j].After(c.createdOrder[i]) })
+}
+
+// inCreatedOrder returns the page of components selected by filter in (created_at, id) order,
+// newest first when descending, walking createdOrder so that a page costs no sort. limit > 0
+// selects at most limit components starting at offset; otherwise every component from offset
+// onwards is returned. Assumes the read lock is held.
+func (c *ComponentCache) inCreatedOrder(filter Filter, descending bool, offset, limit int) []*models.Component {
+	var page []*models.Component
+	for i := range c.createdOrder {
+		key := c.createdOrder[i]
+		if descending {
+			key = c.createdOrder[len(c.createdOrder)-1-i]
+		}
+		comp := c.componentsByID[key.ID]
+		if !filter.Matches(comp) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if limit > 0 && len(page) == limit {
+			break
+		}
+		page = append(page, comp)
+	}
+	return page
+}
+
+// JSONAfter returns, as a JSON array, up to limit components selected by filter in
+// (created_at, id) order, starting after the given cursor or from the beginning when after is nil.
+// next is the cursor to resume from, or nil when no matching components follow the page.
+func (c *ComponentCache) JSONAfter(filter Filter, after *Cursor, limit int) (body []byte, next *Cursor, err error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	filter = c.withReader(filter)
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(c.createdOrder), func(i int) bool { return c.createdOrder[i].After(*after) })
+	}
+	var page []*models.Component
+	for _, key := range c.createdOrder[start:] {
+		comp := c.componentsByID[key.ID]
+		if !filter.Matches(comp) {
+			continue
+		}
+		if limit > 0 && len(page) == limit {
+			last := cursorOf(page[len(page)-1])
+			next = &last
+			break
+		}
+		page = append(page, comp)
+	}
+	body, err = c.appendJSONArray(make([]byte, 0, c.jsonSizeHint(page)), page)
+	return body, next, err
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func cursorTestComponent(id int64, createdAt string) *models.Component {
+	return &models.Component{ID: id, Name: "Comp", ParentID: invalidNullInt64(), CreatedAt: createdAt}
+}
+
+// pageIDs decodes a JSONAfter page into its component IDs.
+func pageIDs(t *testing.T, body []byte) []int64 {
+	t.Helper()
+	var components []*models.Component
+	if err := json.Unmarshal(body, &components); err != nil {
+		t.Fatalf("Invalid page JSON %s: %v", body, err)
+	}
+	ids := []int64{}
+	for _, comp := range components {
+		ids = append(ids, comp.ID)
+	}
+	return ids
+}
+
+func TestComponentCache_JSONAfter(t *testing.T) {
+	// Loaded newest first, as the store lists them; IDs 2 and 3 share a timestamp.
+	components := []*models.Component{
+		cursorTestComponent(4, "2024-01-03T00:00:00Z"),
+		cursorTestComponent(3, "2024-01-02T00:00:00Z"),
+		cursorTestComponent(2, "2024-01-02T00:00:00Z"),
+		cursorTestComponent(1, "2024-01-01T00:00:00Z"),
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+
+	body, next, err := cache.JSONAfter(Filter{}, nil, 2)
+	if err != nil {
+		t.Fatalf("JSONAfter failed: %v", err)
+	}
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("Expected first page [1 2], got %v", ids)
+	}
+	if next == nil || next.ID != 2 {
+		t.Fatalf("Expected a next cursor at component 2, got %+v", next)
+	}
+
+	// Inserting before the cursor and deleting a seen component must not shift the next page.
+	cache.Set(cursorTestComponent(5, "2023-12-31T00:00:00Z"))
+	cache.Delete(1)
+	cache.Set(cursorTestComponent(6, "2024-01-04T00:00:00Z"))
+
+	body, next, err = cache.JSONAfter(Filter{}, next, 2)
+	if err != nil {
+		t.Fatalf("JSONAfter failed: %v", err)
+	}
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{3, 4}) {
+		t.Errorf("Expected second page [3 4], got %v", ids)
+	}
+	body, next, _ = cache.JSONAfter(Filter{}, next, 2)
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{6}) || next != nil {
+		t.Errorf("Expected a last page [6] without next cursor, got %v, %+v", ids, next)
+	}
+
+	// An update keeps one index entry per component.
+	renamed := *cursorTestComponent(3, "2024-01-02T00:00:00Z")
+	renamed.Name = "Renamed"
+	cache.Set(&renamed)
+	if len(cache.createdOrder) != len(cache.componentsByID) {
+		t.Errorf("Expected %d index entries, got %d", len(cache.componentsByID), len(cache.createdOrder))
+	}
+	body, _, _ = cache.JSONAfter(Filter{}, nil, 0)
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{5, 2, 3, 4, 6}) {
+		t.Errorf("Expected full order [5 2 3 4 6], got %v", ids)
+	}
+}
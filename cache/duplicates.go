@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"component-service/models"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Thresholds of FindDuplicates.
+const (
+	// DescriptionSimilarity is how alike two descriptions' words must be to count as near-identical.
+	DescriptionSimilarity = 0.9
+	// minDuplicateDescriptionWords keeps short descriptions, such as "Spare", from pairing everything
+	// that shares them.
+	minDuplicateDescriptionWords = 5
+	// maxDuplicatePostings is how many components may share a name trigram or description word for
+	// it to still propose candidates; commoner ones say little and would make the scan quadratic.
+	maxDuplicatePostings = 500
+)
+
+// Reasons a DuplicatePair gives for flagging two components.
+const (
+	DuplicateReasonName        = "name"        // their names' trigrams are at least the scan's minimum similarity
+	DuplicateReasonDescription = "description" // their descriptions are near-identical
+	DuplicateReasonKeyPrefix   = "key:"        // followed by a metadata key both hold the same value at
+)
+
+// DuplicateOptions tunes FindDuplicates.
+type DuplicateOptions struct {
+	MinNameSimilarity float64  // between 0 and 1
+	KeyFields         []string // metadata keys holding identifiers from other systems
+}
+
+// DuplicatePair is two components that are likely duplicates. ID is the older of the two, which
+// DuplicateID would be merged into.
+type DuplicatePair struct {
+	ID                    int64    `json:"id"`
+	DuplicateID           int64    `json:"duplicate_id"`
+	Score                 float64  `json:"score"` // the strongest signal, 1 for a shared key
+	NameSimilarity        float64  `json:"name_similarity"`
+	DescriptionSimilarity float64  `json:"description_similarity"`
+	Reasons               []string `json:"reasons"`
+}
+
+// duplicateCandidate is what FindDuplicates compares of a component, copied out of the cache so
+// the scan does not hold its lock.
+type duplicateCandidate struct {
+	id           int64
+	nameGrams    map[string]bool
+	descWords    map[string]bool
+	keys         map[string]string // key field to value
+	shortOrEmpty bool              // the description is too short to compare
+}
+
+// FindDuplicates compares the cached components pairwise and returns the pairs that look like
+// duplicates, strongest first: those whose names are at least opts.MinNameSimilarity alike, as the
+// Jaccard index of their trigrams; whose descriptions are near-identical; or that hold the same
+// value at one of opts.KeyFields. Only pairs sharing a name trigram, a description word or a key
+// value are compared. It calls report as it goes and stops with ctx's error once ctx is done.
+func (c *ComponentCache) FindDuplicates(ctx context.Context, opts DuplicateOptions, report func(done, total int)) ([]DuplicatePair, error) {
+	candidates := c.duplicateCandidates(opts.KeyFields)
+	byGram := make(map[string][]int)
+	byWord := make(map[string][]int)
+	byKey := make(map[string][]int)
+	for i, cand := range candidates {
+		for gram := range cand.nameGrams {
+			byGram[gram] = append(byGram[gram], i)
+		}
+		if !cand.shortOrEmpty {
+			for word := range cand.descWords {
+				byWord[word] = append(byWord[word], i)
+			}
+		}
+		for field, value := range cand.keys {
+			byKey[field+"\x00"+value] = append(byKey[field+"\x00"+value], i)
+		}
+	}
+
+	var pairs []DuplicatePair
+	for i, cand := range candidates {
+		if i%100 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			report(i, len(candidates))
+		}
+		// Only later candidates are compared, so each pair is looked at once
+		others := make(map[int]bool)
+		propose := func(index map[string][]int, key string) {
+			if postings := index[key]; len(postings) <= maxDuplicatePostings {
+				for _, j := range postings {
+					if j > i {
+						others[j] = true
+					}
+				}
+			}
+		}
+		for gram := range cand.nameGrams {
+			propose(byGram, gram)
+		}
+		if !cand.shortOrEmpty {
+			for word := range cand.descWords {
+				propose(byWord, word)
+			}
+		}
+		for field, value := range cand.keys {
+			for _, j := range byKey[field+"\x00"+value] { // identical keys are always compared
+				if j > i {
+					others[j] = true
+				}
+			}
+		}
+		for j := range others {
+			if pair, ok := compareDuplicates(cand, candidates[j], opts); ok {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	report(len(candidates), len(candidates))
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		if pairs[i].ID != pairs[j].ID {
+			return pairs[i].ID < pairs[j].ID
+		}
+		return pairs[i].DuplicateID < pairs[j].DuplicateID
+	})
+	return pairs, nil
+}
+
+// duplicateCandidates copies what FindDuplicates compares of each cached component, ordered by ID.
+func (c *ComponentCache) duplicateCandidates(keyFields []string) []duplicateCandidate {
+	c.rlock()
+	defer c.mu.RUnlock()
+	candidates := make([]duplicateCandidate, 0, len(c.componentsByID))
+	for _, comp := range c.componentsByID {
+		words := SearchTerms(comp.Description)
+		candidates = append(candidates, duplicateCandidate{
+			id:           comp.ID,
+			nameGrams:    Trigrams(comp.Name),
+			descWords:    wordSet(comp.Description),
+			keys:         metadataKeys(comp, keyFields),
+			shortOrEmpty: len(words) < minDuplicateDescriptionWords,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].id < candidates[j].id })
+	return candidates
+}
+
+// compareDuplicates scores a pair of candidates, a being the older, and reports whether any signal
+// flags them as duplicates.
+func compareDuplicates(a, b duplicateCandidate, opts DuplicateOptions) (DuplicatePair, bool) {
+	pair := DuplicatePair{
+		ID:             a.id,
+		DuplicateID:    b.id,
+		NameSimilarity: roundSimilarity(jaccard(a.nameGrams, b.nameGrams)),
+		Reasons:        []string{},
+	}
+	if pair.NameSimilarity >= opts.MinNameSimilarity {
+		pair.Reasons = append(pair.Reasons, DuplicateReasonName)
+		pair.Score = pair.NameSimilarity
+	}
+	if !a.shortOrEmpty && !b.shortOrEmpty {
+		pair.DescriptionSimilarity = roundSimilarity(jaccard(a.descWords, b.descWords))
+		if pair.DescriptionSimilarity >= DescriptionSimilarity {
+			pair.Reasons = append(pair.Reasons, DuplicateReasonDescription)
+			pair.Score = max(pair.Score, pair.DescriptionSimilarity)
+		}
+	}
+	for _, field := range opts.KeyFields {
+		if value, ok := a.keys[field]; ok && b.keys[field] == value {
+			pair.Reasons = append(pair.Reasons, DuplicateReasonKeyPrefix+field)
+			pair.Score = 1
+		}
+	}
+	return pair, len(pair.Reasons) > 0
+}
+
+// Trigrams returns the set of trigrams of text's words, each lowercased and padded with two spaces
+// before and one after, the way PostgreSQL's pg_trgm extension computes them.
+func Trigrams(text string) map[string]bool {
+	grams := make(map[string]bool)
+	for _, word := range SearchTerms(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams[string(runes[i:i+3])] = true
+		}
+	}
+	return grams
+}
+
+// jaccard returns the size of the intersection of two sets over that of their union, 0 for two
+// empty sets.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for item := range a {
+		if b[item] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// roundSimilarity rounds a similarity to three decimals, so reports read well and thresholds
+// compare the values shown.
+func roundSimilarity(s float64) float64 {
+	return float64(int(s*1000+0.5)) / 1000
+}
+
+// metadataKeys returns the values comp's metadata holds at fields, by field. Only strings that are
+// not blank and numbers count; other values identify nothing.
+func metadataKeys(comp *models.Component, fields []string) map[string]string {
+	if len(fields) == 0 || len(comp.Metadata) == 0 {
+		return nil
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(comp.Metadata, &metadata); err != nil {
+		return nil
+	}
+	keys := make(map[string]string)
+	for _, field := range fields {
+		switch value := metadata[field].(type) {
+		case string:
+			if value = strings.TrimSpace(value); value != "" {
+				keys[field] = value
+			}
+		case float64:
+			keys[field] = strconv.FormatFloat(value, 'g', -1, 64)
+		}
+	}
+	return keys
+}
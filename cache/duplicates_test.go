@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"component-service/models"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTrigrams(t *testing.T) {
+	got := Trigrams("Cat")
+	want := map[string]bool{"  c": true, " ca": true, "cat": true, "at ": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Trigrams(\"Cat\") = %v; expected %v", got, want)
+	}
+	if s := jaccard(Trigrams("Feed pump"), Trigrams("feed pump")); s != 1 {
+		t.Errorf("Expected names differing in case to be identical, got %v", s)
+	}
+	if s := jaccard(Trigrams(""), Trigrams("")); s != 0 {
+		t.Errorf("Expected empty names to share nothing, got %v", s)
+	}
+}
+
+func TestComponentCache_FindDuplicates(t *testing.T) {
+	const description = "Centrifugal pump feeding the boiler from the condensate tank."
+	comp := func(id int64, name, description, metadata string) *models.Component {
+		comp := aclTestComponent(id, 0)
+		comp.Name, comp.Description = name, description
+		if metadata != "" {
+			comp.Metadata = json.RawMessage(metadata)
+		}
+		return comp
+	}
+	// 1 and 2 differ by a letter; 3 and 4 share a description; 5 and 6 share an asset tag; 7 and 8
+	// share a short description only.
+	c, err := LoadComponentCache(&MockComponentStore{mockComponents: []*models.Component{
+		comp(1, "Boiler feed pump north", "", ""),
+		comp(2, "Boiler feed pumps, North", "", ""),
+		comp(3, "Pump A", description, ""),
+		comp(4, "Spare", description+" ", ""),
+		comp(5, "Valve", "", `{"asset_tag": "AT-001"}`),
+		comp(6, "Gate", "", `{"asset_tag": " AT-001", "serial": 12}`),
+		comp(7, "Relay", "Spare", ""),
+		comp(8, "Contactor", "Spare", `{"asset_tag": ""}`),
+	}}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadComponentCache failed: %v", err)
+	}
+
+	var reported int
+	pairs, err := c.FindDuplicates(context.Background(), DuplicateOptions{MinNameSimilarity: 0.8, KeyFields: []string{"asset_tag"}}, func(done, total int) {
+		reported = total
+	})
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if reported != 8 {
+		t.Errorf("Expected progress over 8 components, got %d", reported)
+	}
+	type flagged struct {
+		id, duplicateID int64
+		reasons         []string
+	}
+	var got []flagged
+	for _, pair := range pairs {
+		got = append(got, flagged{pair.ID, pair.DuplicateID, pair.Reasons})
+	}
+	want := []flagged{
+		{3, 4, []string{DuplicateReasonDescription}},
+		{5, 6, []string{DuplicateReasonKeyPrefix + "asset_tag"}},
+		{1, 2, []string{DuplicateReasonName}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindDuplicates() = %+v; expected %+v", got, want)
+	}
+	if pairs[1].Score != 1 || pairs[1].NameSimilarity != 0 {
+		t.Errorf("Expected a shared key to score 1 whatever the names, got %+v", pairs[1])
+	}
+	if pairs[2].Score != 0.88 {
+		t.Errorf("Expected names differing by a letter to score 0.88, got %+v", pairs[2])
+	}
+
+	// Without key fields, and with a stricter threshold, nothing but the description matches
+	pairs, _ = c.FindDuplicates(context.Background(), DuplicateOptions{MinNameSimilarity: 1}, func(int, int) {})
+	if len(pairs) != 1 || pairs[0].ID != 3 {
+		t.Errorf("Expected only the shared description to be flagged, got %+v", pairs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.FindDuplicates(ctx, DuplicateOptions{MinNameSimilarity: 0.8}, func(int, int) {}); err == nil {
+		t.Error("Expected a canceled scan to fail")
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"component-service/models"
+	"strings"
+)
+
+// Filter selects components for list endpoints. The zero value matches every component.
+type Filter struct {
+	Name         string          // exact, case-sensitive name
+	NameContains string          // case-insensitive name substring
+	Type         string          // exact type
+	Tag          string          // one of the component's tags
+	Metadata     []MetadataMatch // every one must match
+	// Reader, when not nil, keeps only the components the principal it points to may read, so
+	// counts and pages leave out the others. The cache checks it against its ACL index, and the
+	// store's database queries against the persisted entries.
+	Reader *string
+
+	readable func(id int64) bool // Reader's check against a cache's ACL index; see withReader
+}
+
+// MetadataMatch selects the components whose metadata holds Value at the top-level Key, compared
+// as models.Component.MetadataText gives it.
+type MetadataMatch struct {
+	Key, Value string
+}
+
+// IsZero reports whether the filter matches every component.
+func (f Filter) IsZero() bool {
+	return f.Name == "" && f.NameContains == "" && f.Type == "" && f.Tag == "" && len(f.Metadata) == 0 && f.Reader == nil
+}
+
+// withReader binds filter's Reader to the cache's ACL index. Assumes the read lock is held, and
+// the filter returned is only used while it is.
+func (c *ComponentCache) withReader(filter Filter) Filter {
+	if filter.Reader != nil {
+		principal := *filter.Reader
+		filter.readable = func(id int64) bool { return c.effectiveACL[id].allows(principal, PermissionRead) }
+	}
+	return filter
+}
+
+// Matches reports whether a component satisfies every condition of the filter. Reader applies
+// only once the filter is bound to a cache with withReader.
+func (f Filter) Matches(component *models.Component) bool {
+	if f.readable != nil && !f.readable(component.ID) {
+		return false
+	}
+	if f.Name != "" && component.Name != f.Name {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(component.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if f.Type != "" && component.Type != f.Type {
+		return false
+	}
+	if f.Tag != "" && !component.HasTag(f.Tag) {
+		return false
+	}
+	for _, match := range f.Metadata {
+		if text, ok := component.MetadataText(match.Key); !ok || text != match.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// filtered returns the components the filter selects, in their order. The zero filter returns
+// components itself.
+func (f Filter) filtered(components []*models.Component) []*models.Component {
+	if f.IsZero() {
+		return components
+	}
+	var matches []*models.Component
+	for _, comp := range components {
+		if f.Matches(comp) {
+			matches = append(matches, comp)
+		}
+	}
+	return matches
+}
+
+// indexName adds a component to nameIndex. Assumes the write lock is held.
+func (c *ComponentCache) indexName(component *models.Component) {
+	c.nameIndex[component.Name] = append(c.nameIndex[component.Name], component.ID)
+}
+
+// unindexName removes a component from nameIndex. Assumes the write lock is held.
+func (c *ComponentCache) unindexName(component *models.Component) {
+	ids := c.nameIndex[component.Name]
+	for i, id := range ids {
+		if id == component.ID {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(c.nameIndex, component.Name)
+	} else {
+		c.nameIndex[component.Name] = ids
+	}
+}
+
+// matching returns the components selected by filter: every component for the zero filter,
+// candidates from nameIndex for an exact name or from tagIndex for a tag, or a scan otherwise.
+// Assumes the read lock is held.
+func (c *ComponentCache) matching(filter Filter) []*models.Component {
+	if filter.IsZero() {
+		return c.allComponents
+	}
+	var matches []*models.Component
+	if filter.Name != "" || filter.Tag != "" {
+		candidates := c.nameIndex[filter.Name]
+		if filter.Name == "" || (filter.Tag != "" && len(c.tagIndex[filter.Tag]) < len(candidates)) {
+			candidates = c.tagIndex[filter.Tag]
+		}
+		for _, id := range candidates {
+			if comp := c.componentsByID[id]; filter.Matches(comp) {
+				matches = append(matches, comp)
+			}
+		}
+		return matches
+	}
+	for _, comp := range c.allComponents {
+		if filter.Matches(comp) {
+			matches = append(matches, comp)
+		}
+	}
+	return matches
+}
+
+// CountMatching returns the number of components selected by filter.
+func (c *ComponentCache) CountMatching(filter Filter) int {
+	c.rlock()
+	defer c.mu.RUnlock()
+	if filter.IsZero() {
+		return len(c.allComponents)
+	}
+	filter = c.withReader(filter)
+	// A filter on the name or a tag alone is counted from its index
+	if filter.NameContains == "" && filter.Type == "" && len(filter.Metadata) == 0 && filter.Reader == nil {
+		if filter.Tag == "" {
+			return len(c.nameIndex[filter.Name])
+		}
+		if filter.Name == "" {
+			return len(c.tagIndex[filter.Tag])
+		}
+	}
+	return len(c.matching(filter))
+}
+
+// CountChildrenMatching returns the number of parentID's direct children selected by filter.
+func (c *ComponentCache) CountChildrenMatching(parentID int64, filter Filter) int {
+	c.rlock()
+	defer c.mu.RUnlock()
+	return len(c.withReader(filter).filtered(c.childrenByParentID[parentID]))
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"component-service/models"
+	"reflect"
+	"testing"
+)
+
+func TestComponentCache_Filter(t *testing.T) {
+	components := []*models.Component{
+		{ID: 1, Name: "Pump", Type: "device", Tags: []string{"critical", "rotating"}, Metadata: []byte(`{"vendor":"acme","rated_kw":7.5}`), ParentID: invalidNullInt64()},
+		{ID: 2, Name: "Pump", Tags: []string{"rotating"}, Metadata: []byte(`{"vendor":"flowco","spec":{"iso":9906}}`), ParentID: nullInt64(1)},
+		{ID: 3, Name: "Backup pump", Type: "device", Tags: []string{"spare"}, ParentID: invalidNullInt64()},
+		{ID: 4, Name: "Valve", Tags: []string{"critical"}, ParentID: invalidNullInt64()},
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+
+	check := func(label string, filter Filter, expected []int64) {
+		t.Helper()
+		body, err := cache.AllJSON(filter, Sort{}, 0, 0)
+		if err != nil {
+			t.Fatalf("%s: AllJSON failed: %v", label, err)
+		}
+		if ids := pageIDs(t, body); !reflect.DeepEqual(ids, expected) {
+			t.Errorf("%s: expected %v, got %v", label, expected, ids)
+		}
+		if count := cache.CountMatching(filter); count != len(expected) {
+			t.Errorf("%s: expected count %d, got %d", label, len(expected), count)
+		}
+	}
+
+	// Without a sort, components come newest first; with equal timestamps, by descending ID.
+	check("no filter", Filter{}, []int64{4, 3, 2, 1})
+	check("exact name", Filter{Name: "Pump"}, []int64{2, 1})
+	check("exact name is case-sensitive", Filter{Name: "pump"}, []int64{})
+	check("substring is case-insensitive", Filter{NameContains: "PUMP"}, []int64{3, 2, 1})
+	check("both", Filter{Name: "Pump", NameContains: "ump"}, []int64{2, 1})
+	check("type", Filter{Type: "device"}, []int64{3, 1})
+	check("exact name and type", Filter{Name: "Pump", Type: "device"}, []int64{1})
+	check("tag", Filter{Tag: "critical"}, []int64{4, 1})
+	check("tag and exact name", Filter{Name: "Pump", Tag: "critical"}, []int64{1})
+	check("tag and substring", Filter{NameContains: "pump", Tag: "rotating"}, []int64{2, 1})
+	check("unknown tag", Filter{Tag: "absent"}, []int64{})
+	check("metadata", Filter{Metadata: []MetadataMatch{{Key: "vendor", Value: "acme"}}}, []int64{1})
+	check("metadata number as written", Filter{Metadata: []MetadataMatch{{Key: "rated_kw", Value: "7.5"}}}, []int64{1})
+	check("every metadata match", Filter{Metadata: []MetadataMatch{{Key: "vendor", Value: "acme"}, {Key: "rated_kw", Value: "7.50"}}}, []int64{})
+	check("metadata object never matches", Filter{Metadata: []MetadataMatch{{Key: "spec", Value: `{"iso":9906}`}}}, []int64{})
+	check("metadata and exact name", Filter{Name: "Pump", Metadata: []MetadataMatch{{Key: "vendor", Value: "flowco"}}}, []int64{2})
+
+	renamed := *components[1]
+	renamed.Name = "Valve"
+	cache.Set(&renamed)
+	cache.Delete(1) // 2 becomes a root but keeps its new name
+	check("after rename and delete", Filter{Name: "Pump"}, []int64{})
+	check("renamed into", Filter{Name: "Valve"}, []int64{4, 2})
+	if _, ok := cache.nameIndex["Pump"]; ok {
+		t.Error("Expected the empty name index entry to be removed")
+	}
+	check("tag after delete", Filter{Tag: "critical"}, []int64{4})
+
+	retagged := *components[2]
+	retagged.Tags = []string{"critical"}
+	cache.Set(&retagged)
+	check("retagged into", Filter{Tag: "critical"}, []int64{4, 3})
+	check("retagged out of", Filter{Tag: "spare"}, []int64{})
+	if _, ok := cache.tagIndex["spare"]; ok {
+		t.Error("Expected the empty tag index entry to be removed")
+	}
+	if results := cache.Search("pump", "critical", 0); len(results) != 1 || results[0].Component.ID != 3 {
+		t.Errorf("Expected the search for tagged pumps to find 3, got %+v", results)
+	}
+
+	body, next, err := cache.JSONAfter(Filter{NameContains: "valve"}, nil, 1)
+	if err != nil {
+		t.Fatalf("JSONAfter failed: %v", err)
+	}
+	if ids := pageIDs(t, body); len(ids) != 1 || next == nil {
+		t.Errorf("Expected one filtered component and a next cursor, got %v, %+v", ids, next)
+	}
+}
+
+func TestComponentCache_FilterChildren(t *testing.T) {
+	components := []*models.Component{
+		{ID: 1, Name: "Line", ParentID: invalidNullInt64()},
+		{ID: 2, Name: "Pump", Type: "device", ParentID: nullInt64(1)},
+		{ID: 3, Name: "Manuals", Type: "folder", ParentID: nullInt64(1)},
+		{ID: 4, Name: "Valve", Type: "device", ParentID: nullInt64(1)},
+		{ID: 5, Name: "Seal", Type: "device", ParentID: nullInt64(4)},
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+	devices := Filter{Type: "device"}
+
+	body, err := cache.ChildrenJSON(1, devices, Sort{}, 0, 0)
+	if ids := pageIDs(t, body); err != nil || !reflect.DeepEqual(ids, []int64{2, 4}) {
+		t.Errorf("ChildrenJSON(1, devices) = %v, %v; expected [2 4]", ids, err)
+	}
+	body, _ = cache.ChildrenJSON(1, devices, Sort{}, 1, 1)
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{4}) {
+		t.Errorf("ChildrenJSON(1, devices, offset 1, limit 1) = %v; expected [4]", ids)
+	}
+	if count := cache.CountChildrenMatching(1, devices); count != 2 {
+		t.Errorf("CountChildrenMatching(1, devices) = %d; expected 2", count)
+	}
+	if count := cache.CountChildrenMatching(1, Filter{}); count != 3 {
+		t.Errorf("CountChildrenMatching(1) = %d; expected 3", count)
+	}
+
+	body, total, err := cache.DescendantsJSON(1, 0, devices, 0, 0)
+	if ids := pageIDs(t, body); err != nil || !reflect.DeepEqual(ids, []int64{2, 4, 5}) || total != 3 {
+		t.Errorf("DescendantsJSON(1, devices) = %v, %d, %v; expected [2 4 5], 3", ids, total, err)
+	}
+	body, total, _ = cache.DescendantsJSON(1, 0, Filter{Type: "folder"}, 1, 0)
+	if ids := pageIDs(t, body); len(ids) != 0 || total != 1 {
+		t.Errorf("DescendantsJSON(1, folders, offset 1) = %v, %d; expected [], 1", ids, total)
+	}
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"sort"
+)
+
+// FlatRow is a component denormalized for reporting: its place in the tree and the size of its
+// subtree are spelled out, so each row stands on its own without joins.
+type FlatRow struct {
+	ID              int64    `json:"id"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	ParentID        *int64   `json:"parent_id"`
+	ParentName      *string  `json:"parent_name"`
+	RootID          int64    `json:"root_id"`
+	RootName        string   `json:"root_name"`
+	Depth           int      `json:"depth"`          // 0 for a root
+	AncestorIDs     []int64  `json:"ancestor_ids"`   // root first, ending with the parent
+	AncestorNames   []string `json:"ancestor_names"` // in the order of AncestorIDs
+	ChildCount      int      `json:"child_count"`
+	DescendantCount int      `json:"descendant_count"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
+}
+
+// flatProjection caches FlatRows and their JSON encodings. It is built on first read and kept
+// incrementally: writes drop the rows they make stale, and the next read rebuilds only those.
+// Readers fill it under the read lock, holding flatMu; writers drop rows under the write lock.
+type flatProjection struct {
+	rows     map[int64]*FlatRow
+	json     map[int64][]byte
+	ordered  []*FlatRow // every row, by ID; nil when rows were dropped since the last read
+	complete bool
+}
+
+func newFlatProjection() flatProjection {
+	return flatProjection{rows: make(map[int64]*FlatRow), json: make(map[int64][]byte)}
+}
+
+// FlatRows returns the reporting row of every component, ordered by ID. Rows are shared with the
+// cache and must not be modified. A component whose parent chain loops back on itself has no
+// root, and is left out.
+func (c *ComponentCache) FlatRows() []*FlatRow {
+	c.rlock()
+	defer c.mu.RUnlock()
+	c.flatMu.Lock()
+	defer c.flatMu.Unlock()
+	return c.fillFlat()
+}
+
+// FlatJSON returns FlatRows as a JSON array, concatenating each row's cached encoding.
+func (c *ComponentCache) FlatJSON() ([]byte, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	c.flatMu.Lock()
+	defer c.flatMu.Unlock()
+	rows := c.fillFlat()
+	size := 2
+	for _, row := range rows {
+		size += len(c.flat.json[row.ID]) + 1
+	}
+	buf := make([]byte, 0, size)
+	buf = append(buf, '[')
+	for i, row := range rows {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		fragment := c.flat.json[row.ID]
+		if fragment == nil {
+			var err error
+			if fragment, err = json.Marshal(row); err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, fragment...)
+	}
+	return append(buf, ']'), nil
+}
+
+// fillFlat rebuilds the rows dropped since the last read and returns every row by ID. Rows are
+// built top down, so a parent's row is current before its children's, then their counts are
+// summed bottom up. Assumes the read lock and flatMu are held.
+func (c *ComponentCache) fillFlat() []*FlatRow {
+	p := &c.flat
+	if p.complete {
+		return p.ordered
+	}
+	order := c.topDown()
+	var missing []*models.Component
+	for _, comp := range order {
+		if p.rows[comp.ID] != nil {
+			continue
+		}
+		row := &FlatRow{
+			ID:          comp.ID,
+			Name:        comp.Name,
+			Description: comp.Description,
+			RootID:      comp.ID,
+			RootName:    comp.Name,
+			ChildCount:  len(c.childrenByParentID[comp.ID]),
+			CreatedAt:   comp.CreatedAt,
+			UpdatedAt:   comp.UpdatedAt,
+		}
+		if parent := p.rows[comp.ParentID.Int64]; comp.ParentID.Valid && parent != nil {
+			parentID, parentName := parent.ID, parent.Name
+			row.ParentID, row.ParentName = &parentID, &parentName
+			row.RootID, row.RootName = parent.RootID, parent.RootName
+			row.Depth = parent.Depth + 1
+			row.AncestorIDs = append(append(make([]int64, 0, row.Depth), parent.AncestorIDs...), parent.ID)
+			row.AncestorNames = append(append(make([]string, 0, row.Depth), parent.AncestorNames...), parent.Name)
+		} else if comp.ParentID.Valid {
+			parentID := comp.ParentID.Int64 // a parent the cache does not hold
+			row.ParentID = &parentID
+		}
+		if row.AncestorIDs == nil {
+			row.AncestorIDs, row.AncestorNames = []int64{}, []string{}
+		}
+		p.rows[comp.ID] = row
+		missing = append(missing, comp)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		row := p.rows[missing[i].ID]
+		for _, child := range c.childrenByParentID[row.ID] {
+			if childRow := p.rows[child.ID]; childRow != nil {
+				row.DescendantCount += 1 + childRow.DescendantCount
+			}
+		}
+		p.json[row.ID] = marshalFlatRow(row)
+	}
+
+	p.ordered = make([]*FlatRow, len(order))
+	for i, comp := range order {
+		p.ordered[i] = p.rows[comp.ID]
+	}
+	sort.Slice(p.ordered, func(i, j int) bool { return p.ordered[i].ID < p.ordered[j].ID })
+	p.complete = true
+	return p.ordered
+}
+
+// topDown returns every component reachable from a root, or from a component whose parent the
+// cache does not hold, with each parent before its children. Assumes the read lock is held.
+func (c *ComponentCache) topDown() []*models.Component {
+	order := make([]*models.Component, 0, len(c.componentsByID))
+	for _, comp := range c.allComponents {
+		if _, hasParent := c.componentsByID[comp.ParentID.Int64]; !comp.ParentID.Valid || !hasParent {
+			order = append(order, comp)
+		}
+	}
+	for next := 0; next < len(order); next++ {
+		order = append(order, c.childrenByParentID[order[next].ID]...)
+	}
+	return order
+}
+
+func marshalFlatRow(row *FlatRow) []byte {
+	fragment, err := json.Marshal(row)
+	if err != nil {
+		return nil
+	}
+	return fragment
+}
+
+// dropFlat drops the row of id. Assumes the write lock is held.
+func (c *ComponentCache) dropFlat(id int64) {
+	p := &c.flat
+	delete(p.rows, id)
+	delete(p.json, id)
+	p.ordered, p.complete = nil, false
+}
+
+// dropFlatSubtree drops the rows below id, whose ancestry changes with id's name or place.
+// Assumes the write lock is held.
+func (c *ComponentCache) dropFlatSubtree(id int64) {
+	if len(c.flat.rows) == 0 {
+		return
+	}
+	for _, comp := range c.descendants(id, 0) {
+		c.dropFlat(comp.ID)
+	}
+}
+
+// dropFlatAncestors drops the rows of parentID and its ancestors, whose counts change when a
+// component joins or leaves their subtree. Assumes the write lock is held.
+func (c *ComponentCache) dropFlatAncestors(parentID sql.NullInt64) {
+	if len(c.flat.rows) == 0 {
+		return
+	}
+	for steps := 0; parentID.Valid && steps <= len(c.componentsByID); steps++ { // bounded in case of a parent cycle
+		c.dropFlat(parentID.Int64)
+		comp, exists := c.componentsByID[parentID.Int64]
+		if !exists {
+			return
+		}
+		parentID = comp.ParentID
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestComponentCache_Flat(t *testing.T) {
+	// 1 -> 2 -> 3, and 4 on its own.
+	components := []*models.Component{
+		aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2), aclTestComponent(4, 0),
+	}
+	for _, comp := range components {
+		comp.Name = string(rune('a' + comp.ID - 1))
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	c := GlobalComponentCache
+
+	type summary struct {
+		root, depth, children, descendants int64
+		ancestors                          []string
+	}
+	summarize := func() map[int64]summary {
+		rows := c.FlatRows()
+		got := make(map[int64]summary, len(rows))
+		for i, row := range rows {
+			if i > 0 && rows[i-1].ID >= row.ID {
+				t.Errorf("FlatRows not ordered by ID: %d before %d", rows[i-1].ID, row.ID)
+			}
+			got[row.ID] = summary{row.RootID, int64(row.Depth), int64(row.ChildCount), int64(row.DescendantCount), row.AncestorNames}
+		}
+		return got
+	}
+
+	want := map[int64]summary{
+		1: {1, 0, 1, 2, []string{}},
+		2: {1, 1, 1, 1, []string{"a"}},
+		3: {1, 2, 0, 0, []string{"a", "b"}},
+		4: {4, 0, 0, 0, []string{}},
+	}
+	if got := summarize(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatRows = %+v; want %+v", got, want)
+	}
+
+	// Renaming 1 and moving 2 under 4 leave every row but 3's stale.
+	c.Set(&models.Component{ID: 1, Name: "z", ParentID: invalidNullInt64()})
+	c.Set(&models.Component{ID: 2, Name: "b", ParentID: nullInt64(4)})
+	want = map[int64]summary{
+		1: {1, 0, 0, 0, []string{}},
+		2: {4, 1, 1, 1, []string{"d"}},
+		3: {4, 2, 0, 0, []string{"d", "b"}},
+		4: {4, 0, 1, 2, []string{}},
+	}
+	if got := summarize(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatRows after rename and move = %+v; want %+v", got, want)
+	}
+
+	// Deleting 2 makes 3 a root.
+	c.Delete(2)
+	want = map[int64]summary{
+		1: {1, 0, 0, 0, []string{}},
+		3: {3, 0, 0, 0, []string{}},
+		4: {4, 0, 0, 0, []string{}},
+	}
+	if got := summarize(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatRows after delete = %+v; want %+v", got, want)
+	}
+
+	body, err := c.FlatJSON()
+	if err != nil {
+		t.Fatalf("FlatJSON failed: %v", err)
+	}
+	var rows []FlatRow
+	if err := json.Unmarshal(body, &rows); err != nil {
+		t.Fatalf("FlatJSON returned invalid JSON: %v", err)
+	}
+	if len(rows) != 3 || rows[1].ID != 3 || rows[1].ParentID != nil || rows[1].RootName != "c" {
+		t.Errorf("FlatJSON = %s; want rows 1, 3 and 4 with 3 a root", body)
+	}
+}
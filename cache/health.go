@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The ways the cache degrades. Each is counted by reason in an expvar map published at
+// /debug/vars, and logged as a "cache_event" line with its kind and reason.
+const (
+	// KindBypassed counts reads served from the database instead of the cache.
+	KindBypassed = "bypassed"
+	// KindStale counts failures that leave the cache behind the database until they recover.
+	KindStale = "stale"
+	// KindEvicting counts entries the cache dropped, such as delta-sync history.
+	KindEvicting = "evicting"
+)
+
+// Reasons for degradation.
+const (
+	ReasonNotInitialized     = "not_initialized"      // bypassed: there is no cache to read
+	ReasonStrongConsistency  = "strong_consistency"   // bypassed: the client sent X-Consistency: strong
+	ReasonReloadFailed       = "reload_failed"        // stale: invalidated components could not be reloaded
+	ReasonCDCPollFailed      = "cdc_poll_failed"      // stale: writes made around the service are not applied
+	ReasonFollowerSyncFailed = "follower_sync_failed" // stale: the primary's changes are not applied
+	ReasonJournalTrimmed     = "sync_journal_trimmed" // evicting: the oldest delta-sync changes were dropped
+	ReasonRebuilt            = "rebuilt"              // evicting: the cache was rebuilt, expiring every checkpoint
+)
+
+// eventLogInterval bounds how often bypasses and evictions of one reason are logged; the lines
+// in between are summed into the next.
+const eventLogInterval = time.Minute
+
+// recentWindow is how long a bypass or eviction keeps the cache reported as degraded.
+const recentWindow = 5 * time.Minute
+
+var (
+	bypassedReads = expvar.NewMap("cache_bypassed_reads")
+	staleEvents   = expvar.NewMap("cache_stale_events")
+	evictions     = expvar.NewMap("cache_evicted_entries")
+)
+
+// metricOf returns the expvar map counting kind.
+func metricOf(kind string) *expvar.Map {
+	switch kind {
+	case KindBypassed:
+		return bypassedReads
+	case KindStale:
+		return staleEvents
+	default:
+		return evictions
+	}
+}
+
+// condition is the state of one kind and reason of degradation.
+type condition struct {
+	since    time.Time // when a stale condition began; zero once it recovered
+	detail   string    // the latest error of a stale condition
+	last     time.Time // the latest bypass or eviction
+	logged   time.Time // when the condition was last logged
+	unlogged int64     // bypasses or evictions since then
+}
+
+// health tracks the degradation of the global cache.
+var health = struct {
+	mu         sync.Mutex
+	conditions map[string]*condition // by kind and reason, as "stale.reload_failed"
+	now        func() time.Time
+}{conditions: map[string]*condition{}, now: time.Now}
+
+// conditionOf returns the condition of kind and reason, creating it. Assumes health.mu.
+func conditionOf(kind, reason string) *condition {
+	key := kind + "." + reason
+	cond := health.conditions[key]
+	if cond == nil {
+		cond = &condition{}
+		health.conditions[key] = cond
+	}
+	return cond
+}
+
+// RecordBypass counts a read that went to the database instead of the cache.
+func RecordBypass(reason string) {
+	recordEvent(KindBypassed, reason, 1)
+}
+
+// recordEviction counts n entries the cache dropped.
+func recordEviction(reason string, n int) {
+	recordEvent(KindEvicting, reason, int64(n))
+}
+
+// recordEvent counts n bypasses or evictions, logging them at most once per eventLogInterval.
+func recordEvent(kind, reason string, n int64) {
+	metricOf(kind).Add(reason, n)
+	health.mu.Lock()
+	now := health.now()
+	cond := conditionOf(kind, reason)
+	cond.last = now
+	cond.unlogged += n
+	var count int64
+	if now.Sub(cond.logged) >= eventLogInterval {
+		count, cond.unlogged, cond.logged = cond.unlogged, 0, now
+	}
+	health.mu.Unlock()
+	if count > 0 {
+		field := "reads"
+		if kind == KindEvicting {
+			field = "entries"
+		}
+		logEvent(kind, reason, field, count)
+	}
+}
+
+// MarkStale records a failure that leaves the cache behind the database for reason, until
+// ClearStale. The first failure is logged; the ones after it are counted.
+func MarkStale(reason string, err error) {
+	staleEvents.Add(reason, 1)
+	health.mu.Lock()
+	cond := conditionOf(KindStale, reason)
+	started := cond.since.IsZero()
+	if started {
+		cond.since = health.now()
+	}
+	cond.detail = err.Error()
+	health.mu.Unlock()
+	if started {
+		logEvent(KindStale, reason, "error", err.Error())
+	}
+}
+
+// ClearStale records that reason no longer leaves the cache behind, logging the recovery of a
+// stale condition.
+func ClearStale(reason string) {
+	health.mu.Lock()
+	cond := health.conditions[KindStale+"."+reason]
+	var lasted time.Duration
+	if cond != nil && !cond.since.IsZero() {
+		lasted = health.now().Sub(cond.since)
+		cond.since, cond.detail = time.Time{}, ""
+	}
+	health.mu.Unlock()
+	if lasted > 0 {
+		logEvent("recovered", reason, "stale_for", lasted.Round(time.Millisecond).String())
+	}
+}
+
+// logEvent writes a structured cache event: logfmt key=value pairs after "cache_event", values
+// quoted when they hold spaces or quotes.
+func logEvent(kind, reason string, fields ...interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cache_event kind=%s reason=%s", kind, reason)
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fmt.Sprint(fields[i+1])
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", fields[i], value)
+	}
+	log.Print(b.String())
+}
+
+// Degradation is an active condition of HealthState.
+type Degradation struct {
+	Kind   string    `json:"kind"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`            // when a stale condition began, or the latest bypass or eviction
+	Detail string    `json:"detail,omitempty"` // the latest error of a stale condition
+}
+
+// HealthState describes the global cache for readiness probes.
+type HealthState struct {
+	Status          string           `json:"status"` // ok, degraded (serving, but slower or behind) or unavailable
+	Components      int              `json:"components"`
+	WritePolicy     WritePolicy      `json:"write_policy,omitempty"`
+	StaleComponents int              `json:"stale_components"` // invalidated under write-around, awaiting reload
+	Degraded        []Degradation    `json:"degraded"`         // active stale conditions, and bypasses and evictions of the last 5 minutes
+	BypassedReads   map[string]int64 `json:"bypassed_reads"`   // since start, by reason
+	StaleEvents     map[string]int64 `json:"stale_events"`
+	EvictedEntries  map[string]int64 `json:"evicted_entries"`
+}
+
+// Health reports the state of the global cache.
+func Health() HealthState {
+	state := HealthState{
+		Status:         "ok",
+		Degraded:       []Degradation{},
+		BypassedReads:  counts(bypassedReads),
+		StaleEvents:    counts(staleEvents),
+		EvictedEntries: counts(evictions),
+	}
+	if c := GlobalComponentCache; c != nil {
+		// Read without rlock, so a probe never waits on a reload from the database
+		c.mu.RLock()
+		state.Components = len(c.allComponents)
+		c.mu.RUnlock()
+		state.WritePolicy = c.WritePolicy()
+		state.StaleComponents = int(c.staleCount.Load())
+	} else {
+		state.Status = "unavailable"
+	}
+
+	health.mu.Lock()
+	now := health.now()
+	for key, cond := range health.conditions {
+		kind, reason, _ := strings.Cut(key, ".")
+		switch {
+		case !cond.since.IsZero():
+			state.Degraded = append(state.Degraded, Degradation{Kind: kind, Reason: reason, Since: cond.since, Detail: cond.detail})
+		case !cond.last.IsZero() && now.Sub(cond.last) < recentWindow:
+			state.Degraded = append(state.Degraded, Degradation{Kind: kind, Reason: reason, Since: cond.last})
+		}
+	}
+	health.mu.Unlock()
+	sort.Slice(state.Degraded, func(i, j int) bool {
+		a, b := state.Degraded[i], state.Degraded[j]
+		return a.Kind < b.Kind || a.Kind == b.Kind && a.Reason < b.Reason
+	})
+	if state.Status == "ok" && len(state.Degraded) > 0 {
+		state.Status = "degraded"
+	}
+	return state
+}
+
+// counts copies an expvar map of counters.
+func counts(m *expvar.Map) map[string]int64 {
+	out := map[string]int64{}
+	m.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok {
+			out[kv.Key] = v.Value()
+		}
+	})
+	return out
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"bytes"
+	"component-service/models"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetHealth clears the degradation conditions and captures the log, returning it; both are
+// restored when the test ends. The expvar counters only grow, so tests compare them before and
+// after.
+func resetHealth(t *testing.T) (*bytes.Buffer, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	health.mu.Lock()
+	conditions, clock := health.conditions, health.now
+	health.conditions, health.now = map[string]*condition{}, func() time.Time { return now }
+	health.mu.Unlock()
+	var buf bytes.Buffer
+	writer, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		health.mu.Lock()
+		health.conditions, health.now = conditions, clock
+		health.mu.Unlock()
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+	return &buf, &now
+}
+
+func TestHealthBypassAndEviction(t *testing.T) {
+	defer func(c *ComponentCache) { GlobalComponentCache = c }(GlobalComponentCache)
+	logs, now := resetHealth(t)
+	GlobalComponentCache = nil
+	if state := Health(); state.Status != "unavailable" {
+		t.Errorf("Expected no cache to be unavailable, got %s", state.Status)
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{aclTestComponent(1, 0)}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	if state := Health(); state.Status != "ok" || state.Components != 1 || len(state.Degraded) != 0 {
+		t.Errorf("Expected a fresh cache to be ok, got %+v", state)
+	}
+
+	before := Health().BypassedReads[ReasonStrongConsistency]
+	for i := 0; i < 3; i++ {
+		RecordBypass(ReasonStrongConsistency)
+	}
+	state := Health()
+	if got := state.BypassedReads[ReasonStrongConsistency] - before; got != 3 {
+		t.Errorf("Expected 3 bypassed reads counted, got %d", got)
+	}
+	if state.Status != "degraded" || len(state.Degraded) != 1 || state.Degraded[0].Kind != KindBypassed || state.Degraded[0].Reason != ReasonStrongConsistency {
+		t.Errorf("Expected a recent bypass to degrade the cache, got %+v", state)
+	}
+	if got := logs.String(); got != "cache_event kind=bypassed reason=strong_consistency reads=1\n" {
+		t.Errorf("Expected the first bypass logged and the others held back, got %q", got)
+	}
+
+	// The held-back bypasses are summed into the next line, a minute on
+	*now = now.Add(eventLogInterval)
+	RecordBypass(ReasonStrongConsistency)
+	if !strings.HasSuffix(logs.String(), "cache_event kind=bypassed reason=strong_consistency reads=3\n") {
+		t.Errorf("Expected the held-back bypasses summed, got %q", logs.String())
+	}
+	*now = now.Add(recentWindow)
+	if state := Health(); state.Status != "ok" {
+		t.Errorf("Expected old bypasses to stop degrading the cache, got %+v", state.Degraded)
+	}
+
+	// Rebuilding evicts what the cache held, and trimming the journal its oldest changes
+	evicted := Health().EvictedEntries
+	defer func(previous int) { syncJournalLimit = previous }(syncJournalLimit)
+	syncJournalLimit = 4
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{aclTestComponent(1, 0)}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		GlobalComponentCache.Set(aclTestComponent(1, 0))
+	}
+	state = Health()
+	if got := state.EvictedEntries[ReasonRebuilt] - evicted[ReasonRebuilt]; got != 1 {
+		t.Errorf("Expected the rebuild to evict 1 component, got %d", got)
+	}
+	if got := state.EvictedEntries[ReasonJournalTrimmed] - evicted[ReasonJournalTrimmed]; got != 3 {
+		t.Errorf("Expected the journal to drop 3 changes, got %d", got)
+	}
+}
+
+func TestHealthStale(t *testing.T) {
+	defer func(c *ComponentCache, cfg Config) { GlobalComponentCache, GlobalConfig = c, cfg }(GlobalComponentCache, GlobalConfig)
+	logs, now := resetHealth(t)
+	GlobalComponentCache, GlobalConfig.WritePolicy = nil, WriteAround
+	store := &loaderMockStore{MockComponentStore: MockComponentStore{mockComponents: []*models.Component{aclTestComponent(1, 0)}}}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+
+	store.err = errors.New("connection refused")
+	GlobalComponentCache.Invalidate(1)
+	GlobalComponentCache.GetByID(1)
+	*now = now.Add(time.Second)
+	GlobalComponentCache.GetByID(1)
+	state := Health()
+	if state.Status != "degraded" || state.StaleComponents != 1 || len(state.Degraded) != 1 {
+		t.Fatalf("Expected a failed reload to leave the cache stale, got %+v", state)
+	}
+	if d := state.Degraded[0]; d.Kind != KindStale || d.Reason != ReasonReloadFailed || !strings.Contains(d.Detail, "connection refused") {
+		t.Errorf("Expected the reload failure reported, got %+v", d)
+	}
+	if got := strings.Count(logs.String(), "kind=stale"); got != 1 {
+		t.Errorf("Expected one stale event logged for two failures, got %d: %q", got, logs.String())
+	}
+
+	store.err = nil
+	store.components = map[int64]*models.Component{1: aclTestComponent(1, 0)}
+	*now = now.Add(2 * time.Second)
+	GlobalComponentCache.GetByID(1)
+	if state := Health(); state.Status != "ok" || state.StaleComponents != 0 {
+		t.Errorf("Expected the reload to clear the stale condition, got %+v", state)
+	}
+	if !strings.HasSuffix(logs.String(), "cache_event kind=recovered reason=reload_failed stale_for=3s\n") {
+		t.Errorf("Expected the recovery logged, got %q", logs.String())
+	}
+}
+
+func TestLogEvent(t *testing.T) {
+	logs, _ := resetHealth(t)
+	logEvent(KindStale, ReasonCDCPollFailed, "error", `dial "db": refused`, "count", 2)
+	if got := logs.String(); got != `cache_event kind=stale reason=cdc_poll_failed error="dial \"db\": refused" count=2`+"\n" {
+		t.Errorf("Unexpected event line %q", got)
+	}
+}
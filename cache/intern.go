@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"unique"
+)
+
+// internStrings controls whether component strings are canonicalized as they enter the cache.
+// It is only switched off by benchmarks to measure the saving.
+var internStrings = true
+
+// internComponentStrings replaces the component's repetitive string fields with canonical copies,
+// so components sharing a name, type, tag, description or timestamp share one backing array. unique handles
+// are weak: a value no longer referenced by any component is reclaimed by the GC, so the intern
+// table never needs pruning on Delete. Must be called on the cache's own copy of the component,
+// whose tags and metadata it also copies, so the caller's slices are never shared.
+func internComponentStrings(component *models.Component) {
+	if len(component.Tags) > 0 {
+		component.Tags = append([]string(nil), component.Tags...)
+	}
+	if len(component.Metadata) > 0 {
+		component.Metadata = append(json.RawMessage(nil), component.Metadata...)
+	}
+	if !internStrings {
+		return
+	}
+	component.Name = intern(component.Name)
+	component.Type = intern(component.Type)
+	component.Status = intern(component.Status)
+	for i, tag := range component.Tags {
+		component.Tags[i] = intern(tag)
+	}
+	component.Description = intern(component.Description)
+	component.CreatedAt = intern(component.CreatedAt)
+	component.UpdatedAt = intern(component.UpdatedAt)
+}
+
+func intern(s string) string {
+	if s == "" {
+		return s
+	}
+	return unique.Make(s).Value()
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"component-service/models"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// repetitiveComponents builds n components whose names and descriptions repeat across the set,
+// each backed by its own freshly allocated string as if decoded from separate database rows.
+func repetitiveComponents(n int) []*models.Component {
+	components := make([]*models.Component, n)
+	for i := range components {
+		components[i] = &models.Component{
+			ID:          int64(i + 1),
+			Name:        strings.Clone(fmt.Sprintf("service-%d", i%50)),
+			Description: strings.Clone(fmt.Sprintf("Shared description for component group %d", i%10)),
+			CreatedAt:   strings.Clone("2024-01-01T00:00:00Z"),
+			UpdatedAt:   strings.Clone("2024-01-01T00:00:00Z"),
+		}
+	}
+	return components
+}
+
+func TestInitGlobalCache_InternsStrings(t *testing.T) {
+	components := repetitiveComponents(100)
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+
+	a := GlobalComponentCache.componentsByID[1]
+	b := GlobalComponentCache.componentsByID[51] // same name as component 1
+	if a.Name != b.Name {
+		t.Fatalf("Expected matching names, got %q and %q", a.Name, b.Name)
+	}
+	if unsafe.StringData(a.Name) != unsafe.StringData(b.Name) {
+		t.Errorf("Expected equal names to share one backing array after interning")
+	}
+	if unsafe.StringData(a.CreatedAt) != unsafe.StringData(b.UpdatedAt) {
+		t.Errorf("Expected equal timestamps to share one backing array after interning")
+	}
+
+	updated := *a
+	updated.Name = strings.Clone(b.Name)
+	GlobalComponentCache.Set(&updated)
+	if unsafe.StringData(GlobalComponentCache.componentsByID[1].Name) != unsafe.StringData(b.Name) {
+		t.Errorf("Expected Set to intern the stored name")
+	}
+}
+
+// BenchmarkInitGlobalCache_Interning reports the heap retained by a populated cache with and
+// without string interning, as retained-bytes/op.
+func BenchmarkInitGlobalCache_Interning(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		name := "plain"
+		if enabled {
+			name = "interned"
+		}
+		b.Run(name, func(b *testing.B) {
+			defer func(previous bool) { internStrings = previous }(internStrings)
+			internStrings = enabled
+
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				GlobalComponentCache = nil
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				store := &MockComponentStore{mockComponents: repetitiveComponents(10000)}
+				b.StartTimer()
+
+				if err := InitGlobalCache(store); err != nil {
+					b.Fatalf("InitGlobalCache failed: %v", err)
+				}
+
+				b.StopTimer()
+				store.mockComponents = nil // drop the source rows so only the cache's copies stay reachable
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if after.HeapAlloc > before.HeapAlloc {
+					retained += after.HeapAlloc - before.HeapAlloc
+				}
+				runtime.KeepAlive(GlobalComponentCache)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-bytes/op")
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+)
+
+// marshalFragment returns the component's JSON encoding, or nil if it cannot be marshaled,
+// in which case readers fall back to marshaling on demand.
+func marshalFragment(component *models.Component) []byte {
+	fragment, err := json.Marshal(component)
+	if err != nil {
+		return nil
+	}
+	return fragment
+}
+
+// appendJSONArray appends a JSON array of the given components to buf, reusing each component's
+// pre-marshaled fragment. Assumes the read lock is held.
+func (c *ComponentCache) appendJSONArray(buf []byte, components []*models.Component) ([]byte, error) {
+	buf = append(buf, '[')
+	for i, comp := range components {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		fragment := c.jsonByID[comp.ID]
+		if fragment == nil {
+			var err error
+			if fragment, err = json.Marshal(comp); err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, fragment...)
+	}
+	return append(buf, ']'), nil
+}
+
+// AllJSON returns the components selected by filter as a JSON array, equivalent to marshaling
+// GetAll() but built by concatenating cached fragments instead of re-marshaling each struct.
+// Components are in order, or newest first for the zero Sort, as the database lists them. limit >
+// 0 selects the page of at most limit components starting at offset; otherwise every component
+// from offset onwards is returned. Creation orders walk the created_at index instead of sorting.
+func (c *ComponentCache) AllJSON(filter Filter, order Sort, offset, limit int) ([]byte, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	filter = c.withReader(filter)
+	var components []*models.Component
+	switch {
+	case order.IsZero():
+		components = c.inCreatedOrder(filter, true, offset, limit)
+	case order.Field == SortByCreatedAt:
+		components = c.inCreatedOrder(filter, order.Descending, offset, limit)
+	default:
+		components = pageOf(order.sorted(c.matching(filter)), offset, limit)
+	}
+	return c.appendJSONArray(make([]byte, 0, c.jsonSizeHint(components)), components)
+}
+
+// ChildrenJSON returns the direct children of parentID selected by filter as a JSON array,
+// equivalent to marshaling the result of GetChildren, in order (by position for the zero Sort, as
+// the database lists them). limit > 0 selects the page of at most limit children starting at
+// offset, so large fan-outs are never copied in full. A parent without children, or an offset
+// past the end, yields an empty array.
+func (c *ComponentCache) ChildrenJSON(parentID int64, filter Filter, order Sort, offset, limit int) ([]byte, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	if order.IsZero() {
+		order = siblingOrder
+	}
+	children := pageOf(order.sorted(c.withReader(filter).filtered(c.childrenByParentID[parentID])), offset, limit)
+	return c.appendJSONArray(make([]byte, 0, c.jsonSizeHint(children)), children)
+}
+
+// DescendantsJSON returns the page of rootID's descendants selected by filter as a JSON array,
+// with the number of those in total. The subtree is walked breadth first, so components come level by level,
+// and by ID within a level. maxDepth > 0 keeps only the first maxDepth levels, 1 being the
+// children. limit > 0 selects the page of at most limit components starting at offset; otherwise
+// every descendant from offset onwards is returned.
+func (c *ComponentCache) DescendantsJSON(rootID int64, maxDepth int, filter Filter, offset, limit int) ([]byte, int, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	descendants := c.withReader(filter).filtered(c.descendants(rootID, maxDepth))
+	page := pageOf(descendants, offset, limit)
+	body, err := c.appendJSONArray(make([]byte, 0, c.jsonSizeHint(page)), page)
+	return body, len(descendants), err
+}
+
+// pageOf returns the [offset, offset+limit) window of components; limit <= 0 means no upper bound.
+func pageOf(components []*models.Component, offset, limit int) []*models.Component {
+	if offset >= len(components) {
+		return nil
+	}
+	if offset > 0 {
+		components = components[offset:]
+	}
+	if limit > 0 && limit < len(components) {
+		components = components[:limit]
+	}
+	return components
+}
+
+// jsonSizeHint sums fragment lengths so the output buffer is allocated once. Assumes the read lock is held.
+func (c *ComponentCache) jsonSizeHint(components []*models.Component) int {
+	size := 2
+	for _, comp := range components {
+		size += len(c.jsonByID[comp.ID]) + 1
+	}
+	return size
+}
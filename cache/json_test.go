@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"testing"
+)
+
+// assertJSONMatchesMarshal checks the fragment-built output against encoding/json on the getters' results.
+func assertJSONMatchesMarshal(t *testing.T, label string, got []byte, components []*models.Component) {
+	t.Helper()
+	expected, err := json.Marshal(components)
+	if err != nil {
+		t.Fatalf("%s: json.Marshal failed: %v", label, err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("%s: got %s, expected %s", label, got, expected)
+	}
+}
+
+func TestComponentCache_JSONFragments(t *testing.T) {
+	c1 := *comp1Global
+	c2 := *comp2Global
+	c3 := *comp3Global
+	c6 := *comp6Global
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c1, &c2, &c3, &c6}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+
+	check := func(label string) {
+		t.Helper()
+		all, err := cache.AllJSON(Filter{}, Sort{}, 0, 0)
+		if err != nil {
+			t.Fatalf("%s: AllJSON failed: %v", label, err)
+		}
+		assertJSONMatchesMarshal(t, label+" all", all, cache.GetAll())
+		for _, parentID := range []int64{RootParentIDKey, 1, 2} {
+			children, err := cache.ChildrenJSON(parentID, Filter{}, Sort{}, 0, 0)
+			if err != nil {
+				t.Fatalf("%s: ChildrenJSON(%d) failed: %v", label, parentID, err)
+			}
+			expected, _ := cache.GetChildren(parentID)
+			assertJSONMatchesMarshal(t, label+" children", children, expected)
+		}
+	}
+
+	check("after init")
+
+	renamed := c3
+	renamed.Name = "Comp 3 renamed"
+	renamed.ParentID = nullInt64(2)
+	cache.Set(&renamed)
+	check("after set")
+
+	cache.Delete(2) // children 3 and 6 become roots
+	check("after delete")
+
+	empty, err := cache.ChildrenJSON(999, Filter{}, Sort{}, 0, 0)
+	if err != nil || string(empty) != "[]" {
+		t.Errorf("Expected [] for a parent without children, got %s (err %v)", empty, err)
+	}
+}
+
+func TestComponentCache_ChildrenJSONPages(t *testing.T) {
+	components := []*models.Component{{ID: 1, Name: "Parent"}}
+	for id := int64(2); id <= 6; id++ {
+		components = append(components, &models.Component{ID: id, Name: "Child", ParentID: nullInt64(1)})
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	if count := GlobalComponentCache.ChildCount(1); count != 5 {
+		t.Errorf("Expected 5 children, got %d", count)
+	}
+	all, _ := GlobalComponentCache.GetChildren(1)
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		expected      []*models.Component
+	}{
+		{name: "first page", offset: 0, limit: 2, expected: all[0:2]},
+		{name: "middle page", offset: 2, limit: 2, expected: all[2:4]},
+		{name: "short last page", offset: 4, limit: 2, expected: all[4:5]},
+		{name: "offset past end", offset: 5, limit: 2, expected: []*models.Component{}},
+		{name: "offset without limit", offset: 3, limit: 0, expected: all[3:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GlobalComponentCache.ChildrenJSON(1, Filter{}, Sort{}, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("ChildrenJSON failed: %v", err)
+			}
+			assertJSONMatchesMarshal(t, tt.name, got, tt.expected)
+		})
+	}
+}
+
+func TestComponentCache_AllJSONPages(t *testing.T) {
+	c1 := *comp1Global
+	c2 := *comp2Global
+	c3 := *comp3Global
+	c4 := *comp4Global
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c1, &c2, &c3, &c4}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	if count := GlobalComponentCache.Count(); count != 4 {
+		t.Errorf("Expected 4 components, got %d", count)
+	}
+	all := GlobalComponentCache.GetAll()
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		expected      []*models.Component
+	}{
+		{name: "first page", offset: 0, limit: 3, expected: all[0:3]},
+		{name: "short last page", offset: 3, limit: 3, expected: all[3:4]},
+		{name: "offset past end", offset: 4, limit: 3, expected: []*models.Component{}},
+		{name: "offset without limit", offset: 1, limit: 0, expected: all[1:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GlobalComponentCache.AllJSON(Filter{}, Sort{}, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("AllJSON failed: %v", err)
+			}
+			assertJSONMatchesMarshal(t, tt.name, got, tt.expected)
+		})
+	}
+}
+
+func BenchmarkListJSON(b *testing.B) {
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: repetitiveComponents(5000)}); err != nil {
+		b.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(GlobalComponentCache.GetAll()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("fragments", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := GlobalComponentCache.AllJSON(Filter{}, Sort{}, 0, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
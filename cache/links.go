@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+)
+
+// indexLinks adds a component to linkIndex under each component it refers to. Assumes the write
+// lock is held.
+func (c *ComponentCache) indexLinks(component *models.Component) {
+	for _, ref := range component.Links() {
+		c.linkIndex[ref] = append(c.linkIndex[ref], component.ID)
+	}
+}
+
+// unindexLinks removes a component from linkIndex. Assumes the write lock is held.
+func (c *ComponentCache) unindexLinks(component *models.Component) {
+	for _, ref := range component.Links() {
+		ids := c.linkIndex[ref]
+		for i, id := range ids {
+			if id == component.ID {
+				ids = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(c.linkIndex, ref)
+		} else {
+			c.linkIndex[ref] = ids
+		}
+	}
+}
+
+// Backlinks returns the components whose description or metadata refers to a component, by its
+// ID or by its current slug, ordered by ID. found is false when the component is not cached.
+func (c *ComponentCache) Backlinks(id int64) (components []*models.Component, found bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	target, found := c.componentsByID[id]
+	if !found {
+		return nil, false
+	}
+	sources := append([]int64(nil), c.linkIndex[models.ComponentRef{ID: id}]...)
+	if target.Slug != "" {
+		sources = append(sources, c.linkIndex[models.ComponentRef{Slug: target.Slug}]...)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+	components = make([]*models.Component, 0, len(sources))
+	for i, sourceID := range sources {
+		if sourceID == id || (i > 0 && sourceID == sources[i-1]) {
+			continue // a component naming its own slug, or referring both ways
+		}
+		components = append(components, c.readOut(c.componentsByID[sourceID]))
+	}
+	return components, true
+}
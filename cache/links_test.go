@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"component-service/models"
+	"testing"
+)
+
+func TestComponentCache_Backlinks(t *testing.T) {
+	described := func(id, parentID int64, slug, description string) *models.Component {
+		comp := aclTestComponent(id, parentID)
+		comp.Slug = slug
+		comp.Description = description
+		return comp
+	}
+	c, err := LoadComponentCache(&MockComponentStore{mockComponents: []*models.Component{
+		described(1, 0, "plant", "Home of [[pump]]."),
+		described(2, 1, "pump", "See [[pump]] and /components/2, its manual."),
+		described(3, 1, "valve", "Downstream of /components/2 and [[pump]]."),
+		described(4, 0, "notes", "Mentions [[#3]]."),
+	}}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadComponentCache failed: %v", err)
+	}
+	expectBacklinks := func(id int64, want ...int64) {
+		t.Helper()
+		components, found := c.Backlinks(id)
+		if !found {
+			t.Fatalf("Backlinks(%d) found nothing", id)
+		}
+		var got []int64
+		for _, comp := range components {
+			got = append(got, comp.ID)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Backlinks(%d) = %v; expected %v", id, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Backlinks(%d) = %v; expected %v", id, got, want)
+			}
+		}
+	}
+	expectBacklinks(2, 1, 3) // by ID and slug, each once; not 2 itself
+	expectBacklinks(3, 4)
+	expectBacklinks(4)
+
+	// An edit drops the references it removes; a deleted component refers to nothing.
+	edited := *described(3, 1, "valve", "Downstream of the pump.")
+	c.Set(&edited)
+	expectBacklinks(2, 1)
+	c.Delete(1)
+	expectBacklinks(2)
+	c.Delete(4)
+	expectBacklinks(3)
+
+	// A slug reference counts for whichever component holds the slug.
+	c.Set(described(5, 0, "gasket", "Fits [[seal]]."))
+	c.Set(described(6, 0, "seal", ""))
+	expectBacklinks(6, 5)
+	if _, found := c.Backlinks(99); found {
+		t.Error("Backlinks of an unknown component found it")
+	}
+}
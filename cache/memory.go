@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"component-service/models"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"unsafe"
+)
+
+// Approximation constants for Go runtime data structures on 64-bit platforms.
+const (
+	pointerBytes     = 8
+	sliceHeaderBytes = 24
+	mapHeaderBytes   = 48
+	// mapEntryOverhead accounts for per-entry control bytes and buckets kept below full
+	// (maps grow at roughly 80% load), expressed as a multiplier on key+value bytes.
+	mapEntryOverhead = 1.3
+)
+
+// MemoryStats is an approximate breakdown of the cache's memory footprint. Values are estimates
+// derived from element counts and string lengths, intended to guide eviction and deduplication
+// decisions rather than to match heap profiles exactly.
+type MemoryStats struct {
+	Components         int              `json:"components"`
+	ParentGroups       int              `json:"parent_groups"`
+	ComponentStructs   int64            `json:"component_structs_bytes"` // models.Component values and their tag slices, excluding string data
+	StringData         int64            `json:"string_data_bytes"`       // distinct string bytes referenced by components
+	StringDataByField  map[string]int64 `json:"string_data_by_field_bytes"`
+	ComponentsByIDMap  int64            `json:"components_by_id_bytes"`      // map[int64]*Component
+	ChildrenByParentID int64            `json:"children_by_parent_id_bytes"` // map header/entries plus child slices
+	AllComponentsSlice int64            `json:"all_components_bytes"`        // []*Component backing array
+	JSONFragments      int64            `json:"json_fragments_bytes"`        // pre-marshaled JSON per component
+	SubtreeHashes      int64            `json:"subtree_hashes_bytes"`        // Merkle hash per component
+	CreatedOrder       int64            `json:"created_order_bytes"`         // sorted (created_at, id) index for cursor paging
+	NameIndex          int64            `json:"name_index_bytes"`            // component IDs by name, excluding the shared name strings
+	SlugIndex          int64            `json:"slug_index_bytes"`            // component ID by slug, excluding the shared slug strings
+	TagIndex           int64            `json:"tag_index_bytes"`             // component IDs by tag, excluding the shared tag strings
+	LinkIndex          int64            `json:"link_index_bytes"`            // component IDs by the components they refer to, for backlinks
+	Total              int64            `json:"total_bytes"`
+}
+
+// MemoryStats walks the cache under a read lock and estimates the memory held by each structure.
+func (c *ComponentCache) MemoryStats() MemoryStats {
+	c.rlock()
+	defer c.mu.RUnlock()
+
+	stats := MemoryStats{
+		Components:        len(c.componentsByID),
+		ParentGroups:      len(c.childrenByParentID),
+		StringDataByField: map[string]int64{},
+	}
+
+	// Every index points at the same *Component, so struct and string data are counted once, via componentsByID.
+	stats.ComponentStructs = int64(len(c.componentsByID)) * int64(unsafe.Sizeof(models.Component{}))
+	// Interned strings share a backing array; count each array once, against the first field seen using it.
+	seen := make(map[*byte]struct{})
+	countString := func(field, s string) {
+		if len(s) == 0 {
+			return
+		}
+		data := unsafe.StringData(s)
+		if _, ok := seen[data]; ok {
+			return
+		}
+		seen[data] = struct{}{}
+		stats.StringDataByField[field] += int64(len(s))
+	}
+	for _, comp := range c.componentsByID {
+		stats.ComponentStructs += int64(cap(comp.Tags)) * int64(unsafe.Sizeof(""))
+		countString("name", comp.Name)
+		countString("slug", comp.Slug)
+		countString("type", comp.Type)
+		countString("status", comp.Status)
+		for _, tag := range comp.Tags {
+			countString("tags", tag)
+		}
+		countString("description", comp.Description)
+		if len(comp.Metadata) > 0 {
+			stats.StringDataByField["metadata"] += int64(cap(comp.Metadata)) // never shared
+		}
+		countString("created_at", comp.CreatedAt)
+		countString("updated_at", comp.UpdatedAt)
+	}
+	for _, bytes := range stats.StringDataByField {
+		stats.StringData += bytes
+	}
+
+	stats.ComponentsByIDMap = mapBytes(len(c.componentsByID), 8, pointerBytes)
+
+	stats.ChildrenByParentID = mapBytes(len(c.childrenByParentID), 8, sliceHeaderBytes)
+	for _, children := range c.childrenByParentID {
+		stats.ChildrenByParentID += int64(cap(children)) * pointerBytes
+	}
+
+	stats.AllComponentsSlice = sliceHeaderBytes + int64(cap(c.allComponents))*pointerBytes
+
+	stats.JSONFragments = mapBytes(len(c.jsonByID), 8, sliceHeaderBytes)
+	for _, fragment := range c.jsonByID {
+		stats.JSONFragments += int64(cap(fragment))
+	}
+
+	stats.SubtreeHashes = mapBytes(len(c.hashByID), 8, sha256.Size)
+
+	stats.CreatedOrder = sliceHeaderBytes + int64(cap(c.createdOrder))*int64(unsafe.Sizeof(Cursor{}))
+
+	stats.NameIndex = mapBytes(len(c.nameIndex), int(unsafe.Sizeof("")), sliceHeaderBytes)
+	for _, ids := range c.nameIndex {
+		stats.NameIndex += int64(cap(ids)) * 8
+	}
+
+	stats.SlugIndex = mapBytes(len(c.slugIndex), int(unsafe.Sizeof("")), 8)
+
+	stats.TagIndex = mapBytes(len(c.tagIndex), int(unsafe.Sizeof("")), sliceHeaderBytes)
+	for _, ids := range c.tagIndex {
+		stats.TagIndex += int64(cap(ids)) * 8
+	}
+
+	stats.LinkIndex = mapBytes(len(c.linkIndex), int(unsafe.Sizeof(models.ComponentRef{})), sliceHeaderBytes)
+	for ref, ids := range c.linkIndex {
+		stats.LinkIndex += int64(len(ref.Slug)) + int64(cap(ids))*8
+	}
+
+	stats.Total = stats.ComponentStructs + stats.StringData + stats.ComponentsByIDMap + stats.ChildrenByParentID + stats.AllComponentsSlice + stats.JSONFragments + stats.SubtreeHashes + stats.CreatedOrder + stats.NameIndex + stats.SlugIndex + stats.TagIndex + stats.LinkIndex
+	return stats
+}
+
+func mapBytes(entries int, keyBytes, valueBytes int) int64 {
+	return mapHeaderBytes + int64(float64(entries*(keyBytes+valueBytes))*mapEntryOverhead)
+}
+
+// WriteSnapshot writes every cached component to w as a gzip-compressed JSON array, taken under
+// a single read lock so the snapshot is internally consistent.
+func (c *ComponentCache) WriteSnapshot(w io.Writer) error {
+	components := c.GetAll()
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(components); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
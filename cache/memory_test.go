@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"bytes"
+	"component-service/models"
+	"compress/gzip"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestComponentCache_MemoryStats(t *testing.T) {
+	c1 := *comp1Global
+	c2 := *comp2Global
+	c3 := *comp3Global
+
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	empty := GlobalComponentCache.MemoryStats()
+	if empty.Components != 0 || empty.StringData != 0 {
+		t.Errorf("Expected empty cache stats, got %+v", empty)
+	}
+
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c1, &c2, &c3}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	stats := GlobalComponentCache.MemoryStats()
+
+	if stats.Components != 3 {
+		t.Errorf("Expected 3 components, got %d", stats.Components)
+	}
+	if stats.ParentGroups != 2 { // roots and children of 1
+		t.Errorf("Expected 2 parent groups, got %d", stats.ParentGroups)
+	}
+	expectedNameBytes := int64(len(c1.Name) + len(c2.Name) + len(c3.Name))
+	if stats.StringDataByField["name"] != expectedNameBytes {
+		t.Errorf("Expected %d name bytes, got %d", expectedNameBytes, stats.StringDataByField["name"])
+	}
+	sum := stats.ComponentStructs + stats.StringData + stats.ComponentsByIDMap + stats.ChildrenByParentID + stats.AllComponentsSlice + stats.JSONFragments + stats.SubtreeHashes + stats.CreatedOrder + stats.NameIndex + stats.SlugIndex + stats.TagIndex + stats.LinkIndex
+	if stats.Total != sum {
+		t.Errorf("Total %d does not match sum of structures %d", stats.Total, sum)
+	}
+	if stats.Total <= empty.Total {
+		t.Errorf("Expected populated cache (%d bytes) to exceed empty cache (%d bytes)", stats.Total, empty.Total)
+	}
+}
+
+func TestComponentCache_WriteSnapshot(t *testing.T) {
+	c1 := *comp1Global
+	c2 := *comp2Global
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c1, &c2}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := GlobalComponentCache.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Snapshot is not valid gzip: %v", err)
+	}
+	var decoded []*models.Component
+	if err := json.NewDecoder(gz).Decode(&decoded); err != nil {
+		t.Fatalf("Snapshot is not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, GlobalComponentCache.GetAll()) {
+		t.Errorf("Snapshot %+v does not match cache contents %+v", decoded, GlobalComponentCache.GetAll())
+	}
+}
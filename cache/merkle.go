@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"component-service/models"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+)
+
+// Subtree hashes are Merkle-style: a component's hash covers its own content and, in ID order,
+// the hashes of its children, so two mirrors agree on a subtree's hash exactly when they agree on
+// every component in it. Content is the ID, name and description; timestamps are left out so that
+// mirrors formatting them differently still agree.
+
+// contentHash hashes a component's own fields. Strings are length-prefixed so field boundaries
+// cannot be shifted to produce collisions.
+func contentHash(comp *models.Component) [sha256.Size]byte {
+	h := sha256.New()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(comp.ID))
+	h.Write(buf[:])
+	for _, field := range []string{comp.Name, comp.Description} {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(field)))
+		h.Write(buf[:])
+		h.Write([]byte(field))
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// combineHashes hashes a node's content hash followed by its children's subtree hashes.
+func combineHashes(content [sha256.Size]byte, children [][sha256.Size]byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(content[:])
+	for _, child := range children {
+		h.Write(child[:])
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// sortedByID returns the components ordered by ID, the order children are folded into hashes.
+func sortedByID(components []*models.Component) []*models.Component {
+	sorted := append([]*models.Component(nil), components...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// subtreeHashes computes the hash of every cached component's subtree from scratch, iteratively so
+// deep trees cannot overflow the stack. It seeds hashByID when the cache is built; afterwards
+// rehashFrom keeps hashes current. Assumes the read lock is held.
+func (c *ComponentCache) subtreeHashes() map[int64][sha256.Size]byte {
+	hashes := make(map[int64][sha256.Size]byte, len(c.componentsByID))
+	type frame struct {
+		comp     *models.Component
+		expanded bool
+	}
+	stack := make([]frame, 0, len(c.childrenByParentID[RootParentIDKey]))
+	for _, root := range c.childrenByParentID[RootParentIDKey] {
+		stack = append(stack, frame{comp: root})
+	}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if !top.expanded {
+			stack[len(stack)-1].expanded = true
+			for _, child := range c.childrenByParentID[top.comp.ID] {
+				if _, done := hashes[child.ID]; !done {
+					stack = append(stack, frame{comp: child})
+				}
+			}
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		children := sortedByID(c.childrenByParentID[top.comp.ID])
+		childHashes := make([][sha256.Size]byte, len(children))
+		for i, child := range children {
+			childHashes[i] = hashes[child.ID]
+		}
+		hashes[top.comp.ID] = combineHashes(contentHash(top.comp), childHashes)
+	}
+	return hashes
+}
+
+// rehashFrom recomputes the subtree hash of id from its children's stored hashes, then of each
+// ancestor in turn, which is all a single change can affect. Assumes the write lock is held.
+func (c *ComponentCache) rehashFrom(id int64) {
+	for steps := 0; steps <= len(c.componentsByID); steps++ { // bounded in case of a parent cycle
+		comp, exists := c.componentsByID[id]
+		if !exists {
+			return
+		}
+		children := sortedByID(c.childrenByParentID[id])
+		childHashes := make([][sha256.Size]byte, len(children))
+		for i, child := range children {
+			childHashes[i] = c.hashByID[child.ID]
+		}
+		c.hashByID[id] = combineHashes(contentHash(comp), childHashes)
+		if !comp.ParentID.Valid {
+			return
+		}
+		id = comp.ParentID.Int64
+	}
+}
+
+// SubtreeHash returns the hex-encoded Merkle hash of the subtree rooted at id.
+func (c *ComponentCache) SubtreeHash(id int64) (string, bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	if _, exists := c.componentsByID[id]; !exists {
+		return "", false
+	}
+	return hexHash(c.hashByID[id]), true
+}
+
+// forestHash folds the hashes of all root subtrees, in ID order, into one hash for the whole cache.
+// Assumes the read lock is held.
+func (c *ComponentCache) forestHash(hashes map[int64][sha256.Size]byte) [sha256.Size]byte {
+	roots := sortedByID(c.childrenByParentID[RootParentIDKey])
+	rootHashes := make([][sha256.Size]byte, len(roots))
+	for i, root := range roots {
+		rootHashes[i] = hashes[root.ID]
+	}
+	return combineHashes([sha256.Size]byte{}, rootHashes)
+}
+
+func hexHash(sum [sha256.Size]byte) string {
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"component-service/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAmbiguousPath is returned by a strict path resolution when siblings share a name on the path.
+var ErrAmbiguousPath = errors.New("ambiguous path")
+
+// PathStep picks the component a path segment names among candidates, the visible siblings
+// sharing that name in sibling order. The first candidate wins, unless strict and there are
+// several, which fails with ErrAmbiguousPath. The walk does not backtrack: later segments are
+// looked up under the winner only. names are the segments resolved so far, for the error.
+func PathStep(candidates []*models.Component, names []string, strict bool) (*models.Component, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("component at path %s not found", FormatPath(names))
+	}
+	if strict && len(candidates) > 1 {
+		ids := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			ids[i] = fmt.Sprint(candidate.ID)
+		}
+		return nil, fmt.Errorf("%w: %s names components %s", ErrAmbiguousPath, FormatPath(names), strings.Join(ids, ", "))
+	}
+	return candidates[0], nil
+}
+
+// FormatPath renders names as a path, escaping slashes and backslashes within them.
+func FormatPath(names []string) string {
+	var path strings.Builder
+	for _, name := range names {
+		path.WriteString("/" + pathEscaper.Replace(name))
+	}
+	return path.String()
+}
+
+var pathEscaper = strings.NewReplacer(`\`, `\\`, `/`, `\/`)
+
+// ParsePath splits a path such as /plant/line-a/pump into its names. A name containing a slash
+// or a backslash escapes it with a backslash. The path must start with a slash and name at least
+// one component, and no name may be empty.
+func ParsePath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") || path == "/" {
+		return nil, fmt.Errorf("a path starts with / and names at least one component")
+	}
+	var names []string
+	var name strings.Builder
+	escaped := false
+	for _, r := range path[1:] {
+		switch {
+		case escaped:
+			if r != '/' && r != '\\' {
+				return nil, fmt.Errorf(`only / and \ can be escaped in a path`)
+			}
+			name.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '/':
+			names = append(names, name.String())
+			name.Reset()
+		default:
+			name.WriteRune(r)
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf(`a path cannot end with a lone \`)
+	}
+	names = append(names, name.String())
+	for _, n := range names {
+		if n == "" {
+			return nil, fmt.Errorf("a path cannot have an empty name")
+		}
+	}
+	return names, nil
+}
+
+// ResolvePath returns the component names leads to from the roots, each name choosing among the
+// children of the component before it, as PathStep does. visible, when not nil, leaves out the
+// components it refuses, as if they did not exist. A missing component fails with a "not found"
+// error.
+func (c *ComponentCache) ResolvePath(names []string, visible func(id int64) bool, strict bool) (*models.Component, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("component at path / not found")
+	}
+	c.rlock()
+	defer c.mu.RUnlock()
+	parentKey := int64(RootParentIDKey)
+	var found *models.Component
+	for i, name := range names {
+		var candidates []*models.Component
+		for _, child := range c.childrenByParentID[parentKey] {
+			if child.Name == name && (visible == nil || visible(child.ID)) {
+				candidates = append(candidates, child)
+			}
+		}
+		var err error
+		if found, err = PathStep(siblingOrder.sorted(candidates), names[:i+1], strict); err != nil {
+			return nil, err
+		}
+		parentKey = found.ID
+	}
+	return c.readOut(found), nil
+}
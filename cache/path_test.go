@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"component-service/models"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for path, want := range map[string][]string{
+		"/plant":             {"plant"},
+		"/plant/line a/pump": {"plant", "line a", "pump"},
+		`/a\/b/c\\d`:         {"a/b", `c\d`},
+	} {
+		got, err := ParsePath(path)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParsePath(%q) = %q, %v; expected %q", path, got, err, want)
+		}
+		if formatted := FormatPath(got); formatted != path {
+			t.Errorf("FormatPath(%q) = %q; expected %q", got, formatted, path)
+		}
+	}
+	for _, path := range []string{"", "/", "plant", "/plant/", "//plant", `/pl\ant`, `/plant\`} {
+		if _, err := ParsePath(path); err == nil {
+			t.Errorf("ParsePath(%q): expected an error", path)
+		}
+	}
+}
+
+func TestComponentCache_ResolvePath(t *testing.T) {
+	// plant (1) holds two units, 2 and 3, both named "unit"; 3 comes first by position. Each holds
+	// a pump, 4 and 5.
+	named := func(id, parentID, position int64, name string) *models.Component {
+		comp := aclTestComponent(id, parentID)
+		comp.Name, comp.Position = name, position
+		return comp
+	}
+	store := &MockComponentStore{mockComponents: []*models.Component{
+		named(1, 0, 0, "plant"), named(2, 1, 1, "unit"), named(3, 1, 0, "unit"),
+		named(4, 2, 0, "pump"), named(5, 3, 0, "pump"),
+	}}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+
+	for _, tc := range []struct {
+		name    string
+		path    []string
+		visible func(int64) bool
+		strict  bool
+		want    int64
+		err     string
+	}{
+		{"unique", []string{"plant"}, nil, false, 1, ""},
+		{"first sibling wins", []string{"plant", "unit", "pump"}, nil, false, 5, ""},
+		{"hidden sibling skipped", []string{"plant", "unit", "pump"}, func(id int64) bool { return id != 3 }, false, 4, ""},
+		{"strict and ambiguous", []string{"plant", "unit", "pump"}, nil, true, 0, "/plant/unit names components 3, 2"},
+		{"strict with one visible", []string{"plant", "unit"}, func(id int64) bool { return id != 3 }, true, 2, ""},
+		{"missing", []string{"plant", "pump"}, nil, false, 0, "/plant/pump not found"},
+		{"no backtracking", []string{"plant", "unit", "valve"}, nil, false, 0, "not found"},
+		{"hidden", []string{"plant"}, func(int64) bool { return false }, false, 0, "not found"},
+	} {
+		got, err := cache.ResolvePath(tc.path, tc.visible, tc.strict)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.err, err)
+			}
+			if tc.strict && !errors.Is(err, ErrAmbiguousPath) && strings.Contains(tc.err, "names") {
+				t.Errorf("%s: expected ErrAmbiguousPath, got %v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil || got.ID != tc.want {
+			t.Errorf("%s: expected component %d, got %+v, %v", tc.name, tc.want, got, err)
+		}
+	}
+}
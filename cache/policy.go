@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"component-service/models"
+	"fmt"
+)
+
+// WritePolicy chooses how the service's own writes reach the cache.
+type WritePolicy string
+
+const (
+	// WriteThrough stores each written component in the cache as soon as the write commits, so
+	// the next read finds it without touching the database.
+	WriteThrough WritePolicy = "write-through"
+
+	// WriteAround only invalidates the written components. The first read after the write
+	// reloads them from the database, batching the cache work of consecutive writes into one
+	// query and keeping it off the write path.
+	WriteAround WritePolicy = "write-around"
+)
+
+// ComponentLoader is implemented by stores that can read components by ID. InitGlobalCache
+// requires it of its store under WriteAround, to reload invalidated components.
+type ComponentLoader interface {
+	// LoadComponents returns the components with the given IDs that exist, in any order.
+	LoadComponents(ids []int64) ([]*models.Component, error)
+}
+
+// parseWritePolicy parses a CACHE_WRITE_POLICY value.
+func parseWritePolicy(value string) (WritePolicy, error) {
+	switch policy := WritePolicy(value); policy {
+	case WriteThrough, WriteAround:
+		return policy, nil
+	case "write-back":
+		return "", fmt.Errorf("CACHE_WRITE_POLICY=write-back is not supported: acknowledging writes before they commit needs a durable write queue, which the service does not have")
+	default:
+		return "", fmt.Errorf("invalid CACHE_WRITE_POLICY %q: expected %s or %s", value, WriteThrough, WriteAround)
+	}
+}
+
+// WritePolicy returns the policy writers must follow for this cache.
+func (c *ComponentCache) WritePolicy() WritePolicy {
+	if c.config.WritePolicy == "" {
+		return WriteThrough
+	}
+	return c.config.WritePolicy
+}
+
+// Invalidate marks components as changed in the database. Their cached values, or their absence,
+// are replaced from the loader before the next read. IDs that no longer exist are deleted, with
+// their children becoming roots as Delete does.
+func (c *ComponentCache) Invalidate(ids ...int64) {
+	c.staleMu.Lock()
+	defer c.staleMu.Unlock()
+	for _, id := range ids {
+		c.staleIDs[id] = true
+	}
+	c.staleCount.Store(int64(len(c.staleIDs)))
+}
+
+// rlock takes the read lock once invalidated components have been reloaded. Every read goes
+// through it, so no read serves a component invalidated before it started.
+func (c *ComponentCache) rlock() {
+	c.reloadStale()
+	c.mu.RLock()
+}
+
+// reloadStale reloads the invalidated components. Readers arriving meanwhile wait for it, as do
+// writers invalidating more. When the loader fails the cache is marked stale (see MarkStale), the
+// components stay invalidated for the next read to retry, and this read serves the values cached
+// before.
+func (c *ComponentCache) reloadStale() {
+	if c.staleCount.Load() == 0 {
+		return
+	}
+	c.staleMu.Lock()
+	defer c.staleMu.Unlock()
+	if len(c.staleIDs) == 0 || c.loader == nil {
+		return
+	}
+	ids := make([]int64, 0, len(c.staleIDs))
+	for id := range c.staleIDs {
+		ids = append(ids, id)
+	}
+	components, err := c.loader.LoadComponents(ids)
+	if err != nil {
+		MarkStale(ReasonReloadFailed, fmt.Errorf("reloading %d invalidated components: %w", len(ids), err))
+		return
+	}
+	c.SetMany(components)
+	found := make(map[int64]bool, len(components))
+	for _, component := range components {
+		found[component.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			c.Delete(id)
+		}
+	}
+	c.staleIDs = make(map[int64]bool)
+	c.staleCount.Store(0)
+	ClearStale(ReasonReloadFailed)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"component-service/models"
+	"errors"
+	"testing"
+)
+
+// loaderMockStore is a MockComponentStore that also loads components by ID, from components, which
+// tests change to simulate database writes.
+type loaderMockStore struct {
+	MockComponentStore
+	components map[int64]*models.Component
+	loads      int
+	err        error
+}
+
+func (m *loaderMockStore) LoadComponents(ids []int64) ([]*models.Component, error) {
+	m.loads++
+	if m.err != nil {
+		return nil, m.err
+	}
+	var found []*models.Component
+	for _, id := range ids {
+		if comp, ok := m.components[id]; ok {
+			found = append(found, comp)
+		}
+	}
+	return found, nil
+}
+
+func TestWriteAround(t *testing.T) {
+	defer func(cfg Config) { GlobalConfig = cfg }(GlobalConfig)
+	GlobalConfig.WritePolicy = WriteAround
+	if err := InitGlobalCache(&MockComponentStore{}); err == nil {
+		t.Fatal("Expected write-around to need a store that loads components")
+	}
+
+	// 1 -> 2 -> 3.
+	store := &loaderMockStore{MockComponentStore: MockComponentStore{mockComponents: []*models.Component{
+		aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2),
+	}}}
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	c := GlobalComponentCache
+	if c.WritePolicy() != WriteAround {
+		t.Fatalf("Expected write-around, got %s", c.WritePolicy())
+	}
+
+	// Renaming 3, deleting 2 (whose child 3 becomes a root in the database) and adding 4.
+	renamed := aclTestComponent(3, 0)
+	renamed.Name = "Renamed"
+	store.components = map[int64]*models.Component{1: aclTestComponent(1, 0), 3: renamed, 4: aclTestComponent(4, 1)}
+	c.Invalidate(2, 3, 4)
+	if store.loads != 0 {
+		t.Errorf("Expected Invalidate not to load, got %d loads", store.loads)
+	}
+
+	if comp, found := c.GetByID(3); !found || comp.Name != "Renamed" || comp.ParentID.Valid {
+		t.Errorf("Expected 3 reloaded as a renamed root, got %+v, %v", comp, found)
+	}
+	if _, found := c.GetByID(2); found {
+		t.Error("Expected 2 deleted on reload")
+	}
+	if children, _ := c.GetChildren(1); len(children) != 1 || children[0].ID != 4 {
+		t.Errorf("Expected 4 as the only child of 1, got %v", children)
+	}
+	c.GetAll()
+	if store.loads != 1 {
+		t.Errorf("Expected one load for all invalidated components, got %d", store.loads)
+	}
+
+	// A failed reload serves the cached value and is retried by the next read.
+	store.err = errors.New("database unavailable")
+	store.components[1] = &models.Component{ID: 1, Name: "Reloaded"}
+	c.Invalidate(1)
+	if comp, _ := c.GetByID(1); comp.Name != "Comp" {
+		t.Errorf("Expected the cached value while reloading fails, got %+v", comp)
+	}
+	store.err = nil
+	if comp, _ := c.GetByID(1); comp.Name != "Reloaded" {
+		t.Errorf("Expected the retried reload, got %+v", comp)
+	}
+	if store.loads != 3 {
+		t.Errorf("Expected 3 loads, got %d", store.loads)
+	}
+}
@@ -0,0 +1,341 @@
+package cache
+
+import (
+	"component-service/models"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// cacheOp is one randomly generated write. Kind picks the operation; A and B pick the components
+// it applies to among those in the model, so every generated sequence is valid against the tree it
+// runs on.
+type cacheOp struct {
+	Kind, A, B uint8
+}
+
+// cacheModel is the expected state of the cache: what the database would hold after the same
+// writes. The cache is checked against it, and against itself, after every operation.
+type cacheModel struct {
+	components map[int64]models.Component
+	nextID     int64
+}
+
+// ids returns the model's component IDs in ascending order, so an index picks the same component
+// on every run of a sequence.
+func (m *cacheModel) ids() []int64 {
+	ids := make([]int64, 0, len(m.components))
+	for id := range m.components {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// pick returns the component ID at index n modulo the model's size, or false when it is empty.
+func (m *cacheModel) pick(n uint8) (int64, bool) {
+	ids := m.ids()
+	if len(ids) == 0 {
+		return 0, false
+	}
+	return ids[int(n)%len(ids)], true
+}
+
+// pickParent returns a parent for a component: a root when n selects one past the last component.
+func (m *cacheModel) pickParent(n uint8) sql.NullInt64 {
+	ids := m.ids()
+	if i := int(n) % (len(ids) + 1); i < len(ids) {
+		return nullInt64(ids[i])
+	}
+	return sql.NullInt64{}
+}
+
+// within reports whether id is rootID or one of its descendants.
+func (m *cacheModel) within(id, rootID int64) bool {
+	for steps := 0; steps <= len(m.components); steps++ {
+		if id == rootID {
+			return true
+		}
+		comp := m.components[id]
+		if !comp.ParentID.Valid {
+			return false
+		}
+		id = comp.ParentID.Int64
+	}
+	return false
+}
+
+// moved returns the component id moved under the parent picked by n, or false when that parent
+// would create a cycle.
+func (m *cacheModel) moved(id int64, n uint8) (models.Component, bool) {
+	comp := m.components[id]
+	comp.ParentID = m.pickParent(n)
+	if comp.ParentID.Valid && m.within(comp.ParentID.Int64, id) {
+		return comp, false
+	}
+	return comp, true
+}
+
+// apply runs op against the cache and the model alike, and describes it for failure messages.
+func (m *cacheModel) apply(c *ComponentCache, op cacheOp) string {
+	names := []string{"pump", "valve", "line", "Pump"}
+	tagSets := [][]string{nil, {"critical"}, {"critical", "spare"}, {"spare"}}
+	switch op.Kind % 7 {
+	case 0:
+		m.nextID++
+		comp := models.Component{
+			ID:        m.nextID,
+			Name:      names[int(op.B)%len(names)],
+			Slug:      fmt.Sprintf("component-%d", m.nextID),
+			ParentID:  m.pickParent(op.A),
+			CreatedAt: time.Unix(int64(op.B%4), 0).UTC().Format(time.RFC3339),
+			Tags:      tagSets[int(op.A)%len(tagSets)],
+		}
+		m.components[comp.ID] = comp
+		c.Set(&comp)
+		return fmt.Sprintf("create %d under %v", comp.ID, comp.ParentID)
+	case 1:
+		id, ok := m.pick(op.A)
+		if !ok {
+			return "rename nothing"
+		}
+		comp := m.components[id]
+		comp.Name = names[int(op.B)%len(names)]
+		m.components[id] = comp
+		c.Set(&comp)
+		return fmt.Sprintf("rename %d to %q", id, comp.Name)
+	case 2:
+		id, ok := m.pick(op.A)
+		if !ok {
+			return "move nothing"
+		}
+		comp, ok := m.moved(id, op.B)
+		if !ok {
+			return fmt.Sprintf("move %d into its own subtree, skipped", id)
+		}
+		m.components[id] = comp
+		c.Set(&comp)
+		return fmt.Sprintf("move %d under %v", id, comp.ParentID)
+	case 3:
+		id, ok := m.pick(op.A)
+		if !ok {
+			return "delete nothing"
+		}
+		delete(m.components, id)
+		for childID, child := range m.components {
+			if child.ParentID.Valid && child.ParentID.Int64 == id {
+				child.ParentID = sql.NullInt64{}
+				m.components[childID] = child
+			}
+		}
+		c.Delete(id)
+		return fmt.Sprintf("delete %d", id)
+	case 4:
+		id, ok := m.pick(op.A)
+		if !ok {
+			return "delete nothing"
+		}
+		var subtree []int64
+		for _, other := range m.ids() {
+			if m.within(other, id) {
+				subtree = append(subtree, other)
+			}
+		}
+		for _, other := range subtree {
+			delete(m.components, other)
+		}
+		c.DeleteSubtree(id)
+		return fmt.Sprintf("delete subtree %d", id)
+	case 5:
+		id, ok := m.pick(op.A)
+		if !ok {
+			return "retag nothing"
+		}
+		comp := m.components[id]
+		comp.Tags = tagSets[int(op.B)%len(tagSets)]
+		m.components[id] = comp
+		c.Set(&comp)
+		return fmt.Sprintf("retag %d with %q", id, comp.Tags)
+	default:
+		// A batch move of two components, each under the parent the other's pick selects. The
+		// second move is checked against the tree after the first, as the database would.
+		first, ok := m.pick(op.A)
+		second, _ := m.pick(op.B)
+		if !ok {
+			return "batch move nothing"
+		}
+		var batch []*models.Component
+		for _, move := range [][2]int64{{first, int64(op.B)}, {second, int64(op.A)}} {
+			if comp, ok := m.moved(move[0], uint8(move[1])); ok {
+				m.components[comp.ID] = comp
+				batch = append(batch, &comp)
+			}
+		}
+		c.SetMany(batch)
+		return fmt.Sprintf("batch move of %d and %d", first, second)
+	}
+}
+
+// checkCacheInvariants returns an error describing the first way c's indexes disagree with each
+// other or with the model.
+func checkCacheInvariants(c *ComponentCache, m *cacheModel) error {
+	if err := checkCacheIndexes(c); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.componentsByID) != len(m.components) {
+		return fmt.Errorf("componentsByID holds %d components; the model holds %d", len(c.componentsByID), len(m.components))
+	}
+	for id, want := range m.components {
+		got, found := c.componentsByID[id]
+		if !found {
+			return fmt.Errorf("component %d is missing from componentsByID", id)
+		}
+		if got.ParentID != want.ParentID || got.Name != want.Name {
+			return fmt.Errorf("component %d is cached as %q under %v; expected %q under %v", id, got.Name, got.ParentID, want.Name, want.ParentID)
+		}
+		if !reflect.DeepEqual(got.Tags, want.Tags) {
+			return fmt.Errorf("component %d is cached with tags %q; expected %q", id, got.Tags, want.Tags)
+		}
+	}
+	return nil
+}
+
+// checkCacheIndexes returns an error describing the first way c's indexes disagree with each
+// other: every component listed once in allComponents and once under its parent, no children
+// listed under a missing parent, and the name, tag, slug, creation order, JSON and hash indexes in
+// step.
+// Every component is expected to have a slug of its own, as the store guarantees.
+func checkCacheIndexes(c *ComponentCache) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen := make(map[int64]int, len(c.allComponents))
+	for _, comp := range c.allComponents {
+		seen[comp.ID]++
+		if c.componentsByID[comp.ID] != comp {
+			return fmt.Errorf("allComponents holds a stale copy of component %d", comp.ID)
+		}
+	}
+	for id := range c.componentsByID {
+		if seen[id] != 1 {
+			return fmt.Errorf("component %d appears %d times in allComponents; expected once", id, seen[id])
+		}
+	}
+	if len(c.allComponents) != len(c.componentsByID) {
+		return fmt.Errorf("allComponents holds %d entries for %d components", len(c.allComponents), len(c.componentsByID))
+	}
+
+	listed := make(map[int64]int, len(c.componentsByID))
+	for parentKey, children := range c.childrenByParentID {
+		if len(children) == 0 {
+			return fmt.Errorf("parent %d has an empty children entry", parentKey)
+		}
+		if _, found := c.componentsByID[parentKey]; !found && parentKey != RootParentIDKey {
+			return fmt.Errorf("children of %d are listed, but %d is not cached", parentKey, parentKey)
+		}
+		for _, child := range children {
+			listed[child.ID]++
+			if c.componentsByID[child.ID] != child {
+				return fmt.Errorf("children of %d hold a stale or deleted component %d", parentKey, child.ID)
+			}
+			if getParentKey(child.ParentID) != parentKey {
+				return fmt.Errorf("component %d is listed under %d, but its parent is %v", child.ID, parentKey, child.ParentID)
+			}
+		}
+	}
+	for id := range c.componentsByID {
+		if listed[id] != 1 {
+			return fmt.Errorf("component %d appears %d times in childrenByParentID; expected once", id, listed[id])
+		}
+	}
+
+	if len(c.jsonByID) != len(c.componentsByID) {
+		return fmt.Errorf("jsonByID holds %d fragments for %d components", len(c.jsonByID), len(c.componentsByID))
+	}
+	fresh := c.subtreeHashes()
+	if len(c.hashByID) != len(fresh) {
+		return fmt.Errorf("hashByID holds %d hashes for %d components", len(c.hashByID), len(fresh))
+	}
+	for id, hash := range fresh {
+		if c.hashByID[id] != hash {
+			return fmt.Errorf("the subtree hash of %d is stale", id)
+		}
+	}
+
+	named := 0
+	for name, ids := range c.nameIndex {
+		for _, id := range ids {
+			named++
+			if comp, found := c.componentsByID[id]; !found || comp.Name != name {
+				return fmt.Errorf("nameIndex lists %d under %q", id, name)
+			}
+		}
+	}
+	if named != len(c.componentsByID) {
+		return fmt.Errorf("nameIndex holds %d entries for %d components", named, len(c.componentsByID))
+	}
+	tagged := 0
+	for tag, ids := range c.tagIndex {
+		if len(ids) == 0 {
+			return fmt.Errorf("tag %q has an empty tagIndex entry", tag)
+		}
+		for _, id := range ids {
+			if comp, found := c.componentsByID[id]; !found || !comp.HasTag(tag) {
+				return fmt.Errorf("tagIndex lists %d under %q", id, tag)
+			}
+		}
+		tagged += len(ids)
+	}
+	tags := 0
+	for _, comp := range c.componentsByID {
+		tags += len(comp.Tags)
+	}
+	if tagged != tags {
+		return fmt.Errorf("tagIndex holds %d entries for %d tags", tagged, tags)
+	}
+	for slug, id := range c.slugIndex {
+		if comp, found := c.componentsByID[id]; !found || comp.Slug != slug {
+			return fmt.Errorf("slugIndex maps %q to %d", slug, id)
+		}
+	}
+	if len(c.slugIndex) != len(c.componentsByID) {
+		return fmt.Errorf("slugIndex holds %d entries for %d components", len(c.slugIndex), len(c.componentsByID))
+	}
+	if len(c.createdOrder) != len(c.componentsByID) {
+		return fmt.Errorf("createdOrder holds %d entries for %d components", len(c.createdOrder), len(c.componentsByID))
+	}
+	for i := 1; i < len(c.createdOrder); i++ {
+		if !c.createdOrder[i].After(c.createdOrder[i-1]) {
+			return fmt.Errorf("createdOrder is out of order at %d", i)
+		}
+	}
+	return nil
+}
+
+// TestComponentCache_RandomWrites applies random sequences of creates, renames, retags, moves,
+// deletes and batch moves, checking after each that every index agrees with the others and with a model of
+// the tree. A failure reports the sequence that broke an invariant and the operations run.
+func TestComponentCache_RandomWrites(t *testing.T) {
+	property := func(ops []cacheOp) bool {
+		c := NewComponentCache()
+		m := &cacheModel{components: make(map[int64]models.Component)}
+		var history []string
+		for _, op := range ops {
+			history = append(history, m.apply(c, op))
+			if err := checkCacheInvariants(c, m); err != nil {
+				t.Errorf("%v after:\n%v", err, history)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
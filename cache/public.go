@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+)
+
+// PublicSource is implemented by stores that persist public visibility flags. InitGlobalCache
+// loads the flagged component IDs from its store when Config.LoadPublic is set.
+type PublicSource interface {
+	ListPublicComponentIDs() ([]int64, error)
+}
+
+// SetPublic flags a component, and with it its subtree, as publicly visible, or clears its flag.
+func (c *ComponentCache) SetPublic(componentID int64, public bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if public {
+		c.publicIDs[componentID] = true
+	} else {
+		delete(c.publicIDs, componentID)
+	}
+	c.accessChanges++
+}
+
+// IsPublic reports whether a component is publicly visible, that is, whether it or one of its
+// ancestors is flagged. flagged reports whether the component carries the flag itself.
+func (c *ComponentCache) IsPublic(componentID int64) (public, flagged bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	if len(c.publicIDs) == 0 {
+		return false, false
+	}
+	flagged = c.publicIDs[componentID]
+	id := componentID
+	for steps := 0; steps <= len(c.componentsByID); steps++ { // bounded in case of a parent cycle
+		comp, exists := c.componentsByID[id]
+		if !exists {
+			return false, flagged
+		}
+		if c.publicIDs[id] {
+			return true, flagged
+		}
+		if !comp.ParentID.Valid {
+			return false, flagged
+		}
+		id = comp.ParentID.Int64
+	}
+	return false, flagged
+}
+
+// PublicJSON returns the flagged components as a JSON array, ordered by ID. Their descendants
+// are public too but are not listed.
+func (c *ComponentCache) PublicJSON() ([]byte, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	flagged := make([]*models.Component, 0, len(c.publicIDs))
+	for id := range c.publicIDs {
+		if comp, exists := c.componentsByID[id]; exists {
+			flagged = append(flagged, comp)
+		}
+	}
+	sort.Slice(flagged, func(i, j int) bool { return flagged[i].ID < flagged[j].ID })
+	return c.appendJSONArray(make([]byte, 0, c.jsonSizeHint(flagged)), flagged)
+}
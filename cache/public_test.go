@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"testing"
+)
+
+// publicMockStore is a MockComponentStore that also persists visibility flags.
+type publicMockStore struct {
+	MockComponentStore
+	publicIDs []int64
+}
+
+func (m *publicMockStore) ListPublicComponentIDs() ([]int64, error) {
+	return m.publicIDs, nil
+}
+
+func TestComponentCache_Public(t *testing.T) {
+	// 1 -> 2 -> 3, and 4 on its own.
+	store := &publicMockStore{
+		MockComponentStore: MockComponentStore{mockComponents: []*models.Component{
+			aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2), aclTestComponent(4, 0),
+		}},
+		publicIDs: []int64{2},
+	}
+	defer func(cfg Config) { GlobalConfig = cfg }(GlobalConfig)
+	GlobalConfig.LoadPublic = true
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	c := GlobalComponentCache
+
+	for _, tc := range []struct {
+		id              int64
+		public, flagged bool
+	}{
+		{1, false, false}, {2, true, true}, {3, true, false}, {4, false, false}, {99, false, false},
+	} {
+		public, flagged := c.IsPublic(tc.id)
+		if public != tc.public || flagged != tc.flagged {
+			t.Errorf("IsPublic(%d) = %v, %v; want %v, %v", tc.id, public, flagged, tc.public, tc.flagged)
+		}
+	}
+
+	c.SetPublic(4, true)
+	body, err := c.PublicJSON()
+	if err != nil {
+		t.Fatalf("PublicJSON failed: %v", err)
+	}
+	var listed []models.Component
+	if err := json.Unmarshal(body, &listed); err != nil {
+		t.Fatalf("PublicJSON returned invalid JSON: %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != 2 || listed[1].ID != 4 {
+		t.Errorf("PublicJSON listed %+v; want components 2 and 4", listed)
+	}
+
+	c.SetPublic(2, false)
+	if public, _ := c.IsPublic(3); public {
+		t.Error("component 3 is still public after its ancestor's flag was cleared")
+	}
+	c.Delete(4)
+	if body, _ := c.PublicJSON(); string(body) != "[]" {
+		t.Errorf("PublicJSON after deleting the last flagged component = %s; want []", body)
+	}
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of a term found in the name and in the description. They mirror the A and B weights
+// of the PostgreSQL search index, so both backends rank a name match above a description match.
+const (
+	searchNameWeight        = 1.0
+	searchDescriptionWeight = 0.4
+)
+
+// SearchResult is a component matched by a full-text search, with its relevance.
+type SearchResult struct {
+	Rank      float64           `json:"rank"`
+	Component *models.Component `json:"component"`
+}
+
+// SearchTerms splits search text into lowercase words, the way the database's 'simple' text
+// search configuration does.
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Search scans every cached component for those whose name or description contains every word
+// of text, and returns the limit best ranked, highest first. A tag that is not empty keeps only
+// the components having it. It is the fallback for databases without a full-text index, or when
+// the database is unreachable.
+func (c *ComponentCache) Search(text, tag string, limit int) []SearchResult {
+	terms := SearchTerms(text)
+	results := []SearchResult{}
+	if len(terms) == 0 {
+		return results
+	}
+
+	c.rlock()
+	defer c.mu.RUnlock()
+	for _, comp := range c.matching(Filter{Tag: tag}) {
+		if rank := searchRank(terms, comp); rank > 0 {
+			results = append(results, SearchResult{Rank: rank, Component: comp})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Component.ID < results[j].Component.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		results[i].Component = c.readOut(results[i].Component)
+	}
+	return results
+}
+
+// searchRank scores a component against terms, or returns 0 when any term is missing from both
+// its name and its description.
+func searchRank(terms []string, comp *models.Component) float64 {
+	nameWords := wordSet(comp.Name)
+	descriptionWords := wordSet(comp.Description)
+	var rank float64
+	for _, term := range terms {
+		var termRank float64
+		if nameWords[term] {
+			termRank += searchNameWeight
+		}
+		if descriptionWords[term] {
+			termRank += searchDescriptionWeight
+		}
+		if termRank == 0 {
+			return 0
+		}
+		rank += termRank
+	}
+	return rank / float64(len(terms))
+}
+
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range SearchTerms(text) {
+		words[word] = true
+	}
+	return words
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"component-service/models"
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	if terms := SearchTerms("  Hydraulic-PUMP, 42a "); !reflect.DeepEqual(terms, []string{"hydraulic", "pump", "42a"}) {
+		t.Errorf("Unexpected terms %v", terms)
+	}
+	if terms := SearchTerms(" -- "); len(terms) != 0 {
+		t.Errorf("Expected no terms, got %v", terms)
+	}
+}
+
+func TestComponentCache_Search(t *testing.T) {
+	components := []*models.Component{
+		{ID: 1, Name: "Valve", Description: "Controls the hydraulic pump", ParentID: invalidNullInt64()},
+		{ID: 2, Name: "Hydraulic pump", Description: "Main pump", ParentID: invalidNullInt64()},
+		{ID: 3, Name: "Pump housing", Description: "Cast iron", ParentID: invalidNullInt64()},
+		{ID: 4, Name: "Pumpkin", ParentID: invalidNullInt64()},
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+
+	ids := func(results []SearchResult) []int64 {
+		ids := []int64{}
+		for _, result := range results {
+			ids = append(ids, result.Component.ID)
+		}
+		return ids
+	}
+
+	// Name matches outrank description matches; every word must match; words match whole.
+	if got := ids(GlobalComponentCache.Search("PUMP", "", 0)); !reflect.DeepEqual(got, []int64{2, 3, 1}) {
+		t.Errorf("Expected [2 3 1] for pump, got %v", got)
+	}
+	if got := ids(GlobalComponentCache.Search("hydraulic pump", "", 0)); !reflect.DeepEqual(got, []int64{2, 1}) {
+		t.Errorf("Expected [2 1] for hydraulic pump, got %v", got)
+	}
+	if got := ids(GlobalComponentCache.Search("pump", "", 1)); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("Expected the limit to keep the best match, got %v", got)
+	}
+	if got := GlobalComponentCache.Search("?!", "", 0); len(got) != 0 {
+		t.Errorf("Expected no results without words, got %v", ids(got))
+	}
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSimulation is returned when a simulated operation could not be applied, such as a
+// move that would make a component its own ancestor.
+var ErrInvalidSimulation = errors.New("invalid simulation")
+
+// Operations that Simulate projects.
+const (
+	OperationMove   = "move"   // reparent the component, with its subtree, under NewParentID
+	OperationDelete = "delete" // delete the component, leaving its children as roots
+	OperationMerge  = "merge"  // reparent the component's children under IntoID, then delete it
+)
+
+// Simulation describes an operation to project on a component.
+type Simulation struct {
+	Operation   string
+	NewParentID sql.NullInt64 // for move; not Valid to make the component a root
+	IntoID      int64         // for merge
+}
+
+// Impact is the projected effect of a simulated operation.
+type Impact struct {
+	Operation   string `json:"operation"`
+	ComponentID int64  `json:"component_id"`
+	// AffectedDescendants counts the components below ComponentID whose ancestry would change.
+	AffectedDescendants int `json:"affected_descendants"`
+	// ResultingDepth is the depth of the deepest component the operation would move, roots being
+	// at depth 0, or 0 when it moves none.
+	ResultingDepth  int              `json:"resulting_depth"`
+	BrokenRelations []BrokenRelation `json:"broken_relations"`
+}
+
+// Relation types reported in a BrokenRelation.
+const (
+	RelationParent = "parent" // the component would lose its parent and become a root
+	RelationACL    = "acl"    // an ACL entry the component inherits would no longer apply
+	RelationPublic = "public" // the component would no longer be public through a flagged ancestor
+)
+
+// BrokenRelation is a relation a component would lose. RelatedID is the lost parent, or the
+// component whose ACL entry or public flag would no longer apply. Relations a component loses
+// apply to its subtree too, which is not listed.
+type BrokenRelation struct {
+	Type        string `json:"type"`
+	ComponentID int64  `json:"component_id"`
+	RelatedID   int64  `json:"related_id"`
+	Principal   string `json:"principal,omitempty"` // the ACL entry's principal
+}
+
+// Simulate projects an operation on the component id from the cached tree, ACLs and public flags,
+// without applying it. It fails with a "not found" error when id or the operation's target is
+// not cached, and with ErrInvalidSimulation when the operation could not be applied.
+func (c *ComponentCache) Simulate(id int64, sim Simulation) (*Impact, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	comp, exists := c.componentsByID[id]
+	if !exists {
+		return nil, fmt.Errorf("component with ID %d not found", id)
+	}
+	impact := &Impact{Operation: sim.Operation, ComponentID: id, BrokenRelations: []BrokenRelation{}}
+	switch sim.Operation {
+	case OperationMove:
+		newParentKey := getParentKey(sim.NewParentID)
+		if err := c.checkTarget(id, newParentKey, "move it under"); err != nil {
+			return nil, err
+		}
+		if newParentKey == getParentKey(comp.ParentID) {
+			impact.ResultingDepth = c.depth(id) + c.height(id)
+			return impact, nil
+		}
+		impact.AffectedDescendants = len(c.descendants(id, 0))
+		impact.ResultingDepth = c.depthUnder(newParentKey) + c.height(id)
+		impact.BrokenRelations = append(impact.BrokenRelations, c.brokenByReparenting(comp, newParentKey)...)
+	case OperationDelete:
+		impact.AffectedDescendants = len(c.descendants(id, 0))
+		for _, child := range sortedByID(c.childrenByParentID[id]) {
+			impact.ResultingDepth = max(impact.ResultingDepth, c.height(child.ID))
+			impact.BrokenRelations = append(impact.BrokenRelations, BrokenRelation{Type: RelationParent, ComponentID: child.ID, RelatedID: id})
+			impact.BrokenRelations = append(impact.BrokenRelations, c.brokenByReparenting(child, RootParentIDKey)...)
+		}
+	case OperationMerge:
+		if sim.IntoID == id {
+			return nil, fmt.Errorf("%w: component %d cannot be merged into itself", ErrInvalidSimulation, id)
+		}
+		if err := c.checkTarget(id, sim.IntoID, "merge it into"); err != nil {
+			return nil, err
+		}
+		impact.AffectedDescendants = len(c.descendants(id, 0))
+		for _, child := range sortedByID(c.childrenByParentID[id]) {
+			impact.ResultingDepth = max(impact.ResultingDepth, c.depthUnder(sim.IntoID)+c.height(child.ID))
+			impact.BrokenRelations = append(impact.BrokenRelations, c.brokenByReparenting(child, sim.IntoID)...)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidSimulation, sim.Operation)
+	}
+	return impact, nil
+}
+
+// checkTarget checks that the component targetKey, which id's subtree would be attached under,
+// exists and lies outside that subtree. RootParentIDKey stands for the roots. Assumes the read
+// lock is held.
+func (c *ComponentCache) checkTarget(id, targetKey int64, action string) error {
+	if targetKey == RootParentIDKey {
+		return nil
+	}
+	if _, exists := c.componentsByID[targetKey]; !exists {
+		return fmt.Errorf("component with ID %d not found to %s", targetKey, action)
+	}
+	for ancestor := range c.ancestors(targetKey) {
+		if ancestor == id {
+			return fmt.Errorf("%w: component %d is %d itself or one of its descendants", ErrInvalidSimulation, targetKey, id)
+		}
+	}
+	return nil
+}
+
+// ancestors returns id and every component above it. A parent cycle ends the walk. Assumes the
+// read lock is held.
+func (c *ComponentCache) ancestors(id int64) map[int64]bool {
+	seen := make(map[int64]bool)
+	for {
+		comp, exists := c.componentsByID[id]
+		if !exists || seen[id] {
+			return seen
+		}
+		seen[id] = true
+		if !comp.ParentID.Valid {
+			return seen
+		}
+		id = comp.ParentID.Int64
+	}
+}
+
+// depth returns the number of ancestors of id. Assumes the read lock is held.
+func (c *ComponentCache) depth(id int64) int {
+	return len(c.ancestors(id)) - 1
+}
+
+// depthUnder returns the depth of a child of parentKey, RootParentIDKey standing for the roots.
+// Assumes the read lock is held.
+func (c *ComponentCache) depthUnder(parentKey int64) int {
+	if parentKey == RootParentIDKey {
+		return 0
+	}
+	return c.depth(parentKey) + 1
+}
+
+// height returns the number of levels below id, 0 for a leaf. Assumes the read lock is held.
+func (c *ComponentCache) height(id int64) int {
+	levels := 0
+	visited := map[int64]bool{id: true}
+	for level := []int64{id}; ; levels++ {
+		var next []int64
+		for _, parentID := range level {
+			for _, child := range c.childrenByParentID[parentID] {
+				if !visited[child.ID] {
+					visited[child.ID] = true
+					next = append(next, child.ID)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return levels
+		}
+		level = next
+	}
+}
+
+// brokenByReparenting returns the inherited ACL entries and public flag comp would lose under
+// parentKey, RootParentIDKey standing for none. Its own entries and flag go with it. Assumes the
+// read lock is held.
+func (c *ComponentCache) brokenByReparenting(comp *models.Component, parentKey int64) []BrokenRelation {
+	var broken []BrokenRelation
+	inherited := aclTable(nil)
+	if parentKey != RootParentIDKey {
+		inherited = c.effectiveACL[parentKey]
+	}
+	own := make(map[string]bool, len(c.aclByID[comp.ID]))
+	for _, entry := range c.aclByID[comp.ID] {
+		own[entry.Principal] = true
+	}
+	var lost []ACLEntry
+	for principal, entry := range c.effectiveACL[comp.ID] {
+		if own[principal] {
+			continue
+		}
+		if kept, found := inherited[principal]; !found || kept != entry {
+			lost = append(lost, entry)
+		}
+	}
+	for _, entry := range sortedEntries(lost) {
+		broken = append(broken, BrokenRelation{Type: RelationACL, ComponentID: comp.ID, RelatedID: entry.ComponentID, Principal: entry.Principal})
+	}
+
+	if len(c.publicIDs) > 0 && !c.publicIDs[comp.ID] && comp.ParentID.Valid {
+		flaggedID, public := c.publicFlag(comp.ParentID.Int64)
+		if _, stillPublic := c.publicFlag(parentKey); public && !stillPublic {
+			broken = append(broken, BrokenRelation{Type: RelationPublic, ComponentID: comp.ID, RelatedID: flaggedID})
+		}
+	}
+	return broken
+}
+
+// publicFlag returns the nearest flagged component among id and its ancestors, RootParentIDKey
+// standing for none. Assumes the read lock is held.
+func (c *ComponentCache) publicFlag(id int64) (flaggedID int64, found bool) {
+	seen := make(map[int64]bool)
+	for id != RootParentIDKey && !seen[id] {
+		comp, exists := c.componentsByID[id]
+		if !exists {
+			return 0, false
+		}
+		if c.publicIDs[id] {
+			return id, true
+		}
+		if !comp.ParentID.Valid {
+			return 0, false
+		}
+		seen[id] = true
+		id = comp.ParentID.Int64
+	}
+	return 0, false
+}
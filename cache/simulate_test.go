@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComponentCache_Simulate(t *testing.T) {
+	// 1 -> 2 -> 3 -> 4 and 1 -> 5, with 6 on its own. 1 is public.
+	store := &aclMockStore{
+		MockComponentStore: MockComponentStore{mockComponents: []*models.Component{
+			aclTestComponent(1, 0), aclTestComponent(2, 1), aclTestComponent(3, 2), aclTestComponent(4, 3),
+			aclTestComponent(5, 1), aclTestComponent(6, 0),
+		}},
+		entries: []ACLEntry{
+			{ComponentID: 1, Principal: "alice", Permission: PermissionWrite},
+			{ComponentID: 1, Principal: EveryonePrincipal, Permission: PermissionRead},
+			{ComponentID: 3, Principal: "bob", Permission: PermissionAdmin},
+		},
+	}
+	defer func(cfg Config) { GlobalConfig = cfg }(GlobalConfig)
+	GlobalConfig.LoadACL = true
+	if err := InitGlobalCache(store); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+	cache.SetPublic(1, true)
+
+	move := func(parentID int64) Simulation {
+		return Simulation{Operation: OperationMove, NewParentID: sql.NullInt64{Int64: parentID, Valid: parentID != 0}}
+	}
+	for _, tc := range []struct {
+		name     string
+		id       int64
+		sim      Simulation
+		affected int
+		depth    int
+		broken   []BrokenRelation
+	}{
+		{"move out of the ACL and public subtree", 2, move(6), 2, 3, []BrokenRelation{
+			{Type: RelationACL, ComponentID: 2, RelatedID: 1, Principal: EveryonePrincipal},
+			{Type: RelationACL, ComponentID: 2, RelatedID: 1, Principal: "alice"},
+			{Type: RelationPublic, ComponentID: 2, RelatedID: 1},
+		}},
+		{"move to a root", 5, move(0), 0, 0, []BrokenRelation{
+			{Type: RelationACL, ComponentID: 5, RelatedID: 1, Principal: EveryonePrincipal},
+			{Type: RelationACL, ComponentID: 5, RelatedID: 1, Principal: "alice"},
+			{Type: RelationPublic, ComponentID: 5, RelatedID: 1},
+		}},
+		{"move within the same ACL", 2, move(5), 2, 4, []BrokenRelation{}},
+		{"move under the same parent", 2, move(1), 0, 3, []BrokenRelation{}},
+		{"delete", 2, Simulation{Operation: OperationDelete}, 2, 1, []BrokenRelation{
+			{Type: RelationParent, ComponentID: 3, RelatedID: 2},
+			{Type: RelationACL, ComponentID: 3, RelatedID: 1, Principal: EveryonePrincipal},
+			{Type: RelationACL, ComponentID: 3, RelatedID: 1, Principal: "alice"},
+			{Type: RelationPublic, ComponentID: 3, RelatedID: 1},
+		}},
+		{"delete a leaf", 4, Simulation{Operation: OperationDelete}, 0, 0, []BrokenRelation{}},
+		{"merge", 2, Simulation{Operation: OperationMerge, IntoID: 5}, 2, 3, []BrokenRelation{}},
+		{"merge out of the ACL", 2, Simulation{Operation: OperationMerge, IntoID: 6}, 2, 2, []BrokenRelation{
+			{Type: RelationACL, ComponentID: 3, RelatedID: 1, Principal: EveryonePrincipal},
+			{Type: RelationACL, ComponentID: 3, RelatedID: 1, Principal: "alice"},
+			{Type: RelationPublic, ComponentID: 3, RelatedID: 1},
+		}},
+	} {
+		impact, err := cache.Simulate(tc.id, tc.sim)
+		if err != nil {
+			t.Errorf("%s: Simulate failed: %v", tc.name, err)
+			continue
+		}
+		if impact.Operation != tc.sim.Operation || impact.ComponentID != tc.id {
+			t.Errorf("%s: expected %s of %d, got %s of %d", tc.name, tc.sim.Operation, tc.id, impact.Operation, impact.ComponentID)
+		}
+		if impact.AffectedDescendants != tc.affected || impact.ResultingDepth != tc.depth {
+			t.Errorf("%s: expected %d affected at depth %d, got %d at depth %d", tc.name, tc.affected, tc.depth, impact.AffectedDescendants, impact.ResultingDepth)
+		}
+		if !reflect.DeepEqual(impact.BrokenRelations, tc.broken) {
+			t.Errorf("%s: expected broken relations %+v, got %+v", tc.name, tc.broken, impact.BrokenRelations)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		id      int64
+		sim     Simulation
+		invalid bool
+	}{
+		{"missing component", 99, Simulation{Operation: OperationDelete}, false},
+		{"missing new parent", 2, move(99), false},
+		{"missing merge target", 2, Simulation{Operation: OperationMerge, IntoID: 99}, false},
+		{"move under itself", 2, move(2), true},
+		{"move under a descendant", 2, move(4), true},
+		{"merge into itself", 2, Simulation{Operation: OperationMerge, IntoID: 2}, true},
+		{"merge into a descendant", 2, Simulation{Operation: OperationMerge, IntoID: 3}, true},
+		{"unknown operation", 2, Simulation{Operation: "copy"}, true},
+	} {
+		_, err := cache.Simulate(tc.id, tc.sim)
+		if tc.invalid && !errors.Is(err, ErrInvalidSimulation) {
+			t.Errorf("%s: expected ErrInvalidSimulation, got %v", tc.name, err)
+		}
+		if !tc.invalid && (err == nil || !strings.Contains(err.Error(), "not found")) {
+			t.Errorf("%s: expected a not found error, got %v", tc.name, err)
+		}
+	}
+}
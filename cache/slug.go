@@ -0,0 +1,30 @@
+package cache
+
+import "component-service/models"
+
+// indexSlug adds a component to slugIndex. Components without a slug are not indexed. Assumes the
+// write lock is held.
+func (c *ComponentCache) indexSlug(component *models.Component) {
+	if component.Slug != "" {
+		c.slugIndex[component.Slug] = component.ID
+	}
+}
+
+// unindexSlug removes a component from slugIndex, unless another component holds its slug by now.
+// Assumes the write lock is held.
+func (c *ComponentCache) unindexSlug(component *models.Component) {
+	if id, found := c.slugIndex[component.Slug]; found && id == component.ID {
+		delete(c.slugIndex, component.Slug)
+	}
+}
+
+// GetBySlug retrieves a component by its slug from the cache.
+func (c *ComponentCache) GetBySlug(slug string) (*models.Component, bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	id, found := c.slugIndex[slug]
+	if !found {
+		return nil, false
+	}
+	return c.readOut(c.componentsByID[id]), true
+}
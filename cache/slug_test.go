@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"component-service/models"
+	"testing"
+)
+
+func TestComponentCache_GetBySlug(t *testing.T) {
+	slugged := func(id, parentID int64, slug string) *models.Component {
+		comp := aclTestComponent(id, parentID)
+		comp.Slug = slug
+		return comp
+	}
+	c, err := LoadComponentCache(&MockComponentStore{mockComponents: []*models.Component{
+		slugged(1, 0, "plant"), slugged(2, 1, "pump"), slugged(3, 2, "valve"), aclTestComponent(4, 0),
+	}}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadComponentCache failed: %v", err)
+	}
+	expectSlug := func(slug string, wantID int64) {
+		t.Helper()
+		comp, found := c.GetBySlug(slug)
+		switch {
+		case wantID == 0 && found:
+			t.Errorf("GetBySlug(%q) = %d; expected none", slug, comp.ID)
+		case wantID != 0 && (!found || comp.ID != wantID):
+			t.Errorf("GetBySlug(%q) = %v, %v; expected %d", slug, comp, found, wantID)
+		}
+	}
+	expectSlug("pump", 2)
+	expectSlug("", 0) // components without a slug are not indexed
+
+	// A rename keeps the slug; a new slug replaces the old one.
+	renamed := *slugged(2, 1, "pump")
+	renamed.Name = "Feed pump"
+	c.Set(&renamed)
+	expectSlug("pump", 2)
+	renamed.Slug = "feed-pump"
+	c.Set(&renamed)
+	expectSlug("pump", 0)
+	expectSlug("feed-pump", 2)
+
+	// Deleting frees the slug; the orphaned child keeps its own.
+	c.Delete(2)
+	expectSlug("feed-pump", 0)
+	expectSlug("valve", 3)
+	c.DeleteSubtree(1)
+	expectSlug("plant", 0)
+	expectSlug("valve", 3)
+
+	// A purged component's slug can be given to a new one.
+	c.Delete(3)
+	c.Set(slugged(5, 0, "valve"))
+	expectSlug("valve", 5)
+}
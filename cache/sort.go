@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+	"time"
+)
+
+// Sort fields accepted by list endpoints.
+const (
+	SortByName      = "name"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+
+	// sortByPosition orders siblings as arranged with POST /components/{id}/reorder. Positions
+	// only compare between siblings, so it is not offered to list endpoints.
+	sortByPosition = "position"
+)
+
+// SortFields lists the accepted Sort.Field values.
+var SortFields = []string{SortByName, SortByCreatedAt, SortByUpdatedAt}
+
+// Sort orders list results by one field, breaking ties by ID in the same direction. The zero
+// value keeps each listing's default order.
+type Sort struct {
+	Field      string // one of SortFields, or "" for the default order
+	Descending bool
+}
+
+// siblingOrder is the default order of child listings and trees: by position, then oldest first,
+// as in the database.
+var siblingOrder = Sort{Field: sortByPosition}
+
+// IsZero reports whether the listing keeps its default order.
+func (s Sort) IsZero() bool {
+	return s.Field == ""
+}
+
+// sortKey is a component's value for a sort field, extracted once per sort.
+type sortKey struct {
+	name     string
+	position int64
+	at       time.Time
+}
+
+func (s Sort) keyOf(component *models.Component) sortKey {
+	switch s.Field {
+	case SortByName:
+		return sortKey{name: component.Name}
+	case SortByUpdatedAt:
+		at, _ := time.Parse(time.RFC3339Nano, component.UpdatedAt)
+		return sortKey{at: at}
+	case sortByPosition:
+		at, _ := time.Parse(time.RFC3339Nano, component.CreatedAt)
+		return sortKey{position: component.Position, at: at}
+	default:
+		at, _ := time.Parse(time.RFC3339Nano, component.CreatedAt)
+		return sortKey{at: at}
+	}
+}
+
+// sorted returns components in s order, leaving the input untouched; the zero Sort returns the
+// input itself. Timestamps compare as instants, not as strings, since cached timestamps may carry
+// different UTC offsets.
+func (s Sort) sorted(components []*models.Component) []*models.Component {
+	if s.IsZero() || len(components) < 2 {
+		return components
+	}
+	type keyed struct {
+		key       sortKey
+		component *models.Component
+	}
+	entries := make([]keyed, len(components))
+	for i, comp := range components {
+		entries[i] = keyed{key: s.keyOf(comp), component: comp}
+	}
+	less := func(a, b keyed) bool {
+		if s.Field == SortByName {
+			if a.key.name != b.key.name {
+				return a.key.name < b.key.name
+			}
+		} else if a.key.position != b.key.position {
+			return a.key.position < b.key.position
+		} else if !a.key.at.Equal(b.key.at) {
+			return a.key.at.Before(b.key.at)
+		}
+		return a.component.ID < b.component.ID
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if s.Descending {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+	result := make([]*models.Component, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.component
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"component-service/models"
+	"reflect"
+	"testing"
+)
+
+func TestComponentCache_Sort(t *testing.T) {
+	components := []*models.Component{
+		{ID: 1, Name: "Root", ParentID: invalidNullInt64(), CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-05T00:00:00Z"},
+		{ID: 2, Name: "beta", ParentID: nullInt64(1), CreatedAt: "2024-01-03T00:00:00Z", UpdatedAt: "2024-01-03T00:00:00Z"},
+		// Same instant as component 2, written with another UTC offset.
+		{ID: 3, Name: "Alpha", ParentID: nullInt64(1), CreatedAt: "2024-01-03T02:00:00+02:00", UpdatedAt: "2024-01-04T00:00:00Z"},
+		{ID: 4, Name: "Alpha", ParentID: nullInt64(1), CreatedAt: "2024-01-02T00:00:00Z", UpdatedAt: "2024-01-02T00:00:00Z"},
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	cache := GlobalComponentCache
+
+	tests := []struct {
+		order    Sort
+		expected []int64
+	}{
+		{Sort{}, []int64{3, 2, 4, 1}}, // newest first, as the database lists them
+		{Sort{Field: SortByName}, []int64{3, 4, 1, 2}},
+		{Sort{Field: SortByName, Descending: true}, []int64{2, 1, 4, 3}},
+		{Sort{Field: SortByCreatedAt}, []int64{1, 4, 2, 3}},
+		{Sort{Field: SortByUpdatedAt, Descending: true}, []int64{1, 3, 2, 4}},
+	}
+	for _, tt := range tests {
+		body, err := cache.AllJSON(Filter{}, tt.order, 0, 0)
+		if err != nil {
+			t.Fatalf("%+v: AllJSON failed: %v", tt.order, err)
+		}
+		if ids := pageIDs(t, body); !reflect.DeepEqual(ids, tt.expected) {
+			t.Errorf("%+v: expected %v, got %v", tt.order, tt.expected, ids)
+		}
+	}
+
+	// Sorting applies before paging, and never reorders the cache itself.
+	body, _ := cache.ChildrenJSON(1, Filter{}, Sort{Field: SortByCreatedAt, Descending: true}, 1, 1)
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{2}) {
+		t.Errorf("Expected the second child by newest first to be [2], got %v", ids)
+	}
+	body, _ = cache.ChildrenJSON(1, Filter{}, Sort{}, 0, 0)
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{4, 2, 3}) {
+		t.Errorf("Expected children oldest first by default, [4 2 3], got %v", ids)
+	}
+	if children, _ := cache.GetChildren(1); children[0].ID != 4 {
+		t.Errorf("Expected GetChildren oldest first, got %d first", children[0].ID)
+	}
+	body, _ = cache.AllJSON(Filter{}, Sort{}, 1, 2)
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{2, 4}) {
+		t.Errorf("Expected the default order's second page of 2 to be [2 4], got %v", ids)
+	}
+}
+
+func TestComponentCache_SiblingOrder(t *testing.T) {
+	// Positions come first; equal positions fall back to creation order, then ID.
+	components := []*models.Component{
+		{ID: 1, Name: "Root", ParentID: invalidNullInt64(), CreatedAt: "2024-01-01T00:00:00Z"},
+		{ID: 2, Name: "First created", ParentID: nullInt64(1), Position: 2, CreatedAt: "2024-01-02T00:00:00Z"},
+		{ID: 3, Name: "Moved up", ParentID: nullInt64(1), Position: 0, CreatedAt: "2024-01-04T00:00:00Z"},
+		{ID: 4, Name: "Tied, older", ParentID: nullInt64(1), Position: 1, CreatedAt: "2024-01-03T00:00:00Z"},
+		{ID: 5, Name: "Tied, newer", ParentID: nullInt64(1), Position: 1, CreatedAt: "2024-01-05T00:00:00Z"},
+	}
+	c := NewComponentCacheWithConfig(DefaultConfig())
+	c.SetMany(components)
+
+	children, _ := c.GetChildren(1)
+	var ids []int64
+	for _, child := range children {
+		ids = append(ids, child.ID)
+	}
+	if expected := []int64{3, 4, 5, 2}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("GetChildren: expected %v, got %v", expected, ids)
+	}
+	body, err := c.ChildrenJSON(1, Filter{}, Sort{}, 0, 0)
+	if err != nil {
+		t.Fatalf("ChildrenJSON failed: %v", err)
+	}
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{3, 4, 5, 2}) {
+		t.Errorf("ChildrenJSON: expected [3 4 5 2], got %v", ids)
+	}
+	body, err = c.ChildrenJSON(1, Filter{}, Sort{Field: SortByCreatedAt}, 0, 0)
+	if err != nil {
+		t.Fatalf("ChildrenJSON failed: %v", err)
+	}
+	if ids := pageIDs(t, body); !reflect.DeepEqual(ids, []int64{2, 4, 3, 5}) {
+		t.Errorf("ChildrenJSON by created_at: expected [2 4 3 5], got %v", ids)
+	}
+}
@@ -0,0 +1,152 @@
+//go:build stress
+
+package cache
+
+import (
+	"component-service/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The stress tests run only with the stress build tag, for STRESS_DURATION (default 10s), and are
+// meant for the race detector:
+//
+//	go test -tags stress -race -run Stress ./cache ./api
+//
+// They check locking changes: a data race fails the run, and so does an index found inconsistent.
+
+// stressDuration reads STRESS_DURATION, a Go duration.
+func stressDuration(t *testing.T) time.Duration {
+	value := os.Getenv("STRESS_DURATION")
+	if value == "" {
+		return 10 * time.Second
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		t.Fatalf("invalid STRESS_DURATION %q: %v", value, err)
+	}
+	return d
+}
+
+// TestStress_ComponentCache runs random writes from several goroutines against readers calling
+// the cache's reads. Every 50th write verifies the indexes against the writers' model. Writers take
+// turns, as database transactions serialize their writes; readers take no lock but the cache's own.
+func TestStress_ComponentCache(t *testing.T) {
+	const writers, readers, maxComponents = 4, 8, 300
+	c := NewComponentCache()
+	m := &cacheModel{components: make(map[int64]models.Component)}
+	var writeMu sync.Mutex // guards m, writes and checks
+	writes, checks := 0, 0
+	ctx, stop := context.WithTimeout(context.Background(), stressDuration(t))
+	defer stop()
+	var wg sync.WaitGroup
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				op := cacheOp{Kind: uint8(rng.Intn(256)), A: uint8(rng.Intn(256)), B: uint8(rng.Intn(256))}
+				writeMu.Lock()
+				if rng.Intn(2) == 0 && len(m.components) < maxComponents {
+					op.Kind = 0 // create half the time, so the tree grows despite deletes
+				}
+				m.apply(c, op)
+				if writes++; writes%50 == 0 {
+					if err := checkCacheInvariants(c, m); err != nil {
+						t.Errorf("after %d writes: %v", writes, err)
+						stop()
+					}
+					checks++
+				}
+				writeMu.Unlock()
+			}
+		}(rand.New(rand.NewSource(int64(i))))
+	}
+
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if err := stressRead(c, rng); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(rand.New(rand.NewSource(int64(writers + i))))
+	}
+
+	wg.Wait()
+	t.Logf("%d writes, %d integrity checks; %d components at the end", writes, checks, c.Count())
+}
+
+// stressRead makes one random read of c and returns an error when its result is inconsistent in
+// itself: a child listed under another parent, JSON that does not parse, a page longer than its
+// total. Reads racing writes may miss components, so misses are not errors.
+func stressRead(c *ComponentCache, rng *rand.Rand) error {
+	id := rng.Int63n(int64(c.Count())+10) + 1
+	var body []byte
+	var err error
+	switch rng.Intn(12) {
+	case 0:
+		children, _ := c.GetChildren(id)
+		for _, child := range children {
+			if !child.ParentID.Valid || child.ParentID.Int64 != id {
+				return fmt.Errorf("GetChildren(%d) returned component %d under %v", id, child.ID, child.ParentID)
+			}
+		}
+	case 1:
+		all := c.GetAll()
+		seen := make(map[int64]bool, len(all))
+		for _, comp := range all {
+			if seen[comp.ID] {
+				return fmt.Errorf("GetAll returned component %d twice", comp.ID)
+			}
+			seen[comp.ID] = true
+		}
+	case 2:
+		body, err = c.AllJSON(Filter{Name: "pump"}, Sort{}, 0, 20)
+	case 3:
+		body, err = c.ChildrenJSON(id, Filter{}, Sort{}, 0, 0)
+	case 4:
+		var total int
+		body, total, err = c.DescendantsJSON(id, rng.Intn(3), Filter{}, 0, 10)
+		var page []json.RawMessage
+		if err == nil && json.Unmarshal(body, &page) == nil && len(page) > total {
+			return fmt.Errorf("DescendantsJSON(%d) returned %d components of %d", id, len(page), total)
+		}
+	case 5:
+		body, err = c.TreeJSON(id, TreeOptions{MaxDepth: 3})
+	case 6:
+		body, err = c.ForestJSON(TreeOptions{MaxNodes: 100})
+	case 7:
+		body, err = c.FlatJSON()
+	case 8:
+		checkpoint := c.Checkpoint(false)
+		if _, err := c.Delta(checkpoint.Checkpoint); err != nil {
+			return fmt.Errorf("Delta from a fresh checkpoint: %w", err)
+		}
+	case 9:
+		c.SubtreeHash(id)
+		c.Search("pump", "", 10)
+		c.CountMatching(Filter{NameContains: "u"})
+	case 10:
+		c.Simulate(id, Simulation{Operation: OperationDelete})
+		c.ResolvePath([]string{"pump", "valve"}, nil, false)
+		c.GetBySlug(fmt.Sprintf("component-%d", id))
+	default:
+		err = c.WriteSnapshot(io.Discard)
+	}
+	if err == nil && body != nil && !json.Valid(body) {
+		return fmt.Errorf("read returned invalid JSON: %s", body)
+	}
+	return nil
+}
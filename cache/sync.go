@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"component-service/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syncJournalLimit is the number of change records kept for delta sync. Checkpoints older than
+// the retained window must be replaced by a full download.
+var syncJournalLimit = 10000
+
+var (
+	// ErrInvalidCheckpoint is returned for a checkpoint token this cache could not have issued.
+	ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+	// ErrCheckpointExpired is returned when the changes since a checkpoint are no longer retained,
+	// either because the journal moved past it or because the cache was rebuilt (e.g. on restart).
+	ErrCheckpointExpired = errors.New("checkpoint expired")
+)
+
+// SubtreeHash is the Merkle hash of the subtree rooted at a component.
+type SubtreeHash struct {
+	ID   int64  `json:"id"`
+	Hash string `json:"hash"`
+}
+
+// SyncCheckpoint identifies the cache state a client has synchronized to.
+type SyncCheckpoint struct {
+	Checkpoint string              `json:"checkpoint"` // opaque token to pass as ?since= on the next delta
+	Hash       string              `json:"hash"`       // hash over every root subtree
+	Roots      []SubtreeHash       `json:"roots"`
+	Components []*models.Component `json:"components,omitempty"`
+}
+
+// SyncDelta lists what changed between two checkpoints. Upserted holds the current state of every
+// component created, modified or moved since the old checkpoint; Deleted holds removed IDs.
+// Branches gives the new hash of each root subtree containing an upserted component, so a client
+// can verify the branches it patched; Hash verifies the whole copy.
+type SyncDelta struct {
+	Since      string              `json:"since"`
+	Checkpoint string              `json:"checkpoint"`
+	Hash       string              `json:"hash"`
+	Upserted   []*models.Component `json:"upserted"`
+	Deleted    []int64             `json:"deleted"`
+	Branches   []SubtreeHash       `json:"branches"`
+}
+
+// syncJournal records the IDs of changed components in sequence order. Assumes the cache's lock.
+type syncJournal struct {
+	epoch   string      // distinguishes cache generations so tokens from before a rebuild are rejected
+	seq     uint64      // sequence number of the latest change
+	floor   uint64      // changes after this sequence number are fully retained
+	entries []int64     // entries[i] is the component changed at sequence floor+1+i
+	trimmed func(n int) // called with the number of entries dropped, when not nil
+}
+
+func newSyncJournal() syncJournal {
+	return syncJournal{epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// record appends a change, dropping the oldest half of the journal once it exceeds the limit.
+func (j *syncJournal) record(id int64) {
+	j.seq++
+	j.entries = append(j.entries, id)
+	if len(j.entries) > syncJournalLimit {
+		drop := len(j.entries) - syncJournalLimit/2
+		j.floor += uint64(drop)
+		j.entries = append([]int64(nil), j.entries[drop:]...)
+		if j.trimmed != nil {
+			j.trimmed(drop)
+		}
+	}
+}
+
+func (j *syncJournal) token() string {
+	return fmt.Sprintf("%s.%d", j.epoch, j.seq)
+}
+
+// changedSince returns the distinct IDs changed after the sequence encoded in token.
+func (j *syncJournal) changedSince(token string) ([]int64, error) {
+	epoch, seqText, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidCheckpoint
+	}
+	since, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCheckpoint
+	}
+	if epoch != j.epoch {
+		return nil, ErrCheckpointExpired
+	}
+	if since > j.seq {
+		return nil, ErrInvalidCheckpoint
+	}
+	if since < j.floor {
+		return nil, ErrCheckpointExpired
+	}
+	seen := make(map[int64]struct{})
+	var ids []int64
+	for _, id := range j.entries[since-j.floor:] {
+		if _, dup := seen[id]; !dup {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Generation identifies the state of the cache: it changes with every change to a component, an
+// ACL or a public flag, and when the cache is rebuilt. A response computed from the cache can be
+// tagged with the generation it was read at, and served again while the generation holds.
+func (c *ComponentCache) Generation() string {
+	c.rlock()
+	defer c.mu.RUnlock()
+	return fmt.Sprintf("%s.%d.%d", c.journal.epoch, c.journal.seq, c.accessChanges)
+}
+
+// Checkpoint returns a token for the current cache state with its root subtree hashes and,
+// when includeComponents is set, every component, all taken under one read lock so the data and
+// token are consistent.
+func (c *ComponentCache) Checkpoint(includeComponents bool) SyncCheckpoint {
+	c.rlock()
+	defer c.mu.RUnlock()
+
+	hashes := c.hashByID
+	checkpoint := SyncCheckpoint{
+		Checkpoint: c.journal.token(),
+		Hash:       hexHash(c.forestHash(hashes)),
+		Roots:      []SubtreeHash{},
+	}
+	for _, root := range sortedByID(c.childrenByParentID[RootParentIDKey]) {
+		checkpoint.Roots = append(checkpoint.Roots, SubtreeHash{ID: root.ID, Hash: hexHash(hashes[root.ID])})
+	}
+	if includeComponents {
+		checkpoint.Components = make([]*models.Component, 0, len(c.allComponents))
+		for _, comp := range c.allComponents {
+			compCopy := *comp
+			checkpoint.Components = append(checkpoint.Components, &compCopy)
+		}
+	}
+	return checkpoint
+}
+
+// Delta returns the changes since the given checkpoint token.
+func (c *ComponentCache) Delta(since string) (*SyncDelta, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+
+	ids, err := c.journal.changedSince(since)
+	if err != nil {
+		return nil, err
+	}
+	hashes := c.hashByID
+	delta := &SyncDelta{
+		Since:      since,
+		Checkpoint: c.journal.token(),
+		Hash:       hexHash(c.forestHash(hashes)),
+		Upserted:   []*models.Component{},
+		Deleted:    []int64{},
+		Branches:   []SubtreeHash{},
+	}
+	branchRoots := make(map[int64]struct{})
+	for _, id := range ids {
+		comp, exists := c.componentsByID[id]
+		if !exists {
+			delta.Deleted = append(delta.Deleted, id)
+			continue
+		}
+		compCopy := *comp
+		delta.Upserted = append(delta.Upserted, &compCopy)
+		if rootID, ok := c.rootOf(comp); ok {
+			branchRoots[rootID] = struct{}{}
+		}
+	}
+	for rootID := range branchRoots {
+		delta.Branches = append(delta.Branches, SubtreeHash{ID: rootID, Hash: hexHash(hashes[rootID])})
+	}
+	sortSubtreeHashes(delta.Branches)
+	return delta, nil
+}
+
+// rootOf walks parent links to the top-level ancestor of comp. It reports false if the chain is
+// broken or cyclic. Assumes the read lock is held.
+func (c *ComponentCache) rootOf(comp *models.Component) (int64, bool) {
+	for steps := 0; steps <= len(c.componentsByID); steps++ {
+		if !comp.ParentID.Valid {
+			return comp.ID, true
+		}
+		parent, exists := c.componentsByID[comp.ParentID.Int64]
+		if !exists {
+			return 0, false
+		}
+		comp = parent
+	}
+	return 0, false
+}
+
+func sortSubtreeHashes(hashes []SubtreeHash) {
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].ID < hashes[j].ID })
+}
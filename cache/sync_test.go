@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"component-service/models"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newSyncTestCache(t *testing.T) *ComponentCache {
+	t.Helper()
+	c1 := *comp1Global // root
+	c2 := *comp2Global // child of 1
+	c3 := *comp3Global // child of 1
+	c4 := *comp4Global // root
+	c5 := *comp5Global // child of 4
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c1, &c2, &c3, &c4, &c5}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	return GlobalComponentCache
+}
+
+func TestSubtreeHashes(t *testing.T) {
+	cache := newSyncTestCache(t)
+	before := cache.Checkpoint(false)
+	if len(before.Roots) != 2 || before.Roots[0].ID != 1 || before.Roots[1].ID != 4 {
+		t.Fatalf("Expected roots 1 and 4 in ID order, got %+v", before.Roots)
+	}
+
+	// The same content loaded in a different order hashes identically.
+	c5 := *comp5Global
+	c4 := *comp4Global
+	c3 := *comp3Global
+	c2 := *comp2Global
+	c1 := *comp1Global
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: []*models.Component{&c5, &c4, &c3, &c2, &c1}}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	reordered := GlobalComponentCache.Checkpoint(false)
+	if reordered.Hash != before.Hash || !reflect.DeepEqual(reordered.Roots, before.Roots) {
+		t.Errorf("Expected identical hashes regardless of load order")
+	}
+
+	// Changing a grandchild-level field changes its root's hash and the forest hash only.
+	renamed := c5
+	renamed.Name = "Comp 5 renamed"
+	GlobalComponentCache.Set(&renamed)
+	after := GlobalComponentCache.Checkpoint(false)
+	if after.Hash == before.Hash {
+		t.Error("Expected the forest hash to change")
+	}
+	if after.Roots[0].Hash != before.Roots[0].Hash {
+		t.Error("Expected the untouched subtree under root 1 to keep its hash")
+	}
+	if after.Roots[1].Hash == before.Roots[1].Hash {
+		t.Error("Expected the subtree under root 4 to change hash")
+	}
+}
+
+func TestIncrementalHashesMatchFullRecompute(t *testing.T) {
+	cache := newSyncTestCache(t)
+	assertConsistent := func(step string) {
+		t.Helper()
+		full := cache.subtreeHashes()
+		if !reflect.DeepEqual(cache.hashByID, full) {
+			t.Errorf("%s: incremental hashes diverged from a full recompute", step)
+		}
+	}
+	assertConsistent("init")
+
+	renamed := *comp5Global
+	renamed.Name = "Comp 5 renamed"
+	cache.Set(&renamed)
+	assertConsistent("rename leaf")
+
+	moved := *comp2Global
+	moved.ParentID = nullInt64(5)
+	cache.Set(&moved)
+	assertConsistent("move subtree")
+
+	cache.Set(&models.Component{ID: 8, Name: "Comp 8", ParentID: nullInt64(2)})
+	assertConsistent("add grandchild")
+
+	cache.Delete(5) // 2 becomes a root, taking 8 with it
+	assertConsistent("delete with orphans")
+
+	before, _ := cache.SubtreeHash(2)
+	cache.Delete(8)
+	after, _ := cache.SubtreeHash(2)
+	if before == after {
+		t.Error("Expected deleting a child to change its parent's subtree hash")
+	}
+	assertConsistent("delete leaf")
+
+	if _, found := cache.SubtreeHash(8); found {
+		t.Error("Expected no hash for a deleted component")
+	}
+}
+
+func TestSyncDelta(t *testing.T) {
+	cache := newSyncTestCache(t)
+	start := cache.Checkpoint(true)
+	if len(start.Components) != 5 {
+		t.Fatalf("Expected 5 components in checkpoint, got %d", len(start.Components))
+	}
+
+	empty, err := cache.Delta(start.Checkpoint)
+	if err != nil {
+		t.Fatalf("Delta failed: %v", err)
+	}
+	if len(empty.Upserted) != 0 || len(empty.Deleted) != 0 || empty.Hash != start.Hash || empty.Checkpoint != start.Checkpoint {
+		t.Errorf("Expected an empty delta for the current checkpoint, got %+v", empty)
+	}
+
+	moved := *comp3Global
+	moved.ParentID = nullInt64(4)
+	cache.Set(&moved)
+	cache.Set(&moved) // repeated changes are reported once
+	cache.Delete(2)
+	added := &models.Component{ID: 7, Name: "Comp 7", ParentID: nullInt64(5)}
+	cache.Set(added)
+
+	delta, err := cache.Delta(start.Checkpoint)
+	if err != nil {
+		t.Fatalf("Delta failed: %v", err)
+	}
+	var upsertedIDs []int64
+	for _, comp := range delta.Upserted {
+		upsertedIDs = append(upsertedIDs, comp.ID)
+	}
+	if !reflect.DeepEqual(upsertedIDs, []int64{3, 7}) {
+		t.Errorf("Expected upserted IDs [3 7], got %v", upsertedIDs)
+	}
+	if !reflect.DeepEqual(delta.Deleted, []int64{2}) {
+		t.Errorf("Expected deleted IDs [2], got %v", delta.Deleted)
+	}
+	if len(delta.Branches) != 1 || delta.Branches[0].ID != 4 {
+		t.Errorf("Expected the branch under root 4 to be reported, got %+v", delta.Branches)
+	}
+
+	current := cache.Checkpoint(false)
+	if delta.Checkpoint != current.Checkpoint || delta.Hash != current.Hash {
+		t.Errorf("Expected delta to end at the current checkpoint")
+	}
+}
+
+func TestSyncDeltaCheckpointErrors(t *testing.T) {
+	defer func(previous int) { syncJournalLimit = previous }(syncJournalLimit)
+	syncJournalLimit = 4
+
+	cache := newSyncTestCache(t)
+	start := cache.Checkpoint(false)
+	for i := 0; i < 5; i++ {
+		renamed := *comp1Global
+		renamed.Description = string(rune('a' + i))
+		cache.Set(&renamed)
+	}
+
+	tests := []struct {
+		name    string
+		since   string
+		wantErr error
+	}{
+		{name: "trimmed from journal", since: start.Checkpoint, wantErr: ErrCheckpointExpired},
+		{name: "previous cache generation", since: "otherepoch.1", wantErr: ErrCheckpointExpired},
+		{name: "malformed", since: "not-a-token", wantErr: ErrInvalidCheckpoint},
+		{name: "from the future", since: cache.journal.epoch + ".999", wantErr: ErrInvalidCheckpoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := cache.Delta(tt.since); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Delta(%q) error = %v, expected %v", tt.since, err, tt.wantErr)
+			}
+		})
+	}
+
+	recent := cache.journal.epoch + ".4"
+	if _, err := cache.Delta(recent); err != nil {
+		t.Errorf("Expected a retained checkpoint to be served, got %v", err)
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	cache := newSyncTestCache(t)
+	start := cache.Generation()
+	if cache.Generation() != start {
+		t.Fatalf("Expected the generation to hold while nothing changes")
+	}
+	seen := map[string]bool{start: true}
+	changes := []func(){
+		func() { cache.Set(&models.Component{ID: 7, Name: "Comp 7"}) },
+		func() { cache.Delete(7) },
+		func() { cache.SetACL(1, []ACLEntry{{Principal: "alice", Permission: PermissionRead}}) },
+		func() { cache.SetPublic(4, true) },
+	}
+	for i, change := range changes {
+		change()
+		generation := cache.Generation()
+		if seen[generation] {
+			t.Errorf("Change %d: expected a new generation, got %q again", i, generation)
+		}
+		seen[generation] = true
+	}
+	if rebuilt := newSyncTestCache(t); seen[rebuilt.Generation()] {
+		t.Errorf("Expected a rebuilt cache to start a new generation")
+	}
+}
@@ -0,0 +1,28 @@
+package cache
+
+import "component-service/models"
+
+// indexTags adds a component to tagIndex under each of its tags. Assumes the write lock is held.
+func (c *ComponentCache) indexTags(component *models.Component) {
+	for _, tag := range component.Tags {
+		c.tagIndex[tag] = append(c.tagIndex[tag], component.ID)
+	}
+}
+
+// unindexTags removes a component from tagIndex. Assumes the write lock is held.
+func (c *ComponentCache) unindexTags(component *models.Component) {
+	for _, tag := range component.Tags {
+		ids := c.tagIndex[tag]
+		for i, id := range ids {
+			if id == component.ID {
+				ids = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(c.tagIndex, tag)
+		} else {
+			c.tagIndex[tag] = ids
+		}
+	}
+}
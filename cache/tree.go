@@ -0,0 +1,300 @@
+package cache
+
+import (
+	"bytes"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrTreeTooLarge is returned when a tree holds more components than the caller allows.
+var ErrTreeTooLarge = errors.New("tree too large")
+
+// TreeOptions shapes a nested tree. MaxDepth > 0 stops the tree that many levels below its roots,
+// whose components are written without "children", marking them as not expanded. Keep, when not
+// nil, hides the components it rejects: one with a descendant Keep accepts, at any depth, is
+// written as a placeholder holding only its ID and "hidden":true, so the descendant stays
+// reachable where it is in the tree; one without is left out with its subtree. Grafts, when not
+// nil, gives the subtrees of other caches to attach under a component, after its own children, and
+// is not asked about hidden components. A tree of more than MaxNodes components, placeholders and
+// grafts included, fails with ErrTreeTooLarge.
+type TreeOptions struct {
+	MaxDepth int
+	MaxNodes int
+	Keep     func(id int64) bool
+	Grafts   func(id int64) []Graft
+}
+
+// Graft is a subtree of another cache attached under a component of this one, such as a subtree
+// of another instance mounted read-only. Its root's object gains Mark's fields, such as
+// `"mount":"team-a"`, telling clients where the foreign IDs come from. Keep and Grafts do not apply
+// inside it.
+type Graft struct {
+	Cache  *ComponentCache
+	RootID int64
+	Mark   string
+}
+
+// treeWriter encodes trees as nested JSON: each component's object gains a "children" array
+// holding its children's objects, in sibling order, and so on down. baseDepth is the depth of the roots
+// within the whole tree, which is not zero for a graft, and nodes counts the components written,
+// across grafts.
+type treeWriter struct {
+	TreeOptions
+	children  func(id int64) []*models.Component
+	fragment  func(comp *models.Component) []byte // nil falls back to json.Marshal
+	mark      string                              // added to the roots' objects
+	baseDepth int
+	nodes     *int
+	reaches   map[int64]bool // whether a hidden component has a kept descendant, by ID
+}
+
+// appendTrees appends the trees rooted at roots to buf, hiding the components Keep rejects as
+// TreeOptions describes. The walk is iterative, so deep trees cannot overflow the stack, and visits
+// each component once, so a parent cycle cannot loop it.
+func (t treeWriter) appendTrees(buf []byte, roots []*models.Component) ([]byte, error) {
+	type frame struct {
+		id       int64
+		hidden   bool
+		children []*models.Component
+		next     int
+	}
+	if t.nodes == nil {
+		t.nodes = new(int)
+	}
+	if t.reaches == nil {
+		t.reaches = make(map[int64]bool)
+	}
+	var stack []frame
+	visited := make(map[int64]bool)
+	enter := func(comp *models.Component) error {
+		if *t.nodes == t.MaxNodes {
+			return fmt.Errorf("%w: more than %d components", ErrTreeTooLarge, t.MaxNodes)
+		}
+		*t.nodes++
+		visited[comp.ID] = true
+		hidden := t.Keep != nil && !t.Keep(comp.ID)
+		var fragment []byte
+		if hidden {
+			fragment = []byte(`{"id":` + strconv.FormatInt(comp.ID, 10) + `,"hidden":true}`)
+		} else if t.fragment != nil {
+			fragment = t.fragment(comp)
+		}
+		if fragment == nil {
+			var err error
+			if fragment, err = json.Marshal(comp); err != nil {
+				return err
+			}
+		}
+		if t.mark != "" && len(stack) == 0 { // fragment is shared with the cache, so it is copied
+			marked := append([]byte{}, bytes.TrimSuffix(fragment, []byte("}"))...)
+			fragment = append(append(marked, ','), t.mark+"}"...)
+		}
+		if t.MaxDepth > 0 && t.baseDepth+len(stack) == t.MaxDepth { // the stack holds comp's ancestors
+			buf = append(buf, fragment...)
+			return nil
+		}
+		buf = append(buf, bytes.TrimSuffix(fragment, []byte("}"))...)
+		buf = append(buf, `,"children":[`...)
+		stack = append(stack, frame{id: comp.ID, hidden: hidden, children: t.visible(t.children(comp.ID), visited)})
+		return nil
+	}
+
+	for i, root := range t.visible(roots, visited) {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if err := enter(root); err != nil {
+			return nil, err
+		}
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.next == len(top.children) {
+				if !top.hidden {
+					var err error
+					if buf, err = t.appendGrafts(buf, top.id, top.next > 0, len(stack)); err != nil {
+						return nil, err
+					}
+				}
+				buf = append(buf, "]}"...)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if top.next > 0 {
+				buf = append(buf, ',')
+			}
+			child := top.children[top.next]
+			top.next++
+			if err := enter(child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf, nil
+}
+
+// appendGrafts appends the subtrees grafted under id, at depth levels below the roots, to its
+// children array, which already holds children when hasChildren is set.
+func (t treeWriter) appendGrafts(buf []byte, id int64, hasChildren bool, depth int) ([]byte, error) {
+	if t.Grafts == nil {
+		return buf, nil
+	}
+	for _, graft := range t.Grafts(id) {
+		if graft.Cache == nil {
+			continue
+		}
+		graft.Cache.rlock()
+		root, found := graft.Cache.componentsByID[graft.RootID]
+		if !found {
+			graft.Cache.mu.RUnlock()
+			continue
+		}
+		if hasChildren {
+			buf = append(buf, ',')
+		}
+		w := graft.Cache.treeWriter(TreeOptions{MaxDepth: t.MaxDepth, MaxNodes: t.MaxNodes})
+		w.mark, w.baseDepth, w.nodes = graft.Mark, t.baseDepth+depth, t.nodes
+		var err error
+		buf, err = w.appendTrees(buf, []*models.Component{root})
+		graft.Cache.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		hasChildren = true
+	}
+	return buf, nil
+}
+
+// visible orders components as siblings, leaving out those already visited and those Keep rejects
+// without a descendant it accepts.
+func (t treeWriter) visible(components []*models.Component, visited map[int64]bool) []*models.Component {
+	sorted := append([]*models.Component{}, siblingOrder.sorted(components)...)
+	kept := sorted[:0]
+	for _, comp := range sorted {
+		if !visited[comp.ID] && (t.Keep == nil || t.Keep(comp.ID) || t.reachesKept(comp.ID)) {
+			kept = append(kept, comp)
+		}
+	}
+	return kept
+}
+
+// reachesKept reports whether a component has a descendant Keep accepts. The search stops at the
+// first one, and every component on the path down to it is remembered as reaching it; a subtree
+// searched in full is remembered as not reaching any. Each component is thus searched about once
+// per tree, however deeply hidden components nest.
+func (t treeWriter) reachesKept(id int64) bool {
+	if reaches, known := t.reaches[id]; known {
+		return reaches
+	}
+	type frame struct {
+		id       int64
+		children []*models.Component
+		next     int
+	}
+	stack := []frame{{id: id, children: t.children(id)}}
+	searched := map[int64]bool{id: true}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.children) {
+			t.reaches[top.id] = false
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		child := top.children[top.next]
+		top.next++
+		reaches, known := t.reaches[child.ID]
+		if !known && !searched[child.ID] {
+			if t.Keep(child.ID) {
+				reaches = true
+			} else {
+				searched[child.ID] = true
+				stack = append(stack, frame{id: child.ID, children: t.children(child.ID)})
+				continue
+			}
+		}
+		if reaches {
+			for _, ancestor := range stack {
+				t.reaches[ancestor.id] = true
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// TreeJSON returns the subtree rooted at rootID as a nested JSON object, shaped by opts, or null
+// when opts.Keep rejects the root and every component below it.
+func (c *ComponentCache) TreeJSON(rootID int64, opts TreeOptions) ([]byte, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	root, found := c.componentsByID[rootID]
+	if !found {
+		return nil, fmt.Errorf("component with ID %d not found", rootID)
+	}
+	return nullWhenEmpty(c.treeWriter(opts).appendTrees(nil, []*models.Component{root}))
+}
+
+// ForestJSON returns every root component's tree as a JSON array of nested objects, like
+// TreeJSON. The roots are in sibling order.
+func (c *ComponentCache) ForestJSON(opts TreeOptions) ([]byte, error) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	body, err := c.treeWriter(opts).appendTrees([]byte{'['}, c.childrenByParentID[RootParentIDKey])
+	if err != nil {
+		return nil, err
+	}
+	return append(body, ']'), nil
+}
+
+// treeWriter writes trees from the cache's children index and pre-marshaled fragments. Assumes the
+// read lock is held while it is used.
+func (c *ComponentCache) treeWriter(opts TreeOptions) treeWriter {
+	return treeWriter{
+		TreeOptions: opts,
+		children:    func(id int64) []*models.Component { return c.childrenByParentID[id] },
+		fragment:    func(comp *models.Component) []byte { return c.jsonByID[comp.ID] },
+	}
+}
+
+// TreeJSONOf nests components loaded elsewhere, such as from the database: like TreeJSON when
+// rootID is valid, and like ForestJSON otherwise. components must hold every component of the
+// tree, or of the forest, down to opts.MaxDepth.
+func TreeJSONOf(components []*models.Component, rootID sql.NullInt64, opts TreeOptions) ([]byte, error) {
+	childrenByParentID := make(map[int64][]*models.Component)
+	var roots []*models.Component
+	for _, comp := range components {
+		if rootID.Valid && comp.ID == rootID.Int64 {
+			roots = append(roots, comp)
+		} else if comp.ParentID.Valid {
+			childrenByParentID[comp.ParentID.Int64] = append(childrenByParentID[comp.ParentID.Int64], comp)
+		} else if !rootID.Valid {
+			roots = append(roots, comp)
+		}
+	}
+	t := treeWriter{
+		TreeOptions: opts,
+		children:    func(id int64) []*models.Component { return childrenByParentID[id] },
+	}
+	if rootID.Valid {
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("component with ID %d not found", rootID.Int64)
+		}
+		return nullWhenEmpty(t.appendTrees(nil, roots))
+	}
+	body, err := t.appendTrees([]byte{'['}, roots)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, ']'), nil
+}
+
+// nullWhenEmpty turns the output of appendTrees for a single root that was left out into null.
+func nullWhenEmpty(body []byte, err error) ([]byte, error) {
+	if err == nil && len(body) == 0 {
+		return []byte("null"), nil
+	}
+	return body, err
+}
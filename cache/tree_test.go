@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComponentCache_TreeJSON(t *testing.T) {
+	// 1 -> 3 -> 4, 1 -> 2, and 5 on its own.
+	components := []*models.Component{
+		aclTestComponent(1, 0), aclTestComponent(3, 1), aclTestComponent(2, 1), aclTestComponent(4, 3), aclTestComponent(5, 0),
+	}
+	if err := InitGlobalCache(&MockComponentStore{mockComponents: components}); err != nil {
+		t.Fatalf("InitGlobalCache failed: %v", err)
+	}
+	c := GlobalComponentCache
+	node := func(id, children string) string {
+		parent := `{"Int64":0,"Valid":false}`
+		switch id {
+		case "2", "3":
+			parent = `{"Int64":1,"Valid":true}`
+		case "4":
+			parent = `{"Int64":3,"Valid":true}`
+		}
+		return `{"id":` + id + `,"name":"Comp","description":"","parent_id":` + parent + `,"position":0,"children":[` + children + `]}`
+	}
+	unexpanded := func(id string) string {
+		return strings.TrimSuffix(node(id, ""), `,"children":[]}`) + "}"
+	}
+
+	body, err := c.TreeJSON(1, TreeOptions{MaxNodes: 10})
+	if err != nil {
+		t.Fatalf("TreeJSON failed: %v", err)
+	}
+	want := node("1", node("2", "")+","+node("3", node("4", "")))
+	if string(body) != want {
+		t.Errorf("TreeJSON(1) = %s; want %s", body, want)
+	}
+
+	body, err = c.ForestJSON(TreeOptions{MaxNodes: 10, Keep: func(id int64) bool { return id != 3 && id != 4 }})
+	if err != nil {
+		t.Fatalf("ForestJSON failed: %v", err)
+	}
+	want = "[" + node("1", node("2", "")) + "," + node("5", "") + "]"
+	if string(body) != want {
+		t.Errorf("ForestJSON hiding 3 and 4 = %s; want %s", body, want)
+	}
+
+	// A hidden component above a visible one stays as a placeholder.
+	body, err = c.ForestJSON(TreeOptions{MaxNodes: 10, Keep: func(id int64) bool { return id != 3 }})
+	if err != nil {
+		t.Fatalf("ForestJSON failed: %v", err)
+	}
+	want = "[" + node("1", node("2", "")+`,{"id":3,"hidden":true,"children":[`+node("4", "")+"]}") + "," + node("5", "") + "]"
+	if string(body) != want {
+		t.Errorf("ForestJSON hiding 3 = %s; want %s", body, want)
+	}
+
+	// Components at the depth limit are written without "children".
+	body, err = c.TreeJSON(1, TreeOptions{MaxDepth: 1, MaxNodes: 10})
+	if err != nil {
+		t.Fatalf("TreeJSON with a depth failed: %v", err)
+	}
+	if want := node("1", unexpanded("2")+","+unexpanded("3")); string(body) != want {
+		t.Errorf("TreeJSON(1, depth 1) = %s; want %s", body, want)
+	}
+
+	if _, err := c.TreeJSON(1, TreeOptions{MaxNodes: 3}); !errors.Is(err, ErrTreeTooLarge) {
+		t.Errorf("TreeJSON over the limit returned %v; want ErrTreeTooLarge", err)
+	}
+	if _, err := c.TreeJSON(99, TreeOptions{MaxNodes: 10}); err == nil {
+		t.Error("TreeJSON of a missing component succeeded")
+	}
+
+	// The database fallback nests the same way.
+	body, err = TreeJSONOf(components, nullInt64(1), TreeOptions{MaxNodes: 10})
+	if err != nil {
+		t.Fatalf("TreeJSONOf failed: %v", err)
+	}
+	if want := node("1", node("2", "")+","+node("3", node("4", ""))); string(body) != want {
+		t.Errorf("TreeJSONOf(1) = %s; want %s", body, want)
+	}
+}
+
+func TestComponentCache_TreeJSONMixedVisibility(t *testing.T) {
+	// A chain 1 -> 2 -> ... -> 210, each with a leaf child 1000+i. Only the chain's multiples of 50
+	// and the leaves under multiples of 40 are visible, so nothing below 200 is.
+	var components []*models.Component
+	for i := int64(1); i <= 210; i++ {
+		components = append(components, aclTestComponent(i, i-1), aclTestComponent(1000+i, i))
+	}
+	c := NewComponentCacheWithConfig(DefaultConfig())
+	c.SetMany(components)
+	keep := func(id int64) bool {
+		if id > 1000 {
+			return (id-1000)%40 == 0
+		}
+		return id%50 == 0
+	}
+	var calls int
+	counted := func(id int64) bool {
+		calls++
+		return keep(id)
+	}
+
+	body, err := c.TreeJSON(1, TreeOptions{MaxNodes: 1000, Keep: counted})
+	if err != nil {
+		t.Fatalf("TreeJSON failed: %v", err)
+	}
+	type node struct {
+		ID       int64  `json:"id"`
+		Hidden   bool   `json:"hidden"`
+		Name     string `json:"name"`
+		Children []node `json:"children"`
+	}
+	var tree node
+	if err := json.Unmarshal(body, &tree); err != nil {
+		t.Fatalf("TreeJSON returned invalid JSON: %v", err)
+	}
+
+	// Hidden components carry no name and stay where they are, so every visible one is under its
+	// real parent.
+	seen := make(map[int64]bool)
+	var walk func(n node)
+	walk = func(n node) {
+		seen[n.ID] = true
+		if n.Hidden == keep(n.ID) {
+			t.Errorf("Component %d has hidden=%t", n.ID, n.Hidden)
+		}
+		if n.Hidden && n.Name != "" {
+			t.Errorf("Placeholder %d reveals its name", n.ID)
+		}
+		if n.Hidden && len(n.Children) == 0 {
+			t.Errorf("Placeholder %d has nothing visible under it", n.ID)
+		}
+		for _, child := range n.Children {
+			if child.ID != n.ID+1 && child.ID != n.ID+1000 {
+				t.Errorf("Component %d is under %d", child.ID, n.ID)
+			}
+			walk(child)
+		}
+	}
+	walk(tree)
+	for _, comp := range components {
+		if keep(comp.ID) && !seen[comp.ID] {
+			t.Errorf("Visible component %d is missing from the tree", comp.ID)
+		}
+	}
+	if seen[1001] || seen[1199] || seen[201] {
+		t.Error("Expected hidden components without visible descendants to be left out")
+	}
+	if !seen[199] {
+		t.Error("Expected the hidden components above 200 to be kept as placeholders")
+	}
+	if calls > 4*len(components) {
+		t.Errorf("Keep was called %d times for %d components; want each searched about once", calls, len(components))
+	}
+
+	// At the depth limit a placeholder is written without children, to be expanded later.
+	body, err = c.TreeJSON(1, TreeOptions{MaxDepth: 1, MaxNodes: 10, Keep: keep})
+	if err != nil {
+		t.Fatalf("TreeJSON with a depth failed: %v", err)
+	}
+	if want := `{"id":1,"hidden":true,"children":[{"id":2,"hidden":true}]}`; string(body) != want {
+		t.Errorf("TreeJSON(1, depth 1) = %s; want %s", body, want)
+	}
+
+	// A hidden root with nothing visible under it is null.
+	body, err = c.TreeJSON(1001, TreeOptions{MaxNodes: 10, Keep: keep})
+	if err != nil || string(body) != "null" {
+		t.Errorf("TreeJSON(1001) = %s, %v; want null", body, err)
+	}
+
+	// The database fallback hides the same way.
+	fromDB, err := TreeJSONOf(components, nullInt64(1), TreeOptions{MaxNodes: 1000, Keep: keep})
+	if err != nil {
+		t.Fatalf("TreeJSONOf failed: %v", err)
+	}
+	var fromDBTree node
+	if err := json.Unmarshal(fromDB, &fromDBTree); err != nil {
+		t.Fatalf("TreeJSONOf returned invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(tree, fromDBTree) {
+		t.Error("TreeJSONOf hid components differently from TreeJSON")
+	}
+}
+
+func TestComponentCache_TreeJSONGrafts(t *testing.T) {
+	// Locally 1 -> 2; the remote cache holds 7 -> 8 -> 9, with 7 grafted under 2.
+	local := NewComponentCacheWithConfig(DefaultConfig())
+	local.SetMany([]*models.Component{aclTestComponent(1, 0), aclTestComponent(2, 1)})
+	remote, err := LoadComponentCache(&MockComponentStore{mockComponents: []*models.Component{
+		aclTestComponent(7, 0), aclTestComponent(8, 7), aclTestComponent(9, 8),
+	}}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadComponentCache failed: %v", err)
+	}
+	grafts := func(id int64) []Graft {
+		if id == 2 {
+			return []Graft{{Cache: remote, RootID: 7, Mark: `"mount":"team-a"`}}
+		}
+		return nil
+	}
+
+	body, err := local.TreeJSON(1, TreeOptions{MaxDepth: 3, MaxNodes: 10, Grafts: grafts})
+	if err != nil {
+		t.Fatalf("TreeJSON with grafts failed: %v", err)
+	}
+	want := `{"id":1,"name":"Comp","description":"","parent_id":{"Int64":0,"Valid":false},"position":0,"children":[` +
+		`{"id":2,"name":"Comp","description":"","parent_id":{"Int64":1,"Valid":true},"position":0,"children":[` +
+		`{"id":7,"name":"Comp","description":"","parent_id":{"Int64":0,"Valid":false},"position":0,"mount":"team-a","children":[` +
+		`{"id":8,"name":"Comp","description":"","parent_id":{"Int64":7,"Valid":true},"position":0}]}]}]}`
+	if string(body) != want {
+		t.Errorf("TreeJSON(1) with grafts = %s; want %s", body, want)
+	}
+	if fragment := string(remote.jsonByID[7]); strings.Contains(fragment, "mount") {
+		t.Errorf("Marking a graft modified the cached fragment: %s", fragment)
+	}
+
+	// Grafted components count toward the limit.
+	if _, err := local.TreeJSON(1, TreeOptions{MaxNodes: 4, Grafts: grafts}); !errors.Is(err, ErrTreeTooLarge) {
+		t.Errorf("TreeJSON with grafts over the limit returned %v; want ErrTreeTooLarge", err)
+	}
+}
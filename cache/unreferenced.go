@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"component-service/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// viewLog records when each component was last read on its own, by ID, slug or path. Reads are
+// not persisted, so it only covers the time since the cache was created.
+type viewLog struct {
+	mu    sync.Mutex
+	since time.Time
+	at    map[int64]time.Time
+}
+
+func newViewLog() viewLog {
+	return viewLog{since: time.Now().UTC(), at: make(map[int64]time.Time)}
+}
+
+// RecordView notes that a component was read now, so Unreferenced does not report it as idle.
+// It takes its own lock rather than the cache's, so reads do not contend with writers.
+func (c *ComponentCache) RecordView(id int64) {
+	c.views.mu.Lock()
+	c.views.at[id] = time.Now().UTC()
+	c.views.mu.Unlock()
+}
+
+// ViewsRecordedSince returns when the cache started recording views. A component read only
+// before then looks as if it was never viewed.
+func (c *ComponentCache) ViewsRecordedSince() time.Time {
+	return c.views.since
+}
+
+// forgetViews drops the recorded views of deleted components.
+func (c *ComponentCache) forgetViews(ids ...int64) {
+	c.views.mu.Lock()
+	for _, id := range ids {
+		delete(c.views.at, id)
+	}
+	c.views.mu.Unlock()
+}
+
+// Unreferenced returns the components nothing depends on that have sat idle since idleSince,
+// ordered by ID: leaves with no children, that refer to no other component and are referred to by
+// none, with no ACL entries or public flag of their own, neither created, updated nor viewed
+// after idleSince.
+func (c *ComponentCache) Unreferenced(idleSince time.Time) []*models.Component {
+	c.views.mu.Lock()
+	viewedAt := make(map[int64]time.Time, len(c.views.at))
+	for id, at := range c.views.at {
+		viewedAt[id] = at
+	}
+	c.views.mu.Unlock()
+
+	c.rlock()
+	defer c.mu.RUnlock()
+	var unreferenced []*models.Component
+	for id, comp := range c.componentsByID {
+		if len(c.childrenByParentID[id]) > 0 || len(c.aclByID[id]) > 0 || c.publicIDs[id] {
+			continue
+		}
+		if viewedAt[id].After(idleSince) || changedAfter(comp.CreatedAt, idleSince) || changedAfter(comp.UpdatedAt, idleSince) {
+			continue
+		}
+		if c.referenced(comp) || len(comp.Links()) > 0 {
+			continue
+		}
+		unreferenced = append(unreferenced, c.readOut(comp))
+	}
+	sort.Slice(unreferenced, func(i, j int) bool { return unreferenced[i].ID < unreferenced[j].ID })
+	return unreferenced
+}
+
+// referenced reports whether another component refers to comp, by ID or by slug. Assumes the read
+// lock is held.
+func (c *ComponentCache) referenced(comp *models.Component) bool {
+	refs := [2]models.ComponentRef{{ID: comp.ID}, {Slug: comp.Slug}}
+	for i, ref := range refs {
+		if i == 1 && comp.Slug == "" {
+			break
+		}
+		for _, sourceID := range c.linkIndex[ref] {
+			if sourceID != comp.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// changedAfter reports whether an RFC 3339 timestamp is after t. One that does not parse counts as
+// after, so a component of unknown age is never reported idle.
+func changedAfter(timestamp string, t time.Time) bool {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	return err != nil || parsed.After(t)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"component-service/models"
+	"testing"
+	"time"
+)
+
+func TestComponentCache_Unreferenced(t *testing.T) {
+	const old, recent = "2020-01-01T00:00:00Z", "2030-01-01T00:00:00Z"
+	idleSince := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dated := func(id, parentID int64, slug, description, updatedAt string) *models.Component {
+		comp := aclTestComponent(id, parentID)
+		comp.Slug, comp.Description = slug, description
+		comp.CreatedAt, comp.UpdatedAt = old, updatedAt
+		return comp
+	}
+	// 1 has a child; 2 is updated recently; 3 refers to 4 by slug; 5 and 6 are idle leaves; 7 has no
+	// timestamps.
+	c, err := LoadComponentCache(&MockComponentStore{mockComponents: []*models.Component{
+		dated(1, 0, "", "", old),
+		dated(2, 1, "", "", recent),
+		dated(3, 1, "", "Wired to [[relay]].", old),
+		dated(4, 0, "relay", "", old),
+		dated(5, 0, "", "", old),
+		dated(6, 0, "", "", old),
+		aclTestComponent(7, 0),
+	}}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadComponentCache failed: %v", err)
+	}
+	expectUnreferenced := func(want ...int64) {
+		t.Helper()
+		var got []int64
+		for _, comp := range c.Unreferenced(idleSince) {
+			got = append(got, comp.ID)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Unreferenced() = %v; expected %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Unreferenced() = %v; expected %v", got, want)
+			}
+		}
+	}
+	expectUnreferenced(5, 6)
+
+	// A view since idleSince keeps a component off the report, until it is deleted.
+	c.RecordView(6)
+	expectUnreferenced(5)
+	c.Delete(6)
+	c.views.mu.Lock()
+	_, kept := c.views.at[6]
+	c.views.mu.Unlock()
+	if kept {
+		t.Errorf("Expected the views of a deleted component to be forgotten")
+	}
+
+	// Dropping the reference frees both ends; a component with its own ACL entries is kept.
+	c.Set(dated(3, 1, "", "Wired to a relay.", old))
+	expectUnreferenced(3, 4, 5)
+	c.SetACL(5, []ACLEntry{{ComponentID: 5, Principal: "alice", Permission: PermissionRead}})
+	expectUnreferenced(3, 4)
+}
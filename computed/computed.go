@@ -0,0 +1,228 @@
+// Package computed evaluates server-computed component fields. Each field is defined once by an
+// expression over the component's own fields, other computed fields and rollups over its
+// children, for example
+//
+//	health = worst(children.health, "ok", "degraded", "down")
+//	size   = 1 + sum(children.size)
+//
+// Values are computed only when asked for, and memoized by a key that changes whenever the
+// component or anything below it does, so unchanged subtrees are not evaluated twice.
+package computed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// OwnFields are the component fields expressions can read. They are the fields a subtree hash
+// covers, so memoizing by subtree hash stays exact.
+var OwnFields = []string{"id", "name", "description"}
+
+// maxMemoEntries bounds the memo. Entries for superseded subtree states are never looked up
+// again, so rather than tracking them the memo is simply cleared when it fills up.
+const maxMemoEntries = 100000
+
+// Node is a component as the evaluator sees it.
+type Node interface {
+	// Field returns one of OwnFields: id as a float64, name and description as strings.
+	Field(name string) Value
+	Children() []Node
+	// Key identifies the state of the component and its whole subtree: equal keys must mean
+	// equal computed values. An empty key disables memoization for the node.
+	Key() string
+}
+
+// Fields is a set of computed field definitions, with the memo of their values.
+type Fields struct {
+	names []string
+	exprs map[string]expr
+
+	mu   sync.Mutex
+	memo map[memoKey]Value
+}
+
+type memoKey struct {
+	node  string // Node.Key
+	field string
+}
+
+// Parse compiles field definitions, mapping each field name to its expression. It rejects
+// syntax errors, unknown names, names that shadow own fields and fields that depend on each
+// other in a cycle. Rollups may refer to any field, since they evaluate it one level down.
+func Parse(definitions map[string]string) (*Fields, error) {
+	f := &Fields{exprs: make(map[string]expr, len(definitions)), memo: make(map[memoKey]Value)}
+	for name, source := range definitions {
+		if isOwnField(name) || name == "children" {
+			return nil, fmt.Errorf("computed field %q: the name is reserved", name)
+		}
+		e, err := parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("computed field %q: %w", name, err)
+		}
+		f.names = append(f.names, name)
+		f.exprs[name] = e
+	}
+	sort.Strings(f.names)
+	for _, name := range f.names {
+		if err := f.checkReferences(name, nil); err != nil {
+			return nil, fmt.Errorf("computed field %q: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+// FromEnv loads definitions from the JSON file named by COMPUTED_FIELDS_FILE, an object mapping
+// field names to expressions. It returns nil Fields when the variable is unset.
+func FromEnv() (*Fields, error) {
+	path := os.Getenv("COMPUTED_FIELDS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading COMPUTED_FIELDS_FILE: %w", err)
+	}
+	var definitions map[string]string
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return nil, fmt.Errorf("error parsing COMPUTED_FIELDS_FILE %s: expected an object of field names to expressions: %w", path, err)
+	}
+	return Parse(definitions)
+}
+
+// Names returns the computed field names in sorted order.
+func (f *Fields) Names() []string {
+	return append([]string(nil), f.names...)
+}
+
+// Evaluate computes every field for a node. A field whose expression fails, say by adding a
+// string to a number, fails the whole evaluation.
+func (f *Fields) Evaluate(node Node) (map[string]Value, error) {
+	e := f.evaluation(node)
+	values := make(map[string]Value, len(f.names))
+	for _, name := range f.names {
+		value, err := e.field(name)
+		if err != nil {
+			return nil, fmt.Errorf("computed field %q: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// checkReferences walks the names an expression reads on its own node, failing on unknown names
+// and on cycles. path holds the fields being checked further up.
+func (f *Fields) checkReferences(name string, path []string) error {
+	for _, seen := range path {
+		if seen == name {
+			return fmt.Errorf("cycle through %v", append(path, name))
+		}
+	}
+	var err error
+	walk(f.exprs[name], func(e expr) {
+		if err != nil {
+			return
+		}
+		switch e := e.(type) {
+		case *fieldRef:
+			if isOwnField(e.name) {
+				return
+			}
+			if _, known := f.exprs[e.name]; !known {
+				err = fmt.Errorf("unknown field %q", e.name)
+				return
+			}
+			err = f.checkReferences(e.name, append(path, name))
+		case *rollup:
+			if _, known := f.exprs[e.field]; !known && !isOwnField(e.field) {
+				err = fmt.Errorf("unknown field %q in children.%s", e.field, e.field)
+			}
+		}
+	})
+	return err
+}
+
+// walk calls visit on e and every expression inside it.
+func walk(e expr, visit func(expr)) {
+	visit(e)
+	switch e := e.(type) {
+	case *unaryOp:
+		walk(e.operand, visit)
+	case *binaryOp:
+		walk(e.left, visit)
+		walk(e.right, visit)
+	case *call:
+		for _, arg := range e.args {
+			walk(arg, visit)
+		}
+	}
+}
+
+func isOwnField(name string) bool {
+	for _, own := range OwnFields {
+		if name == own {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fields) lookup(key memoKey) (Value, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, found := f.memo[key]
+	return value, found
+}
+
+func (f *Fields) remember(key memoKey, value Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.memo) >= maxMemoEntries {
+		f.memo = make(map[memoKey]Value)
+	}
+	f.memo[key] = value
+}
+
+// evaluation computes fields for one node, keeping the values it has computed so far.
+type evaluation struct {
+	fields *Fields
+	node   Node
+	values map[string]Value
+}
+
+func (f *Fields) evaluation(node Node) *evaluation {
+	return &evaluation{fields: f, node: node, values: make(map[string]Value)}
+}
+
+func (e *evaluation) child(node Node) *evaluation {
+	return e.fields.evaluation(node)
+}
+
+// field returns an own field, or a computed field from this evaluation, the memo, or its
+// expression, in that order.
+func (e *evaluation) field(name string) (Value, error) {
+	if isOwnField(name) {
+		return e.node.Field(name), nil
+	}
+	if value, done := e.values[name]; done {
+		return value, nil
+	}
+	key := memoKey{node: e.node.Key(), field: name}
+	if key.node != "" {
+		if value, found := e.fields.lookup(key); found {
+			e.values[name] = value
+			return value, nil
+		}
+	}
+	value, err := e.fields.exprs[name].eval(e)
+	if err != nil {
+		return nil, err
+	}
+	e.values[name] = value
+	if key.node != "" {
+		e.fields.remember(key, value)
+	}
+	return value, nil
+}
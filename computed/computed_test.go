@@ -0,0 +1,141 @@
+package computed
+
+import (
+	"strings"
+	"testing"
+)
+
+// testNode is an in-memory component tree.
+type testNode struct {
+	id       float64
+	name     string
+	children []*testNode
+	key      string
+}
+
+func (n *testNode) Field(name string) Value {
+	switch name {
+	case "id":
+		return n.id
+	case "name":
+		return n.name
+	}
+	return ""
+}
+
+func (n *testNode) Children() []Node {
+	nodes := make([]Node, len(n.children))
+	for i, child := range n.children {
+		nodes[i] = child
+	}
+	return nodes
+}
+
+func (n *testNode) Key() string { return n.key }
+
+func TestParseErrors(t *testing.T) {
+	for definitions, want := range map[[2]string]string{
+		{"a", "1 +"}:                   "unexpected end",
+		{"a", "nope(1)"}:               "unknown function",
+		{"a", "missing + 1"}:           "unknown field",
+		{"a", "max(children.missing)"}: "unknown field",
+		{"a", "len(children.name)"}:    "can only be aggregated",
+		{"a", "max(name)"}:             "first argument must be children",
+		{"a", "if(true, 1)"}:           "takes 3 arguments",
+		{"a", "a + 1"}:                 "cycle",
+		{"name", "1"}:                  "reserved",
+		{"a", `"unterminated`}:         "unterminated string",
+		{"a", "1 2"}:                   "unexpected",
+	} {
+		_, err := Parse(map[string]string{definitions[0]: definitions[1]})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%s = %s) error = %v; want it to mention %q", definitions[0], definitions[1], err, want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	fields, err := Parse(map[string]string{
+		"size":   "1 + sum(children.size)",
+		"health": `if(count(children) == 0, if(name == "broken", "down", "ok"), worst(children.health, "ok", "degraded", "down"))`,
+		"label":  `concat(upper(name), " (", size, ")")`,
+		"mean":   "avg(children.id)",
+		"empty":  "max(children.name)",
+		"ratio":  "id / 0",
+		"flag":   `!(size > 1) || name != "root"`,
+	})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	leaf := &testNode{id: 3, name: "broken"}
+	mid := &testNode{id: 2, name: "mid", children: []*testNode{leaf}}
+	root := &testNode{id: 1, name: "root", children: []*testNode{mid, {id: 4, name: "fine"}}}
+	values, err := fields.Evaluate(root)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	for name, want := range map[string]Value{
+		"size": 4.0, "health": "down", "label": "ROOT (4)", "mean": 3.0, "empty": "mid", "ratio": nil, "flag": false,
+	} {
+		if values[name] != want {
+			t.Errorf("%s = %#v; want %#v", name, values[name], want)
+		}
+	}
+
+	values, err = fields.Evaluate(leaf)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if values["empty"] != nil || values["mean"] != nil || values["size"] != 1.0 {
+		t.Errorf("aggregates over no children = %v, %v, %v; want nil, nil, 1", values["empty"], values["mean"], values["size"])
+	}
+}
+
+func TestEvaluateTypeError(t *testing.T) {
+	fields, err := Parse(map[string]string{"bad": "name + 1"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = fields.Evaluate(&testNode{id: 1, name: "x"})
+	if err == nil || !strings.Contains(err.Error(), `computed field "bad"`) {
+		t.Errorf("Evaluate error = %v; want a type error naming the field", err)
+	}
+}
+
+// countingNode wraps a testNode and counts how often its children are listed, which happens once
+// per rollup that is actually evaluated rather than read from the memo.
+type countingNode struct {
+	*testNode
+	listed *int
+}
+
+func (n countingNode) Children() []Node {
+	*n.listed++
+	return n.testNode.Children()
+}
+
+func TestEvaluateMemoizesByKey(t *testing.T) {
+	fields, err := Parse(map[string]string{"size": "1 + sum(children.size)"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	listed := 0
+	node := countingNode{&testNode{id: 1, key: "v1", children: []*testNode{{id: 2, key: "c"}}}, &listed}
+
+	for i := 0; i < 3; i++ {
+		if _, err := fields.Evaluate(node); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+	if listed != 1 {
+		t.Errorf("children listed %d times for an unchanged key; want 1", listed)
+	}
+	node.key = "v2"
+	if _, err := fields.Evaluate(node); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if listed != 2 {
+		t.Errorf("children listed %d times after the key changed; want 2", listed)
+	}
+}
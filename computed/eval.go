@@ -0,0 +1,393 @@
+package computed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Value is a computed value: float64, string, bool or nil. A rollup over children evaluates to
+// a []Value, which only aggregate functions accept.
+type Value interface{}
+
+type expr interface {
+	eval(e *evaluation) (Value, error)
+}
+
+type literal struct{ value Value }
+
+// fieldRef names one of the component's own fields or another computed field.
+type fieldRef struct{ name string }
+
+// rollup is children.field: the field's value for each direct child.
+type rollup struct{ field string }
+
+type unaryOp struct {
+	op      string
+	operand expr
+}
+
+type binaryOp struct {
+	op          string
+	left, right expr
+}
+
+type call struct {
+	name string
+	fn   function
+	args []expr
+}
+
+func (l *literal) eval(*evaluation) (Value, error) { return l.value, nil }
+
+func (f *fieldRef) eval(e *evaluation) (Value, error) { return e.field(f.name) }
+
+func (r *rollup) eval(e *evaluation) (Value, error) {
+	children := e.node.Children()
+	values := make([]Value, len(children))
+	for i, child := range children {
+		value, err := e.child(child).field(r.field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (u *unaryOp) eval(e *evaluation) (Value, error) {
+	operand, err := u.operand.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if u.op == "!" {
+		b, ok := operand.(bool)
+		if !ok {
+			return nil, fmt.Errorf("! needs a boolean, got %s", describe(operand))
+		}
+		return !b, nil
+	}
+	n, ok := operand.(float64)
+	if !ok {
+		return nil, fmt.Errorf("- needs a number, got %s", describe(operand))
+	}
+	return -n, nil
+}
+
+func (b *binaryOp) eval(e *evaluation) (Value, error) {
+	left, err := b.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	// && and || short-circuit, so the right side may guard against what the left rules out.
+	if b.op == "&&" || b.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs booleans, got %s", b.op, describe(left))
+		}
+		if l == (b.op == "||") {
+			return l, nil
+		}
+		right, err := b.right.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs booleans, got %s", b.op, describe(right))
+		}
+		return r, nil
+	}
+	right, err := b.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	switch b.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+	if b.op == "<" || b.op == "<=" || b.op == ">" || b.op == ">=" {
+		cmp, err := compare(left, right)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.op, err)
+		}
+		switch b.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s needs numbers, got %s and %s", b.op, describe(left), describe(right))
+	}
+	switch b.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		if r == 0 {
+			return nil, nil // division by zero has no value, like an aggregate over no children
+		}
+		return l / r, nil
+	}
+}
+
+func (c *call) eval(e *evaluation) (Value, error) {
+	args := make([]Value, len(c.args))
+	for i, arg := range c.args {
+		value, err := arg.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	value, err := c.fn.apply(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.name, err)
+	}
+	return value, nil
+}
+
+// check validates a call's arity, and that rollups appear exactly where the function aggregates.
+func (c *call) check() error {
+	if len(c.args) < c.fn.minArgs || (c.fn.maxArgs >= 0 && len(c.args) > c.fn.maxArgs) {
+		return fmt.Errorf("%s takes %s", c.name, c.fn.arity())
+	}
+	for i, arg := range c.args {
+		_, isRollup := arg.(*rollup)
+		if c.fn.aggregate && i == 0 && !isRollup {
+			return fmt.Errorf("%s aggregates over children; its first argument must be children or children.<field>", c.name)
+		}
+		if isRollup && (!c.fn.aggregate || i > 0) {
+			return fmt.Errorf("children can only be aggregated, as in max(children.<field>), not passed to %s", c.name)
+		}
+	}
+	return nil
+}
+
+// function is a built-in. An aggregate function takes a rollup as its first argument.
+type function struct {
+	minArgs, maxArgs int // maxArgs < 0 means any number
+	aggregate        bool
+	apply            func(args []Value) (Value, error)
+}
+
+func (f function) arity() string {
+	switch {
+	case f.maxArgs < 0:
+		return fmt.Sprintf("at least %d arguments", f.minArgs)
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d arguments", f.minArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", f.minArgs, f.maxArgs)
+	}
+}
+
+// functions are the built-ins expressions can call.
+var functions = map[string]function{
+	"count": {1, 1, true, func(args []Value) (Value, error) {
+		return float64(len(args[0].([]Value))), nil
+	}},
+	"sum": {1, 1, true, func(args []Value) (Value, error) {
+		numbers, err := numbersOf(args[0].([]Value))
+		total := 0.0
+		for _, n := range numbers {
+			total += n
+		}
+		return total, err
+	}},
+	"avg": {1, 1, true, func(args []Value) (Value, error) {
+		numbers, err := numbersOf(args[0].([]Value))
+		if err != nil || len(numbers) == 0 {
+			return nil, err
+		}
+		total := 0.0
+		for _, n := range numbers {
+			total += n
+		}
+		return total / float64(len(numbers)), nil
+	}},
+	"min":   {1, 1, true, func(args []Value) (Value, error) { return extreme(args[0].([]Value), -1) }},
+	"max":   {1, 1, true, func(args []Value) (Value, error) { return extreme(args[0].([]Value), 1) }},
+	"worst": {1, -1, true, func(args []Value) (Value, error) { return ranked(args, 1) }},
+	"best":  {1, -1, true, func(args []Value) (Value, error) { return ranked(args, -1) }},
+	"any": {1, 1, true, func(args []Value) (Value, error) {
+		for _, v := range args[0].([]Value) {
+			if v == true {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	"all": {1, 1, true, func(args []Value) (Value, error) {
+		for _, v := range args[0].([]Value) {
+			if v != true {
+				return false, nil
+			}
+		}
+		return true, nil
+	}},
+	"if": {3, 3, false, func(args []Value) (Value, error) {
+		cond, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("condition must be a boolean, got %s", describe(args[0]))
+		}
+		if cond {
+			return args[1], nil
+		}
+		return args[2], nil
+	}},
+	"coalesce": {1, -1, false, func(args []Value) (Value, error) {
+		for _, v := range args {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	}},
+	"concat": {1, -1, false, func(args []Value) (Value, error) {
+		var b strings.Builder
+		for _, v := range args {
+			if v != nil {
+				b.WriteString(fmt.Sprint(v))
+			}
+		}
+		return b.String(), nil
+	}},
+	"len": {1, 1, false, func(args []Value) (Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("needs a string, got %s", describe(args[0]))
+		}
+		return float64(len([]rune(s))), nil
+	}},
+	"lower": {1, 1, false, stringFunc(strings.ToLower)},
+	"upper": {1, 1, false, stringFunc(strings.ToUpper)},
+}
+
+func stringFunc(f func(string) string) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("needs a string, got %s", describe(args[0]))
+		}
+		return f(s), nil
+	}
+}
+
+// numbersOf returns the numbers in values, skipping nulls.
+func numbersOf(values []Value) ([]float64, error) {
+	numbers := make([]float64, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("needs numbers, got %s", describe(v))
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// extreme returns the smallest (sign -1) or largest (sign 1) non-null value, or nil if there is
+// none. Values must all be numbers or all be strings.
+func extreme(values []Value, sign int) (Value, error) {
+	var result Value
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if result == nil {
+			result = v
+			continue
+		}
+		cmp, err := compare(v, result)
+		if err != nil {
+			return nil, err
+		}
+		if cmp*sign > 0 {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+// ranked implements worst and best. With only the rollup it behaves like max and min. Further
+// arguments list values from best to worst, as in worst(children.health, "ok", "degraded",
+// "down"); a value missing from that list ranks worse than all of them.
+func ranked(args []Value, sign int) (Value, error) {
+	values := args[0].([]Value)
+	order := args[1:]
+	if len(order) == 0 {
+		return extreme(values, sign)
+	}
+	rank := func(v Value) int {
+		for i, candidate := range order {
+			if v == candidate {
+				return i
+			}
+		}
+		return len(order)
+	}
+	var result Value
+	found := false
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if !found || (rank(v)-rank(result))*sign > 0 {
+			result, found = v, true
+		}
+	}
+	return result, nil
+}
+
+// compare orders two numbers or two strings.
+func compare(a, b Value) (int, error) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1, nil
+			case a > b:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", describe(a), describe(b))
+}
+
+func describe(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return fmt.Sprintf("number %v", v)
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case []Value:
+		return "a list of children's values"
+	}
+	return fmt.Sprintf("%T", v)
+}
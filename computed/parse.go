@@ -0,0 +1,271 @@
+package computed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The expression grammar, loosest binding first:
+//
+//	expr    = and { "||" and }
+//	and     = compare { "&&" compare }
+//	compare = sum [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) sum ]
+//	sum     = product { ( "+" | "-" ) product }
+//	product = unary { ( "*" | "/" ) unary }
+//	unary   = ( "-" | "!" ) unary | primary
+//	primary = number | string | "true" | "false" | "null" | "(" expr ")"
+//	        | name | "children" [ "." name ] | name "(" [ expr { "," expr } ] ")"
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenName
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators lists the operator tokens, two-character ones first so they win over their prefixes.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "!", "(", ")", ",", "."}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(source); {
+		c := rune(source[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c >= '0' && c <= '9':
+			end := pos
+			for end < len(source) && (source[end] >= '0' && source[end] <= '9' || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{tokenNumber, source[pos:end], pos})
+			pos = end
+		case c == '"':
+			end := pos + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at offset %d", pos)
+			}
+			tokens = append(tokens, token{tokenString, source[pos : end+1], pos})
+			pos = end + 1
+		case c == '_' || unicode.IsLetter(c):
+			end := pos
+			for end < len(source) && (source[end] == '_' || unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{tokenName, source[pos:end], pos})
+			pos = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[pos:], op) {
+					tokens = append(tokens, token{tokenOperator, op, pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, pos)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// parser is a recursive-descent parser over the tokens of one expression.
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// parse parses a complete expression.
+func parse(source string) (expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.next] }
+
+// accept consumes the next token if it is one of the given operators.
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q at offset %d, found %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+// binaryLevel parses operand { op operand } for one precedence level.
+func (p *parser) binaryLevel(operand func() (expr, error), ops ...string) (expr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryOp{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) or() (expr, error)      { return p.binaryLevel(p.and, "||") }
+func (p *parser) and() (expr, error)     { return p.binaryLevel(p.compare, "&&") }
+func (p *parser) sum() (expr, error)     { return p.binaryLevel(p.product, "+", "-") }
+func (p *parser) product() (expr, error) { return p.binaryLevel(p.unary, "*", "/") }
+
+func (p *parser) compare() (expr, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryOp{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) unary() (expr, error) {
+	if op, ok := p.accept("-", "!"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryOp{op: op, operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.next++
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return &literal{value: value}, nil
+	case tokenString:
+		p.next++
+		value, err := strconv.Unquote(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s at offset %d", t.text, t.pos)
+		}
+		return &literal{value: value}, nil
+	case tokenName:
+		p.next++
+		return p.name(t)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if _, ok := p.accept("("); ok {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+// name parses what follows a name: a literal keyword, a rollup, a call or a field reference.
+func (p *parser) name(t token) (expr, error) {
+	switch t.text {
+	case "true":
+		return &literal{value: true}, nil
+	case "false":
+		return &literal{value: false}, nil
+	case "null":
+		return &literal{value: nil}, nil
+	case "children":
+		if _, ok := p.accept("."); !ok {
+			return &rollup{field: "id"}, nil
+		}
+		field := p.peek()
+		if field.kind != tokenName {
+			return nil, fmt.Errorf("expected a field name after \"children.\" at offset %d", field.pos)
+		}
+		p.next++
+		return &rollup{field: field.text}, nil
+	}
+	if _, ok := p.accept("("); !ok {
+		return &fieldRef{name: t.text}, nil
+	}
+	fn, known := functions[t.text]
+	if !known {
+		return nil, fmt.Errorf("unknown function %q at offset %d", t.text, t.pos)
+	}
+	c := &call{name: t.text, fn: fn}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	return c, c.check()
+}
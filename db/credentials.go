@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// PasswordSource supplies the database password. It is consulted every time the pool dials a
+// new physical connection, so sources backed by files or secret managers pick up rotated
+// credentials without a restart.
+type PasswordSource interface {
+	Password(ctx context.Context) (string, error)
+	// Invalidate drops any cached value after the database rejected it.
+	Invalidate()
+}
+
+// passwordSourceFromEnv picks the password source from the environment, in order of precedence:
+//   - DB_IAM_AUTH=rds: short-lived Amazon RDS IAM auth tokens, regenerated before they expire
+//   - DB_PASSWORD_FILE: file containing the password (Docker/Kubernetes secrets), re-read on every dial
+//   - VAULT_ADDR + DB_VAULT_SECRET_PATH: HashiCorp Vault secret, cached for DB_CREDENTIALS_TTL
+//   - DB_PASSWORD: static password
+func passwordSourceFromEnv(cfg ConnConfig) (PasswordSource, error) {
+	switch iamAuth := strings.ToLower(os.Getenv("DB_IAM_AUTH")); iamAuth {
+	case "":
+	case "rds":
+		return rdsIAMPasswordSourceFromEnv(cfg.Host, cfg.Port, cfg.User)
+	default:
+		return nil, fmt.Errorf("unsupported DB_IAM_AUTH %q (supported: rds)", iamAuth)
+	}
+	if path := os.Getenv("DB_PASSWORD_FILE"); path != "" {
+		return &FilePasswordSource{Path: path}, nil
+	}
+	if addr, secretPath := os.Getenv("VAULT_ADDR"), os.Getenv("DB_VAULT_SECRET_PATH"); addr != "" && secretPath != "" {
+		token, err := envOrFile("VAULT_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE is required when DB_VAULT_SECRET_PATH is set")
+		}
+		ttl := 5 * time.Minute
+		if ttlParam := os.Getenv("DB_CREDENTIALS_TTL"); ttlParam != "" {
+			ttl, err = time.ParseDuration(ttlParam)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DB_CREDENTIALS_TTL %q: %w", ttlParam, err)
+			}
+		}
+		field := os.Getenv("DB_VAULT_SECRET_FIELD")
+		if field == "" {
+			field = "password"
+		}
+		return &VaultPasswordSource{Addr: addr, Token: token, SecretPath: secretPath, Field: field, TTL: ttl}, nil
+	}
+	return StaticPasswordSource(os.Getenv("DB_PASSWORD")), nil
+}
+
+// envOrFile returns the value of the environment variable name, or the trimmed contents of
+// the file named by name+"_FILE" when that is set instead.
+func envOrFile(name string) (string, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading %s_FILE: %w", name, err)
+		}
+		return strings.TrimSpace(string(contents)), nil
+	}
+	return os.Getenv(name), nil
+}
+
+// StaticPasswordSource is a password that never changes.
+type StaticPasswordSource string
+
+func (s StaticPasswordSource) Password(ctx context.Context) (string, error) { return string(s), nil }
+func (s StaticPasswordSource) Invalidate()                                  {}
+
+// FilePasswordSource reads the password from a file on every call, so a secret rewritten in
+// place by the orchestrator is used by the next connection.
+type FilePasswordSource struct {
+	Path string
+}
+
+func (s *FilePasswordSource) Password(ctx context.Context) (string, error) {
+	contents, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading DB_PASSWORD_FILE %s: %w", s.Path, err)
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func (s *FilePasswordSource) Invalidate() {}
+
+// VaultPasswordSource reads the password from a HashiCorp Vault KV secret (v1 or v2) and
+// caches it for TTL.
+type VaultPasswordSource struct {
+	Addr       string
+	Token      string
+	SecretPath string // e.g. "secret/data/component-service/db"
+	Field      string
+	TTL        time.Duration
+	Client     *http.Client
+
+	mu        sync.Mutex
+	cached    string
+	fetchedAt time.Time
+}
+
+func (s *VaultPasswordSource) Password(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached != "" && time.Since(s.fetchedAt) < s.TTL {
+		return s.cached, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(s.Addr, "/")+"/v1/"+strings.TrimLeft(s.SecretPath, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("error building Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error reading secret from Vault: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding Vault response: %w", err)
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok { // KV v2 wraps the secret in data.data
+		data = nested
+	}
+	password, ok := data[s.Field].(string)
+	if !ok {
+		return "", fmt.Errorf("Vault secret %s has no string field %q", s.SecretPath, s.Field)
+	}
+
+	if s.cached != "" && s.cached != password {
+		log.Printf("Database credentials rotated in Vault secret %s", s.SecretPath)
+	}
+	s.cached = password
+	s.fetchedAt = time.Now()
+	return password, nil
+}
+
+func (s *VaultPasswordSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetchedAt = time.Time{}
+}
+
+// credentialConnector is a driver.Connector that resolves the password each time it dials,
+// retrying once with a fresh value if the cached one was rejected.
+type credentialConnector struct {
+	dialect  Dialect
+	cfg      ConnConfig
+	password PasswordSource
+	driver   driver.Driver
+}
+
+func newCredentialConnector(dialect Dialect, cfg ConnConfig, password PasswordSource) (*credentialConnector, error) {
+	// Build a throwaway connector only to learn which driver.Driver the dialect uses.
+	probe, err := dialect.Connector(dialect.DSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s connector: %w", dialect.Name(), err)
+	}
+	return &credentialConnector{dialect: dialect, cfg: cfg, password: password, driver: probe.Driver()}, nil
+}
+
+func (c *credentialConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connect(ctx)
+	if err != nil && isAuthError(err) {
+		c.password.Invalidate()
+		conn, err = c.connect(ctx)
+	}
+	return conn, err
+}
+
+func (c *credentialConnector) connect(ctx context.Context) (driver.Conn, error) {
+	password, err := c.password.Password(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg := c.cfg
+	cfg.Password = password
+	connector, err := c.dialect.Connector(c.dialect.DSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *credentialConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// isAuthError reports whether a dial failed because the credentials were rejected.
+func isAuthError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "28P01" || pqErr.Code == "28000"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1045
+	}
+	return false
+}
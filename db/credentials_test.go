@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilePasswordSource_PicksUpRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(path, []byte("first-secret\n"), 0600); err != nil {
+		t.Fatalf("Error writing password file: %v", err)
+	}
+	source := &FilePasswordSource{Path: path}
+
+	password, err := source.Password(context.Background())
+	if err != nil || password != "first-secret" {
+		t.Fatalf("Expected 'first-secret', got %q (err=%v)", password, err)
+	}
+
+	if err := os.WriteFile(path, []byte("second-secret"), 0600); err != nil {
+		t.Fatalf("Error rewriting password file: %v", err)
+	}
+	password, err = source.Password(context.Background())
+	if err != nil || password != "second-secret" {
+		t.Errorf("Expected rotated 'second-secret', got %q (err=%v)", password, err)
+	}
+
+	missing := &FilePasswordSource{Path: filepath.Join(t.TempDir(), "missing")}
+	if _, err := missing.Password(context.Background()); err == nil {
+		t.Error("Expected error for missing password file")
+	}
+}
+
+func TestEnvOrFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_user")
+	if err := os.WriteFile(path, []byte("  file-user \n"), 0600); err != nil {
+		t.Fatalf("Error writing user file: %v", err)
+	}
+
+	t.Setenv("TEST_DB_USER", "env-user")
+	if value, _ := envOrFile("TEST_DB_USER"); value != "env-user" {
+		t.Errorf("Expected env value 'env-user', got %q", value)
+	}
+	t.Setenv("TEST_DB_USER_FILE", path)
+	if value, _ := envOrFile("TEST_DB_USER"); value != "file-user" {
+		t.Errorf("Expected file value 'file-user' to take precedence, got %q", value)
+	}
+}
+
+func TestVaultPasswordSource(t *testing.T) {
+	requests := 0
+	current := "vault-secret-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/component-service/db" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"password": "` + current + `"}, "metadata": {"version": 1}}}`))
+	}))
+	defer server.Close()
+
+	source := &VaultPasswordSource{
+		Addr:       server.URL,
+		Token:      "test-token",
+		SecretPath: "secret/data/component-service/db",
+		Field:      "password",
+		TTL:        time.Hour,
+	}
+
+	password, err := source.Password(context.Background())
+	if err != nil || password != "vault-secret-1" {
+		t.Fatalf("Expected 'vault-secret-1', got %q (err=%v)", password, err)
+	}
+
+	current = "vault-secret-2"
+	if password, _ := source.Password(context.Background()); password != "vault-secret-1" {
+		t.Errorf("Expected cached 'vault-secret-1' within TTL, got %q", password)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 Vault request while cached, got %d", requests)
+	}
+
+	source.Invalidate()
+	if password, _ := source.Password(context.Background()); password != "vault-secret-2" {
+		t.Errorf("Expected rotated 'vault-secret-2' after Invalidate, got %q", password)
+	}
+
+	source.Token = "wrong-token"
+	source.Invalidate()
+	if _, err := source.Password(context.Background()); err == nil {
+		t.Error("Expected error when Vault rejects the token")
+	}
+}
+
+func TestPostgresDSNQuotesPassword(t *testing.T) {
+	cfg := ConnConfig{Host: "localhost", Port: "5432", User: "u", Password: `it's a \secret`, Name: "components", SSLMode: "disable"}
+	expected := `host=localhost port=5432 user=u password='it\'s a \\secret' dbname=components sslmode=disable`
+	if got := (PostgresDialect{}).DSN(cfg); got != expected {
+		t.Errorf("Postgres DSN = %q, expected %q", got, expected)
+	}
+	if _, err := (PostgresDialect{}).Connector(expected); err != nil {
+		t.Errorf("lib/pq rejected the quoted DSN: %v", err)
+	}
+}
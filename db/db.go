@@ -1,72 +1,186 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"time"
 )
 
 var DB *sql.DB
 
+// ErrNotInitialized is returned by GetDB before a successful InitDB.
+var ErrNotInitialized = errors.New("database connection is not initialized; call InitDB first")
+
+const (
+	defaultConnectTimeout = 30 * time.Second
+	pingAttemptTimeout    = 5 * time.Second
+	maxPingBackoff        = 5 * time.Second
+)
+
 // InitDB initializes the database connection.
 // It expects database connection details from environment variables:
 // DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE
-func InitDB() {
+// DB_DRIVER selects the backend dialect (postgres or mysql) and defaults to postgres.
+// DB_USER_FILE, DB_PASSWORD_FILE and a Vault secret (VAULT_ADDR, DB_VAULT_SECRET_PATH) can
+// replace the plain user/password variables; see passwordSourceFromEnv.
+//
+// The database is pinged until it answers or DB_CONNECT_TIMEOUT (default 30s) passes, so the
+// service can start alongside a database that is still coming up. Configuration errors are
+// returned immediately. On error DB is left nil.
+func InitDB() error {
+	dialect, err := DialectFor(os.Getenv("DB_DRIVER"))
+	if err != nil {
+		return fmt.Errorf("error selecting database dialect: %w", err)
+	}
+	CurrentDialect = dialect
+
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
+	dbUser, err := envOrFile("DB_USER")
+	if err != nil {
+		return fmt.Errorf("error reading database user: %w", err)
+	}
 	dbName := os.Getenv("DB_NAME")
 	dbSSLMode := os.Getenv("DB_SSLMODE")
 
 	if dbHost == "" || dbPort == "" || dbUser == "" || dbName == "" {
-		log.Fatal("Database environment variables (DB_HOST, DB_PORT, DB_USER, DB_NAME) are required.")
+		return errors.New("database environment variables (DB_HOST, DB_PORT, DB_USER, DB_NAME) are required")
 	}
 
+	iamAuth := os.Getenv("DB_IAM_AUTH") != ""
 	if dbSSLMode == "" {
 		dbSSLMode = "disable" // Default SSL mode
+		if iamAuth {
+			dbSSLMode = "require" // IAM auth tokens are only accepted over TLS
+		}
 	}
 
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
+	timeouts, err := sessionTimeoutsFromEnv()
+	if err != nil {
+		return fmt.Errorf("error configuring session timeouts: %w", err)
+	}
+	connectTimeout := defaultConnectTimeout
+	if value := os.Getenv("DB_CONNECT_TIMEOUT"); value != "" {
+		connectTimeout, err = time.ParseDuration(value)
+		if err != nil || connectTimeout <= 0 {
+			return fmt.Errorf("invalid DB_CONNECT_TIMEOUT %q: expected a positive duration such as 30s", value)
+		}
+	}
 
-	var err error
-	DB, err = sql.Open("postgres", connStr)
+	connConfig := ConnConfig{
+		Host:                    dbHost,
+		Port:                    dbPort,
+		User:                    dbUser,
+		Name:                    dbName,
+		SSLMode:                 dbSSLMode,
+		AllowCleartextPasswords: iamAuth,
+		Timeouts:                timeouts,
+	}
+	passwordSource, err := passwordSourceFromEnv(connConfig)
 	if err != nil {
-		log.Fatalf("Error opening database connection: %v", err)
+		return fmt.Errorf("error configuring database credentials: %w", err)
+	}
+
+	// With rotating credentials, recycle pooled connections periodically so the pool re-dials
+	// with the current secret well before the old one is revoked.
+	var maxLifetime time.Duration
+	if _, static := passwordSource.(StaticPasswordSource); !static {
+		maxLifetime = 30 * time.Minute
+		if lifetimeParam := os.Getenv("DB_CONN_MAX_LIFETIME"); lifetimeParam != "" {
+			maxLifetime, err = time.ParseDuration(lifetimeParam)
+			if err != nil {
+				return fmt.Errorf("invalid DB_CONN_MAX_LIFETIME %q: %w", lifetimeParam, err)
+			}
+		}
 	}
 
-	err = DB.Ping()
+	connector, err := newCredentialConnector(dialect, connConfig, passwordSource)
 	if err != nil {
-		log.Fatalf("Error pinging database: %v. Please ensure PostgreSQL is running and accessible, and the connection details are correct.", err)
+		return fmt.Errorf("error opening database connection: %w", err)
 	}
+	conn := sql.OpenDB(connector)
+	conn.SetConnMaxLifetime(maxLifetime)
 
-	log.Println("Successfully connected to the PostgreSQL database!")
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := pingUntilReady(ctx, conn); err != nil {
+		conn.Close()
+		return fmt.Errorf("error pinging database %s:%s within %s (check that it is running and the connection details are correct): %w", dbHost, dbPort, connectTimeout, err)
+	}
+	DB = conn
+
+	log.Printf("Successfully connected to the %s database!", dialect.Name())
 
 	// Optional: You can execute the schema.sql here if you want to ensure tables are created
 	// This is useful for development but might be handled by migrations in production.
 	// Example:
 	// schemaBytes, err := os.ReadFile("db/schema.sql")
 	// if err != nil {
-	//     log.Fatalf("Error reading schema.sql: %v", err)
+	//     return fmt.Errorf("error reading schema.sql: %w", err)
 	// }
 	// _, err = DB.Exec(string(schemaBytes))
 	// if err != nil {
-	//     log.Fatalf("Error executing schema.sql: %v", err)
+	//     return fmt.Errorf("error executing schema.sql: %w", err)
 	// }
 	// log.Println("Database schema applied successfully.")
+	return nil
+}
+
+// pingUntilReady pings with exponential backoff until the database answers or ctx is done, and
+// returns the last ping error in the latter case.
+func pingUntilReady(ctx context.Context, conn *sql.DB) error {
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, pingAttemptTimeout)
+		err := conn.PingContext(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		log.Printf("Database not ready (attempt %d): %v; retrying in %s", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxPingBackoff)
+	}
+}
+
+// sessionTimeoutsFromEnv reads DB_STATEMENT_TIMEOUT (default 30s), DB_LOCK_TIMEOUT (default 10s)
+// and DB_IDLE_IN_TRANSACTION_TIMEOUT (default 60s) as Go durations. "0" disables a timeout.
+func sessionTimeoutsFromEnv() (SessionTimeouts, error) {
+	timeouts := SessionTimeouts{
+		Statement:         30 * time.Second,
+		Lock:              10 * time.Second,
+		IdleInTransaction: 60 * time.Second,
+	}
+	for name, target := range map[string]*time.Duration{
+		"DB_STATEMENT_TIMEOUT":           &timeouts.Statement,
+		"DB_LOCK_TIMEOUT":                &timeouts.Lock,
+		"DB_IDLE_IN_TRANSACTION_TIMEOUT": &timeouts.IdleInTransaction,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return timeouts, fmt.Errorf("invalid %s %q: expected a non-negative duration such as 30s", name, value)
+		}
+		*target = parsed
+	}
+	return timeouts, nil
 }
 
-// GetDB returns the active database connection.
-func GetDB() *sql.DB {
+// GetDB returns the active database connection, or ErrNotInitialized before InitDB succeeds.
+func GetDB() (*sql.DB, error) {
 	if DB == nil {
-		// This case should ideally not happen if InitDB is called at application start.
-		// Consider how to handle this based on your application's lifecycle.
-		// For now, we'll log a fatal error.
-		log.Fatal("Database connection is not initialized. Call InitDB first.")
+		return nil, ErrNotInitialized
 	}
-	return DB
+	return DB, nil
 }
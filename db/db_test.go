@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionTimeoutsFromEnv(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		timeouts, err := sessionTimeoutsFromEnv()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := SessionTimeouts{Statement: 30 * time.Second, Lock: 10 * time.Second, IdleInTransaction: 60 * time.Second}
+		if timeouts != expected {
+			t.Errorf("Expected defaults %+v, got %+v", expected, timeouts)
+		}
+	})
+
+	t.Run("Overrides and disabling", func(t *testing.T) {
+		t.Setenv("DB_STATEMENT_TIMEOUT", "5s")
+		t.Setenv("DB_LOCK_TIMEOUT", "0")
+		timeouts, err := sessionTimeoutsFromEnv()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if timeouts.Statement != 5*time.Second || timeouts.Lock != 0 || timeouts.IdleInTransaction != 60*time.Second {
+			t.Errorf("Unexpected timeouts %+v", timeouts)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Setenv("DB_IDLE_IN_TRANSACTION_TIMEOUT", "forever")
+		if _, err := sessionTimeoutsFromEnv(); err == nil {
+			t.Error("Expected error for invalid duration")
+		}
+	})
+}
+
+func TestGetDBBeforeInit(t *testing.T) {
+	defer func(previous *sql.DB) { DB = previous }(DB)
+	DB = nil
+	if conn, err := GetDB(); conn != nil || !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Expected ErrNotInitialized, got %v, %v", conn, err)
+	}
+}
+
+func TestInitDBReturnsErrors(t *testing.T) {
+	defer func(previous *sql.DB) { DB = previous }(DB)
+	DB = nil
+
+	t.Run("Missing configuration", func(t *testing.T) {
+		t.Setenv("DB_HOST", "")
+		if err := InitDB(); err == nil || !strings.Contains(err.Error(), "DB_HOST") {
+			t.Errorf("Expected a configuration error naming DB_HOST, got %v", err)
+		}
+	})
+
+	t.Run("Unreachable database", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "postgres")
+		t.Setenv("DB_HOST", "127.0.0.1")
+		t.Setenv("DB_PORT", "1") // nothing listens here, so every ping is refused
+		t.Setenv("DB_USER", "u")
+		t.Setenv("DB_PASSWORD", "p")
+		t.Setenv("DB_NAME", "components")
+		t.Setenv("DB_CONNECT_TIMEOUT", "600ms")
+		start := time.Now()
+		err := InitDB()
+		if err == nil {
+			t.Fatal("Expected an error for an unreachable database")
+		}
+		if elapsed := time.Since(start); elapsed < 500*time.Millisecond || elapsed > 5*time.Second {
+			t.Errorf("Expected pings to be retried until DB_CONNECT_TIMEOUT, returned after %s", elapsed)
+		}
+		if DB != nil {
+			t.Error("Expected DB to stay nil after a failed InitDB")
+		}
+	})
+
+	t.Run("Invalid connect timeout", func(t *testing.T) {
+		t.Setenv("DB_HOST", "127.0.0.1")
+		t.Setenv("DB_PORT", "1")
+		t.Setenv("DB_USER", "u")
+		t.Setenv("DB_NAME", "components")
+		t.Setenv("DB_CONNECT_TIMEOUT", "soon")
+		if err := InitDB(); err == nil || !strings.Contains(err.Error(), "DB_CONNECT_TIMEOUT") {
+			t.Errorf("Expected a DB_CONNECT_TIMEOUT error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Dialect captures the SQL differences between the supported database backends.
+// Store code writes queries once, in PostgreSQL style ($1, $2, ...), and passes them
+// through Rebind before execution.
+type Dialect interface {
+	// Name is the value of DB_DRIVER that selects this dialect.
+	Name() string
+	// DriverName is the database/sql driver name passed to sql.Open.
+	DriverName() string
+	// DSN builds the driver-specific connection string.
+	DSN(cfg ConnConfig) string
+	// Connector creates a driver.Connector for a DSN built by DSN.
+	Connector(dsn string) (driver.Connector, error)
+	// Rebind rewrites $N placeholders into the dialect's placeholder syntax.
+	Rebind(query string) string
+	// SupportsReturning reports whether INSERT ... RETURNING is available.
+	// When it is not, callers fall back to sql.Result.LastInsertId.
+	SupportsReturning() bool
+	// UpsertClause returns the clause appended to an INSERT to turn it into an upsert
+	// on conflictColumns, updating updateColumns from the incoming row.
+	UpsertClause(conflictColumns []string, updateColumns []string) string
+	// SchemaFile is the file under db/ holding this dialect's schema.
+	SchemaFile() string
+	// ExplainStatement wraps a query so that executing it returns the plan as text rows.
+	ExplainStatement(query string) string
+	// FullScanMarker is the text that identifies a full table scan in an ExplainStatement plan.
+	FullScanMarker() string
+	// SupportsCursors reports whether DECLARE ... CURSOR / FETCH can be used in a plain transaction.
+	SupportsCursors() bool
+	// SnapshotQuery returns a query yielding one row of (position text, timestamp) that identifies
+	// the snapshot seen by the current transaction, e.g. to stamp exports. position may be empty.
+	SnapshotQuery() string
+	// SnapshotIsolation is the weakest isolation level giving a transaction one consistent snapshot.
+	SnapshotIsolation() sql.IsolationLevel
+	// AdvisoryLockQueries returns queries that try to take, without waiting, and release a
+	// session-level lock named by $1 (before Rebind). The try query yields one boolean. Both are
+	// empty when the backend has no such locks.
+	AdvisoryLockQueries() (tryLock, unlock string)
+	// FullTextSearchQueries returns the statement that refreshes the search index of the
+	// components with IDs between $1 and $2, and a query ranking components against the search
+	// text $1, limited to $2 rows, keeping only those tagged $3 unless $3 is empty. The search
+	// query selects the component columns followed by a float rank.
+	// Both are empty when the backend has no full-text index.
+	FullTextSearchQueries() (index, search string)
+	// JSONTextCondition returns a condition that column, holding a JSON object or NULL, has at
+	// the top-level key $1 a string, number or boolean whose text is $2: a string unquoted, the
+	// others as written. It may reference $1 and $2 more than once.
+	JSONTextCondition(column string) string
+	// ReportingRefreshStatements returns the statements that refresh the reporting views of the
+	// schema file, in dependency order. It is empty when the views are always current.
+	ReportingRefreshStatements() []string
+}
+
+// ConnConfig holds the connection details read from the environment.
+type ConnConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	// AllowCleartextPasswords lets the MySQL driver send the password in clear text over TLS,
+	// which RDS IAM authentication requires.
+	AllowCleartextPasswords bool
+	// Timeouts are applied as session settings on every connection.
+	Timeouts SessionTimeouts
+}
+
+// SessionTimeouts bound how long a single connection may be stuck. A zero value leaves the
+// server default in place.
+type SessionTimeouts struct {
+	Statement         time.Duration // statement_timeout / max_execution_time
+	Lock              time.Duration // lock_timeout / innodb_lock_wait_timeout
+	IdleInTransaction time.Duration // idle_in_transaction_session_timeout (PostgreSQL only)
+}
+
+// CurrentDialect is the dialect selected by InitDB. It defaults to PostgreSQL.
+var CurrentDialect Dialect = PostgresDialect{}
+
+// Rebind rewrites a PostgreSQL-style query for the current dialect.
+func Rebind(query string) string {
+	return CurrentDialect.Rebind(query)
+}
+
+// DialectFor returns the dialect registered under the given DB_DRIVER value.
+// An empty name selects PostgreSQL.
+func DialectFor(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "", "postgres", "postgresql":
+		return PostgresDialect{}, nil
+	case "mysql", "mariadb":
+		return MySQLDialect{}, nil
+	case "cockroach", "cockroachdb":
+		return CockroachDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (supported: postgres, mysql, cockroach)", name)
+	}
+}
+
+// PostgresDialect is the default dialect, backed by github.com/lib/pq.
+type PostgresDialect struct{}
+
+func (PostgresDialect) Name() string       { return "postgres" }
+func (PostgresDialect) DriverName() string { return "postgres" }
+
+func (PostgresDialect) DSN(cfg ConnConfig) string {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, quotePostgresDSNValue(cfg.Password), cfg.Name, cfg.SSLMode)
+	// lib/pq sends unrecognized keys as run-time parameters in the startup message.
+	if cfg.Timeouts.Statement > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", cfg.Timeouts.Statement.Milliseconds())
+	}
+	if cfg.Timeouts.Lock > 0 {
+		dsn += fmt.Sprintf(" lock_timeout=%d", cfg.Timeouts.Lock.Milliseconds())
+	}
+	if cfg.Timeouts.IdleInTransaction > 0 {
+		dsn += fmt.Sprintf(" idle_in_transaction_session_timeout=%d", cfg.Timeouts.IdleInTransaction.Milliseconds())
+	}
+	return dsn
+}
+
+func (PostgresDialect) Connector(dsn string) (driver.Connector, error) {
+	return pq.NewConnector(dsn)
+}
+
+// quotePostgresDSNValue quotes a key/value DSN value so passwords containing spaces or quotes
+// (common with generated secrets) survive parsing.
+func quotePostgresDSNValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+func (PostgresDialect) Rebind(query string) string { return query }
+func (PostgresDialect) SupportsReturning() bool    { return true }
+func (PostgresDialect) SchemaFile() string         { return "schema.sql" }
+
+func (PostgresDialect) ExplainStatement(query string) string { return "EXPLAIN " + query }
+func (PostgresDialect) FullScanMarker() string               { return "Seq Scan" }
+func (PostgresDialect) SupportsCursors() bool                { return true }
+
+func (PostgresDialect) SnapshotIsolation() sql.IsolationLevel { return sql.LevelRepeatableRead }
+
+// SnapshotQuery reports the WAL LSN (the replay position on a standby) and the transaction start time.
+func (PostgresDialect) SnapshotQuery() string {
+	return "SELECT (CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END)::text, now()"
+}
+
+// AdvisoryLockQueries hashes the name to an advisory lock key.
+func (PostgresDialect) AdvisoryLockQueries() (string, string) {
+	return "SELECT pg_try_advisory_lock(hashtext($1))", "SELECT pg_advisory_unlock(hashtext($1))"
+}
+
+// FullTextSearchQueries maintains the search_vector tsvector column, weighting name above
+// description. The 'simple' configuration neither stems nor drops stop words, so part numbers
+// and non-English names match as typed.
+func (PostgresDialect) FullTextSearchQueries() (string, string) {
+	index := `UPDATE components SET search_vector =
+		setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+		WHERE id BETWEEN $1 AND $2`
+	search := `SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at, ts_rank(search_vector, query) AS rank
+		FROM components, plainto_tsquery('simple', $1) AS query
+		WHERE search_vector @@ query AND deleted_at IS NULL
+			AND ($3::text = '' OR id IN (SELECT component_id FROM component_tags WHERE tag = $3))
+		ORDER BY rank DESC, id
+		LIMIT $2`
+	return index, search
+}
+
+// JSONTextCondition types the value before comparing it, since ->> also gives the text of
+// nested objects and arrays.
+func (PostgresDialect) JSONTextCondition(column string) string {
+	return fmt.Sprintf("jsonb_typeof(%[1]s -> $1::text) IN ('string', 'number', 'boolean') AND %[1]s ->> $1::text = $2", column)
+}
+
+// ReportingRefreshStatements refreshes concurrently, so BI queries keep reading the previous
+// contents while a refresh runs; CockroachDB accepts the keyword and always behaves that way.
+// component_flat counts descendants from component_closure, which is therefore refreshed first.
+func (PostgresDialect) ReportingRefreshStatements() []string {
+	return []string{
+		"REFRESH MATERIALIZED VIEW CONCURRENTLY reporting.component_closure",
+		"REFRESH MATERIALIZED VIEW CONCURRENTLY reporting.component_flat",
+	}
+}
+
+func (PostgresDialect) UpsertClause(conflictColumns []string, updateColumns []string) string {
+	sets := make([]string, 0, len(updateColumns))
+	for _, col := range updateColumns {
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+	}
+	if len(sets) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflictColumns, ", "))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflictColumns, ", "), strings.Join(sets, ", "))
+}
+
+// MySQLDialect supports MySQL and MariaDB via github.com/go-sql-driver/mysql.
+type MySQLDialect struct{}
+
+func (MySQLDialect) Name() string       { return "mysql" }
+func (MySQLDialect) DriverName() string { return "mysql" }
+
+func (MySQLDialect) DSN(cfg ConnConfig) string {
+	tls := "false"
+	if cfg.SSLMode != "" && cfg.SSLMode != "disable" {
+		tls = "true"
+	}
+	// parseTime makes DATETIME/TIMESTAMP columns scan into time.Time like they do with lib/pq.
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&tls=%s",
+		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name, tls)
+	if cfg.AllowCleartextPasswords {
+		dsn += "&allowCleartextPasswords=true"
+	}
+	// Unrecognized DSN parameters are applied by the driver as session system variables.
+	// MySQL only limits SELECT execution time and has no idle-in-transaction timeout.
+	if cfg.Timeouts.Statement > 0 {
+		dsn += fmt.Sprintf("&max_execution_time=%d", cfg.Timeouts.Statement.Milliseconds())
+	}
+	if cfg.Timeouts.Lock > 0 {
+		seconds := int64(cfg.Timeouts.Lock.Seconds())
+		if seconds < 1 {
+			seconds = 1 // innodb_lock_wait_timeout has one-second granularity
+		}
+		dsn += fmt.Sprintf("&innodb_lock_wait_timeout=%d", seconds)
+	}
+	return dsn
+}
+
+func (MySQLDialect) Connector(dsn string) (driver.Connector, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.NewConnector(cfg)
+}
+
+var positionalParamPattern = regexp.MustCompile(`\$\d+`)
+
+// Rebind replaces $N with ?. MySQL placeholders are purely positional, so queries must
+// reference $1..$N in ascending order and never reuse a parameter.
+func (MySQLDialect) Rebind(query string) string {
+	return positionalParamPattern.ReplaceAllString(query, "?")
+}
+
+func (MySQLDialect) SupportsReturning() bool { return false }
+func (MySQLDialect) SchemaFile() string      { return "schema_mysql.sql" }
+
+// ExplainStatement uses the TREE format (MySQL 8.0.16+), which returns a single text column.
+func (MySQLDialect) ExplainStatement(query string) string { return "EXPLAIN FORMAT=TREE " + query }
+func (MySQLDialect) FullScanMarker() string               { return "Table scan" }
+
+// SupportsCursors is false: MySQL cursors only exist inside stored programs. Exports read each
+// batch with a keyset query instead.
+func (MySQLDialect) SupportsCursors() bool { return false }
+
+// SnapshotQuery reports only a timestamp: replication positions differ between MySQL (GTID sets)
+// and MariaDB, and neither is tied to the transaction's read view.
+func (MySQLDialect) SnapshotQuery() string { return "SELECT '', NOW(6)" }
+
+func (MySQLDialect) SnapshotIsolation() sql.IsolationLevel { return sql.LevelRepeatableRead }
+
+// AdvisoryLockQueries uses named locks; GET_LOCK returns NULL on error, which counts as not acquired.
+func (MySQLDialect) AdvisoryLockQueries() (string, string) {
+	return "SELECT COALESCE(GET_LOCK($1, 0), 0) = 1", "SELECT RELEASE_LOCK($1)"
+}
+
+// FullTextSearchQueries is unsupported; searches are served from the component cache.
+func (MySQLDialect) FullTextSearchQueries() (string, string) { return "", "" }
+
+// ReportingRefreshStatements is empty: the MySQL reporting views are plain views.
+func (MySQLDialect) ReportingRefreshStatements() []string { return nil }
+
+// JSONTextCondition builds the path from the key with JSON_QUOTE, so keys holding dots, quotes
+// or spaces name a single member. A JSON number reads back as MySQL stores it, so 1.50 matches
+// "1.5" where PostgreSQL matches "1.50".
+func (MySQLDialect) JSONTextCondition(column string) string {
+	value := fmt.Sprintf("JSON_EXTRACT(%s, CONCAT('$.', JSON_QUOTE($1)))", column)
+	return fmt.Sprintf("JSON_TYPE(%[1]s) IN ('STRING', 'INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL', 'BOOLEAN') AND JSON_UNQUOTE(%[1]s) = $2", value)
+}
+
+func (MySQLDialect) UpsertClause(conflictColumns []string, updateColumns []string) string {
+	if len(updateColumns) == 0 {
+		// MySQL has no DO NOTHING; a self-assignment of the first conflict column is the idiom.
+		return fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", conflictColumns[0], conflictColumns[0])
+	}
+	sets := make([]string, 0, len(updateColumns))
+	for _, col := range updateColumns {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", col, col))
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+// CockroachDialect speaks the PostgreSQL wire protocol through lib/pq. It differs from
+// PostgresDialect in its schema (no PL/pgSQL trigger) and in how transactions are retried;
+// see ExecuteTx.
+type CockroachDialect struct {
+	PostgresDialect
+}
+
+func (CockroachDialect) Name() string           { return "cockroach" }
+func (CockroachDialect) SchemaFile() string     { return "schema_cockroach.sql" }
+func (CockroachDialect) FullScanMarker() string { return "FULL SCAN" }
+
+// SnapshotQuery reports the transaction's HLC commit timestamp, which orders it against every
+// other transaction in the cluster. It is only valid under serializable isolation.
+func (CockroachDialect) SnapshotQuery() string {
+	return "SELECT cluster_logical_timestamp()::STRING, now()"
+}
+
+// SnapshotIsolation is SERIALIZABLE, CockroachDB's default: weaker levels are opt-in cluster
+// settings and cluster_logical_timestamp() is unavailable under them.
+func (CockroachDialect) SnapshotIsolation() sql.IsolationLevel { return sql.LevelSerializable }
+
+// AdvisoryLockQueries is unsupported: CockroachDB accepts pg_try_advisory_lock for compatibility
+// but it always succeeds without locking anything.
+func (CockroachDialect) AdvisoryLockQueries() (string, string) { return "", "" }
+
+// FullTextSearchQueries is unsupported: tsvector support differs between the supported
+// CockroachDB versions, so searches are served from the component cache.
+func (CockroachDialect) FullTextSearchQueries() (string, string) { return "", "" }
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialectFor(t *testing.T) {
+	tests := []struct {
+		driver   string
+		expected string
+		wantErr  bool
+	}{
+		{driver: "", expected: "postgres"},
+		{driver: "postgres", expected: "postgres"},
+		{driver: "PostgreSQL", expected: "postgres"},
+		{driver: "mysql", expected: "mysql"},
+		{driver: "mariadb", expected: "mysql"},
+		{driver: "oracle", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			dialect, err := DialectFor(tt.driver)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error for driver %q, got dialect %v", tt.driver, dialect)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DialectFor(%q) returned error: %v", tt.driver, err)
+			}
+			if dialect.Name() != tt.expected {
+				t.Errorf("DialectFor(%q) = %s, expected %s", tt.driver, dialect.Name(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "UPDATE components SET name = $1, description = $2 WHERE id = $10"
+
+	if got := (PostgresDialect{}).Rebind(query); got != query {
+		t.Errorf("Postgres Rebind changed the query: %s", got)
+	}
+	expected := "UPDATE components SET name = ?, description = ? WHERE id = ?"
+	if got := (MySQLDialect{}).Rebind(query); got != expected {
+		t.Errorf("MySQL Rebind = %q, expected %q", got, expected)
+	}
+}
+
+func TestUpsertClause(t *testing.T) {
+	conflict := []string{"id"}
+	update := []string{"name", "description"}
+
+	expectedPG := " ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description"
+	if got := (PostgresDialect{}).UpsertClause(conflict, update); got != expectedPG {
+		t.Errorf("Postgres UpsertClause = %q, expected %q", got, expectedPG)
+	}
+	expectedMySQL := " ON DUPLICATE KEY UPDATE name = VALUES(name), description = VALUES(description)"
+	if got := (MySQLDialect{}).UpsertClause(conflict, update); got != expectedMySQL {
+		t.Errorf("MySQL UpsertClause = %q, expected %q", got, expectedMySQL)
+	}
+	if got := (PostgresDialect{}).UpsertClause(conflict, nil); got != " ON CONFLICT (id) DO NOTHING" {
+		t.Errorf("Postgres UpsertClause without updates = %q", got)
+	}
+}
+
+func TestDSN(t *testing.T) {
+	cfg := ConnConfig{Host: "db", Port: "3306", User: "u", Password: "p", Name: "components", SSLMode: "disable"}
+	expected := "u:p@tcp(db:3306)/components?parseTime=true&tls=false"
+	if got := (MySQLDialect{}).DSN(cfg); got != expected {
+		t.Errorf("MySQL DSN = %q, expected %q", got, expected)
+	}
+}
+
+func TestDSNWithSessionTimeouts(t *testing.T) {
+	cfg := ConnConfig{
+		Host: "db", Port: "5432", User: "u", Password: "p", Name: "components", SSLMode: "disable",
+		Timeouts: SessionTimeouts{Statement: 30 * time.Second, Lock: 500 * time.Millisecond, IdleInTransaction: time.Minute},
+	}
+
+	expectedPG := "host=db port=5432 user=u password='p' dbname=components sslmode=disable statement_timeout=30000 lock_timeout=500 idle_in_transaction_session_timeout=60000"
+	if got := (PostgresDialect{}).DSN(cfg); got != expectedPG {
+		t.Errorf("Postgres DSN = %q, expected %q", got, expectedPG)
+	}
+
+	cfg.Port = "3306"
+	expectedMySQL := "u:p@tcp(db:3306)/components?parseTime=true&tls=false&max_execution_time=30000&innodb_lock_wait_timeout=1"
+	if got := (MySQLDialect{}).DSN(cfg); got != expectedMySQL {
+		t.Errorf("MySQL DSN = %q, expected %q", got, expectedMySQL)
+	}
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		expected sql.IsolationLevel
+	}{
+		{dialect: PostgresDialect{}, expected: sql.LevelRepeatableRead},
+		{dialect: MySQLDialect{}, expected: sql.LevelRepeatableRead},
+		{dialect: CockroachDialect{}, expected: sql.LevelSerializable},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.SnapshotIsolation(); got != tt.expected {
+			t.Errorf("%s SnapshotIsolation = %v, expected %v", tt.dialect.Name(), got, tt.expected)
+		}
+	}
+}
+
+func TestAdvisoryLockQueries(t *testing.T) {
+	for _, dialect := range []Dialect{PostgresDialect{}, MySQLDialect{}} {
+		tryLock, unlock := dialect.AdvisoryLockQueries()
+		if tryLock == "" || unlock == "" {
+			t.Errorf("%s: expected advisory lock queries", dialect.Name())
+		}
+	}
+	if tryLock, unlock := (CockroachDialect{}).AdvisoryLockQueries(); tryLock != "" || unlock != "" {
+		t.Errorf("Expected CockroachDB to report advisory locks as unsupported, got %q / %q", tryLock, unlock)
+	}
+}
+
+func TestFullTextSearchQueries(t *testing.T) {
+	index, search := PostgresDialect{}.FullTextSearchQueries()
+	if !strings.Contains(index, "search_vector") || !strings.Contains(search, "$2") || !strings.Contains(search, "component_tags") {
+		t.Errorf("Expected PostgreSQL search queries over search_vector, got %q / %q", index, search)
+	}
+	for _, dialect := range []Dialect{MySQLDialect{}, CockroachDialect{}} {
+		if index, search := dialect.FullTextSearchQueries(); index != "" || search != "" {
+			t.Errorf("%s: expected full-text search to be unsupported, got %q / %q", dialect.Name(), index, search)
+		}
+	}
+}
+
+func TestReportingRefreshStatements(t *testing.T) {
+	for _, dialect := range []Dialect{PostgresDialect{}, CockroachDialect{}} {
+		statements := dialect.ReportingRefreshStatements()
+		if len(statements) != 2 || !strings.Contains(statements[0], "component_closure") || !strings.Contains(statements[1], "component_flat") {
+			t.Errorf("%s: expected component_closure refreshed before component_flat, got %q", dialect.Name(), statements)
+		}
+	}
+	if statements := (MySQLDialect{}).ReportingRefreshStatements(); len(statements) != 0 {
+		t.Errorf("Expected MySQL reporting views to need no refresh, got %q", statements)
+	}
+}
+
+func TestJSONTextCondition(t *testing.T) {
+	for _, dialect := range []Dialect{PostgresDialect{}, CockroachDialect{}} {
+		expected := "jsonb_typeof(metadata -> $1::text) IN ('string', 'number', 'boolean') AND metadata ->> $1::text = $2"
+		if got := dialect.JSONTextCondition("metadata"); got != expected {
+			t.Errorf("%s: JSONTextCondition = %q, expected %q", dialect.Name(), got, expected)
+		}
+	}
+	got := (MySQLDialect{}).JSONTextCondition("metadata")
+	if !strings.Contains(got, "JSON_EXTRACT(metadata, CONCAT('$.', JSON_QUOTE($1)))") || !strings.HasSuffix(got, ") = $2") {
+		t.Errorf("Expected MySQL to extract the quoted key $1 and compare it with $2, got %q", got)
+	}
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rdsTokenLifetime is how long RDS accepts an IAM auth token for new connections.
+	rdsTokenLifetime = 15 * time.Minute
+	// rdsTokenRefreshMargin is how long before expiry a cached token is replaced.
+	rdsTokenRefreshMargin = 5 * time.Minute
+)
+
+// AWSCredentials are the static or temporary credentials used to sign RDS auth tokens.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// awsCredentialsFromEnv reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// (each also accepted as a *_FILE variant, e.g. for credentials projected by a sidecar).
+func awsCredentialsFromEnv() (AWSCredentials, error) {
+	var creds AWSCredentials
+	var err error
+	if creds.AccessKeyID, err = envOrFile("AWS_ACCESS_KEY_ID"); err != nil {
+		return creds, err
+	}
+	if creds.SecretAccessKey, err = envOrFile("AWS_SECRET_ACCESS_KEY"); err != nil {
+		return creds, err
+	}
+	if creds.SessionToken, err = envOrFile("AWS_SESSION_TOKEN"); err != nil {
+		return creds, err
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for RDS IAM authentication")
+	}
+	return creds, nil
+}
+
+// RDSIAMPasswordSource generates Amazon RDS IAM authentication tokens to use as the database
+// password. Tokens are cached and regenerated shortly before they expire. Credentials are
+// re-read through LoadCredentials on every refresh so rotated temporary credentials are used.
+type RDSIAMPasswordSource struct {
+	Host            string
+	Port            string
+	User            string
+	Region          string
+	LoadCredentials func() (AWSCredentials, error)
+	Now             func() time.Time // overridable for tests
+
+	mu          sync.Mutex
+	cached      string
+	generatedAt time.Time
+}
+
+func (s *RDSIAMPasswordSource) Password(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.cached != "" && now.Sub(s.generatedAt) < rdsTokenLifetime-rdsTokenRefreshMargin {
+		return s.cached, nil
+	}
+	creds, err := s.LoadCredentials()
+	if err != nil {
+		return "", err
+	}
+	token, err := buildRDSAuthToken(s.Host, s.Port, s.User, s.Region, creds, now)
+	if err != nil {
+		return "", err
+	}
+	s.cached = token
+	s.generatedAt = now
+	return token, nil
+}
+
+func (s *RDSIAMPasswordSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = ""
+}
+
+func (s *RDSIAMPasswordSource) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// buildRDSAuthToken presigns an rds-db:connect request with AWS Signature Version 4. The token
+// is the presigned URL without its scheme, as expected by RDS.
+func buildRDSAuthToken(host, port, user, region string, creds AWSCredentials, now time.Time) (string, error) {
+	if region == "" {
+		return "", fmt.Errorf("an AWS region is required for RDS IAM authentication")
+	}
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+	const service = "rds-db"
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", shortDate, region, service)
+	hostPort := host + ":" + port
+
+	params := map[string]string{
+		"Action":              "connect",
+		"DBUser":              user,
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    creds.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       fmt.Sprintf("%d", int(rdsTokenLifetime.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.SessionToken != "" {
+		params["X-Amz-Security-Token"] = creds.SessionToken
+	}
+	canonicalQuery := canonicalQueryString(params)
+
+	emptyPayloadHash := sha256Hex("")
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		canonicalQuery,
+		"host:" + hostPort + "\n",
+		"host",
+		emptyPayloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	signingKey := sigV4SigningKey(creds.SecretAccessKey, shortDate, region, service)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return hostPort + "/?" + canonicalQuery + "&X-Amz-Signature=" + signature, nil
+}
+
+// canonicalQueryString sorts parameters by key and applies SigV4 URI encoding (RFC 3986
+// unreserved characters kept, everything else percent-encoded, spaces as %20).
+func canonicalQueryString(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(params[key]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+func sigV4Escape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func sigV4SigningKey(secret, shortDate, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), shortDate)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// rdsIAMPasswordSourceFromEnv configures IAM authentication when DB_IAM_AUTH=rds. The region
+// comes from DB_IAM_REGION, falling back to AWS_REGION.
+func rdsIAMPasswordSourceFromEnv(host, port, user string) (*RDSIAMPasswordSource, error) {
+	region := os.Getenv("DB_IAM_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("DB_IAM_REGION or AWS_REGION is required when DB_IAM_AUTH=rds")
+	}
+	if _, err := awsCredentialsFromEnv(); err != nil {
+		return nil, err
+	}
+	return &RDSIAMPasswordSource{
+		Host:            host,
+		Port:            port,
+		User:            user,
+		Region:          region,
+		LoadCredentials: awsCredentialsFromEnv,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Signing key derivation example from the AWS Signature Version 4 documentation.
+func TestSigV4SigningKey(t *testing.T) {
+	key := sigV4SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "iam")
+	expected := "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"
+	if got := hex.EncodeToString(key); got != expected {
+		t.Errorf("Signing key = %s, expected %s", got, expected)
+	}
+}
+
+func TestBuildRDSAuthToken(t *testing.T) {
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session/token+1"}
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	token, err := buildRDSAuthToken("db.example.us-east-1.rds.amazonaws.com", "5432", "app_user", "us-east-1", creds, now)
+	if err != nil {
+		t.Fatalf("buildRDSAuthToken returned error: %v", err)
+	}
+	if strings.HasPrefix(token, "https://") {
+		t.Errorf("Token must not include a scheme: %s", token)
+	}
+	if !strings.HasPrefix(token, "db.example.us-east-1.rds.amazonaws.com:5432/?") {
+		t.Fatalf("Token does not start with host:port/?: %s", token)
+	}
+
+	query, err := url.ParseQuery(token[strings.Index(token, "?")+1:])
+	if err != nil {
+		t.Fatalf("Token query is not parseable: %v", err)
+	}
+	expectations := map[string]string{
+		"Action":               "connect",
+		"DBUser":               "app_user",
+		"X-Amz-Algorithm":      "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":     "AKIDEXAMPLE/20240301/us-east-1/rds-db/aws4_request",
+		"X-Amz-Date":           "20240301T123000Z",
+		"X-Amz-Expires":        "900",
+		"X-Amz-Security-Token": "session/token+1",
+		"X-Amz-SignedHeaders":  "host",
+	}
+	for key, expected := range expectations {
+		if got := query.Get(key); got != expected {
+			t.Errorf("Query parameter %s = %q, expected %q", key, got, expected)
+		}
+	}
+	if len(query.Get("X-Amz-Signature")) != 64 {
+		t.Errorf("Expected a 64-char hex signature, got %q", query.Get("X-Amz-Signature"))
+	}
+	if !strings.HasSuffix(token, "&X-Amz-Signature="+query.Get("X-Amz-Signature")) {
+		t.Error("Signature must be the last query parameter")
+	}
+
+	again, _ := buildRDSAuthToken("db.example.us-east-1.rds.amazonaws.com", "5432", "app_user", "us-east-1", creds, now)
+	if again != token {
+		t.Error("Token generation is not deterministic for identical inputs")
+	}
+
+	if _, err := buildRDSAuthToken("host", "5432", "user", "", creds, now); err == nil {
+		t.Error("Expected error without a region")
+	}
+}
+
+func TestRDSIAMPasswordSource_RefreshesBeforeExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	loads := 0
+	source := &RDSIAMPasswordSource{
+		Host:   "db.example.com",
+		Port:   "5432",
+		User:   "app_user",
+		Region: "eu-west-1",
+		LoadCredentials: func() (AWSCredentials, error) {
+			loads++
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		},
+		Now: func() time.Time { return now },
+	}
+
+	first, err := source.Password(context.Background())
+	if err != nil {
+		t.Fatalf("Password returned error: %v", err)
+	}
+	now = now.Add(5 * time.Minute)
+	if cached, _ := source.Password(context.Background()); cached != first || loads != 1 {
+		t.Errorf("Expected cached token within refresh window (loads=%d)", loads)
+	}
+	now = now.Add(6 * time.Minute) // 11 minutes old: inside the 5 minute refresh margin
+	if refreshed, _ := source.Password(context.Background()); refreshed == first || loads != 2 {
+		t.Errorf("Expected a new token near expiry (loads=%d)", loads)
+	}
+
+	source.Invalidate()
+	source.Password(context.Background())
+	if loads != 3 {
+		t.Errorf("Expected Invalidate to force a new token, loads=%d", loads)
+	}
+}
@@ -11,17 +11,298 @@ CREATE TABLE IF NOT EXISTS components (
 
 -- Optional: Index for parent_id for faster querying of children
 CREATE INDEX IF NOT EXISTS idx_components_parent_id ON components(parent_id);
+-- Sibling lookups by name (e.g. resolving a child of a given parent by its name)
+CREATE INDEX IF NOT EXISTS idx_components_parent_id_name ON components(parent_id, name);
+-- Listing in creation order (the default list ordering) and by recent modification
+CREATE INDEX IF NOT EXISTS idx_components_created_at_id ON components(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_components_updated_at ON components(updated_at);
 
--- Optional: Trigger to update updated_at timestamp on row update
+-- Optional: Trigger to update updated_at timestamp on row update. Updates that only refresh
+-- search_vector are index maintenance, not modifications, and leave updated_at alone.
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
 BEGIN
-    NEW.updated_at = NOW();
+    IF (to_jsonb(NEW) - 'search_vector' - 'updated_at') IS DISTINCT FROM (to_jsonb(OLD) - 'search_vector' - 'updated_at') THEN
+        NEW.updated_at = NOW();
+    END IF;
     RETURN NEW;
 END;
 $$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_components_updated_at ON components;
 CREATE TRIGGER update_components_updated_at
 BEFORE UPDATE ON components
 FOR EACH ROW
 EXECUTE FUNCTION update_updated_at_column();
+
+-- Full-text search over name and description (GET /components/search). The store refreshes
+-- search_vector on every write; the UPDATE backfills rows written before the column existed.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS search_vector tsvector;
+CREATE INDEX IF NOT EXISTS idx_components_search_vector ON components USING GIN (search_vector);
+UPDATE components SET search_vector =
+    setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+    WHERE search_vector IS NULL;
+
+-- Order among siblings (POST /components/{id}/reorder). Rows written before the column existed
+-- share position 0 and keep their creation order until reordered.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_components_parent_id_position ON components(parent_id, position);
+
+-- Soft deletes (DELETE /components/{id}): deleted_at marks a component as deleted until it is
+-- restored or purged. Reads, the closure table and the reporting views leave such rows out.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Slugs (GET /components/slug/{slug}): unique, URL-safe references, derived from the name on
+-- create and kept on rename. Soft-deleted components keep theirs until purged. Rows written
+-- before the column existed get their name's slug followed by their ID, which cannot collide.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+UPDATE components SET slug =
+    COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(LOWER(REGEXP_REPLACE(name, '[^a-zA-Z0-9]+', '-', 'g')), 100)), ''), 'component') || '-' || id
+    WHERE slug IS NULL;
+ALTER TABLE components ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_components_slug ON components(slug);
+
+-- Component types (?type=): free-form lowercase labels, checked against COMPONENT_TYPES when it is
+-- set. The empty string means untyped, which is what rows written before the column existed get.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS type VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_components_type ON components(type);
+
+-- Lifecycle status (POST /components/{id}/status): one of the statuses STATUS_TRANSITIONS names,
+-- moved between only along its transitions. Rows written before the column existed are active.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
+
+-- Optimistic concurrency (If-Match): every write the service makes to a row increments its version,
+-- and a write naming the version it read is refused once another has incremented it.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- Component metadata (?metadata.<key>=): a JSON object of the integrator's own, or NULL for none.
+-- Filters compare one key's text with ->>, which a GIN index cannot serve, so there is none.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- Optional: unique sibling names (UNIQUE_SIBLING_NAMES=true). The service checks names inside its
+-- writes; this index also refuses two concurrent writes racing past the check, and writes made
+-- around the service. Roots count as siblings of each other; soft-deleted components do not
+-- count. Creating it fails while duplicates remain, so rename those first. It also refuses moves,
+-- restores and imports that would duplicate a name, which the service does not check.
+-- CREATE UNIQUE INDEX IF NOT EXISTS idx_components_sibling_name ON components((COALESCE(parent_id, 0)), name)
+--     WHERE deleted_at IS NULL;
+
+-- Per-component access control lists (see "Access Control" in README.md). Entries inherit down
+-- the tree; deleting a component deletes its entries.
+CREATE TABLE IF NOT EXISTS component_acl (
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    principal VARCHAR(255) NOT NULL,
+    permission VARCHAR(16) NOT NULL CHECK (permission IN ('none', 'read', 'write', 'admin')),
+    PRIMARY KEY (component_id, principal)
+);
+
+-- Read-only share links to a subtree (POST /components/{id}/share). Only token hashes are stored.
+CREATE TABLE IF NOT EXISTS share_links (
+    id SERIAL PRIMARY KEY,
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_share_links_component_id ON share_links(component_id);
+
+-- Components flagged publicly visible, with their subtrees, on the public router (PUBLIC_PORT).
+CREATE TABLE IF NOT EXISTS public_components (
+    component_id INTEGER PRIMARY KEY REFERENCES components(id) ON DELETE CASCADE,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Closure table of the tree (see "Closure Table" in README.md): one row per (ancestor, descendant)
+-- pair, each component paired with itself at depth 0. The store keeps it current in the
+-- transaction of every write; the INSERT backfills it when the table is new. Writes made around
+-- the service leave it stale until POST /admin/closure/rebuild.
+CREATE TABLE IF NOT EXISTS component_closure (
+    ancestor_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    descendant_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    depth INTEGER NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id)
+);
+CREATE INDEX IF NOT EXISTS idx_component_closure_descendant_id ON component_closure(descendant_id, depth);
+INSERT INTO component_closure (ancestor_id, descendant_id, depth)
+WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+    SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT closure.ancestor_id, c.id, closure.depth + 1
+    FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+    WHERE closure.depth < 10000
+)
+SELECT ancestor_id, descendant_id, MIN(depth) FROM closure
+WHERE NOT EXISTS (SELECT 1 FROM component_closure)
+GROUP BY ancestor_id, descendant_id;
+
+-- ID mapping of catalog imports (POST /components/import): the component created for each
+-- component of a source instance, by its ID there. Imports from one source serialize on its row
+-- in component_import_sources.
+CREATE TABLE IF NOT EXISTS component_import_sources (
+    source VARCHAR(255) PRIMARY KEY,
+    last_imported_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE TABLE IF NOT EXISTS component_id_map (
+    source VARCHAR(255) NOT NULL REFERENCES component_import_sources(source) ON DELETE CASCADE,
+    source_id BIGINT NOT NULL,
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    PRIMARY KEY (source, source_id)
+);
+CREATE INDEX IF NOT EXISTS idx_component_id_map_component_id ON component_id_map(component_id);
+
+-- Tags of components (?tag= filters), one row per tag. The primary key lists a component's tags;
+-- the tag index finds the components having one.
+CREATE TABLE IF NOT EXISTS component_tags (
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (component_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_component_tags_tag ON component_tags(tag);
+
+-- Attribute schemas (/attribute-schemas): the attributes components of a type must or may have
+-- in their metadata, one row per attribute, in the order defined. enum_values is a JSON array of
+-- the strings allowed, or NULL for any.
+CREATE TABLE IF NOT EXISTS attribute_schemas (
+    type VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    data_type VARCHAR(16) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values TEXT,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (type, name)
+);
+
+-- Options of attribute schemas, one row per type with a schema. A strict schema rejects metadata
+-- keys it does not define.
+CREATE TABLE IF NOT EXISTS attribute_schema_options (
+    type VARCHAR(64) PRIMARY KEY,
+    strict BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Files attached to components (/components/{id}/attachments). The data is kept by the storage
+-- ATTACHMENTS_STORAGE configures, under storage_key; rows go with their component when it is
+-- purged, and the service deletes the data then.
+CREATE TABLE IF NOT EXISTS component_attachments (
+    id SERIAL PRIMARY KEY,
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(512) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_component_attachments_component_id ON component_attachments(component_id);
+
+-- Comments on components (/components/{id}/comments). They go with their component when it is
+-- purged.
+CREATE TABLE IF NOT EXISTS component_comments (
+    id SERIAL PRIMARY KEY,
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_component_comments_component_id ON component_comments(component_id);
+
+-- Watches (/components/{id}/watch): the principals following changes to a component, or to its
+-- whole subtree. They go with their component when it is purged.
+CREATE TABLE IF NOT EXISTS component_watches (
+    component_id INTEGER NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    principal VARCHAR(255) NOT NULL,
+    subtree BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (component_id, principal)
+);
+CREATE INDEX IF NOT EXISTS idx_component_watches_principal ON component_watches(principal);
+
+-- Outbound webhooks (/admin/webhooks): the URLs component changes are POSTed to, signed with
+-- secret. events lists the event types delivered, comma-separated; empty for all of them.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Each attempt to deliver an event to a webhook, kept for WEBHOOK_DELIVERY_RETENTION.
+-- status_code is 0 when no response arrived. They go with their webhook when it is deleted.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id CHAR(32) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    component_id BIGINT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    response_body TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    succeeded BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id_created_at ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+-- The event schema version each webhook receives (see "Event Schemas" in README.md). Webhooks
+-- registered before the column existed keep version 1.
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 1;
+
+-- The body each attempt POSTed, which POST /admin/webhooks/{id}/deliveries/{deliveryID}/replay
+-- sends again. Attempts recorded before the column existed cannot be replayed.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS payload TEXT;
+
+-- Idempotency keys of POST /components: the response to each principal's request, replayed to
+-- its retries for IDEMPOTENCY_KEY_TTL. status_code is 0 while the request is being served.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key VARCHAR(255) NOT NULL,
+    principal VARCHAR(255) NOT NULL DEFAULT '',
+    fingerprint CHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_header TEXT,
+    response_body TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idempotency_key, principal)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+-- Reporting views for BI tools that query the database directly (see "Reporting Views" in
+-- README.md). They are materialized, so reads cost no recursion, and refreshed by the service
+-- (POST /admin/reporting/refresh, or every REPORTING_REFRESH_INTERVAL). Recursion stops at depth
+-- 10000, so a parent cycle in the data cannot make a refresh run forever. Views created before
+-- deleted_at existed still list soft-deleted components; drop them to have them recreated.
+CREATE SCHEMA IF NOT EXISTS reporting;
+
+-- Every (ancestor, descendant) pair, including each component paired with itself at depth 0.
+CREATE MATERIALIZED VIEW IF NOT EXISTS reporting.component_closure AS
+WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+    SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT closure.ancestor_id, c.id, closure.depth + 1
+    FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+    WHERE closure.depth < 10000
+)
+SELECT ancestor_id, descendant_id, depth FROM closure;
+-- REFRESH ... CONCURRENTLY needs a unique index; the others serve the common lookups.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_component_closure_pair ON reporting.component_closure(ancestor_id, descendant_id);
+CREATE INDEX IF NOT EXISTS idx_component_closure_descendant ON reporting.component_closure(descendant_id, depth);
+
+-- One denormalized row per component reachable from a root, as served by GET /components/flat.
+CREATE MATERIALIZED VIEW IF NOT EXISTS reporting.component_flat AS
+WITH RECURSIVE paths (id, name, root_id, root_name, depth, ancestor_ids, ancestor_names) AS (
+    SELECT id, name::TEXT, id, name::TEXT, 0, ARRAY[]::INTEGER[], ARRAY[]::TEXT[] FROM components WHERE parent_id IS NULL AND deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.name::TEXT, p.root_id, p.root_name, p.depth + 1, p.ancestor_ids || p.id, p.ancestor_names || p.name
+    FROM paths p JOIN components c ON c.parent_id = p.id AND c.deleted_at IS NULL
+    WHERE p.depth < 10000
+)
+SELECT c.id, c.name, c.description, c.parent_id, p.ancestor_names[p.depth] AS parent_name,
+    p.root_id, p.root_name, p.depth, p.ancestor_ids, p.ancestor_names,
+    (SELECT COUNT(*) FROM components child WHERE child.parent_id = c.id AND child.deleted_at IS NULL) AS child_count,
+    (SELECT COUNT(*) - 1 FROM reporting.component_closure cc WHERE cc.ancestor_id = c.id) AS descendant_count,
+    c.created_at, c.updated_at
+FROM paths p JOIN components c ON c.id = p.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_component_flat_id ON reporting.component_flat(id);
+CREATE INDEX IF NOT EXISTS idx_component_flat_root_id ON reporting.component_flat(root_id);
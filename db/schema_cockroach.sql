@@ -0,0 +1,238 @@
+-- schema_cockroach.sql
+-- CockroachDB equivalent of schema.sql, used with DB_DRIVER=cockroach.
+-- The update_updated_at_column trigger is omitted: PL/pgSQL triggers are not available on
+-- all supported CockroachDB versions, and the store always sets updated_at explicitly.
+
+CREATE TABLE IF NOT EXISTS components (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    parent_id INT8 REFERENCES components(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT current_timestamp(),
+    updated_at TIMESTAMPTZ DEFAULT current_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_components_parent_id ON components(parent_id);
+CREATE INDEX IF NOT EXISTS idx_components_parent_id_name ON components(parent_id, name);
+CREATE INDEX IF NOT EXISTS idx_components_created_at_id ON components(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_components_updated_at ON components(updated_at);
+
+-- Order among siblings (POST /components/{id}/reorder). Rows written before the column existed
+-- share position 0 and keep their creation order until reordered.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS position INT8 NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_components_parent_id_position ON components(parent_id, position);
+
+-- Soft deletes; see schema.sql.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Slugs; see schema.sql. The store always sets the slug; the default only fills rows written
+-- before the column existed, with a unique placeholder, since a backfill UPDATE cannot share a
+-- transaction with the column's addition here.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS slug VARCHAR(255) NOT NULL DEFAULT 'component-' || unique_rowid()::STRING;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_components_slug ON components(slug);
+
+-- Component types; see schema.sql.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS type VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_components_type ON components(type);
+
+-- Lifecycle status; see schema.sql.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
+
+-- Versions; see schema.sql.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- Component metadata; see schema.sql.
+ALTER TABLE components ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- Optional: unique sibling names; see schema.sql. Expression indexes need CockroachDB 21.2 or later.
+-- CREATE UNIQUE INDEX IF NOT EXISTS idx_components_sibling_name ON components((COALESCE(parent_id, 0)), name)
+--     WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS component_acl (
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    principal VARCHAR(255) NOT NULL,
+    permission VARCHAR(16) NOT NULL CHECK (permission IN ('none', 'read', 'write', 'admin')),
+    PRIMARY KEY (component_id, principal)
+);
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_share_links_component_id ON share_links(component_id);
+
+CREATE TABLE IF NOT EXISTS public_components (
+    component_id INT8 PRIMARY KEY REFERENCES components(id) ON DELETE CASCADE,
+    published_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp()
+);
+
+-- Closure table; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_closure (
+    ancestor_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    descendant_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id)
+);
+CREATE INDEX IF NOT EXISTS idx_component_closure_descendant_id ON component_closure(descendant_id, depth);
+INSERT INTO component_closure (ancestor_id, descendant_id, depth)
+WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+    SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT closure.ancestor_id, c.id, closure.depth + 1
+    FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+    WHERE closure.depth < 10000
+)
+SELECT ancestor_id, descendant_id, MIN(depth) FROM closure
+WHERE NOT EXISTS (SELECT 1 FROM component_closure)
+GROUP BY ancestor_id, descendant_id;
+
+-- Import ID mapping; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_import_sources (
+    source VARCHAR(255) PRIMARY KEY,
+    last_imported_at TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS component_id_map (
+    source VARCHAR(255) NOT NULL REFERENCES component_import_sources(source) ON DELETE CASCADE,
+    source_id INT8 NOT NULL,
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    PRIMARY KEY (source, source_id)
+);
+CREATE INDEX IF NOT EXISTS idx_component_id_map_component_id ON component_id_map(component_id);
+
+-- Component tags; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_tags (
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (component_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_component_tags_tag ON component_tags(tag);
+
+-- Attribute schemas; see schema.sql.
+CREATE TABLE IF NOT EXISTS attribute_schemas (
+    type VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    data_type VARCHAR(16) NOT NULL,
+    required BOOL NOT NULL DEFAULT FALSE,
+    enum_values STRING,
+    position INT8 NOT NULL,
+    PRIMARY KEY (type, name)
+);
+
+-- Attribute schema options; see schema.sql.
+CREATE TABLE IF NOT EXISTS attribute_schema_options (
+    type VARCHAR(64) PRIMARY KEY,
+    strict BOOL NOT NULL DEFAULT FALSE
+);
+
+-- Component attachments; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_attachments (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size INT8 NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(512) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp()
+);
+CREATE INDEX IF NOT EXISTS idx_component_attachments_component_id ON component_attachments(component_id);
+
+-- Component comments; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_comments (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    body STRING NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp()
+);
+CREATE INDEX IF NOT EXISTS idx_component_comments_component_id ON component_comments(component_id);
+
+-- Component watches; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_watches (
+    component_id INT8 NOT NULL REFERENCES components(id) ON DELETE CASCADE,
+    principal VARCHAR(255) NOT NULL,
+    subtree BOOL NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (component_id, principal)
+);
+CREATE INDEX IF NOT EXISTS idx_component_watches_principal ON component_watches(principal);
+
+-- Webhooks and their deliveries; see schema.sql.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp()
+);
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    webhook_id INT8 NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id CHAR(32) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    component_id INT8 NOT NULL,
+    attempt INT8 NOT NULL,
+    status_code INT8 NOT NULL DEFAULT 0,
+    error STRING,
+    response_body STRING,
+    duration_ms INT8 NOT NULL DEFAULT 0,
+    succeeded BOOL NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp()
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id_created_at ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+-- Event schema versions of webhooks; see schema.sql.
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS schema_version INT8 NOT NULL DEFAULT 1;
+
+-- Delivery payloads, for replays; see schema.sql.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS payload STRING;
+
+-- Idempotency keys; see schema.sql.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key VARCHAR(255) NOT NULL,
+    principal VARCHAR(255) NOT NULL DEFAULT '',
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT8 NOT NULL DEFAULT 0,
+    response_header STRING,
+    response_body STRING,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (idempotency_key, principal)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+-- Reporting views; see schema.sql.
+CREATE SCHEMA IF NOT EXISTS reporting;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS reporting.component_closure AS
+WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+    SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT closure.ancestor_id, c.id, closure.depth + 1
+    FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+    WHERE closure.depth < 10000
+)
+SELECT ancestor_id, descendant_id, depth FROM closure;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_component_closure_pair ON reporting.component_closure(ancestor_id, descendant_id);
+CREATE INDEX IF NOT EXISTS idx_component_closure_descendant ON reporting.component_closure(descendant_id, depth);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS reporting.component_flat AS
+WITH RECURSIVE paths (id, name, root_id, root_name, depth, ancestor_ids, ancestor_names) AS (
+    SELECT id, name::STRING, id, name::STRING, 0, ARRAY[]::INT8[], ARRAY[]::STRING[] FROM components WHERE parent_id IS NULL AND deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.name::STRING, p.root_id, p.root_name, p.depth + 1, p.ancestor_ids || p.id, p.ancestor_names || p.name
+    FROM paths p JOIN components c ON c.parent_id = p.id AND c.deleted_at IS NULL
+    WHERE p.depth < 10000
+)
+SELECT c.id, c.name, c.description, c.parent_id, p.ancestor_names[p.depth] AS parent_name,
+    p.root_id, p.root_name, p.depth, p.ancestor_ids, p.ancestor_names,
+    (SELECT COUNT(*) FROM components child WHERE child.parent_id = c.id AND child.deleted_at IS NULL) AS child_count,
+    (SELECT COUNT(*) - 1 FROM reporting.component_closure cc WHERE cc.ancestor_id = c.id) AS descendant_count,
+    c.created_at, c.updated_at
+FROM paths p JOIN components c ON c.id = p.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_component_flat_id ON reporting.component_flat(id);
+CREATE INDEX IF NOT EXISTS idx_component_flat_root_id ON reporting.component_flat(root_id);
@@ -0,0 +1,250 @@
+-- schema_mysql.sql
+-- MySQL/MariaDB equivalent of schema.sql, used with DB_DRIVER=mysql.
+
+CREATE TABLE IF NOT EXISTS components (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    parent_id BIGINT NULL,
+    -- Order among siblings (POST /components/{id}/reorder). Tables created before the column
+    -- existed need: ALTER TABLE components ADD COLUMN position BIGINT NOT NULL DEFAULT 0,
+    -- ADD INDEX idx_components_parent_id_position (parent_id, position);
+    position BIGINT NOT NULL DEFAULT 0,
+    -- Soft deletes; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE components ADD COLUMN deleted_at TIMESTAMP(6) NULL;
+    deleted_at TIMESTAMP(6) NULL,
+    -- Slugs; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE components ADD COLUMN slug VARCHAR(255) NULL;
+    -- UPDATE components SET slug = CONCAT(COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(LOWER(
+    --     REGEXP_REPLACE(name, '[^a-zA-Z0-9]+', '-')), 100)), ''), 'component'), '-', id);
+    -- ALTER TABLE components MODIFY slug VARCHAR(255) NOT NULL, ADD UNIQUE INDEX idx_components_slug (slug);
+    slug VARCHAR(255) NOT NULL,
+    -- Component types; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE components ADD COLUMN type VARCHAR(64) NOT NULL DEFAULT '', ADD INDEX idx_components_type (type);
+    type VARCHAR(64) NOT NULL DEFAULT '',
+    -- Lifecycle status; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE components ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'active';
+    status VARCHAR(32) NOT NULL DEFAULT 'active',
+    -- Versions; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE components ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+    version BIGINT NOT NULL DEFAULT 1,
+    -- Component metadata; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE components ADD COLUMN metadata JSON NULL;
+    metadata JSON NULL,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    -- ON UPDATE replaces the PostgreSQL update_updated_at_column trigger
+    updated_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_components_parent FOREIGN KEY (parent_id) REFERENCES components(id) ON DELETE SET NULL,
+    UNIQUE INDEX idx_components_slug (slug),
+    INDEX idx_components_type (type),
+    INDEX idx_components_parent_id (parent_id),
+    INDEX idx_components_parent_id_name (parent_id, name),
+    INDEX idx_components_parent_id_position (parent_id, position),
+    INDEX idx_components_created_at_id (created_at, id),
+    INDEX idx_components_updated_at (updated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Optional: unique sibling names; see schema.sql. MySQL has no partial indexes: the functional key
+-- part is NULL for soft-deleted components, and NULLs never collide. It needs MySQL 8.0.13 or later.
+-- CREATE UNIQUE INDEX idx_components_sibling_name ON components(
+--     (CASE WHEN deleted_at IS NULL THEN COALESCE(parent_id, 0) END), name);
+
+CREATE TABLE IF NOT EXISTS component_acl (
+    component_id BIGINT NOT NULL,
+    principal VARCHAR(255) NOT NULL,
+    permission VARCHAR(16) NOT NULL,
+    PRIMARY KEY (component_id, principal),
+    CONSTRAINT fk_component_acl_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE,
+    CONSTRAINT chk_component_acl_permission CHECK (permission IN ('none', 'read', 'write', 'admin'))
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    component_id BIGINT NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    expires_at TIMESTAMP(6) NULL,
+    revoked_at TIMESTAMP(6) NULL,
+    CONSTRAINT fk_share_links_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE,
+    UNIQUE INDEX idx_share_links_token_hash (token_hash),
+    INDEX idx_share_links_component_id (component_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS public_components (
+    component_id BIGINT PRIMARY KEY,
+    published_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_public_components_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Closure table; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_closure (
+    ancestor_id BIGINT NOT NULL,
+    descendant_id BIGINT NOT NULL,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id),
+    INDEX idx_component_closure_descendant_id (descendant_id, depth),
+    CONSTRAINT fk_component_closure_ancestor FOREIGN KEY (ancestor_id) REFERENCES components(id) ON DELETE CASCADE,
+    CONSTRAINT fk_component_closure_descendant FOREIGN KEY (descendant_id) REFERENCES components(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT INTO component_closure (ancestor_id, descendant_id, depth)
+WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+    SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT closure.ancestor_id, c.id, closure.depth + 1
+    FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+    WHERE closure.depth < 10000
+)
+SELECT ancestor_id, descendant_id, MIN(depth) FROM closure
+WHERE NOT EXISTS (SELECT 1 FROM component_closure)
+GROUP BY ancestor_id, descendant_id;
+
+-- Import ID mapping; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_import_sources (
+    source VARCHAR(255) PRIMARY KEY,
+    last_imported_at TIMESTAMP(6) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE IF NOT EXISTS component_id_map (
+    source VARCHAR(255) NOT NULL,
+    source_id BIGINT NOT NULL,
+    component_id BIGINT NOT NULL,
+    PRIMARY KEY (source, source_id),
+    INDEX idx_component_id_map_component_id (component_id),
+    CONSTRAINT fk_component_id_map_source FOREIGN KEY (source) REFERENCES component_import_sources(source) ON DELETE CASCADE,
+    CONSTRAINT fk_component_id_map_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Component tags; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_tags (
+    component_id BIGINT NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (component_id, tag),
+    INDEX idx_component_tags_tag (tag),
+    CONSTRAINT fk_component_tags_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Attribute schemas; see schema.sql.
+CREATE TABLE IF NOT EXISTS attribute_schemas (
+    type VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    data_type VARCHAR(16) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values TEXT,
+    position INT NOT NULL,
+    PRIMARY KEY (type, name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Attribute schema options; see schema.sql.
+CREATE TABLE IF NOT EXISTS attribute_schema_options (
+    type VARCHAR(64) PRIMARY KEY,
+    strict BOOLEAN NOT NULL DEFAULT FALSE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Component attachments; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_attachments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    component_id BIGINT NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(512) NOT NULL,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_component_attachments_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE,
+    INDEX idx_component_attachments_component_id (component_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Component comments; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_comments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    component_id BIGINT NOT NULL,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_component_comments_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE,
+    INDEX idx_component_comments_component_id (component_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Component watches; see schema.sql.
+CREATE TABLE IF NOT EXISTS component_watches (
+    component_id BIGINT NOT NULL,
+    principal VARCHAR(255) NOT NULL,
+    subtree BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (component_id, principal),
+    CONSTRAINT fk_component_watches_component FOREIGN KEY (component_id) REFERENCES components(id) ON DELETE CASCADE,
+    INDEX idx_component_watches_principal (principal)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Webhooks and their deliveries; see schema.sql.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events VARCHAR(255) NOT NULL DEFAULT '',
+    -- Event schema versions; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE webhooks ADD COLUMN schema_version INT NOT NULL DEFAULT 1;
+    schema_version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id BIGINT NOT NULL,
+    event_id CHAR(32) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    component_id BIGINT NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error TEXT,
+    response_body TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    succeeded BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    -- Delivery payloads, for replays; see schema.sql. Tables created before the column existed need:
+    -- ALTER TABLE webhook_deliveries ADD COLUMN payload MEDIUMTEXT NULL;
+    payload MEDIUMTEXT NULL,
+    CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    INDEX idx_webhook_deliveries_webhook_id_created_at (webhook_id, created_at),
+    INDEX idx_webhook_deliveries_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Idempotency keys; see schema.sql.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key VARCHAR(255) NOT NULL,
+    principal VARCHAR(255) NOT NULL DEFAULT '',
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    response_header TEXT,
+    response_body MEDIUMTEXT,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (idempotency_key, principal),
+    INDEX idx_idempotency_keys_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Reporting views; see schema.sql. MySQL has neither materialized views nor schemas apart from
+-- databases, so these are plain views, prefixed reporting_, that are current on every read and
+-- need no refresh. Ancestor lists are JSON arrays.
+CREATE OR REPLACE VIEW reporting_component_closure AS
+WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+    SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT closure.ancestor_id, c.id, closure.depth + 1
+    FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+    WHERE closure.depth < 10000
+)
+SELECT ancestor_id, descendant_id, depth FROM closure;
+
+CREATE OR REPLACE VIEW reporting_component_flat AS
+WITH RECURSIVE paths (id, name, root_id, root_name, depth, ancestor_ids, ancestor_names) AS (
+    SELECT id, name, id, name, 0, JSON_ARRAY(), JSON_ARRAY() FROM components WHERE parent_id IS NULL AND deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.name, p.root_id, p.root_name, p.depth + 1,
+        JSON_ARRAY_APPEND(p.ancestor_ids, '$', p.id), JSON_ARRAY_APPEND(p.ancestor_names, '$', p.name)
+    FROM paths p JOIN components c ON c.parent_id = p.id AND c.deleted_at IS NULL
+    WHERE p.depth < 10000
+)
+SELECT c.id, c.name, c.description, c.parent_id, parent.name AS parent_name,
+    p.root_id, p.root_name, p.depth, p.ancestor_ids, p.ancestor_names,
+    (SELECT COUNT(*) FROM components child WHERE child.parent_id = c.id AND child.deleted_at IS NULL) AS child_count,
+    (SELECT COUNT(*) - 1 FROM reporting_component_closure cc WHERE cc.ancestor_id = c.id) AS descendant_count,
+    c.created_at, c.updated_at
+FROM paths p JOIN components c ON c.id = p.id LEFT JOIN components parent ON parent.id = c.parent_id;
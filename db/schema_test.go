@@ -0,0 +1,57 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestSchemaApplies executes the schema file of the configured DB_DRIVER against the test
+// database, so every supported backend (including CockroachDB) is validated by the same test.
+// Like the store and API tests, it requires DB_HOST, DB_USER and DB_NAME to point at a
+// dedicated test database.
+func TestSchemaApplies(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" || os.Getenv("DB_USER") == "" || os.Getenv("DB_NAME") == "" {
+		t.Skip("Skipping schema test: DB_HOST, DB_USER, or DB_NAME environment variables not set.")
+	}
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+
+	schemaBytes, err := os.ReadFile(CurrentDialect.SchemaFile())
+	if err != nil {
+		t.Fatalf("Error reading %s: %v", CurrentDialect.SchemaFile(), err)
+	}
+
+	// lib/pq runs multi-statement scripts in one Exec; the MySQL driver needs them split.
+	// The schema is applied twice to check that it is idempotent.
+	for pass := 1; pass <= 2; pass++ {
+		if _, ok := CurrentDialect.(MySQLDialect); ok {
+			for _, stmt := range strings.Split(string(schemaBytes), ";") {
+				if strings.TrimSpace(stripSQLComments(stmt)) == "" {
+					continue
+				}
+				if _, err := DB.Exec(stmt); err != nil {
+					t.Fatalf("Pass %d: error applying %s statement %q: %v", pass, CurrentDialect.SchemaFile(), stmt, err)
+				}
+			}
+		} else if _, err := DB.Exec(string(schemaBytes)); err != nil {
+			t.Fatalf("Pass %d: error applying %s: %v", pass, CurrentDialect.SchemaFile(), err)
+		}
+	}
+
+	var count int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM components").Scan(&count); err != nil {
+		t.Errorf("components table not queryable after applying schema: %v", err)
+	}
+}
+
+func stripSQLComments(stmt string) string {
+	var lines []string
+	for _, line := range strings.Split(stmt, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// maxTxRetries bounds how many times ExecuteTx re-runs a transaction after a retryable error.
+const maxTxRetries = 5
+
+// txRetryBaseDelay is the backoff before the first retry; it doubles on every attempt.
+var txRetryBaseDelay = 10 * time.Millisecond
+
+// ExecuteTx runs fn inside a transaction and commits it, retrying when the database reports
+// a serialization failure or deadlock. fn may therefore run more than once and must not have
+// side effects outside the transaction (cache updates belong after ExecuteTx returns).
+//
+// On CockroachDB the retries follow the client-side protocol: the transaction opens
+// SAVEPOINT cockroach_restart and rolls back to it between attempts, keeping its priority.
+func ExecuteTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	if _, ok := CurrentDialect.(CockroachDialect); ok {
+		return executeCockroachTx(conn, fn)
+	}
+
+	var err error
+	for attempt := 0; attempt <= maxTxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(txRetryBaseDelay << (attempt - 1))
+		}
+		err = runTx(conn, fn)
+		if err == nil || !IsRetryableError(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("transaction failed after %d retries: %w", maxTxRetries, err)
+}
+
+func runTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func executeCockroachTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("SAVEPOINT cockroach_restart"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err = fn(tx)
+		if err == nil {
+			// RELEASE is where CockroachDB reports most retryable errors, so it stays inside the loop.
+			if _, err = tx.Exec("RELEASE SAVEPOINT cockroach_restart"); err == nil {
+				return tx.Commit()
+			}
+		}
+		if !IsRetryableError(err) {
+			tx.Rollback()
+			return err
+		}
+		if attempt == maxTxRetries {
+			tx.Rollback()
+			return fmt.Errorf("transaction failed after %d retries: %w", maxTxRetries, err)
+		}
+		if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT cockroach_restart"); rbErr != nil {
+			tx.Rollback()
+			return rbErr
+		}
+	}
+}
+
+// IsRetryableError reports whether err is a transient concurrency failure after which the
+// whole transaction can be safely retried: a serialization failure (SQLSTATE 40001, which is
+// how CockroachDB reports every restart) or a deadlock (40P01, MySQL 1213).
+func IsRetryableError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213
+	}
+	return false
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation (SQLSTATE 23505, MySQL
+// 1062), as when two transactions race to claim the same value.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "nil", err: nil, retryable: false},
+		{name: "plain error", err: errors.New("boom"), retryable: false},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, retryable: true},
+		{name: "wrapped serialization failure", err: fmt.Errorf("error updating component: %w", &pq.Error{Code: "40001"}), retryable: true},
+		{name: "postgres deadlock", err: &pq.Error{Code: "40P01"}, retryable: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, retryable: false},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, retryable: true},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}, retryable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.retryable {
+				t.Errorf("IsRetryableError(%v) = %v, expected %v", tt.err, got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		unique bool
+	}{
+		{name: "nil", err: nil, unique: false},
+		{name: "plain error", err: errors.New("boom"), unique: false},
+		{name: "postgres unique violation", err: &pq.Error{Code: "23505"}, unique: true},
+		{name: "wrapped unique violation", err: fmt.Errorf("error creating component: %w", &pq.Error{Code: "23505"}), unique: true},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, unique: false},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}, unique: true},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, unique: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUniqueViolation(tt.err); got != tt.unique {
+				t.Errorf("IsUniqueViolation(%v) = %v, expected %v", tt.err, got, tt.unique)
+			}
+		})
+	}
+}
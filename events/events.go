@@ -0,0 +1,85 @@
+// Package events publishes component changes to in-process subscribers once they are committed.
+// Events are computed by the store, which is the only place that knows both the state before a
+// write and the state after it.
+package events
+
+import (
+	"component-service/models"
+	"component-service/tracing"
+	"sync"
+	"time"
+)
+
+// Type names the kind of change an event describes.
+type Type string
+
+const (
+	ComponentCreated Type = "component.created"
+	ComponentUpdated Type = "component.updated" // any change that keeps the parent
+	ComponentMoved   Type = "component.moved"   // parent changed, possibly along with other fields
+	ComponentDeleted Type = "component.deleted"
+)
+
+// Event describes one committed change to a component.
+//
+// OldParentID and NewParentID are the component's parent before and after the change, null for
+// a root. A move carries both, so consumers can update the branch the component left and the
+// branch it joined without refetching either. OldParentID is always null on create and
+// NewParentID on delete; on a plain update both hold the unchanged parent.
+//
+// Trace identifies the HTTP request that made the change, so consumers can follow it back; it is
+// nil for changes no request made, such as those applied from change data capture.
+type Event struct {
+	Type        Type              `json:"type"`
+	ComponentID int64             `json:"component_id"`
+	OldParentID *int64            `json:"old_parent_id"`
+	NewParentID *int64            `json:"new_parent_id"`
+	Component   *models.Component `json:"component,omitempty"` // state after the change; nil on delete or when not read
+	OccurredAt  time.Time         `json:"occurred_at"`
+	Trace       *tracing.Context  `json:"trace,omitempty"`
+}
+
+// Handler receives published events. It runs on the publishing goroutine, so it must return
+// quickly and must not modify the event's component.
+type Handler func(Event)
+
+var (
+	mu       sync.RWMutex
+	handlers = map[int]Handler{}
+	nextID   int
+)
+
+// Subscribe registers h for every event published from now on. The returned function removes it.
+func Subscribe(h Handler) (unsubscribe func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	id := nextID
+	nextID++
+	handlers[id] = h
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(handlers, id)
+	}
+}
+
+// HasSubscribers reports whether any handler is registered, so publishers can skip work
+// needed only to build events.
+func HasSubscribers() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(handlers) > 0
+}
+
+// Publish delivers e to every subscriber.
+func Publish(e Event) {
+	mu.RLock()
+	subscribers := make([]Handler, 0, len(handlers))
+	for _, h := range handlers {
+		subscribers = append(subscribers, h)
+	}
+	mu.RUnlock()
+	for _, h := range subscribers {
+		h(e)
+	}
+}
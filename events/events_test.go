@@ -0,0 +1,29 @@
+package events
+
+import "testing"
+
+func TestSubscribePublish(t *testing.T) {
+	if HasSubscribers() {
+		t.Fatal("Expected no subscribers initially")
+	}
+	var got []int64
+	unsubscribe := Subscribe(func(e Event) { got = append(got, e.ComponentID) })
+	if !HasSubscribers() {
+		t.Fatal("Expected a subscriber after Subscribe")
+	}
+	Publish(Event{Type: ComponentCreated, ComponentID: 1})
+
+	// A handler may unsubscribe itself while being delivered to.
+	var selfRemoving func()
+	selfRemoving = Subscribe(func(Event) { selfRemoving() })
+	Publish(Event{Type: ComponentUpdated, ComponentID: 2})
+	unsubscribe()
+	Publish(Event{Type: ComponentDeleted, ComponentID: 3})
+
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Expected events [1 2], got %v", got)
+	}
+	if HasSubscribers() {
+		t.Error("Expected no subscribers after unsubscribing")
+	}
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFeedHistory is the number of events a Feed keeps unless configured otherwise.
+const DefaultFeedHistory = 1000
+
+// FeedEntry is an event in a Feed, with the ID it was numbered with.
+type FeedEntry struct {
+	ID    string
+	Event Event
+}
+
+// Feed numbers published events and keeps the most recent ones, so that a client streaming them
+// can resume after a disconnect from the last one it received. IDs have the form
+// {epoch}-{sequence}: the epoch is the time the feed was created, so an ID given out before a
+// restart is recognized as unknown instead of being mistaken for a recent one.
+type Feed struct {
+	epoch string
+
+	mu      sync.Mutex
+	ring    []Event // the kept events, oldest at ring[(next-count)%len(ring)]
+	count   int
+	next    uint64        // sequence of the next event, from 1
+	changed chan struct{} // closed and replaced whenever an event is added
+
+	unsubscribe func()
+}
+
+// NewFeed returns a feed keeping the last history events. It receives nothing until Start.
+func NewFeed(history int) *Feed {
+	return &Feed{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		ring:    make([]Event, history),
+		next:    1,
+		changed: make(chan struct{}),
+	}
+}
+
+// FeedFromEnv returns a feed keeping the number of events in CHANGE_FEED_HISTORY, which defaults
+// to DefaultFeedHistory. It returns nil for 0, which disables the feed.
+func FeedFromEnv() (*Feed, error) {
+	history := DefaultFeedHistory
+	if value := os.Getenv("CHANGE_FEED_HISTORY"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid CHANGE_FEED_HISTORY %q: expected a non-negative integer", value)
+		}
+		history = parsed
+	}
+	if history == 0 {
+		return nil, nil
+	}
+	return NewFeed(history), nil
+}
+
+// Start subscribes the feed to every event published from now on.
+func (f *Feed) Start() {
+	f.unsubscribe = Subscribe(f.add)
+}
+
+// Stop unsubscribes the feed. The events it kept can still be read.
+func (f *Feed) Stop() {
+	if f.unsubscribe != nil {
+		f.unsubscribe()
+	}
+}
+
+// add numbers e and keeps it, dropping the oldest event when the feed is full.
+func (f *Feed) add(e Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ring[f.next%uint64(len(f.ring))] = e
+	f.next++
+	if f.count < len(f.ring) {
+		f.count++
+	}
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// LastID returns the ID of the newest event, or of the position before the first one while
+// there is none, to resume after.
+func (f *Feed) LastID() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.id(f.next - 1)
+}
+
+// After returns the kept events following the one with ID lastID, oldest first, and a channel
+// closed once another event is added. ok is false when the feed cannot tell what followed
+// lastID: it was not given out by this feed, or events after it have been dropped. The caller
+// should then resume from LastID, having lost what came in between.
+func (f *Feed) After(lastID string) (entries []FeedEntry, changed <-chan struct{}, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	epoch, sequence, found := strings.Cut(lastID, "-")
+	last, err := strconv.ParseUint(sequence, 10, 64)
+	oldest := f.next - uint64(f.count)
+	if !found || err != nil || epoch != f.epoch || last >= f.next || last+1 < oldest {
+		return nil, f.changed, false
+	}
+	for s := last + 1; s < f.next; s++ {
+		entries = append(entries, FeedEntry{ID: f.id(s), Event: f.ring[s%uint64(len(f.ring))]})
+	}
+	return entries, f.changed, true
+}
+
+func (f *Feed) id(sequence uint64) string {
+	return f.epoch + "-" + strconv.FormatUint(sequence, 10)
+}
@@ -0,0 +1,71 @@
+package events
+
+import "testing"
+
+func TestFeed(t *testing.T) {
+	f := NewFeed(3)
+	f.Start()
+	defer f.Stop()
+
+	start := f.LastID()
+	entries, changed, ok := f.After(start)
+	if !ok || len(entries) != 0 {
+		t.Fatalf("After(%q) of an empty feed = %v, %v; want nothing, ok", start, entries, ok)
+	}
+	Publish(Event{ComponentID: 1})
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Expected the channel from After to be closed by a new event")
+	}
+	Publish(Event{ComponentID: 2})
+
+	entries, _, ok = f.After(start)
+	if !ok || len(entries) != 2 || entries[0].Event.ComponentID != 1 || entries[1].Event.ComponentID != 2 {
+		t.Fatalf("After(%q) = %v, %v; want events 1 and 2", start, entries, ok)
+	}
+	first := entries[0].ID
+	if entries, _, ok = f.After(first); !ok || len(entries) != 1 || entries[0].Event.ComponentID != 2 {
+		t.Errorf("After(%q) = %v, %v; want event 2", first, entries, ok)
+	}
+	if entries, _, ok = f.After(f.LastID()); !ok || len(entries) != 0 {
+		t.Errorf("After(LastID) = %v, %v; want nothing, ok", entries, ok)
+	}
+
+	// Two more events push the first out: resuming after the start would miss it.
+	Publish(Event{ComponentID: 3})
+	Publish(Event{ComponentID: 4})
+	if _, _, ok = f.After(start); ok {
+		t.Error("After an ID whose successor was dropped should not be ok")
+	}
+	if entries, _, ok = f.After(first); !ok || len(entries) != 3 || entries[0].Event.ComponentID != 2 {
+		t.Errorf("After(%q) = %v, %v; want events 2 to 4", first, entries, ok)
+	}
+
+	for _, id := range []string{"", "garbage", "0-1", NewFeed(3).LastID(), f.epoch + "-99"} {
+		if _, _, ok := f.After(id); ok {
+			t.Errorf("After(%q) should not be ok", id)
+		}
+	}
+}
+
+func TestFeedFromEnv(t *testing.T) {
+	for value, wantHistory := range map[string]int{"": DefaultFeedHistory, "5": 5, "0": 0} {
+		t.Setenv("CHANGE_FEED_HISTORY", value)
+		f, err := FeedFromEnv()
+		switch {
+		case err != nil:
+			t.Errorf("CHANGE_FEED_HISTORY=%q: %v", value, err)
+		case wantHistory == 0 && f != nil:
+			t.Errorf("CHANGE_FEED_HISTORY=%q returned a feed; want none", value)
+		case wantHistory != 0 && (f == nil || len(f.ring) != wantHistory):
+			t.Errorf("CHANGE_FEED_HISTORY=%q returned %v; want a feed keeping %d events", value, f, wantHistory)
+		}
+	}
+	for _, value := range []string{"-1", "many"} {
+		t.Setenv("CHANGE_FEED_HISTORY", value)
+		if _, err := FeedFromEnv(); err == nil {
+			t.Errorf("CHANGE_FEED_HISTORY=%q should be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the format events are sent out in, by webhooks and the change
+// streams, unless a receiver asks for an older one. It changes only when a field is removed or
+// changes meaning; added fields keep it.
+const SchemaVersion = 1
+
+// SchemaVersions are the versions events can be sent out in, oldest first. A version replaced by
+// a new SchemaVersion stays listed for a deprecation window, so receivers move to the new one at
+// their own pace, and is then removed.
+var SchemaVersions = []int{1}
+
+// Types are the event types, in the order they are documented.
+var Types = []Type{ComponentCreated, ComponentUpdated, ComponentMoved, ComponentDeleted}
+
+// message is an event as sent out in version 1: the event's fields, with the version and, from
+// webhooks, the event's ID.
+type message struct {
+	ID            string `json:"id,omitempty"`
+	SchemaVersion int    `json:"schema_version"`
+	Event
+}
+
+// Encode returns e as sent out in schema version version, as JSON. id, when not empty, is sent as
+// the event's "id".
+func Encode(e Event, version int, id string) ([]byte, error) {
+	switch version {
+	case 1:
+		return json.Marshal(message{ID: id, SchemaVersion: 1, Event: e})
+	default:
+		return nil, fmt.Errorf("events are not sent in schema version %d: expected one of %v", version, SchemaVersions)
+	}
+}
+
+// IsSchemaVersion reports whether events can be sent out in version.
+func IsSchemaVersion(version int) bool {
+	for _, v := range SchemaVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema returns the JSON Schema of events of type t in schema version version, or nil when
+// there is none. Its $id is the path it is published at.
+func Schema(t Type, version int) map[string]interface{} {
+	if version != 1 || !isType(t) {
+		return nil
+	}
+	id := map[string]interface{}{"type": "integer"}
+	nullableID := map[string]interface{}{"type": []string{"integer", "null"}}
+	null := map[string]interface{}{"type": "null"}
+	oldParent, newParent := interface{}(nullableID), interface{}(nullableID)
+	component := interface{}(map[string]interface{}{
+		"$ref":        "#/$defs/component",
+		"description": "The component after the change; absent when it could not be read",
+	})
+	switch t {
+	case ComponentCreated:
+		oldParent = null
+	case ComponentDeleted:
+		newParent, component = null, false
+	}
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         SchemaPath(t, version),
+		"title":       string(t),
+		"description": typeDescriptions[t],
+		"type":        "object",
+		"required":    []string{"schema_version", "type", "component_id", "old_parent_id", "new_parent_id", "occurred_at"},
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type": "string", "pattern": "^[0-9a-f]{32}$",
+				"description": "Sent by webhooks: the same in every attempt and for every webhook",
+			},
+			"schema_version": map[string]interface{}{"const": version},
+			"type":           map[string]interface{}{"const": string(t)},
+			"component_id":   id,
+			"old_parent_id":  oldParent,
+			"new_parent_id":  newParent,
+			"component":      component,
+			"occurred_at":    map[string]interface{}{"type": "string", "format": "date-time"},
+			"trace": map[string]interface{}{
+				"type":        "object",
+				"description": "The request that made the change; absent for changes no request made",
+				"required":    []string{"traceparent", "request_id"},
+				"properties": map[string]interface{}{
+					"traceparent": map[string]interface{}{"type": "string"},
+					"tracestate":  map[string]interface{}{"type": "string"},
+					"request_id":  map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+		"$defs": map[string]interface{}{
+			"component": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id", "name", "description", "parent_id", "position"},
+				"properties": map[string]interface{}{
+					"id":          id,
+					"name":        map[string]interface{}{"type": "string"},
+					"slug":        map[string]interface{}{"type": "string"},
+					"type":        map[string]interface{}{"type": "string"},
+					"status":      map[string]interface{}{"type": "string"},
+					"tags":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					"description": map[string]interface{}{"type": "string"},
+					"metadata":    map[string]interface{}{"type": "object"},
+					"parent_id": map[string]interface{}{
+						"type":        "object",
+						"description": "Valid is false for a root",
+						"required":    []string{"Int64", "Valid"},
+						"properties": map[string]interface{}{
+							"Int64": id,
+							"Valid": map[string]interface{}{"type": "boolean"},
+						},
+					},
+					"position":   map[string]interface{}{"type": "integer"},
+					"version":    map[string]interface{}{"type": "integer"},
+					"created_at": map[string]interface{}{"type": "string", "format": "date-time"},
+					"updated_at": map[string]interface{}{"type": "string", "format": "date-time"},
+				},
+			},
+		},
+	}
+}
+
+// SchemaPath returns the path the JSON Schema of events of type t in version is published at.
+func SchemaPath(t Type, version int) string {
+	return fmt.Sprintf("/schemas/v%d/%s.json", version, t)
+}
+
+var typeDescriptions = map[Type]string{
+	ComponentCreated: "A component was created. old_parent_id is null.",
+	ComponentUpdated: "A component changed, keeping its parent: old_parent_id and new_parent_id are that parent.",
+	ComponentMoved:   "A component's parent changed, possibly along with other fields.",
+	ComponentDeleted: "A component was deleted. new_parent_id is null, and component is absent.",
+}
+
+func isType(t Type) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"component-service/models"
+	"component-service/tracing"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	parent := int64(1)
+	e := Event{Type: ComponentMoved, ComponentID: 2, OldParentID: nil, NewParentID: &parent, OccurredAt: time.Unix(0, 0).UTC()}
+	data, err := Encode(e, 1, "0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"0123456789abcdef0123456789abcdef","schema_version":1,"type":"component.moved","component_id":2,"old_parent_id":null,"new_parent_id":1,"occurred_at":"1970-01-01T00:00:00Z"}`
+	if string(data) != want {
+		t.Errorf("Encode = %s, want %s", data, want)
+	}
+	if data, _ := Encode(e, 1, ""); strings.Contains(string(data), `"id"`) {
+		t.Errorf("Expected no id without one, got %s", data)
+	}
+	if _, err := Encode(e, 99, ""); err == nil {
+		t.Error("Expected an unknown schema version to be refused")
+	}
+}
+
+// TestSchemaCoversEvents catches fields added to events, or to components, without the schema.
+func TestSchemaCoversEvents(t *testing.T) {
+	parent := int64(1)
+	full := Event{
+		Type: ComponentUpdated, ComponentID: 2, OldParentID: &parent, NewParentID: &parent, OccurredAt: time.Now(),
+		Component: &models.Component{
+			ID: 2, Name: "pump", Slug: "pump", Type: "device", Status: "active", Tags: []string{"a"}, Description: "d",
+			Metadata: json.RawMessage(`{"k":1}`), ParentID: sql.NullInt64{Int64: 1, Valid: true}, Position: 1, Version: 2,
+			CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-01T00:00:00Z",
+		},
+		Trace: &tracing.Context{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01", TraceState: "a=b", RequestID: "r"},
+	}
+	for _, version := range SchemaVersions {
+		data, err := Encode(full, version, "0123456789abcdef0123456789abcdef")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		nested := map[string]map[string]interface{}{}
+		for _, name := range []string{"component", "trace"} {
+			var members map[string]interface{}
+			if err := json.Unmarshal(fields[name], &members); err != nil {
+				t.Fatal(err)
+			}
+			nested[name] = members
+		}
+
+		for _, eventType := range Types {
+			schema := Schema(eventType, version)
+			if schema == nil {
+				t.Fatalf("No schema for %s in version %d", eventType, version)
+			}
+			properties := schema["properties"].(map[string]interface{})
+			for name := range fields {
+				if _, found := properties[name]; !found {
+					t.Errorf("%s v%d: field %q is not in the schema", eventType, version, name)
+				}
+			}
+			component := schema["$defs"].(map[string]interface{})["component"].(map[string]interface{})["properties"].(map[string]interface{})
+			for name := range nested["component"] {
+				if _, found := component[name]; !found {
+					t.Errorf("%s v%d: component field %q is not in the schema", eventType, version, name)
+				}
+			}
+			trace := properties["trace"].(map[string]interface{})["properties"].(map[string]interface{})
+			for name := range nested["trace"] {
+				if _, found := trace[name]; !found {
+					t.Errorf("%s v%d: trace field %q is not in the schema", eventType, version, name)
+				}
+			}
+		}
+	}
+	if Schema("component.renamed", SchemaVersion) != nil || Schema(ComponentCreated, 99) != nil {
+		t.Error("Expected no schema for an unknown type or version")
+	}
+	if !IsSchemaVersion(SchemaVersion) {
+		t.Error("Expected the current version to be sent")
+	}
+}
@@ -0,0 +1,205 @@
+// Package federation mounts subtrees of other explorer instances read-only under local
+// components, so one instance can present an org-wide tree spanning team-owned deployments. Each
+// mount replicates its instance's components into a private cache through the /sync endpoints, as
+// a follower does, and its subtree is grafted under the local component when trees are built. The
+// mounted components keep their remote IDs and are never written here.
+package federation
+
+import (
+	"component-service/cache"
+	"component-service/follower"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPollInterval = 10 * time.Second
+
+// validMountName restricts mount names to what can appear in a URL path segment unescaped.
+var validMountName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Mount attaches the subtree rooted at RemoteRootID on the instance at RemoteURL under the local
+// component ParentID.
+type Mount struct {
+	Name         string
+	ParentID     int64
+	RemoteURL    *url.URL // the remote instance's base URL
+	RemoteRootID int64
+
+	replica *follower.Follower
+}
+
+// Federation is the set of configured mounts.
+type Federation struct {
+	Mounts []*Mount
+
+	byParentID map[int64][]*Mount
+}
+
+// FromEnv configures federation from the environment:
+//
+//	FEDERATION_MOUNTS         comma-separated NAME:PARENT_ID:URL entries, where URL addresses the
+//	                          remote subtree's root, e.g. team-a:12:https://team-a.example/components/1
+//	FEDERATION_POLL_INTERVAL  how often mounts poll their instance for changes (default 10s)
+//
+// It returns a nil Federation when no mount is configured.
+func FromEnv() (*Federation, error) {
+	value := os.Getenv("FEDERATION_MOUNTS")
+	if value == "" {
+		return nil, nil
+	}
+	pollInterval := defaultPollInterval
+	if interval := os.Getenv("FEDERATION_POLL_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid FEDERATION_POLL_INTERVAL %q: expected a positive duration", interval)
+		}
+		pollInterval = parsed
+	}
+	var mounts []*Mount
+	for _, entry := range strings.Split(value, ",") {
+		mount, err := parseMount(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		mount.replica = &follower.Follower{
+			PrimaryURL:   mount.RemoteURL,
+			PollInterval: pollInterval,
+			Client:       &http.Client{Timeout: 30 * time.Second},
+			Private:      true,
+		}
+		mounts = append(mounts, mount)
+	}
+	return New(mounts)
+}
+
+// parseMount parses one FEDERATION_MOUNTS entry.
+func parseMount(entry string) (*Mount, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid FEDERATION_MOUNTS entry %q: %s; expected NAME:PARENT_ID:URL such as team-a:12:https://team-a.example/components/1", entry, reason)
+	}
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 {
+		return nil, invalid("missing fields")
+	}
+	if !validMountName.MatchString(parts[0]) {
+		return nil, invalid("the name may only hold letters, digits, '-' and '_'")
+	}
+	parentID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || parentID <= 0 {
+		return nil, invalid("the parent ID must be a positive integer")
+	}
+	remote, err := url.Parse(parts[2])
+	if err != nil || remote.Scheme == "" || remote.Host == "" {
+		return nil, invalid("the URL must be absolute")
+	}
+	base, rootID, found := strings.Cut(strings.TrimRight(remote.Path, "/"), "/components/")
+	if !found || strings.Contains(rootID, "/") {
+		return nil, invalid("the URL must address a component, ending in /components/{id}")
+	}
+	remoteRootID, err := strconv.ParseInt(rootID, 10, 64)
+	if err != nil || remoteRootID <= 0 {
+		return nil, invalid("the URL must end in a component ID")
+	}
+	return &Mount{
+		Name:         parts[0],
+		ParentID:     parentID,
+		RemoteURL:    &url.URL{Scheme: remote.Scheme, User: remote.User, Host: remote.Host, Path: base},
+		RemoteRootID: remoteRootID,
+	}, nil
+}
+
+// New indexes mounts, which must have distinct names.
+func New(mounts []*Mount) (*Federation, error) {
+	f := &Federation{Mounts: mounts, byParentID: make(map[int64][]*Mount)}
+	names := make(map[string]bool, len(mounts))
+	for _, mount := range mounts {
+		if names[mount.Name] {
+			return nil, fmt.Errorf("federation mount %q is configured more than once", mount.Name)
+		}
+		names[mount.Name] = true
+		f.byParentID[mount.ParentID] = append(f.byParentID[mount.ParentID], mount)
+	}
+	return f, nil
+}
+
+// Start begins replicating every mount in the background until ctx is cancelled. It does not
+// wait for the first sync: an unreachable instance leaves its mount out of trees rather than
+// keeping this one from starting.
+func (f *Federation) Start(ctx context.Context) {
+	for _, mount := range f.Mounts {
+		go func(mount *Mount) {
+			log.Printf("Mounting %s from %s under component %d", mount.Name, mount.RemoteURL, mount.ParentID)
+			if err := mount.replica.Start(ctx); err != nil {
+				return
+			}
+			mount.replica.Run(ctx)
+		}(mount)
+	}
+}
+
+// Mount returns the mount named name, or nil.
+func (f *Federation) Mount(name string) *Mount {
+	for _, mount := range f.Mounts {
+		if mount.Name == name {
+			return mount
+		}
+	}
+	return nil
+}
+
+// Grafts returns the synced mounts attached under the local component id, for cache.TreeOptions.
+// Each mounted root is marked with "mount" holding the mount's name.
+func (f *Federation) Grafts(id int64) []cache.Graft {
+	var grafts []cache.Graft
+	for _, mount := range f.byParentID[id] {
+		if replica := mount.Cache(); replica != nil {
+			grafts = append(grafts, cache.Graft{Cache: replica, RootID: mount.RemoteRootID, Mark: fmt.Sprintf(`"mount":%q`, mount.Name)})
+		}
+	}
+	return grafts
+}
+
+// Cache returns the mount's replica of its instance's components, or nil before the first sync.
+func (m *Mount) Cache() *cache.ComponentCache {
+	if m.replica == nil {
+		return nil
+	}
+	return m.replica.Cache()
+}
+
+// MountStatus reports a mount's configuration and how current its replica is.
+type MountStatus struct {
+	Name         string   `json:"name"`
+	ParentID     int64    `json:"parent_id"`
+	Remote       string   `json:"remote"`
+	RemoteRootID int64    `json:"remote_root_id"`
+	Synced       bool     `json:"synced"`
+	LagSeconds   *float64 `json:"lag_seconds"` // null before the first sync
+}
+
+// Status reports every mount, in configuration order.
+func (f *Federation) Status() []MountStatus {
+	statuses := make([]MountStatus, 0, len(f.Mounts))
+	for _, mount := range f.Mounts {
+		status := MountStatus{
+			Name:         mount.Name,
+			ParentID:     mount.ParentID,
+			Remote:       mount.RemoteURL.Redacted(),
+			RemoteRootID: mount.RemoteRootID,
+		}
+		if mount.Cache() != nil {
+			lag := mount.replica.Lag().Seconds()
+			status.Synced, status.LagSeconds = true, &lag
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
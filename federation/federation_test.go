@@ -0,0 +1,53 @@
+package federation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("FEDERATION_MOUNTS", "")
+	f, err := FromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, f, "Expected no federation without mounts")
+
+	t.Setenv("FEDERATION_MOUNTS", "team-a:12:https://team-a.example/explorer/components/1, team_b:12:http://10.0.0.5:8080/components/40/")
+	t.Setenv("FEDERATION_POLL_INTERVAL", "5s")
+	f, err = FromEnv()
+	require.NoError(t, err)
+	require.Len(t, f.Mounts, 2)
+	assert.Equal(t, "team-a", f.Mounts[0].Name)
+	assert.Equal(t, int64(12), f.Mounts[0].ParentID)
+	assert.Equal(t, "https://team-a.example/explorer", f.Mounts[0].RemoteURL.String())
+	assert.Equal(t, int64(1), f.Mounts[0].RemoteRootID)
+	assert.Equal(t, "http://10.0.0.5:8080", f.Mounts[1].RemoteURL.String())
+	assert.Equal(t, int64(40), f.Mounts[1].RemoteRootID)
+	assert.True(t, f.Mounts[1].replica.Private)
+	assert.Equal(t, "5s", f.Mounts[1].replica.PollInterval.String())
+
+	assert.Empty(t, f.Grafts(12), "Expected mounts to be left out before their first sync")
+	for _, status := range f.Status() {
+		assert.False(t, status.Synced)
+		assert.Nil(t, status.LagSeconds)
+	}
+
+	for _, invalid := range []string{
+		"team-a:12",
+		"team a:12:https://team-a.example/components/1",
+		"team-a:root:https://team-a.example/components/1",
+		"team-a:12:/components/1",
+		"team-a:12:https://team-a.example/components",
+		"team-a:12:https://team-a.example/components/1/tree",
+		"team-a:12:https://team-a.example/components/1,team-a:13:https://team-a.example/components/2",
+	} {
+		t.Setenv("FEDERATION_MOUNTS", invalid)
+		_, err := FromEnv()
+		assert.Error(t, err, invalid)
+	}
+	t.Setenv("FEDERATION_MOUNTS", "team-a:12:https://team-a.example/components/1")
+	t.Setenv("FEDERATION_POLL_INTERVAL", "often")
+	_, err = FromEnv()
+	assert.Error(t, err)
+}
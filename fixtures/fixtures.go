@@ -0,0 +1,328 @@
+// Package fixtures describes component hierarchies in YAML for tests, and loads them into the
+// database through a store or straight into the cache, so a test declares the world it runs in
+// instead of building it one create call at a time. A fixture reads:
+//
+//	components:
+//	  - name: plant
+//	    acl: {"*": read, alice: admin}
+//	    public: true
+//	    children:
+//	      - name: pump
+//	        description: Main feed pump
+//	        metadata: {vendor: acme, rated_kw: 7.5}
+//	      - key: spare-pump
+//	        name: pump
+//
+// Components are referred to by key, which defaults to the name and must be unique. They are
+// created depth first in document order, so loading a fixture always yields the same hierarchy
+// in the same order; loaded into the cache alone, the components are numbered 1, 2, ... in that
+// order.
+package fixtures
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed testdata/*.yaml
+var files embed.FS
+
+// Node is a component of a fixture, with its subtree.
+type Node struct {
+	Key         string                      `yaml:"key"`
+	Name        string                      `yaml:"name"`
+	Type        string                      `yaml:"type"`
+	Tags        []string                    `yaml:"tags"` // sorted, as components store them
+	Description string                      `yaml:"description"`
+	Metadata    map[string]interface{}      `yaml:"metadata"` // stored as a JSON object
+	ACL         map[string]cache.Permission `yaml:"acl"`      // the component's own entries, by principal
+	Public      bool                        `yaml:"public"`
+	Children    []Node                      `yaml:"children"`
+}
+
+// Fixture is a parsed fixture file.
+type Fixture struct {
+	Components []Node `yaml:"components"`
+}
+
+// Parse reads a fixture from YAML. Unknown fields, nameless components and duplicate keys are
+// errors.
+func Parse(data []byte) (*Fixture, error) {
+	var f Fixture
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("error parsing fixture: %w", err)
+	}
+	seen := make(map[string]bool)
+	var check func(nodes []Node) error
+	check = func(nodes []Node) error {
+		for _, node := range nodes {
+			if node.Name == "" {
+				return fmt.Errorf("error parsing fixture: a component has no name")
+			}
+			key := node.key()
+			if seen[key] {
+				return fmt.Errorf("error parsing fixture: duplicate key %q; give one of the components a key", key)
+			}
+			seen[key] = true
+			if err := check(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(f.Components); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Named reads the fixture file name.yaml shipped in this package's testdata directory.
+func Named(name string) (*Fixture, error) {
+	data, err := files.ReadFile("testdata/" + name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("error reading fixture %s: %w", name, err)
+	}
+	return Parse(data)
+}
+
+// key returns the key a node is referred to by.
+func (n Node) key() string {
+	if n.Key != "" {
+		return n.Key
+	}
+	return n.Name
+}
+
+// metadata returns the node's metadata as JSON, or nil for none.
+func (n Node) metadata() json.RawMessage {
+	if len(n.Metadata) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(n.Metadata)
+	if err != nil {
+		panic(fmt.Sprintf("fixture component %q: %v", n.key(), err)) // YAML maps always marshal
+	}
+	return data
+}
+
+// walk calls fn for each node depth first in document order, with the key of its parent, or ""
+// for the roots. It stops at the first error.
+func (f *Fixture) walk(fn func(node Node, parentKey string) error) error {
+	var visit func(nodes []Node, parentKey string) error
+	visit = func(nodes []Node, parentKey string) error {
+		for _, node := range nodes {
+			if err := fn(node, parentKey); err != nil {
+				return err
+			}
+			if err := visit(node.Children, node.key()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(f.Components, "")
+}
+
+// World is a loaded fixture: its components with their IDs, ACL entries and public flags.
+type World struct {
+	byKey      map[string]*models.Component
+	components []*models.Component // in creation order
+	entries    []cache.ACLEntry
+	publicIDs  []int64
+}
+
+func newWorld() *World {
+	return &World{byKey: make(map[string]*models.Component)}
+}
+
+// add records a loaded component with its fixture node.
+func (w *World) add(node Node, component *models.Component) {
+	w.byKey[node.key()] = component
+	w.components = append(w.components, component)
+	principals := make([]string, 0, len(node.ACL))
+	for principal := range node.ACL {
+		principals = append(principals, principal)
+	}
+	sort.Strings(principals)
+	for _, principal := range principals {
+		w.entries = append(w.entries, cache.ACLEntry{ComponentID: component.ID, Principal: principal, Permission: node.ACL[principal]})
+	}
+	if node.Public {
+		w.publicIDs = append(w.publicIDs, component.ID)
+	}
+}
+
+// parentID returns the ID of the component keyed parentKey, or a NULL for "".
+func (w *World) parentID(parentKey string) sql.NullInt64 {
+	if parentKey == "" {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: w.byKey[parentKey].ID, Valid: true}
+}
+
+// ID returns the ID of the component keyed key. It panics for an unknown key, a mistake in the
+// test itself.
+func (w *World) ID(key string) int64 {
+	return w.Component(key).ID
+}
+
+// Component returns the component keyed key as it was loaded. It panics for an unknown key.
+func (w *World) Component(key string) *models.Component {
+	component, found := w.byKey[key]
+	if !found {
+		panic(fmt.Sprintf("fixtures: no component keyed %q", key))
+	}
+	return component
+}
+
+// ListComponents returns the components in creation order, so a World can seed a cache.
+func (w *World) ListComponents() ([]*models.Component, error) { return w.components, nil }
+
+// ListACLEntries returns the components' ACL entries.
+func (w *World) ListACLEntries() ([]cache.ACLEntry, error) { return w.entries, nil }
+
+// ListPublicComponentIDs returns the components flagged public.
+func (w *World) ListPublicComponentIDs() ([]int64, error) { return w.publicIDs, nil }
+
+// createdAt is when the first component of a Memory world was created; each next one follows a
+// minute later.
+var createdAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Memory numbers the fixture's components 1, 2, ... without storing them anywhere, for a cache or
+// a fake store to serve. Slugs are derived from the names as the store derives them.
+func (f *Fixture) Memory() *World {
+	w := newWorld()
+	siblings := make(map[string]int64)
+	slugs := make(map[string]bool)
+	f.walk(func(node Node, parentKey string) error {
+		slug := models.FreeSlug(models.Slugify(node.Name), func(slug string) bool { return slugs[slug] })
+		slugs[slug] = true
+		w.add(node, &models.Component{
+			ID:          int64(len(w.components) + 1),
+			Name:        node.Name,
+			Slug:        slug,
+			Type:        node.Type,
+			Tags:        node.Tags,
+			Description: node.Description,
+			Metadata:    node.metadata(),
+			ParentID:    w.parentID(parentKey),
+			Position:    siblings[parentKey],
+			CreatedAt:   createdAt.Add(time.Duration(len(w.components)) * time.Minute).Format(time.RFC3339),
+		})
+		siblings[parentKey]++
+		return nil
+	})
+	for _, component := range w.components {
+		component.UpdatedAt = component.CreatedAt
+	}
+	return w
+}
+
+// Store is what a fixture is loaded through; *store.ComponentStore satisfies it.
+type Store interface {
+	CreateComponent(component *models.Component) (int64, error)
+	GetComponentByID(id int64) (*models.Component, error)
+	ReplaceComponentACL(id int64, entries []cache.ACLEntry) error
+	SetComponentPublic(id int64, public bool) error
+	PurgeComponent(id int64) error
+}
+
+// Load creates the fixture's components through s, which also keeps the cache in step when it is
+// initialized. A failure leaves the components created so far in place; Unload the returned
+// World to remove them.
+func (f *Fixture) Load(s Store) (*World, error) {
+	w := newWorld()
+	err := f.walk(func(node Node, parentKey string) error {
+		id, err := s.CreateComponent(&models.Component{Name: node.Name, Type: node.Type, Tags: node.Tags, Description: node.Description, Metadata: node.metadata(), ParentID: w.parentID(parentKey)})
+		if err != nil {
+			return fmt.Errorf("error creating fixture component %q: %w", node.key(), err)
+		}
+		component, err := s.GetComponentByID(id)
+		if err != nil {
+			return fmt.Errorf("error reading fixture component %q: %w", node.key(), err)
+		}
+		w.add(node, component)
+		if len(node.ACL) > 0 {
+			if err := s.ReplaceComponentACL(id, w.entries[len(w.entries)-len(node.ACL):]); err != nil {
+				return fmt.Errorf("error setting the ACL of fixture component %q: %w", node.key(), err)
+			}
+		}
+		if node.Public {
+			if err := s.SetComponentPublic(id, true); err != nil {
+				return fmt.Errorf("error flagging fixture component %q public: %w", node.key(), err)
+			}
+		}
+		return nil
+	})
+	return w, err
+}
+
+// Unload purges the World's components through s, children first. Components the test already
+// purged are skipped.
+func (w *World) Unload(s Store) error {
+	for i := len(w.components) - 1; i >= 0; i-- {
+		id := w.components[i].ID
+		if err := s.PurgeComponent(id); err != nil && !isNotFound(err) {
+			return fmt.Errorf("error removing fixture component %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// isNotFound reports whether err is the store's error for a missing component.
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "not found")
+}
+
+// MustLoad loads the named fixture through s for the length of t, failing t when it cannot, and
+// unloads it when t ends.
+func MustLoad(t testing.TB, s Store, name string) *World {
+	t.Helper()
+	f, err := Named(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := f.Load(s)
+	t.Cleanup(func() {
+		if err := w.Unload(s); err != nil {
+			t.Errorf("fixture %s: %v", name, err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// MustLoadCache seeds a new global cache with the named fixture, numbered as by Memory, with its
+// ACL entries and public flags loaded, for the length of t. The previous cache and config are
+// restored when t ends.
+func MustLoadCache(t testing.TB, name string) *World {
+	t.Helper()
+	f, err := Named(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, previousConfig := cache.GlobalComponentCache, cache.GlobalConfig
+	t.Cleanup(func() { cache.GlobalComponentCache, cache.GlobalConfig = previous, previousConfig })
+	cache.GlobalConfig.LoadACL = true
+	cache.GlobalConfig.LoadPublic = true
+	w := f.Memory()
+	if err := cache.InitGlobalCache(w); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
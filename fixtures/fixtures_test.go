@@ -0,0 +1,136 @@
+package fixtures
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a Store keeping components in a map.
+type memoryStore struct {
+	components map[int64]*models.Component
+	acl        map[int64][]cache.ACLEntry
+	public     map[int64]bool
+	nextID     int64
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{components: map[int64]*models.Component{}, acl: map[int64][]cache.ACLEntry{}, public: map[int64]bool{}, nextID: 100}
+}
+
+func (s *memoryStore) CreateComponent(component *models.Component) (int64, error) {
+	s.nextID++
+	created := *component
+	created.ID = s.nextID
+	s.components[created.ID] = &created
+	return created.ID, nil
+}
+
+func (s *memoryStore) GetComponentByID(id int64) (*models.Component, error) {
+	component, found := s.components[id]
+	if !found {
+		return nil, fmt.Errorf("component with ID %d not found", id)
+	}
+	return component, nil
+}
+
+func (s *memoryStore) ReplaceComponentACL(id int64, entries []cache.ACLEntry) error {
+	s.acl[id] = entries
+	return nil
+}
+
+func (s *memoryStore) SetComponentPublic(id int64, public bool) error {
+	s.public[id] = public
+	return nil
+}
+
+func (s *memoryStore) PurgeComponent(id int64) error {
+	if _, found := s.components[id]; !found {
+		return fmt.Errorf("component with ID %d not found for deletion", id)
+	}
+	delete(s.components, id)
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name, yaml, err string
+	}{
+		{"duplicate names", "components: [{name: a}, {name: a}]", `duplicate key "a"`},
+		{"duplicate keys", "components: [{name: a, children: [{key: a, name: b}]}]", `duplicate key "a"`},
+		{"no name", "components: [{description: x}]", "has no name"},
+		{"unknown field", "components: [{name: a, parent: b}]", "field parent not found"},
+		{"unknown permission", "components: [{name: a, acl: {alice: own}}]", "unknown permission"},
+	} {
+		_, err := Parse([]byte(tc.yaml))
+		assert.ErrorContains(t, err, tc.err, tc.name)
+	}
+	_, err := Named("missing")
+	assert.Error(t, err)
+}
+
+func TestMemory(t *testing.T) {
+	f, err := Named("plant")
+	require.NoError(t, err)
+	w := f.Memory()
+
+	for key, id := range map[string]int64{"plant": 1, "line-a": 2, "pump-a": 3, "valve": 4, "line-b": 5, "pump-b": 6, "stores": 7} {
+		assert.Equal(t, id, w.ID(key), key)
+	}
+	pump := w.Component("pump-b")
+	assert.Equal(t, "pump", pump.Name)
+	assert.Equal(t, "Line B feed pump", pump.Description)
+	assert.JSONEq(t, `{"vendor": "flowco", "rated_kw": 11}`, string(pump.Metadata))
+	assert.Nil(t, w.Component("stores").Metadata)
+	assert.Equal(t, "pump-2", pump.Slug, "Expected the second pump's slug to be numbered")
+	assert.Equal(t, "pump", w.Component("pump-a").Slug)
+	assert.Equal(t, sql.NullInt64{Int64: 5, Valid: true}, pump.ParentID)
+	assert.Equal(t, int64(1), w.Component("line-b").Position)
+	assert.Equal(t, int64(1), w.Component("stores").Position)
+	assert.Equal(t, "2024-01-01T00:06:00Z", w.Component("stores").CreatedAt)
+
+	entries, _ := w.ListACLEntries()
+	assert.Equal(t, []cache.ACLEntry{
+		{ComponentID: 5, Principal: cache.EveryonePrincipal, Permission: cache.PermissionRead},
+		{ComponentID: 5, Principal: "operator", Permission: cache.PermissionWrite},
+	}, entries)
+	public, _ := w.ListPublicComponentIDs()
+	assert.Equal(t, []int64{1}, public)
+	assert.Panics(t, func() { w.ID("pump") })
+
+	assert.Equal(t, w.Component("pump-a"), f.Memory().Component("pump-a"), "Expected every load to number alike")
+}
+
+func TestLoadAndUnload(t *testing.T) {
+	s := newMemoryStore()
+	f, err := Named("plant")
+	require.NoError(t, err)
+	w, err := f.Load(s)
+	require.NoError(t, err)
+
+	assert.Len(t, s.components, 7)
+	assert.Equal(t, sql.NullInt64{Int64: w.ID("line-a"), Valid: true}, s.components[w.ID("valve")].ParentID)
+	assert.Len(t, s.acl[w.ID("line-b")], 2)
+	assert.True(t, s.public[w.ID("plant")])
+
+	require.NoError(t, s.PurgeComponent(w.ID("stores"))) // already gone: skipped
+	require.NoError(t, w.Unload(s))
+	assert.Empty(t, s.components)
+}
+
+func TestMustLoadCache(t *testing.T) {
+	previous := cache.GlobalComponentCache
+	t.Run("loaded", func(t *testing.T) {
+		w := MustLoadCache(t, "acl_tree")
+		c := cache.GlobalComponentCache
+		assert.Equal(t, 4, c.Count())
+		assert.True(t, c.Allows(w.ID("grandchild"), "alice", cache.PermissionAdmin))
+		assert.False(t, c.Allows(w.ID("grandchild"), "bob", cache.PermissionWrite))
+	})
+	assert.Same(t, previous, cache.GlobalComponentCache, "Expected the previous cache back")
+}
@@ -0,0 +1,302 @@
+// Package follower runs an instance as a read-only follower of a primary. The follower has no
+// database access: it fills the component cache from the primary's /sync endpoints and keeps it
+// current by polling for deltas, serves reads from the cache, and redirects everything else to
+// the primary.
+package follower
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultMaxStaleness = 30 * time.Second
+)
+
+// errResyncRequired signals that deltas can no longer be applied and a full checkpoint is needed.
+var errResyncRequired = errors.New("full resync required")
+
+// Follower replicates the primary's components into the local cache.
+type Follower struct {
+	PrimaryURL   *url.URL
+	PollInterval time.Duration
+	MaxStaleness time.Duration // reads are refused once the last successful sync is older than this
+	Client       *http.Client
+
+	// Principal is sent to the primary in PrincipalHeader, so a primary enforcing ACLs serves
+	// every component; it must be one of the primary's ACL_ADMIN_PRINCIPALS. Empty sends none.
+	Principal       string
+	PrincipalHeader string
+
+	// Private replicates into a cache of the follower's own, read through Cache, instead of the
+	// global one, so an instance can replicate other instances besides serving its own components.
+	Private bool
+
+	mu         sync.RWMutex
+	checkpoint string
+	lastSync   time.Time
+	cache      *cache.ComponentCache // the private cache, once synced
+}
+
+// FromEnv returns a Follower when FOLLOWER_PRIMARY_URL is set, or nil otherwise.
+// FOLLOWER_POLL_INTERVAL (default 1s) and FOLLOWER_MAX_STALENESS (default 30s) are durations.
+// FOLLOWER_PRINCIPAL is the principal sent in ACL_PRINCIPAL_HEADER (default X-Principal).
+func FromEnv() (*Follower, error) {
+	primary := os.Getenv("FOLLOWER_PRIMARY_URL")
+	if primary == "" {
+		return nil, nil
+	}
+	primaryURL, err := url.Parse(strings.TrimRight(primary, "/"))
+	if err != nil || primaryURL.Scheme == "" || primaryURL.Host == "" {
+		return nil, fmt.Errorf("invalid FOLLOWER_PRIMARY_URL %q: expected an absolute URL such as https://primary:8080", primary)
+	}
+	f := &Follower{
+		PrimaryURL:   primaryURL,
+		PollInterval: defaultPollInterval,
+		MaxStaleness: defaultMaxStaleness,
+		Client:       &http.Client{Timeout: 30 * time.Second},
+
+		Principal:       os.Getenv("FOLLOWER_PRINCIPAL"),
+		PrincipalHeader: os.Getenv("ACL_PRINCIPAL_HEADER"),
+	}
+	if f.PrincipalHeader == "" {
+		f.PrincipalHeader = "X-Principal"
+	}
+	for name, target := range map[string]*time.Duration{
+		"FOLLOWER_POLL_INTERVAL": &f.PollInterval,
+		"FOLLOWER_MAX_STALENESS": &f.MaxStaleness,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid %s %q: expected a positive duration", name, value)
+			}
+			*target = parsed
+		}
+	}
+	return f, nil
+}
+
+// Start performs the initial full sync, retrying until it succeeds or ctx is done.
+func (f *Follower) Start(ctx context.Context) error {
+	for {
+		err := f.resync(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Initial sync from primary %s failed: %v", f.PrimaryURL, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.PollInterval):
+		}
+	}
+}
+
+// Run polls the primary for deltas until ctx is cancelled, falling back to a full resync when the
+// primary no longer has the changes since our checkpoint or the applied result fails verification.
+// Unless Private, a failed sync marks the global cache stale until one succeeds.
+func (f *Follower) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := f.applyDelta(ctx)
+		if errors.Is(err, errResyncRequired) {
+			log.Printf("Follower resyncing from primary: %v", err)
+			err = f.resync(ctx)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Follower sync from primary %s failed: %v", f.PrimaryURL, err)
+			if !f.Private {
+				cache.MarkStale(cache.ReasonFollowerSyncFailed, err)
+			}
+		} else if err == nil && !f.Private {
+			cache.ClearStale(cache.ReasonFollowerSyncFailed)
+		}
+	}
+}
+
+// Lag is the time since the last successful sync.
+func (f *Follower) Lag() time.Duration {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.lastSync.IsZero() {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Since(f.lastSync)
+}
+
+// Cache returns the cache the follower replicates into: its private cache, or nil before the
+// first sync, when Private is set, and the global cache otherwise.
+func (f *Follower) Cache() *cache.ComponentCache {
+	if !f.Private {
+		return cache.GlobalComponentCache
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cache
+}
+
+// componentList adapts a fetched component list to cache.ComponentStoreInterface.
+type componentList []*models.Component
+
+func (l componentList) ListComponents() ([]*models.Component, error) { return l, nil }
+
+func (f *Follower) resync(ctx context.Context) error {
+	var checkpoint cache.SyncCheckpoint
+	if err := f.getJSON(ctx, "/sync/checkpoint?include=components", &checkpoint); err != nil {
+		return err
+	}
+	replica, err := f.load(componentList(checkpoint.Components))
+	if err != nil {
+		return err
+	}
+	// A primary enforcing ACLs sends no hash with the part of the tree a principal may read
+	if local := replica.Checkpoint(false).Hash; checkpoint.Hash != "" && local != checkpoint.Hash {
+		return fmt.Errorf("hash mismatch after full sync: primary %s, local %s", checkpoint.Hash, local)
+	}
+	if f.Private {
+		f.mu.Lock()
+		f.cache = replica
+		f.mu.Unlock()
+	}
+	f.synced(checkpoint.Checkpoint)
+	log.Printf("Follower synced %d components from primary at checkpoint %s", len(checkpoint.Components), checkpoint.Checkpoint)
+	return nil
+}
+
+// load builds the cache a full sync replaces the current one with. A private cache is swapped in
+// only once verified; the global cache is rebuilt in place, as at startup.
+func (f *Follower) load(components componentList) (*cache.ComponentCache, error) {
+	if f.Private {
+		return cache.LoadComponentCache(components, cache.DefaultConfig())
+	}
+	if err := cache.InitGlobalCache(components); err != nil {
+		return nil, err
+	}
+	return cache.GlobalComponentCache, nil
+}
+
+func (f *Follower) applyDelta(ctx context.Context) error {
+	f.mu.RLock()
+	since := f.checkpoint
+	f.mu.RUnlock()
+
+	var delta cache.SyncDelta
+	err := f.getJSON(ctx, "/sync/delta?since="+url.QueryEscape(since), &delta)
+	var status statusError
+	if errors.As(err, &status) && (status.code == http.StatusGone || status.code == http.StatusBadRequest) {
+		return fmt.Errorf("%w: primary answered %d for checkpoint %s", errResyncRequired, status.code, since)
+	}
+	if err != nil {
+		return err
+	}
+	replica := f.Cache()
+	if replica == nil {
+		return fmt.Errorf("%w: nothing synced yet", errResyncRequired)
+	}
+	for _, comp := range delta.Upserted {
+		replica.Set(comp)
+	}
+	for _, id := range delta.Deleted {
+		replica.Delete(id)
+	}
+	if local := replica.Checkpoint(false).Hash; delta.Hash != "" && local != delta.Hash {
+		return fmt.Errorf("%w: hash mismatch after delta (primary %s, local %s)", errResyncRequired, delta.Hash, local)
+	}
+	f.synced(delta.Checkpoint)
+	return nil
+}
+
+func (f *Follower) synced(checkpoint string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checkpoint = checkpoint
+	f.lastSync = time.Now()
+}
+
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string { return fmt.Sprintf("primary returned HTTP %d", e.code) }
+
+func (f *Follower) getJSON(ctx context.Context, pathAndQuery string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.PrimaryURL.String()+pathAndQuery, nil)
+	if err != nil {
+		return err
+	}
+	if f.Principal != "" {
+		req.Header.Set(f.PrincipalHeader, f.Principal)
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError{code: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// Handler serves cache-backed reads through next and redirects everything else to the primary:
+// writes, and reads that need the database or the primary's state (strongly consistent reads,
+// exports, admin diagnostics, admin jobs, the unreferenced report, webhooks, attribute schemas, attachments, comments, share
+// links, visibility, watches). 307 preserves the method and body. Reads fail with 503 once the follower is
+// staler than MaxStaleness; otherwise X-Follower-Lag reports the lag in seconds.
+func (f *Follower) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !servedLocally(r) {
+			target := f.PrimaryURL.String() + r.URL.RequestURI()
+			http.Redirect(w, r, target, http.StatusTemporaryRedirect)
+			return
+		}
+		lag := f.Lag()
+		if lag > f.MaxStaleness {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.PollInterval.Seconds())+1))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Follower data is staler than %s; retry or use the primary at %s", f.MaxStaleness, f.PrimaryURL)})
+			return
+		}
+		w.Header().Set("X-Follower-Lag", strconv.FormatFloat(lag.Seconds(), 'f', 3, 64))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// servedLocally reports whether a request can be answered from the follower's cache. Reads asking
+// for strong consistency, and reads of what the cache does not hold, need the primary's database.
+func servedLocally(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("X-Consistency") == "strong" {
+		return false
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	return path != "components/export" && !strings.HasPrefix(path, "admin/diagnostics/") && !strings.HasPrefix(path, "admin/jobs/") && path != "admin/unreferenced" && path != "admin/duplicates" && path != "admin/webhooks" && !strings.HasPrefix(path, "admin/webhooks/") &&
+		path != "attribute-schemas" && !strings.HasPrefix(path, "attribute-schemas/") &&
+		!strings.HasSuffix(path, "/attachments") && !strings.Contains(path, "/attachments/") &&
+		!strings.HasSuffix(path, "/comments") && !strings.Contains(path, "/comments/") &&
+		!strings.HasPrefix(path, "shared/") && !strings.HasSuffix(path, "/share") && !strings.HasSuffix(path, "/visibility") &&
+		path != "watches" && path != "components/watch" && path != "components/events"
+}
@@ -0,0 +1,185 @@
+package follower
+
+import (
+	"component-service/cache"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePrimary serves recorded /sync responses. The bodies are produced by a real cache so the
+// hashes are genuine; the follower then rebuilds that same global cache from them.
+type fakePrimary struct {
+	checkpoint []byte
+	deltas     map[string][]byte // by since token
+	principal  string            // the X-Principal of the last request
+}
+
+func (p *fakePrimary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.principal = r.Header.Get("X-Principal")
+	switch r.URL.Path {
+	case "/sync/checkpoint":
+		w.Write(p.checkpoint)
+	case "/sync/delta":
+		body, ok := p.deltas[r.URL.Query().Get("since")]
+		if !ok {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.Write(body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newPrimaryState(t *testing.T) (*fakePrimary, cache.SyncCheckpoint) {
+	t.Helper()
+	components := []*models.Component{
+		{ID: 1, Name: "Root"},
+		{ID: 2, Name: "Child", ParentID: sql.NullInt64{Int64: 1, Valid: true}},
+		{ID: 3, Name: "Other root"},
+	}
+	require.NoError(t, cache.InitGlobalCache(componentList(components)))
+	start := cache.GlobalComponentCache.Checkpoint(true)
+	checkpointBody, err := json.Marshal(start)
+	require.NoError(t, err)
+
+	cache.GlobalComponentCache.Set(&models.Component{ID: 4, Name: "Grandchild", ParentID: sql.NullInt64{Int64: 2, Valid: true}})
+	cache.GlobalComponentCache.Delete(3)
+	delta, err := cache.GlobalComponentCache.Delta(start.Checkpoint)
+	require.NoError(t, err)
+	deltaBody, err := json.Marshal(delta)
+	require.NoError(t, err)
+
+	return &fakePrimary{checkpoint: checkpointBody, deltas: map[string][]byte{start.Checkpoint: deltaBody}}, start
+}
+
+func newTestFollower(t *testing.T, primary http.Handler) *Follower {
+	t.Helper()
+	server := httptest.NewServer(primary)
+	t.Cleanup(server.Close)
+	primaryURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return &Follower{PrimaryURL: primaryURL, PollInterval: time.Second, MaxStaleness: time.Minute, Client: server.Client()}
+}
+
+func TestFollowerSync(t *testing.T) {
+	primary, start := newPrimaryState(t)
+	f := newTestFollower(t, primary)
+	f.Principal, f.PrincipalHeader = "replica", "X-Principal"
+
+	require.NoError(t, f.Start(context.Background()))
+	assert.Equal(t, "replica", primary.principal)
+	assert.Equal(t, start.Hash, cache.GlobalComponentCache.Checkpoint(false).Hash)
+	_, found := cache.GlobalComponentCache.GetByID(3)
+	assert.True(t, found, "Expected the checkpoint's components to be loaded")
+
+	require.NoError(t, f.applyDelta(context.Background()))
+	_, found = cache.GlobalComponentCache.GetByID(3)
+	assert.False(t, found, "Expected the deleted component to be removed")
+	children, _ := cache.GlobalComponentCache.GetChildren(2)
+	assert.Len(t, children, 1)
+
+	// The delta moved the follower to a checkpoint the fake primary has no delta for: it must resync.
+	err := f.applyDelta(context.Background())
+	assert.ErrorIs(t, err, errResyncRequired)
+}
+
+func TestFollowerSyncPrivate(t *testing.T) {
+	primary, start := newPrimaryState(t)
+	global := cache.GlobalComponentCache
+	f := newTestFollower(t, primary)
+	f.Private = true
+	assert.Nil(t, f.Cache(), "Expected no private cache before the first sync")
+
+	require.NoError(t, f.Start(context.Background()))
+	require.NotNil(t, f.Cache())
+	assert.NotSame(t, global, f.Cache())
+	assert.Same(t, global, cache.GlobalComponentCache, "Expected the global cache to be left alone")
+	assert.Equal(t, start.Hash, f.Cache().Checkpoint(false).Hash)
+
+	require.NoError(t, f.applyDelta(context.Background()))
+	_, found := f.Cache().GetByID(3)
+	assert.False(t, found, "Expected the delta to apply to the private cache")
+}
+
+func TestFollowerDeltaHashMismatch(t *testing.T) {
+	primary, start := newPrimaryState(t)
+	var delta cache.SyncDelta
+	require.NoError(t, json.Unmarshal(primary.deltas[start.Checkpoint], &delta))
+	delta.Hash = "tampered"
+	primary.deltas[start.Checkpoint], _ = json.Marshal(delta)
+
+	f := newTestFollower(t, primary)
+	require.NoError(t, f.Start(context.Background()))
+	assert.ErrorIs(t, f.applyDelta(context.Background()), errResyncRequired)
+}
+
+func TestFollowerHandler(t *testing.T) {
+	primaryURL, _ := url.Parse("http://primary:8080")
+	f := &Follower{PrimaryURL: primaryURL, PollInterval: time.Second, MaxStaleness: time.Minute}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := f.Handler(next)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/components/1")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Expected reads to be refused before the first sync")
+
+	f.synced("epoch.1")
+	rr = serve(http.MethodGet, "/components/1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("X-Follower-Lag"))
+
+	for _, tc := range []struct{ method, target string }{
+		{http.MethodPost, "/components"},
+		{http.MethodPut, "/components/1"},
+		{http.MethodDelete, "/components/1?cascade=true"},
+		{http.MethodGet, "/components/export?format=ndjson"},
+		{http.MethodGet, "/admin/diagnostics/indexes"},
+		{http.MethodGet, "/admin/jobs/search-reindex-1"},
+		{http.MethodGet, "/admin/unreferenced"},
+		{http.MethodGet, "/admin/duplicates"},
+		{http.MethodGet, "/admin/webhooks/1/deliveries"},
+		{http.MethodGet, "/shared/token/components/1"},
+		{http.MethodGet, "/components/1/share"},
+		{http.MethodGet, "/components/1/visibility"},
+		{http.MethodGet, "/attribute-schemas/pump"},
+		{http.MethodGet, "/components/1/attachments"},
+		{http.MethodGet, "/components/1/attachments/2"},
+		{http.MethodGet, "/components/1/comments"},
+		{http.MethodGet, "/watches"},
+		{http.MethodGet, "/components/watch"},
+		{http.MethodGet, "/components/events"},
+	} {
+		rr = serve(tc.method, tc.target)
+		assert.Equal(t, http.StatusTemporaryRedirect, rr.Code, "%s %s", tc.method, tc.target)
+		assert.Equal(t, "http://primary:8080"+tc.target, rr.Header().Get("Location"), "%s %s", tc.method, tc.target)
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/components/1", nil)
+	req.Header.Set("X-Consistency", "strong")
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, rr.Code, "Expected strong reads to go to the primary")
+
+	f.mu.Lock()
+	f.lastSync = time.Now().Add(-2 * time.Minute)
+	f.mu.Unlock()
+	rr = serve(http.MethodGet, "/components")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Expected reads to be refused once too stale")
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
@@ -1,8 +1,22 @@
 module component-service
 
-go 1.22.2
+go 1.23
 
 require (
-	github.com/lib/pq v1.10.9 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
+	github.com/go-sql-driver/mysql v1.8.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/lib/pq v1.10.9
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/yuin/goldmark v1.7.13
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/net v0.26.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
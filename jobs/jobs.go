@@ -0,0 +1,115 @@
+// Package jobs runs operator-triggered background tasks inside the process and tracks their
+// progress, so an admin endpoint can start long work, return at once and be polled.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxFinishedJobs bounds how many completed jobs are remembered for polling.
+const maxFinishedJobs = 100
+
+// Status is a job's lifecycle state.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// ErrAlreadyRunning is returned by Start while a job of the same name is running.
+var ErrAlreadyRunning = errors.New("a job with this name is already running")
+
+// Job is a snapshot of a job's state.
+type Job struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Status     Status     `json:"status"`
+	Done       int        `json:"done"`  // units of work completed so far
+	Total      int        `json:"total"` // units of work expected, 0 while unknown
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Func is the work of a job. It calls report as it progresses and should return once ctx is done.
+type Func func(ctx context.Context, report func(done, total int)) error
+
+// Runner starts jobs and keeps their state. At most one job per name runs at a time.
+type Runner struct {
+	mu       sync.Mutex
+	nextID   int
+	jobs     map[string]*Job
+	finished []string          // IDs of completed jobs, oldest first
+	running  map[string]string // job name to the ID of its running job
+}
+
+// NewRunner returns an empty Runner.
+func NewRunner() *Runner {
+	return &Runner{jobs: map[string]*Job{}, running: map[string]string{}}
+}
+
+// Default is the process-wide runner used by the admin endpoints.
+var Default = NewRunner()
+
+// Start runs fn in the background under name and returns its initial state.
+func (r *Runner) Start(name string, fn Func) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.running[name]; ok {
+		return *r.jobs[id], ErrAlreadyRunning
+	}
+	r.nextID++
+	job := &Job{ID: fmt.Sprintf("%s-%d", name, r.nextID), Name: name, Status: StatusRunning, StartedAt: time.Now().UTC()}
+	r.jobs[job.ID] = job
+	r.running[name] = job.ID
+
+	go func() {
+		log.Printf("Job %s started", job.ID)
+		err := fn(context.Background(), func(done, total int) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			job.Done, job.Total = done, total
+		})
+		r.finish(job, err)
+	}()
+	return *job, nil
+}
+
+func (r *Runner) finish(job *Job, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	job.Status = StatusSucceeded
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		log.Printf("Job %s failed: %v", job.ID, err)
+	} else {
+		log.Printf("Job %s finished: %d of %d done", job.ID, job.Done, job.Total)
+	}
+	delete(r.running, job.Name)
+	r.finished = append(r.finished, job.ID)
+	if len(r.finished) > maxFinishedJobs {
+		delete(r.jobs, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+}
+
+// Get returns the state of the job with the given ID.
+func (r *Runner) Get(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls the runner until the job leaves the running state.
+func waitFor(t *testing.T, r *Runner, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, _ := r.Get(id); job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return Job{}
+}
+
+func TestRunner(t *testing.T) {
+	r := NewRunner()
+	release := make(chan struct{})
+	job, err := r.Start("reindex", func(ctx context.Context, report func(done, total int)) error {
+		report(1, 2)
+		<-release
+		report(2, 2)
+		return nil
+	})
+	if err != nil || job.Status != StatusRunning {
+		t.Fatalf("Expected a running job, got %+v, %v", job, err)
+	}
+
+	if again, err := r.Start("reindex", func(context.Context, func(int, int)) error { return nil }); !errors.Is(err, ErrAlreadyRunning) || again.ID != job.ID {
+		t.Errorf("Expected ErrAlreadyRunning with the running job, got %+v, %v", again, err)
+	}
+
+	close(release)
+	finished := waitFor(t, r, job.ID)
+	if finished.Status != StatusSucceeded || finished.Done != 2 || finished.Total != 2 || finished.FinishedAt == nil {
+		t.Errorf("Unexpected finished job %+v", finished)
+	}
+
+	failed, err := r.Start("reindex", func(context.Context, func(int, int)) error { return errors.New("boom") })
+	if err != nil {
+		t.Fatalf("Expected a new job once the first finished: %v", err)
+	}
+	if job := waitFor(t, r, failed.ID); job.Status != StatusFailed || job.Error != "boom" {
+		t.Errorf("Expected a failed job, got %+v", job)
+	}
+
+	if _, found := r.Get("missing"); found {
+		t.Error("Expected an unknown ID not to be found")
+	}
+}
+
+func TestRunnerForgetsOldJobs(t *testing.T) {
+	r := NewRunner()
+	var first string
+	for i := 0; i <= maxFinishedJobs; i++ {
+		job, _ := r.Start("noop", func(context.Context, func(int, int)) error { return nil })
+		waitFor(t, r, job.ID)
+		if i == 0 {
+			first = job.ID
+		}
+	}
+	if _, found := r.Get(first); found {
+		t.Errorf("Expected the oldest of %d finished jobs to be forgotten", maxFinishedJobs+1)
+	}
+}
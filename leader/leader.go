@@ -0,0 +1,176 @@
+// Package leader elects one replica to run singleton background jobs. Leadership is a
+// session-level database advisory lock: it is held for as long as the connection that took it
+// stays open, so a crashed or partitioned leader loses it and another replica takes over.
+package leader
+
+import (
+	"component-service/db"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLockName      = "component-service-leader"
+	defaultRetryInterval = 5 * time.Second
+	defaultCheckInterval = 2 * time.Second
+)
+
+// isLeader is the leadership gauge, published at /debug/vars: 1 while this replica runs the
+// singleton jobs, 0 otherwise.
+var isLeader = expvar.NewInt("leader_is_leader")
+
+// Job is a singleton background job. It must return promptly once ctx is cancelled, which
+// happens when leadership is lost or the elector stops.
+type Job func(ctx context.Context)
+
+// Elector runs jobs on exactly one replica at a time.
+type Elector struct {
+	DB            *sql.DB    // nil disables election: the jobs always run here
+	Dialect       db.Dialect // supplies the lock queries
+	LockName      string
+	RetryInterval time.Duration // how often followers try to take over
+	CheckInterval time.Duration // how often the leader verifies its lock connection
+}
+
+// FromEnv configures election from LEADER_ELECTION: "advisory-lock" elects through the database,
+// while unset or "off" (single-replica deployments) makes this replica always lead.
+// LEADER_LOCK_NAME names the lock; replicas sharing a name compete for it.
+func FromEnv(database *sql.DB, dialect db.Dialect) (*Elector, error) {
+	e := &Elector{Dialect: dialect, LockName: defaultLockName, RetryInterval: defaultRetryInterval, CheckInterval: defaultCheckInterval}
+	switch mode := os.Getenv("LEADER_ELECTION"); mode {
+	case "", "off":
+		return e, nil
+	case "advisory-lock":
+		if tryLock, _ := dialect.AdvisoryLockQueries(); tryLock == "" {
+			return nil, fmt.Errorf("LEADER_ELECTION=advisory-lock is not supported on %s", dialect.Name())
+		}
+		e.DB = database
+	default:
+		return nil, fmt.Errorf("unsupported LEADER_ELECTION %q (expected advisory-lock or off)", mode)
+	}
+	if name := os.Getenv("LEADER_LOCK_NAME"); name != "" {
+		e.LockName = name
+	}
+	if interval := os.Getenv("LEADER_RETRY_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid LEADER_RETRY_INTERVAL %q", interval)
+		}
+		e.RetryInterval = parsed
+	}
+	return e, nil
+}
+
+// IsLeader reports whether this replica currently runs the singleton jobs.
+func IsLeader() bool {
+	return isLeader.Value() == 1
+}
+
+// Run competes for leadership until ctx is cancelled, running jobs whenever this replica leads.
+func (e *Elector) Run(ctx context.Context, jobs ...Job) {
+	if e.DB == nil {
+		e.lead(ctx, jobs, nil)
+		return
+	}
+	log.Printf("Leader election: competing for advisory lock %q", e.LockName)
+	for {
+		conn, acquired, err := e.tryAcquire(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("Leader election: error trying lock %q: %v", e.LockName, err)
+		case acquired:
+			log.Printf("Leader election: acquired lock %q; starting %d singleton jobs", e.LockName, len(jobs))
+			e.lead(ctx, jobs, conn)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.RetryInterval):
+		}
+	}
+}
+
+// tryAcquire takes the lock on a dedicated connection, which is returned only when acquired.
+func (e *Elector) tryAcquire(ctx context.Context) (*sql.Conn, bool, error) {
+	conn, err := e.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	tryLock, _ := e.Dialect.AdvisoryLockQueries()
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, e.Dialect.Rebind(tryLock), e.LockName).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+	return conn, true, nil
+}
+
+// lead runs jobs until ctx is cancelled or, with a lock connection, until that connection fails.
+func (e *Elector) lead(ctx context.Context, jobs []Job, conn *sql.Conn) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	isLeader.Set(1)
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			job(leaderCtx)
+		}(job)
+	}
+
+	if conn != nil {
+		e.holdLock(ctx, conn)
+	} else {
+		<-ctx.Done()
+	}
+	cancel()
+	wg.Wait()
+	isLeader.Set(0)
+	if conn != nil {
+		e.release(conn)
+	}
+}
+
+// holdLock returns when ctx is cancelled or the lock connection stops answering, in which case
+// the database may already have released the lock to another replica.
+func (e *Elector) holdLock(ctx context.Context, conn *sql.Conn) {
+	ticker := time.NewTicker(e.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, e.CheckInterval)
+		err := conn.PingContext(checkCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Leader election: lost connection holding lock %q, stepping down: %v", e.LockName, err)
+			return
+		}
+	}
+}
+
+// release unlocks and discards the lock connection. It is never returned to the pool, where it
+// could keep holding the lock if the unlock failed.
+func (e *Elector) release(conn *sql.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.CheckInterval)
+	defer cancel()
+	_, unlock := e.Dialect.AdvisoryLockQueries()
+	// A connection the pool already closed as broken took its session, and the lock, with it.
+	if _, err := conn.ExecContext(ctx, e.Dialect.Rebind(unlock), e.LockName); err != nil && !errors.Is(err, sql.ErrConnDone) {
+		log.Printf("Leader election: error releasing lock %q: %v", e.LockName, err)
+	}
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+	log.Printf("Leader election: released lock %q", e.LockName)
+}
@@ -0,0 +1,230 @@
+package leader
+
+import (
+	"component-service/db"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockServer emulates session-level advisory locks: a lock belongs to a connection until that
+// connection unlocks it or closes.
+type lockServer struct {
+	mu     sync.Mutex
+	holder *lockConn
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) { return &lockConn{server: s}, nil }
+func (s *lockServer) Driver() driver.Driver                        { return nil }
+
+type lockConn struct {
+	server *lockServer
+	broken bool
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *lockConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.server.holder == c {
+		c.server.holder = nil
+	}
+	return nil
+}
+
+func (c *lockConn) Ping(context.Context) error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *lockConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "pg_try_advisory_lock") {
+		return nil, errors.New("unexpected query " + query)
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	acquired := c.server.holder == nil || c.server.holder == c
+	if acquired {
+		c.server.holder = c
+	}
+	return &boolRows{value: acquired}, nil
+}
+
+func (c *lockConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "pg_advisory_unlock") {
+		return nil, errors.New("unexpected query " + query)
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.server.holder == c {
+		c.server.holder = nil
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type boolRows struct {
+	value bool
+	done  bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"acquired"} }
+func (r *boolRows) Close() error      { return nil }
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func newTestElector(server *lockServer) *Elector {
+	return &Elector{
+		DB:            sql.OpenDB(server),
+		Dialect:       db.PostgresDialect{},
+		LockName:      "test",
+		RetryInterval: 10 * time.Millisecond,
+		CheckInterval: 10 * time.Millisecond,
+	}
+}
+
+// countingJob reports how many instances are running at once.
+type countingJob struct {
+	mu      sync.Mutex
+	running int
+	started int
+}
+
+func (j *countingJob) run(ctx context.Context) {
+	j.mu.Lock()
+	j.running++
+	j.started++
+	j.mu.Unlock()
+	<-ctx.Done()
+	j.mu.Lock()
+	j.running--
+	j.mu.Unlock()
+}
+
+func (j *countingJob) snapshot() (running, started int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.running, j.started
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestElectorFailover(t *testing.T) {
+	server := &lockServer{}
+	job := &countingJob{}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() { newTestElector(server).Run(ctxA, job.run); close(doneA) }()
+	waitFor(t, "the first replica to lead", func() bool { running, _ := job.snapshot(); return running == 1 })
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	doneB := make(chan struct{})
+	go func() { newTestElector(server).Run(ctxB, job.run); close(doneB) }()
+	time.Sleep(50 * time.Millisecond)
+	if running, started := job.snapshot(); running != 1 || started != 1 {
+		t.Fatalf("Expected the job to run once while the first replica leads, got running=%d started=%d", running, started)
+	}
+
+	stopA()
+	<-doneA
+	waitFor(t, "the second replica to take over", func() bool { running, started := job.snapshot(); return running == 1 && started == 2 })
+
+	stopB()
+	<-doneB
+	if running, _ := job.snapshot(); running != 0 {
+		t.Errorf("Expected no jobs running after both replicas stopped, got %d", running)
+	}
+	if IsLeader() {
+		t.Error("Expected the leadership gauge to be reset")
+	}
+}
+
+func TestElectorStepsDownWhenLockConnectionFails(t *testing.T) {
+	server := &lockServer{}
+	job := &countingJob{}
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() { stop(); <-done }()
+	go func() { newTestElector(server).Run(ctx, job.run); close(done) }()
+	waitFor(t, "leadership", func() bool { running, _ := job.snapshot(); return running == 1 })
+
+	server.mu.Lock()
+	server.holder.broken = true
+	server.mu.Unlock()
+
+	// The job stops, the broken connection is discarded (releasing the lock), and leadership is
+	// re-acquired on a fresh connection.
+	waitFor(t, "re-election", func() bool { running, started := job.snapshot(); return running == 1 && started == 2 })
+	server.mu.Lock()
+	healthy := server.holder != nil && !server.holder.broken
+	server.mu.Unlock()
+	if !healthy {
+		t.Error("Expected the lock to be held on a healthy connection")
+	}
+}
+
+func TestElectorWithoutElection(t *testing.T) {
+	job := &countingJob{}
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { (&Elector{}).Run(ctx, job.run); close(done) }()
+	waitFor(t, "the job to start", func() bool { running, _ := job.snapshot(); return running == 1 })
+	if !IsLeader() {
+		t.Error("Expected the leadership gauge to be set")
+	}
+	stop()
+	<-done
+	if running, _ := job.snapshot(); running != 0 {
+		t.Errorf("Expected the job to stop, got %d running", running)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("LEADER_ELECTION", "")
+	e, err := FromEnv(nil, db.PostgresDialect{})
+	if err != nil || e.DB != nil {
+		t.Errorf("Expected election to be off by default, got %+v, %v", e, err)
+	}
+
+	t.Setenv("LEADER_ELECTION", "advisory-lock")
+	if _, err := FromEnv(nil, db.CockroachDialect{}); err == nil {
+		t.Error("Expected advisory-lock election to be rejected on CockroachDB")
+	}
+	t.Setenv("LEADER_RETRY_INTERVAL", "soon")
+	if _, err := FromEnv(nil, db.PostgresDialect{}); err == nil {
+		t.Error("Expected an invalid LEADER_RETRY_INTERVAL to be rejected")
+	}
+
+	t.Setenv("LEADER_ELECTION", "zookeeper")
+	if _, err := FromEnv(nil, db.PostgresDialect{}); err == nil {
+		t.Error("Expected an unknown LEADER_ELECTION mode to be rejected")
+	}
+}
@@ -0,0 +1,419 @@
+// Package loadgen drives a synthetic read/write mix against a running instance and reports latency
+// percentiles per operation, so capacity planning and performance regressions can be measured
+// repeatably. It runs as the loadgen subcommand:
+//
+//	component-service loadgen -target http://localhost:8080 -start-qps 10 -qps 200 -ramp 1m -duration 5m
+//
+// Requests are issued open-loop, at a rate that ramps linearly from -start-qps to -qps over -ramp
+// and then holds, so a slow server cannot slow the offered load down and hide its own latency.
+// Reads navigate the tree as a client browsing it would: each starts at a root and descends a
+// random number of levels, so components near the top are requested most. Children the reads list
+// join the tree, which therefore grows as the run explores it. Writes go to a scratch subtree
+// created under a new root for the run and deleted, with cascade, when it ends.
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Op is one kind of request the generator sends.
+type Op string
+
+const (
+	OpGet         Op = "get"         // GET /components/{id}
+	OpChildren    Op = "children"    // GET /components/{id}/children
+	OpDescendants Op = "descendants" // GET /components/{id}/descendants?depth=2
+	OpList        Op = "list"        // GET /components/?limit=50
+	OpSearch      Op = "search"      // GET /components/search?q=<a known name>
+	OpCreate      Op = "create"      // POST /components/ under a scratch component
+	OpPatch       Op = "patch"       // PATCH /components/{id} of a scratch component
+	OpDelete      Op = "delete"      // DELETE /components/{id} of a scratch leaf
+)
+
+// ops lists every Op, in report order.
+var ops = []Op{OpGet, OpChildren, OpDescendants, OpList, OpSearch, OpCreate, OpPatch, OpDelete}
+
+// DefaultMix is mostly reads, as browsing clients send.
+const DefaultMix = "get=40,children=25,descendants=5,list=5,search=5,create=10,patch=8,delete=2"
+
+// Mix weighs the operations a run sends. Each request picks an operation with a probability
+// proportional to its weight.
+type Mix map[Op]int
+
+// ParseMix reads a mix written as op=weight pairs separated by commas, such as "get=9,create=1".
+// Operations left out are not sent.
+func ParseMix(s string) (Mix, error) {
+	mix := Mix{}
+	total := 0
+	for _, pair := range strings.Split(s, ",") {
+		name, weight, found := strings.Cut(strings.TrimSpace(pair), "=")
+		op := Op(name)
+		known := false
+		for _, candidate := range ops {
+			known = known || op == candidate
+		}
+		if !found || !known {
+			return nil, fmt.Errorf("invalid mix entry %q: expected op=weight, where op is one of %s", pair, opNames())
+		}
+		n, err := strconv.Atoi(weight)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s: expected a non-negative integer", weight, op)
+		}
+		mix[op] += n
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("invalid mix %q: every weight is zero", s)
+	}
+	return mix, nil
+}
+
+func opNames() string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = string(op)
+	}
+	return strings.Join(names, ", ")
+}
+
+// pick draws an operation.
+func (m Mix) pick() Op {
+	total := 0
+	for _, op := range ops {
+		total += m[op]
+	}
+	n := rand.Intn(total)
+	for _, op := range ops {
+		if n < m[op] {
+			return op
+		}
+		n -= m[op]
+	}
+	return OpGet // unreachable: n < total
+}
+
+// Config is a run's settings.
+type Config struct {
+	Target         *url.URL      // the instance, such as http://localhost:8080
+	Duration       time.Duration // how long requests are sent, ramp included
+	StartQPS       float64       // the rate at the start of the ramp
+	QPS            float64       // the rate reached at the end of the ramp and held after it
+	Ramp           time.Duration // 0 starts at QPS
+	Concurrency    int           // requests in flight at most; more are dropped and counted
+	Mix            Mix
+	Header         http.Header   // sent with every request, such as the ACL principal
+	ReportInterval time.Duration // progress lines are written this often; 0 writes none
+	Keep           bool          // leaves the scratch subtree in place
+}
+
+// rateAt is the offered rate, in requests per second, elapsed into the run.
+func (c Config) rateAt(elapsed time.Duration) float64 {
+	if c.Ramp <= 0 || elapsed >= c.Ramp {
+		return c.QPS
+	}
+	return c.StartQPS + (c.QPS-c.StartQPS)*float64(elapsed)/float64(c.Ramp)
+}
+
+// Generator sends a Config's load and records its latencies.
+type Generator struct {
+	cfg      Config
+	client   *http.Client
+	tree     *tree
+	rec      *recorder
+	progress io.Writer
+	dropped  atomic.Int64
+	names    atomic.Int64 // numbers the components the run creates
+}
+
+// New returns a Generator for cfg writing progress lines to progress.
+func New(cfg Config, progress io.Writer) *Generator {
+	return &Generator{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{MaxIdleConnsPerHost: cfg.Concurrency}},
+		tree:     newTree(),
+		rec:      newRecorder(),
+		progress: progress,
+	}
+}
+
+// Run seeds the tree from the first page of components, creates the scratch root, sends load until
+// the configured duration passes or ctx is done, and removes the scratch subtree unless Keep is
+// set. Requests in flight when sending stops are waited for and recorded.
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	if err := g.seed(); err != nil {
+		return nil, err
+	}
+	scratch, err := g.create(0, fmt.Sprintf("loadgen-%d", time.Now().Unix()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the scratch root: %w", err)
+	}
+	g.tree.scratch = scratch
+
+	start := time.Now()
+	inFlight := make(chan struct{}, g.cfg.Concurrency)
+	var wg sync.WaitGroup
+	next, lastReport := start, start
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= g.cfg.Duration || ctx.Err() != nil {
+			break
+		}
+		next = next.Add(time.Duration(float64(time.Second) / g.cfg.rateAt(elapsed)))
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+		select {
+		case inFlight <- struct{}{}:
+			wg.Add(1)
+			go func(op Op) {
+				defer func() { <-inFlight; wg.Done() }()
+				g.do(op)
+			}(g.cfg.Mix.pick())
+		default:
+			g.dropped.Add(1) // the client, not the server, is saturated
+		}
+		if g.cfg.ReportInterval > 0 && time.Since(lastReport) >= g.cfg.ReportInterval {
+			g.reportProgress(time.Since(start), time.Since(lastReport))
+			lastReport = time.Now()
+		}
+	}
+	wg.Wait()
+	report := g.rec.report(time.Since(start), g.dropped.Load())
+
+	if !g.cfg.Keep {
+		if err := g.send(http.MethodDelete, fmt.Sprintf("/components/%d?cascade=true", scratch), nil, nil); err != nil {
+			return report, fmt.Errorf("error deleting the scratch subtree of component %d: %w", scratch, err)
+		}
+	}
+	return report, nil
+}
+
+// reportProgress writes the rates and latencies of the interval just ended.
+func (g *Generator) reportProgress(elapsed, interval time.Duration) {
+	count, errs, p50, p99 := g.rec.interval()
+	fmt.Fprintf(g.progress, "%6s offered %.1f/s completed %.1f/s errors %d dropped %d p50 %s p99 %s\n",
+		elapsed.Round(time.Second), g.cfg.rateAt(elapsed), float64(count)/interval.Seconds(), errs, g.dropped.Load(),
+		formatMillis(p50), formatMillis(p99))
+}
+
+// do sends one op and records it. A patch or delete without a scratch component to act on creates
+// one instead.
+func (g *Generator) do(op Op) {
+	var target int64
+	ok := true
+	switch op {
+	case OpPatch:
+		target, ok = g.tree.pickScratch()
+	case OpDelete:
+		target, ok = g.tree.takeScratchLeaf()
+	}
+	if !ok {
+		op = OpCreate
+	}
+	start := time.Now()
+	var err error
+	switch op {
+	case OpGet:
+		err = g.send(http.MethodGet, fmt.Sprintf("/components/%d", g.tree.browse()), nil, nil)
+	case OpChildren:
+		parent := g.tree.browse()
+		var children []listedComponent
+		if err = g.send(http.MethodGet, fmt.Sprintf("/components/%d/children?limit=100", parent), nil, &children); err == nil {
+			for _, child := range children {
+				g.tree.add(child.ID, parent, child.Name)
+			}
+		}
+	case OpDescendants:
+		err = g.send(http.MethodGet, fmt.Sprintf("/components/%d/descendants?depth=2&limit=100", g.tree.browse()), nil, nil)
+	case OpList:
+		err = g.send(http.MethodGet, "/components/?limit=50", nil, nil)
+	case OpSearch:
+		err = g.send(http.MethodGet, "/components/search?q="+url.QueryEscape(g.tree.name(g.tree.browse())), nil, nil)
+	case OpCreate:
+		_, err = g.create(g.tree.browseScratch(), fmt.Sprintf("loadgen-%d", g.names.Add(1)))
+	case OpPatch:
+		body := map[string]string{"description": fmt.Sprintf("patched at %s", time.Now().Format(time.RFC3339Nano))}
+		err = g.send(http.MethodPatch, fmt.Sprintf("/components/%d", target), body, nil)
+	case OpDelete:
+		err = g.send(http.MethodDelete, fmt.Sprintf("/components/%d", target), nil, nil)
+	}
+	g.rec.record(op, time.Since(start), err)
+}
+
+// listedComponent is the part of a component response the generator reads.
+type listedComponent struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID struct {
+		Int64 int64
+		Valid bool
+	} `json:"parent_id"`
+}
+
+// seedLimit is how many components the tree is seeded with.
+const seedLimit = 1000
+
+// seed adds the first page of components to the tree. Components whose parent is not on the page
+// are taken for roots, so a run on a large catalog starts from the newest subtrees.
+func (g *Generator) seed() error {
+	var components []listedComponent
+	if err := g.send(http.MethodGet, fmt.Sprintf("/components/?limit=%d", seedLimit), nil, &components); err != nil {
+		return fmt.Errorf("error listing components to seed the tree: %w", err)
+	}
+	listed := make(map[int64]bool, len(components))
+	for _, comp := range components {
+		listed[comp.ID] = true
+	}
+	for _, comp := range components {
+		parent := int64(0)
+		if comp.ParentID.Valid && listed[comp.ParentID.Int64] {
+			parent = comp.ParentID.Int64
+		}
+		g.tree.add(comp.ID, parent, comp.Name)
+	}
+	return nil
+}
+
+// create creates a component named name under parent, or as a root for 0, and adds it to the tree.
+func (g *Generator) create(parent int64, name string) (int64, error) {
+	body := map[string]interface{}{"name": name, "description": "Created by loadgen"}
+	if parent != 0 {
+		body["parent_id"] = parent
+	}
+	var created listedComponent
+	if err := g.send(http.MethodPost, "/components/", body, &created); err != nil {
+		return 0, err
+	}
+	g.tree.add(created.ID, parent, name)
+	return created.ID, nil
+}
+
+// errStatus is a response whose status is not 2xx.
+var errStatus = errors.New("unexpected status")
+
+// send makes a request to the target with body, when not nil, as JSON, and decodes a 2xx response
+// into out, when not nil.
+func (g *Generator) send(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, g.cfg.Target.String()+path, reader)
+	if err != nil {
+		return err
+	}
+	for name, values := range g.cfg.Header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%w %d from %s %s", errStatus, resp.StatusCode, method, path)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// headerFlag collects repeated -header "Name: value" flags.
+type headerFlag http.Header
+
+func (h headerFlag) String() string { return "" }
+
+func (h headerFlag) Set(value string) error {
+	name, v, found := strings.Cut(value, ":")
+	if !found || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected Name: value")
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(v))
+	return nil
+}
+
+// Main runs the loadgen subcommand with args, the command line after "loadgen", writing the report
+// to stdout and progress and errors to stderr. It returns the process exit code. An interrupt stops
+// sending early; the report then covers the requests sent so far.
+func Main(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	target := flags.String("target", "http://localhost:8080", "base URL of the instance to load")
+	duration := flags.Duration("duration", time.Minute, "how long to send requests, ramp included")
+	startQPS := flags.Float64("start-qps", 1, "requests per second at the start of the ramp")
+	qps := flags.Float64("qps", 50, "requests per second at the end of the ramp, held until the run ends")
+	ramp := flags.Duration("ramp", 0, "how long the rate takes to climb from -start-qps to -qps")
+	concurrency := flags.Int("concurrency", 64, "requests in flight at most; requests beyond are dropped and counted")
+	mix := flags.String("mix", DefaultMix, "operations and their weights, as op=weight pairs separated by commas; ops are "+opNames())
+	interval := flags.Duration("report-interval", 10*time.Second, "how often to write a progress line to stderr; 0 for never")
+	jsonPath := flags.String("json", "", "also write the report as JSON to this file, for comparing runs")
+	keep := flags.Bool("keep", false, "keep the scratch subtree the writes go to instead of deleting it")
+	header := headerFlag{}
+	flags.Var(header, "header", `header sent with every request, as "Name: value"; repeatable`)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg := Config{Duration: *duration, StartQPS: *startQPS, QPS: *qps, Ramp: *ramp, Concurrency: *concurrency,
+		Header: http.Header(header), ReportInterval: *interval, Keep: *keep}
+	var err error
+	if cfg.Target, err = url.Parse(strings.TrimRight(*target, "/")); err != nil || cfg.Target.Scheme == "" || cfg.Target.Host == "" {
+		fmt.Fprintf(stderr, "invalid -target %q: expected an absolute URL such as http://localhost:8080\n", *target)
+		return 2
+	}
+	if cfg.Mix, err = ParseMix(*mix); err != nil {
+		fmt.Fprintf(stderr, "invalid -mix: %v\n", err)
+		return 2
+	}
+	if cfg.Duration <= 0 || cfg.StartQPS <= 0 || cfg.QPS <= 0 || cfg.Concurrency <= 0 || cfg.Ramp < 0 {
+		fmt.Fprintln(stderr, "-duration, -start-qps, -qps and -concurrency must be positive, and -ramp not negative")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := New(cfg, stderr).Run(ctx)
+	if report != nil {
+		report.WriteText(stdout)
+		if *jsonPath != "" {
+			if err := report.writeJSONFile(*jsonPath); err != nil {
+				fmt.Fprintf(stderr, "error writing the JSON report: %v\n", err)
+				return 1
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "loadgen: %v\n", err)
+		return 1
+	}
+	return 0
+}
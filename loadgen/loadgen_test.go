@@ -0,0 +1,208 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("get=3, create=1,get=1")
+	require.NoError(t, err)
+	assert.Equal(t, Mix{OpGet: 4, OpCreate: 1}, mix)
+	for i := 0; i < 20; i++ {
+		assert.Contains(t, []Op{OpGet, OpCreate}, mix.pick())
+	}
+	_, err = ParseMix(DefaultMix)
+	assert.NoError(t, err)
+
+	for _, bad := range []string{"", "get", "fetch=1", "get=-1", "get=x", "get=0,create=0"} {
+		_, err := ParseMix(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRateAt(t *testing.T) {
+	cfg := Config{StartQPS: 10, QPS: 110, Ramp: 10 * time.Second}
+	assert.Equal(t, 10.0, cfg.rateAt(0))
+	assert.Equal(t, 60.0, cfg.rateAt(5*time.Second))
+	assert.Equal(t, 110.0, cfg.rateAt(time.Minute))
+	assert.Equal(t, 110.0, Config{StartQPS: 1, QPS: 110}.rateAt(0), "Expected no ramp to start at the target rate")
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(sorted, 99.9))
+	assert.Equal(t, time.Millisecond, percentile(sorted[:1], 99))
+	assert.Zero(t, percentile(nil, 50))
+}
+
+func TestTree(t *testing.T) {
+	tr := newTree()
+	tr.add(1, 0, "plant")
+	tr.add(2, 1, "line")
+	tr.add(2, 0, "line") // known: stays under 1
+	tr.add(10, 0, "scratch")
+	tr.scratch = 10
+	assert.Equal(t, []int64{1, 10}, tr.roots)
+
+	_, ok := tr.pickScratch()
+	assert.False(t, ok, "Expected an empty scratch subtree to have nothing to patch")
+	tr.add(11, 10, "a")
+	tr.add(12, 11, "b")
+	for i := 0; i < 20; i++ {
+		id, ok := tr.pickScratch()
+		assert.True(t, ok)
+		assert.Contains(t, []int64{11, 12}, id)
+		assert.Contains(t, []int64{1, 2, 10, 11, 12}, tr.browse())
+	}
+	leaf, ok := tr.takeScratchLeaf()
+	assert.True(t, ok)
+	assert.Equal(t, int64(12), leaf)
+	leaf, _ = tr.takeScratchLeaf()
+	assert.Equal(t, int64(11), leaf)
+	_, ok = tr.takeScratchLeaf()
+	assert.False(t, ok)
+}
+
+// fakeInstance serves the endpoints the generator calls from memory.
+type fakeInstance struct {
+	mu         sync.Mutex
+	nextID     int64
+	parents    map[int64]int64
+	deleted    map[int64]bool
+	cascaded   []int64
+	principals map[string]int
+}
+
+func newFakeInstance() *fakeInstance {
+	return &fakeInstance{nextID: 3, parents: map[int64]int64{1: 0, 2: 1}, deleted: map[int64]bool{}, principals: map[string]int{}}
+}
+
+func (f *fakeInstance) component(id int64) map[string]interface{} {
+	parent := map[string]interface{}{"Int64": f.parents[id], "Valid": f.parents[id] != 0}
+	return map[string]interface{}{"id": id, "name": fmt.Sprintf("component %d", id), "parent_id": parent}
+}
+
+func (f *fakeInstance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.principals[r.Header.Get("X-Principal")]++
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/components"), "/"), "/")
+	respond := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	if parts[0] == "" || parts[0] == "search" {
+		if r.Method == http.MethodPost {
+			var body struct {
+				Name     string `json:"name"`
+				ParentID int64  `json:"parent_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if _, ok := f.parents[body.ParentID]; body.ParentID != 0 && (!ok || f.deleted[body.ParentID]) {
+				respond(http.StatusUnprocessableEntity, nil)
+				return
+			}
+			id := f.nextID
+			f.nextID++
+			f.parents[id] = body.ParentID
+			respond(http.StatusCreated, f.component(id))
+			return
+		}
+		var all []map[string]interface{}
+		for id := range f.parents {
+			if !f.deleted[id] {
+				all = append(all, f.component(id))
+			}
+		}
+		respond(http.StatusOK, all)
+		return
+	}
+	id, _ := strconv.ParseInt(parts[0], 10, 64)
+	if _, ok := f.parents[id]; !ok || f.deleted[id] {
+		respond(http.StatusNotFound, nil)
+		return
+	}
+	switch {
+	case r.Method == http.MethodDelete:
+		f.deleted[id] = true
+		if r.URL.Query().Get("cascade") == "true" {
+			f.cascaded = append(f.cascaded, id)
+		}
+		respond(http.StatusOK, nil)
+	case len(parts) == 2 && parts[1] == "children":
+		children := []map[string]interface{}{}
+		for child, parent := range f.parents {
+			if parent == id && !f.deleted[child] {
+				children = append(children, f.component(child))
+			}
+		}
+		respond(http.StatusOK, children)
+	default:
+		respond(http.StatusOK, f.component(id))
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake := newFakeInstance()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	mix, _ := ParseMix(DefaultMix)
+	var progress bytes.Buffer
+	cfg := Config{
+		Target: target, Duration: 300 * time.Millisecond, StartQPS: 50, QPS: 300, Ramp: 100 * time.Millisecond,
+		Concurrency: 8, Mix: mix, Header: http.Header{"X-Principal": {"loadgen"}}, ReportInterval: 100 * time.Millisecond,
+	}
+
+	report, err := New(cfg, &progress).Run(context.Background())
+	require.NoError(t, err)
+	total := report.Ops[len(report.Ops)-1]
+	assert.Equal(t, "total", total.Op)
+	assert.Greater(t, total.Count, 20, "Expected the run to send requests at the ramped rate")
+	assert.Zero(t, total.Errors, "Expected every request to succeed")
+	assert.LessOrEqual(t, total.P50, total.P99)
+	assert.Contains(t, progress.String(), "offered")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if assert.Len(t, fake.cascaded, 1, "Expected the scratch subtree to be deleted with cascade") {
+		assert.Equal(t, int64(0), fake.parents[fake.cascaded[0]], "Expected the scratch root to be a root")
+	}
+	assert.Equal(t, map[string]int{"loadgen": fake.principals["loadgen"]}, fake.principals, "Expected the header on every request")
+
+	var text bytes.Buffer
+	report.WriteText(&text)
+	assert.Contains(t, text.String(), "p99.9")
+}
+
+func TestMainFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-target", "localhost:8080"},
+		{"-mix", "get=0"},
+		{"-qps", "0"},
+		{"-header", "no colon"},
+	} {
+		var stderr bytes.Buffer
+		assert.Equal(t, 2, Main(args, &bytes.Buffer{}, &stderr), "%q", args)
+		assert.NotEmpty(t, stderr.String(), "%q", args)
+	}
+}
@@ -0,0 +1,154 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder keeps the latency of every successful request, by op, and counts the failed ones.
+type recorder struct {
+	mu        sync.Mutex
+	latencies map[Op][]time.Duration
+	errors    map[Op]int
+	// since the last progress line
+	recent       []time.Duration
+	recentErrors int
+}
+
+func newRecorder() *recorder {
+	return &recorder{latencies: map[Op][]time.Duration{}, errors: map[Op]int{}}
+}
+
+// record adds a request that took latency and failed with err, or succeeded when err is nil.
+func (r *recorder) record(op Op, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[op]++
+		r.recentErrors++
+		return
+	}
+	r.latencies[op] = append(r.latencies[op], latency)
+	r.recent = append(r.recent, latency)
+}
+
+// interval returns the requests recorded since the last call, with their median and 99th
+// percentile latencies, and starts a new interval.
+func (r *recorder) interval() (count, errs int, p50, p99 time.Duration) {
+	r.mu.Lock()
+	recent, errs := r.recent, r.recentErrors
+	r.recent, r.recentErrors = nil, 0
+	r.mu.Unlock()
+	sorted := sortedDurations(recent)
+	return len(recent) + errs, errs, percentile(sorted, 50), percentile(sorted, 99)
+}
+
+// report summarizes every request recorded over a run that lasted elapsed.
+func (r *recorder) report(elapsed time.Duration, dropped int64) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := &Report{Elapsed: elapsed.Seconds(), Dropped: dropped}
+	var all []time.Duration
+	for _, op := range ops {
+		if len(r.latencies[op]) == 0 && r.errors[op] == 0 {
+			continue
+		}
+		all = append(all, r.latencies[op]...)
+		report.Ops = append(report.Ops, summarize(string(op), r.latencies[op], r.errors[op]))
+	}
+	total := summarize("total", all, 0)
+	for _, stats := range report.Ops {
+		total.Errors += stats.Errors
+	}
+	total.Count += total.Errors
+	report.Ops = append(report.Ops, total)
+	return report
+}
+
+// Report is the outcome of a run. Latencies are of successful requests, in milliseconds.
+type Report struct {
+	Elapsed float64   `json:"elapsed_seconds"`
+	Dropped int64     `json:"dropped"` // requests not sent because -concurrency were in flight
+	Ops     []OpStats `json:"ops"`     // by op, in a fixed order, then the total
+}
+
+// OpStats summarizes the requests of one op.
+type OpStats struct {
+	Op     string  `json:"op"`
+	Count  int     `json:"count"`  // requests completed, failed ones included
+	Errors int     `json:"errors"` // transport errors and non-2xx responses
+	Mean   float64 `json:"mean_ms"`
+	P50    float64 `json:"p50_ms"`
+	P90    float64 `json:"p90_ms"`
+	P99    float64 `json:"p99_ms"`
+	P999   float64 `json:"p999_ms"`
+	Max    float64 `json:"max_ms"`
+}
+
+// summarize computes the stats of latencies, the successful requests, and errors failed ones.
+func summarize(op string, latencies []time.Duration, errors int) OpStats {
+	sorted := sortedDurations(latencies)
+	stats := OpStats{Op: op, Count: len(sorted) + errors, Errors: errors}
+	if len(sorted) == 0 {
+		return stats
+	}
+	var sum time.Duration
+	for _, latency := range sorted {
+		sum += latency
+	}
+	stats.Mean = millis(sum / time.Duration(len(sorted)))
+	stats.P50 = millis(percentile(sorted, 50))
+	stats.P90 = millis(percentile(sorted, 90))
+	stats.P99 = millis(percentile(sorted, 99))
+	stats.P999 = millis(percentile(sorted, 99.9))
+	stats.Max = millis(sorted[len(sorted)-1])
+	return stats
+}
+
+// WriteText writes the report as a table.
+func (r *Report) WriteText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tcount\terrors\trate/s\tmean\tp50\tp90\tp99\tp99.9\tmax\t")
+	for _, s := range r.Ops {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n",
+			s.Op, s.Count, s.Errors, float64(s.Count)/r.Elapsed, s.Mean, s.P50, s.P90, s.P99, s.P999, s.Max)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Latencies in ms over %.1fs; %d requests dropped at the concurrency limit.\n", r.Elapsed, r.Dropped)
+}
+
+// writeJSONFile writes the report to path as indented JSON.
+func (r *Report) writeJSONFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// sortedDurations returns durations sorted, smallest first.
+func sortedDurations(durations []time.Duration) []time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, or 0 when it is empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+func millis(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+func formatMillis(d time.Duration) string { return fmt.Sprintf("%.2fms", millis(d)) }
@@ -0,0 +1,116 @@
+package loadgen
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// descendChance is the probability that a walk goes down one more level, where it can.
+const descendChance = 0.6
+
+// tree is the part of the hierarchy the generator knows about: the components it was seeded with,
+// the children its reads listed and the components it created.
+type tree struct {
+	mu       sync.Mutex
+	roots    []int64
+	children map[int64][]int64
+	parent   map[int64]int64 // 0 for the roots
+	names    map[int64]string
+	scratch  int64 // the root of the run's writes; set before load starts
+}
+
+func newTree() *tree {
+	return &tree{children: map[int64][]int64{}, parent: map[int64]int64{}, names: map[int64]string{}}
+}
+
+// add records a component under parent, or as a root for 0. A component already known is left
+// where it is.
+func (t *tree) add(id, parent int64, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, known := t.parent[id]; known {
+		return
+	}
+	t.parent[id] = parent
+	t.names[id] = name
+	if parent == 0 {
+		t.roots = append(t.roots, id)
+	} else {
+		t.children[parent] = append(t.children[parent], id)
+	}
+}
+
+// walk descends from start, at each level going on to a random child with descendChance.
+// Assumes t.mu is held.
+func (t *tree) walk(start int64) int64 {
+	node := start
+	for len(t.children[node]) > 0 && rand.Float64() < descendChance {
+		siblings := t.children[node]
+		node = siblings[rand.Intn(len(siblings))]
+	}
+	return node
+}
+
+// browse picks a component as a client navigating from a random root would reach it.
+func (t *tree) browse() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.roots) == 0 {
+		return t.scratch
+	}
+	return t.walk(t.roots[rand.Intn(len(t.roots))])
+}
+
+// browseScratch picks a component of the scratch subtree, the scratch root included.
+func (t *tree) browseScratch() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.walk(t.scratch)
+}
+
+// pickScratch picks a component of the scratch subtree below the scratch root. ok is false when it
+// has none.
+func (t *tree) pickScratch() (id int64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.children[t.scratch]) == 0 {
+		return 0, false
+	}
+	for id == 0 || id == t.scratch {
+		id = t.walk(t.scratch)
+	}
+	return id, true
+}
+
+// takeScratchLeaf removes a leaf of the scratch subtree, other than the scratch root, and returns
+// it, so no later request picks it. ok is false when the scratch root has no children.
+func (t *tree) takeScratchLeaf() (id int64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.children[t.scratch]) == 0 {
+		return 0, false
+	}
+	id = t.scratch
+	for len(t.children[id]) > 0 {
+		siblings := t.children[id]
+		id = siblings[rand.Intn(len(siblings))]
+	}
+	parent := t.parent[id]
+	siblings := t.children[parent]
+	for i, sibling := range siblings {
+		if sibling == id {
+			t.children[parent] = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+	delete(t.parent, id)
+	delete(t.names, id)
+	return id, true
+}
+
+// name returns a known component's name.
+func (t *tree) name(id int64) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.names[id]
+}
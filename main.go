@@ -1,37 +1,169 @@
 package main
 
 import (
+	"component-service/accesslog"
 	"component-service/api"
+	"component-service/attachments"
 	"component-service/cache" // Added
 	"component-service/db"
+	"component-service/events"
+	"component-service/federation"
+	"component-service/follower"
+	"component-service/leader"
+	"component-service/loadgen"
+	"component-service/signing"
+	"component-service/slo"
 	"component-service/store" // Added
+	"component-service/tracing"
+	"component-service/webhooks"
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 )
 
+// singletonJobs are background jobs that must run on exactly one replica. The elected leader runs
+// them; see leader.FromEnv.
+var singletonJobs []leader.Job
+
 func main() {
+	// Subcommands run instead of the service
+	if len(os.Args) > 1 && os.Args[1] == "loadgen" {
+		os.Exit(loadgen.Main(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Load environment variables or configuration if any
 	// Example: godotenv.Load() if using .env file
 
-	// Initialize database connection
-	db.InitDB() // This function should handle database connection details and pooling
-	log.Println("Database initialized.")
+	// Read configuration first, so a typo fails fast instead of after waiting for dependencies
+	replica, err := follower.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure follower mode: %v", err)
+	}
+	// Access logs go to their own writer, separate from the application log
+	accessLog, err := accesslog.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure access log: %v", err)
+	}
+	// Requests are measured against the objectives from the start, followers included
+	objectives, err := slo.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure SLO tracking: %v", err)
+	}
+	if cache.GlobalConfig, err = cache.ConfigFromEnv(); err != nil {
+		log.Fatalf("Failed to configure component cache: %v", err)
+	}
+	if cache.GlobalConfig.WritePolicy == cache.WriteAround && replica != nil {
+		log.Fatalf("CACHE_WRITE_POLICY=write-around is not supported in follower mode: followers have no database to reload from")
+	}
+	if store.UniqueSiblingNames, err = store.UniqueSiblingNamesFromEnv(); err != nil {
+		log.Fatalf("Failed to configure sibling names: %v", err)
+	}
+	if store.ComponentTypes, err = store.ComponentTypesFromEnv(); err != nil {
+		log.Fatalf("Failed to configure component types: %v", err)
+	}
+	if store.ComponentStatuses, err = store.ComponentStatusesFromEnv(); err != nil {
+		log.Fatalf("Failed to configure component statuses: %v", err)
+	}
+	if store.AttachmentStorage, store.MaxAttachmentBytes, err = attachments.FromEnv(); err != nil {
+		log.Fatalf("Failed to configure attachment storage: %v", err)
+	}
+	if api.RequireIfMatch, err = api.RequireIfMatchFromEnv(); err != nil {
+		log.Fatalf("Failed to configure optimistic concurrency: %v", err)
+	}
+	if store.IdempotencyKeyTTL, err = store.IdempotencyKeyTTLFromEnv(); err != nil {
+		log.Fatalf("Failed to configure idempotency keys: %v", err)
+	}
+	if api.ExportBatchSize, err = api.ExportBatchSizeFromEnv(); err != nil {
+		log.Fatalf("Failed to configure exports: %v", err)
+	}
+	if api.MaxUnpaginatedChildren, err = api.MaxUnpaginatedChildrenFromEnv(); err != nil {
+		log.Fatalf("Failed to configure child listings: %v", err)
+	}
+	if api.TreeWalkTimeout, err = api.TreeWalkTimeoutFromEnv(); err != nil {
+		log.Fatalf("Failed to configure tree walks: %v", err)
+	}
+	changeFeed, err := events.FeedFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure change feed: %v", err)
+	}
+	webhookConfig, err := webhooks.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure webhooks: %v", err)
+	}
+	// Webhooks and the change streams sign events with the same keys, published by every instance
+	eventKeys, err := signing.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure event signing: %v", err)
+	}
+	webhookConfig.Keys = eventKeys
+	api.EnableEventSigning(eventKeys)
+	// ACLs are evaluated against the cache, which then has to load them
+	aclEnforcer, err := api.ACLFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure access control: %v", err)
+	}
+	if aclEnforcer != nil && replica != nil {
+		log.Fatalf("ACL_ENABLED is not supported in follower mode: followers do not replicate ACL entries")
+	}
+	cache.GlobalConfig.LoadACL = aclEnforcer != nil
+	publicRouter, err := api.PublicFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure public router: %v", err)
+	}
+	if publicRouter != nil && replica != nil {
+		log.Fatalf("PUBLIC_PORT is not supported in follower mode: followers do not replicate visibility flags")
+	}
+	cache.GlobalConfig.LoadPublic = publicRouter != nil
+	mounted, err := federation.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure federation: %v", err)
+	}
 
-	// Initialize the component cache
-	// The ComponentStore is needed by InitGlobalCache to fetch initial data.
-	cs := &store.ComponentStore{} // Create an instance that satisfies store.ComponentStoreInterface
-	if err := cache.InitGlobalCache(cs); err != nil {
-		// If cache initialization fails, it might be critical for the application.
-		// Depending on requirements, you might allow the app to run with a disabled cache
-		// or treat this as a fatal error. Here, we treat it as fatal.
-		log.Fatalf("Failed to initialize component cache: %v", err)
+	// A follower has no database: its cache is replicated from the primary's sync endpoints
+	if replica != nil {
+		log.Printf("Starting as a read-only follower of %s", replica.PrimaryURL)
+		if err := replica.Start(context.Background()); err != nil {
+			log.Fatalf("Failed to sync from primary: %v", err)
+		}
+		go replica.Run(context.Background())
+	} else if err := initPrimary(); err != nil {
+		log.Fatalf("Startup failed: %v", err)
+	}
+	if changeFeed != nil && replica == nil {
+		// Only the primary writes, so only it has changes to stream; followers redirect to it
+		changeFeed.Start()
+		api.EnableChangeFeed(changeFeed)
+	}
+	if replica == nil {
+		// Each primary delivers the changes it makes; webhooks are registered in the shared database
+		dispatcher := webhooks.New(&store.ComponentStore{}, webhookConfig)
+		dispatcher.Start()
+		api.EnableWebhooks(dispatcher)
+	}
+
+	// Mounts replicate other instances in the background, so one being down does not block startup
+	if mounted != nil {
+		mounted.Start(context.Background())
+		api.EnableFederation(mounted)
 	}
-	log.Println("Component cache initialized.")
 
 	// Setup HTTP routing
 	// ComponentsHandler will use the store (and implicitly the cache through store methods)
 	http.HandleFunc("/components/", api.ComponentsHandler) // Handles /components/ and /components/{id}
+	http.HandleFunc("/admin/", api.AdminHandler)           // Operator diagnostics
+	http.HandleFunc("/sync/", api.SyncHandler)             // Differential sync for offline clients
+	http.HandleFunc("/federation/", api.FederationHandler) // Subtrees mounted from other instances
+	http.HandleFunc("/attribute-schemas", api.AttributeSchemasHandler)
+	http.HandleFunc("/attribute-schemas/", api.AttributeSchemasHandler) // Attribute schemas of component types
+	http.HandleFunc("/readyz", api.ReadyzHandler)                       // Readiness, with the cache's state
+	http.HandleFunc("/watches", api.WatchesHandler)                     // The principal's watches, with change digests
+	http.HandleFunc("/openapi.json", api.OpenAPIHandler)                // The OpenAPI document of these endpoints
+	http.HandleFunc("/docs", api.DocsHandler)                           // Swagger UI browsing /openapi.json
+	http.HandleFunc("/.well-known/jwks.json", api.JWKSHandler)          // Public keys of signed events
+	http.HandleFunc("/schemas", api.EventSchemasHandler)
+	http.HandleFunc("/schemas/", api.EventSchemasHandler) // JSON Schemas of the events sent out
 
 	// Optional: Root handler for service health check or info
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -47,8 +179,80 @@ func main() {
 	if port == "" {
 		port = "8080" // Default port if not specified
 	}
+	var handler http.Handler = api.YAMLHandler(api.ShareLinkHandler(aclEnforcer.Handler(http.DefaultServeMux)))
+	if replica != nil {
+		handler = replica.Handler(handler) // serve reads locally, redirect writes to the primary
+	}
+	handler = objectives.Handler(handler)
+	api.EnableSLO(objectives)
+	if publicRouter != nil {
+		// The public view gets a listener of its own, so none of the endpoints above are reachable through it
+		go func() {
+			log.Printf("Public read-only view starting on port %s", publicRouter.Port)
+			if err := http.ListenAndServe(":"+publicRouter.Port, tracing.Handler(accessLog.Handler(publicRouter.Handler()))); err != nil {
+				log.Fatalf("Failed to start public server: %v", err)
+			}
+		}()
+	}
 	log.Printf("Server starting on port %s\n", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	// Every request is traced, so its access log line and the events it publishes share its IDs
+	if err := http.ListenAndServe(":"+port, tracing.Handler(accessLog.Handler(handler))); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// initPrimary connects to the database, loads the component cache, starts the optional CDC
+// consumer and joins leader election, in that order. Followers skip all of this.
+func initPrimary() error {
+	// Initialize database connection, waiting up to DB_CONNECT_TIMEOUT for it to become reachable
+	if err := db.InitDB(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	log.Println("Database initialized.")
+
+	// Initialize the component cache
+	// The ComponentStore is needed by InitGlobalCache to fetch initial data.
+	cs := &store.ComponentStore{} // Create an instance that satisfies store.ComponentStoreInterface
+	if err := cache.InitGlobalCache(cs); err != nil {
+		// Serving without a cache would silently move every read onto the database, so this is
+		// treated as fatal.
+		return fmt.Errorf("failed to initialize component cache: %w", err)
+	}
+	log.Println("Component cache initialized.")
+
+	// Optionally follow the database's logical replication stream so the cache also reflects
+	// writes made outside this process
+	cdcConsumer, err := store.CDCConsumerFromEnv(cs)
+	if err != nil {
+		return fmt.Errorf("failed to configure CDC consumer: %w", err)
+	}
+
+	// Periodically refresh the database's reporting views, if configured
+	refresher, err := store.ReportingRefresherFromEnv(cs)
+	if err != nil {
+		return fmt.Errorf("failed to configure reporting refresh: %w", err)
+	}
+	if refresher != nil {
+		singletonJobs = append(singletonJobs, refresher.Run)
+	}
+
+	// Run singleton jobs only on the replica holding leadership
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	elector, err := leader.FromEnv(dbConn, db.CurrentDialect)
+	if err != nil {
+		return fmt.Errorf("failed to configure leader election: %w", err)
+	}
+
+	if cdcConsumer != nil {
+		go func() {
+			if err := cdcConsumer.Run(context.Background()); err != nil {
+				log.Printf("CDC consumer stopped: %v", err)
+			}
+		}()
+	}
+	go elector.Run(context.Background(), singletonJobs...)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package markdown renders component descriptions, which are written in Markdown, as HTML that is
+// safe to embed in a page. Rendering happens on the server so that every UI shows a description
+// the same way, and sanitizing it here means no UI has to guard against script injection itself.
+package markdown
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// maxCacheEntries bounds a Cache. Entries for superseded descriptions are never looked up again,
+// so rather than tracking them the cache is simply cleared when it fills up.
+const maxCacheEntries = 100000
+
+var (
+	// converter renders GitHub Flavored Markdown: tables, strikethrough, task lists and bare links
+	// on top of CommonMark. Raw HTML in the source is left out of its output.
+	converter = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// policy keeps the elements and attributes user-generated content may use, and drops scripts,
+	// event handlers, styles and links to anything but http, https and mailto URLs.
+	policy = bluemonday.UGCPolicy()
+)
+
+// Render returns source rendered as sanitized HTML. An empty source renders as "".
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return string(policy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// Cache memoizes rendered descriptions by component and the time it was last updated, so a
+// component is rendered again only once it changes. The zero value is ready to use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+type cacheKey struct {
+	componentID int64
+	updatedAt   string
+}
+
+type cacheEntry struct {
+	source string // rendered; compared on lookup in case a change kept the timestamp
+	html   string
+}
+
+// Render returns the description of component id, as updated at updatedAt, rendered by Render.
+func (c *Cache) Render(id int64, updatedAt, source string) (string, error) {
+	key := cacheKey{componentID: id, updatedAt: updatedAt}
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.source == source {
+		return entry.html, nil
+	}
+	html, err := Render(source)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxCacheEntries {
+		c.entries = make(map[cacheKey]cacheEntry)
+	}
+	c.entries[key] = cacheEntry{source: source, html: html}
+	return html, nil
+}
@@ -0,0 +1,57 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	for source, want := range map[string]string{
+		"":                                  "",
+		"# Title\n\nSome *text*.":           "<h1>Title</h1>\n<p>Some <em>text</em>.</p>\n",
+		"| a |\n|---|\n| b |":               "<table>",
+		"~~gone~~":                          "<del>gone</del>",
+		"see https://example.com":           `<a href="https://example.com" rel="nofollow">https://example.com</a>`,
+		`[ok](https://example.com "title")`: `<a href="https://example.com" title="title" rel="nofollow">ok</a>`,
+	} {
+		got, err := Render(source)
+		require.NoError(t, err, source)
+		assert.Contains(t, got, want, source)
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	for _, source := range []string{
+		"<script>alert(1)</script>",
+		"text <script>alert(1)</script>",
+		`<img src=x onerror="alert(1)">`,
+		`inline <b onclick="alert(1)">bold</b>`,
+		"[click](javascript:alert(1))",
+		"![img](javascript:alert(1))",
+		"<a href=\"javascript:alert(1)\">raw</a>",
+		"<iframe src=\"https://evil.test\"></iframe>",
+	} {
+		got, err := Render(source)
+		require.NoError(t, err, source)
+		for _, unsafe := range []string{"<script", "onerror", "onclick", "javascript:", "<iframe"} {
+			assert.NotContains(t, got, unsafe, "Render(%q) keeps %s", source, unsafe)
+		}
+	}
+}
+
+func TestCacheRendersOncePerUpdate(t *testing.T) {
+	var c Cache
+	first, err := c.Render(1, "2024-01-01T00:00:00Z", "*a*")
+	require.NoError(t, err)
+	require.Equal(t, "<p><em>a</em></p>\n", first)
+
+	c.entries[cacheKey{1, "2024-01-01T00:00:00Z"}] = cacheEntry{source: "*a*", html: "memoized"}
+	got, _ := c.Render(1, "2024-01-01T00:00:00Z", "*a*")
+	assert.Equal(t, "memoized", got, "Expected the memoized HTML of an unchanged component")
+	got, _ = c.Render(1, "2024-01-02T00:00:00Z", "*b*")
+	assert.Equal(t, "<p><em>b</em></p>\n", got, "Expected a render after an update")
+	got, _ = c.Render(1, "2024-01-01T00:00:00Z", "*c*")
+	assert.Equal(t, "<p><em>c</em></p>\n", got, "Expected a changed source with the same timestamp to be rendered again")
+}
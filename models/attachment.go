@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// MaxAttachmentFilenameLength bounds the length of an attachment's file name.
+const MaxAttachmentFilenameLength = 255
+
+// Attachment is a file attached to a component. Its data is kept apart, in attachment storage.
+type Attachment struct {
+	ID          int64  `json:"id"`
+	ComponentID int64  `json:"component_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`       // In bytes
+	SHA256      string `json:"sha256"`     // Hex digest of the data, also served as its ETag
+	CreatedAt   string `json:"created_at"` // Stored as RFC3339 string, converted from time.Time
+}
+
+// CleanAttachmentFilename returns the file name an upload is stored under: the last element of
+// name, which clients may send as a full path, trimmed of spaces. It must be non-empty, at most
+// MaxAttachmentFilenameLength bytes, and free of control characters.
+func CleanAttachmentFilename(name string) (string, error) {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("filename is required")
+	}
+	if len(name) > MaxAttachmentFilenameLength {
+		return "", fmt.Errorf("filename exceeds %d bytes", MaxAttachmentFilenameLength)
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("filename %q contains control characters", name)
+	}
+	return name, nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanAttachmentFilename(t *testing.T) {
+	for name, want := range map[string]string{
+		"manual.pdf":                 "manual.pdf",
+		"  wiring diagram.png ":      "wiring diagram.png",
+		"/tmp/uploads/datasheet.csv": "datasheet.csv",
+		`C:\Users\ops\photo.jpg`:     "photo.jpg",
+	} {
+		if got, err := CleanAttachmentFilename(name); err != nil || got != want {
+			t.Errorf("CleanAttachmentFilename(%q) = %q, %v; expected %q", name, got, err, want)
+		}
+	}
+
+	for _, name := range []string{"", "  ", "/", ".", "bad\x00name", "line\nbreak", strings.Repeat("a", MaxAttachmentFilenameLength+1)} {
+		if got, err := CleanAttachmentFilename(name); err == nil {
+			t.Errorf("CleanAttachmentFilename(%q) = %q; expected an error", name, got)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MaxAttributes bounds the attributes of one schema.
+const MaxAttributes = 100
+
+// MaxAttributeNameLength bounds the length of an attribute name.
+const MaxAttributeNameLength = 255
+
+// AttributeType is the data type of an attribute a schema defines.
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeInteger AttributeType = "integer" // a number with no fractional part
+	AttributeBoolean AttributeType = "boolean"
+)
+
+// AttributeDefinition defines one attribute: a top-level key of a component's metadata.
+type AttributeDefinition struct {
+	Name     string        `json:"name"`
+	DataType AttributeType `json:"data_type"`
+	Required bool          `json:"required"`
+	Enum     []string      `json:"enum,omitempty"` // the values allowed, for strings only
+}
+
+// AttributeSchema is the set of attributes defined for the components of one type. Metadata keys
+// it does not define stay free-form, unless the schema is Strict.
+type AttributeSchema struct {
+	Type       string                `json:"type"`
+	Strict     bool                  `json:"strict"` // reject metadata keys no attribute defines
+	Attributes []AttributeDefinition `json:"attributes"`
+}
+
+// AttributeError reports metadata that does not conform to a schema, naming the attribute.
+type AttributeError struct {
+	Attribute string `json:"attribute"`
+	Reason    string `json:"reason"`
+}
+
+func (e *AttributeError) Error() string {
+	return fmt.Sprintf("attribute %q %s", e.Attribute, e.Reason)
+}
+
+// Validate checks a schema is well-formed: a valid type, and 1 to MaxAttributes attributes with
+// distinct non-empty names, known data types and, where given, distinct enum values on string
+// attributes.
+func (s *AttributeSchema) Validate() error {
+	if !IsTypeName(s.Type) {
+		return fmt.Errorf("invalid component type %q: expected a lowercase letter followed by lowercase letters, digits, - and _", s.Type)
+	}
+	if len(s.Attributes) == 0 || len(s.Attributes) > MaxAttributes {
+		return fmt.Errorf("a schema defines 1 to %d attributes", MaxAttributes)
+	}
+	names := make(map[string]bool, len(s.Attributes))
+	for _, attr := range s.Attributes {
+		if attr.Name == "" || len(attr.Name) > MaxAttributeNameLength {
+			return fmt.Errorf("attribute names must be 1 to %d bytes long", MaxAttributeNameLength)
+		}
+		if names[attr.Name] {
+			return fmt.Errorf("attribute %q is defined twice", attr.Name)
+		}
+		names[attr.Name] = true
+		switch attr.DataType {
+		case AttributeString, AttributeNumber, AttributeInteger, AttributeBoolean:
+		default:
+			return fmt.Errorf("attribute %q has unknown data type %q: expected string, number, integer or boolean", attr.Name, attr.DataType)
+		}
+		if attr.Enum == nil {
+			continue
+		}
+		if attr.DataType != AttributeString {
+			return fmt.Errorf("attribute %q has enum values but is not a string", attr.Name)
+		}
+		if len(attr.Enum) == 0 {
+			return fmt.Errorf("attribute %q has an empty enum", attr.Name)
+		}
+		values := make(map[string]bool, len(attr.Enum))
+		for _, value := range attr.Enum {
+			if values[value] {
+				return fmt.Errorf("attribute %q lists enum value %q twice", attr.Name, value)
+			}
+			values[value] = true
+		}
+	}
+	return nil
+}
+
+// Check validates normalized metadata, as NormalizeMetadata returns it, against the schema. An
+// attribute holding null counts as missing. The error is an *AttributeError, for the first of the
+// Violations.
+func (s *AttributeSchema) Check(metadata json.RawMessage) error {
+	if violations := s.Violations(metadata, s.Strict); len(violations) > 0 {
+		return violations[0]
+	}
+	return nil
+}
+
+// Violations lists every way normalized metadata does not conform to the schema: the attributes
+// it defines, in order, then with strict the keys it does not define, sorted, whether or not the
+// schema itself is Strict.
+func (s *AttributeSchema) Violations(metadata json.RawMessage, strict bool) []*AttributeError {
+	var object map[string]json.RawMessage
+	if len(metadata) > 0 {
+		json.Unmarshal(metadata, &object) // normalized, so an object
+	}
+	var violations []*AttributeError
+	defined := make(map[string]bool, len(s.Attributes))
+	for _, attr := range s.Attributes {
+		defined[attr.Name] = true
+		value, found := object[attr.Name]
+		if !found || string(value) == "null" {
+			if attr.Required {
+				violations = append(violations, &AttributeError{Attribute: attr.Name, Reason: "is required"})
+			}
+			continue
+		}
+		if reason := attr.check(value); reason != "" {
+			violations = append(violations, &AttributeError{Attribute: attr.Name, Reason: reason})
+		}
+	}
+	if !strict {
+		return violations
+	}
+	var undefined []string
+	for key := range object {
+		if !defined[key] {
+			undefined = append(undefined, key)
+		}
+	}
+	sort.Strings(undefined)
+	for _, key := range undefined {
+		violations = append(violations, &AttributeError{Attribute: key, Reason: fmt.Sprintf("is not defined by the schema of type %q", s.Type)})
+	}
+	return violations
+}
+
+// check returns why value does not conform to the attribute, or "" when it does.
+func (a *AttributeDefinition) check(value json.RawMessage) string {
+	switch a.DataType {
+	case AttributeString:
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			return "must be a string"
+		}
+		if a.Enum == nil {
+			return ""
+		}
+		for _, allowed := range a.Enum {
+			if text == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %q", a.Enum)
+	case AttributeNumber, AttributeInteger:
+		var number float64
+		if err := json.Unmarshal(value, &number); err != nil {
+			return "must be a number"
+		}
+		if a.DataType == AttributeInteger && math.Trunc(number) != number {
+			return "must be an integer"
+		}
+	case AttributeBoolean:
+		if !bytes.Equal(value, []byte("true")) && !bytes.Equal(value, []byte("false")) {
+			return "must be a boolean"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAttributeSchemaValidate(t *testing.T) {
+	valid := AttributeSchema{Type: "pump", Attributes: []AttributeDefinition{
+		{Name: "vendor", DataType: AttributeString, Required: true, Enum: []string{"acme", "flowco"}},
+		{Name: "rated_kw", DataType: AttributeNumber},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() = %v; expected nil", err)
+	}
+	for name, schema := range map[string]AttributeSchema{
+		"bad type":       {Type: "Pump", Attributes: valid.Attributes},
+		"no attributes":  {Type: "pump"},
+		"empty name":     {Type: "pump", Attributes: []AttributeDefinition{{DataType: AttributeString}}},
+		"duplicate name": {Type: "pump", Attributes: []AttributeDefinition{{Name: "a", DataType: AttributeString}, {Name: "a", DataType: AttributeNumber}}},
+		"unknown type":   {Type: "pump", Attributes: []AttributeDefinition{{Name: "a", DataType: "date"}}},
+		"numeric enum":   {Type: "pump", Attributes: []AttributeDefinition{{Name: "a", DataType: AttributeInteger, Enum: []string{"1"}}}},
+		"empty enum":     {Type: "pump", Attributes: []AttributeDefinition{{Name: "a", DataType: AttributeString, Enum: []string{}}}},
+		"duplicate enum": {Type: "pump", Attributes: []AttributeDefinition{{Name: "a", DataType: AttributeString, Enum: []string{"x", "x"}}}},
+	} {
+		if err := schema.Validate(); err == nil {
+			t.Errorf("Validate() of the %s schema succeeded; expected an error", name)
+		}
+	}
+}
+
+func TestAttributeSchemaCheck(t *testing.T) {
+	schema := AttributeSchema{Type: "pump", Attributes: []AttributeDefinition{
+		{Name: "vendor", DataType: AttributeString, Required: true, Enum: []string{"acme", "flowco"}},
+		{Name: "rated_kw", DataType: AttributeNumber},
+		{Name: "stages", DataType: AttributeInteger},
+		{Name: "sealed", DataType: AttributeBoolean},
+	}}
+	for _, metadata := range []string{
+		`{"vendor":"acme"}`,
+		`{"vendor":"flowco","rated_kw":7.5,"stages":3,"sealed":false,"color":"red"}`,
+		`{"vendor":"acme","stages":2.0,"rated_kw":null}`,
+	} {
+		if err := schema.Check(json.RawMessage(metadata)); err != nil {
+			t.Errorf("Check(%s) = %v; expected nil", metadata, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		metadata, attribute string
+	}{
+		{``, "vendor"},
+		{`{"vendor":null}`, "vendor"},
+		{`{"vendor":"other"}`, "vendor"},
+		{`{"vendor":42}`, "vendor"},
+		{`{"vendor":"acme","rated_kw":"7.5"}`, "rated_kw"},
+		{`{"vendor":"acme","stages":2.5}`, "stages"},
+		{`{"vendor":"acme","sealed":"true"}`, "sealed"},
+	} {
+		err := schema.Check(json.RawMessage(tc.metadata))
+		var attrErr *AttributeError
+		if !errors.As(err, &attrErr) || attrErr.Attribute != tc.attribute {
+			t.Errorf("Check(%s) = %v; expected an AttributeError on %q", tc.metadata, err, tc.attribute)
+		}
+	}
+}
+
+func TestAttributeSchemaStrict(t *testing.T) {
+	schema := AttributeSchema{Type: "pump", Strict: true, Attributes: []AttributeDefinition{
+		{Name: "vendor", DataType: AttributeString, Required: true},
+		{Name: "rated_kw", DataType: AttributeNumber},
+	}}
+	if err := schema.Check(json.RawMessage(`{"vendor":"acme","rated_kw":7.5}`)); err != nil {
+		t.Errorf("Check of defined keys = %v; expected nil", err)
+	}
+	err := schema.Check(json.RawMessage(`{"vendor":"acme","color":"red"}`))
+	var attrErr *AttributeError
+	if !errors.As(err, &attrErr) || attrErr.Attribute != "color" {
+		t.Errorf("Check of an undefined key = %v; expected an AttributeError on \"color\"", err)
+	}
+
+	violations := schema.Violations(json.RawMessage(`{"zone":1,"rated_kw":"high","color":"red"}`), true)
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Attribute+" "+v.Reason)
+	}
+	want := []string{"vendor is required", "rated_kw must be a number", `color is not defined by the schema of type "pump"`, `zone is not defined by the schema of type "pump"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations = %q; expected %q", got, want)
+	}
+
+	schema.Strict = false
+	if err := schema.Check(json.RawMessage(`{"vendor":"acme","color":"red"}`)); err != nil {
+		t.Errorf("Check of an undefined key without Strict = %v; expected nil", err)
+	}
+	if n := len(schema.Violations(json.RawMessage(`{"vendor":"acme","color":"red"}`), true)); n != 1 {
+		t.Errorf("Violations with strict of a non-strict schema = %d; expected the undefined key", n)
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxCommentLength bounds the length of a comment's body, in bytes.
+const MaxCommentLength = 10000
+
+// MaxCommentAuthorLength bounds the length of a comment's author.
+const MaxCommentAuthorLength = 255
+
+// Comment is a remark left on a component, for teams discussing it.
+type Comment struct {
+	ID          int64  `json:"id"`
+	ComponentID int64  `json:"component_id"`
+	Author      string `json:"author"` // The principal who wrote it; empty when unknown
+	Body        string `json:"body"`
+	CreatedAt   string `json:"created_at"` // Stored as RFC3339 string, converted from time.Time
+}
+
+// CleanComment returns the author and body a comment is stored with, trimmed of surrounding
+// spaces. The body must be non-empty valid UTF-8 of at most MaxCommentLength bytes; the author at
+// most MaxCommentAuthorLength bytes, free of control characters.
+func CleanComment(author, body string) (string, string, error) {
+	author, body = strings.TrimSpace(author), strings.TrimSpace(body)
+	if body == "" {
+		return "", "", fmt.Errorf("body is required")
+	}
+	if len(body) > MaxCommentLength {
+		return "", "", fmt.Errorf("body exceeds %d bytes", MaxCommentLength)
+	}
+	if !utf8.ValidString(body) {
+		return "", "", fmt.Errorf("body is not valid UTF-8")
+	}
+	if len(author) > MaxCommentAuthorLength {
+		return "", "", fmt.Errorf("author exceeds %d bytes", MaxCommentAuthorLength)
+	}
+	if strings.IndexFunc(author, unicode.IsControl) >= 0 {
+		return "", "", fmt.Errorf("author %q contains control characters", author)
+	}
+	return author, body, nil
+}
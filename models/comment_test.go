@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanComment(t *testing.T) {
+	author, body, err := CleanComment(" alice ", "\n  Check the seal.\nIt leaks.  ")
+	if err != nil || author != "alice" || body != "Check the seal.\nIt leaks." {
+		t.Errorf("CleanComment = %q, %q, %v; expected trimmed values", author, body, err)
+	}
+	if _, body, err := CleanComment("", strings.Repeat("a", MaxCommentLength)); err != nil || len(body) != MaxCommentLength {
+		t.Errorf("Expected a body of %d bytes accepted, got %v", MaxCommentLength, err)
+	}
+
+	for _, tc := range []struct{ author, body string }{
+		{"alice", ""},
+		{"alice", " \n\t "},
+		{"alice", strings.Repeat("a", MaxCommentLength+1)},
+		{"alice", "bad \xff byte"},
+		{strings.Repeat("a", MaxCommentAuthorLength+1), "hello"},
+		{"bad\nauthor", "hello"},
+	} {
+		if _, _, err := CleanComment(tc.author, tc.body); err == nil {
+			t.Errorf("CleanComment(%q, %q): expected an error", tc.author, tc.body)
+		}
+	}
+}
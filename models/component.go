@@ -1,13 +1,77 @@
 package models
 
-import "database/sql"
+import (
+	"database/sql"
+	"encoding/json"
+)
 
 // Component represents a hierarchical component in the system.
 type Component struct {
-	ID          int64          `json:"id"`
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	ParentID    sql.NullInt64  `json:"parent_id,omitempty"` // Use sql.NullInt64 for nullable foreign key
-	CreatedAt   string         `json:"created_at,omitempty"` // Stored as RFC3339 string, converted from time.Time
-	UpdatedAt   string         `json:"updated_at,omitempty"` // Stored as RFC3339 string, converted from time.Time
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug,omitempty"`   // Unique and URL-safe; generated from the name on create and kept on rename
+	Type        string          `json:"type,omitempty"`   // One of the configured types, or empty when untyped
+	Status      string          `json:"status,omitempty"` // Lifecycle status; see POST /components/{id}/status
+	Tags        []string        `json:"tags,omitempty"`   // Sorted, without repeats; see NormalizeTags
+	Description string          `json:"description"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`   // A JSON object of the integrator's own, or nil; see NormalizeMetadata
+	ParentID    sql.NullInt64   `json:"parent_id,omitempty"`  // Use sql.NullInt64 for nullable foreign key
+	Position    int64           `json:"position"`             // Order among siblings, ascending; see POST /components/{id}/reorder
+	Version     int64           `json:"version,omitempty"`    // Incremented by every change; checked against If-Match on writes
+	CreatedAt   string          `json:"created_at,omitempty"` // Stored as RFC3339 string, converted from time.Time
+	UpdatedAt   string          `json:"updated_at,omitempty"` // Stored as RFC3339 string, converted from time.Time
+}
+
+// ComponentPatch is a partial update of a component. Nil fields are left unchanged; a ParentID
+// that is not Valid makes the component a root. Tags replaces every tag; AddTags and RemoveTags
+// change only the tags they list, and are applied after Tags; a tag in both is removed. Metadata
+// replaces the whole object; JSON null removes it. A Version other than 0 is the version the
+// component must be at for the patch to apply.
+type ComponentPatch struct {
+	Name        *string
+	Type        *string
+	Description *string
+	Metadata    *json.RawMessage
+	ParentID    *sql.NullInt64
+	Tags        *[]string
+	AddTags     []string
+	RemoveTags  []string
+	Version     int64
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p ComponentPatch) IsEmpty() bool {
+	return p.Name == nil && p.Type == nil && p.Description == nil && p.Metadata == nil && p.ParentID == nil &&
+		p.Tags == nil && len(p.AddTags) == 0 && len(p.RemoveTags) == 0
+}
+
+// ChangesTags reports whether the patch touches the component's tags.
+func (p ComponentPatch) ChangesTags() bool {
+	return p.Tags != nil || len(p.AddTags) > 0 || len(p.RemoveTags) > 0
+}
+
+// PatchedTags returns the tags a component holding current has once the patch applies, not yet
+// normalized.
+func (p ComponentPatch) PatchedTags(current []string) []string {
+	if p.Tags != nil {
+		current = *p.Tags
+	}
+	removed := make(map[string]bool, len(p.RemoveTags))
+	for _, tag := range p.RemoveTags {
+		removed[tag] = true
+	}
+	tags := make([]string, 0, len(current)+len(p.AddTags))
+	for _, tag := range append(append([]string(nil), current...), p.AddTags...) {
+		if !removed[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ComponentMove reparents one component as part of a batch move. A NewParentID that is not Valid
+// makes the component a root.
+type ComponentMove struct {
+	ID          int64
+	NewParentID sql.NullInt64
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+)
+
+// ComponentRef is a reference to a component found in another component's text: by ID, or by
+// slug when Slug is set.
+type ComponentRef struct {
+	ID   int64
+	Slug string
+}
+
+// componentRefPattern matches the ways text refers to a component: a path or URL to
+// /components/{id} or /components/slug/{slug}, with whatever follows it, and the wiki-style
+// [[#id]] and [[slug]].
+var componentRefPattern = regexp.MustCompile(
+	`/components/(?:slug/([a-z0-9]+(?:-[a-z0-9]+)*)|([0-9]+))\b|\[\[(?:#([0-9]+)|([a-z0-9]+(?:-[a-z0-9]+)*))\]\]`)
+
+// Links returns the components c refers to in its description and in the strings of its
+// metadata, each once, in the order they first appear. A reference to c itself is left out when it
+// is by ID; one by slug is kept, as the slug may be another component's by the time it is
+// resolved.
+func (c *Component) Links() []ComponentRef {
+	var refs []ComponentRef
+	seen := map[ComponentRef]bool{}
+	add := func(text string) {
+		for _, match := range componentRefPattern.FindAllStringSubmatch(text, -1) {
+			var ref ComponentRef
+			switch {
+			case match[1] != "":
+				ref.Slug = match[1]
+			case match[4] != "":
+				ref.Slug = match[4]
+			default:
+				id, err := strconv.ParseInt(match[2]+match[3], 10, 64)
+				if err != nil || id == c.ID {
+					continue
+				}
+				ref.ID = id
+			}
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	add(c.Description)
+	if len(c.Metadata) > 0 {
+		var metadata interface{}
+		if err := json.Unmarshal(c.Metadata, &metadata); err == nil {
+			eachString(metadata, add)
+		}
+	}
+	return refs
+}
+
+// eachString calls f with every string in a decoded JSON value, at any depth. Object keys are not
+// visited.
+func eachString(value interface{}, f func(string)) {
+	switch v := value.(type) {
+	case string:
+		f(v)
+	case []interface{}:
+		for _, element := range v {
+			eachString(element, f)
+		}
+	case map[string]interface{}:
+		for _, element := range v {
+			eachString(element, f)
+		}
+	}
+}
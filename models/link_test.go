@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	c := &Component{
+		ID: 7,
+		Description: "Fed by [[main-pump]] and [[#12]]; see https://explorer.example.com/components/12/tree, " +
+			"/components/slug/seal-kit, /components/7 (itself) and [the manual](/components/30). " +
+			"Not references: /components/abc, [[Bad Slug]], components/40, [[#x]].",
+		Metadata: json.RawMessage(`{"upstream":"/components/31","spares":["[[gasket]]",{"note":"[[main-pump]]"}],"[[key]]":1}`),
+	}
+	want := []ComponentRef{{Slug: "main-pump"}, {ID: 12}, {Slug: "seal-kit"}, {ID: 30}, {ID: 31}, {Slug: "gasket"}}
+	got := c.Links()
+	if len(got) != len(want) {
+		t.Fatalf("Links() = %v; want %v", got, want)
+	}
+	// Metadata objects are unordered, so only the description's references keep a fixed order
+	seen := map[ComponentRef]bool{}
+	for _, ref := range got {
+		seen[ref] = true
+	}
+	for _, ref := range want {
+		if !seen[ref] {
+			t.Errorf("Links() = %v; missing %v", got, ref)
+		}
+	}
+	if !reflect.DeepEqual(got[:4], want[:4]) {
+		t.Errorf("Links() = %v; want the description's references first, in order", got)
+	}
+
+	if refs := (&Component{ID: 1, Description: "plain text"}).Links(); refs != nil {
+		t.Errorf("Links() of a component without references = %v; want nil", refs)
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxMetadataBytes bounds the size of a component's metadata, compacted.
+const MaxMetadataBytes = 16 << 10
+
+// NormalizeMetadata validates metadata as components store it: a JSON object, compacted, of at
+// most MaxMetadataBytes. It returns nil for none: absent, null, or the empty object.
+func NormalizeMetadata(metadata json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(metadata)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &object); err != nil || object == nil {
+		return nil, fmt.Errorf("metadata must be a JSON object")
+	}
+	if len(object) == 0 {
+		return nil, nil
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, trimmed); err != nil {
+		return nil, fmt.Errorf("metadata must be a JSON object")
+	}
+	if compacted.Len() > MaxMetadataBytes {
+		return nil, fmt.Errorf("metadata of %d bytes exceeds the limit of %d", compacted.Len(), MaxMetadataBytes)
+	}
+	return compacted.Bytes(), nil
+}
+
+// MetadataText returns the text of the top-level key of a component's metadata, as PostgreSQL's
+// ->> operator gives it: a string unquoted, a number or boolean as written. ok is false when the
+// key is missing or holds null, an object or an array, which metadata filters never match.
+func (c *Component) MetadataText(key string) (text string, ok bool) {
+	if len(c.Metadata) == 0 {
+		return "", false
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(c.Metadata, &object); err != nil {
+		return "", false
+	}
+	value, found := object[key]
+	if !found || len(value) == 0 {
+		return "", false
+	}
+	switch value[0] {
+	case '"':
+		if err := json.Unmarshal(value, &text); err != nil {
+			return "", false
+		}
+		return text, true
+	case 'n', '{', '[':
+		return "", false
+	default:
+		return string(value), true
+	}
+}
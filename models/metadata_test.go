@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeMetadata(t *testing.T) {
+	for _, tc := range []struct {
+		metadata, want string
+	}{
+		{"", ""},
+		{"null", ""},
+		{" {} ", ""},
+		{`{ "vendor": "acme", "spec": {"iso": 9906} }`, `{"vendor":"acme","spec":{"iso":9906}}`},
+	} {
+		got, err := NormalizeMetadata(json.RawMessage(tc.metadata))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("NormalizeMetadata(%q) = %q, %v; expected %q", tc.metadata, got, err, tc.want)
+		}
+	}
+
+	large := `{"blob":"` + strings.Repeat("x", MaxMetadataBytes) + `"}`
+	for _, metadata := range []string{`"acme"`, `["acme"]`, `42`, `{"vendor":`, large} {
+		if _, err := NormalizeMetadata(json.RawMessage(metadata)); err == nil {
+			t.Errorf("NormalizeMetadata(%.20q) succeeded; expected an error", metadata)
+		}
+	}
+}
+
+func TestMetadataText(t *testing.T) {
+	c := &Component{Metadata: json.RawMessage(`{"vendor":"acme","rated_kw":7.50,"certified":true,"spec":{"iso":9906},"ports":[1],"note":null}`)}
+	for key, want := range map[string]string{"vendor": "acme", "rated_kw": "7.50", "certified": "true"} {
+		if got, ok := c.MetadataText(key); !ok || got != want {
+			t.Errorf("MetadataText(%q) = %q, %v; expected %q", key, got, ok, want)
+		}
+	}
+	for _, key := range []string{"spec", "ports", "note", "missing"} {
+		if got, ok := c.MetadataText(key); ok {
+			t.Errorf("MetadataText(%q) = %q; expected no text", key, got)
+		}
+	}
+	if _, ok := (&Component{}).MetadataText("vendor"); ok {
+		t.Error("a component without metadata has no keys")
+	}
+}
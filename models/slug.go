@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxSlugBaseLength bounds the part of a slug derived from the name, leaving room for the numeric
+// suffix that keeps it unique.
+const MaxSlugBaseLength = 100
+
+// fallbackSlug is the slug base of a name with no letters or digits to derive one from.
+const fallbackSlug = "component"
+
+// Slugify derives a slug base from a component name: ASCII letters and digits, lowercased, with
+// every run of other characters turned into a single hyphen, and no hyphen at either end.
+// "Main Feed Pump #2" becomes "main-feed-pump-2".
+func Slugify(name string) string {
+	var slug strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingHyphen && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			pendingHyphen = false
+			slug.WriteRune(r)
+			if slug.Len() >= MaxSlugBaseLength {
+				break
+			}
+		} else {
+			pendingHyphen = true
+		}
+	}
+	if slug.Len() == 0 {
+		return fallbackSlug
+	}
+	return slug.String()
+}
+
+// FreeSlug returns base, or base-2, base-3, ... whichever taken refuses first.
+func FreeSlug(base string, taken func(slug string) bool) string {
+	slug := base
+	for n := 2; taken(slug); n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug
+}
+
+// IsSlug reports whether s has the form Slugify and FreeSlug produce: lowercase ASCII letters and
+// digits in hyphen-separated runs.
+func IsSlug(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' || strings.Contains(s, "--") {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	for name, want := range map[string]string{
+		"pump":               "pump",
+		"Main Feed Pump #2":  "main-feed-pump-2",
+		"  line--a / B  ":    "line-a-b",
+		"in/out":             "in-out",
+		"Kühlwasser Pumpe":   "k-hlwasser-pumpe",
+		"#!?":                "component",
+		"":                   "component",
+		"ISO 9001:2015 (QA)": "iso-9001-2015-qa",
+	} {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q; expected %q", name, got, want)
+		}
+		if got := Slugify(name); !IsSlug(got) {
+			t.Errorf("Slugify(%q) = %q, which IsSlug refuses", name, got)
+		}
+	}
+	long := Slugify(strings.Repeat("ab ", 100))
+	if len(long) > MaxSlugBaseLength || !IsSlug(long) {
+		t.Errorf("Slugify of a long name = %q (%d bytes); expected a slug of at most %d bytes", long, len(long), MaxSlugBaseLength)
+	}
+}
+
+func TestFreeSlug(t *testing.T) {
+	taken := map[string]bool{"pump": true, "pump-2": true, "pump-4": true}
+	isTaken := func(slug string) bool { return taken[slug] }
+	if got := FreeSlug("valve", isTaken); got != "valve" {
+		t.Errorf("FreeSlug(valve) = %q; expected valve", got)
+	}
+	if got := FreeSlug("pump", isTaken); got != "pump-3" {
+		t.Errorf("FreeSlug(pump) = %q; expected pump-3", got)
+	}
+}
+
+func TestIsSlug(t *testing.T) {
+	for _, slug := range []string{"pump", "pump-2", "a-b-c", "42"} {
+		if !IsSlug(slug) {
+			t.Errorf("IsSlug(%q) = false; expected true", slug)
+		}
+	}
+	for _, slug := range []string{"", "-pump", "pump-", "pu--mp", "Pump", "pump_2", "pump/2", "pümp"} {
+		if IsSlug(slug) {
+			t.Errorf("IsSlug(%q) = true; expected false", slug)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxStatusLength bounds the length of a lifecycle status.
+const MaxStatusLength = 32
+
+// DefaultStatusTransitions is the lifecycle components follow unless configured otherwise: drafts
+// become active, active components are deprecated, and deprecated ones are retired or reinstated.
+const DefaultStatusTransitions = "draft>active>deprecated>retired,deprecated>active"
+
+// IsStatusName reports whether s is a well-formed status: a lowercase ASCII letter followed by
+// lowercase letters, digits, hyphens and underscores, at most MaxStatusLength bytes in all.
+func IsStatusName(s string) bool {
+	return len(s) <= MaxStatusLength && IsTypeName(s)
+}
+
+// StatusGraph is the lifecycle of components: the statuses they may have, and the transitions
+// allowed between them.
+type StatusGraph struct {
+	names []string            // in the order first named; the first is the initial status
+	next  map[string][]string // allowed transitions, by status they leave, in the order configured
+}
+
+// ParseStatusGraph parses comma-separated chains of statuses joined by ">", each allowing the
+// transitions between consecutive statuses, such as "draft>active>retired,retired>active". The
+// first status named is the one components are created with.
+func ParseStatusGraph(spec string) (*StatusGraph, error) {
+	g := &StatusGraph{next: make(map[string][]string)}
+	for _, chain := range strings.Split(spec, ",") {
+		if strings.TrimSpace(chain) == "" {
+			continue
+		}
+		statuses := strings.Split(chain, ">")
+		if len(statuses) < 2 {
+			return nil, fmt.Errorf("invalid transition %q: expected statuses joined by >", strings.TrimSpace(chain))
+		}
+		for i, status := range statuses {
+			status = strings.TrimSpace(status)
+			if !IsStatusName(status) {
+				return nil, fmt.Errorf("invalid status %q: expected a lowercase letter followed by lowercase letters, digits, - and _, at most %d bytes", status, MaxStatusLength)
+			}
+			if _, known := g.next[status]; !known {
+				g.names = append(g.names, status)
+				g.next[status] = nil
+			}
+			if i == 0 {
+				continue
+			}
+			from := strings.TrimSpace(statuses[i-1])
+			if from == status || g.Allows(from, status) {
+				return nil, fmt.Errorf("transition %s>%s is listed twice or leads nowhere", from, status)
+			}
+			g.next[from] = append(g.next[from], status)
+		}
+	}
+	if len(g.names) == 0 {
+		return nil, fmt.Errorf("no status transitions in %q", spec)
+	}
+	return g, nil
+}
+
+// Initial returns the status components are created with.
+func (g *StatusGraph) Initial() string {
+	return g.names[0]
+}
+
+// Has reports whether status is part of the lifecycle.
+func (g *StatusGraph) Has(status string) bool {
+	_, ok := g.next[status]
+	return ok
+}
+
+// Allows reports whether a component may move from one status to another. A component whose
+// status is not part of the lifecycle, such as one set before it was reconfigured, may move to any
+// status that is.
+func (g *StatusGraph) Allows(from, to string) bool {
+	if !g.Has(to) {
+		return false
+	}
+	if !g.Has(from) {
+		return true
+	}
+	for _, status := range g.next[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the statuses a component may move to from status, in the order configured.
+func (g *StatusGraph) Next(status string) []string {
+	if !g.Has(status) {
+		return g.names
+	}
+	return g.next[status]
+}
+
+// Names returns every status in the order first named.
+func (g *StatusGraph) Names() []string {
+	return g.names
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusGraph(t *testing.T) {
+	g, err := ParseStatusGraph(DefaultStatusTransitions)
+	if err != nil {
+		t.Fatalf("ParseStatusGraph: %v", err)
+	}
+	if got := strings.Join(g.Names(), ","); got != "draft,active,deprecated,retired" || g.Initial() != "draft" {
+		t.Errorf("Names() = %q, Initial() = %q; expected the default lifecycle starting at draft", got, g.Initial())
+	}
+	for _, tc := range []struct {
+		from, to string
+		want     bool
+	}{
+		{"draft", "active", true},
+		{"active", "deprecated", true},
+		{"deprecated", "active", true},
+		{"deprecated", "retired", true},
+		{"draft", "retired", false},
+		{"retired", "active", false},
+		{"active", "active", false},
+		{"active", "archived", false},
+		{"legacy", "retired", true}, // a status the lifecycle lacks may move anywhere in it
+	} {
+		if got := g.Allows(tc.from, tc.to); got != tc.want {
+			t.Errorf("Allows(%q, %q) = %v; expected %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+	if got := strings.Join(g.Next("deprecated"), ","); got != "retired,active" {
+		t.Errorf("Next(deprecated) = %q; expected retired,active", got)
+	}
+	if len(g.Next("retired")) != 0 || len(g.Next("legacy")) != 4 {
+		t.Errorf("Expected no way out of retired, and every status from an unknown one")
+	}
+
+	for _, spec := range []string{"", " , ", "draft", "draft>Active", "draft>draft", "draft>active,draft>active", "draft>>active", strings.Repeat("x", MaxStatusLength+1) + ">y"} {
+		if _, err := ParseStatusGraph(spec); err == nil {
+			t.Errorf("ParseStatusGraph(%q) succeeded; expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxTagLength bounds the length of a tag, and MaxTags the number of tags a component may have.
+const (
+	MaxTagLength = 64
+	MaxTags      = 32
+)
+
+// IsTagName reports whether s is a well-formed tag. Tags are spelled like component types: a
+// lowercase ASCII letter followed by lowercase letters, digits, hyphens and underscores, at most
+// MaxTagLength bytes in all.
+func IsTagName(s string) bool {
+	return IsTypeName(s)
+}
+
+// NormalizeTags validates tags and returns them sorted, without repeats, as components store
+// them. It returns nil for no tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !IsTagName(tag) {
+			return nil, fmt.Errorf("%q is not a tag: expected a lowercase letter followed by lowercase letters, digits, - and _", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%d tags exceed the limit of %d per component", len(normalized), MaxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// HasTag reports whether tag is among the component's tags.
+func (c *Component) HasTag(tag string) bool {
+	i := sort.SearchStrings(c.Tags, tag)
+	return i < len(c.Tags) && c.Tags[i] == tag
+}
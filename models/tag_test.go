@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	for _, tc := range []struct {
+		tags, want []string
+	}{
+		{nil, nil},
+		{[]string{}, nil},
+		{[]string{"rotating", "critical", "rotating"}, []string{"critical", "rotating"}},
+		{[]string{"io-bus", "plc_2"}, []string{"io-bus", "plc_2"}},
+	} {
+		got, err := NormalizeTags(tc.tags)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("NormalizeTags(%q) = %q, %v; expected %q", tc.tags, got, err, tc.want)
+		}
+	}
+
+	for _, tags := range [][]string{{"Critical"}, {"ok", ""}, {"two words"}} {
+		if _, err := NormalizeTags(tags); err == nil {
+			t.Errorf("NormalizeTags(%q) succeeded; expected an error", tags)
+		}
+	}
+	many := make([]string, MaxTags+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("t%d", i)
+	}
+	if _, err := NormalizeTags(many); err == nil {
+		t.Errorf("NormalizeTags accepted %d tags; expected at most %d", len(many), MaxTags)
+	}
+	if _, err := NormalizeTags(append(many[:MaxTags:MaxTags], "t0")); err != nil {
+		t.Errorf("NormalizeTags refused %d tags with a repeat: %v", MaxTags+1, err)
+	}
+}
+
+func TestHasTag(t *testing.T) {
+	c := &Component{Tags: []string{"critical", "rotating"}}
+	for tag, want := range map[string]bool{"critical": true, "rotating": true, "crit": false, "spare": false} {
+		if got := c.HasTag(tag); got != want {
+			t.Errorf("HasTag(%q) = %v; expected %v", tag, got, want)
+		}
+	}
+	if (&Component{}).HasTag("critical") {
+		t.Error("an untagged component has no tags")
+	}
+}
+
+func TestPatchedTags(t *testing.T) {
+	replaced := []string{"spare"}
+	for _, tc := range []struct {
+		name  string
+		patch ComponentPatch
+		want  []string
+	}{
+		{"add", ComponentPatch{AddTags: []string{"spare"}}, []string{"critical", "rotating", "spare"}},
+		{"remove", ComponentPatch{RemoveTags: []string{"critical", "absent"}}, []string{"rotating"}},
+		{"replace", ComponentPatch{Tags: &replaced}, []string{"spare"}},
+		{"replace and add", ComponentPatch{Tags: &replaced, AddTags: []string{"critical"}}, []string{"spare", "critical"}},
+		{"added and removed", ComponentPatch{AddTags: []string{"spare"}, RemoveTags: []string{"spare"}}, []string{"critical", "rotating"}},
+	} {
+		if got := tc.patch.PatchedTags([]string{"critical", "rotating"}); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: PatchedTags = %q; expected %q", tc.name, got, tc.want)
+		}
+		if !tc.patch.ChangesTags() || tc.patch.IsEmpty() {
+			t.Errorf("%s: expected the patch to change tags", tc.name)
+		}
+	}
+	if (ComponentPatch{}).ChangesTags() {
+		t.Error("the empty patch changes no tags")
+	}
+}
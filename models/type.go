@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxTypeLength bounds the length of a component type.
+const MaxTypeLength = 64
+
+// IsTypeName reports whether s is a well-formed component type: a lowercase ASCII letter followed
+// by lowercase letters, digits, hyphens and underscores, at most MaxTypeLength bytes in all.
+func IsTypeName(s string) bool {
+	if s == "" || len(s) > MaxTypeLength || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// TypeRegistry is the set of types components may have. The nil registry allows every
+// well-formed type.
+type TypeRegistry struct {
+	names   []string // in the order configured
+	allowed map[string]bool
+}
+
+// ParseTypeRegistry parses a comma-separated list of types, such as "folder,service,device".
+// Blank entries are skipped; a malformed or repeated one is an error.
+func ParseTypeRegistry(list string) (*TypeRegistry, error) {
+	r := &TypeRegistry{allowed: make(map[string]bool)}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !IsTypeName(name) {
+			return nil, fmt.Errorf("invalid component type %q: expected a lowercase letter followed by lowercase letters, digits, - and _", name)
+		}
+		if r.allowed[name] {
+			return nil, fmt.Errorf("component type %q is listed twice", name)
+		}
+		r.allowed[name] = true
+		r.names = append(r.names, name)
+	}
+	if len(r.names) == 0 {
+		return nil, fmt.Errorf("no component types in %q", list)
+	}
+	return r, nil
+}
+
+// Allows reports whether a component may have type t. The empty type, that of an untyped
+// component, is always allowed.
+func (r *TypeRegistry) Allows(t string) bool {
+	if t == "" {
+		return true
+	}
+	if r == nil {
+		return IsTypeName(t)
+	}
+	return r.allowed[t]
+}
+
+// Names returns the allowed types in the order they were configured, or nil for the nil registry.
+func (r *TypeRegistry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	return r.names
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsTypeName(t *testing.T) {
+	for _, name := range []string{"folder", "service", "io-device", "plc_2", "x"} {
+		if !IsTypeName(name) {
+			t.Errorf("IsTypeName(%q) = false; expected true", name)
+		}
+	}
+	for _, name := range []string{"", "Folder", "2fa", "-device", "io device", "gerät", strings.Repeat("x", MaxTypeLength+1)} {
+		if IsTypeName(name) {
+			t.Errorf("IsTypeName(%q) = true; expected false", name)
+		}
+	}
+}
+
+func TestTypeRegistry(t *testing.T) {
+	r, err := ParseTypeRegistry(" folder, service ,device,")
+	if err != nil {
+		t.Fatalf("ParseTypeRegistry: %v", err)
+	}
+	if got := strings.Join(r.Names(), ","); got != "folder,service,device" {
+		t.Errorf("Names() = %q; expected folder,service,device", got)
+	}
+	for typ, want := range map[string]bool{"": true, "folder": true, "device": true, "pump": false, "Folder": false} {
+		if got := r.Allows(typ); got != want {
+			t.Errorf("Allows(%q) = %v; expected %v", typ, got, want)
+		}
+	}
+
+	var any *TypeRegistry
+	if !any.Allows("pump") || any.Allows("Not A Type") || any.Names() != nil {
+		t.Errorf("the nil registry should allow every well-formed type and list none")
+	}
+
+	for _, list := range []string{"", " , ", "folder,Folder", "folder,folder"} {
+		if _, err := ParseTypeRegistry(list); err == nil {
+			t.Errorf("ParseTypeRegistry(%q) succeeded; expected an error", list)
+		}
+	}
+}
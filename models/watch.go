@@ -0,0 +1,10 @@
+package models
+
+// Watch is a principal's subscription to changes of a component, or of its whole subtree when
+// Subtree is set.
+type Watch struct {
+	ComponentID int64  `json:"component_id"`
+	Principal   string `json:"principal"`
+	Subtree     bool   `json:"subtree"`
+	CreatedAt   string `json:"created_at"` // Stored as RFC3339 string, converted from time.Time
+}
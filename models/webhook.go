@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// Bounds of a webhook's fields.
+const (
+	MaxWebhookURLLength    = 2048
+	MinWebhookSecretLength = 16
+	MaxWebhookSecretLength = 255
+)
+
+// WebhookEventTypes are the event types a webhook can subscribe to, as the events package names
+// them.
+var WebhookEventTypes = []string{"component.created", "component.updated", "component.moved", "component.deleted"}
+
+// WebhookSchemaVersions are the event schema versions a webhook can receive, as the events package
+// lists them, oldest first: the last is the current one.
+var WebhookSchemaVersions = []int{1}
+
+// Webhook is a URL component changes are POSTed to, signed with Secret.
+type Webhook struct {
+	ID     int64    `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // write-only: responses leave it out
+	Events []string `json:"events"`           // event types delivered; empty for all of them
+	// SchemaVersion is the version events are delivered in. CleanWebhook sets the current one
+	// for 0.
+	SchemaVersion int `json:"schema_version"`
+	// CreatedAt is stored as RFC3339 string, converted from time.Time.
+	CreatedAt string `json:"created_at"`
+}
+
+// Wants reports whether the webhook subscribes to events of type eventType.
+func (h *Webhook) Wants(eventType string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	i := sort.SearchStrings(h.Events, eventType)
+	return i < len(h.Events) && h.Events[i] == eventType
+}
+
+// CleanWebhook validates a webhook's URL, secret, events and schema version, and returns its events
+// sorted, without repeats, as webhooks store them. The URL must be absolute http or https; the
+// secret between MinWebhookSecretLength and MaxWebhookSecretLength bytes; the schema version one
+// of WebhookSchemaVersions, or 0 for the current one.
+func CleanWebhook(h *Webhook) error {
+	if len(h.URL) > MaxWebhookURLLength {
+		return fmt.Errorf("url exceeds %d bytes", MaxWebhookURLLength)
+	}
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q is not an absolute http or https URL", h.URL)
+	}
+	if len(h.Secret) < MinWebhookSecretLength || len(h.Secret) > MaxWebhookSecretLength {
+		return fmt.Errorf("secret must be between %d and %d bytes", MinWebhookSecretLength, MaxWebhookSecretLength)
+	}
+	if h.SchemaVersion == 0 {
+		h.SchemaVersion = WebhookSchemaVersions[len(WebhookSchemaVersions)-1]
+	} else if !isWebhookSchemaVersion(h.SchemaVersion) {
+		return fmt.Errorf("schema_version %d is not sent: expected one of %v", h.SchemaVersion, WebhookSchemaVersions)
+	}
+	seen := make(map[string]bool, len(h.Events))
+	events := make([]string, 0, len(h.Events))
+	for _, event := range h.Events {
+		if !isWebhookEventType(event) {
+			return fmt.Errorf("%q is not an event type: expected one of %v", event, WebhookEventTypes)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	sort.Strings(events)
+	h.Events = events
+	return nil
+}
+
+func isWebhookEventType(s string) bool {
+	for _, t := range WebhookEventTypes {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebhookSchemaVersion(version int) bool {
+	for _, v := range WebhookSchemaVersions {
+		if version == v {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID          int64  `json:"id"`
+	WebhookID   int64  `json:"webhook_id"`
+	EventID     string `json:"event_id"` // the same in every attempt, and for every webhook
+	EventType   string `json:"event_type"`
+	ComponentID int64  `json:"component_id"`
+	Attempt     int    `json:"attempt"`     // 1 for the first
+	StatusCode  int    `json:"status_code"` // 0 when no response arrived
+	Error       string `json:"error,omitempty"`
+	// ResponseBody is the start of the response, at most MaxDeliveryResponseBytes.
+	ResponseBody string `json:"response_body,omitempty"`
+	DurationMS   int64  `json:"duration_ms"`
+	Succeeded    bool   `json:"succeeded"`
+	// CreatedAt is stored as RFC3339 string, converted from time.Time.
+	CreatedAt string `json:"created_at"`
+	// Payload is the body POSTed, kept for replays. Listings leave it out.
+	Payload string `json:"-"`
+}
+
+// MaxDeliveryResponseBytes bounds the response body kept with a WebhookDelivery.
+const MaxDeliveryResponseBytes = 1024
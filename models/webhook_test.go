@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanWebhook(t *testing.T) {
+	h := &Webhook{URL: "https://hooks.example.com/explorer", Secret: "0123456789abcdef",
+		Events: []string{"component.deleted", "component.created", "component.deleted"}}
+	if err := CleanWebhook(h); err != nil {
+		t.Fatalf("CleanWebhook failed: %v", err)
+	}
+	if strings.Join(h.Events, ",") != "component.created,component.deleted" {
+		t.Errorf("Events = %v; expected them sorted without repeats", h.Events)
+	}
+	if h.SchemaVersion != WebhookSchemaVersions[len(WebhookSchemaVersions)-1] {
+		t.Errorf("SchemaVersion = %d; expected the current one", h.SchemaVersion)
+	}
+	if !h.Wants("component.created") || h.Wants("component.updated") {
+		t.Errorf("Expected the webhook to want only the events it lists")
+	}
+	if all := (&Webhook{}); !all.Wants("component.moved") {
+		t.Errorf("Expected a webhook without events to want them all")
+	}
+
+	for _, tc := range []Webhook{
+		{URL: "hooks.example.com/explorer", Secret: "0123456789abcdef"},
+		{URL: "ftp://hooks.example.com/explorer", Secret: "0123456789abcdef"},
+		{URL: "https://" + strings.Repeat("a", MaxWebhookURLLength), Secret: "0123456789abcdef"},
+		{URL: "https://hooks.example.com/explorer", Secret: "too short"},
+		{URL: "https://hooks.example.com/explorer", Secret: strings.Repeat("s", MaxWebhookSecretLength+1)},
+		{URL: "https://hooks.example.com/explorer", Secret: "0123456789abcdef", Events: []string{"component.renamed"}},
+		{URL: "https://hooks.example.com/explorer", Secret: "0123456789abcdef", SchemaVersion: 99},
+	} {
+		if err := CleanWebhook(&tc); err == nil {
+			t.Errorf("CleanWebhook(%+v): expected an error", tc)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2, 3) // 2 per second, bursts of 3
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("a")
+		assert.True(t, ok, "request %d within the burst", i)
+	}
+	ok, retryAfter := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	ok, _ = l.Allow("b")
+	assert.True(t, ok, "Buckets are per key")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok, "One token refilled")
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+
+	// Quiet clients are swept once their bucket is full again.
+	now = now.Add(time.Hour)
+	l.Allow("c")
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestLimiterHandler(t *testing.T) {
+	l := New(1, 1)
+	handler := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, serve("10.0.0.1:1234").Code)
+	rr := serve("10.0.0.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "Ports of one client share a bucket")
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve("10.0.0.2:1234").Code)
+}
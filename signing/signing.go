@@ -0,0 +1,183 @@
+// Package signing signs the events the service sends out, so receivers can check that an event
+// came from it without sharing a secret with it. An event is signed with an Ed25519 key as a JSON
+// Web Signature (RFC 7515, RFC 8037) whose header names the key, and the public keys are published
+// as a JSON Web Key Set, which the usual JOSE libraries verify against.
+//
+// Keys are rotated through EVENT_SIGNING_KEYS: the first key signs, the others are only published.
+// A new key is added second, so receivers learn it before anything is signed with it, and moved
+// first once they have; the old key then stays published until nothing signed with it is in
+// flight.
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Algorithm is the JWS "alg" of the signatures: Ed25519.
+const Algorithm = "EdDSA"
+
+// keyIDPattern is what a key ID may hold: it is sent in headers and JSON as is.
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// KeyRing holds the signing keys, the one signing first.
+type KeyRing struct {
+	keys []key
+}
+
+type key struct {
+	id      string
+	private ed25519.PrivateKey
+}
+
+// JWK is a public key of a JWKSet.
+type JWK struct {
+	KeyType   string `json:"kty"` // "OKP"
+	Curve     string `json:"crv"` // "Ed25519"
+	X         string `json:"x"`   // the public key, base64url
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"` // "sig"
+	Algorithm string `json:"alg"`
+}
+
+// JWKSet is the JSON Web Key Set of a KeyRing's public keys.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// header is the protected header of a signature.
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// ParseKeys parses a comma-separated list of keys, each a key ID, a colon and the 32-byte Ed25519
+// seed in base64 (standard or URL alphabet, padded or not), such as the output of
+// `openssl rand -base64 32`. The first key signs.
+func ParseKeys(list string) (*KeyRing, error) {
+	k := &KeyRing{}
+	seen := map[string]bool{}
+	for _, item := range strings.Split(list, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(item), ":")
+		if !found || !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("%q is not a key: expected a key ID of up to 64 letters, digits, '.', '_' or '-', a colon and a base64 seed", redact(item))
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("key ID %q is used twice", id)
+		}
+		seen[id] = true
+		seed, err := base64.RawURLEncoding.DecodeString(strings.NewReplacer("+", "-", "/", "_", "=", "").Replace(encoded))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("the seed of key %q is not %d bytes in base64", id, ed25519.SeedSize)
+		}
+		k.keys = append(k.keys, key{id: id, private: ed25519.NewKeyFromSeed(seed)})
+	}
+	return k, nil
+}
+
+// redact keeps the key ID of a malformed key, leaving its seed out of error messages.
+func redact(item string) string {
+	id, _, _ := strings.Cut(strings.TrimSpace(item), ":")
+	return id + ":..."
+}
+
+// FromEnv returns the keys in EVENT_SIGNING_KEYS, parsed by ParseKeys, or nil when it is unset,
+// which leaves events unsigned.
+func FromEnv() (*KeyRing, error) {
+	value := os.Getenv("EVENT_SIGNING_KEYS")
+	if value == "" {
+		return nil, nil
+	}
+	k, err := ParseKeys(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EVENT_SIGNING_KEYS: %w", err)
+	}
+	return k, nil
+}
+
+// KeyID returns the ID of the key that signs.
+func (k *KeyRing) KeyID() string {
+	return k.keys[0].id
+}
+
+// Sign returns payload signed with the first key, in the JWS compact serialization: the header,
+// the payload and the signature, each in base64url, separated by dots.
+func (k *KeyRing) Sign(payload []byte) string {
+	signingInput := k.encodedHeader() + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(k.keys[0].private, []byte(signingInput)))
+}
+
+// SignDetached returns the signature of payload as Sign does, with the payload left out (RFC 7515
+// appendix F): the header, two dots and the signature. It is sent along with the payload itself.
+func (k *KeyRing) SignDetached(payload []byte) string {
+	parts := strings.Split(k.Sign(payload), ".")
+	return parts[0] + ".." + parts[2]
+}
+
+func (k *KeyRing) encodedHeader() string {
+	encoded, _ := json.Marshal(header{Algorithm: Algorithm, KeyID: k.keys[0].id})
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// JWKSet returns the public keys, the one signing first.
+func (k *KeyRing) JWKSet() JWKSet {
+	set := JWKSet{Keys: make([]JWK, len(k.keys))}
+	for i, key := range k.keys {
+		set.Keys[i] = JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.private.Public().(ed25519.PublicKey)),
+			KeyID:     key.id,
+			Use:       "sig",
+			Algorithm: Algorithm,
+		}
+	}
+	return set
+}
+
+// ErrInvalidSignature is returned by Verify for a signature that does not check out.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Verify checks a signature from Sign or SignDetached against the keys of set and returns the
+// payload signed. A detached signature is checked against payload; for one from Sign, payload is
+// ignored.
+func Verify(set JWKSet, jws string, payload []byte) ([]byte, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected three dot-separated parts", ErrInvalidSignature)
+	}
+	var h header
+	encodedHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(encodedHeader, &h) != nil || h.Algorithm != Algorithm {
+		return nil, fmt.Errorf("%w: expected an %s header", ErrInvalidSignature, Algorithm)
+	}
+	if parts[1] == "" {
+		parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	} else if payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("%w: the payload is not base64url", ErrInvalidSignature)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: the signature is not base64url", ErrInvalidSignature)
+	}
+	for _, jwk := range set.Keys {
+		if jwk.KeyID != h.KeyID {
+			continue
+		}
+		public, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(public) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %q is not an Ed25519 public key", jwk.KeyID)
+		}
+		if !ed25519.Verify(public, []byte(parts[0]+"."+parts[1]), signature) {
+			return nil, ErrInvalidSignature
+		}
+		return payload, nil
+	}
+	return nil, fmt.Errorf("%w: no key %q", ErrInvalidSignature, h.KeyID)
+}
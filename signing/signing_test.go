@@ -0,0 +1,72 @@
+package signing
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc8037Seed is the private key of RFC 8037, appendix A.1.
+const rfc8037Seed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+
+func seed(t *testing.T, hexSeed string, encoding *base64.Encoding) string {
+	raw, err := hex.DecodeString(hexSeed)
+	require.NoError(t, err)
+	return encoding.EncodeToString(raw)
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("2024-06:" + seed(t, rfc8037Seed, base64.StdEncoding) + ", 2024-01:" + seed(t, strings.Repeat("ab", 32), base64.RawURLEncoding))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06", keys.KeyID())
+	set := keys.JWKSet()
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, JWK{KeyType: "OKP", Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo", KeyID: "2024-06", Use: "sig", Algorithm: "EdDSA"}, set.Keys[0])
+	assert.Equal(t, "2024-01", set.Keys[1].KeyID)
+
+	for _, list := range []string{
+		"",
+		"no-seed",
+		"a b:" + seed(t, rfc8037Seed, base64.StdEncoding),
+		"short:c2VlZA==",
+		"a:" + seed(t, rfc8037Seed, base64.StdEncoding) + ",a:" + seed(t, rfc8037Seed, base64.StdEncoding),
+	} {
+		_, err := ParseKeys(list)
+		assert.Error(t, err, list)
+	}
+	_, err = ParseKeys("short:c2VlZA==")
+	assert.NotContains(t, err.Error(), "c2VlZA", "Expected the seed to be left out of errors")
+}
+
+func TestSignAndVerify(t *testing.T) {
+	old, err := ParseKeys("old:" + seed(t, strings.Repeat("ab", 32), base64.StdEncoding))
+	require.NoError(t, err)
+	rotated, err := ParseKeys("new:" + seed(t, rfc8037Seed, base64.StdEncoding) + ",old:" + seed(t, strings.Repeat("ab", 32), base64.StdEncoding))
+	require.NoError(t, err)
+	payload := []byte(`{"type":"component.created"}`)
+
+	jws := rotated.Sign(payload)
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(jws, ".")[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"alg":"EdDSA","kid":"new"}`, string(header))
+	got, err := Verify(rotated.JWKSet(), jws, nil)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	// Signatures of the old key still verify after the rotation, new ones not before it
+	_, err = Verify(rotated.JWKSet(), old.Sign(payload), nil)
+	assert.NoError(t, err)
+	_, err = Verify(old.JWKSet(), jws, nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	detached := rotated.SignDetached(payload)
+	assert.Contains(t, detached, "..")
+	_, err = Verify(rotated.JWKSet(), detached, payload)
+	assert.NoError(t, err)
+	_, err = Verify(rotated.JWKSet(), detached, []byte(`{"type":"component.deleted"}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,292 @@
+// Package slo tracks the requests an instance serves against service level objectives for
+// availability and latency, and reports the error budget left over rolling windows. Counts are
+// kept in memory, per minute, so each instance reports its own traffic since it started.
+package slo
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketWidth is the resolution of the windows: requests are counted per minute.
+const bucketWidth = time.Minute
+
+// maxWindow bounds the longest window, and so the memory the buckets take.
+const maxWindow = 30 * 24 * time.Hour
+
+// Objectives are the targets requests are measured against.
+type Objectives struct {
+	Availability     float64         // percent of requests answered without a 5xx status
+	Latency          float64         // percent of requests answered within LatencyThreshold
+	LatencyThreshold time.Duration   // the latency a request must not exceed to count as fast
+	Windows          []time.Duration // the rolling windows reported, shortest first
+}
+
+// DefaultObjectives are used for whatever SLO_* variables leave unset.
+var DefaultObjectives = Objectives{
+	Availability:     99.9,
+	Latency:          99,
+	LatencyThreshold: 300 * time.Millisecond,
+	Windows:          []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour},
+}
+
+// bucket counts the requests of one minute.
+type bucket struct {
+	minute              int64 // since the Unix epoch
+	total, failed, slow int64
+}
+
+// Tracker counts requests by outcome and reports them against its objectives.
+type Tracker struct {
+	objectives Objectives
+	started    time.Time
+
+	mu      sync.Mutex
+	buckets []bucket // a ring, indexed by minute, covering the longest window
+	now     func() time.Time
+}
+
+// New returns a Tracker measuring requests against objectives, which must be valid.
+func New(objectives Objectives) *Tracker {
+	longest := objectives.Windows[len(objectives.Windows)-1]
+	return &Tracker{
+		objectives: objectives,
+		started:    time.Now(),
+		buckets:    make([]bucket, longest/bucketWidth),
+		now:        time.Now,
+	}
+}
+
+// FromEnv configures a Tracker from the environment, starting from DefaultObjectives:
+//
+//	SLO_AVAILABILITY       percent of requests to answer without a 5xx status (default 99.9)
+//	SLO_LATENCY            percent of requests to answer within SLO_LATENCY_THRESHOLD (default 99)
+//	SLO_LATENCY_THRESHOLD  a duration such as 300ms (default 300ms)
+//	SLO_WINDOWS            comma-separated windows in whole minutes, hours or days (default 1h,6h,24h,7d)
+func FromEnv() (*Tracker, error) {
+	objectives := DefaultObjectives
+	for name, target := range map[string]*float64{
+		"SLO_AVAILABILITY": &objectives.Availability,
+		"SLO_LATENCY":      &objectives.Latency,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 || parsed >= 100 {
+				return nil, fmt.Errorf("invalid %s %q: expected a percentage above 0 and below 100, such as 99.9", name, value)
+			}
+			*target = parsed
+		}
+	}
+	if value := os.Getenv("SLO_LATENCY_THRESHOLD"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid SLO_LATENCY_THRESHOLD %q: expected a positive duration such as 300ms", value)
+		}
+		objectives.LatencyThreshold = parsed
+	}
+	if value := os.Getenv("SLO_WINDOWS"); value != "" {
+		windows, err := ParseWindows(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SLO_WINDOWS: %w", err)
+		}
+		objectives.Windows = windows
+	}
+	return New(objectives), nil
+}
+
+// ParseWindows parses a comma-separated list of windows such as "1h,6h,24h,7d", each a whole
+// number of minutes up to 30 days, and returns them shortest first. Blank entries are skipped.
+func ParseWindows(list string) ([]time.Duration, error) {
+	var windows []time.Duration
+	seen := map[time.Duration]bool{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var window time.Duration
+		var err error
+		if days, ok := strings.CutSuffix(entry, "d"); ok {
+			var n int
+			n, err = strconv.Atoi(days)
+			window = time.Duration(n) * 24 * time.Hour
+		} else {
+			window, err = time.ParseDuration(entry)
+		}
+		if err != nil || window <= 0 || window > maxWindow || window%bucketWidth != 0 {
+			return nil, fmt.Errorf("window %q: expected a whole number of minutes, hours or days up to 30d, such as 6h", entry)
+		}
+		if !seen[window] {
+			seen[window] = true
+			windows = append(windows, window)
+		}
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no windows in %q", list)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+	return windows, nil
+}
+
+// Record counts a request answered with status after latency.
+func (t *Tracker) Record(status int, latency time.Duration) {
+	minute := t.now().Unix() / int64(bucketWidth/time.Second)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := &t.buckets[minute%int64(len(t.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if status >= 500 {
+		b.failed++
+	}
+	if latency > t.objectives.LatencyThreshold {
+		b.slow++
+	}
+}
+
+// Handler records every request to next but operator requests under /admin/ and readiness
+// probes, and exports and attachment transfers, whose latency grows with the data streamed rather
+// than the service's health.
+func (t *Tracker) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/admin/") || r.URL.Path == "/readyz" || strings.Trim(r.URL.Path, "/") == "components/export" ||
+			strings.Contains(r.URL.Path, "/attachments") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := t.now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		t.Record(rec.status, t.now().Sub(start))
+	})
+}
+
+// Report is the state of the objectives over each window.
+type Report struct {
+	Objectives ObjectivesReport `json:"objectives"`
+	Uptime     float64          `json:"uptime_seconds"`
+	Windows    []WindowReport   `json:"windows"`
+}
+
+// ObjectivesReport lists the objectives, as configured.
+type ObjectivesReport struct {
+	Availability     float64 `json:"availability"`
+	Latency          float64 `json:"latency"`
+	LatencyThreshold float64 `json:"latency_threshold_ms"`
+}
+
+// WindowReport covers the requests of one rolling window. While the instance has been up for
+// less than the window, it covers the uptime only.
+type WindowReport struct {
+	Window       string    `json:"window"`
+	Covered      float64   `json:"covered_seconds"`
+	Requests     int64     `json:"requests"`
+	Availability Indicator `json:"availability"`
+	Latency      Indicator `json:"latency"`
+}
+
+// Indicator measures one objective over a window.
+type Indicator struct {
+	Bad             int64    `json:"bad"`              // requests failed, or slower than the threshold
+	SLI             *float64 `json:"sli"`              // percent of good requests; null without requests
+	Objective       float64  `json:"objective"`        // percent
+	BudgetRemaining float64  `json:"budget_remaining"` // fraction of the error budget left; negative once overspent
+	BurnRate        float64  `json:"burn_rate"`        // 1 spends the budget exactly over the window
+}
+
+// Report sums the buckets of each window.
+func (t *Tracker) Report() *Report {
+	now := t.now()
+	report := &Report{
+		Objectives: ObjectivesReport{
+			Availability:     t.objectives.Availability,
+			Latency:          t.objectives.Latency,
+			LatencyThreshold: float64(t.objectives.LatencyThreshold) / float64(time.Millisecond),
+		},
+		Uptime: now.Sub(t.started).Seconds(),
+	}
+	current := now.Unix() / int64(bucketWidth/time.Second)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, window := range t.objectives.Windows {
+		var total, failed, slow int64
+		for minute := current - int64(window/bucketWidth) + 1; minute <= current; minute++ {
+			if b := t.buckets[minute%int64(len(t.buckets))]; b.minute == minute {
+				total, failed, slow = total+b.total, failed+b.failed, slow+b.slow
+			}
+		}
+		report.Windows = append(report.Windows, WindowReport{
+			Window:       FormatWindow(window),
+			Covered:      min(window, now.Sub(t.started)).Seconds(),
+			Requests:     total,
+			Availability: indicator(total, failed, t.objectives.Availability),
+			Latency:      indicator(total, slow, t.objectives.Latency),
+		})
+	}
+	return report
+}
+
+// indicator measures bad requests out of total against objective, a percentage below 100.
+func indicator(total, bad int64, objective float64) Indicator {
+	ind := Indicator{Bad: bad, Objective: objective, BudgetRemaining: 1}
+	if total == 0 {
+		return ind
+	}
+	sli := 100 * float64(total-bad) / float64(total)
+	ind.SLI = &sli
+	ind.BurnRate = (100 - sli) / (100 - objective)
+	ind.BudgetRemaining = 1 - ind.BurnRate
+	return ind
+}
+
+// FormatWindow writes a window as ParseWindows reads it, in the largest unit that divides it.
+func FormatWindow(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", window/(24*time.Hour))
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	}
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working through the wrapper.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
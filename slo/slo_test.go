@@ -0,0 +1,121 @@
+package slo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("7d, 1h,30m,,60m")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{30 * time.Minute, time.Hour, 7 * 24 * time.Hour}, windows)
+	for _, window := range windows {
+		parsed, err := ParseWindows(FormatWindow(window))
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{window}, parsed)
+	}
+
+	for _, bad := range []string{"", ",", "90s", "0h", "31d", "-1h", "1w", "d"} {
+		_, err := ParseWindows(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("SLO_AVAILABILITY", "99.5")
+	t.Setenv("SLO_WINDOWS", "1h,1d")
+	tracker, err := FromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 99.5, tracker.objectives.Availability)
+	assert.Equal(t, DefaultObjectives.Latency, tracker.objectives.Latency)
+	assert.Len(t, tracker.buckets, 24*60)
+
+	for name, value := range map[string]string{
+		"SLO_AVAILABILITY":      "100",
+		"SLO_LATENCY":           "ninety",
+		"SLO_LATENCY_THRESHOLD": "0s",
+		"SLO_WINDOWS":           "1y",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			_, err := FromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	tracker := New(Objectives{Availability: 99, Latency: 90, LatencyThreshold: 100 * time.Millisecond, Windows: []time.Duration{time.Hour, 2 * time.Hour}})
+	now := time.Date(2026, 1, 1, 12, 0, 30, 0, time.UTC)
+	tracker.started = now.Add(-90 * time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	// 90 minutes ago: 100 requests, 5 failed, all fast
+	now = now.Add(-90 * time.Minute)
+	for i := 0; i < 100; i++ {
+		status := http.StatusOK
+		if i < 5 {
+			status = http.StatusInternalServerError
+		}
+		tracker.Record(status, time.Millisecond)
+	}
+	// now: 200 requests, none failed, 10 slow
+	now = now.Add(90 * time.Minute)
+	for i := 0; i < 200; i++ {
+		latency := time.Millisecond
+		if i < 10 {
+			latency = time.Second
+		}
+		tracker.Record(http.StatusNotFound, latency)
+	}
+
+	report := tracker.Report()
+	assert.Equal(t, ObjectivesReport{Availability: 99, Latency: 90, LatencyThreshold: 100}, report.Objectives)
+	require.Len(t, report.Windows, 2)
+	hour, twoHours := report.Windows[0], report.Windows[1]
+
+	assert.Equal(t, "1h", hour.Window)
+	assert.Equal(t, 3600.0, hour.Covered)
+	assert.Equal(t, int64(200), hour.Requests)
+	assert.Equal(t, 100.0, *hour.Availability.SLI)
+	assert.Equal(t, 1.0, hour.Availability.BudgetRemaining)
+	assert.Equal(t, int64(10), hour.Latency.Bad)
+	assert.InDelta(t, 95.0, *hour.Latency.SLI, 1e-9)
+	assert.InDelta(t, 0.5, hour.Latency.BurnRate, 1e-9)
+	assert.InDelta(t, 0.5, hour.Latency.BudgetRemaining, 1e-9)
+
+	assert.Equal(t, "2h", twoHours.Window)
+	assert.Equal(t, 90*60.0, twoHours.Covered, "Expected the window to cover the uptime only")
+	assert.Equal(t, int64(300), twoHours.Requests)
+	assert.Equal(t, int64(5), twoHours.Availability.Bad)
+	assert.InDelta(t, 5.0/3, twoHours.Availability.BurnRate, 1e-9)
+	assert.Less(t, twoHours.Availability.BudgetRemaining, 0.0, "Expected an overspent budget to go negative")
+
+	// Three hours on, every bucket has left both windows
+	now = now.Add(3 * time.Hour)
+	report = tracker.Report()
+	assert.Zero(t, report.Windows[1].Requests)
+	assert.Nil(t, report.Windows[1].Availability.SLI)
+	assert.Equal(t, 1.0, report.Windows[1].Availability.BudgetRemaining)
+}
+
+func TestHandler(t *testing.T) {
+	tracker := New(DefaultObjectives)
+	handler := tracker.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/components/1" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	for _, target := range []string{"/components", "/components/1", "/admin/slo", "/components/export", "/components/1/attachments/2", "/readyz"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	window := tracker.Report().Windows[0]
+	assert.Equal(t, int64(2), window.Requests, "Expected admin requests, probes, exports and attachments to go unrecorded")
+	assert.Equal(t, int64(1), window.Availability.Bad)
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"database/sql"
+	"fmt"
+)
+
+// ListACLEntries returns the ACL entries of every component that is not soft-deleted. It
+// implements cache.ACLSource, so the cache loads them when it is built.
+func (s *ComponentStore) ListACLEntries() ([]cache.ACLEntry, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.Query("SELECT a.component_id, a.principal, a.permission FROM component_acl a JOIN components c ON c.id = a.component_id WHERE c.deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("error listing ACL entries: %w", err)
+	}
+	defer rows.Close()
+	var entries []cache.ACLEntry
+	for rows.Next() {
+		var entry cache.ACLEntry
+		var permission string
+		if err := rows.Scan(&entry.ComponentID, &entry.Principal, &permission); err != nil {
+			return nil, fmt.Errorf("error scanning ACL entry: %w", err)
+		}
+		if entry.Permission, err = cache.ParsePermission(permission); err != nil {
+			return nil, fmt.Errorf("error reading ACL entry of component %d for %q: %w", entry.ComponentID, entry.Principal, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ACL entries: %w", err)
+	}
+	return entries, nil
+}
+
+// ReplaceComponentACL replaces a component's own ACL entries, then recompiles the cache's ACL
+// index for its subtree. Principals must be unique within entries.
+func (s *ComponentStore) ReplaceComponentACL(id int64, entries []cache.ACLEntry) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		// Locking the component serializes concurrent replacements and fails cleanly if it is
+		// deleted meanwhile.
+		before, err := lockComponentParent(tx, id)
+		if err != nil || before == nil {
+			return err
+		}
+		found = true
+		if _, err := tx.Exec(db.Rebind("DELETE FROM component_acl WHERE component_id = $1"), id); err != nil {
+			return fmt.Errorf("error clearing ACL of component %d: %w", id, err)
+		}
+		for _, entry := range entries {
+			_, err := tx.Exec(db.Rebind("INSERT INTO component_acl (component_id, principal, permission) VALUES ($1, $2, $3)"),
+				id, entry.Principal, entry.Permission.String())
+			if err != nil {
+				return fmt.Errorf("error adding ACL entry of component %d for %q: %w", id, entry.Principal, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("component with ID %d not found", id)
+	}
+	if cache.GlobalComponentCache != nil {
+		cache.GlobalComponentCache.SetACL(id, entries)
+	}
+	return nil
+}
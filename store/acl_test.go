@@ -0,0 +1,72 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceComponentACL(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	parent := createTestComponent(t, "ACLParent", "", sql.NullInt64{Valid: false})
+	child := createTestComponent(t, "ACLChild", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+
+	err := testStore.ReplaceComponentACL(parent.ID, []cache.ACLEntry{
+		{Principal: "alice", Permission: cache.PermissionWrite},
+		{Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, testStore.ReplaceComponentACL(child.ID, []cache.ACLEntry{{Principal: "bob", Permission: cache.PermissionRead}}))
+
+	entries, err := testStore.ListACLEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// Replacing overwrites; deleting the component drops its entries.
+	assert.NoError(t, testStore.ReplaceComponentACL(parent.ID, nil))
+	assert.NoError(t, testStore.DeleteComponent(child.ID))
+	entries, err = testStore.ListACLEntries()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	err = testStore.ReplaceComponentACL(child.ID, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestListingsReader(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	// The private root is alice's, but bob may read its child; the open root has no ACL.
+	private := createTestComponent(t, "ReaderPrivate", "", sql.NullInt64{})
+	child := createTestComponent(t, "ReaderChild", "", sql.NullInt64{Int64: private.ID, Valid: true})
+	createTestComponent(t, "ReaderOpen", "", sql.NullInt64{})
+	require.NoError(t, testStore.ReplaceComponentACL(private.ID, []cache.ACLEntry{
+		{Principal: "alice", Permission: cache.PermissionRead},
+		{Principal: cache.EveryonePrincipal, Permission: cache.PermissionNone},
+	}))
+	require.NoError(t, testStore.ReplaceComponentACL(child.ID, []cache.ACLEntry{{Principal: "bob", Permission: cache.PermissionRead}}))
+
+	strong := testStore.Strong()
+	for principal, want := range map[string]int{"alice": 3, "bob": 2, "carol": 1} {
+		count, err := strong.CountComponents(cache.Filter{Reader: &principal})
+		require.NoError(t, err)
+		assert.Equal(t, want, count, principal)
+	}
+	bob, carol := "bob", "carol"
+	body, err := strong.ListComponentsJSON(cache.Filter{Reader: &bob}, cache.Sort{}, 1, 1)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"name":"ReaderChild"`, "Expected the page to be cut from the readable components")
+	count, err := strong.CountChildComponents(private.ID, cache.Filter{Reader: &carol})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,291 @@
+package store
+
+import (
+	"component-service/attachments"
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrAttachmentsDisabled is returned by attachment operations when no storage is configured.
+var ErrAttachmentsDisabled = errors.New("attachments are disabled")
+
+// ErrAttachmentTooLarge is returned by CreateAttachment for data over MaxAttachmentBytes.
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+// ErrInvalidAttachment is returned by CreateAttachment for a file name CleanAttachmentFilename
+// rejects.
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// AttachmentStorage keeps the data of attachments; nil disables them. main sets it from the
+// environment with attachments.FromEnv.
+var AttachmentStorage attachments.Storage
+
+// MaxAttachmentBytes bounds the size of one attachment.
+var MaxAttachmentBytes int64 = attachments.DefaultMaxBytes
+
+const attachmentColumns = "id, component_id, filename, content_type, size, sha256, created_at"
+
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	var createdAt time.Time
+	err := row.Scan(&attachment.ID, &attachment.ComponentID, &attachment.Filename, &attachment.ContentType,
+		&attachment.Size, &attachment.SHA256, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	attachment.CreatedAt = createdAt.Format(time.RFC3339)
+	return attachment, nil
+}
+
+// CreateAttachment stores the data read from body as a file attached to a live component. The
+// data is spooled to a temporary file first, to learn its size and digest before it reaches
+// storage, and is deleted from storage again when the row cannot be written.
+func (s *ComponentStore) CreateAttachment(ctx context.Context, componentID int64, filename, contentType string, body io.Reader) (*models.Attachment, error) {
+	if AttachmentStorage == nil {
+		return nil, ErrAttachmentsDisabled
+	}
+	filename, err := models.CleanAttachmentFilename(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttachment, err)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	if found, err := componentExists(dbConn, componentID); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, fmt.Errorf("component with ID %d not found", componentID)
+	}
+
+	spool, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, fmt.Errorf("error buffering attachment: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	digest := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, digest), io.LimitReader(body, MaxAttachmentBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading attachment: %w", err)
+	}
+	if size > MaxAttachmentBytes {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrAttachmentTooLarge, MaxAttachmentBytes)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error buffering attachment: %w", err)
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("error generating attachment key: %w", err)
+	}
+	key := fmt.Sprintf("components/%d/%s", componentID, hex.EncodeToString(random))
+	if err := AttachmentStorage.Put(ctx, key, spool, size, contentType); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		ComponentID: componentID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(digest.Sum(nil)),
+	}
+	createdAt := time.Now().UTC()
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, err := lockComponentParent(tx, componentID)
+		if err != nil || before == nil {
+			found = false
+			return err
+		}
+		found = true
+		attachment.ID, err = insertReturningID(tx,
+			"INSERT INTO component_attachments (component_id, filename, content_type, size, sha256, storage_key, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			componentID, attachment.Filename, attachment.ContentType, attachment.Size, attachment.SHA256, key, createdAt)
+		if err != nil {
+			return fmt.Errorf("error creating attachment for component %d: %w", componentID, err)
+		}
+		return nil
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("component with ID %d not found", componentID)
+	}
+	if err != nil {
+		deleteAttachmentData([]string{key})
+		return nil, err
+	}
+	attachment.CreatedAt = createdAt.Format(time.RFC3339)
+	return attachment, nil
+}
+
+// componentExists reports whether a live component has id.
+func componentExists(exec sqlExecutor, id int64) (bool, error) {
+	var found int64
+	err := exec.QueryRow(db.Rebind("SELECT id FROM components WHERE id = $1 AND deleted_at IS NULL"), id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading component with ID %d: %w", id, err)
+	}
+	return true, nil
+}
+
+// ListAttachments returns the attachments of a live component, oldest first.
+func (s *ComponentStore) ListAttachments(componentID int64) ([]*models.Attachment, error) {
+	if AttachmentStorage == nil {
+		return nil, ErrAttachmentsDisabled
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	if found, err := componentExists(dbConn, componentID); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, fmt.Errorf("component with ID %d not found", componentID)
+	}
+	rows, err := dbConn.Query(db.Rebind("SELECT "+attachmentColumns+" FROM component_attachments WHERE component_id = $1 ORDER BY created_at, id"), componentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing attachments of component %d: %w", componentID, err)
+	}
+	defer rows.Close()
+	list := []*models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attachment: %w", err)
+		}
+		list = append(list, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attachments: %w", err)
+	}
+	return list, nil
+}
+
+// attachmentRow reads a live component's attachment and its storage key, or returns nil when
+// either is missing.
+func attachmentRow(exec sqlExecutor, componentID, attachmentID int64) (*models.Attachment, string, error) {
+	var key string
+	attachment := &models.Attachment{}
+	var createdAt time.Time
+	err := exec.QueryRow(db.Rebind(`SELECT a.id, a.component_id, a.filename, a.content_type, a.size, a.sha256, a.created_at, a.storage_key
+		FROM component_attachments a JOIN components c ON c.id = a.component_id
+		WHERE a.id = $1 AND a.component_id = $2 AND c.deleted_at IS NULL`), attachmentID, componentID).Scan(
+		&attachment.ID, &attachment.ComponentID, &attachment.Filename, &attachment.ContentType,
+		&attachment.Size, &attachment.SHA256, &createdAt, &key)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading attachment %d of component %d: %w", attachmentID, componentID, err)
+	}
+	attachment.CreatedAt = createdAt.Format(time.RFC3339)
+	return attachment, key, nil
+}
+
+// OpenAttachment returns an attachment of a live component with its data, which the caller
+// must close.
+func (s *ComponentStore) OpenAttachment(ctx context.Context, componentID, attachmentID int64) (*models.Attachment, io.ReadCloser, error) {
+	if AttachmentStorage == nil {
+		return nil, nil, ErrAttachmentsDisabled
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment, key, err := attachmentRow(dbConn, componentID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment == nil {
+		return nil, nil, fmt.Errorf("attachment %d of component %d not found", attachmentID, componentID)
+	}
+	data, err := AttachmentStorage.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, data, nil
+}
+
+// DeleteAttachment removes an attachment of a live component, then its data.
+func (s *ComponentStore) DeleteAttachment(componentID, attachmentID int64) error {
+	if AttachmentStorage == nil {
+		return ErrAttachmentsDisabled
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var key string
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		attachment, k, err := attachmentRow(tx, componentID, attachmentID)
+		if err != nil {
+			return err
+		}
+		found, key = attachment != nil, k
+		if !found {
+			return nil
+		}
+		if _, err := tx.Exec(db.Rebind("DELETE FROM component_attachments WHERE id = $1"), attachmentID); err != nil {
+			return fmt.Errorf("error deleting attachment %d of component %d: %w", attachmentID, componentID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("attachment %d of component %d not found", attachmentID, componentID)
+	}
+	deleteAttachmentData([]string{key})
+	return nil
+}
+
+// attachmentKeys returns the storage keys of a component's attachments inside tx.
+func attachmentKeys(tx *sql.Tx, componentID int64) ([]string, error) {
+	rows, err := tx.Query(db.Rebind("SELECT storage_key FROM component_attachments WHERE component_id = $1"), componentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing attachments of component %d: %w", componentID, err)
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error scanning attachment: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// deleteAttachmentData deletes the data under keys once their rows are gone. The rows are what
+// clients see, so failures are logged rather than returned; the data they leave is unreachable.
+func deleteAttachmentData(keys []string) {
+	if AttachmentStorage == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := AttachmentStorage.Delete(context.Background(), key); err != nil {
+			fmt.Printf("Error deleting attachment data %s: %v\n", key, err)
+		}
+	}
+}
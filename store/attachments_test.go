@@ -0,0 +1,71 @@
+package store
+
+import (
+	"component-service/attachments"
+	"component-service/db"
+	"context"
+	"database/sql"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachments(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	dir := t.TempDir()
+	storage, err := attachments.NewLocal(dir)
+	require.NoError(t, err)
+	AttachmentStorage, MaxAttachmentBytes = storage, 16
+	defer func() { AttachmentStorage, MaxAttachmentBytes = nil, attachments.DefaultMaxBytes }()
+	ctx := context.Background()
+	comp := createTestComponent(t, "Pump", "", sql.NullInt64{Valid: false})
+
+	attachment, err := testStore.CreateAttachment(ctx, comp.ID, "docs/manual.txt", "", strings.NewReader("read me"))
+	require.NoError(t, err)
+	assert.Equal(t, "manual.txt", attachment.Filename)
+	assert.Equal(t, "application/octet-stream", attachment.ContentType)
+	assert.Equal(t, int64(7), attachment.Size)
+
+	list, err := testStore.ListAttachments(comp.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	found, data, err := testStore.OpenAttachment(ctx, comp.ID, attachment.ID)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(data)
+	data.Close()
+	assert.Equal(t, "read me", string(content))
+	assert.Equal(t, attachment.SHA256, found.SHA256)
+
+	_, err = testStore.CreateAttachment(ctx, comp.ID, "big.bin", "", strings.NewReader(strings.Repeat("x", 17)))
+	assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+	_, err = testStore.CreateAttachment(ctx, 88888, "a.txt", "", strings.NewReader("x"))
+	assert.ErrorContains(t, err, "not found")
+
+	// Soft delete keeps the attachment for a restore; a purge removes its data too
+	require.NoError(t, testStore.DeleteComponent(comp.ID))
+	_, _, err = testStore.OpenAttachment(ctx, comp.ID, attachment.ID)
+	assert.ErrorContains(t, err, "not found")
+	require.NoError(t, testStore.PurgeComponent(comp.ID))
+	files := 0
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			files++
+		}
+		return nil
+	})
+	assert.Zero(t, files, "Expected the purge to delete the attachment data")
+
+	other := createTestComponent(t, "Valve", "", sql.NullInt64{Valid: false})
+	attachment, err = testStore.CreateAttachment(ctx, other.ID, "a.txt", "text/plain", strings.NewReader("x"))
+	require.NoError(t, err)
+	require.NoError(t, testStore.DeleteAttachment(other.ID, attachment.ID))
+	assert.ErrorContains(t, testStore.DeleteAttachment(other.ID, attachment.ID), "not found")
+}
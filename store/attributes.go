@@ -0,0 +1,255 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidAttributes is returned by a write giving a component metadata that does not conform
+// to the attribute schema of its type. It wraps the *models.AttributeError naming the attribute.
+var ErrInvalidAttributes = errors.New("invalid attributes")
+
+// ErrInvalidAttributeSchema is returned when replacing an attribute schema that is malformed, or
+// is for a type ComponentTypes does not allow.
+var ErrInvalidAttributeSchema = errors.New("invalid attribute schema")
+
+// ErrAttributeSchemaNotFound is returned for a type that has no attribute schema.
+var ErrAttributeSchemaNotFound = errors.New("attribute schema not found")
+
+// ListAttributeSchemas returns every attribute schema, by type.
+func (s *ComponentStore) ListAttributeSchemas() ([]*models.AttributeSchema, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	return readAttributeSchemas(dbConn, `SELECT s.type, s.name, s.data_type, s.required, s.enum_values, COALESCE(o.strict, FALSE)
+		FROM attribute_schemas s LEFT JOIN attribute_schema_options o ON o.type = s.type ORDER BY s.type, s.position`)
+}
+
+// GetAttributeSchema returns the attribute schema of type t, failing with
+// ErrAttributeSchemaNotFound when it has none.
+func (s *ComponentStore) GetAttributeSchema(t string) (*models.AttributeSchema, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := attributeSchemaOf(dbConn, t)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("%w: type %q has none", ErrAttributeSchemaNotFound, t)
+	}
+	return schema, nil
+}
+
+// ReplaceAttributeSchema defines the attributes of a type, and whether the schema is strict,
+// replacing any defined before. The components of the type are checked against it when next
+// written; those already stored are not, so AttributeViolations is worth reading before making a
+// schema stricter.
+func (s *ComponentStore) ReplaceAttributeSchema(schema *models.AttributeSchema) error {
+	if err := schema.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributeSchema, err)
+	}
+	if err := checkType(schema.Type); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributeSchema, err)
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	return db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(db.Rebind("DELETE FROM attribute_schemas WHERE type = $1"), schema.Type); err != nil {
+			return fmt.Errorf("error clearing attribute schema of type %q: %w", schema.Type, err)
+		}
+		for i, attr := range schema.Attributes {
+			var enum interface{}
+			if attr.Enum != nil {
+				values, _ := json.Marshal(attr.Enum)
+				enum = string(values)
+			}
+			_, err := tx.Exec(db.Rebind("INSERT INTO attribute_schemas (type, name, data_type, required, enum_values, position) VALUES ($1, $2, $3, $4, $5, $6)"),
+				schema.Type, attr.Name, string(attr.DataType), attr.Required, enum, i)
+			if err != nil {
+				return fmt.Errorf("error defining attribute %q of type %q: %w", attr.Name, schema.Type, err)
+			}
+		}
+		_, err := tx.Exec(db.Rebind("INSERT INTO attribute_schema_options (type, strict) VALUES ($1, $2)"+
+			db.CurrentDialect.UpsertClause([]string{"type"}, []string{"strict"})), schema.Type, schema.Strict)
+		if err != nil {
+			return fmt.Errorf("error setting options of attribute schema of type %q: %w", schema.Type, err)
+		}
+		return nil
+	})
+}
+
+// DeleteAttributeSchema removes the attribute schema of type t, leaving the metadata of its
+// components free-form. It fails with ErrAttributeSchemaNotFound when t has none.
+func (s *ComponentStore) DeleteAttributeSchema(t string) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	return db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		result, err := tx.Exec(db.Rebind("DELETE FROM attribute_schemas WHERE type = $1"), t)
+		if err != nil {
+			return fmt.Errorf("error deleting attribute schema of type %q: %w", t, err)
+		}
+		if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+			return fmt.Errorf("%w: type %q has none", ErrAttributeSchemaNotFound, t)
+		}
+		if _, err := tx.Exec(db.Rebind("DELETE FROM attribute_schema_options WHERE type = $1"), t); err != nil {
+			return fmt.Errorf("error deleting options of attribute schema of type %q: %w", t, err)
+		}
+		return nil
+	})
+}
+
+// ComponentAttributeViolations lists how the metadata of a component breaks the attribute schema
+// of its type.
+type ComponentAttributeViolations struct {
+	ID         int64                    `json:"id"`
+	Name       string                   `json:"name"`
+	Violations []*models.AttributeError `json:"violations"`
+}
+
+// AttributeViolationReport is what AttributeViolations finds among the components of a type.
+type AttributeViolationReport struct {
+	Type       string                         `json:"type"`
+	Strict     bool                           `json:"strict"`    // whether the schema is strict already
+	Checked    int                            `json:"checked"`   // components of the type
+	Violating  int                            `json:"violating"` // of them, those that break the schema
+	Components []ComponentAttributeViolations `json:"components"`
+}
+
+// AttributeViolations checks the stored components of type t against its attribute schema, as
+// if it were strict, and reports those that break it: the first limit of them by ID, though all
+// are counted. These are the components that would fail to be written once the schema is strict,
+// or that were stored before the schema was last replaced. It fails with
+// ErrAttributeSchemaNotFound when t has no schema.
+func (s *ComponentStore) AttributeViolations(t string, limit int) (*AttributeViolationReport, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := attributeSchemaOf(dbConn, t)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("%w: type %q has none", ErrAttributeSchemaNotFound, t)
+	}
+	rows, err := dbConn.Query(db.Rebind("SELECT id, name, metadata FROM components WHERE type = $1 AND deleted_at IS NULL ORDER BY id"), t)
+	if err != nil {
+		return nil, fmt.Errorf("error reading components of type %q: %w", t, err)
+	}
+	defer rows.Close()
+	report := &AttributeViolationReport{Type: t, Strict: schema.Strict, Components: []ComponentAttributeViolations{}}
+	for rows.Next() {
+		var component ComponentAttributeViolations
+		var metadata []byte
+		if err := rows.Scan(&component.ID, &component.Name, &metadata); err != nil {
+			return nil, fmt.Errorf("error scanning component of type %q: %w", t, err)
+		}
+		report.Checked++
+		component.Violations = schema.Violations(metadata, true)
+		if len(component.Violations) == 0 {
+			continue
+		}
+		report.Violating++
+		if len(report.Components) < limit {
+			report.Components = append(report.Components, component)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating components of type %q: %w", t, err)
+	}
+	return report, nil
+}
+
+// checkAttributes fails with ErrInvalidAttributes unless metadata, normalized, conforms to the
+// attribute schema of type t, read through exec. Untyped components and types without a schema
+// pass.
+func checkAttributes(exec sqlExecutor, t string, metadata json.RawMessage) error {
+	if t == "" {
+		return nil
+	}
+	schema, err := attributeSchemaOf(exec, t)
+	if err != nil || schema == nil {
+		return err
+	}
+	if err := schema.Check(metadata); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAttributes, err)
+	}
+	return nil
+}
+
+// checkPatchedAttributes checks the type and metadata id has once patch is applied, as
+// checkAttributes does, reading the current ones inside tx when the patch leaves them alone.
+// metadata is the patch's, normalized. A patch setting neither is not checked.
+func checkPatchedAttributes(tx *sql.Tx, id int64, patch models.ComponentPatch, metadata json.RawMessage) error {
+	if patch.Type == nil && patch.Metadata == nil {
+		return nil
+	}
+	var t string
+	var current []byte
+	if err := tx.QueryRow(db.Rebind("SELECT type, metadata FROM components WHERE id = $1"), id).Scan(&t, &current); err != nil {
+		return fmt.Errorf("error reading type and metadata of component with ID %d: %w", id, err)
+	}
+	if patch.Type != nil {
+		t = *patch.Type
+	}
+	if patch.Metadata == nil {
+		metadata = current
+	}
+	return checkAttributes(tx, t, metadata)
+}
+
+// attributeSchemaOf reads the attribute schema of type t, or returns nil when it has none.
+func attributeSchemaOf(exec sqlExecutor, t string) (*models.AttributeSchema, error) {
+	schemas, err := readAttributeSchemas(exec, `SELECT s.type, s.name, s.data_type, s.required, s.enum_values, COALESCE(o.strict, FALSE)
+		FROM attribute_schemas s LEFT JOIN attribute_schema_options o ON o.type = s.type WHERE s.type = $1 ORDER BY s.position`, t)
+	if err != nil || len(schemas) == 0 {
+		return nil, err
+	}
+	return schemas[0], nil
+}
+
+// readAttributeSchemas runs a query selecting (type, name, data_type, required, enum_values,
+// strict) rows, grouped by type and in attribute order, and assembles the schemas.
+func readAttributeSchemas(exec sqlExecutor, query string, args ...interface{}) ([]*models.AttributeSchema, error) {
+	rows, err := exec.Query(db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading attribute schemas: %w", err)
+	}
+	defer rows.Close()
+	var schemas []*models.AttributeSchema
+	for rows.Next() {
+		var t, dataType string
+		var attr models.AttributeDefinition
+		var enum sql.NullString
+		var strict bool
+		if err := rows.Scan(&t, &attr.Name, &dataType, &attr.Required, &enum, &strict); err != nil {
+			return nil, fmt.Errorf("error scanning attribute schema: %w", err)
+		}
+		attr.DataType = models.AttributeType(dataType)
+		if enum.Valid {
+			if err := json.Unmarshal([]byte(enum.String), &attr.Enum); err != nil {
+				return nil, fmt.Errorf("error reading enum values of attribute %q of type %q: %w", attr.Name, t, err)
+			}
+		}
+		if len(schemas) == 0 || schemas[len(schemas)-1].Type != t {
+			schemas = append(schemas, &models.AttributeSchema{Type: t, Strict: strict})
+		}
+		last := schemas[len(schemas)-1]
+		last.Attributes = append(last.Attributes, attr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attribute schemas: %w", err)
+	}
+	return schemas, nil
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeSchemas(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	defer db.DB.Exec("DELETE FROM attribute_schemas")
+	defer db.DB.Exec("DELETE FROM attribute_schema_options")
+
+	schema := &models.AttributeSchema{Type: "pump", Attributes: []models.AttributeDefinition{
+		{Name: "vendor", DataType: models.AttributeString, Required: true, Enum: []string{"acme", "flowco"}},
+		{Name: "rated_kw", DataType: models.AttributeNumber},
+	}}
+	require.NoError(t, testStore.ReplaceAttributeSchema(schema))
+	got, err := testStore.GetAttributeSchema("pump")
+	require.NoError(t, err)
+	assert.Equal(t, schema, got)
+	schemas, err := testStore.ListAttributeSchemas()
+	require.NoError(t, err)
+	assert.Equal(t, []*models.AttributeSchema{schema}, schemas)
+	err = testStore.ReplaceAttributeSchema(&models.AttributeSchema{Type: "pump", Attributes: []models.AttributeDefinition{{Name: "a", DataType: "date"}}})
+	assert.ErrorIs(t, err, ErrInvalidAttributeSchema)
+
+	_, err = testStore.CreateComponent(&models.Component{Name: "Pump", Type: "pump"})
+	var attrErr *models.AttributeError
+	if assert.ErrorIs(t, err, ErrInvalidAttributes) && assert.True(t, errors.As(err, &attrErr)) {
+		assert.Equal(t, "vendor", attrErr.Attribute)
+	}
+	pumpID, err := testStore.CreateComponent(&models.Component{Name: "Pump", Type: "pump", Metadata: json.RawMessage(`{"vendor":"acme","rated_kw":7.5}`)})
+	require.NoError(t, err)
+	_, err = testStore.CreateComponent(&models.Component{Name: "Untyped", Metadata: json.RawMessage(`{"vendor":"other"}`)})
+	assert.NoError(t, err, "untyped components have no schema")
+
+	bad := json.RawMessage(`{"vendor":"acme","rated_kw":"high"}`)
+	assert.ErrorIs(t, testStore.PatchComponent(pumpID, models.ComponentPatch{Metadata: &bad}), ErrInvalidAttributes)
+	assert.ErrorIs(t, testStore.UpdateComponent(pumpID, &models.Component{Name: "Pump", Type: "pump"}), ErrInvalidAttributes)
+	name := "Main pump"
+	assert.NoError(t, testStore.PatchComponent(pumpID, models.ComponentPatch{Name: &name}), "a patch leaving type and metadata alone is not checked")
+	gaugeID, err := testStore.CreateComponent(&models.Component{Name: "Gauge", Type: "gauge"})
+	require.NoError(t, err)
+	pump := "pump"
+	assert.ErrorIs(t, testStore.PatchComponent(gaugeID, models.ComponentPatch{Type: &pump}), ErrInvalidAttributes, "the current metadata is checked against the new type's schema")
+
+	_, err = testStore.ImportComponents("attributes", []*models.Component{{ID: 1, Name: "Pump", Type: "pump"}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	assert.ErrorIs(t, err, ErrInvalidAttributes)
+
+	require.NoError(t, testStore.DeleteAttributeSchema("pump"))
+	assert.ErrorIs(t, testStore.DeleteAttributeSchema("pump"), ErrAttributeSchemaNotFound)
+	_, err = testStore.GetAttributeSchema("pump")
+	assert.ErrorIs(t, err, ErrAttributeSchemaNotFound)
+	assert.NoError(t, testStore.PatchComponent(pumpID, models.ComponentPatch{Metadata: &bad}), "without a schema metadata is free-form")
+}
+
+func TestStrictAttributeSchemas(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	defer db.DB.Exec("DELETE FROM attribute_schemas")
+	defer db.DB.Exec("DELETE FROM attribute_schema_options")
+
+	schema := &models.AttributeSchema{Type: "pump", Attributes: []models.AttributeDefinition{
+		{Name: "vendor", DataType: models.AttributeString},
+	}}
+	require.NoError(t, testStore.ReplaceAttributeSchema(schema))
+	cleanID, err := testStore.CreateComponent(&models.Component{Name: "Clean", Type: "pump", Metadata: json.RawMessage(`{"vendor":"acme"}`)})
+	require.NoError(t, err)
+	colorID, err := testStore.CreateComponent(&models.Component{Name: "Colored", Type: "pump", Metadata: json.RawMessage(`{"vendor":"acme","color":"red"}`)})
+	require.NoError(t, err, "a schema that is not strict leaves other keys free-form")
+	_, err = testStore.CreateComponent(&models.Component{Name: "Other", Type: "gauge", Metadata: json.RawMessage(`{"color":"red"}`)})
+	require.NoError(t, err)
+
+	report, err := testStore.AttributeViolations("pump", 10)
+	require.NoError(t, err)
+	assert.False(t, report.Strict)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, 1, report.Violating)
+	if assert.Len(t, report.Components, 1) {
+		assert.Equal(t, colorID, report.Components[0].ID)
+		assert.Equal(t, "color", report.Components[0].Violations[0].Attribute)
+	}
+	report, err = testStore.AttributeViolations("pump", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Violating, "violations beyond the limit are still counted")
+	assert.Empty(t, report.Components)
+	_, err = testStore.AttributeViolations("gauge", 10)
+	assert.ErrorIs(t, err, ErrAttributeSchemaNotFound)
+
+	schema.Strict = true
+	require.NoError(t, testStore.ReplaceAttributeSchema(schema))
+	got, err := testStore.GetAttributeSchema("pump")
+	require.NoError(t, err)
+	assert.True(t, got.Strict)
+	_, err = testStore.CreateComponent(&models.Component{Name: "Sized", Type: "pump", Metadata: json.RawMessage(`{"size":3}`)})
+	var attrErr *models.AttributeError
+	if assert.ErrorIs(t, err, ErrInvalidAttributes) && assert.True(t, errors.As(err, &attrErr)) {
+		assert.Equal(t, "size", attrErr.Attribute)
+	}
+	name := "Renamed"
+	assert.NoError(t, testStore.PatchComponent(colorID, models.ComponentPatch{Name: &name}), "stored violations are only checked when the metadata is written")
+	metadata := json.RawMessage(`{"vendor":"flowco","color":"blue"}`)
+	assert.ErrorIs(t, testStore.PatchComponent(cleanID, models.ComponentPatch{Metadata: &metadata}), ErrInvalidAttributes)
+
+	require.NoError(t, testStore.DeleteAttributeSchema("pump"))
+	require.NoError(t, testStore.ReplaceAttributeSchema(&models.AttributeSchema{Type: "pump", Attributes: schema.Attributes}))
+	got, err = testStore.GetAttributeSchema("pump")
+	require.NoError(t, err)
+	assert.False(t, got.Strict, "deleting a schema deletes its options")
+}
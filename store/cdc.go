@@ -0,0 +1,382 @@
+package store
+
+import (
+	"bytes"
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultCDCSlot         = "component_service_cache"
+	defaultCDCPollInterval = time.Second
+	defaultCDCBatchSize    = 1000
+)
+
+// CDCConsumer keeps the component cache in step with the database by reading row changes from a
+// PostgreSQL logical replication slot using the wal2json output plugin. It sees every committed
+// change to the components and component_tags tables regardless of which process made it, so no
+// application-level hooks or triggers are needed.
+//
+// Changes are read with pg_logical_slot_peek_changes over an ordinary connection and the slot is
+// only advanced once they have been applied, so a crash replays rather than loses changes.
+// Applying a change is idempotent.
+type CDCConsumer struct {
+	Store        *ComponentStore
+	Slot         string
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// CDCConsumerFromEnv returns a consumer when CDC_MODE=wal2json, or nil when CDC is disabled.
+// CDC_SLOT names the replication slot (default component_service_cache; each replica needs its
+// own) and CDC_POLL_INTERVAL sets how often it is read (default 1s).
+func CDCConsumerFromEnv(s *ComponentStore) (*CDCConsumer, error) {
+	mode := os.Getenv("CDC_MODE")
+	if mode == "" || mode == "off" {
+		return nil, nil
+	}
+	if mode != "wal2json" {
+		return nil, fmt.Errorf("unsupported CDC_MODE %q (expected wal2json)", mode)
+	}
+	if name := db.CurrentDialect.Name(); name != "postgres" {
+		return nil, fmt.Errorf("CDC_MODE=wal2json requires PostgreSQL, not %s", name)
+	}
+	consumer := &CDCConsumer{Store: s, Slot: defaultCDCSlot, PollInterval: defaultCDCPollInterval, BatchSize: defaultCDCBatchSize}
+	if slot := os.Getenv("CDC_SLOT"); slot != "" {
+		consumer.Slot = slot
+	}
+	if interval := os.Getenv("CDC_POLL_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid CDC_POLL_INTERVAL %q", interval)
+		}
+		consumer.PollInterval = parsed
+	}
+	return consumer, nil
+}
+
+// Run creates the slot if needed and applies changes until ctx is cancelled. Poll errors are
+// logged, mark the cache stale until a poll succeeds, and are retried on the next tick.
+func (c *CDCConsumer) Run(ctx context.Context) error {
+	if err := c.ensureSlot(ctx); err != nil {
+		return err
+	}
+	log.Printf("CDC consumer reading replication slot %s every %s", c.Slot, c.PollInterval)
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			applied, err := c.poll(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("CDC poll of slot %s failed: %v", c.Slot, err)
+				cache.MarkStale(cache.ReasonCDCPollFailed, err)
+				break
+			}
+			cache.ClearStale(cache.ReasonCDCPollFailed)
+			if applied < c.BatchSize {
+				break // caught up; otherwise drain the backlog without waiting
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *CDCConsumer) ensureSlot(ctx context.Context) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var exists bool
+	if err := dbConn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", c.Slot).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking replication slot %s: %w", c.Slot, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := dbConn.ExecContext(ctx, "SELECT pg_create_logical_replication_slot($1, 'wal2json')", c.Slot); err != nil {
+		return fmt.Errorf("error creating replication slot %s (requires wal_level=logical, the wal2json plugin and the REPLICATION privilege): %w", c.Slot, err)
+	}
+	log.Printf("Created logical replication slot %s", c.Slot)
+	return nil
+}
+
+// poll applies up to BatchSize pending changes and then advances the slot past them.
+func (c *CDCConsumer) poll(ctx context.Context) (int, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	rows, err := dbConn.QueryContext(ctx,
+		`SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2,
+			'format-version', '2', 'include-transaction', 'false', 'add-tables', '*.components,*.component_tags')`,
+		c.Slot, c.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("error reading changes: %w", err)
+	}
+	defer rows.Close()
+
+	applied := 0
+	lastLSN := ""
+	for rows.Next() {
+		var lsn string
+		var data []byte
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return applied, fmt.Errorf("error scanning change: %w", err)
+		}
+		if err := c.apply(data); err != nil {
+			return applied, fmt.Errorf("error applying change at %s: %w", lsn, err)
+		}
+		applied++
+		lastLSN = lsn
+	}
+	if err := rows.Err(); err != nil {
+		return applied, fmt.Errorf("error iterating changes: %w", err)
+	}
+	rows.Close()
+
+	if lastLSN != "" {
+		if _, err := dbConn.ExecContext(ctx, "SELECT pg_replication_slot_advance($1, $2::pg_lsn)", c.Slot, lastLSN); err != nil {
+			return applied, fmt.Errorf("error advancing slot to %s: %w", lastLSN, err)
+		}
+	}
+	return applied, nil
+}
+
+func (c *CDCConsumer) apply(data []byte) error {
+	change, err := parseWal2JSONChange(data)
+	if err != nil {
+		return err
+	}
+	if cache.GlobalComponentCache == nil {
+		return nil
+	}
+	switch change.action {
+	case "I", "U":
+		// Tags live in their own table, and changes to them arrive on their own. A component new to
+		// the cache, created or restored, has its tags read.
+		if cached, found := cache.GlobalComponentCache.GetByID(change.id); found {
+			change.component.Tags = cached.Tags
+		} else if err := c.readTags(change.component); err != nil {
+			return err
+		}
+		cache.GlobalComponentCache.Set(change.component)
+	case "D":
+		cache.GlobalComponentCache.Delete(change.id)
+	case actionTags:
+		return c.reloadTags(change.id)
+	case "T":
+		log.Printf("CDC saw TRUNCATE on %s; rebuilding the cache", change.table)
+		return cache.InitGlobalCache(c.Store)
+	}
+	return nil
+}
+
+// reloadTags reads a cached component's tags as committed and updates the cache with them. A
+// component the cache does not hold is left alone: it was deleted, or is soft-deleted, and has
+// its tags read when a change to its row brings it back.
+func (c *CDCConsumer) reloadTags(id int64) error {
+	cached, found := cache.GlobalComponentCache.GetByID(id)
+	if !found {
+		return nil
+	}
+	if err := c.readTags(cached); err != nil {
+		return err
+	}
+	cache.GlobalComponentCache.Set(cached)
+	return nil
+}
+
+// readTags sets component's tags as committed.
+func (c *CDCConsumer) readTags(component *models.Component) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	component.Tags, err = tagsOf(dbConn, component.ID)
+	return err
+}
+
+// actionTags is the cdcChange action of any row change to component_tags: the component's tags
+// are read again, whichever rows changed.
+const actionTags = "tags"
+
+// cdcChange is one parsed wal2json row change.
+type cdcChange struct {
+	action    string            // I, U, D, T or actionTags
+	table     string            // changed table, for T
+	id        int64             // component ID, for every action but T
+	component *models.Component // new row, for I and U
+}
+
+// wal2jsonColumn is a column value in wal2json format-version 2 output.
+type wal2jsonColumn struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+type wal2jsonMessage struct {
+	Action   string           `json:"action"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+// parseWal2JSONChange decodes a format-version 2 message for the components or component_tags
+// table.
+func parseWal2JSONChange(data []byte) (*cdcChange, error) {
+	var msg wal2jsonMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, fmt.Errorf("error decoding wal2json message: %w", err)
+	}
+	if msg.Table == "component_tags" && msg.Action != "T" {
+		return tagsChange(msg)
+	}
+	change := &cdcChange{action: msg.Action}
+	switch msg.Action {
+	case "I", "U":
+		component, err := componentFromColumns(msg.Columns)
+		if err != nil {
+			return nil, err
+		}
+		change.component = component
+		change.id = component.ID
+		if softDeleted(msg.Columns) {
+			// A soft delete leaves the cache like a delete, and a restore adds the row back.
+			change.action, change.component = "D", nil
+		}
+	case "D":
+		for _, col := range msg.Identity {
+			if col.Name == "id" {
+				id, err := strconv.ParseInt(string(col.Value), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid id %s in delete: %w", col.Value, err)
+				}
+				change.id = id
+			}
+		}
+		if change.id == 0 {
+			return nil, fmt.Errorf("delete without id in identity (check the table's REPLICA IDENTITY)")
+		}
+	case "T":
+		change.table = msg.Table
+	default:
+		return nil, fmt.Errorf("unexpected wal2json action %q", msg.Action)
+	}
+	return change, nil
+}
+
+// tagsChange decodes a row change to component_tags into the actionTags change of its component:
+// the new row's component_id for an insert or update, the old row's for a delete.
+func tagsChange(msg wal2jsonMessage) (*cdcChange, error) {
+	columns := msg.Columns
+	switch msg.Action {
+	case "I", "U":
+	case "D":
+		columns = msg.Identity
+	default:
+		return nil, fmt.Errorf("unexpected wal2json action %q", msg.Action)
+	}
+	for _, col := range columns {
+		if col.Name == "component_id" {
+			id, err := strconv.ParseInt(string(col.Value), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid component_id %s in tag change: %w", col.Value, err)
+			}
+			return &cdcChange{action: actionTags, id: id}, nil
+		}
+	}
+	return nil, fmt.Errorf("tag change without component_id (check the table's REPLICA IDENTITY)")
+}
+
+// softDeleted reports whether a row's deleted_at column is set.
+func softDeleted(columns []wal2jsonColumn) bool {
+	for _, col := range columns {
+		if col.Name == "deleted_at" {
+			return string(col.Value) != "null"
+		}
+	}
+	return false
+}
+
+func componentFromColumns(columns []wal2jsonColumn) (*models.Component, error) {
+	component := &models.Component{}
+	for _, col := range columns {
+		if string(col.Value) == "null" {
+			continue // NULL parent_id stays invalid and NULL metadata nil; other columns are NOT NULL
+		}
+		var err error
+		switch col.Name {
+		case "id":
+			component.ID, err = strconv.ParseInt(string(col.Value), 10, 64)
+		case "parent_id":
+			component.ParentID = sql.NullInt64{Valid: true}
+			component.ParentID.Int64, err = strconv.ParseInt(string(col.Value), 10, 64)
+		case "position":
+			component.Position, err = strconv.ParseInt(string(col.Value), 10, 64)
+		case "version":
+			component.Version, err = strconv.ParseInt(string(col.Value), 10, 64)
+		case "name":
+			err = json.Unmarshal(col.Value, &component.Name)
+		case "slug":
+			err = json.Unmarshal(col.Value, &component.Slug)
+		case "type":
+			err = json.Unmarshal(col.Value, &component.Type)
+		case "status":
+			err = json.Unmarshal(col.Value, &component.Status)
+		case "description":
+			err = json.Unmarshal(col.Value, &component.Description)
+		case "metadata":
+			// wal2json sends jsonb as its text, in a JSON string
+			var text string
+			if err = json.Unmarshal(col.Value, &text); err == nil {
+				component.Metadata = json.RawMessage(text)
+			}
+		case "created_at":
+			component.CreatedAt, err = postgresTimestampToRFC3339(col.Value)
+		case "updated_at":
+			component.UpdatedAt, err = postgresTimestampToRFC3339(col.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %s: %w", col.Name, col.Value, err)
+		}
+	}
+	if component.ID == 0 {
+		return nil, fmt.Errorf("row change without id")
+	}
+	return component, nil
+}
+
+// postgresTimestampLayouts cover timestamptz text output with whole-hour and other offsets.
+var postgresTimestampLayouts = []string{"2006-01-02 15:04:05.999999999-07", "2006-01-02 15:04:05.999999999-07:00"}
+
+// postgresTimestampToRFC3339 converts a quoted timestamptz value to the format the store uses.
+func postgresTimestampToRFC3339(raw json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return "", err
+	}
+	for _, layout := range postgresTimestampLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t.Format(time.RFC3339), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized timestamp %q", text)
+}
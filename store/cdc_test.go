@@ -0,0 +1,86 @@
+package store
+
+import (
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWal2JSONChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *cdcChange
+		wantErr  bool
+	}{
+		{
+			name: "insert root",
+			data: `{"action":"I","schema":"public","table":"components","columns":[{"name":"id","type":"integer","value":7},{"name":"name","type":"character varying(255)","value":"Pump \"A\""},{"name":"description","type":"text","value":""},{"name":"metadata","type":"jsonb","value":null},{"name":"parent_id","type":"integer","value":null},{"name":"created_at","type":"timestamp with time zone","value":"2024-03-05 14:07:09.123456+00"},{"name":"updated_at","type":"timestamp with time zone","value":"2024-03-05 14:07:09.123456+00"}]}`,
+			expected: &cdcChange{action: "I", id: 7, component: &models.Component{
+				ID: 7, Name: `Pump "A"`, CreatedAt: "2024-03-05T14:07:09Z", UpdatedAt: "2024-03-05T14:07:09Z",
+			}},
+		},
+		{
+			name: "update with parent and non-hour offset",
+			data: `{"action":"U","schema":"public","table":"components","columns":[{"name":"id","type":"integer","value":8},{"name":"name","type":"character varying(255)","value":"Valve"},{"name":"description","type":"text","value":"d"},{"name":"metadata","type":"jsonb","value":"{\"vendor\": \"acme\"}"},{"name":"parent_id","type":"integer","value":7},{"name":"created_at","type":"timestamp with time zone","value":"2024-03-05 14:07:09+05:30"},{"name":"updated_at","type":"timestamp with time zone","value":"2024-03-06 09:00:00+05:30"}],"identity":[{"name":"id","type":"integer","value":8}]}`,
+			expected: &cdcChange{action: "U", id: 8, component: &models.Component{
+				ID: 8, Name: "Valve", Description: "d", Metadata: json.RawMessage(`{"vendor": "acme"}`), ParentID: sql.NullInt64{Int64: 7, Valid: true},
+				CreatedAt: "2024-03-05T14:07:09+05:30", UpdatedAt: "2024-03-06T09:00:00+05:30",
+			}},
+		},
+		{
+			name:     "soft delete",
+			data:     `{"action":"U","schema":"public","table":"components","columns":[{"name":"id","type":"integer","value":8},{"name":"name","type":"character varying(255)","value":"Valve"},{"name":"parent_id","type":"integer","value":7},{"name":"deleted_at","type":"timestamp with time zone","value":"2024-03-06 09:00:00+00"}],"identity":[{"name":"id","type":"integer","value":8}]}`,
+			expected: &cdcChange{action: "D", id: 8},
+		},
+		{
+			name:     "restore",
+			data:     `{"action":"U","schema":"public","table":"components","columns":[{"name":"id","type":"integer","value":8},{"name":"name","type":"character varying(255)","value":"Valve"},{"name":"deleted_at","type":"timestamp with time zone","value":null}],"identity":[{"name":"id","type":"integer","value":8}]}`,
+			expected: &cdcChange{action: "U", id: 8, component: &models.Component{ID: 8, Name: "Valve"}},
+		},
+		{
+			name:     "delete",
+			data:     `{"action":"D","schema":"public","table":"components","identity":[{"name":"id","type":"integer","value":9}]}`,
+			expected: &cdcChange{action: "D", id: 9},
+		},
+		{
+			name:     "truncate",
+			data:     `{"action":"T","schema":"public","table":"components"}`,
+			expected: &cdcChange{action: "T", table: "components"},
+		},
+		{
+			name:     "tag added",
+			data:     `{"action":"I","schema":"public","table":"component_tags","columns":[{"name":"component_id","type":"integer","value":7},{"name":"tag","type":"character varying(64)","value":"critical"}]}`,
+			expected: &cdcChange{action: actionTags, id: 7},
+		},
+		{
+			name:     "tag removed",
+			data:     `{"action":"D","schema":"public","table":"component_tags","identity":[{"name":"component_id","type":"integer","value":7},{"name":"tag","type":"character varying(64)","value":"critical"}]}`,
+			expected: &cdcChange{action: actionTags, id: 7},
+		},
+		{
+			name:     "tags truncated",
+			data:     `{"action":"T","schema":"public","table":"component_tags"}`,
+			expected: &cdcChange{action: "T", table: "component_tags"},
+		},
+		{name: "tag removed without identity", data: `{"action":"D","schema":"public","table":"component_tags","identity":[]}`, wantErr: true},
+		{name: "delete without identity", data: `{"action":"D","schema":"public","table":"components","identity":[]}`, wantErr: true},
+		{name: "bad timestamp", data: `{"action":"I","columns":[{"name":"id","value":1},{"name":"created_at","value":"yesterday"}]}`, wantErr: true},
+		{name: "unknown action", data: `{"action":"M","prefix":"x"}`, wantErr: true},
+		{name: "not json", data: `BEGIN 123`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := parseWal2JSONChange([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, change)
+		})
+	}
+}
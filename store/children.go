@@ -0,0 +1,156 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChildErrors is returned by CreateChildren when some of the children cannot be created, none
+// then being created. It maps the index of each such child to why: a missing name, or
+// ErrInvalidType, ErrInvalidStatus, ErrInvalidTags, ErrInvalidMetadata, ErrInvalidAttributes or
+// ErrDuplicateName, as on create.
+type ChildErrors map[int]error
+
+func (e ChildErrors) Error() string {
+	indexes := make([]int, 0, len(e))
+	for i := range e {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	reasons := make([]string, len(indexes))
+	for n, i := range indexes {
+		reasons[n] = fmt.Sprintf("child %d: %v", i, e[i])
+	}
+	return fmt.Sprintf("%d of the children cannot be created: %s", len(e), strings.Join(reasons, "; "))
+}
+
+// CreateChildren creates components under parentID in one transaction, after its current
+// children and in the order given, and returns their IDs in that order. Each is checked as
+// CreateComponent checks it, and gets a slug the same way. A parent that does not exist fails with
+// ErrParentNotFound; children that cannot be created fail with ChildErrors, naming every one of
+// them, and then none is created. The parent is locked meanwhile, so it cannot be deleted or moved
+// from under its new children.
+func (s *ComponentStore) CreateChildren(parentID int64, children []*models.Component) ([]int64, error) {
+	childErrors := ChildErrors{}
+	statuses := make([]string, len(children))
+	tags := make([][]string, len(children))
+	metadata := make([]json.RawMessage, len(children))
+	for i, child := range children {
+		var err error
+		if child.Name == "" {
+			err = errors.New("name is required")
+		} else if err = checkType(child.Type); err == nil {
+			if statuses[i], err = checkStatus(child.Status); err == nil {
+				if tags[i], err = checkTags(child.Tags); err == nil {
+					metadata[i], err = checkMetadata(child.Metadata)
+				}
+			}
+		}
+		if err != nil {
+			childErrors[i] = err
+		}
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	parent := sql.NullInt64{Int64: parentID, Valid: true}
+	var ids []int64
+	err = executeTxClaimingSlugs(dbConn, func(tx *sql.Tx) error {
+		ids = ids[:0] // fn may run again when the transaction is retried
+		for i := range children {
+			if errors.Is(childErrors[i], ErrInvalidAttributes) || errors.Is(childErrors[i], ErrDuplicateName) {
+				delete(childErrors, i) // found by an earlier run, which may have differed
+			}
+		}
+		locked, err := lockComponentParent(tx, parentID)
+		if err != nil {
+			return err
+		}
+		if locked == nil {
+			return fmt.Errorf("%w: component with ID %d does not exist", ErrParentNotFound, parentID)
+		}
+		now := time.Now()
+		named := make(map[string]int, len(children)) // the first child of each name, by name
+		for i, child := range children {
+			first, repeated := named[child.Name]
+			if !repeated {
+				named[child.Name] = i
+			}
+			if childErrors[i] != nil {
+				continue
+			}
+			err := checkAttributes(tx, child.Type, metadata[i])
+			if err == nil && UniqueSiblingNames && repeated {
+				err = fmt.Errorf("%w: child %d is named %q too", ErrDuplicateName, first, child.Name)
+			}
+			if err == nil {
+				err = checkSiblingName(tx, 0, parent, child.Name)
+			}
+			if errors.Is(err, ErrInvalidAttributes) || errors.Is(err, ErrDuplicateName) {
+				childErrors[i] = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if len(childErrors) > 0 {
+				continue // nothing is created, but the rest are still checked
+			}
+			position, err := nextPosition(tx, parent, 0)
+			if err != nil {
+				return err
+			}
+			slug, err := freeSlug(tx, models.Slugify(child.Name))
+			if err != nil {
+				return err
+			}
+			id, err := insertReturningID(tx, `INSERT INTO components (name, slug, type, status, description, metadata, parent_id, position, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				child.Name, slug, child.Type, statuses[i], child.Description, metadataArg(metadata[i]), parent, position, now, now)
+			if err != nil {
+				return fmt.Errorf("error creating child %d: %w", i, err)
+			}
+			if err := insertClosure(tx, id, parent); err != nil {
+				return err
+			}
+			if err := replaceTags(tx, id, tags[i]); err != nil {
+				return err
+			}
+			if err := refreshSearchIndex(tx, id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if len(childErrors) > 0 {
+			return childErrors
+		}
+		return nil
+	})
+	if err != nil {
+		var rejected ChildErrors
+		if errors.As(err, &rejected) {
+			return nil, rejected
+		}
+		return nil, fmt.Errorf("error creating children of component %d: %w", parentID, err)
+	}
+
+	afters := s.afterWriteMany(dbConn, ids, "create")
+	for _, id := range ids {
+		after := afters[id]
+		if after == nil {
+			after = &models.Component{ID: id, ParentID: parent}
+		}
+		events.Publish(s.componentEvent(nil, after))
+	}
+	return ids, nil
+}
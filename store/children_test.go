@@ -0,0 +1,47 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChildErrorsMessage(t *testing.T) {
+	err := ChildErrors{3: ErrInvalidTags, 0: errors.New("name is required")}
+	assert.Equal(t, "2 of the children cannot be created: child 0: name is required; child 3: invalid tags", err.Error())
+}
+
+func TestCreateChildren(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	s := &ComponentStore{}
+	parent := createTestComponent(t, "ChildrenParent", "", sql.NullInt64{})
+
+	ids, err := s.CreateChildren(parent.ID, []*models.Component{{Name: "First", Tags: []string{"critical"}}, {Name: "Second"}})
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	children, err := s.Strong().ListChildComponents(parent.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, []string{"First", "Second"}, []string{children[0].Name, children[1].Name})
+	assert.Equal(t, []string{"critical"}, children[0].Tags)
+
+	_, err = s.CreateChildren(parent.ID, []*models.Component{{Name: "Third"}, {Name: "Bad", Type: "no such type"}, {Name: ""}})
+	var rejected ChildErrors
+	require.ErrorAs(t, err, &rejected)
+	assert.Len(t, rejected, 2)
+	assert.ErrorIs(t, rejected[1], ErrInvalidType)
+	children, err = s.Strong().ListChildComponents(parent.ID)
+	require.NoError(t, err)
+	assert.Len(t, children, 2, "a rejected batch creates nothing")
+
+	_, err = s.CreateChildren(999999, []*models.Component{{Name: "Orphan"}})
+	assert.ErrorIs(t, err, ErrParentNotFound)
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// closureFromAdjacency selects the closure table as the adjacency list defines it: every
+// (ancestor, descendant) pair, each component paired with itself at depth 0. Recursion stops at
+// maxDescendantDepth ($1), and grouping keeps a single row per pair, so a parent cycle in the data
+// cannot break the primary key.
+const closureFromAdjacency = `WITH RECURSIVE closure (ancestor_id, descendant_id, depth) AS (
+	SELECT id, id, 0 FROM components WHERE deleted_at IS NULL
+	UNION ALL
+	SELECT closure.ancestor_id, c.id, closure.depth + 1
+	FROM closure JOIN components c ON c.parent_id = closure.descendant_id AND c.deleted_at IS NULL
+	WHERE closure.depth < $1
+)
+SELECT ancestor_id, descendant_id, MIN(depth) AS depth FROM closure GROUP BY ancestor_id, descendant_id`
+
+// The maintenance functions below run in the transaction of the write they follow. Subqueries on
+// component_closure are wrapped in DISTINCT derived tables, which MySQL materializes, because it
+// does not let a DELETE read the table it deletes from.
+
+// insertClosure adds a new component, which has no descendants yet, to the closure table.
+func insertClosure(tx *sql.Tx, id int64, parentID sql.NullInt64) error {
+	_, err := tx.Exec(db.Rebind("INSERT INTO component_closure (ancestor_id, descendant_id, depth) VALUES ($1, $2, 0)"), id, id)
+	if err != nil {
+		return fmt.Errorf("error adding component %d to the closure table: %w", id, err)
+	}
+	return attachClosure(tx, id, parentID)
+}
+
+// moveClosure moves id's subtree under newParentID in the closure table, once the component's
+// parent_id has been updated. Moving a component under its own descendant fails with ErrCycle. The
+// check walks parent_id rather than trusting the closure table, which writes made around the
+// service may have left stale.
+func moveClosure(tx *sql.Tx, id int64, newParentID sql.NullInt64) error {
+	move := []models.ComponentMove{{ID: id, NewParentID: newParentID}}
+	if err := checkMovesAcyclic(tx, move, map[int64]sql.NullInt64{id: newParentID}); err != nil {
+		return err
+	}
+	if err := detachClosure(tx, id); err != nil {
+		return err
+	}
+	return attachClosure(tx, id, newParentID)
+}
+
+// detachClosure removes the pairs linking id's subtree to the ancestors of id, leaving the subtree
+// as if id were a root.
+func detachClosure(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(db.Rebind(`DELETE FROM component_closure
+		WHERE descendant_id IN (SELECT descendant_id FROM (SELECT DISTINCT descendant_id FROM component_closure WHERE ancestor_id = $1) AS subtree)
+		AND ancestor_id NOT IN (SELECT descendant_id FROM (SELECT DISTINCT descendant_id FROM component_closure WHERE ancestor_id = $2) AS subtree)`),
+		id, id)
+	if err != nil {
+		return fmt.Errorf("error detaching component %d in the closure table: %w", id, err)
+	}
+	return nil
+}
+
+// attachClosure links id's subtree, detached or new, to parentID and its ancestors.
+func attachClosure(tx *sql.Tx, id int64, parentID sql.NullInt64) error {
+	if !parentID.Valid {
+		return nil
+	}
+	_, err := tx.Exec(db.Rebind(`INSERT INTO component_closure (ancestor_id, descendant_id, depth)
+		SELECT a.ancestor_id, d.descendant_id, a.depth + d.depth + 1
+		FROM component_closure a, component_closure d
+		WHERE a.descendant_id = $1 AND d.ancestor_id = $2`),
+		parentID.Int64, id)
+	if err != nil {
+		return fmt.Errorf("error attaching component %d under component %d in the closure table: %w", id, parentID.Int64, err)
+	}
+	return nil
+}
+
+// RebuildClosure replaces the closure table with the one the adjacency list defines, in a single
+// transaction, reporting one unit of work. It repairs the table after writes made around the
+// service, such as direct SQL.
+func (s *ComponentStore) RebuildClosure(ctx context.Context, report func(done, total int)) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	report(0, 1)
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM component_closure"); err != nil {
+			return fmt.Errorf("error clearing the closure table: %w", err)
+		}
+		_, err := tx.ExecContext(ctx, db.Rebind("INSERT INTO component_closure (ancestor_id, descendant_id, depth) "+closureFromAdjacency),
+			maxDescendantDepth)
+		if err != nil {
+			return fmt.Errorf("error filling the closure table: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report(1, 1)
+	return nil
+}
+
+// ClosureCheck compares the closure table with the adjacency list. Missing counts the pairs the
+// table lacks, Extra the pairs it holds that the adjacency list does not define, and WrongDepth the
+// pairs present in both at different depths.
+type ClosureCheck struct {
+	Consistent bool `json:"consistent"`
+	Missing    int  `json:"missing"`
+	Extra      int  `json:"extra"`
+	WrongDepth int  `json:"wrong_depth"`
+}
+
+// CheckClosure compares the closure table with the closure the adjacency list defines.
+func (s *ComponentStore) CheckClosure(ctx context.Context) (*ClosureCheck, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	// closureFromAdjacency is inlined as a derived table, as a CTE cannot be named in both halves.
+	expected := "(" + closureFromAdjacency + ") expected"
+	check := &ClosureCheck{}
+	queries := []struct {
+		name  string
+		query string
+		count *int
+	}{
+		{"missing", `SELECT COUNT(*) FROM ` + expected + `
+			LEFT JOIN component_closure actual ON actual.ancestor_id = expected.ancestor_id AND actual.descendant_id = expected.descendant_id
+			WHERE actual.ancestor_id IS NULL`, &check.Missing},
+		{"extra", `SELECT COUNT(*) FROM component_closure actual
+			LEFT JOIN ` + expected + ` ON expected.ancestor_id = actual.ancestor_id AND expected.descendant_id = actual.descendant_id
+			WHERE expected.ancestor_id IS NULL`, &check.Extra},
+		{"wrong depth", `SELECT COUNT(*) FROM ` + expected + `
+			JOIN component_closure actual ON actual.ancestor_id = expected.ancestor_id AND actual.descendant_id = expected.descendant_id
+			WHERE actual.depth <> expected.depth`, &check.WrongDepth},
+	}
+	for _, q := range queries {
+		if err := dbConn.QueryRowContext(ctx, db.Rebind(q.query), maxDescendantDepth).Scan(q.count); err != nil {
+			return nil, fmt.Errorf("error counting %s closure pairs: %w", q.name, err)
+		}
+	}
+	check.Consistent = check.Missing == 0 && check.Extra == 0 && check.WrongDepth == 0
+	return check, nil
+}
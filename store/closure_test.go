@@ -0,0 +1,103 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClosureMaintenance(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	assertConsistent := func(step string) {
+		check, err := testStore.CheckClosure(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, check.Consistent, "closure table after %s: %+v", step, check)
+	}
+	depth := func(ancestor, descendant int64) int {
+		var d int
+		err := db.DB.QueryRow(db.Rebind("SELECT depth FROM component_closure WHERE ancestor_id = $1 AND descendant_id = $2"),
+			ancestor, descendant).Scan(&d)
+		if err == sql.ErrNoRows {
+			return -1
+		}
+		assert.NoError(t, err)
+		return d
+	}
+
+	// root -> a -> b -> c, and other on its own.
+	root := createTestComponent(t, "ClosureRoot", "", sql.NullInt64{})
+	a := createTestComponent(t, "ClosureA", "", under(root.ID))
+	b := createTestComponent(t, "ClosureB", "", under(a.ID))
+	c := createTestComponent(t, "ClosureC", "", under(b.ID))
+	other := createTestComponent(t, "ClosureOther", "", sql.NullInt64{})
+	assertConsistent("create")
+	assert.Equal(t, 3, depth(root.ID, c.ID))
+	assert.Equal(t, 0, depth(c.ID, c.ID))
+
+	// Moving b under other takes c along.
+	assert.NoError(t, testStore.PatchComponent(b.ID, models.ComponentPatch{ParentID: &sql.NullInt64{Int64: other.ID, Valid: true}}))
+	assertConsistent("patch")
+	assert.Equal(t, -1, depth(root.ID, c.ID))
+	assert.Equal(t, 2, depth(other.ID, c.ID))
+
+	// Moving a component under its own descendant is refused.
+	err := testStore.UpdateComponent(other.ID, &models.Component{Name: "ClosureOther", ParentID: under(c.ID)})
+	assert.ErrorIs(t, err, ErrCycle)
+	assertConsistent("refused update")
+
+	// The check follows parent_id, so a stale closure table cannot let a cycle through.
+	_, err = db.DB.Exec(db.Rebind("DELETE FROM component_closure WHERE descendant_id = $1 AND depth > 0"), c.ID)
+	assert.NoError(t, err)
+	err = testStore.PatchComponent(other.ID, models.ComponentPatch{ParentID: &sql.NullInt64{Int64: c.ID, Valid: true}})
+	assert.ErrorIs(t, err, ErrCycle)
+	assert.NoError(t, testStore.RebuildClosure(context.Background(), func(int, int) {}))
+
+	assert.NoError(t, testStore.UpdateComponent(other.ID, &models.Component{Name: "ClosureOther", ParentID: under(a.ID)}))
+	assertConsistent("update")
+	assert.Equal(t, 4, depth(root.ID, c.ID))
+
+	// Swapping a and other within one batch.
+	assert.NoError(t, testStore.MoveComponents([]models.ComponentMove{
+		{ID: a.ID, NewParentID: under(other.ID)},
+		{ID: other.ID, NewParentID: under(root.ID)},
+	}))
+	assertConsistent("move")
+	assert.Equal(t, 1, depth(other.ID, a.ID))
+
+	// Deleting other leaves a's subtree as a tree of its own.
+	assert.NoError(t, testStore.DeleteComponent(other.ID))
+	assertConsistent("delete")
+	assert.Equal(t, -1, depth(root.ID, a.ID))
+	assert.Equal(t, 3, depth(a.ID, c.ID))
+}
+
+func TestRebuildClosure(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	root := createTestComponent(t, "RebuildRoot", "", sql.NullInt64{})
+	createTestComponent(t, "RebuildChild", "", sql.NullInt64{Int64: root.ID, Valid: true})
+
+	// A write made around the service leaves the table stale.
+	_, err := db.DB.Exec(db.Rebind("DELETE FROM component_closure WHERE ancestor_id = $1 AND depth > 0"), root.ID)
+	assert.NoError(t, err)
+	check, err := testStore.CheckClosure(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, ClosureCheck{Missing: 1}, *check)
+
+	var progress []int
+	assert.NoError(t, testStore.RebuildClosure(context.Background(), func(done, total int) { progress = append(progress, done) }))
+	assert.Equal(t, []int{0, 1}, progress)
+	check, err = testStore.CheckClosure(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, check.Consistent)
+}
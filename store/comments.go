@@ -0,0 +1,145 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidComment is returned by CreateComment for an author or body CleanComment rejects.
+var ErrInvalidComment = errors.New("invalid comment")
+
+// ErrCommentNotPermitted is returned by DeleteComment when canDelete refuses the comment.
+var ErrCommentNotPermitted = errors.New("comment deletion not permitted")
+
+const commentColumns = "id, component_id, author, body, created_at"
+
+func scanComment(row interface{ Scan(...interface{}) error }) (*models.Comment, error) {
+	comment := &models.Comment{}
+	var createdAt time.Time
+	if err := row.Scan(&comment.ID, &comment.ComponentID, &comment.Author, &comment.Body, &createdAt); err != nil {
+		return nil, err
+	}
+	comment.CreatedAt = createdAt.Format(time.RFC3339)
+	return comment, nil
+}
+
+// CreateComment adds a comment by author to a live component.
+func (s *ComponentStore) CreateComment(componentID int64, author, body string) (*models.Comment, error) {
+	author, body, err := models.CleanComment(author, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidComment, err)
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	comment := &models.Comment{ComponentID: componentID, Author: author, Body: body}
+	createdAt := time.Now().UTC()
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, err := lockComponentParent(tx, componentID)
+		if err != nil || before == nil {
+			found = false
+			return err
+		}
+		found = true
+		comment.ID, err = insertReturningID(tx,
+			"INSERT INTO component_comments (component_id, author, body, created_at) VALUES ($1, $2, $3, $4)",
+			componentID, author, body, createdAt)
+		if err != nil {
+			return fmt.Errorf("error creating comment on component %d: %w", componentID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("component with ID %d not found", componentID)
+	}
+	comment.CreatedAt = createdAt.Format(time.RFC3339)
+	return comment, nil
+}
+
+// ListComments returns a page of a live component's comments, oldest first, and how many it has
+// in all. A limit of 0 returns them all.
+func (s *ComponentStore) ListComments(componentID int64, limit, offset int) ([]*models.Comment, int, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, 0, err
+	}
+	if found, err := componentExists(dbConn, componentID); err != nil {
+		return nil, 0, err
+	} else if !found {
+		return nil, 0, fmt.Errorf("component with ID %d not found", componentID)
+	}
+	var total int
+	if err := dbConn.QueryRow(db.Rebind("SELECT COUNT(*) FROM component_comments WHERE component_id = $1"), componentID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting comments of component %d: %w", componentID, err)
+	}
+
+	query := "SELECT " + commentColumns + " FROM component_comments WHERE component_id = $1 ORDER BY created_at, id"
+	args := []interface{}{componentID}
+	if limit > 0 {
+		query += " LIMIT $2 OFFSET $3"
+		args = append(args, limit, offset)
+	}
+	rows, err := dbConn.Query(db.Rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing comments of component %d: %w", componentID, err)
+	}
+	defer rows.Close()
+	list := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning comment: %w", err)
+		}
+		list = append(list, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating comments: %w", err)
+	}
+	return list, total, nil
+}
+
+// DeleteComment removes a comment from a live component. canDelete, when not nil, is asked for
+// the comment first, and refusing fails with ErrCommentNotPermitted.
+func (s *ComponentStore) DeleteComment(componentID, commentID int64, canDelete func(*models.Comment) bool) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		comment, err := scanComment(tx.QueryRow(db.Rebind(`SELECT m.id, m.component_id, m.author, m.body, m.created_at
+			FROM component_comments m JOIN components c ON c.id = m.component_id
+			WHERE m.id = $1 AND m.component_id = $2 AND c.deleted_at IS NULL`), commentID, componentID))
+		if err == sql.ErrNoRows {
+			found = false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading comment %d of component %d: %w", commentID, componentID, err)
+		}
+		found = true
+		if canDelete != nil && !canDelete(comment) {
+			return fmt.Errorf("%w: comment %d of component %d was written by %q", ErrCommentNotPermitted, commentID, componentID, comment.Author)
+		}
+		if _, err := tx.Exec(db.Rebind("DELETE FROM component_comments WHERE id = $1"), commentID); err != nil {
+			return fmt.Errorf("error deleting comment %d of component %d: %w", commentID, componentID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("comment %d of component %d not found", commentID, componentID)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComments(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	comp := createTestComponent(t, "Pump", "", sql.NullInt64{Valid: false})
+
+	first, err := testStore.CreateComment(comp.ID, " alice ", "Seal leaks at 6 bar.")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", first.Author)
+	second, err := testStore.CreateComment(comp.ID, "bob", "Replaced it.")
+	require.NoError(t, err)
+	_, err = testStore.CreateComment(comp.ID, "alice", "   ")
+	assert.ErrorIs(t, err, ErrInvalidComment)
+	_, err = testStore.CreateComment(88888, "alice", "Hello")
+	assert.ErrorContains(t, err, "not found")
+
+	list, total, err := testStore.ListComments(comp.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, list, 2)
+	assert.Equal(t, first.ID, list[0].ID, "Expected the oldest comment first")
+	list, total, err = testStore.ListComments(comp.ID, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, list, 1)
+	assert.Equal(t, second.ID, list[0].ID)
+
+	onlyAlice := func(comment *models.Comment) bool { return comment.Author == "alice" }
+	assert.ErrorIs(t, testStore.DeleteComment(comp.ID, second.ID, onlyAlice), ErrCommentNotPermitted)
+	require.NoError(t, testStore.DeleteComment(comp.ID, first.ID, onlyAlice))
+	assert.ErrorContains(t, testStore.DeleteComment(comp.ID, first.ID, nil), "not found")
+
+	// Soft delete hides the comments; a purge removes them
+	require.NoError(t, testStore.DeleteComponent(comp.ID))
+	_, _, err = testStore.ListComments(comp.ID, 0, 0)
+	assert.ErrorContains(t, err, "not found")
+	require.NoError(t, testStore.PurgeComponent(comp.ID))
+	var remaining int
+	require.NoError(t, db.DB.QueryRow("SELECT COUNT(*) FROM component_comments").Scan(&remaining))
+	assert.Zero(t, remaining)
+}
@@ -3,67 +3,228 @@ package store
 import (
 	"component-service/cache"
 	"component-service/db"
+	"component-service/events"
 	"component-service/models"
+	"component-service/tracing"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
-// ComponentStoreInterface defines the methods that the cache will use to interact with the component store.
-type ComponentStoreInterface interface {
-	ListComponents() ([]*models.Component, error)
+// ComponentStore handles database operations for components.
+type ComponentStore struct {
+	strong bool             // reads bypass the cache
+	trace  *tracing.Context // the request writes are made for, copied onto their events
 }
 
-// ComponentStore handles database operations for components.
-type ComponentStore struct{}
+// Strong returns a store whose reads go to the database even when the cache is initialized, for
+// flows that must read their own writes from the system of record. Writes still update the cache.
+func (s *ComponentStore) Strong() *ComponentStore {
+	return &ComponentStore{strong: true, trace: s.trace}
+}
+
+// WithTrace returns a store whose writes publish events traced to the request t identifies.
+func (s *ComponentStore) WithTrace(t *tracing.Context) *ComponentStore {
+	return &ComponentStore{strong: s.strong, trace: t}
+}
+
+// cached returns the cache reads may be served from, or nil when they must go to the database,
+// counting the read as a cache bypass.
+func (s *ComponentStore) cached() *cache.ComponentCache {
+	if s.strong {
+		cache.RecordBypass(cache.ReasonStrongConsistency)
+		return nil
+	}
+	if cache.GlobalComponentCache == nil {
+		cache.RecordBypass(cache.ReasonNotInitialized)
+	}
+	return cache.GlobalComponentCache
+}
 
-// CreateComponent adds a new component to the database and updates the cache.
+// ErrParentNotFound is returned when a component is created under a parent that does not exist.
+var ErrParentNotFound = errors.New("parent component not found")
+
+// CreateComponent adds a new component to the database, updates the cache and publishes a
+// created event. The component's slug is derived from its name, numbered when already taken, and
+// set on component in place of any given. A parent that does not exist fails with
+// ErrParentNotFound, and a name a sibling has, while UniqueSiblingNames is on, with
+// ErrDuplicateName. A type ComponentTypes does not allow fails with ErrInvalidType, malformed
+// tags with ErrInvalidTags, metadata that is not a JSON object with ErrInvalidMetadata and metadata
+// not conforming to the attribute schema of the type with ErrInvalidAttributes; the tags and
+// metadata are set on component normalized. The component starts in the initial status of
+// ComponentStatuses unless it gives another; one the lifecycle lacks fails with ErrInvalidStatus.
 func (s *ComponentStore) CreateComponent(component *models.Component) (int64, error) {
-	dbConn := db.GetDB()
-	query := `INSERT INTO components (name, description, parent_id, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	var parentID sql.NullInt64
-	if component.ParentID.Valid && component.ParentID.Int64 != 0 {
-		parentID = component.ParentID
+	if err := checkType(component.Type); err != nil {
+		return 0, err
+	}
+	status, err := checkStatus(component.Status)
+	if err != nil {
+		return 0, err
+	}
+	tags, err := checkTags(component.Tags)
+	if err != nil {
+		return 0, err
+	}
+	metadata, err := checkMetadata(component.Metadata)
+	if err != nil {
+		return 0, err
 	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	query := `INSERT INTO components (name, slug, type, status, description, metadata, parent_id, position, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	parentID := normalizeParentID(component.ParentID)
 	var id int64
-	err := dbConn.QueryRow(
-		query,
-		component.Name,
-		component.Description,
-		parentID,
-		time.Now(),
-		time.Now(),
-	).Scan(&id)
+	var slug string
+	err = executeTxClaimingSlugs(dbConn, func(tx *sql.Tx) error {
+		var txErr error
+		if parentID.Valid {
+			// Checked here rather than left to the foreign key, whose error differs per driver.
+			var exists int
+			txErr = tx.QueryRow(db.Rebind("SELECT COUNT(*) FROM components WHERE id = $1 AND deleted_at IS NULL"), parentID.Int64).Scan(&exists)
+			if txErr != nil {
+				return fmt.Errorf("error reading parent component %d: %w", parentID.Int64, txErr)
+			}
+			if exists == 0 {
+				return fmt.Errorf("%w: component with ID %d does not exist", ErrParentNotFound, parentID.Int64)
+			}
+		}
+		if txErr = checkAttributes(tx, component.Type, metadata); txErr != nil {
+			return txErr
+		}
+		// A unique violation here runs fn again, which then finds the sibling that won the race
+		if txErr = checkSiblingName(tx, 0, parentID, component.Name); txErr != nil {
+			return txErr
+		}
+		position, txErr := nextPosition(tx, parentID, 0) // new components come after their siblings
+		if txErr != nil {
+			return txErr
+		}
+		slug, txErr = freeSlug(tx, models.Slugify(component.Name))
+		if txErr != nil {
+			return txErr
+		}
+		id, txErr = insertReturningID(
+			tx,
+			query,
+			component.Name,
+			slug,
+			component.Type,
+			status,
+			component.Description,
+			metadataArg(metadata),
+			parentID,
+			position,
+			time.Now(),
+			time.Now(),
+		)
+		if txErr != nil {
+			return txErr
+		}
+		if txErr = insertClosure(tx, id, parentID); txErr != nil {
+			return txErr
+		}
+		if txErr = replaceTags(tx, id, tags); txErr != nil {
+			return txErr
+		}
+		return refreshSearchIndex(tx, id)
+	})
 
 	if err != nil {
 		return 0, fmt.Errorf("error creating component: %w", err)
 	}
+	component.Slug = slug
+	component.Status = status
+	component.Version = 1
+	component.Tags = tags
+	component.Metadata = metadata
 
-	if cache.GlobalComponentCache != nil {
-		createdComponent := &models.Component{}
-		var createdAt, updatedAt time.Time
-		// Direct DB query to get the component as it was created, including DB-set fields
-		errScan := dbConn.QueryRow("SELECT id, name, description, parent_id, created_at, updated_at FROM components WHERE id = $1", id).Scan(
-			&createdComponent.ID, &createdComponent.Name, &createdComponent.Description, &createdComponent.ParentID, &createdAt, &updatedAt,
-		)
-		if errScan == nil {
-			createdComponent.CreatedAt = createdAt.Format(time.RFC3339)
-			createdComponent.UpdatedAt = updatedAt.Format(time.RFC3339)
-			cache.GlobalComponentCache.Set(createdComponent)
-		} else {
-			// Log error: failed to fetch created component for cache update. Non-fatal for the create operation itself.
-			fmt.Printf("Error fetching component %d for cache update after create: %v\n", id, errScan)
-		}
+	after := s.afterWrite(dbConn, id, "create")
+	if after == nil {
+		after = &models.Component{ID: id, Status: status, ParentID: parentID, Version: 1, Tags: tags, Metadata: metadata}
 	}
+	events.Publish(s.componentEvent(nil, after))
 	return id, nil
 }
 
+// afterWrite reads a component as committed, including DB-set fields, and stores it in the
+// cache, or invalidates it under write-around. The read is skipped when neither the cache nor an
+// event subscriber needs it. Failing to read is logged and returns nil; it does not fail the
+// write itself.
+func (s *ComponentStore) afterWrite(dbConn *sql.DB, id int64, operation string) *models.Component {
+	writeThrough := cacheWritesThrough(id)
+	if !writeThrough && !events.HasSubscribers() {
+		return nil
+	}
+	component := &models.Component{}
+	var createdAt, updatedAt time.Time
+	errScan := dbConn.QueryRow(db.Rebind("SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE id = $1"), id).Scan(
+		&component.ID, &component.Name, &component.Slug, &component.Type, &component.Status, &component.Description, (*[]byte)(&component.Metadata), &component.ParentID, &component.Position, &component.Version, &createdAt, &updatedAt,
+	)
+	if errScan != nil {
+		fmt.Printf("Error fetching component %d for cache update after %s: %v\n", id, operation, errScan)
+		return nil
+	}
+	component.CreatedAt = createdAt.Format(time.RFC3339)
+	component.UpdatedAt = updatedAt.Format(time.RFC3339)
+	if err := attachTags(dbConn, []*models.Component{component}); err != nil {
+		fmt.Printf("Error fetching tags of component %d for cache update after %s: %v\n", id, operation, err)
+		return nil
+	}
+	if writeThrough {
+		cache.GlobalComponentCache.Set(component)
+	}
+	return component
+}
+
+// cacheWritesThrough reports whether written components go into the cache directly. Under
+// write-around it invalidates them instead, for the next read to reload, and returns false, as it
+// does without a cache.
+func cacheWritesThrough(ids ...int64) bool {
+	if cache.GlobalComponentCache == nil {
+		return false
+	}
+	if cache.GlobalComponentCache.WritePolicy() == cache.WriteAround {
+		cache.GlobalComponentCache.Invalidate(ids...)
+		return false
+	}
+	return true
+}
+
+// sqlExecutor is the subset of *sql.DB and *sql.Tx used by store helpers, so the same helper
+// can run standalone or inside db.ExecuteTx.
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertReturningID executes an INSERT written without a RETURNING clause and returns the new row's ID.
+// It appends RETURNING id where the dialect supports it and falls back to LastInsertId otherwise.
+func insertReturningID(exec sqlExecutor, query string, args ...interface{}) (int64, error) {
+	if db.CurrentDialect.SupportsReturning() {
+		var id int64
+		err := exec.QueryRow(db.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	result, err := exec.Exec(db.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
 // GetComponentByID retrieves a component by its ID.
 // It checks the global cache first if initialized.
 func (s *ComponentStore) GetComponentByID(id int64) (*models.Component, error) {
-	if cache.GlobalComponentCache != nil {
-		if component, found := cache.GlobalComponentCache.GetByID(id); found {
+	if c := s.cached(); c != nil {
+		if component, found := c.GetByID(id); found {
 			return component, nil
 		}
 		// If cache is initialized and component is not found, it means it does not exist according to the cache.
@@ -71,17 +232,26 @@ func (s *ComponentStore) GetComponentByID(id int64) (*models.Component, error) {
 	}
 
 	// Fallback to database if cache is not initialized
-	dbConn := db.GetDB()
-	query := "SELECT id, name, description, parent_id, created_at, updated_at FROM components WHERE id = $1"
-	row := dbConn.QueryRow(query, id)
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE id = $1 AND deleted_at IS NULL"
+	row := dbConn.QueryRow(db.Rebind(query), id)
 	component := &models.Component{}
 	var createdAtDb, updatedAtDb time.Time
 
-	err := row.Scan(
+	err = row.Scan(
 		&component.ID,
 		&component.Name,
+		&component.Slug,
+		&component.Type,
+		&component.Status,
 		&component.Description,
+		(*[]byte)(&component.Metadata),
 		&component.ParentID,
+		&component.Position,
+		&component.Version,
 		&createdAtDb,
 		&updatedAtDb,
 	)
@@ -93,89 +263,444 @@ func (s *ComponentStore) GetComponentByID(id int64) (*models.Component, error) {
 	}
 	component.CreatedAt = createdAtDb.Format(time.RFC3339)
 	component.UpdatedAt = updatedAtDb.Format(time.RFC3339)
+	if component.Tags, err = tagsOf(dbConn, id); err != nil {
+		return nil, err
+	}
 	return component, nil
 }
 
-// UpdateComponent updates an existing component in the database and invalidates cache.
+// UpdateComponent updates an existing component in the database, refreshes the cache and
+// publishes an updated event, or a moved event carrying both parents when the parent changed.
+// While UniqueSiblingNames is on, a name a sibling under the new parent has fails with
+// ErrDuplicateName. Types, tags and metadata are checked as on create, and the tags and metadata
+// replace the current ones. The status is left unchanged; see SetComponentStatus. A component
+// giving a Version is updated only at that version, and otherwise fails with ErrVersionConflict.
 func (s *ComponentStore) UpdateComponent(id int64, component *models.Component) error {
-	dbConn := db.GetDB()
-	query := "UPDATE components SET name = $1, description = $2, parent_id = $3, updated_at = $4 WHERE id = $5"
-	var parentID sql.NullInt64
-	if component.ParentID.Valid && component.ParentID.Int64 != 0 {
-		parentID = component.ParentID
+	if err := checkType(component.Type); err != nil {
+		return err
+	}
+	tags, err := checkTags(component.Tags)
+	if err != nil {
+		return err
+	}
+	metadata, err := checkMetadata(component.Metadata)
+	if err != nil {
+		return err
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
 	}
+	query := "UPDATE components SET name = $1, type = $2, description = $3, metadata = $4, parent_id = $5, updated_at = $6, version = version + 1 WHERE id = $7"
+	parentID := normalizeParentID(component.ParentID)
 
-	result, err := dbConn.Exec(
-		query,
-		component.Name,
-		component.Description,
-		parentID,
-		time.Now(), // Set UpdatedAt
-		id,
-	)
+	var before *models.Component
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		var err error
+		before, err = lockComponentParent(tx, id)
+		if err != nil || before == nil {
+			return err
+		}
+		if err := checkVersion(before, component.Version); err != nil {
+			return err
+		}
+		if err := checkSiblingName(tx, id, parentID, component.Name); err != nil {
+			return err
+		}
+		if err := checkAttributes(tx, component.Type, metadata); err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			db.Rebind(query),
+			component.Name,
+			component.Type,
+			component.Description,
+			metadataArg(metadata),
+			parentID,
+			time.Now(), // Set UpdatedAt
+			id,
+		)
+		if err != nil {
+			return fmt.Errorf("error updating component with ID %d: %w", id, err)
+		}
+		if parentID != normalizeParentID(before.ParentID) {
+			if err := moveClosure(tx, id, parentID); err != nil {
+				return err
+			}
+			if err := appendPosition(tx, id, parentID); err != nil {
+				return err
+			}
+		}
+		if err := replaceTags(tx, id, tags); err != nil {
+			return err
+		}
+		return refreshSearchIndex(tx, id)
+	})
 	if err != nil {
-		return fmt.Errorf("error updating component with ID %d: %w", id, err)
+		return asDuplicateName(err, parentID, component.Name)
+	}
+	if before == nil {
+		return fmt.Errorf("component with ID %d not found for update", id)
+	}
+
+	after := s.afterWrite(dbConn, id, "update")
+	if after == nil {
+		after = &models.Component{ID: id, Name: component.Name, Type: component.Type, Tags: tags, Description: component.Description, Metadata: metadata, ParentID: parentID}
 	}
-	rowsAffected, err := result.RowsAffected()
+	events.Publish(s.componentEvent(before, after))
+	return nil
+}
+
+// PatchComponent updates only the fields set in patch, building the UPDATE from them, then
+// refreshes the cache and publishes an updated or moved event like UpdateComponent. Names are
+// checked as UpdateComponent checks them, when the patch sets the name or the parent, and so is a
+// type the patch sets. Tags the patch changes are read and replaced in the same transaction, and
+// checked once patched, so removing tags can make room for others. Metadata the patch sets is
+// checked as on create, and a patch setting the type or the metadata checks the two against the
+// attribute schema once patched. A patch giving a Version applies only at that version, as for
+// UpdateComponent.
+func (s *ComponentStore) PatchComponent(id int64, patch models.ComponentPatch) error {
+	if patch.Type != nil {
+		if err := checkType(*patch.Type); err != nil {
+			return err
+		}
+	}
+	var metadata json.RawMessage
+	if patch.Metadata != nil {
+		var err error
+		if metadata, err = checkMetadata(*patch.Metadata); err != nil {
+			return err
+		}
+	}
+	dbConn, err := db.GetDB()
 	if err != nil {
-		return fmt.Errorf("error getting rows affected for update on component ID %d: %w", id, err)
+		return err
+	}
+	var assignments []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if patch.Name != nil {
+		set("name", *patch.Name)
+	}
+	if patch.Type != nil {
+		set("type", *patch.Type)
+	}
+	if patch.Description != nil {
+		set("description", *patch.Description)
+	}
+	if patch.Metadata != nil {
+		set("metadata", metadataArg(metadata))
 	}
-	if rowsAffected == 0 {
+	if patch.ParentID != nil {
+		set("parent_id", normalizeParentID(*patch.ParentID))
+	}
+	set("updated_at", time.Now())
+	assignments = append(assignments, "version = version + 1")
+	args = append(args, id)
+	query := "UPDATE components SET " + strings.Join(assignments, ", ") + fmt.Sprintf(" WHERE id = $%d", len(args))
+
+	var before *models.Component
+	var name string // the name and parent checked against the siblings, if any
+	var parentID sql.NullInt64
+	var tags []string // the patched tags, if the patch changes them
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		var err error
+		before, err = lockComponentParent(tx, id)
+		if err != nil || before == nil {
+			return err
+		}
+		if err := checkVersion(before, patch.Version); err != nil {
+			return err
+		}
+		if UniqueSiblingNames && (patch.Name != nil || patch.ParentID != nil) {
+			if name, err = patchedName(tx, id, patch); err != nil {
+				return err
+			}
+			parentID = normalizeParentID(before.ParentID)
+			if patch.ParentID != nil {
+				parentID = normalizeParentID(*patch.ParentID)
+			}
+			if err := checkSiblingName(tx, id, parentID, name); err != nil {
+				return err
+			}
+		}
+		if err := checkPatchedAttributes(tx, id, patch, metadata); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(db.Rebind(query), args...); err != nil {
+			return fmt.Errorf("error patching component with ID %d: %w", id, err)
+		}
+		if patch.ParentID != nil && normalizeParentID(*patch.ParentID) != normalizeParentID(before.ParentID) {
+			if err := moveClosure(tx, id, normalizeParentID(*patch.ParentID)); err != nil {
+				return err
+			}
+			if err := appendPosition(tx, id, normalizeParentID(*patch.ParentID)); err != nil {
+				return err
+			}
+		}
+		if patch.ChangesTags() {
+			current, err := tagsOf(tx, id)
+			if err != nil {
+				return err
+			}
+			if tags, err = checkTags(patch.PatchedTags(current)); err != nil {
+				return err
+			}
+			if err := replaceTags(tx, id, tags); err != nil {
+				return err
+			}
+		}
+		if patch.Name == nil && patch.Description == nil {
+			return nil // the indexed text is unchanged
+		}
+		return refreshSearchIndex(tx, id)
+	})
+	if err != nil {
+		return asDuplicateName(err, parentID, name)
+	}
+	if before == nil {
 		return fmt.Errorf("component with ID %d not found for update", id)
 	}
 
-	if cache.GlobalComponentCache != nil {
-		updatedComponent := &models.Component{}
-		var createdAt, updatedAt time.Time
-		// Direct DB query to get the updated component, including new UpdatedAt
-		errScan := dbConn.QueryRow("SELECT id, name, description, parent_id, created_at, updated_at FROM components WHERE id = $1", id).Scan(
-			&updatedComponent.ID, &updatedComponent.Name, &updatedComponent.Description, &updatedComponent.ParentID, &createdAt, &updatedAt,
-		)
-		if errScan == nil {
-			updatedComponent.CreatedAt = createdAt.Format(time.RFC3339)
-			updatedComponent.UpdatedAt = updatedAt.Format(time.RFC3339)
-			cache.GlobalComponentCache.Set(updatedComponent)
-		} else {
-			// Log error: failed to fetch updated component for cache update. Non-fatal.
-			fmt.Printf("Error fetching component %d for cache update after update: %v\n", id, errScan)
+	after := s.afterWrite(dbConn, id, "patch")
+	if after == nil {
+		after = &models.Component{ID: id, ParentID: before.ParentID}
+		if patch.Name != nil {
+			after.Name = *patch.Name
+		}
+		if patch.Type != nil {
+			after.Type = *patch.Type
+		}
+		if patch.Description != nil {
+			after.Description = *patch.Description
 		}
+		if patch.Metadata != nil {
+			after.Metadata = metadata
+		}
+		if patch.ParentID != nil {
+			after.ParentID = normalizeParentID(*patch.ParentID)
+		}
+		after.Tags = tags
 	}
+	events.Publish(s.componentEvent(before, after))
 	return nil
 }
 
-// DeleteComponent removes a component from the database and invalidates cache.
+// DeleteComponent soft-deletes a component: it is stamped with deleted_at and leaves every read,
+// the cache and the closure table, but keeps its row, so RestoreComponent can bring it back.
+// Otherwise it behaves like PurgeComponent on a live component.
 func (s *ComponentStore) DeleteComponent(id int64) error {
-	dbConn := db.GetDB()
-	query := "DELETE FROM components WHERE id = $1"
-	result, err := dbConn.Exec(query, id)
+	return s.deleteComponent(id, false)
+}
+
+// PurgeComponent removes a component's row for good, live or soft-deleted, along with its ACL
+// entries, share links, visibility flag and attachments, whose data leaves storage once the row
+// is gone. Purging a live component updates the cache and publishes a deleted event. Its direct
+// children become roots (ON DELETE SET NULL), and each of them gets a moved event. A soft-deleted
+// component was already removed from both.
+func (s *ComponentStore) PurgeComponent(id int64) error {
+	return s.deleteComponent(id, true)
+}
+
+// ErrDeleteNotPermitted is returned when canDelete refuses a component of a subtree DeleteSubtree
+// would delete.
+var ErrDeleteNotPermitted = errors.New("delete not permitted")
+
+// DeleteSubtree soft-deletes a component and every component below it in one transaction, so
+// nothing becomes a root. The deleted components keep their parent_id, so restoring them from
+// the top down rebuilds the subtree. canDelete, when not nil, is asked for each component, and
+// refusing any fails with ErrDeleteNotPermitted, deleting none. The cache drops the whole
+// subtree, and each component gets a deleted event, parents before their children.
+func (s *ComponentStore) DeleteSubtree(id int64, canDelete func(id int64) bool) error {
+	dbConn, err := db.GetDB()
 	if err != nil {
-		return fmt.Errorf("error deleting component with ID %d: %w", id, err)
+		return err
 	}
-	rowsAffected, err := result.RowsAffected()
+	var removed []*models.Component
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		removed = nil // fn may run again when the transaction is retried
+		before, err := lockComponentParent(tx, id)
+		if err != nil || before == nil {
+			return err
+		}
+		rows, err := tx.Query(db.Rebind(`SELECT c.id, c.parent_id FROM component_closure cl JOIN components c ON c.id = cl.descendant_id
+			WHERE cl.ancestor_id = $1 AND c.deleted_at IS NULL ORDER BY cl.depth, c.id FOR UPDATE`), id)
+		if err != nil {
+			return fmt.Errorf("error listing the subtree of component %d: %w", id, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			component := &models.Component{}
+			if err := rows.Scan(&component.ID, &component.ParentID); err != nil {
+				return fmt.Errorf("error scanning subtree row: %w", err)
+			}
+			removed = append(removed, component)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error listing the subtree of component %d: %w", id, err)
+		}
+		if len(removed) == 0 { // the closure table lacks the component itself; see CheckClosure
+			return fmt.Errorf("component %d is missing from the closure table", id)
+		}
+		for _, component := range removed {
+			if canDelete != nil && !canDelete(component.ID) {
+				return fmt.Errorf("%w: deleting the subtree of component %d requires write permission on component %d", ErrDeleteNotPermitted, id, component.ID)
+			}
+		}
+
+		_, err = tx.Exec(db.Rebind(`UPDATE components SET deleted_at = $1
+			WHERE id IN (SELECT descendant_id FROM component_closure WHERE ancestor_id = $2) AND deleted_at IS NULL`), time.Now(), id)
+		if err != nil {
+			return fmt.Errorf("error deleting the subtree of component %d: %w", id, err)
+		}
+		_, err = tx.Exec(db.Rebind(`DELETE FROM component_closure
+			WHERE descendant_id IN (SELECT descendant_id FROM (SELECT descendant_id FROM component_closure WHERE ancestor_id = $1) AS subtree)`), id)
+		if err != nil {
+			return fmt.Errorf("error removing the subtree of component %d from the closure table: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("component with ID %d not found for deletion", id)
+	}
+
+	ids := make([]int64, len(removed))
+	for i, component := range removed {
+		ids[i] = component.ID
+	}
+	if cacheWritesThrough(ids...) {
+		cache.GlobalComponentCache.DeleteSubtree(id)
+	}
+	for _, component := range removed {
+		events.Publish(s.componentEvent(component, nil))
+	}
+	return nil
+}
+
+func (s *ComponentStore) deleteComponent(id int64, purge bool) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var before *models.Component
+	var orphanIDs []int64
+	var purgedDeleted bool
+	var purgedKeys []string
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		purgedDeleted, purgedKeys = false, nil // fn may run again when the transaction is retried
+		var err error
+		before, err = lockComponentParent(tx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			if !purge {
+				return nil
+			}
+			purgedDeleted, err = lockDeletedComponent(tx, id)
+			if err != nil || !purgedDeleted {
+				return err
+			}
+			purgedKeys, err = purgeRow(tx, id)
+			return err
+		}
+		orphanIDs, err = childIDs(tx, id)
+		if err != nil {
+			return err
+		}
+		// The subtree leaves its ancestors' closure; the component's own pairs go with its row, or
+		// softDeleteRow removes them.
+		if err := detachClosure(tx, id); err != nil {
+			return err
+		}
+		if purge {
+			purgedKeys, err = purgeRow(tx, id)
+			return err
+		}
+		return softDeleteRow(tx, id)
+	})
 	if err != nil {
-		return fmt.Errorf("error getting rows affected for delete on component ID %d: %w", id, err)
+		return err
+	}
+	deleteAttachmentData(purgedKeys)
+	if purgedDeleted {
+		return nil
 	}
-	if rowsAffected == 0 {
+	if before == nil {
 		return fmt.Errorf("component with ID %d not found for deletion", id)
 	}
 
-	if cache.GlobalComponentCache != nil {
+	// The orphans lost their parent too, so write-around reloads them along with the deletion.
+	if cacheWritesThrough(append([]int64{id}, orphanIDs...)...) {
 		cache.GlobalComponentCache.Delete(id)
 	}
+	events.Publish(s.componentEvent(before, nil))
+	for _, orphanID := range orphanIDs {
+		e := s.componentEvent(&models.Component{ID: orphanID, ParentID: sql.NullInt64{Int64: id, Valid: true}}, &models.Component{ID: orphanID})
+		e.Component = nil // the rest of the row was not read; the cache has it when enabled
+		if cache.GlobalComponentCache != nil {
+			if orphan, found := cache.GlobalComponentCache.GetByID(orphanID); found {
+				e.Component = orphan
+			}
+		}
+		events.Publish(e)
+	}
 	return nil
 }
 
+// lockComponentParent reads a component's parent and version inside tx, locking the row until the
+// transaction ends so the state an event reports as "before" cannot change underneath the write.
+// It returns nil when the component does not exist or is soft-deleted.
+func lockComponentParent(tx *sql.Tx, id int64) (*models.Component, error) {
+	before := &models.Component{ID: id}
+	err := tx.QueryRow(db.Rebind("SELECT parent_id, version FROM components WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"), id).Scan(&before.ParentID, &before.Version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading component with ID %d: %w", id, err)
+	}
+	return before, nil
+}
+
+// childIDs returns the IDs of a component's direct children inside tx.
+func childIDs(tx *sql.Tx, parentID int64) ([]int64, error) {
+	rows, err := tx.Query(db.Rebind("SELECT id FROM components WHERE parent_id = $1 AND deleted_at IS NULL"), parentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing children of component %d: %w", parentID, err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning child of component %d: %w", parentID, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // ListComponents retrieves all components.
 // It uses the cache if initialized.
 func (s *ComponentStore) ListComponents() ([]*models.Component, error) {
-	if cache.GlobalComponentCache != nil {
-		return cache.GlobalComponentCache.GetAll(), nil
+	if c := s.cached(); c != nil {
+		return c.GetAll(), nil
 	}
 
 	// Fallback to database if cache is not initialized
-	dbConn := db.GetDB()
-	query := "SELECT id, name, description, parent_id, created_at, updated_at FROM components ORDER BY created_at DESC"
-	rows, err := dbConn.Query(query)
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	query, args := selectFrom(tableComponents, componentColumns...).where(notDeleted).orderBy(newestFirst...).build()
+	rows, err := dbConn.Query(db.Rebind(query), args...)
 	if err != nil {
 		return nil, fmt.Errorf("error listing components: %w", err)
 	}
@@ -187,8 +712,14 @@ func (s *ComponentStore) ListComponents() ([]*models.Component, error) {
 		err_scan := rows.Scan(
 			&component_model.ID,
 			&component_model.Name,
+			&component_model.Slug,
+			&component_model.Type,
+			&component_model.Status,
 			&component_model.Description,
+			(*[]byte)(&component_model.Metadata),
 			&component_model.ParentID,
+			&component_model.Position,
+			&component_model.Version,
 			&createdAtDb,
 			&updatedAtDb,
 		)
@@ -202,21 +733,174 @@ func (s *ComponentStore) ListComponents() ([]*models.Component, error) {
 	if err_rows := rows.Err(); err_rows != nil {
 		return nil, fmt.Errorf("error iterating component rows: %w", err_rows)
 	}
+	rows.Close()
+	tags, err := allTags(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range components {
+		component.Tags = tags[component.ID]
+	}
 	return components, nil
 }
 
+// ListComponentsJSON returns the components selected by filter as a JSON array, in order. With
+// the cache initialized the array is assembled from pre-marshaled fragments, avoiding a marshal per
+// component on hot list paths; otherwise the filter and order are applied in SQL. limit > 0
+// returns only the page of at most limit components starting at offset; otherwise every
+// component from offset onwards is returned.
+func (s *ComponentStore) ListComponentsJSON(filter cache.Filter, order cache.Sort, offset, limit int) ([]byte, error) {
+	if c := s.cached(); c != nil {
+		return c.AllJSON(filter, order, offset, limit)
+	}
+
+	var components []*models.Component
+	if limit > 0 || !filter.IsZero() || !order.IsZero() {
+		query, args := selectFrom(tableComponents, componentColumns...).
+			where(filterPredicates(filter)...).where(notDeleted).
+			orderBy(sortKeys(order, newestFirst)...).page(offset, limit).build()
+		dbConn, err := db.GetDB()
+		if err != nil {
+			return nil, err
+		}
+		rows, err := dbConn.Query(db.Rebind(query), args...)
+		if err != nil {
+			return nil, fmt.Errorf("error listing components: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			component, err := scanComponentRow(rows)
+			if err != nil {
+				return nil, fmt.Errorf("error scanning component row: %w", err)
+			}
+			components = append(components, component)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating component rows: %w", err)
+		}
+		rows.Close()
+		if err := attachTags(dbConn, components); err != nil {
+			return nil, err
+		}
+		if limit == 0 {
+			components = pageFrom(components, offset)
+		}
+	} else {
+		all, err := s.ListComponents()
+		if err != nil {
+			return nil, err
+		}
+		components = pageFrom(all, offset)
+	}
+	if components == nil {
+		components = []*models.Component{}
+	}
+	return json.Marshal(components)
+}
+
+// pageFrom drops the first offset components.
+func pageFrom(components []*models.Component, offset int) []*models.Component {
+	if offset >= len(components) {
+		return nil
+	}
+	return components[offset:]
+}
+
+// ListComponentsAfterJSON returns up to limit components selected by filter in (created_at, id)
+// order, starting after the cursor or from the beginning when after is nil, as a JSON array.
+// next is the cursor to resume from, or nil on the last page.
+func (s *ComponentStore) ListComponentsAfterJSON(filter cache.Filter, after *cache.Cursor, limit int) (body []byte, next *cache.Cursor, err error) {
+	if c := s.cached(); c != nil {
+		return c.JSONAfter(filter, after, limit)
+	}
+
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, nil, err
+	}
+	q := selectFrom(tableComponents, componentColumns...).where(filterPredicates(filter)...).where(notDeleted)
+	if after != nil {
+		q.where(rowAfter([]column{columnCreatedAt, columnID}, after.CreatedAt, after.ID))
+	}
+	// One extra row tells whether another page follows.
+	query, args := q.orderBy(asc(columnCreatedAt), asc(columnID)).page(0, limit+1).build()
+	rows, err := dbConn.Query(db.Rebind(query), args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing components: %w", err)
+	}
+	defer rows.Close()
+	components := []*models.Component{}
+	var lastCreatedAt time.Time
+	for rows.Next() {
+		component := &models.Component{}
+		var createdAtDb, updatedAtDb time.Time
+		if err := rows.Scan(&component.ID, &component.Name, &component.Slug, &component.Type, &component.Status, &component.Description, (*[]byte)(&component.Metadata), &component.ParentID, &component.Position, &component.Version, &createdAtDb, &updatedAtDb); err != nil {
+			return nil, nil, fmt.Errorf("error scanning component row: %w", err)
+		}
+		if len(components) == limit {
+			// The cursor keeps the database's full timestamp precision; the RFC3339 form is truncated to seconds.
+			last := components[limit-1]
+			next = &cache.Cursor{CreatedAt: lastCreatedAt, ID: last.ID}
+			break
+		}
+		component.CreatedAt = createdAtDb.Format(time.RFC3339)
+		component.UpdatedAt = updatedAtDb.Format(time.RFC3339)
+		components = append(components, component)
+		lastCreatedAt = createdAtDb
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating component rows: %w", err)
+	}
+	rows.Close()
+	if err := attachTags(dbConn, components); err != nil {
+		return nil, nil, err
+	}
+	body, err = json.Marshal(components)
+	return body, next, err
+}
+
+// CountComponents returns the number of components selected by filter.
+func (s *ComponentStore) CountComponents(filter cache.Filter) (int, error) {
+	if c := s.cached(); c != nil {
+		return c.CountMatching(filter), nil
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	query, args := countFrom(tableComponents).where(filterPredicates(filter)...).where(notDeleted).build()
+	var count int
+	if err := dbConn.QueryRow(db.Rebind(query), args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting components: %w", err)
+	}
+	return count, nil
+}
+
 // ListChildComponents retrieves all direct children of a given parent component ID.
 // It uses the cache if initialized.
 func (s *ComponentStore) ListChildComponents(parentID int64) ([]*models.Component, error) {
-	if cache.GlobalComponentCache != nil {
-		children, _ := cache.GlobalComponentCache.GetChildren(parentID)
+	return s.ListChildComponentsContext(context.Background(), parentID)
+}
+
+// ListChildComponentsContext is ListChildComponents bound to ctx: it returns ctx's error once the
+// context is done, and the database fallback query is cancelled with it. Tree walks call it once
+// per node so an abandoned request stops at the next node instead of finishing the traversal.
+func (s *ComponentStore) ListChildComponentsContext(ctx context.Context, parentID int64) ([]*models.Component, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c := s.cached(); c != nil {
+		children, _ := c.GetChildren(parentID)
 		return children, nil
 	}
 
 	// Fallback to database if cache is not initialized
-	dbConn := db.GetDB()
-	query := "SELECT id, name, description, parent_id, created_at, updated_at FROM components WHERE parent_id = $1 ORDER BY created_at ASC"
-	rows, err := dbConn.Query(query, parentID)
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	query, args := selectFrom(tableComponents, componentColumns...).where(childrenOf(parentID)...).orderBy(siblingOrder...).build()
+	rows, err := dbConn.QueryContext(ctx, db.Rebind(query), args...)
 	if err != nil {
 		return nil, fmt.Errorf("error listing child components for parent ID %d: %w", parentID, err)
 	}
@@ -228,8 +912,14 @@ func (s *ComponentStore) ListChildComponents(parentID int64) ([]*models.Componen
 		err_scan := rows.Scan(
 			&component_model.ID,
 			&component_model.Name,
+			&component_model.Slug,
+			&component_model.Type,
+			&component_model.Status,
 			&component_model.Description,
+			(*[]byte)(&component_model.Metadata),
 			&component_model.ParentID,
+			&component_model.Position,
+			&component_model.Version,
 			&createdAtDb,
 			&updatedAtDb,
 		)
@@ -241,7 +931,213 @@ func (s *ComponentStore) ListChildComponents(parentID int64) ([]*models.Componen
 		components = append(components, component_model)
 	}
 	if err_rows := rows.Err(); err_rows != nil {
-		return nil, fmt.Errorf("error iterating child component rows for parent ID %d: %w", err_rows)
+		return nil, fmt.Errorf("error iterating child component rows for parent ID %d: %w", parentID, err_rows)
+	}
+	rows.Close()
+	if err := attachTags(dbConn, components); err != nil {
+		return nil, err
 	}
 	return components, nil
 }
+
+// CountChildComponents returns the number of direct children of parentID selected by filter.
+func (s *ComponentStore) CountChildComponents(parentID int64, filter cache.Filter) (int, error) {
+	if c := s.cached(); c != nil {
+		return c.CountChildrenMatching(parentID, filter), nil
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	query, args := countFrom(tableComponents).where(childrenOf(parentID)...).where(filterPredicates(filter)...).build()
+	var count int
+	err = dbConn.QueryRow(db.Rebind(query), args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting child components for parent ID %d: %w", parentID, err)
+	}
+	return count, nil
+}
+
+// ListChildComponentsJSON returns the direct children of parentID selected by filter as a JSON
+// array, in order, using the cache's pre-marshaled fragments when available. limit > 0 returns
+// only the page of at most limit children starting at offset; otherwise every child from offset
+// onwards is returned.
+func (s *ComponentStore) ListChildComponentsJSON(parentID int64, filter cache.Filter, order cache.Sort, offset, limit int) ([]byte, error) {
+	if c := s.cached(); c != nil {
+		return c.ChildrenJSON(parentID, filter, order, offset, limit)
+	}
+
+	var children []*models.Component
+	if limit > 0 || !filter.IsZero() || !order.IsZero() {
+		query, args := selectFrom(tableComponents, componentColumns...).where(childrenOf(parentID)...).where(filterPredicates(filter)...).
+			orderBy(sortKeys(order, siblingOrder)...).page(offset, limit).build()
+		dbConn, err := db.GetDB()
+		if err != nil {
+			return nil, err
+		}
+		rows, err := dbConn.Query(db.Rebind(query), args...)
+		if err != nil {
+			return nil, fmt.Errorf("error listing child components for parent ID %d: %w", parentID, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			component, err := scanComponentRow(rows)
+			if err != nil {
+				return nil, fmt.Errorf("error scanning child component row: %w", err)
+			}
+			children = append(children, component)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating child component rows for parent ID %d: %w", parentID, err)
+		}
+		rows.Close()
+		if err := attachTags(dbConn, children); err != nil {
+			return nil, err
+		}
+		if limit == 0 {
+			children = pageFrom(children, offset)
+		}
+	} else {
+		all, err := s.ListChildComponents(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if offset < len(all) {
+			children = all[offset:]
+		}
+	}
+	if children == nil {
+		children = []*models.Component{}
+	}
+	return json.Marshal(children)
+}
+
+// maxDescendantDepth bounds the recursive descendant query, so a parent cycle in the data cannot
+// make it recurse forever.
+const maxDescendantDepth = 10000
+
+// depthBound returns the recursion bound of the descendant query for a caller's maxDepth, where 0
+// means unlimited.
+func depthBound(maxDepth int) int {
+	if maxDepth <= 0 || maxDepth > maxDescendantDepth {
+		return maxDescendantDepth
+	}
+	return maxDepth
+}
+
+// descendantsCTE selects the IDs and depths of every descendant of $1 as the subtree relation.
+const descendantsCTE = `WITH RECURSIVE subtree (id, depth) AS (
+	SELECT id, 1 FROM components WHERE parent_id = $1 AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, s.depth + 1 FROM components c JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL AND s.depth < $2
+) `
+
+// ListDescendantsJSON returns the page of rootID's descendants selected by filter as a JSON array,
+// with the number of those in total. Components come level by level, and by ID within a level. The cache
+// walks its children index breadth first; without it, a recursive CTE selects the subtree.
+// maxDepth > 0 keeps only the first maxDepth levels, 1 being the children. limit > 0 returns only
+// the page of at most limit components starting at offset; otherwise every descendant from offset
+// onwards is returned.
+func (s *ComponentStore) ListDescendantsJSON(rootID int64, maxDepth int, filter cache.Filter, offset, limit int) ([]byte, int, error) {
+	if c := s.cached(); c != nil {
+		return c.DescendantsJSON(rootID, maxDepth, filter, offset, limit)
+	}
+
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, 0, err
+	}
+	// The filter's columns are the joined component's; subtree has only id and depth, and the
+	// components table keeps its name for the tag predicate to refer to
+	where, filterArgs := whereClause(filterPredicates(filter), 2)
+	args := append([]interface{}{rootID, depthBound(maxDepth)}, filterArgs...)
+	var total int
+	err = dbConn.QueryRow(db.Rebind(descendantsCTE+"SELECT COUNT(*) FROM subtree s JOIN components ON components.id = s.id"+where), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting descendants of component %d: %w", rootID, err)
+	}
+	query := descendantsCTE + `SELECT components.id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at
+		FROM subtree s JOIN components ON components.id = s.id` + where + ` ORDER BY s.depth, components.id`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
+	}
+	rows, err := dbConn.Query(db.Rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing descendants of component %d: %w", rootID, err)
+	}
+	defer rows.Close()
+	descendants := []*models.Component{}
+	for rows.Next() {
+		component, err := scanComponentRow(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning descendant row: %w", err)
+		}
+		descendants = append(descendants, component)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating descendants of component %d: %w", rootID, err)
+	}
+	rows.Close()
+	if err := attachTags(dbConn, descendants); err != nil {
+		return nil, 0, err
+	}
+	if limit == 0 {
+		descendants = pageFrom(descendants, offset)
+	}
+	if descendants == nil {
+		descendants = []*models.Component{}
+	}
+	body, err := json.Marshal(descendants)
+	return body, total, err
+}
+
+// GetTreeJSON returns the subtree rooted at rootID as nested JSON, each component with a
+// "children" array; see cache.TreeJSON. Without the cache, the subtree is selected with the same
+// recursive CTE as ListDescendantsJSON, down to opts.MaxDepth.
+func (s *ComponentStore) GetTreeJSON(rootID int64, opts cache.TreeOptions) ([]byte, error) {
+	if c := s.cached(); c != nil {
+		return c.TreeJSON(rootID, opts)
+	}
+
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	query := descendantsCTE + `SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at
+		FROM components WHERE (id IN (SELECT id FROM subtree) OR id = $3) AND deleted_at IS NULL`
+	rows, err := dbConn.Query(db.Rebind(query), rootID, depthBound(opts.MaxDepth), rootID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subtree of component %d: %w", rootID, err)
+	}
+	defer rows.Close()
+	var components []*models.Component
+	for rows.Next() {
+		component, err := scanComponentRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning subtree row: %w", err)
+		}
+		components = append(components, component)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subtree of component %d: %w", rootID, err)
+	}
+	rows.Close()
+	if err := attachTags(dbConn, components); err != nil {
+		return nil, err
+	}
+	return cache.TreeJSONOf(components, sql.NullInt64{Int64: rootID, Valid: true}, opts)
+}
+
+// GetForestJSON returns every root component's tree as a JSON array of nested objects, like
+// GetTreeJSON.
+func (s *ComponentStore) GetForestJSON(opts cache.TreeOptions) ([]byte, error) {
+	if c := s.cached(); c != nil {
+		return c.ForestJSON(opts)
+	}
+	components, err := s.ListComponents()
+	if err != nil {
+		return nil, err
+	}
+	return cache.TreeJSONOf(components, sql.NullInt64{}, opts)
+}
@@ -1,9 +1,12 @@
 package store
 
 import (
+	"component-service/cache"
 	"component-service/db"
 	"component-service/models"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"log"
 	"os"
 	"testing"
@@ -29,7 +32,9 @@ func TestMain(m *testing.M) {
 		// Do not exit here, allow other non-DB tests in the package if any.
 		// For this file, all tests are DB tests, so they will be skipped by `setupTestDB`.
 	} else {
-		db.InitDB() // Initialize connection using env vars
+		if err := db.InitDB(); err != nil { // Initialize connection using env vars
+			log.Fatalf("Failed to initialize database for store tests: %v", err)
+		}
 		testStore = &ComponentStore{}
 
 		// Optional: Clean up or prepare the database before tests
@@ -133,6 +138,11 @@ func TestCreateComponent(t *testing.T) {
 		assert.True(t, createdChild.ParentID.Valid)
 		assert.Equal(t, parentComp.ID, createdChild.ParentID.Int64)
 	})
+
+	t.Run("Create under a missing parent", func(t *testing.T) {
+		_, err := testStore.CreateComponent(&models.Component{Name: "Orphan", ParentID: sql.NullInt64{Int64: 999999, Valid: true}})
+		assert.ErrorIs(t, err, ErrParentNotFound)
+	})
 }
 
 func TestGetComponentByID(t *testing.T) {
@@ -209,6 +219,36 @@ func TestUpdateComponent(t *testing.T) {
 	})
 }
 
+func TestPatchComponent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+
+	parent := createTestComponent(t, "PatchParent", "", sql.NullInt64{Valid: false})
+	comp := createTestComponent(t, "TestPatch", "Kept description", sql.NullInt64{Int64: parent.ID, Valid: true})
+
+	name := "Patched Name"
+	err := testStore.PatchComponent(comp.ID, models.ComponentPatch{Name: &name})
+	assert.NoError(t, err)
+	patched, err := testStore.GetComponentByID(comp.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Patched Name", patched.Name)
+	assert.Equal(t, "Kept description", patched.Description, "Fields absent from the patch are kept")
+	assert.Equal(t, parent.ID, patched.ParentID.Int64)
+
+	root := sql.NullInt64{}
+	assert.NoError(t, testStore.PatchComponent(comp.ID, models.ComponentPatch{ParentID: &root}))
+	patched, err = testStore.GetComponentByID(comp.ID)
+	assert.NoError(t, err)
+	assert.False(t, patched.ParentID.Valid)
+	assert.Equal(t, "Patched Name", patched.Name)
+
+	err = testStore.PatchComponent(88888, models.ComponentPatch{Name: &name})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found for update")
+}
+
 func TestDeleteComponent(t *testing.T) {
 	if db.DB == nil {
 		t.Skip("Skipping test: DB connection not initialized.")
@@ -318,3 +358,129 @@ func TestListChildComponents(t *testing.T) {
 		assert.Len(t, children, 0)
 	})
 }
+
+func TestListDescendantsJSON(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	root := createTestComponent(t, "DescRoot", "", sql.NullInt64{Valid: false})
+	child := createTestComponent(t, "DescChild", "", sql.NullInt64{Int64: root.ID, Valid: true})
+	grandchild := createTestComponent(t, "DescGrandchild", "", sql.NullInt64{Int64: child.ID, Valid: true})
+	sibling := createTestComponent(t, "DescSibling", "", sql.NullInt64{Int64: root.ID, Valid: true})
+
+	body, total, err := testStore.ListDescendantsJSON(root.ID, 0, cache.Filter{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	var descendants []models.Component
+	assert.NoError(t, json.Unmarshal(body, &descendants))
+	if assert.Len(t, descendants, 3) {
+		// Level by level, by ID within a level.
+		assert.Equal(t, []int64{child.ID, sibling.ID, grandchild.ID}, []int64{descendants[0].ID, descendants[1].ID, descendants[2].ID})
+	}
+
+	body, total, err = testStore.ListDescendantsJSON(root.ID, 0, cache.Filter{}, 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Contains(t, string(body), "DescGrandchild")
+
+	body, total, err = testStore.ListDescendantsJSON(grandchild.ID, 0, cache.Filter{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Equal(t, "[]", string(body))
+}
+
+// TestListChildComponentsContextCancelled needs no database: a done context is checked before
+// either the cache or the database is consulted.
+func TestListChildComponentsContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	children, err := (&ComponentStore{}).ListChildComponentsContext(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, children)
+}
+
+func TestExplainRepresentativeQueries(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	createTestComponent(t, "ExplainRoot", "Desc", sql.NullInt64{Valid: false})
+
+	advice, err := testStore.ExplainRepresentativeQueries()
+	assert.NoError(t, err)
+	assert.NotNil(t, advice)
+	assert.Equal(t, int64(1), advice.RowCount)
+	assert.Len(t, advice.Queries, len(representativeQueries))
+	for _, qp := range advice.Queries {
+		assert.NotEmpty(t, qp.Plan, "Plan for %s should not be empty", qp.Name)
+		if !qp.FullScan {
+			assert.Empty(t, qp.SuggestedIndex, "No index suggestion expected for %s without a full scan", qp.Name)
+		}
+	}
+}
+
+func TestExportComponents(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	root := createTestComponent(t, "ExportRoot", "Desc", sql.NullInt64{Valid: false})
+	createTestComponent(t, "ExportChild1", "Desc", sql.NullInt64{Int64: root.ID, Valid: true})
+	createTestComponent(t, "ExportChild2", "Desc", sql.NullInt64{Int64: root.ID, Valid: true})
+
+	t.Run("Streams all rows across batches", func(t *testing.T) {
+		var names []string
+		var snapshot ExportSnapshot
+		snapshotCalls := 0
+		err := testStore.ExportComponents(context.Background(), 2, func(s ExportSnapshot) {
+			snapshot = s
+			snapshotCalls++
+		}, func(c *models.Component) error {
+			assert.Equal(t, 1, snapshotCalls, "Snapshot must be reported before the first row")
+			names = append(names, c.Name)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ExportRoot", "ExportChild1", "ExportChild2"}, names)
+		assert.False(t, snapshot.Timestamp.IsZero())
+	})
+
+	t.Run("Reads the tags of each batch", func(t *testing.T) {
+		tagged := createTestComponent(t, "ExportTagged", "Desc", sql.NullInt64{Int64: root.ID, Valid: true})
+		assert.NoError(t, replaceTags(db.DB, tagged.ID, []string{"pump", "spare"}))
+		tags := map[string][]string{}
+		err := testStore.ExportComponents(context.Background(), 2, nil, func(c *models.Component) error {
+			tags[c.Name] = c.Tags
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"pump", "spare"}, tags["ExportTagged"])
+		assert.Empty(t, tags["ExportRoot"])
+	})
+
+	t.Run("Reads a single snapshot", func(t *testing.T) {
+		var names []string
+		err := testStore.ExportComponents(context.Background(), 1, nil, func(c *models.Component) error {
+			if len(names) == 0 {
+				createTestComponent(t, "ExportLateArrival", "Desc", sql.NullInt64{Int64: root.ID, Valid: true})
+			}
+			names = append(names, c.Name)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.NotContains(t, names, "ExportLateArrival", "Rows committed after the snapshot must not be exported")
+	})
+
+	t.Run("Stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		seen := 0
+		err := testStore.ExportComponents(ctx, 1, nil, func(c *models.Component) error {
+			seen++
+			cancel()
+			return nil
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, seen, "No rows should be delivered after cancellation")
+	})
+}
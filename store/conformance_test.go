@@ -0,0 +1,223 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/fixtures"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conformanceRead is a read operation of the store, returning its result in a form that compares
+// equal across the cache and database paths when they agree.
+type conformanceRead struct {
+	name string
+	read func(s *ComponentStore) (interface{}, error)
+}
+
+// conformanceReads lists every read the cache and the database both serve, over the plant fixture
+// loaded as w, including missing components and pages past the end.
+func conformanceReads(w *fixtures.World) []conformanceRead {
+	const missing = int64(999999)
+	reads := []conformanceRead{
+		{"list", func(s *ComponentStore) (interface{}, error) { return s.ListComponents() }},
+		{"count", func(s *ComponentStore) (interface{}, error) { return s.CountComponents(cache.Filter{}) }},
+		{"count by name", func(s *ComponentStore) (interface{}, error) { return s.CountComponents(cache.Filter{Name: "pump"}) }},
+		{"count by substring", func(s *ComponentStore) (interface{}, error) {
+			return s.CountComponents(cache.Filter{NameContains: "LINE"})
+		}},
+		{"forest", func(s *ComponentStore) (interface{}, error) { return jsonResult(s.GetForestJSON(cache.TreeOptions{})) }},
+		{"forest one level", func(s *ComponentStore) (interface{}, error) {
+			return jsonResult(s.GetForestJSON(cache.TreeOptions{MaxDepth: 1}))
+		}},
+		{"cursor pages", func(s *ComponentStore) (interface{}, error) {
+			var pages []interface{}
+			var after *cache.Cursor
+			for len(pages) <= 10 {
+				body, next, err := s.ListComponentsAfterJSON(cache.Filter{}, after, 3)
+				if err != nil {
+					return nil, err
+				}
+				page, _ := jsonResult(body, nil)
+				pages = append(pages, page)
+				if next == nil {
+					break
+				}
+				after = next
+			}
+			return pages, nil
+		}},
+	}
+	for _, filter := range []cache.Filter{{}, {Name: "pump"}, {NameContains: "_"}, {Type: "device"}, {Name: "line-a", Type: "line"}, {Tag: "critical"}, {Name: "pump", Tag: "rotating"},
+		{Metadata: []cache.MetadataMatch{{Key: "vendor", Value: "acme"}}}, {Name: "pump", Metadata: []cache.MetadataMatch{{Key: "rated_kw", Value: "11"}}}} {
+		for _, order := range []cache.Sort{{}, {Field: cache.SortByName}, {Field: cache.SortByUpdatedAt, Descending: true}} {
+			for _, page := range [][2]int{{0, 0}, {0, 2}, {2, 2}, {6, 2}, {50, 2}, {3, 0}} {
+				filter, order, offset, limit := filter, order, page[0], page[1]
+				reads = append(reads, conformanceRead{fmt.Sprintf("list %+v %+v offset %d limit %d", filter, order, offset, limit), func(s *ComponentStore) (interface{}, error) {
+					return jsonResult(s.ListComponentsJSON(filter, order, offset, limit))
+				}})
+			}
+		}
+	}
+	ids := map[string]int64{"missing": missing}
+	for _, key := range []string{"plant", "line-a", "pump-a", "valve", "line-b", "pump-b", "stores"} {
+		ids[key] = w.ID(key)
+	}
+	for key, id := range ids {
+		key, id := key, id
+		reads = append(reads,
+			conformanceRead{"get " + key, func(s *ComponentStore) (interface{}, error) { return s.GetComponentByID(id) }},
+			conformanceRead{"children of " + key, func(s *ComponentStore) (interface{}, error) { return s.ListChildComponents(id) }},
+			conformanceRead{"child count of " + key, func(s *ComponentStore) (interface{}, error) { return s.CountChildComponents(id, cache.Filter{}) }},
+			conformanceRead{"device child count of " + key, func(s *ComponentStore) (interface{}, error) {
+				return s.CountChildComponents(id, cache.Filter{Type: "device"})
+			}},
+			conformanceRead{"device children of " + key, func(s *ComponentStore) (interface{}, error) {
+				return jsonResult(s.ListChildComponentsJSON(id, cache.Filter{Type: "device"}, cache.Sort{}, 0, 0))
+			}},
+			conformanceRead{"device descendants of " + key, func(s *ComponentStore) (interface{}, error) {
+				body, total, err := s.ListDescendantsJSON(id, 0, cache.Filter{Type: "device"}, 1, 1)
+				page, err := jsonResult(body, err)
+				return []interface{}{page, total}, err
+			}},
+			conformanceRead{"critical descendants of " + key, func(s *ComponentStore) (interface{}, error) {
+				body, total, err := s.ListDescendantsJSON(id, 0, cache.Filter{Tag: "critical"}, 0, 0)
+				page, err := jsonResult(body, err)
+				return []interface{}{page, total}, err
+			}},
+			conformanceRead{"tree of " + key, func(s *ComponentStore) (interface{}, error) {
+				return jsonResult(s.GetTreeJSON(id, cache.TreeOptions{}))
+			}},
+			conformanceRead{"two-level tree of " + key, func(s *ComponentStore) (interface{}, error) {
+				return jsonResult(s.GetTreeJSON(id, cache.TreeOptions{MaxDepth: 2}))
+			}},
+		)
+		for _, order := range []cache.Sort{{}, {Field: cache.SortByName, Descending: true}} {
+			for _, page := range [][2]int{{0, 0}, {0, 1}, {1, 1}, {5, 1}} {
+				order, offset, limit := order, page[0], page[1]
+				reads = append(reads, conformanceRead{fmt.Sprintf("children of %s %+v offset %d limit %d", key, order, offset, limit), func(s *ComponentStore) (interface{}, error) {
+					return jsonResult(s.ListChildComponentsJSON(id, cache.Filter{}, order, offset, limit))
+				}})
+			}
+		}
+		for _, bounds := range [][3]int{{0, 0, 0}, {1, 0, 0}, {0, 1, 2}, {0, 4, 2}} {
+			maxDepth, offset, limit := bounds[0], bounds[1], bounds[2]
+			reads = append(reads, conformanceRead{fmt.Sprintf("descendants of %s depth %d offset %d limit %d", key, maxDepth, offset, limit), func(s *ComponentStore) (interface{}, error) {
+				body, total, err := s.ListDescendantsJSON(id, maxDepth, cache.Filter{}, offset, limit)
+				page, err := jsonResult(body, err)
+				return []interface{}{page, total}, err
+			}})
+		}
+	}
+	for _, slug := range []string{"plant", "pump", "pump-2", "line-a", "missing"} {
+		slug := slug
+		reads = append(reads, conformanceRead{"slug " + slug, func(s *ComponentStore) (interface{}, error) {
+			return s.GetComponentBySlug(slug)
+		}})
+	}
+	for _, path := range [][]string{{"plant"}, {"plant", "line-a", "pump"}, {"plant", "line-b", "pump"}, {"plant", "pump"}, {"stores", "plant"}} {
+		path := path
+		reads = append(reads, conformanceRead{"path " + cache.FormatPath(path), func(s *ComponentStore) (interface{}, error) {
+			return s.ResolvePath(path, nil, false)
+		}})
+	}
+	return reads
+}
+
+// jsonResult decodes a JSON body, so bodies compare by content rather than formatting.
+func jsonResult(body []byte, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// conformanceOutcome is what a read is compared on: its result encoded as JSON, where a nil and
+// an empty slice both read as empty, and whether it failed with "not found" or otherwise.
+func conformanceOutcome(result interface{}, err error) string {
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return "error: not found"
+		}
+		return "error: " + err.Error()
+	}
+	encoded, encodeErr := json.Marshal(result)
+	if encodeErr != nil {
+		return "error encoding: " + encodeErr.Error()
+	}
+	if string(encoded) == "null" {
+		return "[]"
+	}
+	return string(encoded)
+}
+
+// assertConformance runs every read against the database, then against the current cache, and
+// against a cache freshly loaded from the database, and expects the three to agree.
+func assertConformance(t *testing.T, step string, reads []conformanceRead) {
+	t.Helper()
+	written := cache.GlobalComponentCache
+	fromDB := make([]string, len(reads))
+	for i, r := range reads {
+		fromDB[i] = conformanceOutcome(r.read(testStore.Strong()))
+	}
+	for _, mode := range []string{"written", "reloaded"} {
+		if mode == "reloaded" {
+			require.NoError(t, cache.InitGlobalCache(testStore.Strong()))
+		}
+		for i, r := range reads {
+			assert.Equal(t, fromDB[i], conformanceOutcome(r.read(testStore)), "%s: %s from the %s cache", step, r.name, mode)
+		}
+	}
+	cache.GlobalComponentCache = written
+}
+
+// TestCacheConformance runs every read against the database and the cache, after each write of a
+// sequence that the cache follows through write-through, and expects identical results: the
+// same components in the same order, pages and counts, and the same not-found cases.
+func TestCacheConformance(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	cache.GlobalComponentCache = nil
+	clearComponentsTableForTest()
+	w := fixtures.MustLoad(t, testStore, "plant")
+	require.NoError(t, cache.InitGlobalCache(testStore.Strong()))
+	reads := conformanceReads(w)
+	assertConformance(t, "loaded", reads)
+
+	for _, step := range []struct {
+		name  string
+		write func() error
+	}{
+		{"reorder", func() error { return testStore.ReorderComponent(w.ID("valve"), 0) }},
+		{"move", func() error {
+			return testStore.MoveComponents([]models.ComponentMove{{ID: w.ID("pump-b"), NewParentID: sql.NullInt64{Int64: w.ID("line-a"), Valid: true}}})
+		}},
+		{"move to the roots", func() error {
+			return testStore.MoveComponents([]models.ComponentMove{{ID: w.ID("valve"), NewParentID: sql.NullInt64{}}})
+		}},
+		{"rename", func() error {
+			renamed := "line_a"
+			return testStore.PatchComponent(w.ID("line-a"), models.ComponentPatch{Name: &renamed})
+		}},
+		{"delete", func() error { return testStore.DeleteComponent(w.ID("line-b")) }},
+		{"cascade", func() error { return testStore.DeleteSubtree(w.ID("line-a"), nil) }},
+		{"restore", func() error { return testStore.RestoreComponent(w.ID("line-a"), nil, nil) }},
+		{"purge", func() error { return testStore.PurgeComponent(w.ID("stores")) }},
+	} {
+		require.NoError(t, step.write(), step.name)
+		assertConformance(t, step.name, reads)
+	}
+}
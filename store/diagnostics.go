@@ -0,0 +1,143 @@
+package store
+
+import (
+	"component-service/db"
+	"fmt"
+	"strings"
+)
+
+// smallTableRowThreshold is the row count below which full scans are reported as informational:
+// planners legitimately prefer sequential scans on small tables even when an index exists.
+const smallTableRowThreshold = 10000
+
+// QueryPlan is the EXPLAIN output for one of the store's representative queries.
+type QueryPlan struct {
+	Name           string   `json:"name"`
+	Query          string   `json:"query"`
+	Plan           []string `json:"plan"`
+	FullScan       bool     `json:"full_scan"`
+	SuggestedIndex string   `json:"suggested_index,omitempty"`
+}
+
+// IndexAdvice is the result of ExplainRepresentativeQueries.
+type IndexAdvice struct {
+	Dialect  string      `json:"dialect"`
+	RowCount int64       `json:"row_count"`
+	Queries  []QueryPlan `json:"queries"`
+	Warnings []string    `json:"warnings"`
+}
+
+// representativeQuery mirrors a query pattern issued by the store. Values are inlined literals so
+// the EXPLAIN statements need no bind parameters, which not every dialect allows in EXPLAIN.
+type representativeQuery struct {
+	name           string
+	query          string
+	suggestedIndex string
+}
+
+var representativeQueries = []representativeQuery{
+	{
+		name:  "get_component_by_id",
+		query: "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE id = 1 AND deleted_at IS NULL",
+	},
+	{
+		name:           "list_child_components",
+		query:          "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE parent_id = 1 AND deleted_at IS NULL ORDER BY position ASC, created_at ASC, id ASC",
+		suggestedIndex: "CREATE INDEX idx_components_parent_id_position ON components(parent_id, position)",
+	},
+	{
+		name:           "find_child_by_name",
+		query:          "SELECT id FROM components WHERE parent_id = 1 AND name = 'example'",
+		suggestedIndex: "CREATE INDEX idx_components_parent_id_name ON components(parent_id, name)",
+	},
+	{
+		name:           "list_components_page",
+		query:          "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE deleted_at IS NULL ORDER BY created_at, id LIMIT 50",
+		suggestedIndex: "CREATE INDEX idx_components_created_at_id ON components(created_at, id)",
+	},
+	{
+		name:           "recently_updated",
+		query:          "SELECT id FROM components ORDER BY updated_at DESC LIMIT 50",
+		suggestedIndex: "CREATE INDEX idx_components_updated_at ON components(updated_at)",
+	},
+}
+
+// ExplainRepresentativeQueries runs EXPLAIN on the query shapes the store relies on and reports
+// the ones planned as full table scans, together with the index that would serve them.
+func (s *ComponentStore) ExplainRepresentativeQueries() (*IndexAdvice, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	dialect := db.CurrentDialect
+	advice := &IndexAdvice{Dialect: dialect.Name(), Queries: []QueryPlan{}, Warnings: []string{}}
+
+	if err := dbConn.QueryRow("SELECT COUNT(*) FROM components").Scan(&advice.RowCount); err != nil {
+		return nil, fmt.Errorf("error counting components for index advice: %w", err)
+	}
+
+	for _, rq := range representativeQueries {
+		plan, err := explainQuery(dialect.ExplainStatement(rq.query))
+		if err != nil {
+			return nil, fmt.Errorf("error explaining %s: %w", rq.name, err)
+		}
+		qp := QueryPlan{Name: rq.name, Query: rq.query, Plan: plan}
+		for _, line := range plan {
+			if strings.Contains(line, dialect.FullScanMarker()) {
+				qp.FullScan = true
+				break
+			}
+		}
+		if qp.FullScan && rq.suggestedIndex != "" {
+			qp.SuggestedIndex = rq.suggestedIndex
+			if advice.RowCount >= smallTableRowThreshold {
+				advice.Warnings = append(advice.Warnings, fmt.Sprintf("%s is planned as a full table scan over %d rows; missing index? %s", rq.name, advice.RowCount, rq.suggestedIndex))
+			} else {
+				advice.Warnings = append(advice.Warnings, fmt.Sprintf("%s is planned as a full table scan; expected while the table has fewer than %d rows (%d), otherwise check: %s", rq.name, smallTableRowThreshold, advice.RowCount, rq.suggestedIndex))
+			}
+		}
+		advice.Queries = append(advice.Queries, qp)
+	}
+	return advice, nil
+}
+
+// explainQuery executes an EXPLAIN statement and returns its plan, one text line per row.
+// Plans with several columns (CockroachDB) are joined with tabs.
+func explainQuery(statement string) ([]string, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.Query(statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var plan []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		parts := make([]string, 0, len(values))
+		for _, value := range values {
+			switch v := (*value.(*interface{})).(type) {
+			case nil:
+			case []byte:
+				parts = append(parts, string(v))
+			default:
+				parts = append(parts, fmt.Sprint(v))
+			}
+		}
+		// MySQL's TREE format returns the whole plan in one multi-line value.
+		plan = append(plan, strings.Split(strings.Join(parts, "\t"), "\n")...)
+	}
+	return plan, rows.Err()
+}
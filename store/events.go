@@ -0,0 +1,53 @@
+package store
+
+import (
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"time"
+)
+
+// componentEvent computes the event for a committed change from the component's state before
+// (nil on create) and after it (nil on delete), traced to the request the store was made for.
+// Only the parent is compared: a different parent makes the change a move, whatever else changed
+// with it.
+func (s *ComponentStore) componentEvent(before, after *models.Component) events.Event {
+	e := events.Event{OccurredAt: time.Now().UTC(), Component: after, Trace: s.trace}
+	switch {
+	case before == nil:
+		e.Type = events.ComponentCreated
+		e.ComponentID = after.ID
+	case after == nil:
+		e.Type = events.ComponentDeleted
+		e.ComponentID = before.ID
+	default:
+		e.Type = events.ComponentUpdated
+		e.ComponentID = after.ID
+		if normalizeParentID(before.ParentID) != normalizeParentID(after.ParentID) {
+			e.Type = events.ComponentMoved
+		}
+	}
+	if before != nil {
+		e.OldParentID = parentIDPointer(before.ParentID)
+	}
+	if after != nil {
+		e.NewParentID = parentIDPointer(after.ParentID)
+	}
+	return e
+}
+
+// normalizeParentID maps a parent ID of 0 to NULL, as the store does when writing.
+func normalizeParentID(parentID sql.NullInt64) sql.NullInt64 {
+	if parentID.Valid && parentID.Int64 != 0 {
+		return parentID
+	}
+	return sql.NullInt64{}
+}
+
+func parentIDPointer(parentID sql.NullInt64) *int64 {
+	if parentID = normalizeParentID(parentID); !parentID.Valid {
+		return nil
+	}
+	id := parentID.Int64
+	return &id
+}
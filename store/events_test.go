@@ -0,0 +1,88 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"component-service/tracing"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestComponentEvent(t *testing.T) {
+	root := &models.Component{ID: 1}
+	underTwo := &models.Component{ID: 1, ParentID: sql.NullInt64{Int64: 2, Valid: true}}
+	underThree := &models.Component{ID: 1, Name: "Renamed", ParentID: sql.NullInt64{Int64: 3, Valid: true}}
+
+	tests := []struct {
+		name          string
+		before, after *models.Component
+		expectedType  events.Type
+		oldParent     *int64
+		newParent     *int64
+	}{
+		{"create", nil, underTwo, events.ComponentCreated, nil, int64Ptr(2)},
+		{"update keeping parent", underTwo, underTwo, events.ComponentUpdated, int64Ptr(2), int64Ptr(2)},
+		{"move between parents", underTwo, underThree, events.ComponentMoved, int64Ptr(2), int64Ptr(3)},
+		{"move to root", underTwo, root, events.ComponentMoved, int64Ptr(2), nil},
+		{"move from root", root, underTwo, events.ComponentMoved, nil, int64Ptr(2)},
+		{"parent 0 is root", root, &models.Component{ID: 1, ParentID: sql.NullInt64{Valid: true}}, events.ComponentUpdated, nil, nil},
+		{"delete", underThree, nil, events.ComponentDeleted, int64Ptr(3), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := (&ComponentStore{}).componentEvent(tt.before, tt.after)
+			assert.Equal(t, tt.expectedType, e.Type)
+			assert.Equal(t, int64(1), e.ComponentID)
+			assert.Equal(t, tt.oldParent, e.OldParentID)
+			assert.Equal(t, tt.newParent, e.NewParentID)
+			assert.Same(t, tt.after, e.Component)
+		})
+	}
+}
+
+func TestComponentEventTrace(t *testing.T) {
+	trace := &tracing.Context{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", RequestID: "req-1"}
+	after := &models.Component{ID: 1}
+	assert.Nil(t, (&ComponentStore{}).componentEvent(nil, after).Trace, "Expected no trace outside a request")
+	assert.Same(t, trace, (&ComponentStore{}).WithTrace(trace).componentEvent(nil, after).Trace)
+	assert.Same(t, trace, (&ComponentStore{}).WithTrace(trace).Strong().componentEvent(nil, after).Trace, "Expected Strong to keep the trace")
+}
+
+func TestStorePublishesParentChanges(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	oldParent := createTestComponent(t, "OldParent", "", sql.NullInt64{Valid: false})
+	newParent := createTestComponent(t, "NewParent", "", sql.NullInt64{Valid: false})
+	comp := createTestComponent(t, "Moved", "", sql.NullInt64{Int64: oldParent.ID, Valid: true})
+
+	var published []events.Event
+	unsubscribe := events.Subscribe(func(e events.Event) { published = append(published, e) })
+	defer unsubscribe()
+
+	comp.ParentID = sql.NullInt64{Int64: newParent.ID, Valid: true}
+	assert.NoError(t, testStore.UpdateComponent(comp.ID, comp))
+	assert.NoError(t, testStore.DeleteComponent(newParent.ID))
+
+	if assert.Len(t, published, 3) {
+		assert.Equal(t, events.ComponentMoved, published[0].Type)
+		assert.Equal(t, &oldParent.ID, published[0].OldParentID)
+		assert.Equal(t, &newParent.ID, published[0].NewParentID)
+		assert.Equal(t, "Moved", published[0].Component.Name)
+
+		assert.Equal(t, events.ComponentDeleted, published[1].Type)
+		assert.Equal(t, newParent.ID, published[1].ComponentID)
+
+		// The deleted parent's child became a root.
+		assert.Equal(t, events.ComponentMoved, published[2].Type)
+		assert.Equal(t, comp.ID, published[2].ComponentID)
+		assert.Equal(t, &newParent.ID, published[2].OldParentID)
+		assert.Nil(t, published[2].NewParentID)
+	}
+}
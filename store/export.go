@@ -0,0 +1,159 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DefaultExportBatchSize is the number of rows fetched per round trip when streaming an export.
+const DefaultExportBatchSize = 1000
+
+const exportQuery = "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE deleted_at IS NULL ORDER BY created_at, id"
+
+// exportQueryAfter selects the components following the one with ID $1 in export order, for
+// dialects without cursors. That component's created_at is read back from the snapshot, so the
+// comparison keeps the database's full timestamp precision.
+const exportQueryAfter = "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE deleted_at IS NULL AND (created_at, id) > ((SELECT created_at FROM components WHERE id = $1), $2) ORDER BY created_at, id"
+
+// ExportSnapshot identifies the database snapshot an export was read from.
+type ExportSnapshot struct {
+	Position  string    // dialect-specific log position (PostgreSQL WAL LSN, CockroachDB HLC timestamp); may be empty
+	Timestamp time.Time // time the snapshot was taken
+}
+
+// ExportComponents streams every component, in creation order, to fn. It always reads from the
+// database and never materializes the full result set. All rows come from one snapshot, read in
+// a read-only transaction at the dialect's snapshot isolation level, so concurrent writes cannot
+// produce torn state such as a child without its just-created parent. onSnapshot is called with
+// the snapshot's identity before the first row.
+//
+// Rows are read batchSize at a time, with the tags of each batch: on dialects with cursors a
+// server-side cursor FETCHes them, elsewhere a keyset query selects each batch. Cancelling ctx
+// (e.g. the client disconnecting) aborts the in-flight statement and stops the export between
+// batches.
+func (s *ComponentStore) ExportComponents(ctx context.Context, batchSize int, onSnapshot func(ExportSnapshot), fn func(*models.Component) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultExportBatchSize
+	}
+	dialect := db.CurrentDialect
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	tx, err := dbConn.BeginTx(ctx, &sql.TxOptions{Isolation: dialect.SnapshotIsolation(), ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("error starting export transaction: %w", err)
+	}
+	defer tx.Rollback() // Read-only: nothing to commit, and this also closes any cursor.
+
+	// The first statement of a REPEATABLE READ transaction fixes its snapshot, so the marker
+	// read here describes exactly the data the export sees.
+	var snapshot ExportSnapshot
+	if err := tx.QueryRowContext(ctx, dialect.SnapshotQuery()).Scan(&snapshot.Position, &snapshot.Timestamp); err != nil {
+		return fmt.Errorf("error reading export snapshot: %w", err)
+	}
+	if onSnapshot != nil {
+		onSnapshot(snapshot)
+	}
+
+	// Batches are read whole and their rows closed, so each one's tags can be read on the
+	// transaction's connection, from the same snapshot, before it is handed on.
+	var fetch func(lastID int64) (*sql.Rows, error)
+	if dialect.SupportsCursors() {
+		// Each batch is written to the client inside the transaction, so a slow client leaves it idle
+		// between FETCHes; DB_IDLE_IN_TRANSACTION_TIMEOUT would otherwise end the session mid-export.
+		// Dialects with cursors are the ones that set that timeout.
+		if _, err := tx.ExecContext(ctx, "SET LOCAL idle_in_transaction_session_timeout = 0"); err != nil {
+			return fmt.Errorf("error lifting the idle timeout for export: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR "+exportQuery); err != nil {
+			return fmt.Errorf("error declaring export cursor: %w", err)
+		}
+		statement := fmt.Sprintf("FETCH FORWARD %d FROM export_cursor", batchSize)
+		fetch = func(int64) (*sql.Rows, error) { return tx.QueryContext(ctx, statement) }
+	} else {
+		// Without cursors, each batch is a keyset query after the last component exported
+		limit := fmt.Sprintf(" LIMIT %d", batchSize)
+		fetch = func(lastID int64) (*sql.Rows, error) {
+			if lastID == 0 {
+				return tx.QueryContext(ctx, exportQuery+limit)
+			}
+			return tx.QueryContext(ctx, db.Rebind(exportQueryAfter+limit), lastID, lastID)
+		}
+	}
+	var lastID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, err := fetchExportBatch(tx, fetch, lastID)
+		if err != nil {
+			return err
+		}
+		for _, component := range batch {
+			if err := fn(component); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// fetchExportBatch reads the batch following the component lastID, 0 for the first, with its
+// tags.
+func fetchExportBatch(tx *sql.Tx, fetch func(lastID int64) (*sql.Rows, error), lastID int64) ([]*models.Component, error) {
+	rows, err := fetch(lastID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching export batch: %w", err)
+	}
+	defer rows.Close()
+	var batch []*models.Component
+	for rows.Next() {
+		component, err := scanComponentRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning component row for export: %w", err)
+		}
+		batch = append(batch, component)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error fetching export batch: %w", err)
+	}
+	rows.Close()
+	if err := attachTags(tx, batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// scanComponentRow scans the standard component projection, componentColumns
+// (id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at).
+func scanComponentRow(rows *sql.Rows) (*models.Component, error) {
+	component := &models.Component{}
+	var createdAtDb, updatedAtDb time.Time
+	if err := rows.Scan(
+		&component.ID,
+		&component.Name,
+		&component.Slug,
+		&component.Type,
+		&component.Status,
+		&component.Description,
+		(*[]byte)(&component.Metadata),
+		&component.ParentID,
+		&component.Position,
+		&component.Version,
+		&createdAtDb,
+		&updatedAtDb,
+	); err != nil {
+		return nil, err
+	}
+	component.CreatedAt = createdAtDb.Format(time.RFC3339)
+	component.UpdatedAt = updatedAtDb.Format(time.RFC3339)
+	return component, nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"component-service/cache"
+	"strings"
+)
+
+// likeEscaper escapes LIKE wildcards so a substring filter matches them literally. Backslash is
+// the default LIKE escape character on every supported backend.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterPredicates renders filter as predicates on the components table. It mirrors
+// cache.Filter.Matches.
+func filterPredicates(filter cache.Filter) []predicate {
+	var predicates []predicate
+	if filter.Name != "" {
+		predicates = append(predicates, eq(columnName, filter.Name))
+	}
+	if filter.NameContains != "" {
+		predicates = append(predicates, containsFold(columnName, filter.NameContains))
+	}
+	if filter.Type != "" {
+		predicates = append(predicates, eq(columnType, filter.Type))
+	}
+	if filter.Tag != "" {
+		predicates = append(predicates, taggedWith(filter.Tag))
+	}
+	for _, match := range filter.Metadata {
+		predicates = append(predicates, metadataEquals(match.Key, match.Value))
+	}
+	if filter.Reader != nil {
+		predicates = append(predicates, readableBy(*filter.Reader))
+	}
+	return predicates
+}
+
+// newestFirst is the default order of component listings.
+var newestFirst = []ordering{desc(columnCreatedAt), desc(columnID)}
+
+// sortKeys renders order as ORDER BY keys with an ID tie-break, or returns defaultOrder for the
+// zero Sort. It mirrors cache.Sort; names compare in the database collation.
+func sortKeys(order cache.Sort, defaultOrder []ordering) []ordering {
+	if order.IsZero() {
+		return defaultOrder
+	}
+	col := columnCreatedAt
+	switch order.Field {
+	case cache.SortByName:
+		col = columnName
+	case cache.SortByUpdatedAt:
+		col = columnUpdatedAt
+	}
+	if order.Descending {
+		return []ordering{desc(col), desc(columnID)}
+	}
+	return []ordering{asc(col), asc(columnID)}
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"component-service/cache"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterPredicates(t *testing.T) {
+	assert.Empty(t, filterPredicates(cache.Filter{}))
+
+	query, args := countFrom(tableComponents).where(filterPredicates(cache.Filter{Name: "Pump", NameContains: `50%_Off\`})...).build()
+	assert.Equal(t, "SELECT COUNT(*) FROM components WHERE name = $1 AND LOWER(name) LIKE $2", query)
+	assert.Equal(t, []interface{}{"Pump", `%50\%\_off\\%`}, args)
+
+	query, args = countFrom(tableComponents).where(filterPredicates(cache.Filter{Type: "device", Tag: "critical"})...).build()
+	assert.Equal(t, "SELECT COUNT(*) FROM components WHERE type = $1 AND components.id IN (SELECT component_id FROM component_tags WHERE tag = $2)", query)
+	assert.Equal(t, []interface{}{"device", "critical"}, args)
+
+	query, args = countFrom(tableComponents).where(filterPredicates(cache.Filter{Metadata: []cache.MetadataMatch{{Key: "vendor", Value: "acme"}}})...).build()
+	assert.Equal(t, "SELECT COUNT(*) FROM components WHERE jsonb_typeof(metadata -> $1::text) IN ('string', 'number', 'boolean') AND metadata ->> $2::text = $3", query)
+	assert.Equal(t, []interface{}{"vendor", "vendor", "acme"}, args)
+}
+
+func TestSortKeys(t *testing.T) {
+	assert.Equal(t, newestFirst, sortKeys(cache.Sort{}, newestFirst))
+	assert.Equal(t, []ordering{asc(columnName), asc(columnID)}, sortKeys(cache.Sort{Field: cache.SortByName}, newestFirst))
+	assert.Equal(t, []ordering{desc(columnUpdatedAt), desc(columnID)}, sortKeys(cache.Sort{Field: cache.SortByUpdatedAt, Descending: true}, nil))
+	assert.Equal(t, []ordering{asc(columnCreatedAt), asc(columnID)}, sortKeys(cache.Sort{Field: "name; DROP TABLE components"}, nil), "Unknown fields never reach the SQL")
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"component-service/db"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrIdempotencyKeyInProgress is returned by ClaimIdempotencyKey while another request holding
+// the key has not finished.
+var ErrIdempotencyKeyInProgress = errors.New("idempotency key in progress")
+
+// ErrIdempotencyKeyReused is returned by ClaimIdempotencyKey for a key first used with a
+// different request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+
+// IdempotencyKeyTTL is how long the response to a request with an Idempotency-Key is replayed
+// to retries of it.
+var IdempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyClaimTimeout is how long a claimed key with no response yet blocks retries. A request
+// that died without completing or releasing its key, such as in a crash, leaves such a claim.
+const idempotencyClaimTimeout = time.Minute
+
+// IdempotencyKeyTTLFromEnv reads IDEMPOTENCY_KEY_TTL, a duration that defaults to 24h.
+func IdempotencyKeyTTLFromEnv() (time.Duration, error) {
+	value := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if value == "" {
+		return 24 * time.Hour, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL %q: expected a positive duration such as 24h", value)
+	}
+	return ttl, nil
+}
+
+// IdempotentResponse is the response recorded for a request with an Idempotency-Key, replayed
+// to its retries.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     map[string]string
+	Body       []byte
+}
+
+// ClaimIdempotencyKey claims key for principal's request, identified by fingerprint. It returns a
+// nil response when the key is new, and the request should be served and then completed or
+// released. For a retry it returns the response recorded for the key, or fails with
+// ErrIdempotencyKeyInProgress while there is none yet. A key first used with another fingerprint
+// fails with ErrIdempotencyKeyReused. Keys older than IdempotencyKeyTTL are forgotten.
+func (s *ComponentStore) ClaimIdempotencyKey(key, principal, fingerprint string) (*IdempotentResponse, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if _, err := dbConn.Exec(db.Rebind("DELETE FROM idempotency_keys WHERE created_at < $1 OR (status_code = 0 AND created_at < $2)"),
+		now.Add(-IdempotencyKeyTTL), now.Add(-idempotencyClaimTimeout)); err != nil {
+		return nil, fmt.Errorf("error expiring idempotency keys: %w", err)
+	}
+	result, err := dbConn.Exec(db.Rebind("INSERT INTO idempotency_keys (idempotency_key, principal, fingerprint, status_code, created_at) VALUES ($1, $2, $3, 0, $4)"+
+		db.CurrentDialect.UpsertClause([]string{"idempotency_key", "principal"}, nil)), key, principal, fingerprint, now)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming idempotency key %q: %w", key, err)
+	}
+	if claimed, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("error claiming idempotency key %q: %w", key, err)
+	} else if claimed > 0 {
+		return nil, nil
+	}
+
+	var recordedFingerprint string
+	var header, body sql.NullString
+	response := &IdempotentResponse{}
+	err = dbConn.QueryRow(db.Rebind("SELECT fingerprint, status_code, response_header, response_body FROM idempotency_keys WHERE idempotency_key = $1 AND principal = $2"),
+		key, principal).Scan(&recordedFingerprint, &response.StatusCode, &header, &body)
+	if err == sql.ErrNoRows { // expired or released since the insert
+		return nil, fmt.Errorf("%w: idempotency key %q changed hands; retry the request", ErrIdempotencyKeyInProgress, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading idempotency key %q: %w", key, err)
+	}
+	switch {
+	case recordedFingerprint != fingerprint:
+		return nil, fmt.Errorf("%w: idempotency key %q was used with a different request", ErrIdempotencyKeyReused, key)
+	case response.StatusCode == 0:
+		return nil, fmt.Errorf("%w: a request with idempotency key %q has not finished", ErrIdempotencyKeyInProgress, key)
+	}
+	if header.Valid {
+		if err := json.Unmarshal([]byte(header.String), &response.Header); err != nil {
+			return nil, fmt.Errorf("error decoding the response headers of idempotency key %q: %w", key, err)
+		}
+	}
+	response.Body = []byte(body.String)
+	return response, nil
+}
+
+// CompleteIdempotencyKey records the response to the request that claimed key, for its retries.
+func (s *ComponentStore) CompleteIdempotencyKey(key, principal string, response *IdempotentResponse) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return fmt.Errorf("error encoding the response headers of idempotency key %q: %w", key, err)
+	}
+	if _, err := dbConn.Exec(db.Rebind("UPDATE idempotency_keys SET status_code = $1, response_header = $2, response_body = $3 WHERE idempotency_key = $4 AND principal = $5"),
+		response.StatusCode, string(header), string(response.Body), key, principal); err != nil {
+		return fmt.Errorf("error recording the response of idempotency key %q: %w", key, err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets key, so a retry of the request that claimed it is served anew.
+func (s *ComponentStore) ReleaseIdempotencyKey(key, principal string) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	if _, err := dbConn.Exec(db.Rebind("DELETE FROM idempotency_keys WHERE idempotency_key = $1 AND principal = $2"), key, principal); err != nil {
+		return fmt.Errorf("error releasing idempotency key %q: %w", key, err)
+	}
+	return nil
+}
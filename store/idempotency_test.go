@@ -0,0 +1,64 @@
+package store
+
+import (
+	"component-service/db"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyTTLFromEnv(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "")
+	ttl, err := IdempotencyKeyTTLFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, ttl)
+
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "90m")
+	ttl, err = IdempotencyKeyTTLFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, ttl)
+
+	for _, value := range []string{"0s", "-1h", "a day"} {
+		t.Setenv("IDEMPOTENCY_KEY_TTL", value)
+		_, err = IdempotencyKeyTTLFromEnv()
+		assert.ErrorContains(t, err, "invalid IDEMPOTENCY_KEY_TTL", value)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	_, err := db.DB.Exec("DELETE FROM idempotency_keys")
+	require.NoError(t, err)
+	defer func(ttl time.Duration) { IdempotencyKeyTTL = ttl }(IdempotencyKeyTTL)
+
+	recorded, err := testStore.ClaimIdempotencyKey("key-1", "alice", "fingerprint-a")
+	require.NoError(t, err)
+	assert.Nil(t, recorded, "Expected a new key to be claimed")
+	_, err = testStore.ClaimIdempotencyKey("key-1", "alice", "fingerprint-a")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+	_, err = testStore.ClaimIdempotencyKey("key-1", "alice", "fingerprint-b")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	recorded, err = testStore.ClaimIdempotencyKey("key-1", "bob", "fingerprint-b")
+	require.NoError(t, err)
+	assert.Nil(t, recorded, "Expected keys to be scoped to the principal")
+
+	response := &IdempotentResponse{StatusCode: 201, Header: map[string]string{"Location": "/components/7"}, Body: []byte(`{"id":7}`)}
+	require.NoError(t, testStore.CompleteIdempotencyKey("key-1", "alice", response))
+	recorded, err = testStore.ClaimIdempotencyKey("key-1", "alice", "fingerprint-a")
+	require.NoError(t, err)
+	assert.Equal(t, response, recorded)
+
+	require.NoError(t, testStore.ReleaseIdempotencyKey("key-1", "bob"))
+	recorded, err = testStore.ClaimIdempotencyKey("key-1", "bob", "fingerprint-c")
+	require.NoError(t, err)
+	assert.Nil(t, recorded, "Expected a released key to be claimed anew")
+
+	IdempotencyKeyTTL = -time.Second // everything recorded so far has expired
+	recorded, err = testStore.ClaimIdempotencyKey("key-1", "alice", "fingerprint-d")
+	require.NoError(t, err)
+	assert.Nil(t, recorded, "Expected an expired key to be claimed anew")
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidImport is returned for an import whose components cannot be placed: a parent that is
+// neither imported nor mapped, an ID listed twice, a type ComponentTypes does not allow,
+// malformed tags, or metadata that is not a JSON object or does not conform to the attribute
+// schema of the type.
+var ErrInvalidImport = errors.New("invalid import")
+
+// ErrImportNotPermitted is returned when an import would attach components under a component the
+// caller may not write.
+var ErrImportNotPermitted = errors.New("import not permitted")
+
+// ImportMapping pairs a component's ID in the source instance with its ID here. Created is false
+// for a component an earlier import from the same source already created. SourceID is 0 for a
+// component given inline without an ID.
+type ImportMapping struct {
+	SourceID int64 `json:"source_id,omitempty"`
+	ID       int64 `json:"id"`
+	Created  bool  `json:"created"`
+}
+
+// ImportComponents copies components from another instance, named source, under new IDs. Their
+// ID and parent_id are IDs in the source: a parent_id is translated to the component imported for
+// it, in this batch or an earlier one from the same source. Timestamps are kept when given, and so
+// are slugs, numbered as on create when already taken here. Types, statuses, tags, metadata and
+// attributes are checked as on create, and a component without a status starts in the initial
+// one. The mapping is recorded in component_id_map and returned in input order; components mapped
+// before are left unchanged, so an import can be retried or split into batches, parents first. A
+// component whose import was soft-deleted since is imported again, and its mapping repointed.
+//
+// A component with a negative ID has no ID in the source, such as one written by hand inside its
+// parent: the ID only links it to its children in this batch. It is created on every import and
+// its mapping is not recorded.
+//
+// Imports from the same source run one at a time, serialized on the source's row in
+// component_import_sources, so concurrent ones cannot create a component twice. canAttach, when
+// not nil, is asked for every existing component that receives imported children.
+func (s *ComponentStore) ImportComponents(source string, components []*models.Component, canAttach func(parentID int64) bool) ([]ImportMapping, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	bySourceID := make(map[int64]*models.Component, len(components))
+	tags := make(map[int64][]string, len(components)) // normalized, by source ID
+	metadata := make(map[int64]json.RawMessage, len(components))
+	statuses := make(map[int64]string, len(components)) // by source ID
+	for _, comp := range components {
+		if _, duplicate := bySourceID[comp.ID]; duplicate {
+			return nil, fmt.Errorf("%w: component %d is listed more than once", ErrInvalidImport, comp.ID)
+		}
+		if err := checkType(comp.Type); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidImport, importLabel(comp), err)
+		}
+		if statuses[comp.ID], err = checkStatus(comp.Status); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidImport, importLabel(comp), err)
+		}
+		if tags[comp.ID], err = checkTags(comp.Tags); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidImport, importLabel(comp), err)
+		}
+		if metadata[comp.ID], err = checkMetadata(comp.Metadata); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidImport, importLabel(comp), err)
+		}
+		bySourceID[comp.ID] = comp
+	}
+
+	var mappings []ImportMapping
+	var createdIDs []int64
+	err = executeTxClaimingSlugs(dbConn, func(tx *sql.Tx) error {
+		mappings, createdIDs = mappings[:0], createdIDs[:0] // fn may run again when the transaction is retried
+		now := time.Now()
+		_, err := tx.Exec(db.Rebind("INSERT INTO component_import_sources (source, last_imported_at) VALUES ($1, $2)"+
+			db.CurrentDialect.UpsertClause([]string{"source"}, []string{"last_imported_at"})), source, now)
+		if err != nil {
+			return fmt.Errorf("error locking import source %q: %w", source, err)
+		}
+		mapped, err := importedIDs(tx, source, components)
+		if err != nil {
+			return err
+		}
+		order, err := importOrder(components, bySourceID, mapped)
+		if err != nil {
+			return err
+		}
+
+		createdHere := make(map[int64]bool, len(order)) // by source ID
+		for _, comp := range order {
+			parentID := sql.NullInt64{}
+			if comp.ParentID.Valid {
+				parentID = sql.NullInt64{Int64: mapped[comp.ParentID.Int64], Valid: true}
+				if !createdHere[comp.ParentID.Int64] && canAttach != nil && !canAttach(parentID.Int64) {
+					return fmt.Errorf("%w: attaching components under component %d requires write permission on it", ErrImportNotPermitted, parentID.Int64)
+				}
+			}
+			if err := checkAttributes(tx, comp.Type, metadata[comp.ID]); err != nil {
+				if errors.Is(err, ErrInvalidAttributes) {
+					return fmt.Errorf("%w: %s: %w", ErrInvalidImport, importLabel(comp), err)
+				}
+				return err
+			}
+			position, err := nextPosition(tx, parentID, 0)
+			if err != nil {
+				return err
+			}
+			base := comp.Slug
+			if !models.IsSlug(base) || len(base) > models.MaxSlugBaseLength {
+				base = models.Slugify(comp.Name)
+			}
+			slug, err := freeSlug(tx, base)
+			if err != nil {
+				return err
+			}
+			id, err := insertReturningID(tx, `INSERT INTO components (name, slug, type, status, description, metadata, parent_id, position, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				comp.Name, slug, comp.Type, statuses[comp.ID], comp.Description, metadataArg(metadata[comp.ID]), parentID, position, importTimestamp(comp.CreatedAt, now), importTimestamp(comp.UpdatedAt, now))
+			if err != nil {
+				return fmt.Errorf("error importing %s: %w", importLabel(comp), err)
+			}
+			if err := insertClosure(tx, id, parentID); err != nil {
+				return err
+			}
+			if err := replaceTags(tx, id, tags[comp.ID]); err != nil {
+				return err
+			}
+			if err := refreshSearchIndex(tx, id); err != nil {
+				return err
+			}
+			if comp.ID > 0 {
+				_, err = tx.Exec(db.Rebind("INSERT INTO component_id_map (source, source_id, component_id) VALUES ($1, $2, $3)"+
+					db.CurrentDialect.UpsertClause([]string{"source", "source_id"}, []string{"component_id"})),
+					source, comp.ID, id)
+				if err != nil {
+					return fmt.Errorf("error recording the mapping of component %d: %w", comp.ID, err)
+				}
+			}
+			mapped[comp.ID] = id
+			createdHere[comp.ID] = true
+			createdIDs = append(createdIDs, id)
+		}
+		for _, comp := range components {
+			mapping := ImportMapping{ID: mapped[comp.ID], Created: createdHere[comp.ID]}
+			if comp.ID > 0 {
+				mapping.SourceID = comp.ID
+			}
+			mappings = append(mappings, mapping)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	afters := s.afterWriteMany(dbConn, createdIDs, "import")
+	for _, id := range createdIDs {
+		after := afters[id]
+		if after == nil {
+			after = &models.Component{ID: id}
+		}
+		events.Publish(s.componentEvent(nil, after))
+	}
+	return mappings, nil
+}
+
+// importedIDs reads inside tx which of the components, and of their parents, earlier imports from
+// source created and are not soft-deleted, by source ID.
+func importedIDs(tx *sql.Tx, source string, components []*models.Component) (map[int64]int64, error) {
+	mapped := make(map[int64]int64)
+	placeholders := make([]string, 0, 2*len(components))
+	args := []interface{}{source}
+	for _, comp := range components {
+		if comp.ID > 0 {
+			args = append(args, comp.ID)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		if comp.ParentID.Valid && comp.ParentID.Int64 > 0 {
+			args = append(args, comp.ParentID.Int64)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+	}
+	if len(placeholders) == 0 { // everything was given inline
+		return mapped, nil
+	}
+	rows, err := tx.Query(db.Rebind("SELECT m.source_id, m.component_id FROM component_id_map m JOIN components c ON c.id = m.component_id "+
+		"WHERE c.deleted_at IS NULL AND m.source = $1 AND m.source_id IN ("+
+		strings.Join(placeholders, ", ")+")"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the ID mapping of import source %q: %w", source, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sourceID, id int64
+		if err := rows.Scan(&sourceID, &id); err != nil {
+			return nil, fmt.Errorf("error scanning the ID mapping of import source %q: %w", source, err)
+		}
+		mapped[sourceID] = id
+	}
+	return mapped, rows.Err()
+}
+
+// importOrder returns the components not mapped yet, each after its parent, so a parent_id can be
+// translated when its component is inserted. A parent that is neither imported nor mapped fails
+// with ErrInvalidImport, and a parent cycle among the components with ErrCycle.
+func importOrder(components []*models.Component, bySourceID map[int64]*models.Component, mapped map[int64]int64) ([]*models.Component, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int64]int, len(components))
+	order := make([]*models.Component, 0, len(components))
+	for _, comp := range components {
+		// Walk up to the first ancestor that is placed, then emit the path back down.
+		var path []*models.Component
+		for next := comp; next != nil && state[next.ID] == unvisited; {
+			if _, ok := mapped[next.ID]; ok {
+				break
+			}
+			state[next.ID] = visiting
+			path = append(path, next)
+			if !next.ParentID.Valid {
+				break
+			}
+			parentID := next.ParentID.Int64
+			if state[parentID] == visiting {
+				return nil, fmt.Errorf("%w: importing component %d under component %d", ErrCycle, next.ID, parentID)
+			}
+			if _, ok := mapped[parentID]; ok {
+				break
+			}
+			parent, imported := bySourceID[parentID]
+			if !imported {
+				return nil, fmt.Errorf("%w: the parent %d of %s is neither in the import nor imported before from this source",
+					ErrInvalidImport, parentID, importLabel(next))
+			}
+			next = parent
+		}
+		for i := len(path) - 1; i >= 0; i-- {
+			state[path[i].ID] = done
+			order = append(order, path[i])
+		}
+	}
+	return order, nil
+}
+
+// importLabel names an imported component in errors: by its ID in the source, or by name when it
+// was given inline without one.
+func importLabel(comp *models.Component) string {
+	if comp.ID > 0 {
+		return fmt.Sprintf("component %d", comp.ID)
+	}
+	return fmt.Sprintf("component %q", comp.Name)
+}
+
+// importTimestamp parses an imported RFC3339 timestamp, falling back to now when it is missing or
+// malformed.
+func importTimestamp(value string, now time.Time) time.Time {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed
+	}
+	return now
+}
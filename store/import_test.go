@@ -0,0 +1,119 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportOrder(t *testing.T) {
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	order := func(mapped map[int64]int64, components ...*models.Component) ([]int64, error) {
+		bySourceID := make(map[int64]*models.Component)
+		for _, comp := range components {
+			bySourceID[comp.ID] = comp
+		}
+		ordered, err := importOrder(components, bySourceID, mapped)
+		var ids []int64
+		for _, comp := range ordered {
+			ids = append(ids, comp.ID)
+		}
+		return ids, err
+	}
+
+	// Children listed before their parents are inserted after them.
+	ids, err := order(nil, &models.Component{ID: 3, ParentID: under(2)}, &models.Component{ID: 1}, &models.Component{ID: 2, ParentID: under(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+
+	// Components mapped before are skipped, and can be parents.
+	ids, err = order(map[int64]int64{1: 10}, &models.Component{ID: 1}, &models.Component{ID: 2, ParentID: under(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+
+	_, err = order(nil, &models.Component{ID: 2, ParentID: under(1)})
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	_, err = order(nil, &models.Component{ID: 1, ParentID: under(2)}, &models.Component{ID: 2, ParentID: under(1)})
+	assert.ErrorIs(t, err, ErrCycle)
+	_, err = order(nil, &models.Component{ID: 1, ParentID: under(1)})
+	assert.ErrorIs(t, err, ErrCycle, "Expected a self-parent to be refused")
+}
+
+func TestImportComponents(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	existing := createTestComponent(t, "ImportExisting", "", sql.NullInt64{})
+
+	// Source IDs collide with the existing component's.
+	batch := []*models.Component{
+		{ID: existing.ID + 1, Name: "ImportChild", ParentID: under(existing.ID)},
+		{ID: existing.ID, Name: "ImportRoot", CreatedAt: "2020-01-02T03:04:05Z"},
+	}
+	mapping, err := testStore.ImportComponents("import-test", batch, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, mapping, 2) {
+		assert.Equal(t, existing.ID+1, mapping[0].SourceID)
+		assert.True(t, mapping[0].Created && mapping[1].Created)
+		assert.NotEqual(t, existing.ID, mapping[1].ID)
+		child, err := testStore.GetComponentByID(mapping[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, under(mapping[1].ID), child.ParentID)
+		root, err := testStore.GetComponentByID(mapping[1].ID)
+		assert.NoError(t, err)
+		createdAt, err := time.Parse(time.RFC3339, root.CreatedAt)
+		assert.NoError(t, err)
+		assert.True(t, createdAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), "Expected the source's created_at, got %s", root.CreatedAt)
+	}
+	check, err := testStore.CheckClosure(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, check.Consistent)
+
+	// A later batch reuses the mapping, for its parents and for components already imported.
+	again, err := testStore.ImportComponents("import-test", []*models.Component{
+		batch[1],
+		{ID: 99, Name: "ImportGrandchild", ParentID: under(existing.ID + 1)},
+	}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, again, 2) {
+		assert.Equal(t, ImportMapping{SourceID: existing.ID, ID: mapping[1].ID}, again[0])
+		assert.True(t, again[1].Created)
+		grandchild, err := testStore.GetComponentByID(again[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, under(mapping[0].ID), grandchild.ParentID)
+	}
+
+	// Under another source the same IDs are new components, and unknown parents are refused.
+	_, err = testStore.ImportComponents("import-test-2", []*models.Component{{ID: 99, Name: "x", ParentID: under(existing.ID + 1)}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	_, err = testStore.ImportComponents("import-test", []*models.Component{{ID: 100, Name: "x", ParentID: under(99)}},
+		func(int64) bool { return false })
+	assert.ErrorIs(t, err, ErrImportNotPermitted)
+
+	// Components given inline, with negative IDs, are created every time and not mapped.
+	inline := []*models.Component{
+		{ID: -1, Name: "InlineRoot"},
+		{ID: -2, Name: "InlineChild", ParentID: under(-1)},
+		{ID: 200, Name: "MappedGrandchild", ParentID: under(-2)},
+	}
+	mapping, err = testStore.ImportComponents("import-test", inline, nil)
+	require.NoError(t, err)
+	require.Len(t, mapping, 3)
+	assert.Zero(t, mapping[0].SourceID)
+	assert.Equal(t, int64(200), mapping[2].SourceID)
+	child, err := testStore.GetComponentByID(mapping[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, under(mapping[0].ID), child.ParentID)
+	again, err = testStore.ImportComponents("import-test", inline[:2], nil)
+	require.NoError(t, err)
+	assert.True(t, again[0].Created && again[1].Created)
+	assert.NotEqual(t, mapping[0].ID, again[0].ID)
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MergeComponent folds the component id into intoID in one transaction: id's children are
+// reparented under intoID, after its existing children, and id is then soft-deleted as
+// DeleteComponent does. Merging a component into itself or one of its descendants fails with
+// ErrCycle. With UniqueSiblingNames, a child whose name intoID's children, or another moved
+// child, already have fails with ErrDuplicateName, and nothing is merged. Each child gets a moved
+// event and id a deleted event, as for the move and delete the merge combines.
+func (s *ComponentStore) MergeComponent(id, intoID int64) error {
+	if id == intoID {
+		return fmt.Errorf("%w: component %d cannot be merged into itself", ErrCycle, id)
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	into := sql.NullInt64{Int64: intoID, Valid: true}
+	var before *models.Component
+	var children []*models.Component
+	var name string // of the child being moved, for a unique violation the sibling name index raises
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, children = nil, nil // fn may run again when the transaction is retried
+		// Rows are locked in ID order, as MoveComponents does, so a concurrent batch cannot deadlock on them.
+		for _, lockID := range []int64{min(id, intoID), max(id, intoID)} {
+			locked, err := lockComponentParent(tx, lockID)
+			if err != nil {
+				return err
+			}
+			if locked == nil {
+				return fmt.Errorf("component with ID %d not found for merge", lockID)
+			}
+			if lockID == id {
+				before = locked
+			}
+		}
+		ids, err := childIDs(tx, id)
+		if err != nil {
+			return err
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		moves := make([]models.ComponentMove, 0, len(ids))
+		newParents := make(map[int64]sql.NullInt64, len(ids))
+		for _, childID := range ids {
+			child, err := lockComponentParent(tx, childID)
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue // deleted since it was listed
+			}
+			children = append(children, child)
+			moves = append(moves, models.ComponentMove{ID: childID, NewParentID: into})
+			newParents[childID] = into
+		}
+		// A target below id lies in one of its children's subtrees, which the check walks into.
+		if err := checkMovesAcyclic(tx, moves, newParents); err != nil {
+			if errors.Is(err, ErrCycle) {
+				return fmt.Errorf("%w: component %d is %d itself or one of its descendants", ErrCycle, intoID, id)
+			}
+			return err
+		}
+		now := time.Now()
+		for _, move := range moves {
+			if err := tx.QueryRow(db.Rebind("SELECT name FROM components WHERE id = $1"), move.ID).Scan(&name); err != nil {
+				return fmt.Errorf("error reading name of component with ID %d: %w", move.ID, err)
+			}
+			// Checked after the earlier children have moved, so two children sharing a name are refused too
+			if err := checkSiblingName(tx, move.ID, into, name); err != nil {
+				return err
+			}
+			_, err := tx.Exec(db.Rebind("UPDATE components SET parent_id = $1, updated_at = $2, version = version + 1 WHERE id = $3"), into, now, move.ID)
+			if err != nil {
+				return fmt.Errorf("error moving component with ID %d: %w", move.ID, err)
+			}
+			if err := detachClosure(tx, move.ID); err != nil {
+				return err
+			}
+			if err := attachClosure(tx, move.ID, into); err != nil {
+				return err
+			}
+			if err := appendPosition(tx, move.ID, into); err != nil {
+				return err
+			}
+		}
+		if err := detachClosure(tx, id); err != nil {
+			return err
+		}
+		return softDeleteRow(tx, id)
+	})
+	if err != nil {
+		return asDuplicateName(err, into, name)
+	}
+
+	ids := make([]int64, len(children))
+	for i, child := range children {
+		ids[i] = child.ID
+	}
+	// The children are stored under intoID before id leaves the cache, so they never become roots there.
+	afters := s.afterWriteMany(dbConn, ids, "merge")
+	if cacheWritesThrough(id) {
+		cache.GlobalComponentCache.Delete(id)
+	}
+	for _, child := range children {
+		after := afters[child.ID]
+		if after == nil {
+			after = &models.Component{ID: child.ID, ParentID: into}
+		}
+		events.Publish(s.componentEvent(child, after))
+	}
+	events.Publish(s.componentEvent(before, nil))
+	return nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"component-service/db"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeComponent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	kept := createTestComponent(t, "MergeKept", "", sql.NullInt64{})
+	existing := createTestComponent(t, "MergeExisting", "", under(kept.ID))
+	duplicate := createTestComponent(t, "MergeDuplicate", "", sql.NullInt64{})
+	a := createTestComponent(t, "MergeA", "", under(duplicate.ID))
+	b := createTestComponent(t, "MergeB", "", under(a.ID))
+
+	// A target below the duplicate fails before anything changes.
+	assert.ErrorIs(t, testStore.MergeComponent(duplicate.ID, b.ID), ErrCycle)
+	assert.ErrorIs(t, testStore.MergeComponent(duplicate.ID, duplicate.ID), ErrCycle)
+	err := testStore.MergeComponent(duplicate.ID, b.ID+100)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	require.NoError(t, testStore.MergeComponent(duplicate.ID, kept.ID))
+	_, err = testStore.GetComponentByID(duplicate.ID)
+	assert.Error(t, err, "Expected the duplicate to be deleted")
+	moved, err := testStore.GetComponentByID(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, under(kept.ID), moved.ParentID)
+	children, err := testStore.ListChildComponents(kept.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, existing.ID, children[0].ID, "Merged children follow the existing ones")
+
+	var depth int
+	require.NoError(t, db.DB.QueryRow(db.Rebind("SELECT depth FROM component_closure WHERE ancestor_id = $1 AND descendant_id = $2"), kept.ID, b.ID).Scan(&depth))
+	assert.Equal(t, 2, depth)
+}
+
+func TestMergeComponentSiblingNames(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	UniqueSiblingNames = true
+	defer func() { UniqueSiblingNames = false }()
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	kept := createTestComponent(t, "SiblingsKept", "", sql.NullInt64{})
+	createTestComponent(t, "Pump", "", under(kept.ID))
+	duplicate := createTestComponent(t, "SiblingsDuplicate", "", sql.NullInt64{})
+	pump := createTestComponent(t, "Pump", "", under(duplicate.ID))
+
+	err := testStore.MergeComponent(duplicate.ID, kept.ID)
+	assert.ErrorIs(t, err, ErrDuplicateName)
+	unmoved, err := testStore.GetComponentByID(pump.ID)
+	require.NoError(t, err)
+	assert.Equal(t, under(duplicate.ID), unmoved.ParentID, "Expected nothing to be merged")
+	_, err = testStore.GetComponentByID(duplicate.ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"component-service/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidMetadata is returned by a write giving a component metadata that is not a JSON
+// object, or is larger than models.MaxMetadataBytes.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// checkMetadata normalizes metadata as components store it, failing with ErrInvalidMetadata.
+func checkMetadata(metadata json.RawMessage) (json.RawMessage, error) {
+	normalized, err := models.NormalizeMetadata(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	return normalized, nil
+}
+
+// metadataArg binds normalized metadata to the metadata column: NULL for none, and otherwise
+// text, since lib/pq would send a []byte as bytea.
+func metadataArg(metadata json.RawMessage) interface{} {
+	if metadata == nil {
+		return nil
+	}
+	return string(metadata)
+}
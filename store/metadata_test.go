@@ -0,0 +1,73 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMetadata(t *testing.T) {
+	metadata, err := checkMetadata(json.RawMessage(` { "vendor": "acme" } `))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"vendor":"acme"}`, string(metadata))
+	assert.Equal(t, "{\"vendor\":\"acme\"}", metadataArg(metadata))
+	metadata, err = checkMetadata(json.RawMessage(`{}`))
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+	assert.Nil(t, metadataArg(metadata))
+	_, err = checkMetadata(json.RawMessage(`["acme"]`))
+	assert.ErrorIs(t, err, ErrInvalidMetadata)
+}
+
+func TestComponentMetadata(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	metadataOf := func(id int64) map[string]interface{} {
+		t.Helper()
+		comp, err := testStore.Strong().GetComponentByID(id)
+		require.NoError(t, err)
+		if comp.Metadata == nil {
+			return nil
+		}
+		var object map[string]interface{}
+		require.NoError(t, json.Unmarshal(comp.Metadata, &object))
+		return object
+	}
+
+	pumpID, err := testStore.CreateComponent(&models.Component{Name: "Pump", Metadata: json.RawMessage(`{"vendor": "acme", "rated_kw": 7.5, "spec": {"iso": 9906}}`)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"vendor": "acme", "rated_kw": 7.5, "spec": map[string]interface{}{"iso": 9906.0}}, metadataOf(pumpID))
+	valveID, err := testStore.CreateComponent(&models.Component{Name: "Valve", Metadata: json.RawMessage(`{"vendor": "other", "certified": true}`)})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		key, value string
+		want       int
+	}{
+		{"vendor", "acme", 1},
+		{"rated_kw", "7.5", 1},
+		{"certified", "true", 1},
+		{"spec", `{"iso":9906}`, 0}, // objects never match
+		{"missing", "", 0},
+	} {
+		count, err := testStore.Strong().CountComponents(cache.Filter{Metadata: []cache.MetadataMatch{{Key: tt.key, Value: tt.value}}})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, count, tt.key)
+	}
+
+	cleared := json.RawMessage(`null`)
+	require.NoError(t, testStore.PatchComponent(valveID, models.ComponentPatch{Metadata: &cleared}))
+	assert.Nil(t, metadataOf(valveID))
+	require.NoError(t, testStore.UpdateComponent(pumpID, &models.Component{Name: "Pump"}))
+	assert.Nil(t, metadataOf(pumpID), "PUT replaces the metadata")
+
+	_, err = testStore.CreateComponent(&models.Component{Name: "Bad", Metadata: json.RawMessage(`"acme"`)})
+	assert.ErrorIs(t, err, ErrInvalidMetadata)
+}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrCycle is returned when a parent change would make a component its own ancestor.
+var ErrCycle = errors.New("parent change would create a cycle")
+
+// MoveComponents reparents several components in one transaction: either every move is applied
+// or none is. Moves are checked against the tree as it will be once all of them are applied, so
+// two subtrees can swap places, but a move that would make a component its own ancestor fails the
+// whole batch. The cache is updated in a single locked pass, and each component gets a moved
+// event, or an updated event when its parent did not change.
+func (s *ComponentStore) MoveComponents(moves []models.ComponentMove) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	// Rows are locked in ID order, so concurrent batches cannot deadlock on each other.
+	ordered := append([]models.ComponentMove{}, moves...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].ID < ordered[j].ID })
+	newParents := make(map[int64]sql.NullInt64, len(ordered))
+	for _, move := range ordered {
+		if _, duplicate := newParents[move.ID]; duplicate {
+			return fmt.Errorf("component %d is moved more than once", move.ID)
+		}
+		newParents[move.ID] = normalizeParentID(move.NewParentID)
+	}
+
+	var befores []*models.Component
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		befores = befores[:0] // fn may run again when the transaction is retried
+		for _, move := range ordered {
+			before, err := lockComponentParent(tx, move.ID)
+			if err != nil {
+				return err
+			}
+			if before == nil {
+				return fmt.Errorf("component with ID %d not found for move", move.ID)
+			}
+			befores = append(befores, before)
+		}
+		if err := checkMovesAcyclic(tx, ordered, newParents); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, move := range ordered {
+			_, err := tx.Exec(db.Rebind("UPDATE components SET parent_id = $1, updated_at = $2, version = version + 1 WHERE id = $3"),
+				newParents[move.ID], now, move.ID)
+			if err != nil {
+				return fmt.Errorf("error moving component with ID %d: %w", move.ID, err)
+			}
+		}
+		// Every moved subtree is detached before any is attached, so a subtree attached under
+		// another that moves later gains that one's new ancestors when it is attached in turn.
+		for i, move := range ordered {
+			if newParents[move.ID] != normalizeParentID(befores[i].ParentID) {
+				if err := detachClosure(tx, move.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for i, move := range ordered {
+			if newParents[move.ID] != normalizeParentID(befores[i].ParentID) {
+				if err := attachClosure(tx, move.ID, newParents[move.ID]); err != nil {
+					return err
+				}
+				if err := appendPosition(tx, move.ID, newParents[move.ID]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(ordered))
+	for i, move := range ordered {
+		ids[i] = move.ID
+	}
+	afters := s.afterWriteMany(dbConn, ids, "move")
+	for _, before := range befores {
+		after := afters[before.ID]
+		if after == nil {
+			after = &models.Component{ID: before.ID, ParentID: newParents[before.ID]}
+		}
+		events.Publish(s.componentEvent(before, after))
+	}
+	return nil
+}
+
+// checkMovesAcyclic walks up from each move's new parent through the tree as it will be after
+// the batch, failing with ErrCycle if the walk reaches the moved component. Parents outside the batch are read
+// inside tx, once each.
+func checkMovesAcyclic(tx *sql.Tx, moves []models.ComponentMove, newParents map[int64]sql.NullInt64) error {
+	parents := make(map[int64]sql.NullInt64) // current parents of components outside the batch
+	parentOf := func(id int64) (sql.NullInt64, error) {
+		if parent, moved := newParents[id]; moved {
+			return parent, nil
+		}
+		if parent, known := parents[id]; known {
+			return parent, nil
+		}
+		var parent sql.NullInt64
+		err := tx.QueryRow(db.Rebind("SELECT parent_id FROM components WHERE id = $1 AND deleted_at IS NULL"), id).Scan(&parent)
+		if err == sql.ErrNoRows {
+			return parent, fmt.Errorf("parent component with ID %d not found", id)
+		}
+		if err != nil {
+			return parent, fmt.Errorf("error reading component with ID %d: %w", id, err)
+		}
+		parents[id] = parent
+		return parent, nil
+	}
+
+	for _, move := range moves {
+		visited := make(map[int64]bool)
+		for ancestor := newParents[move.ID]; ancestor.Valid && !visited[ancestor.Int64]; {
+			if ancestor.Int64 == move.ID {
+				return fmt.Errorf("%w: moving component %d under component %d", ErrCycle, move.ID, newParents[move.ID].Int64)
+			}
+			visited[ancestor.Int64] = true // stops at a cycle already in the data that does not involve move.ID
+			var err error
+			if ancestor, err = parentOf(ancestor.Int64); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterWriteMany reads the written components as committed and stores them in the cache
+// together, or invalidates them under write-around. Like afterWrite, the read is skipped when
+// nothing needs it, and a failed read is logged without failing the write.
+func (s *ComponentStore) afterWriteMany(dbConn *sql.DB, ids []int64, operation string) map[int64]*models.Component {
+	afters := make(map[int64]*models.Component, len(ids))
+	writeThrough := cacheWritesThrough(ids...)
+	if len(ids) == 0 || (!writeThrough && !events.HasSubscribers()) {
+		return afters
+	}
+	components, err := loadComponents(dbConn, ids)
+	if err != nil {
+		fmt.Printf("Error fetching components for cache update after %s: %v\n", operation, err)
+		return afters
+	}
+	for _, component := range components {
+		afters[component.ID] = component
+	}
+	if writeThrough {
+		cache.GlobalComponentCache.SetMany(components)
+	}
+	return afters
+}
+
+// LoadComponents reads the components with the given IDs from the database, for the cache to
+// reload the ones it invalidated.
+func (s *ComponentStore) LoadComponents(ids []int64) ([]*models.Component, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	return loadComponents(dbConn, ids)
+}
+
+// loadComponents reads the components with the given IDs that exist, in one query.
+func loadComponents(dbConn *sql.DB, ids []int64) ([]*models.Component, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	query := "SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE id IN (" +
+		strings.Join(placeholders, ", ") + ")"
+	rows, err := dbConn.Query(db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading components: %w", err)
+	}
+	defer rows.Close()
+	components := make([]*models.Component, 0, len(ids))
+	for rows.Next() {
+		component := &models.Component{}
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&component.ID, &component.Name, &component.Slug, &component.Type, &component.Status, &component.Description, (*[]byte)(&component.Metadata), &component.ParentID, &component.Position, &component.Version, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning component: %w", err)
+		}
+		component.CreatedAt = createdAt.Format(time.RFC3339)
+		component.UpdatedAt = updatedAt.Format(time.RFC3339)
+		components = append(components, component)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating components: %w", err)
+	}
+	rows.Close()
+	if err := attachTags(dbConn, components); err != nil {
+		return nil, err
+	}
+	return components, nil
+}
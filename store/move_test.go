@@ -0,0 +1,48 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveComponents(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	root := createTestComponent(t, "MoveRoot", "", sql.NullInt64{Valid: false})
+	a := createTestComponent(t, "MoveA", "", sql.NullInt64{Int64: root.ID, Valid: true})
+	b := createTestComponent(t, "MoveB", "", sql.NullInt64{Int64: a.ID, Valid: true})
+	under := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+
+	// Swapping a and b is valid once both moves apply, though either alone would form a cycle.
+	assert.NoError(t, testStore.MoveComponents([]models.ComponentMove{
+		{ID: a.ID, NewParentID: under(b.ID)},
+		{ID: b.ID, NewParentID: under(root.ID)},
+	}))
+	moved, err := testStore.GetComponentByID(a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, under(b.ID), moved.ParentID)
+
+	// A cycle fails the whole batch, including its valid moves.
+	err = testStore.MoveComponents([]models.ComponentMove{
+		{ID: a.ID, NewParentID: sql.NullInt64{}},
+		{ID: b.ID, NewParentID: under(a.ID)},
+		{ID: root.ID, NewParentID: under(b.ID)},
+	})
+	assert.ErrorIs(t, err, ErrCycle)
+	moved, err = testStore.GetComponentByID(a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, under(b.ID), moved.ParentID)
+
+	err = testStore.MoveComponents([]models.ComponentMove{{ID: a.ID, NewParentID: under(a.ID + b.ID + root.ID)}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+	err = testStore.MoveComponents([]models.ComponentMove{{ID: a.ID}, {ID: a.ID}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "more than once")
+}
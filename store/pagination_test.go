@@ -0,0 +1,77 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPaginationMatchesCache serves the same pages from the database and from a cache loaded from
+// it, and expects identical bodies.
+func TestPaginationMatchesCache(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	defer func(c *cache.ComponentCache) { cache.GlobalComponentCache = c }(cache.GlobalComponentCache)
+	cache.GlobalComponentCache = nil
+	clearComponentsTableForTest()
+	root := createTestComponent(t, "pagination root", "", sql.NullInt64{})
+	for i := 0; i < 7; i++ {
+		createTestComponent(t, fmt.Sprintf("pagination child %c", 'g'-i), "", sql.NullInt64{Int64: root.ID, Valid: true})
+	}
+
+	type listing struct {
+		name string
+		list func() ([]byte, error)
+	}
+	var listings []listing
+	for _, order := range []cache.Sort{{}, {Field: cache.SortByName}, {Field: cache.SortByCreatedAt, Descending: true}} {
+		for _, page := range [][2]int{{0, 0}, {0, 3}, {3, 3}, {6, 3}, {2, 0}} {
+			order, offset, limit := order, page[0], page[1]
+			listings = append(listings,
+				listing{fmt.Sprintf("all %+v offset %d limit %d", order, offset, limit), func() ([]byte, error) {
+					return testStore.ListComponentsJSON(cache.Filter{}, order, offset, limit)
+				}},
+				listing{fmt.Sprintf("filtered %+v offset %d limit %d", order, offset, limit), func() ([]byte, error) {
+					return testStore.ListComponentsJSON(cache.Filter{NameContains: "child"}, order, offset, limit)
+				}},
+				listing{fmt.Sprintf("children %+v offset %d limit %d", order, offset, limit), func() ([]byte, error) {
+					return testStore.ListChildComponentsJSON(root.ID, cache.Filter{}, order, offset, limit)
+				}},
+			)
+		}
+	}
+	cursorPages := func() []string {
+		var pages []string
+		var after *cache.Cursor
+		for {
+			body, next, err := testStore.ListComponentsAfterJSON(cache.Filter{}, after, 3)
+			assert.NoError(t, err)
+			pages = append(pages, string(body))
+			if next == nil || len(pages) > 10 {
+				return pages
+			}
+			after = next
+		}
+	}
+
+	fromDB := make([]string, len(listings))
+	for i, l := range listings {
+		body, err := l.list()
+		assert.NoError(t, err, l.name)
+		fromDB[i] = string(body)
+	}
+	dbCursorPages := cursorPages()
+
+	assert.NoError(t, cache.InitGlobalCache(testStore))
+	for i, l := range listings {
+		body, err := l.list()
+		assert.NoError(t, err, l.name)
+		assert.JSONEq(t, fromDB[i], string(body), l.name)
+	}
+	assert.Equal(t, dbCursorPages, cursorPages())
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"fmt"
+)
+
+// ResolvePath returns the component names leads to from the roots, as cache.ResolvePath does:
+// siblings sharing a name resolve to the first in sibling order, or fail with
+// cache.ErrAmbiguousPath when strict. visible, when not nil, leaves out the components it
+// refuses. Without the cache, each name is looked up with one query.
+func (s *ComponentStore) ResolvePath(names []string, visible func(id int64) bool, strict bool) (*models.Component, error) {
+	if c := s.cached(); c != nil {
+		return c.ResolvePath(names, visible, strict)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("component at path / not found")
+	}
+
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	var found *models.Component
+	parentID := sql.NullInt64{}
+	for i, name := range names {
+		query, args := selectFrom(tableComponents, componentColumns...).
+			where(siblingsOf(parentID)...).where(eq(columnName, name)).orderBy(siblingOrder...).build()
+		rows, err := dbConn.Query(db.Rebind(query), args...)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving path %s: %w", cache.FormatPath(names[:i+1]), err)
+		}
+		var candidates []*models.Component
+		for rows.Next() {
+			candidate, err := scanComponentRow(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning component row: %w", err)
+			}
+			if visible == nil || visible(candidate.ID) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error resolving path %s: %w", cache.FormatPath(names[:i+1]), err)
+		}
+		if found, err = cache.PathStep(candidates, names[:i+1], strict); err != nil {
+			return nil, err
+		}
+		parentID = sql.NullInt64{Int64: found.ID, Valid: true}
+	}
+	if found.Tags, err = tagsOf(dbConn, found.ID); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
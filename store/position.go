@@ -0,0 +1,132 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// siblingOrder is the order of child listings: by position, then as created. Siblings can share
+// a position, such as children left as roots by a deleted parent or created concurrently;
+// reordering numbers them apart.
+var siblingOrder = []ordering{asc(columnPosition), asc(columnCreatedAt), asc(columnID)}
+
+// childrenOf selects the live children of parentID.
+func childrenOf(parentID int64) []predicate {
+	return []predicate{eq(columnParentID, parentID), notDeleted}
+}
+
+// siblingsOf selects the live children of parentID, or the roots when it is not Valid.
+func siblingsOf(parentID sql.NullInt64) []predicate {
+	if !parentID.Valid {
+		return []predicate{isNull(columnParentID), notDeleted}
+	}
+	return childrenOf(parentID.Int64)
+}
+
+// nextPosition returns the position after the last of parentID's children inside tx, leaving out
+// exceptID, the component being appended when it is already among them.
+func nextPosition(tx *sql.Tx, parentID sql.NullInt64, exceptID int64) (int64, error) {
+	query, args := maxFrom(tableComponents, columnPosition).where(siblingsOf(parentID)...).where(notEq(columnID, exceptID)).build()
+	var last sql.NullInt64
+	err := tx.QueryRow(db.Rebind(query), args...).Scan(&last)
+	if err != nil {
+		return 0, fmt.Errorf("error reading sibling positions: %w", err)
+	}
+	if !last.Valid {
+		return 0, nil
+	}
+	return last.Int64 + 1, nil
+}
+
+// appendPosition places id after its new siblings, once it has been moved under parentID.
+func appendPosition(tx *sql.Tx, id int64, parentID sql.NullInt64) error {
+	position, err := nextPosition(tx, parentID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(db.Rebind("UPDATE components SET position = $1 WHERE id = $2"), position, id); err != nil {
+		return fmt.Errorf("error positioning component with ID %d: %w", id, err)
+	}
+	return nil
+}
+
+// ReorderComponent moves a component to index position among its siblings, 0 being the first,
+// shifting the siblings in between by one. A position past the last sibling moves it to the end.
+// The siblings are numbered 0, 1, ... in their new order, and those whose position changed are
+// refreshed in the cache and get an updated event.
+func (s *ComponentStore) ReorderComponent(id int64, position int) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var before *models.Component
+	var changed []int64
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		changed = changed[:0] // fn may run again when the transaction is retried
+		var err error
+		before, err = lockComponentParent(tx, id)
+		if err != nil || before == nil {
+			return err
+		}
+		parentID := normalizeParentID(before.ParentID)
+		query, args := selectFrom(tableComponents, columnID, columnPosition).where(siblingsOf(parentID)...).orderBy(siblingOrder...).forUpdate().build()
+		rows, err := tx.Query(db.Rebind(query), args...)
+		if err != nil {
+			return fmt.Errorf("error listing the siblings of component %d: %w", id, err)
+		}
+		var order []int64
+		positions := make(map[int64]int64)
+		for rows.Next() {
+			var siblingID, siblingPosition int64
+			if err := rows.Scan(&siblingID, &siblingPosition); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning a sibling of component %d: %w", id, err)
+			}
+			positions[siblingID] = siblingPosition
+			if siblingID != id {
+				order = append(order, siblingID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error listing the siblings of component %d: %w", id, err)
+		}
+
+		at := position
+		if at > len(order) {
+			at = len(order)
+		}
+		order = append(order[:at], append([]int64{id}, order[at:]...)...)
+		now := time.Now()
+		for i, siblingID := range order {
+			if positions[siblingID] == int64(i) {
+				continue
+			}
+			if _, err := tx.Exec(db.Rebind("UPDATE components SET position = $1, updated_at = $2, version = version + 1 WHERE id = $3"), i, now, siblingID); err != nil {
+				return fmt.Errorf("error positioning component with ID %d: %w", siblingID, err)
+			}
+			changed = append(changed, siblingID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("component with ID %d not found for reorder", id)
+	}
+
+	afters := s.afterWriteMany(dbConn, changed, "reorder")
+	for _, changedID := range changed {
+		after := afters[changedID]
+		if after == nil {
+			after = &models.Component{ID: changedID, ParentID: before.ParentID}
+		}
+		events.Publish(s.componentEvent(&models.Component{ID: changedID, ParentID: before.ParentID}, after))
+	}
+	return nil
+}
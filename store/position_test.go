@@ -0,0 +1,47 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorderComponent(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	parent := createTestComponent(t, "ReorderParent", "", sql.NullInt64{})
+	under := sql.NullInt64{Int64: parent.ID, Valid: true}
+	a := createTestComponent(t, "A", "", under)
+	b := createTestComponent(t, "B", "", under)
+	c := createTestComponent(t, "C", "", under)
+
+	childOrder := func() []int64 {
+		children, err := testStore.ListChildComponents(parent.ID)
+		require.NoError(t, err)
+		var ids []int64
+		for _, child := range children {
+			ids = append(ids, child.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{a.ID, b.ID, c.ID}, childOrder(), "Expected new children to be appended")
+
+	require.NoError(t, testStore.ReorderComponent(c.ID, 0))
+	assert.Equal(t, []int64{c.ID, a.ID, b.ID}, childOrder())
+	require.NoError(t, testStore.ReorderComponent(c.ID, 99))
+	assert.Equal(t, []int64{a.ID, b.ID, c.ID}, childOrder(), "Expected a position past the end to move it last")
+
+	// A component moved under the parent goes last.
+	other := createTestComponent(t, "Other", "", sql.NullInt64{})
+	require.NoError(t, testStore.MoveComponents([]models.ComponentMove{{ID: other.ID, NewParentID: under}}))
+	require.NoError(t, testStore.ReorderComponent(a.ID, 1))
+	assert.Equal(t, []int64{b.ID, a.ID, c.ID, other.ID}, childOrder())
+
+	assert.Error(t, testStore.ReorderComponent(999999, 0))
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ListPublicComponentIDs returns the components flagged publicly visible, leaving out the
+// soft-deleted ones. It implements
+// cache.PublicSource, so the cache loads them when it is built.
+func (s *ComponentStore) ListPublicComponentIDs() ([]int64, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.Query("SELECT p.component_id FROM public_components p JOIN components c ON c.id = p.component_id WHERE c.deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("error listing public components: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning public component: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating public components: %w", err)
+	}
+	return ids, nil
+}
+
+// SetComponentPublic sets or clears a component's public visibility flag, then updates the cache.
+func (s *ComponentStore) SetComponentPublic(id int64, public bool) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, err := lockComponentParent(tx, id)
+		if err != nil || before == nil {
+			return err
+		}
+		found = true
+		if public {
+			_, err = tx.Exec(db.Rebind("INSERT INTO public_components (component_id, published_at) VALUES ($1, $2)"+
+				db.CurrentDialect.UpsertClause([]string{"component_id"}, nil)), id, time.Now().UTC())
+		} else {
+			_, err = tx.Exec(db.Rebind("DELETE FROM public_components WHERE component_id = $1"), id)
+		}
+		if err != nil {
+			return fmt.Errorf("error changing visibility of component %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("component with ID %d not found", id)
+	}
+	if cache.GlobalComponentCache != nil {
+		cache.GlobalComponentCache.SetPublic(id, public)
+	}
+	return nil
+}
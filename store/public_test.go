@@ -0,0 +1,34 @@
+package store
+
+import (
+	"component-service/db"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetComponentPublic(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	comp := createTestComponent(t, "PublicComp", "", sql.NullInt64{Valid: false})
+
+	// Flagging twice is a no-op rather than a conflict.
+	assert.NoError(t, testStore.SetComponentPublic(comp.ID, true))
+	assert.NoError(t, testStore.SetComponentPublic(comp.ID, true))
+	ids, err := testStore.ListPublicComponentIDs()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{comp.ID}, ids)
+
+	assert.NoError(t, testStore.SetComponentPublic(comp.ID, false))
+	ids, err = testStore.ListPublicComponentIDs()
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	assert.NoError(t, testStore.DeleteComponent(comp.ID))
+	err = testStore.SetComponentPublic(comp.ID, true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
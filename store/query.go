@@ -0,0 +1,254 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The listing queries are assembled by selectQuery from typed parts instead of concatenated
+// strings: identifiers come only from the table and column constants below, and every value,
+// whatever its origin, is bound as an argument. Queries come out with $N placeholders, numbered
+// in order, for db.Rebind to rewrite per dialect.
+
+// table is a table that generated SQL may select from.
+type table string
+
+const tableComponents table = "components"
+
+// column is a column that generated SQL may name. Only the constants below exist, so caller
+// input can never reach a query as an identifier.
+type column string
+
+const (
+	columnID          column = "id"
+	columnName        column = "name"
+	columnSlug        column = "slug"
+	columnType        column = "type"
+	columnStatus      column = "status"
+	columnDescription column = "description"
+	columnMetadata    column = "metadata"
+	columnParentID    column = "parent_id"
+	columnPosition    column = "position"
+	columnVersion     column = "version"
+	columnCreatedAt   column = "created_at"
+	columnUpdatedAt   column = "updated_at"
+	columnDeletedAt   column = "deleted_at"
+)
+
+// componentColumns are the columns scanComponentRow reads, in its order.
+var componentColumns = []column{columnID, columnName, columnSlug, columnType, columnStatus, columnDescription, columnMetadata, columnParentID, columnPosition, columnVersion, columnCreatedAt, columnUpdatedAt}
+
+// predicate is one condition of a WHERE clause. Its SQL holds a ? for each argument, in order,
+// and is only ever assembled by the constructors below.
+type predicate struct {
+	sql  string
+	args []interface{}
+}
+
+// eq selects the rows where col equals value.
+func eq(col column, value interface{}) predicate {
+	return predicate{sql: string(col) + " = ?", args: []interface{}{value}}
+}
+
+// notEq selects the rows where col differs from value.
+func notEq(col column, value interface{}) predicate {
+	return predicate{sql: string(col) + " <> ?", args: []interface{}{value}}
+}
+
+// isNull selects the rows where col is NULL.
+func isNull(col column) predicate {
+	return predicate{sql: string(col) + " IS NULL"}
+}
+
+// containsFold selects the rows where col contains substring, ignoring case. LIKE wildcards in
+// substring match literally.
+func containsFold(col column, substring string) predicate {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(substring)) + "%"
+	return predicate{sql: "LOWER(" + string(col) + ") LIKE ?", args: []interface{}{pattern}}
+}
+
+// hasPrefix selects the rows where col starts with prefix. LIKE wildcards in prefix match
+// literally.
+func hasPrefix(col column, prefix string) predicate {
+	return predicate{sql: string(col) + " LIKE ?", args: []interface{}{likeEscaper.Replace(prefix) + "%"}}
+}
+
+// rowAfter selects the rows whose cols, compared as a row, come after values, as keyset
+// pagination resumes after a cursor.
+func rowAfter(cols []column, values ...interface{}) predicate {
+	names := make([]string, len(cols))
+	markers := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = string(col)
+		markers[i] = "?"
+	}
+	return predicate{sql: "(" + strings.Join(names, ", ") + ") > (" + strings.Join(markers, ", ") + ")", args: values}
+}
+
+// taggedWith selects the components having tag. It names the components table, so queries joining
+// it must not alias it.
+func taggedWith(tag string) predicate {
+	return predicate{sql: "components.id IN (SELECT component_id FROM component_tags WHERE tag = ?)", args: []interface{}{tag}}
+}
+
+// readableBy selects the components principal may read under the ACL entries persisted in
+// component_acl, as cache.ComponentCache.Allows decides it: the entry for principal on the
+// nearest of the component and its ancestors wins, then the nearest everyone entry, and a
+// component no entry applies to is unrestricted. Ancestors come from component_closure, so the
+// check costs two index lookups per row and listings can page in SQL. Like taggedWith, it names
+// the components table.
+func readableBy(principal string) predicate {
+	const nearest = `(SELECT acl.permission FROM component_closure cl JOIN component_acl acl ON acl.component_id = cl.ancestor_id
+		WHERE cl.descendant_id = components.id AND acl.principal = ? ORDER BY cl.depth LIMIT 1)`
+	return predicate{
+		sql: `(NOT EXISTS (SELECT 1 FROM component_closure cl JOIN component_acl acl ON acl.component_id = cl.ancestor_id WHERE cl.descendant_id = components.id)
+		OR COALESCE(` + nearest + `, ` + nearest + `) <> 'none')`,
+		args: []interface{}{principal, cache.EveryonePrincipal},
+	}
+}
+
+// metadataEquals selects the components whose metadata has key holding a string, number or
+// boolean whose text is value, as models.Component.MetadataText reads it.
+func metadataEquals(key, value string) predicate {
+	return dialectPredicate(db.CurrentDialect.JSONTextCondition(string(columnMetadata)), key, value)
+}
+
+var dialectParamPattern = regexp.MustCompile(`\$(\d+)`)
+
+// dialectPredicate turns a condition a dialect wrote with $N placeholders, possibly repeated,
+// into a predicate binding $N to values[N-1] at each use.
+func dialectPredicate(condition string, values ...interface{}) predicate {
+	var args []interface{}
+	sql := dialectParamPattern.ReplaceAllStringFunc(condition, func(param string) string {
+		n, _ := strconv.Atoi(param[1:])
+		args = append(args, values[n-1])
+		return "?"
+	})
+	return predicate{sql: sql, args: args}
+}
+
+// notDeleted selects the components that are not soft-deleted.
+var notDeleted = isNull(columnDeletedAt)
+
+// ordering is one key of an ORDER BY clause.
+type ordering struct {
+	column     column
+	descending bool
+}
+
+// asc orders by col, smallest first.
+func asc(col column) ordering { return ordering{column: col} }
+
+// desc orders by col, largest first.
+func desc(col column) ordering { return ordering{column: col, descending: true} }
+
+// selectQuery is a SELECT on a single table. Its methods add to it and return it, so a query
+// reads as one chain.
+type selectQuery struct {
+	table      table
+	columns    []string
+	predicates []predicate
+	keys       []ordering
+	limit      int
+	offset     int
+	locking    bool
+}
+
+// selectFrom starts a query selecting cols from t.
+func selectFrom(t table, cols ...column) *selectQuery {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = string(col)
+	}
+	return &selectQuery{table: t, columns: names}
+}
+
+// countFrom starts a query counting the rows of t.
+func countFrom(t table) *selectQuery {
+	return &selectQuery{table: t, columns: []string{"COUNT(*)"}}
+}
+
+// maxFrom starts a query selecting the largest value of col in t.
+func maxFrom(t table, col column) *selectQuery {
+	return &selectQuery{table: t, columns: []string{"MAX(" + string(col) + ")"}}
+}
+
+// where adds predicates, all of which a row must satisfy.
+func (q *selectQuery) where(predicates ...predicate) *selectQuery {
+	q.predicates = append(q.predicates, predicates...)
+	return q
+}
+
+// orderBy adds keys to the ORDER BY clause.
+func (q *selectQuery) orderBy(keys ...ordering) *selectQuery {
+	q.keys = append(q.keys, keys...)
+	return q
+}
+
+// page keeps at most limit rows starting at offset. limit <= 0 keeps every row.
+func (q *selectQuery) page(offset, limit int) *selectQuery {
+	q.offset, q.limit = offset, limit
+	return q
+}
+
+// forUpdate locks the selected rows until the transaction ends.
+func (q *selectQuery) forUpdate() *selectQuery {
+	q.locking = true
+	return q
+}
+
+// build renders the query and its arguments, numbering placeholders from $1.
+func (q *selectQuery) build() (string, []interface{}) {
+	var sql strings.Builder
+	sql.WriteString("SELECT " + strings.Join(q.columns, ", ") + " FROM " + string(q.table))
+	where, args := whereClause(q.predicates, 0)
+	sql.WriteString(where)
+	for i, o := range q.keys {
+		if i == 0 {
+			sql.WriteString(" ORDER BY ")
+		} else {
+			sql.WriteString(", ")
+		}
+		direction := "ASC"
+		if o.descending {
+			direction = "DESC"
+		}
+		sql.WriteString(string(o.column) + " " + direction)
+	}
+	if q.limit > 0 {
+		fmt.Fprintf(&sql, " LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, q.limit, q.offset)
+	}
+	if q.locking {
+		sql.WriteString(" FOR UPDATE")
+	}
+	return sql.String(), args
+}
+
+// whereClause renders predicates as a WHERE clause, or "" for none, numbering placeholders after
+// the bound arguments already taken. It serves queries selectQuery cannot express, such as the
+// ones on a recursive CTE.
+func whereClause(predicates []predicate, bound int) (string, []interface{}) {
+	var sql strings.Builder
+	var args []interface{}
+	for i, p := range predicates {
+		if i == 0 {
+			sql.WriteString(" WHERE ")
+		} else {
+			sql.WriteString(" AND ")
+		}
+		parts := strings.Split(p.sql, "?")
+		for j, part := range parts {
+			sql.WriteString(part)
+			if j < len(parts)-1 {
+				args = append(args, p.args[j])
+				fmt.Fprintf(&sql, "$%d", bound+len(args))
+			}
+		}
+	}
+	return sql.String(), args
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"component-service/cache"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectQueryBuild(t *testing.T) {
+	cursor := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		name  string
+		query *selectQuery
+		sql   string
+		args  []interface{}
+	}{
+		{"bare", selectFrom(tableComponents, columnID, columnName),
+			"SELECT id, name FROM components", nil},
+		{"listing page", selectFrom(tableComponents, componentColumns...).where(notDeleted).orderBy(newestFirst...).page(20, 10),
+			"SELECT id, name, slug, type, status, description, metadata, parent_id, position, version, created_at, updated_at FROM components WHERE deleted_at IS NULL ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2",
+			[]interface{}{10, 20}},
+		{"no limit keeps every row", selectFrom(tableComponents, columnID).page(5, 0),
+			"SELECT id FROM components", nil},
+		{"children", countFrom(tableComponents).where(childrenOf(7)...),
+			"SELECT COUNT(*) FROM components WHERE parent_id = $1 AND deleted_at IS NULL", []interface{}{int64(7)}},
+		{"roots", maxFrom(tableComponents, columnPosition).where(siblingsOf(sql.NullInt64{})...).where(notEq(columnID, int64(3))),
+			"SELECT MAX(position) FROM components WHERE parent_id IS NULL AND deleted_at IS NULL AND id <> $1", []interface{}{int64(3)}},
+		{"keyset", selectFrom(tableComponents, columnID).where(eq(columnName, "a"), rowAfter([]column{columnCreatedAt, columnID}, cursor, int64(9))).orderBy(asc(columnCreatedAt), asc(columnID)).page(0, 11),
+			"SELECT id FROM components WHERE name = $1 AND (created_at, id) > ($2, $3) ORDER BY created_at ASC, id ASC LIMIT $4 OFFSET $5",
+			[]interface{}{"a", cursor, int64(9), 11, 0}},
+		{"locked siblings", selectFrom(tableComponents, columnID, columnPosition).where(siblingsOf(sql.NullInt64{Int64: 4, Valid: true})...).orderBy(siblingOrder...).forUpdate(),
+			"SELECT id, position FROM components WHERE parent_id = $1 AND deleted_at IS NULL ORDER BY position ASC, created_at ASC, id ASC FOR UPDATE",
+			[]interface{}{int64(4)}},
+	} {
+		query, args := tc.query.build()
+		assert.Equal(t, tc.sql, query, tc.name)
+		assert.Equal(t, tc.args, args, tc.name)
+	}
+}
+
+func TestSelectQueryBindsValues(t *testing.T) {
+	// Values never reach the SQL text, not even a question mark that would shift the placeholders.
+	hostile := "x' OR '1'='1; DROP TABLE components; --?"
+	query, args := selectFrom(tableComponents, columnID).where(eq(columnName, hostile), containsFold(columnDescription, "?%")).build()
+	assert.Equal(t, "SELECT id FROM components WHERE name = $1 AND LOWER(description) LIKE $2", query)
+	assert.Equal(t, []interface{}{hostile, `%?\%%`}, args)
+}
+
+func TestWhereClause(t *testing.T) {
+	where, args := whereClause(filterPredicates(cache.Filter{Name: "pump", Type: "device"}), 2)
+	assert.Equal(t, " WHERE name = $3 AND type = $4", where)
+	assert.Equal(t, []interface{}{"pump", "device"}, args)
+	where, args = whereClause(nil, 2)
+	assert.Equal(t, "", where)
+	assert.Nil(t, args)
+}
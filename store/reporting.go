@@ -0,0 +1,69 @@
+package store
+
+import (
+	"component-service/db"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// RefreshReporting refreshes the reporting views of the schema file, reporting one unit of work
+// per view. On dialects whose views are always current there is nothing to do.
+func (s *ComponentStore) RefreshReporting(ctx context.Context, report func(done, total int)) error {
+	statements := db.CurrentDialect.ReportingRefreshStatements()
+	report(0, len(statements))
+	if len(statements) == 0 {
+		return nil
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	for i, statement := range statements {
+		if _, err := dbConn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("error refreshing reporting views (%s): %w", statement, err)
+		}
+		report(i+1, len(statements))
+	}
+	return nil
+}
+
+// ReportingRefresher refreshes the reporting views periodically. It is a singleton job: refreshing
+// on every replica would only repeat the same work.
+type ReportingRefresher struct {
+	Store    *ComponentStore
+	Interval time.Duration
+}
+
+// ReportingRefresherFromEnv returns a refresher when REPORTING_REFRESH_INTERVAL is set, or nil
+// when the views are only refreshed on request.
+func ReportingRefresherFromEnv(s *ComponentStore) (*ReportingRefresher, error) {
+	value := os.Getenv("REPORTING_REFRESH_INTERVAL")
+	if value == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid REPORTING_REFRESH_INTERVAL %q", value)
+	}
+	return &ReportingRefresher{Store: s, Interval: interval}, nil
+}
+
+// Run refreshes the views every Interval until ctx is cancelled. A failed refresh is logged and
+// retried on the next tick.
+func (r *ReportingRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Store.RefreshReporting(ctx, func(int, int) {}); err != nil && ctx.Err() == nil {
+				log.Printf("Reporting refresh failed: %v", err)
+			}
+		}
+	}
+}
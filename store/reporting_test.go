@@ -0,0 +1,44 @@
+package store
+
+import (
+	"component-service/db"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshReporting(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	root := createTestComponent(t, "ReportRoot", "", sql.NullInt64{Valid: false})
+	child := createTestComponent(t, "ReportChild", "", sql.NullInt64{Int64: root.ID, Valid: true})
+
+	var reports int
+	err := testStore.RefreshReporting(context.Background(), func(done, total int) { reports++ })
+	assert.NoError(t, err)
+	assert.Equal(t, len(db.CurrentDialect.ReportingRefreshStatements())+1, reports)
+
+	flat, closure := "reporting.component_flat", "reporting.component_closure"
+	if _, ok := db.CurrentDialect.(db.MySQLDialect); ok {
+		flat, closure = "reporting_component_flat", "reporting_component_closure"
+	}
+	var rootID, depth, descendants int64
+	var parentName sql.NullString
+	err = db.DB.QueryRow(db.Rebind("SELECT root_id, depth, parent_name FROM "+flat+" WHERE id = $1"), child.ID).Scan(&rootID, &depth, &parentName)
+	assert.NoError(t, err)
+	assert.Equal(t, root.ID, rootID)
+	assert.Equal(t, int64(1), depth)
+	assert.Equal(t, "ReportRoot", parentName.String)
+	err = db.DB.QueryRow(db.Rebind("SELECT descendant_count FROM "+flat+" WHERE id = $1"), root.ID).Scan(&descendants)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), descendants)
+
+	var pairDepth int64
+	err = db.DB.QueryRow(db.Rebind("SELECT depth FROM "+closure+" WHERE ancestor_id = $1 AND descendant_id = $2"), root.ID, child.ID).Scan(&pairDepth)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), pairDepth)
+}
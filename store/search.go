@@ -0,0 +1,147 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Search backends, as reported by SearchComponents.
+const (
+	SearchBackendDatabase = "database"
+	SearchBackendMemory   = "memory"
+)
+
+// ErrSearchUnavailable is returned when neither the database index nor the cache can serve a search.
+var ErrSearchUnavailable = errors.New("search is unavailable: the database has no full-text index or cannot be reached, and the cache is not initialized")
+
+// SearchComponents returns up to limit components whose name or description contains every word
+// of text, best match first, keeping only those tagged tag unless it is empty. It queries the database's full-text index and falls back to
+// scanning the cache when the dialect has no index or the query fails. backend reports which
+// of the two served the results.
+func (s *ComponentStore) SearchComponents(ctx context.Context, text, tag string, limit int) (results []cache.SearchResult, backend string, err error) {
+	_, search := db.CurrentDialect.FullTextSearchQueries()
+	if search != "" {
+		results, err = searchDatabase(ctx, search, text, tag, limit)
+		if err == nil {
+			return results, SearchBackendDatabase, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		if !errors.Is(err, db.ErrNotInitialized) { // followers have no database and always use the cache
+			log.Printf("Full-text search query failed, falling back to the cache: %v", err)
+		}
+	}
+	if cache.GlobalComponentCache == nil {
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrSearchUnavailable, err)
+		}
+		return nil, "", ErrSearchUnavailable
+	}
+	return cache.GlobalComponentCache.Search(text, tag, limit), SearchBackendMemory, nil
+}
+
+func searchDatabase(ctx context.Context, search, text, tag string, limit int) ([]cache.SearchResult, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.QueryContext(ctx, db.Rebind(search), text, limit, tag)
+	if err != nil {
+		return nil, fmt.Errorf("error searching components: %w", err)
+	}
+	defer rows.Close()
+	results := []cache.SearchResult{}
+	for rows.Next() {
+		component := &models.Component{}
+		var createdAtDb, updatedAtDb time.Time
+		var rank float64
+		if err := rows.Scan(&component.ID, &component.Name, &component.Slug, &component.Type, &component.Status, &component.Description, (*[]byte)(&component.Metadata), &component.ParentID, &component.Position, &component.Version, &createdAtDb, &updatedAtDb, &rank); err != nil {
+			return nil, fmt.Errorf("error scanning search result: %w", err)
+		}
+		component.CreatedAt = createdAtDb.Format(time.RFC3339)
+		component.UpdatedAt = updatedAtDb.Format(time.RFC3339)
+		results = append(results, cache.SearchResult{Rank: rank, Component: component})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+	rows.Close()
+	components := make([]*models.Component, len(results))
+	for i, result := range results {
+		components[i] = result.Component
+	}
+	if err := attachTags(dbConn, components); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// refreshSearchIndex recomputes a component's full-text index entry. It runs inside the
+// transaction that wrote the component, so the index never lags a committed write.
+func refreshSearchIndex(exec sqlExecutor, id int64) error {
+	index, _ := db.CurrentDialect.FullTextSearchQueries()
+	if index == "" {
+		return nil
+	}
+	if _, err := exec.Exec(db.Rebind(index), id, id); err != nil {
+		return fmt.Errorf("error refreshing search index for component %d: %w", id, err)
+	}
+	return nil
+}
+
+// DefaultReindexBatchSize is how many components ReindexSearch refreshes per statement.
+const DefaultReindexBatchSize = 1000
+
+// ReindexSearch recomputes the full-text index entry of every component, in batches of
+// batchSize IDs, one short transaction per batch. Each row keeps its previous entry until its
+// batch commits, so searches keep working throughout. report receives the number of components
+// processed and the total. On dialects without a full-text index there is nothing to rebuild:
+// the in-memory search reads the cache directly.
+func (s *ComponentStore) ReindexSearch(ctx context.Context, batchSize int, report func(done, total int)) error {
+	index, _ := db.CurrentDialect.FullTextSearchQueries()
+	if index == "" {
+		report(0, 0)
+		return nil
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var total int
+	if err := dbConn.QueryRowContext(ctx, "SELECT COUNT(*) FROM components").Scan(&total); err != nil {
+		return fmt.Errorf("error counting components to reindex: %w", err)
+	}
+	report(0, total)
+
+	done := 0
+	var lastID int64
+	for {
+		// The batch is a range of existing IDs, so gaps in the sequence do not shrink it.
+		var first, last sql.NullInt64
+		var count int
+		err := dbConn.QueryRowContext(ctx, db.Rebind(
+			`SELECT MIN(id), MAX(id), COUNT(*) FROM (SELECT id FROM components WHERE id > $1 ORDER BY id LIMIT $2) AS batch`),
+			lastID, batchSize).Scan(&first, &last, &count)
+		if err != nil {
+			return fmt.Errorf("error selecting reindex batch after ID %d: %w", lastID, err)
+		}
+		if count == 0 {
+			break
+		}
+		if _, err := dbConn.ExecContext(ctx, db.Rebind(index), first.Int64, last.Int64); err != nil {
+			return fmt.Errorf("error reindexing components %d to %d: %w", first.Int64, last.Int64, err)
+		}
+		done += count
+		lastID = last.Int64
+		report(done, max(total, done))
+	}
+	return nil
+}
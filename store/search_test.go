@@ -0,0 +1,94 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchTestSource serves a fixed component list to cache.InitGlobalCache.
+type searchTestSource []*models.Component
+
+func (s searchTestSource) ListComponents() ([]*models.Component, error) { return s, nil }
+
+func TestSearchComponentsFallsBackToCache(t *testing.T) {
+	defer func(dialect db.Dialect, c *cache.ComponentCache) {
+		db.CurrentDialect = dialect
+		cache.GlobalComponentCache = c
+	}(db.CurrentDialect, cache.GlobalComponentCache)
+	db.CurrentDialect = db.MySQLDialect{} // no full-text index
+
+	cache.GlobalComponentCache = nil
+	_, _, err := testStore.SearchComponents(context.Background(), "pump", "", 10)
+	assert.ErrorIs(t, err, ErrSearchUnavailable)
+
+	assert.NoError(t, cache.InitGlobalCache(searchTestSource{
+		{ID: 1, Name: "Pump", ParentID: sql.NullInt64{}},
+		{ID: 2, Name: "Valve", ParentID: sql.NullInt64{}},
+	}))
+	results, backend, err := testStore.SearchComponents(context.Background(), "pump", "", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, SearchBackendMemory, backend)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, int64(1), results[0].Component.ID)
+	}
+}
+
+func TestSearchComponentsDatabase(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	if index, _ := db.CurrentDialect.FullTextSearchQueries(); index == "" {
+		t.Skipf("Skipping test: %s has no full-text index", db.CurrentDialect.Name())
+	}
+	clearComponentsTableForTest()
+	createTestComponent(t, "Valve", "Controls the hydraulic pump", sql.NullInt64{Valid: false})
+	pump := createTestComponent(t, "Hydraulic pump", "Main unit", sql.NullInt64{Valid: false})
+	createTestComponent(t, "Housing", "Cast iron", sql.NullInt64{Valid: false})
+
+	results, backend, err := testStore.SearchComponents(context.Background(), "hydraulic pump", "", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, SearchBackendDatabase, backend)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, pump.ID, results[0].Component.ID, "A name match ranks first")
+	}
+
+	// The index follows updates.
+	pump.Name = "Gearbox"
+	pump.Description = "Main unit"
+	assert.NoError(t, testStore.UpdateComponent(pump.ID, pump))
+	results, _, err = testStore.SearchComponents(context.Background(), "gearbox", "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestReindexSearch(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	index, _ := db.CurrentDialect.FullTextSearchQueries()
+	if index == "" {
+		t.Skipf("Skipping test: %s has no full-text index", db.CurrentDialect.Name())
+	}
+	clearComponentsTableForTest()
+	for _, name := range []string{"Gear one", "Gear two", "Gear three"} {
+		createTestComponent(t, name, "", sql.NullInt64{Valid: false})
+	}
+	// Simulate rows written outside the service.
+	_, err := db.DB.Exec("UPDATE components SET search_vector = NULL")
+	assert.NoError(t, err)
+	results, _, _ := testStore.SearchComponents(context.Background(), "gear", "", 10)
+	assert.Empty(t, results)
+
+	var reports [][2]int
+	err = testStore.ReindexSearch(context.Background(), 2, func(done, total int) { reports = append(reports, [2]int{done, total}) })
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int{{0, 3}, {2, 3}, {3, 3}}, reports)
+	results, _, _ = testStore.SearchComponents(context.Background(), "gear", "", 10)
+	assert.Len(t, results, 3)
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"component-service/db"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ShareLink grants read-only access to the subtree rooted at a component to anyone holding its
+// token. Only a hash of the token is stored, so the token is shown once, when the link is created.
+type ShareLink struct {
+	ID          int64      `json:"id"`
+	ComponentID int64      `json:"component_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"` // nil for a link that does not expire
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+// Active reports whether the link still grants access at now.
+func (l *ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
+
+// shareTokenHash returns the stored form of a token.
+func shareTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateShareLink creates a link to the subtree rooted at componentID, expiring at expiresAt
+// unless it is nil, and returns it with its token.
+func (s *ComponentStore) CreateShareLink(componentID int64, expiresAt *time.Time) (*ShareLink, string, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, "", err
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("error generating share token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	link := &ShareLink{ComponentID: componentID, CreatedAt: time.Now().UTC(), ExpiresAt: expiresAt}
+
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, err := lockComponentParent(tx, componentID)
+		if err != nil || before == nil {
+			return err
+		}
+		found = true
+		link.ID, err = insertReturningID(tx,
+			"INSERT INTO share_links (component_id, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4)",
+			componentID, shareTokenHash(token), link.CreatedAt, expiresAt)
+		if err != nil {
+			return fmt.Errorf("error creating share link for component %d: %w", componentID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if !found {
+		return nil, "", fmt.Errorf("component with ID %d not found", componentID)
+	}
+	return link, token, nil
+}
+
+const shareLinkColumns = "id, component_id, created_at, expires_at, revoked_at"
+
+func scanShareLink(row interface{ Scan(...interface{}) error }) (*ShareLink, error) {
+	link := &ShareLink{}
+	var expiresAt, revokedAt sql.NullTime
+	if err := row.Scan(&link.ID, &link.ComponentID, &link.CreatedAt, &expiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		link.RevokedAt = &revokedAt.Time
+	}
+	return link, nil
+}
+
+// ShareLinkByToken returns the link a token belongs to, whether active or not, or nil when the
+// token is unknown.
+func (s *ComponentStore) ShareLinkByToken(token string) (*ShareLink, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	row := dbConn.QueryRow(db.Rebind("SELECT "+shareLinkColumns+" FROM share_links WHERE token_hash = $1"), shareTokenHash(token))
+	link, err := scanShareLink(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up share link: %w", err)
+	}
+	return link, nil
+}
+
+// ListShareLinks returns the links created for a component, newest first, without their tokens.
+func (s *ComponentStore) ListShareLinks(componentID int64) ([]*ShareLink, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.Query(db.Rebind("SELECT "+shareLinkColumns+" FROM share_links WHERE component_id = $1 ORDER BY created_at DESC, id DESC"), componentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing share links of component %d: %w", componentID, err)
+	}
+	defer rows.Close()
+	links := []*ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning share link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating share links: %w", err)
+	}
+	return links, nil
+}
+
+// RevokeShareLink revokes a component's link, effective immediately. Revoking a revoked link
+// keeps its original revocation time.
+func (s *ComponentStore) RevokeShareLink(componentID, linkID int64) (*ShareLink, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	_, err = dbConn.Exec(db.Rebind("UPDATE share_links SET revoked_at = $1 WHERE id = $2 AND component_id = $3 AND revoked_at IS NULL"),
+		time.Now().UTC(), linkID, componentID)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking share link %d: %w", linkID, err)
+	}
+	row := dbConn.QueryRow(db.Rebind("SELECT "+shareLinkColumns+" FROM share_links WHERE id = $1 AND component_id = $2"), linkID, componentID)
+	link, err := scanShareLink(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("share link %d of component %d not found", linkID, componentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading share link %d: %w", linkID, err)
+	}
+	return link, nil
+}
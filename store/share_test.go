@@ -0,0 +1,56 @@
+package store
+
+import (
+	"component-service/db"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareLinkActive(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	assert.True(t, (&ShareLink{}).Active(now))
+	assert.True(t, (&ShareLink{ExpiresAt: &later}).Active(now))
+	assert.False(t, (&ShareLink{ExpiresAt: &earlier}).Active(now))
+	assert.False(t, (&ShareLink{RevokedAt: &earlier}).Active(now))
+}
+
+func TestShareLinks(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	comp := createTestComponent(t, "Shared", "", sql.NullInt64{Valid: false})
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	link, token, err := testStore.CreateShareLink(comp.ID, &expiresAt)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	found, err := testStore.ShareLinkByToken(token)
+	assert.NoError(t, err)
+	if assert.NotNil(t, found) {
+		assert.Equal(t, link.ID, found.ID)
+		assert.True(t, found.Active(time.Now()))
+	}
+	missing, err := testStore.ShareLinkByToken("not-a-token")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	revoked, err := testStore.RevokeShareLink(comp.ID, link.ID)
+	assert.NoError(t, err)
+	assert.False(t, revoked.Active(time.Now()))
+	links, err := testStore.ListShareLinks(comp.ID)
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+
+	_, err = testStore.RevokeShareLink(comp.ID, link.ID+1000)
+	assert.Error(t, err)
+	_, _, err = testStore.CreateShareLink(88888, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrDuplicateName is returned, while UniqueSiblingNames is on, by a write that would give a
+// component the name of a live sibling.
+var ErrDuplicateName = errors.New("duplicate sibling name")
+
+// UniqueSiblingNames makes CreateComponent, UpdateComponent, PatchComponent and MergeComponent
+// refuse a name a live sibling already has, roots being siblings of each other. Soft-deleted components do not
+// count. The check runs inside the write's transaction; only the optional unique index of the
+// schema files also stops two concurrent writes, and writes made around the service.
+var UniqueSiblingNames bool
+
+// UniqueSiblingNamesFromEnv reads UNIQUE_SIBLING_NAMES, a boolean that defaults to false.
+func UniqueSiblingNamesFromEnv() (bool, error) {
+	value := os.Getenv("UNIQUE_SIBLING_NAMES")
+	if value == "" {
+		return false, nil
+	}
+	unique, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid UNIQUE_SIBLING_NAMES %q: expected true or false", value)
+	}
+	return unique, nil
+}
+
+// checkSiblingName fails with ErrDuplicateName inside tx when a live child of parentID other
+// than id, or a live root when parentID is not Valid, is named name. Without UniqueSiblingNames
+// it does nothing.
+func checkSiblingName(tx *sql.Tx, id int64, parentID sql.NullInt64, name string) error {
+	if !UniqueSiblingNames {
+		return nil
+	}
+	query, args := selectFrom(tableComponents, columnID).where(siblingsOf(parentID)...).where(eq(columnName, name), notEq(columnID, id)).page(0, 1).build()
+	var existingID int64
+	err := tx.QueryRow(db.Rebind(query), args...).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading siblings named %q: %w", name, err)
+	}
+	return duplicateNameError(parentID, name)
+}
+
+// duplicateNameError describes a name already taken under parentID.
+func duplicateNameError(parentID sql.NullInt64, name string) error {
+	if !parentID.Valid {
+		return fmt.Errorf("%w: a root component is already named %q", ErrDuplicateName, name)
+	}
+	return fmt.Errorf("%w: component %d already has a child named %q", ErrDuplicateName, parentID.Int64, name)
+}
+
+// asDuplicateName turns a unique violation of a write that checked name under parentID into
+// ErrDuplicateName: a concurrent write took the name after the check, and the index refused the
+// second. Other errors are returned as they are.
+func asDuplicateName(err error, parentID sql.NullInt64, name string) error {
+	if UniqueSiblingNames && db.IsUniqueViolation(err) {
+		return duplicateNameError(parentID, name)
+	}
+	return err
+}
+
+// patchedName returns the name id has once patch is applied, reading the current one inside tx
+// when the patch leaves it alone.
+func patchedName(tx *sql.Tx, id int64, patch models.ComponentPatch) (string, error) {
+	if patch.Name != nil {
+		return *patch.Name, nil
+	}
+	var name string
+	if err := tx.QueryRow(db.Rebind("SELECT name FROM components WHERE id = $1"), id).Scan(&name); err != nil {
+		return "", fmt.Errorf("error reading name of component with ID %d: %w", id, err)
+	}
+	return name, nil
+}
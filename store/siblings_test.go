@@ -0,0 +1,59 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueSiblingNamesFromEnv(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "false": false, "true": true, "1": true} {
+		t.Setenv("UNIQUE_SIBLING_NAMES", value)
+		unique, err := UniqueSiblingNamesFromEnv()
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, unique, value)
+	}
+	t.Setenv("UNIQUE_SIBLING_NAMES", "sometimes")
+	_, err := UniqueSiblingNamesFromEnv()
+	assert.ErrorContains(t, err, "invalid UNIQUE_SIBLING_NAMES")
+}
+
+func TestUniqueSiblingNames(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	UniqueSiblingNames = true
+	defer func() { UniqueSiblingNames = false }()
+
+	plant := createTestComponent(t, "Plant", "", sql.NullInt64{})
+	under := sql.NullInt64{Int64: plant.ID, Valid: true}
+	pump := createTestComponent(t, "Pump", "", under)
+	valve := createTestComponent(t, "Valve", "", under)
+
+	_, err := testStore.CreateComponent(&models.Component{Name: "Pump", ParentID: under})
+	assert.ErrorIs(t, err, ErrDuplicateName)
+	_, err = testStore.CreateComponent(&models.Component{Name: "Plant"})
+	assert.ErrorIs(t, err, ErrDuplicateName, "roots are siblings too")
+	createTestComponent(t, "Pump", "", sql.NullInt64{}) // the same name elsewhere is fine
+
+	err = testStore.UpdateComponent(valve.ID, &models.Component{Name: "Pump", ParentID: under})
+	assert.ErrorIs(t, err, ErrDuplicateName)
+	assert.NoError(t, testStore.UpdateComponent(pump.ID, &models.Component{Name: "Pump", Description: "kept name", ParentID: under}))
+
+	name := "Pump"
+	assert.ErrorIs(t, testStore.PatchComponent(valve.ID, models.ComponentPatch{Name: &name}), ErrDuplicateName)
+	root := sql.NullInt64{}
+	assert.ErrorIs(t, testStore.PatchComponent(pump.ID, models.ComponentPatch{ParentID: &root}), ErrDuplicateName,
+		"a move checks the name under the new parent")
+	description := "no name change"
+	assert.NoError(t, testStore.PatchComponent(valve.ID, models.ComponentPatch{Description: &description}))
+
+	// A soft-deleted component frees its name.
+	require.NoError(t, testStore.DeleteComponent(valve.ID))
+	createTestComponent(t, "Valve", "", under)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"fmt"
+)
+
+// maxSlugAttempts bounds how many times a write that picks slugs runs again after a concurrent
+// one claimed the same slug first.
+const maxSlugAttempts = 5
+
+// freeSlug picks the slug of a new component inside tx: base, or the first of base-2, base-3, ...
+// that no component holds. Soft-deleted components keep their slugs, so a restore gets its own
+// back; only a purge frees one.
+func freeSlug(tx *sql.Tx, base string) (string, error) {
+	query, args := selectFrom(tableComponents, columnSlug).where(hasPrefix(columnSlug, base)).build()
+	rows, err := tx.Query(db.Rebind(query), args...)
+	if err != nil {
+		return "", fmt.Errorf("error reading slugs taken by %q: %w", base, err)
+	}
+	defer rows.Close()
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", fmt.Errorf("error scanning slug: %w", err)
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error reading slugs taken by %q: %w", base, err)
+	}
+	return models.FreeSlug(base, func(slug string) bool { return taken[slug] }), nil
+}
+
+// executeTxClaimingSlugs runs fn in a transaction as db.ExecuteTx does, and runs it again when it
+// loses a slug to a concurrent transaction: the unique index refuses the second insert, and the
+// next attempt sees the slug as taken.
+func executeTxClaimingSlugs(dbConn *sql.DB, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxSlugAttempts; attempt++ {
+		if err = db.ExecuteTx(dbConn, fn); !db.IsUniqueViolation(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// GetComponentBySlug retrieves a component by its slug, from the cache when it is initialized.
+func (s *ComponentStore) GetComponentBySlug(slug string) (*models.Component, error) {
+	if c := s.cached(); c != nil {
+		if component, found := c.GetBySlug(slug); found {
+			return component, nil
+		}
+		return nil, fmt.Errorf("component with slug %q not found", slug)
+	}
+
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	query, args := selectFrom(tableComponents, componentColumns...).where(eq(columnSlug, slug), notDeleted).build()
+	rows, err := dbConn.Query(db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting component by slug %q: %w", slug, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error getting component by slug %q: %w", slug, err)
+		}
+		return nil, fmt.Errorf("component with slug %q not found", slug)
+	}
+	component, err := scanComponentRow(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning component row: %w", err)
+	}
+	rows.Close()
+	if component.Tags, err = tagsOf(dbConn, component.ID); err != nil {
+		return nil, err
+	}
+	return component, nil
+}
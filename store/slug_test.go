@@ -0,0 +1,62 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentSlugs(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	slugOf := func(id int64) string {
+		t.Helper()
+		comp, err := testStore.GetComponentByID(id)
+		require.NoError(t, err)
+		return comp.Slug
+	}
+	first := createTestComponent(t, "Feed Pump", "", sql.NullInt64{})
+	second := createTestComponent(t, "feed pump!", "", sql.NullInt64{})
+	assert.Equal(t, "feed-pump", slugOf(first.ID))
+	assert.Equal(t, "feed-pump-2", slugOf(second.ID))
+
+	found, err := testStore.GetComponentBySlug("feed-pump-2")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, found.ID)
+	_, err = testStore.GetComponentBySlug("missing")
+	assert.ErrorContains(t, err, "not found")
+
+	// A rename keeps the slug, so links to it keep working.
+	renamed := "Booster"
+	require.NoError(t, testStore.PatchComponent(first.ID, models.ComponentPatch{Name: &renamed}))
+	assert.Equal(t, "feed-pump", slugOf(first.ID))
+
+	// A soft-deleted component keeps its slug for its restore; only a purge frees it.
+	require.NoError(t, testStore.DeleteComponent(second.ID))
+	_, err = testStore.GetComponentBySlug("feed-pump-2")
+	assert.ErrorContains(t, err, "not found")
+	third := createTestComponent(t, "Feed pump", "", sql.NullInt64{})
+	assert.Equal(t, "feed-pump-3", slugOf(third.ID))
+	require.NoError(t, testStore.RestoreComponent(second.ID, nil, nil))
+	assert.Equal(t, "feed-pump-2", slugOf(second.ID))
+	require.NoError(t, testStore.PurgeComponent(second.ID))
+	fourth := createTestComponent(t, "Feed pump", "", sql.NullInt64{})
+	assert.Equal(t, "feed-pump-2", slugOf(fourth.ID))
+
+	// Imports keep the source's slug when it is free here.
+	mappings, err := testStore.ImportComponents("slugs", []*models.Component{
+		{ID: 1, Name: "Valve", Slug: "inlet-valve"},
+		{ID: 2, Name: "Valve", Slug: "feed-pump"},
+		{ID: 3, Name: "Valve", Slug: "Not A Slug"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "inlet-valve", slugOf(mappings[0].ID))
+	assert.Equal(t, "feed-pump-4", slugOf(mappings[1].ID))
+	assert.Equal(t, "valve", slugOf(mappings[2].ID))
+}
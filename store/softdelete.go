@@ -0,0 +1,223 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotDeleted is returned when restoring a component that is not soft-deleted.
+var ErrNotDeleted = errors.New("component is not deleted")
+
+// ErrRestoreNotPermitted is returned when canRestore refuses a component's former ACL, or canAttach
+// the parent it would be restored under.
+var ErrRestoreNotPermitted = errors.New("restore not permitted")
+
+// softDeleteRow stamps id as deleted inside tx, once its subtree has been detached from its
+// ancestors' closure. Its children become roots as they would when its row is purged, while the
+// component keeps its own parent_id for a restore.
+func softDeleteRow(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec(db.Rebind("UPDATE components SET parent_id = NULL, version = version + 1 WHERE parent_id = $1 AND deleted_at IS NULL"), id); err != nil {
+		return fmt.Errorf("error detaching the children of component %d: %w", id, err)
+	}
+	if _, err := tx.Exec(db.Rebind("DELETE FROM component_closure WHERE ancestor_id = $1 OR descendant_id = $2"), id, id); err != nil {
+		return fmt.Errorf("error removing component %d from the closure table: %w", id, err)
+	}
+	if _, err := tx.Exec(db.Rebind("UPDATE components SET deleted_at = $1 WHERE id = $2"), time.Now(), id); err != nil {
+		return fmt.Errorf("error deleting component with ID %d: %w", id, err)
+	}
+	return nil
+}
+
+// purgeRow deletes id's row inside tx, with its attachments' rows, and returns the storage keys
+// of their data for deleteAttachmentData once tx commits.
+func purgeRow(tx *sql.Tx, id int64) ([]string, error) {
+	keys, err := attachmentKeys(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(db.Rebind("DELETE FROM components WHERE id = $1"), id); err != nil {
+		return nil, fmt.Errorf("error deleting component with ID %d: %w", id, err)
+	}
+	return keys, nil
+}
+
+// lockDeletedComponent locks a soft-deleted component's row inside tx, reporting whether there is
+// one.
+func lockDeletedComponent(tx *sql.Tx, id int64) (bool, error) {
+	var found int64
+	err := tx.QueryRow(db.Rebind("SELECT id FROM components WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE"), id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading component with ID %d: %w", id, err)
+	}
+	return true, nil
+}
+
+// RestoreComponent brings back a soft-deleted component, with its ACL entries, share links and
+// visibility flag, and publishes a created event. It returns under its former parent, after its
+// siblings, unless that parent is gone, in which case it becomes a root. Children it had when it
+// was deleted stay where they are. A component that is not deleted fails with ErrNotDeleted.
+// canRestore, when not nil, is asked with the effective ACL the component had when it was deleted,
+// which the cache no longer holds, and canAttach with the parent; refusing fails with
+// ErrRestoreNotPermitted.
+func (s *ComponentStore) RestoreComponent(id int64, canAttach func(parentID int64) bool, canRestore func(acl []cache.ACLEntry) bool) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var found bool
+	var parentID sql.NullInt64
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		var deletedAt sql.NullTime
+		err := tx.QueryRow(db.Rebind("SELECT parent_id, deleted_at FROM components WHERE id = $1 FOR UPDATE"), id).Scan(&parentID, &deletedAt)
+		if err == sql.ErrNoRows {
+			found = false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading component with ID %d: %w", id, err)
+		}
+		found = true
+		if !deletedAt.Valid {
+			return fmt.Errorf("%w: component with ID %d", ErrNotDeleted, id)
+		}
+		if canRestore != nil {
+			acl, err := formerACL(tx, id)
+			if err != nil {
+				return err
+			}
+			if !canRestore(acl) {
+				return fmt.Errorf("%w: restoring component %d requires write permission on it", ErrRestoreNotPermitted, id)
+			}
+		}
+		if parentID.Valid {
+			if parent, err := lockComponentParent(tx, parentID.Int64); err != nil {
+				return err
+			} else if parent == nil {
+				parentID = sql.NullInt64{}
+			}
+		}
+		if parentID.Valid && canAttach != nil && !canAttach(parentID.Int64) {
+			return fmt.Errorf("%w: restoring component %d under component %d requires write permission on it", ErrRestoreNotPermitted, id, parentID.Int64)
+		}
+		position, err := nextPosition(tx, parentID, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(db.Rebind("UPDATE components SET deleted_at = NULL, parent_id = $1, position = $2, updated_at = $3, version = version + 1 WHERE id = $4"),
+			parentID, position, time.Now(), id)
+		if err != nil {
+			return fmt.Errorf("error restoring component with ID %d: %w", id, err)
+		}
+		return insertClosure(tx, id, parentID)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("component with ID %d not found for restore", id)
+	}
+
+	after := s.afterWrite(dbConn, id, "restore")
+	if after == nil {
+		after = &models.Component{ID: id, ParentID: parentID}
+	}
+	if cache.GlobalComponentCache != nil {
+		if err := s.restoreCachedAccess(dbConn, id); err != nil {
+			fmt.Printf("Error reloading the ACL and visibility of component %d after restore: %v\n", id, err)
+		}
+	}
+	events.Publish(s.componentEvent(nil, after))
+	return nil
+}
+
+// formerACL returns the effective ACL of a soft-deleted component, read inside tx: its own entries
+// and those it inherited along its parent_id chain, the nearest entry of each principal winning,
+// as the cache compiles them for live components.
+func formerACL(tx *sql.Tx, id int64) ([]cache.ACLEntry, error) {
+	effective := make(map[string]cache.ACLEntry)
+	visited := make(map[int64]bool)
+	for current := (sql.NullInt64{Int64: id, Valid: true}); current.Valid && !visited[current.Int64]; {
+		visited[current.Int64] = true // stops at a parent cycle
+		rows, err := tx.Query(db.Rebind("SELECT principal, permission FROM component_acl WHERE component_id = $1"), current.Int64)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the ACL of component %d: %w", current.Int64, err)
+		}
+		for rows.Next() {
+			entry := cache.ACLEntry{ComponentID: current.Int64}
+			var permission string
+			if err := rows.Scan(&entry.Principal, &permission); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning the ACL of component %d: %w", current.Int64, err)
+			}
+			if entry.Permission, err = cache.ParsePermission(permission); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if _, nearer := effective[entry.Principal]; !nearer {
+				effective[entry.Principal] = entry
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error reading the ACL of component %d: %w", current.Int64, err)
+		}
+		// Deleted ancestors count too: they are what the component inherited from when it was deleted
+		err = tx.QueryRow(db.Rebind("SELECT parent_id FROM components WHERE id = $1"), current.Int64).Scan(&current)
+		if err == sql.ErrNoRows {
+			break // purged
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading component with ID %d: %w", current.Int64, err)
+		}
+	}
+	acl := make([]cache.ACLEntry, 0, len(effective))
+	for _, entry := range effective {
+		acl = append(acl, entry)
+	}
+	return acl, nil
+}
+
+// restoreCachedAccess puts a restored component's ACL entries and visibility flag, which the cache
+// dropped along with the component, back into the cache.
+func (s *ComponentStore) restoreCachedAccess(dbConn *sql.DB, id int64) error {
+	rows, err := dbConn.Query(db.Rebind("SELECT principal, permission FROM component_acl WHERE component_id = $1"), id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var entries []cache.ACLEntry
+	for rows.Next() {
+		var entry cache.ACLEntry
+		var permission string
+		if err := rows.Scan(&entry.Principal, &permission); err != nil {
+			return err
+		}
+		if entry.Permission, err = cache.ParsePermission(permission); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	var flagged int
+	if err := dbConn.QueryRow(db.Rebind("SELECT COUNT(*) FROM public_components WHERE component_id = $1"), id).Scan(&flagged); err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		cache.GlobalComponentCache.SetACL(id, entries)
+	}
+	if flagged > 0 {
+		cache.GlobalComponentCache.SetPublic(id, true)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/fixtures"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	parent := createTestComponent(t, "SoftParent", "", sql.NullInt64{})
+	comp := createTestComponent(t, "SoftDeleted", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+	child := createTestComponent(t, "SoftChild", "", sql.NullInt64{Int64: comp.ID, Valid: true})
+	require.NoError(t, testStore.ReplaceComponentACL(comp.ID, []cache.ACLEntry{{Principal: "alice", Permission: cache.PermissionRead}}))
+
+	require.NoError(t, testStore.DeleteComponent(comp.ID))
+	_, err := testStore.GetComponentByID(comp.ID)
+	assert.ErrorContains(t, err, "not found")
+	children, err := testStore.ListChildComponents(parent.ID)
+	require.NoError(t, err)
+	assert.Empty(t, children, "Expected the soft-deleted component to leave child listings")
+	orphan, err := testStore.GetComponentByID(child.ID)
+	require.NoError(t, err)
+	assert.False(t, orphan.ParentID.Valid, "Expected the child to become a root")
+	entries, err := testStore.ListACLEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.ErrorContains(t, testStore.DeleteComponent(comp.ID), "not found")
+	check, err := testStore.CheckClosure(context.Background())
+	require.NoError(t, err)
+	assert.True(t, check.Consistent)
+
+	// A restore returns it under its parent, with its ACL, but not its former children.
+	assert.ErrorIs(t, testStore.RestoreComponent(comp.ID, func(int64) bool { return false }, nil), ErrRestoreNotPermitted)
+	var former []cache.ACLEntry
+	refuse := func(acl []cache.ACLEntry) bool { former = acl; return false }
+	assert.ErrorIs(t, testStore.RestoreComponent(comp.ID, nil, refuse), ErrRestoreNotPermitted)
+	assert.Equal(t, []cache.ACLEntry{{ComponentID: comp.ID, Principal: "alice", Permission: cache.PermissionRead}}, former, "Expected the ACL the component had when deleted")
+	require.NoError(t, testStore.RestoreComponent(comp.ID, nil, nil))
+	restored, err := testStore.GetComponentByID(comp.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullInt64{Int64: parent.ID, Valid: true}, restored.ParentID)
+	entries, err = testStore.ListACLEntries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	orphan, err = testStore.GetComponentByID(child.ID)
+	require.NoError(t, err)
+	assert.False(t, orphan.ParentID.Valid)
+	assert.ErrorIs(t, testStore.RestoreComponent(comp.ID, nil, nil), ErrNotDeleted)
+	check, err = testStore.CheckClosure(context.Background())
+	require.NoError(t, err)
+	assert.True(t, check.Consistent)
+
+	// Purging works on soft-deleted components too, and cannot be undone.
+	require.NoError(t, testStore.DeleteComponent(comp.ID))
+	require.NoError(t, testStore.PurgeComponent(comp.ID))
+	assert.ErrorContains(t, testStore.RestoreComponent(comp.ID, nil, nil), "not found")
+	assert.ErrorContains(t, testStore.PurgeComponent(comp.ID), "not found")
+}
+
+func TestDeleteSubtree(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	w := fixtures.MustLoad(t, testStore, "plant")
+
+	refused := func(id int64) bool { return id != w.ID("valve") }
+	assert.ErrorIs(t, testStore.DeleteSubtree(w.ID("line-a"), refused), ErrDeleteNotPermitted)
+	_, err := testStore.GetComponentByID(w.ID("valve"))
+	require.NoError(t, err, "Expected a refused cascade to delete nothing")
+
+	require.NoError(t, testStore.DeleteSubtree(w.ID("line-a"), nil))
+	for _, key := range []string{"line-a", "pump-a", "valve"} {
+		_, err := testStore.GetComponentByID(w.ID(key))
+		assert.ErrorContains(t, err, "not found", key)
+	}
+	all, err := testStore.ListComponents()
+	require.NoError(t, err)
+	assert.Len(t, all, 4, "Expected no component to become a root")
+	check, err := testStore.CheckClosure(context.Background())
+	require.NoError(t, err)
+	assert.True(t, check.Consistent)
+	assert.ErrorContains(t, testStore.DeleteSubtree(w.ID("line-a"), nil), "not found")
+
+	// Restoring from the top down rebuilds the subtree.
+	for _, key := range []string{"line-a", "pump-a", "valve"} {
+		require.NoError(t, testStore.RestoreComponent(w.ID(key), nil, nil))
+	}
+	restored, err := testStore.GetComponentByID(w.ID("valve"))
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullInt64{Int64: w.ID("line-a"), Valid: true}, restored.ParentID)
+	check, err = testStore.CheckClosure(context.Background())
+	require.NoError(t, err)
+	assert.True(t, check.Consistent)
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidStatus is returned by a write giving a component a status ComponentStatuses lacks.
+var ErrInvalidStatus = errors.New("invalid status")
+
+// ErrIllegalTransition is returned by SetComponentStatus for a transition ComponentStatuses does
+// not allow from the component's current status.
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// ComponentStatuses is the lifecycle component statuses follow: the statuses they may have, and
+// the transitions allowed between them. main sets it from the environment with
+// ComponentStatusesFromEnv.
+var ComponentStatuses = mustParseStatusGraph(models.DefaultStatusTransitions)
+
+func mustParseStatusGraph(spec string) *models.StatusGraph {
+	g, err := models.ParseStatusGraph(spec)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// ComponentStatusesFromEnv reads STATUS_TRANSITIONS, comma-separated chains of statuses joined by
+// ">", such as "draft>active>retired,retired>active". Unset, it returns the default lifecycle,
+// models.DefaultStatusTransitions.
+func ComponentStatusesFromEnv() (*models.StatusGraph, error) {
+	value := os.Getenv("STATUS_TRANSITIONS")
+	if value == "" {
+		value = models.DefaultStatusTransitions
+	}
+	g, err := models.ParseStatusGraph(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STATUS_TRANSITIONS: %w", err)
+	}
+	return g, nil
+}
+
+// checkStatus returns the status a component is created with: status, or the initial one when
+// empty. A status the lifecycle lacks fails with ErrInvalidStatus.
+func checkStatus(status string) (string, error) {
+	if status == "" {
+		return ComponentStatuses.Initial(), nil
+	}
+	if !ComponentStatuses.Has(status) {
+		return "", invalidStatus(status)
+	}
+	return status, nil
+}
+
+// invalidStatus returns the ErrInvalidStatus for status, listing the lifecycle's statuses.
+func invalidStatus(status string) error {
+	return fmt.Errorf("%w %q: expected one of %s", ErrInvalidStatus, status, strings.Join(ComponentStatuses.Names(), ", "))
+}
+
+// SetComponentStatus moves a live component to status, refreshes the cache and publishes an
+// updated event. The transition is checked against ComponentStatuses with the row locked, so
+// concurrent transitions cannot both pass from the same status: a status the lifecycle lacks fails
+// with ErrInvalidStatus, and a transition it does not allow from the current status with
+// ErrIllegalTransition. Setting the current status again changes nothing and succeeds.
+func (s *ComponentStore) SetComponentStatus(id int64, status string) error {
+	if !ComponentStatuses.Has(status) {
+		return invalidStatus(status)
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	var before *models.Component
+	var changed bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, changed = nil, false
+		current := &models.Component{ID: id}
+		err := tx.QueryRow(db.Rebind("SELECT status, parent_id FROM components WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"), id).Scan(&current.Status, &current.ParentID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading component with ID %d: %w", id, err)
+		}
+		before = current
+		if current.Status == status {
+			return nil
+		}
+		if !ComponentStatuses.Allows(current.Status, status) {
+			next := ComponentStatuses.Next(current.Status)
+			allowed := "none"
+			if len(next) > 0 {
+				allowed = strings.Join(next, ", ")
+			}
+			return fmt.Errorf("%w: component %d cannot move from %s to %s; allowed: %s", ErrIllegalTransition, id, current.Status, status, allowed)
+		}
+		if _, err := tx.Exec(db.Rebind("UPDATE components SET status = $1, updated_at = $2, version = version + 1 WHERE id = $3"), status, time.Now(), id); err != nil {
+			return fmt.Errorf("error setting the status of component with ID %d: %w", id, err)
+		}
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("component with ID %d not found", id)
+	}
+	if !changed {
+		return nil
+	}
+
+	after := s.afterWrite(dbConn, id, "status change")
+	if after == nil {
+		after = &models.Component{ID: id, Status: status, ParentID: before.ParentID}
+	}
+	events.Publish(s.componentEvent(before, after))
+	return nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/events"
+	"component-service/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentStatusesFromEnv(t *testing.T) {
+	t.Setenv("STATUS_TRANSITIONS", "")
+	g, err := ComponentStatusesFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"draft", "active", "deprecated", "retired"}, g.Names())
+
+	t.Setenv("STATUS_TRANSITIONS", "proposed>approved, approved>proposed")
+	g, err = ComponentStatusesFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "proposed", g.Initial())
+
+	t.Setenv("STATUS_TRANSITIONS", "draft>Active")
+	_, err = ComponentStatusesFromEnv()
+	assert.ErrorContains(t, err, "invalid STATUS_TRANSITIONS")
+}
+
+func TestCheckStatus(t *testing.T) {
+	status, err := checkStatus("")
+	assert.NoError(t, err)
+	assert.Equal(t, "draft", status, "components start in the initial status")
+	status, err = checkStatus("deprecated")
+	assert.NoError(t, err)
+	assert.Equal(t, "deprecated", status)
+	_, err = checkStatus("archived")
+	assert.EqualError(t, err, `invalid status "archived": expected one of draft, active, deprecated, retired`)
+	assert.ErrorIs(t, testStore.SetComponentStatus(1, ""), ErrInvalidStatus, "checked before reaching the database")
+}
+
+func TestSetComponentStatus(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	statusOf := func(id int64) string {
+		t.Helper()
+		comp, err := testStore.GetComponentByID(id)
+		require.NoError(t, err)
+		return comp.Status
+	}
+
+	comp := &models.Component{Name: "Pump"}
+	id, err := testStore.CreateComponent(comp)
+	require.NoError(t, err)
+	assert.Equal(t, "draft", comp.Status)
+	assert.Equal(t, "draft", statusOf(id))
+
+	var published []events.Event
+	unsubscribe := events.Subscribe(func(e events.Event) { published = append(published, e) })
+	defer unsubscribe()
+	require.NoError(t, testStore.SetComponentStatus(id, "active"))
+	assert.Equal(t, "active", statusOf(id))
+	require.NoError(t, testStore.SetComponentStatus(id, "active"), "setting the current status again is a no-op")
+	if assert.Len(t, published, 1, "a no-op publishes nothing") {
+		assert.Equal(t, events.ComponentUpdated, published[0].Type)
+		assert.Equal(t, "active", published[0].Component.Status)
+	}
+
+	err = testStore.SetComponentStatus(id, "retired")
+	assert.ErrorIs(t, err, ErrIllegalTransition)
+	assert.ErrorContains(t, err, "cannot move from active to retired; allowed: deprecated")
+	assert.Equal(t, "active", statusOf(id))
+	require.NoError(t, testStore.SetComponentStatus(id, "deprecated"))
+	require.NoError(t, testStore.SetComponentStatus(id, "retired"))
+	assert.ErrorContains(t, testStore.SetComponentStatus(id, "active"), "allowed: none")
+
+	assert.ErrorIs(t, testStore.SetComponentStatus(id, "archived"), ErrInvalidStatus)
+	assert.ErrorContains(t, testStore.SetComponentStatus(id+1000, "active"), "not found")
+
+	require.NoError(t, testStore.UpdateComponent(id, &models.Component{Name: "Pump 2"}))
+	assert.Equal(t, "retired", statusOf(id), "an update leaves the status alone")
+
+	_, err = testStore.CreateComponent(&models.Component{Name: "Valve", Status: "archived"})
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	valve := &models.Component{Name: "Valve", Status: "active"}
+	valveID, err := testStore.CreateComponent(valve)
+	require.NoError(t, err)
+	assert.Equal(t, "active", statusOf(valveID), "a component may be created in any status")
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTags is returned by a write giving a component a malformed tag, or more than
+// models.MaxTags of them.
+var ErrInvalidTags = errors.New("invalid tags")
+
+// tagBatchSize bounds the component IDs per query attachTags sends, keeping it under every
+// driver's placeholder limit.
+const tagBatchSize = 500
+
+// checkTags normalizes tags as components store them, failing with ErrInvalidTags.
+func checkTags(tags []string) ([]string, error) {
+	normalized, err := models.NormalizeTags(tags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTags, err)
+	}
+	return normalized, nil
+}
+
+// replaceTags sets a component's tags to tags, which must be normalized.
+func replaceTags(exec sqlExecutor, id int64, tags []string) error {
+	if _, err := exec.Exec(db.Rebind("DELETE FROM component_tags WHERE component_id = $1"), id); err != nil {
+		return fmt.Errorf("error clearing tags of component %d: %w", id, err)
+	}
+	for _, tag := range tags {
+		if _, err := exec.Exec(db.Rebind("INSERT INTO component_tags (component_id, tag) VALUES ($1, $2)"), id, tag); err != nil {
+			return fmt.Errorf("error tagging component %d with %q: %w", id, tag, err)
+		}
+	}
+	return nil
+}
+
+// tagsOf returns a component's tags, sorted.
+func tagsOf(exec sqlExecutor, id int64) ([]string, error) {
+	component := &models.Component{ID: id}
+	if err := attachTags(exec, []*models.Component{component}); err != nil {
+		return nil, err
+	}
+	return component.Tags, nil
+}
+
+// attachTags reads the tags of components from component_tags and sets them on each, sorted.
+// Components are read from the components table alone, so every database read returning them
+// calls this once its rows are closed.
+func attachTags(exec sqlExecutor, components []*models.Component) error {
+	byID := make(map[int64]*models.Component, len(components))
+	for _, comp := range components {
+		byID[comp.ID] = comp
+		comp.Tags = nil
+	}
+	for start := 0; start < len(components); start += tagBatchSize {
+		batch := components[start:min(start+tagBatchSize, len(components))]
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, len(batch))
+		for i, comp := range batch {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = comp.ID
+		}
+		query := "SELECT component_id, tag FROM component_tags WHERE component_id IN (" + strings.Join(placeholders, ", ") + ") ORDER BY component_id, tag"
+		if err := scanTags(exec, query, args, byID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allTags reads the tags of every component, for reads covering the whole table.
+func allTags(exec sqlExecutor) (map[int64][]string, error) {
+	byID := make(map[int64]*models.Component)
+	if err := scanTags(exec, "SELECT component_id, tag FROM component_tags ORDER BY component_id, tag", nil, byID); err != nil {
+		return nil, err
+	}
+	tags := make(map[int64][]string, len(byID))
+	for id, comp := range byID {
+		tags[id] = comp.Tags
+	}
+	return tags, nil
+}
+
+// scanTags runs a query selecting (component_id, tag) rows in tag order and appends each tag to
+// its component in byID. Rows of components missing from byID are added to it.
+func scanTags(exec sqlExecutor, query string, args []interface{}, byID map[int64]*models.Component) error {
+	rows, err := exec.Query(db.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("error reading tags: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("error scanning tag: %w", err)
+		}
+		comp := byID[id]
+		if comp == nil {
+			comp = &models.Component{ID: id}
+			byID[id] = comp
+		}
+		comp.Tags = append(comp.Tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTags(t *testing.T) {
+	tags, err := checkTags([]string{"spare", "critical", "spare"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"critical", "spare"}, tags)
+	_, err = checkTags([]string{"Critical"})
+	assert.ErrorIs(t, err, ErrInvalidTags)
+}
+
+func TestComponentTags(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	tagsOf := func(id int64) []string {
+		t.Helper()
+		comp, err := testStore.Strong().GetComponentByID(id)
+		require.NoError(t, err)
+		return comp.Tags
+	}
+
+	pump := &models.Component{Name: "Pump", Tags: []string{"rotating", "critical", "rotating"}}
+	pumpID, err := testStore.CreateComponent(pump)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"critical", "rotating"}, pump.Tags, "tags are stored sorted, without repeats")
+	assert.Equal(t, []string{"critical", "rotating"}, tagsOf(pumpID))
+	valve := createTestComponent(t, "Valve", "", sql.NullInt64{Int64: pumpID, Valid: true})
+	assert.Empty(t, valve.Tags)
+
+	require.NoError(t, testStore.PatchComponent(valve.ID, models.ComponentPatch{AddTags: []string{"critical", "spare"}}))
+	assert.Equal(t, []string{"critical", "spare"}, tagsOf(valve.ID))
+	require.NoError(t, testStore.PatchComponent(pumpID, models.ComponentPatch{AddTags: []string{"spare"}, RemoveTags: []string{"rotating"}}))
+	assert.Equal(t, []string{"critical", "spare"}, tagsOf(pumpID))
+
+	for filter, want := range map[string]int{"critical": 2, "spare": 2, "rotating": 0} {
+		count, err := testStore.Strong().CountComponents(cache.Filter{Tag: filter})
+		require.NoError(t, err)
+		assert.Equal(t, want, count, filter)
+	}
+	count, err := testStore.Strong().CountChildComponents(pumpID, cache.Filter{Tag: "spare"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	descendants, total, err := testStore.Strong().ListDescendantsJSON(pumpID, 0, cache.Filter{Tag: "critical"}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Contains(t, string(descendants), `"tags":["critical","spare"]`)
+
+	require.NoError(t, testStore.UpdateComponent(pumpID, &models.Component{Name: "Pump"}))
+	assert.Empty(t, tagsOf(pumpID), "PUT replaces the tags, like every other field")
+
+	err = testStore.PatchComponent(valve.ID, models.ComponentPatch{AddTags: []string{"Spare"}})
+	assert.ErrorIs(t, err, ErrInvalidTags)
+	_, err = testStore.CreateComponent(&models.Component{Name: "Gauge", Tags: []string{"not a tag"}})
+	assert.ErrorIs(t, err, ErrInvalidTags)
+	_, err = testStore.ImportComponents("tags", []*models.Component{{ID: 1, Name: "Gauge", Tags: []string{"Bad"}}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	assert.Equal(t, []string{"critical", "spare"}, tagsOf(valve.ID), "a failed write leaves the tags alone")
+}
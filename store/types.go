@@ -0,0 +1,41 @@
+package store
+
+import (
+	"component-service/models"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrInvalidType is returned by a write giving a component a type ComponentTypes does not allow.
+var ErrInvalidType = errors.New("invalid component type")
+
+// ComponentTypes is the registry the types of created and updated components are checked
+// against. nil, the default, allows every well-formed type; components may always be untyped.
+var ComponentTypes *models.TypeRegistry
+
+// ComponentTypesFromEnv reads COMPONENT_TYPES, a comma-separated list of the types allowed, such
+// as "folder,service,device". Unset, it returns nil.
+func ComponentTypesFromEnv() (*models.TypeRegistry, error) {
+	value := os.Getenv("COMPONENT_TYPES")
+	if value == "" {
+		return nil, nil
+	}
+	registry, err := models.ParseTypeRegistry(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid COMPONENT_TYPES: %w", err)
+	}
+	return registry, nil
+}
+
+// checkType fails with ErrInvalidType unless ComponentTypes allows t.
+func checkType(t string) error {
+	if ComponentTypes.Allows(t) {
+		return nil
+	}
+	if names := ComponentTypes.Names(); names != nil {
+		return fmt.Errorf("%w %q: expected one of %s", ErrInvalidType, t, strings.Join(names, ", "))
+	}
+	return fmt.Errorf("%w %q: expected a lowercase letter followed by lowercase letters, digits, - and _", ErrInvalidType, t)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"component-service/cache"
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentTypesFromEnv(t *testing.T) {
+	t.Setenv("COMPONENT_TYPES", "")
+	registry, err := ComponentTypesFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, registry)
+
+	t.Setenv("COMPONENT_TYPES", "folder, service,device")
+	registry, err = ComponentTypesFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"folder", "service", "device"}, registry.Names())
+
+	t.Setenv("COMPONENT_TYPES", "folder,Service")
+	_, err = ComponentTypesFromEnv()
+	assert.ErrorContains(t, err, "invalid COMPONENT_TYPES")
+}
+
+func TestCheckType(t *testing.T) {
+	assert.NoError(t, checkType(""))
+	assert.NoError(t, checkType("anything"))
+	assert.ErrorIs(t, checkType("Not A Type"), ErrInvalidType)
+
+	registry, err := models.ParseTypeRegistry("folder,device")
+	require.NoError(t, err)
+	ComponentTypes = registry
+	defer func() { ComponentTypes = nil }()
+	assert.NoError(t, checkType(""), "components may always be untyped")
+	assert.NoError(t, checkType("device"))
+	assert.EqualError(t, checkType("service"), `invalid component type "service": expected one of folder, device`)
+}
+
+func TestComponentTypes(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	typeOf := func(id int64) string {
+		t.Helper()
+		comp, err := testStore.GetComponentByID(id)
+		require.NoError(t, err)
+		return comp.Type
+	}
+
+	site := &models.Component{Name: "Plant", Type: "site"}
+	siteID, err := testStore.CreateComponent(site)
+	require.NoError(t, err)
+	assert.Equal(t, "site", typeOf(siteID))
+	under := sql.NullInt64{Int64: siteID, Valid: true}
+	pump := createTestComponent(t, "Pump", "", under)
+	assert.Equal(t, "", pump.Type, "components are untyped unless given one")
+
+	require.NoError(t, testStore.UpdateComponent(pump.ID, &models.Component{Name: "Pump", Type: "device", ParentID: under}))
+	assert.Equal(t, "device", typeOf(pump.ID))
+	folder := "folder"
+	require.NoError(t, testStore.PatchComponent(pump.ID, models.ComponentPatch{Type: &folder}))
+	assert.Equal(t, "folder", typeOf(pump.ID))
+
+	count, err := testStore.Strong().CountChildComponents(siteID, cache.Filter{Type: "folder"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = testStore.Strong().CountComponents(cache.Filter{Type: "device"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	registry, err := models.ParseTypeRegistry("site,device")
+	require.NoError(t, err)
+	ComponentTypes = registry
+	defer func() { ComponentTypes = nil }()
+	_, err = testStore.CreateComponent(&models.Component{Name: "Valve", Type: "folder"})
+	assert.ErrorIs(t, err, ErrInvalidType)
+	err = testStore.UpdateComponent(pump.ID, &models.Component{Name: "Pump", Type: "gadget"})
+	assert.ErrorIs(t, err, ErrInvalidType)
+	_, err = testStore.ImportComponents("types", []*models.Component{{ID: 1, Name: "Valve", Type: "gadget"}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidImport)
+}
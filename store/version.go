@@ -0,0 +1,20 @@
+package store
+
+import (
+	"component-service/models"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is returned by a write naming the version of the component it expects, when
+// another write has changed the component since.
+var ErrVersionConflict = errors.New("version conflict")
+
+// checkVersion fails with ErrVersionConflict when expected is not 0 and before, locked by
+// lockComponentParent, is at another version.
+func checkVersion(before *models.Component, expected int64) error {
+	if expected != 0 && before.Version != expected {
+		return fmt.Errorf("%w: component %d is at version %d, not %d", ErrVersionConflict, before.ID, before.Version, expected)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentVersions(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	versionOf := func(id int64) int64 {
+		t.Helper()
+		comp, err := testStore.GetComponentByID(id)
+		require.NoError(t, err)
+		return comp.Version
+	}
+
+	parent := createTestComponent(t, "Plant", "", sql.NullInt64{})
+	pump := createTestComponent(t, "Pump", "", sql.NullInt64{Int64: parent.ID, Valid: true})
+	assert.Equal(t, int64(1), pump.Version)
+
+	require.NoError(t, testStore.UpdateComponent(pump.ID, &models.Component{Name: "Pump 2", ParentID: pump.ParentID}))
+	assert.Equal(t, int64(2), versionOf(pump.ID), "every write increments the version")
+	require.NoError(t, testStore.UpdateComponent(pump.ID, &models.Component{Name: "Pump 3", ParentID: pump.ParentID, Version: 2}))
+	assert.Equal(t, int64(3), versionOf(pump.ID))
+
+	err := testStore.UpdateComponent(pump.ID, &models.Component{Name: "Stale", Version: 2})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.ErrorContains(t, err, "is at version 3, not 2")
+	name := "Stale"
+	assert.ErrorIs(t, testStore.PatchComponent(pump.ID, models.ComponentPatch{Name: &name, Version: 1}), ErrVersionConflict)
+	assert.Equal(t, int64(3), versionOf(pump.ID), "a refused write changes nothing")
+
+	require.NoError(t, testStore.PatchComponent(pump.ID, models.ComponentPatch{Name: &name, Version: 3}))
+	require.NoError(t, testStore.SetComponentStatus(pump.ID, "active"))
+	assert.Equal(t, int64(5), versionOf(pump.ID))
+	require.NoError(t, testStore.DeleteComponent(parent.ID))
+	assert.Equal(t, int64(6), versionOf(pump.ID), "becoming a root when the parent is deleted is a change too")
+}
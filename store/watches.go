@@ -0,0 +1,113 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const watchColumns = "component_id, principal, subtree, created_at"
+
+func scanWatch(row interface{ Scan(...interface{}) error }) (*models.Watch, error) {
+	watch := &models.Watch{}
+	var createdAt time.Time
+	if err := row.Scan(&watch.ComponentID, &watch.Principal, &watch.Subtree, &createdAt); err != nil {
+		return nil, err
+	}
+	watch.CreatedAt = createdAt.Format(time.RFC3339)
+	return watch, nil
+}
+
+// WatchComponent makes principal watch a live component, or its whole subtree when subtree is
+// set. Watching a component again only changes subtree; created reports whether the watch is new.
+func (s *ComponentStore) WatchComponent(componentID int64, principal string, subtree bool) (watch *models.Watch, created bool, err error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, false, err
+	}
+	var found bool
+	err = db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		before, err := lockComponentParent(tx, componentID)
+		if err != nil || before == nil {
+			found = false
+			return err
+		}
+		found = true
+		watch, err = scanWatch(tx.QueryRow(db.Rebind("SELECT "+watchColumns+" FROM component_watches WHERE component_id = $1 AND principal = $2"),
+			componentID, principal))
+		switch {
+		case err == sql.ErrNoRows:
+			created = true
+			createdAt := time.Now().UTC()
+			watch = &models.Watch{ComponentID: componentID, Principal: principal, Subtree: subtree, CreatedAt: createdAt.Format(time.RFC3339)}
+			_, err = tx.Exec(db.Rebind("INSERT INTO component_watches (component_id, principal, subtree, created_at) VALUES ($1, $2, $3, $4)"),
+				componentID, principal, subtree, createdAt)
+		case err == nil && watch.Subtree != subtree:
+			created = false
+			watch.Subtree = subtree
+			_, err = tx.Exec(db.Rebind("UPDATE component_watches SET subtree = $1 WHERE component_id = $2 AND principal = $3"),
+				subtree, componentID, principal)
+		default:
+			created = false
+		}
+		if err != nil {
+			return fmt.Errorf("error watching component %d for %q: %w", componentID, principal, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, fmt.Errorf("component with ID %d not found", componentID)
+	}
+	return watch, created, nil
+}
+
+// UnwatchComponent stops principal watching a component.
+func (s *ComponentStore) UnwatchComponent(componentID int64, principal string) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	result, err := dbConn.Exec(db.Rebind("DELETE FROM component_watches WHERE component_id = $1 AND principal = $2"), componentID, principal)
+	if err != nil {
+		return fmt.Errorf("error unwatching component %d for %q: %w", componentID, principal, err)
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error unwatching component %d for %q: %w", componentID, principal, err)
+	} else if removed == 0 {
+		return fmt.Errorf("watch of component %d by %q not found", componentID, principal)
+	}
+	return nil
+}
+
+// ListWatches returns principal's watches of live components, oldest first. Watches of deleted
+// components are kept, and listed again if the component is restored.
+func (s *ComponentStore) ListWatches(principal string) ([]*models.Watch, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.Query(db.Rebind(`SELECT w.component_id, w.principal, w.subtree, w.created_at
+		FROM component_watches w JOIN components c ON c.id = w.component_id
+		WHERE w.principal = $1 AND c.deleted_at IS NULL ORDER BY w.created_at, w.component_id`), principal)
+	if err != nil {
+		return nil, fmt.Errorf("error listing watches of %q: %w", principal, err)
+	}
+	defer rows.Close()
+	list := []*models.Watch{}
+	for rows.Next() {
+		watch, err := scanWatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning watch: %w", err)
+		}
+		list = append(list, watch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating watches: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"component-service/db"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatches(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	clearComponentsTableForTest()
+	pump := createTestComponent(t, "Pump", "", sql.NullInt64{Valid: false})
+	valve := createTestComponent(t, "Valve", "", sql.NullInt64{Valid: false})
+
+	watch, created, err := testStore.WatchComponent(pump.ID, "alice", false)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.False(t, watch.Subtree)
+	watch, created, err = testStore.WatchComponent(pump.ID, "alice", true)
+	require.NoError(t, err)
+	assert.False(t, created, "Expected watching again to change the existing watch")
+	assert.True(t, watch.Subtree)
+	_, _, err = testStore.WatchComponent(valve.ID, "alice", false)
+	require.NoError(t, err)
+	_, _, err = testStore.WatchComponent(valve.ID, "bob", false)
+	require.NoError(t, err)
+	_, _, err = testStore.WatchComponent(88888, "alice", false)
+	assert.ErrorContains(t, err, "not found")
+
+	list, err := testStore.ListWatches("alice")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, pump.ID, list[0].ComponentID, "Expected the oldest watch first")
+	assert.True(t, list[0].Subtree)
+
+	require.NoError(t, testStore.UnwatchComponent(valve.ID, "alice"))
+	assert.ErrorContains(t, testStore.UnwatchComponent(valve.ID, "alice"), "not found")
+	list, err = testStore.ListWatches("bob")
+	require.NoError(t, err)
+	assert.Len(t, list, 1, "Expected bob's watch to be kept")
+
+	// Soft delete hides the watch until a restore; a purge removes it
+	require.NoError(t, testStore.DeleteComponent(pump.ID))
+	list, err = testStore.ListWatches("alice")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	require.NoError(t, testStore.PurgeComponent(pump.ID))
+	var remaining int
+	require.NoError(t, db.DB.QueryRow(db.Rebind("SELECT COUNT(*) FROM component_watches WHERE principal = $1"), "alice").Scan(&remaining))
+	assert.Zero(t, remaining)
+}
@@ -0,0 +1,270 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidWebhook is returned by CreateWebhook and UpdateWebhook for a webhook
+// models.CleanWebhook rejects.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+const webhookColumns = "id, url, secret, events, schema_version, created_at"
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	h := &models.Webhook{}
+	var events string
+	var createdAt time.Time
+	if err := row.Scan(&h.ID, &h.URL, &h.Secret, &events, &h.SchemaVersion, &createdAt); err != nil {
+		return nil, err
+	}
+	h.Events = []string{}
+	if events != "" {
+		h.Events = strings.Split(events, ",")
+	}
+	h.CreatedAt = createdAt.Format(time.RFC3339)
+	return h, nil
+}
+
+// CreateWebhook registers a webhook, filling in its ID and creation time.
+func (s *ComponentStore) CreateWebhook(h *models.Webhook) error {
+	if err := models.CleanWebhook(h); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC()
+	h.ID, err = insertReturningID(dbConn, "INSERT INTO webhooks (url, secret, events, schema_version, created_at) VALUES ($1, $2, $3, $4, $5)",
+		h.URL, h.Secret, strings.Join(h.Events, ","), h.SchemaVersion, createdAt)
+	if err != nil {
+		return fmt.Errorf("error creating webhook: %w", err)
+	}
+	h.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+// GetWebhook returns a webhook, with its secret.
+func (s *ComponentStore) GetWebhook(id int64) (*models.Webhook, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	h, err := scanWebhook(dbConn.QueryRow(db.Rebind("SELECT "+webhookColumns+" FROM webhooks WHERE id = $1"), id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook with ID %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook %d: %w", id, err)
+	}
+	return h, nil
+}
+
+// ListWebhooks returns every webhook, with its secret, oldest first.
+func (s *ComponentStore) ListWebhooks() ([]*models.Webhook, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := dbConn.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhooks: %w", err)
+	}
+	defer rows.Close()
+	list := []*models.Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook: %w", err)
+		}
+		list = append(list, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateWebhook replaces a webhook's URL, secret, events and schema version. An empty secret, or a
+// schema version of 0, keeps the current one, so an update never changes the format a receiver
+// gets unasked.
+func (s *ComponentStore) UpdateWebhook(h *models.Webhook) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	return db.ExecuteTx(dbConn, func(tx *sql.Tx) error {
+		current, err := scanWebhook(tx.QueryRow(db.Rebind("SELECT "+webhookColumns+" FROM webhooks WHERE id = $1 FOR UPDATE"), h.ID))
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("webhook with ID %d not found", h.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("error getting webhook %d: %w", h.ID, err)
+		}
+		if h.Secret == "" {
+			h.Secret = current.Secret
+		}
+		if h.SchemaVersion == 0 {
+			h.SchemaVersion = current.SchemaVersion
+		}
+		if err := models.CleanWebhook(h); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+		if _, err := tx.Exec(db.Rebind("UPDATE webhooks SET url = $1, secret = $2, events = $3, schema_version = $4 WHERE id = $5"),
+			h.URL, h.Secret, strings.Join(h.Events, ","), h.SchemaVersion, h.ID); err != nil {
+			return fmt.Errorf("error updating webhook %d: %w", h.ID, err)
+		}
+		h.CreatedAt = current.CreatedAt
+		return nil
+	})
+}
+
+// DeleteWebhook removes a webhook. Its deliveries go with it.
+func (s *ComponentStore) DeleteWebhook(id int64) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	result, err := dbConn.Exec(db.Rebind("DELETE FROM webhooks WHERE id = $1"), id)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook %d: %w", id, err)
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error deleting webhook %d: %w", id, err)
+	} else if removed == 0 {
+		return fmt.Errorf("webhook with ID %d not found", id)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = "id, webhook_id, event_id, event_type, component_id, attempt, status_code, error, response_body, duration_ms, succeeded, created_at"
+
+// scanWebhookDelivery scans webhookDeliveryColumns, then any columns selected after them into extra.
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	var deliveryError, responseBody sql.NullString
+	var createdAt time.Time
+	dest := []interface{}{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.ComponentID, &d.Attempt, &d.StatusCode,
+		&deliveryError, &responseBody, &d.DurationMS, &d.Succeeded, &createdAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	d.Error, d.ResponseBody = deliveryError.String, responseBody.String
+	d.CreatedAt = createdAt.Format(time.RFC3339)
+	return d, nil
+}
+
+// RecordWebhookDelivery logs a delivery attempt, filling in its ID and, when empty, its creation
+// time.
+func (s *ComponentStore) RecordWebhookDelivery(d *models.WebhookDelivery) error {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC()
+	if d.CreatedAt != "" {
+		if createdAt, err = time.Parse(time.RFC3339, d.CreatedAt); err != nil {
+			return fmt.Errorf("invalid delivery time %q: %w", d.CreatedAt, err)
+		}
+	}
+	d.ID, err = insertReturningID(dbConn, `INSERT INTO webhook_deliveries
+		(webhook_id, event_id, event_type, component_id, attempt, status_code, error, response_body, duration_ms, succeeded, created_at, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		d.WebhookID, d.EventID, d.EventType, d.ComponentID, d.Attempt, d.StatusCode, d.Error, d.ResponseBody, d.DurationMS, d.Succeeded, createdAt, d.Payload)
+	if err != nil {
+		return fmt.Errorf("error recording delivery to webhook %d: %w", d.WebhookID, err)
+	}
+	d.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+// ListWebhookDeliveries returns a page of a webhook's delivery attempts, newest first, and how
+// many it has in all.
+func (s *ComponentStore) ListWebhookDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, int, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, 0, err
+	}
+	var found int64
+	if err := dbConn.QueryRow(db.Rebind("SELECT id FROM webhooks WHERE id = $1"), webhookID).Scan(&found); err == sql.ErrNoRows {
+		return nil, 0, fmt.Errorf("webhook with ID %d not found", webhookID)
+	} else if err != nil {
+		return nil, 0, fmt.Errorf("error getting webhook %d: %w", webhookID, err)
+	}
+	var total int
+	if err := dbConn.QueryRow(db.Rebind("SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1"), webhookID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting deliveries of webhook %d: %w", webhookID, err)
+	}
+	rows, err := dbConn.Query(db.Rebind("SELECT "+webhookDeliveryColumns+` FROM webhook_deliveries WHERE webhook_id = $1
+		ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`), webhookID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing deliveries of webhook %d: %w", webhookID, err)
+	}
+	defer rows.Close()
+	list := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning delivery: %w", err)
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating deliveries: %w", err)
+	}
+	return list, total, nil
+}
+
+// GetWebhookDelivery returns one of a webhook's delivery attempts, with its payload, which is
+// empty for attempts recorded before payloads were kept.
+func (s *ComponentStore) GetWebhookDelivery(webhookID, id int64) (*models.WebhookDelivery, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	var payload sql.NullString
+	d, err := scanWebhookDelivery(dbConn.QueryRow(db.Rebind("SELECT "+webhookDeliveryColumns+`, payload
+		FROM webhook_deliveries WHERE webhook_id = $1 AND id = $2`), webhookID, id), &payload)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("delivery %d of webhook %d not found", id, webhookID)
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting delivery %d of webhook %d: %w", id, webhookID, err)
+	}
+	d.Payload = payload.String
+	return d, nil
+}
+
+// WebhookEventDelivered reports whether an attempt to deliver the event eventID to a webhook
+// succeeded.
+func (s *ComponentStore) WebhookEventDelivered(webhookID int64, eventID string) (bool, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return false, err
+	}
+	var delivered int
+	err = dbConn.QueryRow(db.Rebind(`SELECT COUNT(*) FROM webhook_deliveries
+		WHERE webhook_id = $1 AND event_id = $2 AND succeeded`), webhookID, eventID).Scan(&delivered)
+	if err != nil {
+		return false, fmt.Errorf("error checking deliveries of event %s to webhook %d: %w", eventID, webhookID, err)
+	}
+	return delivered > 0, nil
+}
+
+// PruneWebhookDeliveries deletes the delivery attempts made before a time, returning how many.
+func (s *ComponentStore) PruneWebhookDeliveries(before time.Time) (int64, error) {
+	dbConn, err := db.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	result, err := dbConn.Exec(db.Rebind("DELETE FROM webhook_deliveries WHERE created_at < $1"), before)
+	if err != nil {
+		return 0, fmt.Errorf("error pruning webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
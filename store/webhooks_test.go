@@ -0,0 +1,83 @@
+package store
+
+import (
+	"component-service/db"
+	"component-service/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("Skipping test: DB connection not initialized.")
+	}
+	_, err := db.DB.Exec("DELETE FROM webhooks")
+	require.NoError(t, err)
+
+	hook := &models.Webhook{URL: "https://hooks.example.com/a", Secret: "0123456789abcdef", Events: []string{"component.deleted", "component.created"}}
+	require.NoError(t, testStore.CreateWebhook(hook))
+	assert.NotZero(t, hook.ID)
+	assert.Equal(t, []string{"component.created", "component.deleted"}, hook.Events)
+	assert.Equal(t, 1, hook.SchemaVersion)
+	err = testStore.CreateWebhook(&models.Webhook{URL: "not a url", Secret: "0123456789abcdef"})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook), "Expected ErrInvalidWebhook, got %v", err)
+
+	// An update without a secret keeps the current one
+	update := &models.Webhook{ID: hook.ID, URL: "https://hooks.example.com/b"}
+	require.NoError(t, testStore.UpdateWebhook(update))
+	got, err := testStore.GetWebhook(hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/b", got.URL)
+	assert.Equal(t, "0123456789abcdef", got.Secret)
+	assert.Equal(t, 1, got.SchemaVersion, "Expected an update without a schema version to keep the current one")
+	assert.Empty(t, got.Events)
+	assert.ErrorContains(t, testStore.UpdateWebhook(&models.Webhook{ID: 88888, URL: "https://hooks.example.com/c"}), "not found")
+
+	list, err := testStore.ListWebhooks()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	old := time.Now().UTC().Add(-48 * time.Hour).Format(time.RFC3339)
+	for attempt := 1; attempt <= 3; attempt++ {
+		d := &models.WebhookDelivery{WebhookID: hook.ID, EventID: "0123456789abcdef0123456789abcdef", EventType: "component.created",
+			ComponentID: 7, Attempt: attempt, StatusCode: 500, ResponseBody: "busy", DurationMS: 12, Payload: `{"id":"0123456789abcdef0123456789abcdef"}`}
+		if attempt == 1 {
+			d.CreatedAt = old
+		}
+		require.NoError(t, testStore.RecordWebhookDelivery(d))
+	}
+	deliveries, total, err := testStore.ListWebhookDeliveries(hook.ID, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 3, deliveries[0].Attempt, "Expected the newest attempt first")
+	assert.Equal(t, "busy", deliveries[0].ResponseBody)
+	assert.Empty(t, deliveries[0].Payload, "Expected listings to leave the payload out")
+
+	delivery, err := testStore.GetWebhookDelivery(hook.ID, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"0123456789abcdef0123456789abcdef"}`, delivery.Payload)
+	_, err = testStore.GetWebhookDelivery(hook.ID+1, deliveries[0].ID)
+	assert.ErrorContains(t, err, "not found")
+	delivered, err := testStore.WebhookEventDelivered(hook.ID, delivery.EventID)
+	require.NoError(t, err)
+	assert.False(t, delivered)
+	require.NoError(t, testStore.RecordWebhookDelivery(&models.WebhookDelivery{WebhookID: hook.ID, EventID: delivery.EventID, EventType: "component.created",
+		ComponentID: 7, Attempt: 4, StatusCode: 200, Succeeded: true}))
+	delivered, err = testStore.WebhookEventDelivered(hook.ID, delivery.EventID)
+	require.NoError(t, err)
+	assert.True(t, delivered)
+
+	pruned, err := testStore.PruneWebhookDeliveries(time.Now().Add(-24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	require.NoError(t, testStore.DeleteWebhook(hook.ID))
+	assert.ErrorContains(t, testStore.DeleteWebhook(hook.ID), "not found")
+	_, _, err = testStore.ListWebhookDeliveries(hook.ID, 10, 0)
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,142 @@
+// Package tracing follows a change from the HTTP request that made it to everything downstream.
+// Each request gets a W3C trace context (https://www.w3.org/TR/trace-context/) and a request ID,
+// taken from its traceparent and X-Request-ID headers or started afresh, and carried in its
+// context; the store copies them onto the events the request publishes.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceParentHeader carries the W3C trace context: 00-{trace-id}-{parent-id}-{flags}.
+	TraceParentHeader = "traceparent"
+	// TraceStateHeader carries vendor-specific trace data, passed on unchanged.
+	TraceStateHeader = "tracestate"
+	// RequestIDHeader carries an ID for the request, generated when the client sent none.
+	RequestIDHeader = "X-Request-ID"
+)
+
+// maxRequestIDLength bounds a request ID accepted from a client; longer ones are replaced.
+const maxRequestIDLength = 128
+
+// Context identifies the request a change was made by.
+type Context struct {
+	// TraceParent is this service's span of the trace, as a traceparent header value: the
+	// caller's trace ID and flags, with a span ID of its own as the parent ID.
+	TraceParent string `json:"traceparent"`
+	TraceState  string `json:"tracestate,omitempty"`
+	RequestID   string `json:"request_id"`
+}
+
+// TraceID returns the trace ID of c, or "" for a nil Context.
+func (c *Context) TraceID() string {
+	if c == nil || len(c.TraceParent) < 35 {
+		return ""
+	}
+	return c.TraceParent[3:35]
+}
+
+// Inject sets c's headers on h, for a request made on behalf of c, which downstream services
+// then see as a child of this service's span. A nil Context sets nothing.
+func (c *Context) Inject(h http.Header) {
+	if c == nil {
+		return
+	}
+	h.Set(TraceParentHeader, c.TraceParent)
+	if c.TraceState != "" {
+		h.Set(TraceStateHeader, c.TraceState)
+	}
+	h.Set(RequestIDHeader, c.RequestID)
+}
+
+// contextKey is the request context key holding a request's Context.
+type contextKey struct{}
+
+// NewContext returns ctx carrying c.
+func NewContext(ctx context.Context, c *Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the Context ctx carries, or nil.
+func FromContext(ctx context.Context) *Context {
+	c, _ := ctx.Value(contextKey{}).(*Context)
+	return c
+}
+
+// FromRequest returns the Context for a request arriving with headers h. A valid traceparent
+// continues the caller's trace under a new span; without one a new trace starts, and its
+// tracestate is dropped. A missing or malformed X-Request-ID is generated.
+func FromRequest(h http.Header) *Context {
+	c := &Context{RequestID: h.Get(RequestIDHeader)}
+	if traceID, flags, ok := parseTraceParent(h.Get(TraceParentHeader)); ok {
+		c.TraceParent = "00-" + traceID + "-" + randomHex(8) + "-" + flags
+		c.TraceState = h.Get(TraceStateHeader)
+	} else {
+		c.TraceParent = "00-" + randomHex(16) + "-" + randomHex(8) + "-01"
+	}
+	if !validRequestID(c.RequestID) {
+		c.RequestID = randomHex(16)
+	}
+	return c
+}
+
+// Handler wraps next so every request carries its Context, and its response echoes the request
+// ID.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := FromRequest(r.Header)
+		w.Header().Set(RequestIDHeader, c.RequestID)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), c)))
+	})
+}
+
+// parseTraceParent returns the trace ID and flags of a traceparent header value. Versions after
+// 00 may append fields, which are ignored, as the specification asks.
+func parseTraceParent(value string) (traceID, flags string, ok bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || (len(value) > 55 && (value[:2] == "00" || value[55] != '-')) {
+		return "", "", false
+	}
+	version, traceID, parentID, flags := value[:2], value[3:35], value[36:52], value[53:55]
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' || version == "ff" ||
+		!isLowerHex(version) || !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(flags) ||
+		strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", "", false
+	}
+	return traceID, flags, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validRequestID reports whether id can be passed on as given: non-empty, at most
+// maxRequestIDLength bytes, and printable ASCII without spaces, so it is safe in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes, hex-encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b) // never fails on supported platforms
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromRequestContinuesTrace(t *testing.T) {
+	h := http.Header{}
+	h.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Set(TraceStateHeader, "vendor=abc")
+	h.Set(RequestIDHeader, "req-42")
+	c := FromRequest(h)
+	if c.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" || !strings.HasSuffix(c.TraceParent, "-01") {
+		t.Errorf("Expected the caller's trace continued, got %q", c.TraceParent)
+	}
+	if strings.Contains(c.TraceParent, "00f067aa0ba902b7") {
+		t.Errorf("Expected a span of our own, got the caller's in %q", c.TraceParent)
+	}
+	if c.TraceState != "vendor=abc" || c.RequestID != "req-42" {
+		t.Errorf("Expected tracestate and request ID passed on, got %+v", c)
+	}
+	if _, _, ok := parseTraceParent(c.TraceParent); !ok {
+		t.Errorf("Generated an invalid traceparent %q", c.TraceParent)
+	}
+
+	// Later versions may append fields
+	h.Set(TraceParentHeader, "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	if c := FromRequest(h); c.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" || !strings.HasSuffix(c.TraceParent, "-00") {
+		t.Errorf("Expected a later version's trace continued, got %q", c.TraceParent)
+	}
+}
+
+func TestFromRequestStartsTrace(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		h := http.Header{}
+		h.Set(TraceParentHeader, value)
+		h.Set(TraceStateHeader, "vendor=abc")
+		c := FromRequest(h)
+		if c.TraceID() == "" || strings.Contains(c.TraceParent, "4bf92f3577b34da6a3ce929d0e0e4736") || c.TraceState != "" {
+			t.Errorf("traceparent %q: expected a new trace, got %+v", value, c)
+		}
+		if _, _, ok := parseTraceParent(c.TraceParent); !ok {
+			t.Errorf("Generated an invalid traceparent %q", c.TraceParent)
+		}
+	}
+
+	for _, id := range []string{"", "has space", "line\nbreak", strings.Repeat("x", maxRequestIDLength+1)} {
+		h := http.Header{}
+		h[RequestIDHeader] = []string{id}
+		if c := FromRequest(h); c.RequestID == id || len(c.RequestID) != 32 {
+			t.Errorf("Request ID %q: expected a generated one, got %q", id, c.RequestID)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var seen *Context
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/components/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if seen == nil || seen.RequestID != "req-42" {
+		t.Fatalf("Expected the request's Context in its context, got %+v", seen)
+	}
+	if got := rr.Header().Get(RequestIDHeader); got != "req-42" {
+		t.Errorf("Expected the request ID echoed, got %q", got)
+	}
+
+	out := http.Header{}
+	seen.Inject(out)
+	if out.Get(TraceParentHeader) != seen.TraceParent || out.Get(RequestIDHeader) != "req-42" || out.Get(TraceStateHeader) != "" {
+		t.Errorf("Unexpected injected headers %v", out)
+	}
+	(*Context)(nil).Inject(out)
+	if FromContext(req.Context()) != nil || (*Context)(nil).TraceID() != "" {
+		t.Error("Expected no Context outside Handler")
+	}
+}
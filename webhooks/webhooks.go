@@ -0,0 +1,427 @@
+// Package webhooks delivers component changes to the URLs admins register at /admin/webhooks.
+// Each change the events package publishes is encoded once per event schema version, and POSTed to
+// every webhook subscribing to its type in the version the webhook receives, signed with that
+// webhook's secret. Failed deliveries are
+// retried with exponential backoff, and every attempt is recorded for the delivery log.
+//
+// Deliveries are queued in memory by the instance that made the change: one queue per webhook, so
+// each webhook receives its events in order and a slow one delays only itself. Events still queued
+// or waiting for a retry when the process stops are not delivered; an admin can replay them from the
+// delivery log, which keeps each attempt's payload.
+package webhooks
+
+import (
+	"bytes"
+	"component-service/events"
+	"component-service/models"
+	"component-service/signing"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Headers of a delivery. SignatureHeader holds "sha256=" and the hex HMAC-SHA256 of
+// TimestampHeader's value, a dot and the body, keyed with the webhook's secret; see Sign. With
+// Config.Keys, JWSHeader holds the body's detached JWS too, which receivers verify with the
+// service's public keys instead of the secret; see signing.KeyRing.SignDetached.
+const (
+	EventIDHeader   = "X-Webhook-ID"
+	EventTypeHeader = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+	JWSHeader       = "X-Webhook-JWS"
+)
+
+// Defaults of Config.
+const (
+	DefaultMaxAttempts = 5
+	DefaultRetryDelay  = 10 * time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultRetention   = 7 * 24 * time.Hour
+)
+
+// queueLength bounds the deliveries waiting for each webhook; events beyond it are dropped.
+const queueLength = 1000
+
+// refreshInterval is how often the registered webhooks are reread, so registrations made through
+// other instances take effect here too, and pruneInterval how often old deliveries are deleted.
+const (
+	refreshInterval = 30 * time.Second
+	pruneInterval   = time.Hour
+)
+
+// ErrQueueFull is returned by Replay when queueLength deliveries already wait for the webhook.
+var ErrQueueFull = errors.New("too many deliveries queued")
+
+// Payload is the JSON body POSTed for an event in schema version 1; see events.Encode. ID
+// identifies the event: it is the same in every attempt and for every webhook, so receivers can
+// discard repeats.
+type Payload struct {
+	ID            string `json:"id"`
+	SchemaVersion int    `json:"schema_version"`
+	events.Event
+}
+
+// Store persists webhooks and their delivery log. store.ComponentStore implements it.
+type Store interface {
+	ListWebhooks() ([]*models.Webhook, error)
+	RecordWebhookDelivery(d *models.WebhookDelivery) error
+	PruneWebhookDeliveries(before time.Time) (int64, error)
+}
+
+// Config tunes deliveries.
+type Config struct {
+	MaxAttempts int              // attempts per event and webhook, the first included
+	RetryDelay  time.Duration    // wait before the second attempt, doubled before each further one
+	Timeout     time.Duration    // per attempt, response included
+	Retention   time.Duration    // how long delivery attempts are kept
+	Keys        *signing.KeyRing // signs each delivery for JWSHeader; nil leaves it out
+}
+
+// ConfigFromEnv reads the delivery settings from the environment:
+//
+//	WEBHOOK_MAX_ATTEMPTS        attempts per event and webhook (default 5)
+//	WEBHOOK_RETRY_DELAY         wait before the first retry, doubled before each further one (default 10s)
+//	WEBHOOK_TIMEOUT             time allowed for each attempt (default 10s)
+//	WEBHOOK_DELIVERY_RETENTION  how long delivery attempts are kept (default 168h)
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{MaxAttempts: DefaultMaxAttempts, RetryDelay: DefaultRetryDelay, Timeout: DefaultTimeout, Retention: DefaultRetention}
+	if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 || attempts > 20 {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q: expected an integer between 1 and 20", value)
+		}
+		cfg.MaxAttempts = attempts
+	}
+	for _, setting := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"WEBHOOK_RETRY_DELAY", &cfg.RetryDelay},
+		{"WEBHOOK_TIMEOUT", &cfg.Timeout},
+		{"WEBHOOK_DELIVERY_RETENTION", &cfg.Retention},
+	} {
+		if value := os.Getenv(setting.name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return Config{}, fmt.Errorf("invalid %s %q: expected a positive duration", setting.name, value)
+			}
+			*setting.value = parsed
+		}
+	}
+	return cfg, nil
+}
+
+// Sign returns the SignatureHeader value of a delivery: "sha256=" and the hex HMAC-SHA256 of the
+// timestamp, a dot and the body. Signing the timestamp lets receivers reject replayed deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher delivers published events to the registered webhooks. It subscribes to events only
+// while at least one webhook is registered.
+type Dispatcher struct {
+	store  Store
+	config Config
+	client *http.Client
+
+	mu          sync.Mutex
+	endpoints   map[int64]*endpoint
+	unsubscribe func()
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// endpoint is a registered webhook with its queue and the goroutine draining it.
+type endpoint struct {
+	hook  atomic.Pointer[models.Webhook] // replaced when the webhook is updated
+	queue chan delivery
+	stop  chan struct{}
+}
+
+// delivery is an event encoded for the webhooks subscribing to it.
+type delivery struct {
+	eventID     string
+	eventType   string
+	componentID int64
+	body        []byte
+}
+
+// New returns a Dispatcher recording deliveries in s. It delivers nothing until Start.
+func New(s Store, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		store:     s,
+		config:    cfg,
+		client:    &http.Client{Timeout: cfg.Timeout},
+		endpoints: make(map[int64]*endpoint),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start loads the registered webhooks, then rereads them and prunes old deliveries in the
+// background until Stop. Failing to load them is logged, not fatal: they are read again at the
+// next refresh.
+func (d *Dispatcher) Start() {
+	if err := d.Reload(); err != nil {
+		log.Printf("Error loading webhooks: %v", err)
+	}
+	go func() {
+		refresh := time.NewTicker(refreshInterval)
+		prune := time.NewTicker(pruneInterval)
+		defer refresh.Stop()
+		defer prune.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-refresh.C:
+				if err := d.Reload(); err != nil {
+					log.Printf("Error reloading webhooks: %v", err)
+				}
+			case <-prune.C:
+				if _, err := d.store.PruneWebhookDeliveries(time.Now().Add(-d.config.Retention)); err != nil {
+					log.Printf("Error pruning webhook deliveries: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends deliveries, dropping those still queued or waiting for a retry.
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for id, ep := range d.endpoints {
+			close(ep.stop)
+			delete(d.endpoints, id)
+		}
+		if d.unsubscribe != nil {
+			d.unsubscribe()
+			d.unsubscribe = nil
+		}
+	})
+}
+
+// Reload rereads the registered webhooks: new ones start receiving events, updated ones use
+// their new URL, secret and events from their next attempt, and deleted ones stop, dropping what
+// was queued for them.
+func (d *Dispatcher) Reload() error {
+	hooks, err := d.store.ListWebhooks()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.stop:
+		return nil // stopped meanwhile
+	default:
+	}
+	registered := make(map[int64]bool, len(hooks))
+	for _, hook := range hooks {
+		registered[hook.ID] = true
+		if ep, found := d.endpoints[hook.ID]; found {
+			ep.hook.Store(hook)
+			continue
+		}
+		ep := &endpoint{queue: make(chan delivery, queueLength), stop: make(chan struct{})}
+		ep.hook.Store(hook)
+		d.endpoints[hook.ID] = ep
+		go d.run(ep)
+	}
+	for id, ep := range d.endpoints {
+		if !registered[id] {
+			close(ep.stop)
+			delete(d.endpoints, id)
+		}
+	}
+	switch {
+	case len(d.endpoints) > 0 && d.unsubscribe == nil:
+		d.unsubscribe = events.Subscribe(d.enqueue)
+	case len(d.endpoints) == 0 && d.unsubscribe != nil:
+		d.unsubscribe()
+		d.unsubscribe = nil
+	}
+	return nil
+}
+
+// enqueue encodes an event in the schema versions the webhooks subscribing to its type receive,
+// and queues it for them. It runs on the publishing goroutine, so it never waits: a full queue
+// drops the event for that webhook.
+func (d *Dispatcher) enqueue(e events.Event) {
+	d.mu.Lock()
+	var targets []*endpoint
+	for _, ep := range d.endpoints {
+		if ep.hook.Load().Wants(string(e.Type)) {
+			targets = append(targets, ep)
+		}
+	}
+	d.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+	id := newEventID()
+	bodies := map[int][]byte{}
+	for _, ep := range targets {
+		hook := ep.hook.Load()
+		body, encoded := bodies[hook.SchemaVersion]
+		if !encoded {
+			var err error
+			if body, err = events.Encode(e, hook.SchemaVersion, id); err != nil {
+				log.Printf("Error encoding webhook payload for %s of component %d: %v", e.Type, e.ComponentID, err)
+				continue
+			}
+			bodies[hook.SchemaVersion] = body
+		}
+		next := delivery{eventID: id, eventType: string(e.Type), componentID: e.ComponentID, body: body}
+		select {
+		case ep.queue <- next:
+		default:
+			log.Printf("Webhook %d: %d deliveries already queued, dropping event %s", hook.ID, queueLength, id)
+		}
+	}
+}
+
+// Replay queues the payload of a recorded attempt to be sent again to its webhook, after the
+// deliveries already queued for it, with the usual retries. It keeps its event ID, so receivers
+// that saw the event still discard it, and is signed anew with the webhook's current secret. Its
+// attempts are numbered from 1 again. A webhook registered through another instance and not yet
+// loaded here is loaded first.
+func (d *Dispatcher) Replay(record *models.WebhookDelivery) error {
+	ep := d.loaded(record.WebhookID)
+	if ep == nil {
+		if err := d.Reload(); err != nil {
+			return err
+		}
+		if ep = d.loaded(record.WebhookID); ep == nil {
+			return fmt.Errorf("webhook with ID %d not found", record.WebhookID)
+		}
+	}
+	next := delivery{eventID: record.EventID, eventType: record.EventType, componentID: record.ComponentID, body: []byte(record.Payload)}
+	select {
+	case ep.queue <- next:
+		return nil
+	default:
+		return fmt.Errorf("%w: %d deliveries already wait for webhook %d", ErrQueueFull, queueLength, record.WebhookID)
+	}
+}
+
+// loaded returns the endpoint of the loaded webhook with ID id, or nil.
+func (d *Dispatcher) loaded(id int64) *endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.endpoints[id]
+}
+
+// run delivers an endpoint's queued events one at a time, until the endpoint stops.
+func (d *Dispatcher) run(ep *endpoint) {
+	for {
+		select {
+		case <-ep.stop:
+			return
+		case next := <-ep.queue:
+			d.deliver(ep, next)
+		}
+	}
+}
+
+// deliver attempts a delivery until it succeeds, fails for good, or runs out of attempts,
+// recording each attempt.
+func (d *Dispatcher) deliver(ep *endpoint, next delivery) {
+	delay := d.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		hook := ep.hook.Load()
+		record, retry := d.attempt(hook, next)
+		record.Attempt = attempt
+		if err := d.store.RecordWebhookDelivery(record); err != nil {
+			log.Printf("Error recording delivery of event %s to webhook %d: %v", next.eventID, hook.ID, err)
+		}
+		if record.Succeeded || !retry || attempt >= d.config.MaxAttempts {
+			return
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ep.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// attempt POSTs a delivery to hook once. retry reports whether a failure may be temporary: no
+// response, 408, 429 or a 5xx status.
+func (d *Dispatcher) attempt(hook *models.Webhook, next delivery) (record *models.WebhookDelivery, retry bool) {
+	record = &models.WebhookDelivery{
+		WebhookID:   hook.ID,
+		EventID:     next.eventID,
+		EventType:   next.eventType,
+		ComponentID: next.componentID,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		Payload:     string(next.body),
+	}
+	start := time.Now()
+	defer func() { record.DurationMS = time.Since(start).Milliseconds() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(next.body))
+	if err != nil {
+		record.Error = err.Error()
+		return record, false
+	}
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "component-service-webhooks")
+	req.Header.Set(EventIDHeader, next.eventID)
+	req.Header.Set(EventTypeHeader, next.eventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, next.body))
+	if d.config.Keys != nil {
+		req.Header.Set(JWSHeader, d.config.Keys.SignDetached(next.body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		record.Error = err.Error()
+		return record, true
+	}
+	defer resp.Body.Close()
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, models.MaxDeliveryResponseBytes))
+	record.StatusCode = resp.StatusCode
+	// Kept as text: invalid UTF-8 and NUL bytes, which databases reject, are replaced
+	record.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(responseBody), "\uFFFD"), "\x00", "\uFFFD")
+	record.Succeeded = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !record.Succeeded {
+		record.Error = resp.Status
+	}
+	return record, resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// newEventID returns 32 random hex digits.
+func newEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("webhooks: reading random bytes: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
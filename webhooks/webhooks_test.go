@@ -0,0 +1,199 @@
+package webhooks
+
+import (
+	"component-service/events"
+	"component-service/models"
+	"component-service/signing"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps webhooks and deliveries in memory.
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      []*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func (s *memoryStore) ListWebhooks() ([]*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.Webhook(nil), s.hooks...), nil
+}
+
+func (s *memoryStore) RecordWebhookDelivery(d *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, d)
+	return nil
+}
+
+func (s *memoryStore) PruneWebhookDeliveries(before time.Time) (int64, error) { return 0, nil }
+
+func (s *memoryStore) recorded() []*models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.WebhookDelivery(nil), s.deliveries...)
+}
+
+func TestEventTypesAndVersionsMatchModels(t *testing.T) {
+	for _, eventType := range events.Types {
+		assert.Contains(t, models.WebhookEventTypes, string(eventType))
+	}
+	assert.Len(t, models.WebhookEventTypes, 4)
+	assert.Equal(t, events.SchemaVersions, models.WebhookSchemaVersions)
+}
+
+func TestDispatcher(t *testing.T) {
+	const secret = "0123456789abcdef"
+	keys, err := signing.ParseKeys("k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	var mu sync.Mutex
+	var received []Payload
+	failures := 2 // the first attempts fail, then the retry succeeds
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign(secret, r.Header.Get(TimestampHeader), body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if _, err := signing.Verify(keys.JWKSet(), r.Header.Get(JWSHeader), body); err != nil {
+			http.Error(w, "bad JWS", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		var payload Payload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.ID != r.Header.Get(EventIDHeader) {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	s := &memoryStore{hooks: []*models.Webhook{
+		{ID: 1, URL: server.URL, Secret: secret, Events: []string{"component.created", "component.deleted"}, SchemaVersion: 1},
+		{ID: 2, URL: server.URL, Secret: "a different secret", Events: []string{"component.moved"}, SchemaVersion: 1},
+	}}
+	d := New(s, Config{MaxAttempts: 3, RetryDelay: time.Millisecond, Timeout: time.Second, Retention: time.Hour, Keys: keys})
+	d.Start()
+	defer d.Stop()
+
+	events.Publish(events.Event{Type: events.ComponentCreated, ComponentID: 7, Component: &models.Component{ID: 7, Name: "pump"}})
+	events.Publish(events.Event{Type: events.ComponentUpdated, ComponentID: 7}) // no webhook wants it
+	events.Publish(events.Event{Type: events.ComponentDeleted, ComponentID: 7})
+	events.Publish(events.Event{Type: events.ComponentMoved, ComponentID: 8}) // signed with the wrong secret
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2 && len(s.recorded()) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, events.ComponentCreated, received[0].Type, "Expected events in order")
+	assert.Equal(t, 1, received[0].SchemaVersion)
+	assert.Equal(t, "pump", received[0].Component.Name)
+	assert.Equal(t, events.ComponentDeleted, received[1].Type)
+	mu.Unlock()
+
+	var attempts []int
+	for _, delivery := range s.recorded() {
+		if delivery.WebhookID == 2 {
+			assert.Equal(t, http.StatusUnauthorized, delivery.StatusCode)
+			assert.False(t, delivery.Succeeded)
+			continue // a 401 is not retried
+		}
+		attempts = append(attempts, delivery.Attempt)
+		if !delivery.Succeeded {
+			assert.Equal(t, http.StatusServiceUnavailable, delivery.StatusCode)
+			assert.Equal(t, "busy\n", delivery.ResponseBody)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 1}, attempts)
+
+	// Without webhooks the dispatcher stops listening
+	s.mu.Lock()
+	s.hooks = nil
+	s.mu.Unlock()
+	require.NoError(t, d.Reload())
+	assert.False(t, events.HasSubscribers())
+}
+
+func TestDispatcherReplay(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	down := true // the receiver is down until the replay
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, string(body))
+	}))
+	defer server.Close()
+
+	s := &memoryStore{hooks: []*models.Webhook{{ID: 1, URL: server.URL, Secret: "0123456789abcdef", SchemaVersion: events.SchemaVersion}}}
+	d := New(s, Config{MaxAttempts: 2, RetryDelay: time.Millisecond, Timeout: time.Second, Retention: time.Hour})
+	d.Start()
+	defer d.Stop()
+
+	events.Publish(events.Event{Type: events.ComponentCreated, ComponentID: 7})
+	require.Eventually(t, func() bool { return len(s.recorded()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	failed := s.recorded()[1]
+	require.False(t, failed.Succeeded)
+	require.NotEmpty(t, failed.Payload, "Expected the payload to be recorded")
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	require.NoError(t, d.Replay(failed))
+	require.Eventually(t, func() bool { return len(s.recorded()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	replayed := s.recorded()[2]
+	assert.True(t, replayed.Succeeded)
+	assert.Equal(t, 1, replayed.Attempt)
+	assert.Equal(t, failed.EventID, replayed.EventID)
+	mu.Lock()
+	assert.Equal(t, []string{failed.Payload}, received)
+	mu.Unlock()
+
+	assert.ErrorContains(t, d.Replay(&models.WebhookDelivery{WebhookID: 2, Payload: "{}"}), "not found")
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxAttempts, cfg.MaxAttempts)
+
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+	t.Setenv("WEBHOOK_RETRY_DELAY", "1m")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 3, cfg.MaxAttempts)
+	assert.Equal(t, time.Minute, cfg.RetryDelay)
+
+	t.Setenv("WEBHOOK_TIMEOUT", "soon")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+	t.Setenv("WEBHOOK_TIMEOUT", "")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "0")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}